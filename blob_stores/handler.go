@@ -0,0 +1,332 @@
+package blob_stores
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/elorenzorodz/co-library/common"
+)
+
+// Returns nil when uploads are turned off with BLOB_STORE=none.
+func NewBlobStore(envConfig common.EnvConfig) (common.BlobStore, error) {
+	switch envConfig.BlobStore {
+	case "", BlobStoreLocal:
+		return NewLocalBlobStore(envConfig.BlobStoreDir, envConfig.BlobStorePublicURL), nil
+	case BlobStoreS3:
+		if envConfig.S3Bucket == "" || envConfig.S3AccessKeyID == "" || envConfig.S3SecretAccessKey == "" {
+			return nil, fmt.Errorf("S3_BUCKET, S3_ACCESS_KEY_ID and S3_SECRET_ACCESS_KEY are required for the %s blob store", BlobStoreS3)
+		}
+
+		return NewS3BlobStore(envConfig.S3Endpoint, envConfig.S3Region, envConfig.S3Bucket, envConfig.S3AccessKeyID, envConfig.S3SecretAccessKey, envConfig.BlobStorePublicURL), nil
+	case BlobStoreNone:
+		return nil, nil
+	}
+
+	return nil, fmt.Errorf("unknown blob store: %s", envConfig.BlobStore)
+}
+
+// Keys are relative slash separated paths, anything that could escape the
+// store's root is rejected.
+func ValidateBlobKey(key string) error {
+	if key == "" || strings.HasPrefix(key, "/") || strings.Contains(key, "\\") || path.Clean(key) != key {
+		return fmt.Errorf("invalid blob key: %q", key)
+	}
+
+	for _, segment := range strings.Split(key, "/") {
+		if segment == ".." || segment == "." {
+			return fmt.Errorf("invalid blob key: %q", key)
+		}
+	}
+
+	return nil
+}
+
+func NewLocalBlobStore(dir string, baseURL string) *LocalBlobStore {
+	if dir == "" {
+		dir = DefaultBlobStoreDir
+	}
+
+	if baseURL == "" {
+		baseURL = DefaultLocalBlobStoreURL
+	}
+
+	return &LocalBlobStore{
+		Dir:     dir,
+		BaseURL: strings.TrimSuffix(baseURL, "/"),
+	}
+}
+
+func (localBlobStore *LocalBlobStore) Put(ctx context.Context, key string, contentType string, content []byte) (string, error) {
+	if validateError := ValidateBlobKey(key); validateError != nil {
+		return "", validateError
+	}
+
+	blobPath := filepath.Join(localBlobStore.Dir, filepath.FromSlash(key))
+
+	if mkdirError := os.MkdirAll(filepath.Dir(blobPath), 0o755); mkdirError != nil {
+		return "", fmt.Errorf("error creating blob directory: %w", mkdirError)
+	}
+
+	// Written next to the final path and renamed so readers never see half a file.
+	tempFile, createTempError := os.CreateTemp(filepath.Dir(blobPath), ".upload-*")
+
+	if createTempError != nil {
+		return "", fmt.Errorf("error creating blob %s: %w", key, createTempError)
+	}
+
+	defer os.Remove(tempFile.Name())
+
+	if _, writeError := tempFile.Write(content); writeError != nil {
+		tempFile.Close()
+
+		return "", fmt.Errorf("error writing blob %s: %w", key, writeError)
+	}
+
+	if closeError := tempFile.Close(); closeError != nil {
+		return "", fmt.Errorf("error writing blob %s: %w", key, closeError)
+	}
+
+	if chmodError := os.Chmod(tempFile.Name(), 0o644); chmodError != nil {
+		return "", fmt.Errorf("error writing blob %s: %w", key, chmodError)
+	}
+
+	if renameError := os.Rename(tempFile.Name(), blobPath); renameError != nil {
+		return "", fmt.Errorf("error writing blob %s: %w", key, renameError)
+	}
+
+	return localBlobStore.BaseURL + "/" + key, nil
+}
+
+func (localBlobStore *LocalBlobStore) Delete(ctx context.Context, key string) error {
+	if validateError := ValidateBlobKey(key); validateError != nil {
+		return validateError
+	}
+
+	removeError := os.Remove(filepath.Join(localBlobStore.Dir, filepath.FromSlash(key)))
+
+	if removeError != nil && !errors.Is(removeError, os.ErrNotExist) {
+		return fmt.Errorf("error deleting blob %s: %w", key, removeError)
+	}
+
+	return nil
+}
+
+// Serves stored blobs by key, mount it with the base URL stripped from the path.
+// Directories are not listed.
+func (localBlobStore *LocalBlobStore) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	key := strings.TrimPrefix(request.URL.Path, "/")
+
+	if ValidateBlobKey(key) != nil || strings.HasPrefix(path.Base(key), ".") {
+		http.NotFound(writer, request)
+
+		return
+	}
+
+	blobFile, openError := os.Open(filepath.Join(localBlobStore.Dir, filepath.FromSlash(key)))
+
+	if openError != nil {
+		http.NotFound(writer, request)
+
+		return
+	}
+
+	defer blobFile.Close()
+
+	blobFileInfo, statError := blobFile.Stat()
+
+	if statError != nil || blobFileInfo.IsDir() {
+		http.NotFound(writer, request)
+
+		return
+	}
+
+	// Keys are never reused, a new upload gets a new key.
+	writer.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+	http.ServeContent(writer, request, blobFileInfo.Name(), blobFileInfo.ModTime(), blobFile)
+}
+
+func NewS3BlobStore(endpoint string, region string, bucket string, accessKeyID string, secretAccessKey string, publicURL string) *S3BlobStore {
+	if region == "" {
+		region = DefaultS3Region
+	}
+
+	if endpoint == "" {
+		endpoint = fmt.Sprintf("https://s3.%s.amazonaws.com", region)
+	}
+
+	endpoint = strings.TrimSuffix(endpoint, "/")
+
+	if publicURL == "" {
+		publicURL = endpoint + "/" + bucket
+	}
+
+	return &S3BlobStore{
+		Endpoint:        endpoint,
+		Region:          region,
+		Bucket:          bucket,
+		AccessKeyID:     accessKeyID,
+		SecretAccessKey: secretAccessKey,
+		PublicURL:       strings.TrimSuffix(publicURL, "/"),
+		Client:          &http.Client{Timeout: BlobRequestTimeout},
+		now:             time.Now,
+	}
+}
+
+func (s3BlobStore *S3BlobStore) Put(ctx context.Context, key string, contentType string, content []byte) (string, error) {
+	if validateError := ValidateBlobKey(key); validateError != nil {
+		return "", validateError
+	}
+
+	request, requestError := http.NewRequestWithContext(ctx, http.MethodPut, s3BlobStore.objectURL(key), bytes.NewReader(content))
+
+	if requestError != nil {
+		return "", requestError
+	}
+
+	request.Header.Set("Content-Type", contentType)
+
+	if doError := s3BlobStore.do(request, content); doError != nil {
+		return "", fmt.Errorf("error uploading blob %s: %w", key, doError)
+	}
+
+	return s3BlobStore.PublicURL + "/" + key, nil
+}
+
+func (s3BlobStore *S3BlobStore) Delete(ctx context.Context, key string) error {
+	if validateError := ValidateBlobKey(key); validateError != nil {
+		return validateError
+	}
+
+	request, requestError := http.NewRequestWithContext(ctx, http.MethodDelete, s3BlobStore.objectURL(key), nil)
+
+	if requestError != nil {
+		return requestError
+	}
+
+	// S3 answers 204 whether or not the object was there.
+	if doError := s3BlobStore.do(request, nil); doError != nil {
+		return fmt.Errorf("error deleting blob %s: %w", key, doError)
+	}
+
+	return nil
+}
+
+func (s3BlobStore *S3BlobStore) objectURL(key string) string {
+	return s3BlobStore.Endpoint + "/" + s3EscapePath(s3BlobStore.Bucket+"/"+key)
+}
+
+func (s3BlobStore *S3BlobStore) do(request *http.Request, payload []byte) error {
+	SignS3Request(request, payload, s3BlobStore.Region, s3BlobStore.AccessKeyID, s3BlobStore.SecretAccessKey, s3BlobStore.now())
+
+	response, responseError := s3BlobStore.Client.Do(request)
+
+	if responseError != nil {
+		return responseError
+	}
+
+	defer response.Body.Close()
+
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		responseBody, _ := io.ReadAll(io.LimitReader(response.Body, 1024))
+
+		return fmt.Errorf("blob store responded with %s: %s", response.Status, strings.TrimSpace(string(responseBody)))
+	}
+
+	return nil
+}
+
+// Signs the request with AWS Signature Version 4. The host and every header
+// already set on the request are signed, along with the payload hash.
+func SignS3Request(request *http.Request, payload []byte, region string, accessKeyID string, secretAccessKey string, signedAt time.Time) {
+	signedAt = signedAt.UTC()
+	amzDate := signedAt.Format("20060102T150405Z")
+	shortDate := signedAt.Format("20060102")
+	payloadHash := sha256Hex(payload)
+
+	request.Header.Set("X-Amz-Date", amzDate)
+	request.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	canonicalHeaders := map[string]string{"host": request.URL.Host}
+
+	for name, values := range request.Header {
+		canonicalHeaders[strings.ToLower(name)] = strings.TrimSpace(strings.Join(values, ","))
+	}
+
+	headerNames := make([]string, 0, len(canonicalHeaders))
+
+	for name := range canonicalHeaders {
+		headerNames = append(headerNames, name)
+	}
+
+	sort.Strings(headerNames)
+
+	var canonicalHeadersBuilder strings.Builder
+
+	for _, name := range headerNames {
+		canonicalHeadersBuilder.WriteString(name + ":" + canonicalHeaders[name] + "\n")
+	}
+
+	signedHeaders := strings.Join(headerNames, ";")
+
+	canonicalRequest := strings.Join([]string{
+		request.Method,
+		s3EscapePath(request.URL.Path),
+		request.URL.Query().Encode(),
+		canonicalHeadersBuilder.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	credentialScope := shortDate + "/" + region + "/s3/aws4_request"
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + credentialScope + "\n" + sha256Hex([]byte(canonicalRequest))
+
+	signingKey := hmacSHA256([]byte("AWS4"+secretAccessKey), shortDate)
+	signingKey = hmacSHA256(signingKey, region)
+	signingKey = hmacSHA256(signingKey, "s3")
+	signingKey = hmacSHA256(signingKey, "aws4_request")
+
+	request.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		accessKeyID, credentialScope, signedHeaders, hex.EncodeToString(hmacSHA256(signingKey, stringToSign))))
+}
+
+// Percent-encodes everything but unreserved characters and slashes, as S3 expects.
+func s3EscapePath(unescapedPath string) string {
+	var escapedPath strings.Builder
+
+	for _, character := range []byte(unescapedPath) {
+		if ('A' <= character && character <= 'Z') || ('a' <= character && character <= 'z') || ('0' <= character && character <= '9') ||
+			character == '-' || character == '_' || character == '.' || character == '~' || character == '/' {
+			escapedPath.WriteByte(character)
+		} else {
+			fmt.Fprintf(&escapedPath, "%%%02X", character)
+		}
+	}
+
+	return escapedPath.String()
+}
+
+func sha256Hex(content []byte) string {
+	hash := sha256.Sum256(content)
+
+	return hex.EncodeToString(hash[:])
+}
+
+func hmacSHA256(key []byte, message string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(message))
+
+	return mac.Sum(nil)
+}
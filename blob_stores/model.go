@@ -0,0 +1,39 @@
+package blob_stores
+
+import (
+	"net/http"
+	"time"
+)
+
+// Values accepted by the BLOB_STORE environment variable.
+const (
+	BlobStoreLocal = "local"
+	BlobStoreS3    = "s3"
+	BlobStoreNone  = "none"
+)
+
+const (
+	DefaultBlobStoreDir      = "uploads"
+	DefaultLocalBlobStoreURL = "/blobs"
+	DefaultS3Region          = "us-east-1"
+	BlobRequestTimeout       = 30 * time.Second
+)
+
+// Keeps blobs as files under a directory, served back by the API itself.
+type LocalBlobStore struct {
+	Dir     string
+	BaseURL string
+}
+
+// Keeps blobs in a bucket of any S3 compatible service (AWS, MinIO, R2...),
+// addressed path style so custom endpoints work without DNS setup.
+type S3BlobStore struct {
+	Endpoint        string
+	Region          string
+	Bucket          string
+	AccessKeyID     string
+	SecretAccessKey string
+	PublicURL       string
+	Client          *http.Client
+	now             func() time.Time
+}
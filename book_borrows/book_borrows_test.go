@@ -3,15 +3,19 @@ package book_borrows
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/elorenzorodz/co-library/common"
 	"github.com/elorenzorodz/co-library/internal/database"
+	"github.com/elorenzorodz/co-library/notification_templates"
+	"github.com/elorenzorodz/co-library/notifiers"
 	"github.com/google/uuid"
 	"github.com/gorilla/mux"
 )
@@ -19,10 +23,57 @@ import (
 type MockQueries struct {
 	*common.BaseMock
 
-	GetBookFunc       func(ctx context.Context, id uuid.UUID) (database.Book, error)
-	GetBookBorrowFunc func(ctx context.Context, bookID uuid.UUID) (database.BookBorrow, error)
-	IssueBookFunc     func(ctx context.Context, arg database.IssueBookParams) (database.BookBorrow, error)
-	ReturnBookFunc    func(ctx context.Context, arg database.ReturnBookParams) (database.BookBorrow, error)
+	GetBookFunc                  func(ctx context.Context, id uuid.UUID) (database.Book, error)
+	CountAvailableBookCopiesFunc func(ctx context.Context, bookID uuid.UUID) (int64, error)
+	LockBookForIssueFunc         func(ctx context.Context, id uuid.UUID) error
+	IssueBookFunc                func(ctx context.Context, arg database.IssueBookParams) (database.BookBorrow, error)
+	ReturnBookFunc               func(ctx context.Context, arg database.ReturnBookParams) (database.BookBorrow, error)
+
+	CreateBookBorrowRequestFunc       func(ctx context.Context, arg database.CreateBookBorrowRequestParams) (database.BookBorrowRequest, error)
+	GetOpenBookBorrowRequestFunc      func(ctx context.Context, arg database.GetOpenBookBorrowRequestParams) (database.BookBorrowRequest, error)
+	RespondToBookBorrowRequestFunc    func(ctx context.Context, arg database.RespondToBookBorrowRequestParams) (database.BookBorrowRequest, error)
+	CancelBookBorrowRequestFunc       func(ctx context.Context, arg database.CancelBookBorrowRequestParams) (database.BookBorrowRequest, error)
+	MarkBookBorrowRequestIssuedFunc   func(ctx context.Context, arg database.MarkBookBorrowRequestIssuedParams) (int64, error)
+	GetIncomingBookBorrowRequestsFunc func(ctx context.Context, arg database.GetIncomingBookBorrowRequestsParams) ([]database.GetIncomingBookBorrowRequestsRow, error)
+
+	GetOfferedBookReservationsFunc func(ctx context.Context, bookID uuid.UUID) ([]database.BookReservation, error)
+	FulfillBookReservationFunc     func(ctx context.Context, id uuid.UUID) error
+
+	GetBookBorrowByIDFunc           func(ctx context.Context, id uuid.UUID) (database.BookBorrow, error)
+	GetBookBorrowWithOwnerFunc      func(ctx context.Context, id uuid.UUID) (database.GetBookBorrowWithOwnerRow, error)
+	RenewBookBorrowFunc             func(ctx context.Context, arg database.RenewBookBorrowParams) (database.BookBorrowRenewal, error)
+	CountBookBorrowRenewalsFunc     func(ctx context.Context, bookBorrowID uuid.UUID) (int64, error)
+	CountActiveBookReservationsFunc func(ctx context.Context, bookID uuid.UUID) (int64, error)
+
+	ConfirmBookReturnFunc func(ctx context.Context, arg database.ConfirmBookReturnParams) (database.BookBorrow, error)
+	DisputeBookReturnFunc func(ctx context.Context, arg database.DisputeBookReturnParams) (database.BookBorrow, error)
+
+	GetBookBorrowsDueForReminderFunc func(ctx context.Context, arg database.GetBookBorrowsDueForReminderParams) ([]database.GetBookBorrowsDueForReminderRow, error)
+	CreateBookBorrowReminderFunc     func(ctx context.Context, arg database.CreateBookBorrowReminderParams) (database.BookBorrowReminder, error)
+	DeleteBookBorrowReminderFunc     func(ctx context.Context, id uuid.UUID) error
+
+	GetBorrowedBooksFunc func(ctx context.Context, arg database.GetBorrowedBooksParams) ([]database.GetBorrowedBooksRow, error)
+	GetLentBooksFunc     func(ctx context.Context, arg database.GetLentBooksParams) ([]database.GetLentBooksRow, error)
+
+	GetMemberRelationshipFunc func(ctx context.Context, arg database.GetMemberRelationshipParams) (database.GetMemberRelationshipRow, error)
+
+	GetBorrowerReputationStatsFunc func(ctx context.Context, borrowerID uuid.UUID) (database.BorrowerReputationStat, error)
+}
+
+func (mockQueries *MockQueries) GetBorrowerReputationStats(ctx context.Context, borrowerID uuid.UUID) (database.BorrowerReputationStat, error) {
+	if mockQueries.GetBorrowerReputationStatsFunc != nil {
+		return mockQueries.GetBorrowerReputationStatsFunc(ctx, borrowerID)
+	}
+
+	return mockQueries.BaseMock.GetBorrowerReputationStats(ctx, borrowerID)
+}
+
+func (mockQueries *MockQueries) GetMemberRelationship(ctx context.Context, arg database.GetMemberRelationshipParams) (database.GetMemberRelationshipRow, error) {
+	if mockQueries.GetMemberRelationshipFunc != nil {
+		return mockQueries.GetMemberRelationshipFunc(ctx, arg)
+	}
+
+	return mockQueries.BaseMock.GetMemberRelationship(ctx, arg)
 }
 
 func (mockQueries *MockQueries) GetBook(ctx context.Context, id uuid.UUID) (database.Book, error) {
@@ -33,12 +84,20 @@ func (mockQueries *MockQueries) GetBook(ctx context.Context, id uuid.UUID) (data
 	return mockQueries.BaseMock.GetBook(ctx, id)
 }
 
-func (mockQueries *MockQueries) GetBookBorrow(ctx context.Context, bookID uuid.UUID) (database.BookBorrow, error) {
-	if mockQueries.GetBookBorrowFunc != nil {
-		return mockQueries.GetBookBorrowFunc(ctx, bookID)
+func (mockQueries *MockQueries) CountAvailableBookCopies(ctx context.Context, bookID uuid.UUID) (int64, error) {
+	if mockQueries.CountAvailableBookCopiesFunc != nil {
+		return mockQueries.CountAvailableBookCopiesFunc(ctx, bookID)
 	}
 	
-	return mockQueries.BaseMock.GetBookBorrow(ctx, bookID)
+	return mockQueries.BaseMock.CountAvailableBookCopies(ctx, bookID)
+}
+
+func (mockQueries *MockQueries) LockBookForIssue(ctx context.Context, id uuid.UUID) error {
+	if mockQueries.LockBookForIssueFunc != nil {
+		return mockQueries.LockBookForIssueFunc(ctx, id)
+	}
+
+	return mockQueries.BaseMock.LockBookForIssue(ctx, id)
 }
 
 func (mockQueries *MockQueries) IssueBook(ctx context.Context, arg database.IssueBookParams) (database.BookBorrow, error) {
@@ -57,6 +116,186 @@ func (mockQueries *MockQueries) ReturnBook(ctx context.Context, arg database.Ret
 	return mockQueries.BaseMock.ReturnBook(ctx, arg)
 }
 
+func (mockQueries *MockQueries) CreateBookBorrowRequest(ctx context.Context, arg database.CreateBookBorrowRequestParams) (database.BookBorrowRequest, error) {
+	if mockQueries.CreateBookBorrowRequestFunc != nil {
+		return mockQueries.CreateBookBorrowRequestFunc(ctx, arg)
+	}
+
+	return mockQueries.BaseMock.CreateBookBorrowRequest(ctx, arg)
+}
+
+func (mockQueries *MockQueries) GetOpenBookBorrowRequest(ctx context.Context, arg database.GetOpenBookBorrowRequestParams) (database.BookBorrowRequest, error) {
+	if mockQueries.GetOpenBookBorrowRequestFunc != nil {
+		return mockQueries.GetOpenBookBorrowRequestFunc(ctx, arg)
+	}
+
+	return mockQueries.BaseMock.GetOpenBookBorrowRequest(ctx, arg)
+}
+
+func (mockQueries *MockQueries) RespondToBookBorrowRequest(ctx context.Context, arg database.RespondToBookBorrowRequestParams) (database.BookBorrowRequest, error) {
+	if mockQueries.RespondToBookBorrowRequestFunc != nil {
+		return mockQueries.RespondToBookBorrowRequestFunc(ctx, arg)
+	}
+
+	return mockQueries.BaseMock.RespondToBookBorrowRequest(ctx, arg)
+}
+
+func (mockQueries *MockQueries) CancelBookBorrowRequest(ctx context.Context, arg database.CancelBookBorrowRequestParams) (database.BookBorrowRequest, error) {
+	if mockQueries.CancelBookBorrowRequestFunc != nil {
+		return mockQueries.CancelBookBorrowRequestFunc(ctx, arg)
+	}
+
+	return mockQueries.BaseMock.CancelBookBorrowRequest(ctx, arg)
+}
+
+func (mockQueries *MockQueries) MarkBookBorrowRequestIssued(ctx context.Context, arg database.MarkBookBorrowRequestIssuedParams) (int64, error) {
+	if mockQueries.MarkBookBorrowRequestIssuedFunc != nil {
+		return mockQueries.MarkBookBorrowRequestIssuedFunc(ctx, arg)
+	}
+
+	return mockQueries.BaseMock.MarkBookBorrowRequestIssued(ctx, arg)
+}
+
+func (mockQueries *MockQueries) GetIncomingBookBorrowRequests(ctx context.Context, arg database.GetIncomingBookBorrowRequestsParams) ([]database.GetIncomingBookBorrowRequestsRow, error) {
+	if mockQueries.GetIncomingBookBorrowRequestsFunc != nil {
+		return mockQueries.GetIncomingBookBorrowRequestsFunc(ctx, arg)
+	}
+
+	return mockQueries.BaseMock.GetIncomingBookBorrowRequests(ctx, arg)
+}
+
+func (mockQueries *MockQueries) GetOfferedBookReservations(ctx context.Context, bookID uuid.UUID) ([]database.BookReservation, error) {
+	if mockQueries.GetOfferedBookReservationsFunc != nil {
+		return mockQueries.GetOfferedBookReservationsFunc(ctx, bookID)
+	}
+
+	return mockQueries.BaseMock.GetOfferedBookReservations(ctx, bookID)
+}
+
+func (mockQueries *MockQueries) FulfillBookReservation(ctx context.Context, id uuid.UUID) error {
+	if mockQueries.FulfillBookReservationFunc != nil {
+		return mockQueries.FulfillBookReservationFunc(ctx, id)
+	}
+
+	return mockQueries.BaseMock.FulfillBookReservation(ctx, id)
+}
+
+func (mockQueries *MockQueries) GetBookBorrowByID(ctx context.Context, id uuid.UUID) (database.BookBorrow, error) {
+	if mockQueries.GetBookBorrowByIDFunc != nil {
+		return mockQueries.GetBookBorrowByIDFunc(ctx, id)
+	}
+
+	return mockQueries.BaseMock.GetBookBorrowByID(ctx, id)
+}
+
+func (mockQueries *MockQueries) GetBookBorrowWithOwner(ctx context.Context, id uuid.UUID) (database.GetBookBorrowWithOwnerRow, error) {
+	if mockQueries.GetBookBorrowWithOwnerFunc != nil {
+		return mockQueries.GetBookBorrowWithOwnerFunc(ctx, id)
+	}
+
+	return mockQueries.BaseMock.GetBookBorrowWithOwner(ctx, id)
+}
+
+func (mockQueries *MockQueries) RenewBookBorrow(ctx context.Context, arg database.RenewBookBorrowParams) (database.BookBorrowRenewal, error) {
+	if mockQueries.RenewBookBorrowFunc != nil {
+		return mockQueries.RenewBookBorrowFunc(ctx, arg)
+	}
+
+	return mockQueries.BaseMock.RenewBookBorrow(ctx, arg)
+}
+
+func (mockQueries *MockQueries) CountBookBorrowRenewals(ctx context.Context, bookBorrowID uuid.UUID) (int64, error) {
+	if mockQueries.CountBookBorrowRenewalsFunc != nil {
+		return mockQueries.CountBookBorrowRenewalsFunc(ctx, bookBorrowID)
+	}
+
+	return mockQueries.BaseMock.CountBookBorrowRenewals(ctx, bookBorrowID)
+}
+
+func (mockQueries *MockQueries) CountActiveBookReservations(ctx context.Context, bookID uuid.UUID) (int64, error) {
+	if mockQueries.CountActiveBookReservationsFunc != nil {
+		return mockQueries.CountActiveBookReservationsFunc(ctx, bookID)
+	}
+
+	return mockQueries.BaseMock.CountActiveBookReservations(ctx, bookID)
+}
+
+func (mockQueries *MockQueries) ConfirmBookReturn(ctx context.Context, arg database.ConfirmBookReturnParams) (database.BookBorrow, error) {
+	if mockQueries.ConfirmBookReturnFunc != nil {
+		return mockQueries.ConfirmBookReturnFunc(ctx, arg)
+	}
+
+	return mockQueries.BaseMock.ConfirmBookReturn(ctx, arg)
+}
+
+func (mockQueries *MockQueries) DisputeBookReturn(ctx context.Context, arg database.DisputeBookReturnParams) (database.BookBorrow, error) {
+	if mockQueries.DisputeBookReturnFunc != nil {
+		return mockQueries.DisputeBookReturnFunc(ctx, arg)
+	}
+
+	return mockQueries.BaseMock.DisputeBookReturn(ctx, arg)
+}
+
+func (mockQueries *MockQueries) GetBookBorrowsDueForReminder(ctx context.Context, arg database.GetBookBorrowsDueForReminderParams) ([]database.GetBookBorrowsDueForReminderRow, error) {
+	if mockQueries.GetBookBorrowsDueForReminderFunc != nil {
+		return mockQueries.GetBookBorrowsDueForReminderFunc(ctx, arg)
+	}
+
+	return mockQueries.BaseMock.GetBookBorrowsDueForReminder(ctx, arg)
+}
+
+func (mockQueries *MockQueries) CreateBookBorrowReminder(ctx context.Context, arg database.CreateBookBorrowReminderParams) (database.BookBorrowReminder, error) {
+	if mockQueries.CreateBookBorrowReminderFunc != nil {
+		return mockQueries.CreateBookBorrowReminderFunc(ctx, arg)
+	}
+
+	return mockQueries.BaseMock.CreateBookBorrowReminder(ctx, arg)
+}
+
+func (mockQueries *MockQueries) DeleteBookBorrowReminder(ctx context.Context, id uuid.UUID) error {
+	if mockQueries.DeleteBookBorrowReminderFunc != nil {
+		return mockQueries.DeleteBookBorrowReminderFunc(ctx, id)
+	}
+
+	return mockQueries.BaseMock.DeleteBookBorrowReminder(ctx, id)
+}
+
+func (mockQueries *MockQueries) GetBorrowedBooks(ctx context.Context, arg database.GetBorrowedBooksParams) ([]database.GetBorrowedBooksRow, error) {
+	if mockQueries.GetBorrowedBooksFunc != nil {
+		return mockQueries.GetBorrowedBooksFunc(ctx, arg)
+	}
+
+	return mockQueries.BaseMock.GetBorrowedBooks(ctx, arg)
+}
+
+func (mockQueries *MockQueries) GetLentBooks(ctx context.Context, arg database.GetLentBooksParams) ([]database.GetLentBooksRow, error) {
+	if mockQueries.GetLentBooksFunc != nil {
+		return mockQueries.GetLentBooksFunc(ctx, arg)
+	}
+
+	return mockQueries.BaseMock.GetLentBooks(ctx, arg)
+}
+
+type failingNotifier struct{}
+
+func (failingNotifier) Send(ctx context.Context, notification common.Notification) error {
+	return errors.New("provider unavailable")
+}
+
+func newTestReminderRow() database.GetBookBorrowsDueForReminderRow {
+	return database.GetBookBorrowsDueForReminderRow{
+		BookBorrow:        newTestBookBorrow(uuid.New(), newTestUserID()),
+		BookTitle:         "Test Book",
+		OwnerID:           newTestUserID(),
+		OwnerFirstName:    "Jane",
+		OwnerLastName:     "Doe",
+		OwnerEmail:        "jane@example.com",
+		BorrowerFirstName: "John",
+		BorrowerLastName:  "Doe",
+		BorrowerEmail:     "john@example.com",
+	}
+}
+
 func newTestUserID() uuid.UUID {
 	return uuid.New()
 }
@@ -69,6 +308,10 @@ func newTestBook(userId uuid.UUID) database.Book {
 		UserID:    userId,
 		CreatedAt: time.Now(),
 		UpdatedAt: time.Now(),
+		LoanPeriodDays: 14,
+		Visibility: "public",
+		Lendable: true,
+		BorrowerPolicy: "anyone",
 	}
 }
 
@@ -79,9 +322,25 @@ func newTestBookBorrow(bookID, borrowerID uuid.UUID) database.BookBorrow {
 		BorrowerID: borrowerID,
 		IssuedAt:   time.Now(),
 		ReturnedAt: sql.NullTime{Valid: false},
+		DueAt:      time.Now().AddDate(0, 0, 14),
+	}
+}
+
+func newTestBookBorrowRequest(bookID, requesterID uuid.UUID, status string) database.BookBorrowRequest {
+	return database.BookBorrowRequest{
+		ID:          uuid.New(),
+		Status:      status,
+		CreatedAt:   time.Now(),
+		UpdatedAt:   time.Now(),
+		BookID:      bookID,
+		RequesterID: requesterID,
 	}
 }
 
+func approvedBookBorrowRequest(ctx context.Context, arg database.GetOpenBookBorrowRequestParams) (database.BookBorrowRequest, error) {
+	return newTestBookBorrowRequest(arg.BookID, arg.RequesterID, BookBorrowRequestStatusApproved), nil
+}
+
 func TestIssueBook(tTesting *testing.T) {
 	bookUserId := newTestUserID()
 	borrowerID := newTestUserID()
@@ -94,18 +353,54 @@ func TestIssueBook(tTesting *testing.T) {
 	tTesting.Run("Success", func(t *testing.T) {
 		mockQueries := &MockQueries{
 			BaseMock: base,
+			GetOpenBookBorrowRequestFunc: approvedBookBorrowRequest,
 			GetBookFunc: func(ctx context.Context, id uuid.UUID) (database.Book, error) {
 				return testBook, nil
 			},
-			GetBookBorrowFunc: func(ctx context.Context, bookID uuid.UUID) (database.BookBorrow, error) {
-				return database.BookBorrow{}, sql.ErrNoRows
-			},
 			IssueBookFunc: func(ctx context.Context, arg database.IssueBookParams) (database.BookBorrow, error) {
 				if arg.BookID != testBook.ID || arg.BorrowerID != borrowerID {
 					t.Fatalf("IssueBook called with wrong IDs")
 				}
 				return testBorrow, nil
 			},
+			MarkBookBorrowRequestIssuedFunc: func(ctx context.Context, arg database.MarkBookBorrowRequestIssuedParams) (int64, error) {
+				if arg.BookBorrowID != testBorrow.ID {
+					t.Errorf("Expected borrow request to be linked to borrow %s, got %s", testBorrow.ID, arg.BookBorrowID)
+				}
+				return 1, nil
+			},
+		}
+
+		apiConfig := BookBorrowAPIConfig{APIConfig: common.APIConfig{DB: mockQueries}}
+		request := httptest.NewRequest(http.MethodPost, fmt.Sprintf("/api/v1/book_borrows/issue/%s", testBook.ID), nil)
+
+		vars := map[string]string{"bookId": testBook.ID.String()}
+		request = mux.SetURLVars(request, vars)
+		recorder := httptest.NewRecorder()
+
+		apiConfig.IssueBook(recorder, request, borrowerID)
+
+		if recorder.Code != http.StatusCreated {
+			t.Errorf("Expected status %d, got %d. Body: %s", http.StatusCreated, recorder.Code, recorder.Body.String())
+		}
+	})
+
+	// 1a. Success: due date follows the book's loan period.
+	tTesting.Run("DueDateFromLoanPeriod", func(t *testing.T) {
+		mockQueries := &MockQueries{
+			BaseMock: base,
+			GetOpenBookBorrowRequestFunc: approvedBookBorrowRequest,
+			GetBookFunc: func(ctx context.Context, id uuid.UUID) (database.Book, error) {
+				return testBook, nil
+			},
+			IssueBookFunc: func(ctx context.Context, arg database.IssueBookParams) (database.BookBorrow, error) {
+				expectedDueAt := time.Now().UTC().AddDate(0, 0, int(testBook.LoanPeriodDays))
+
+				if arg.DueAt.Sub(expectedDueAt).Abs() > time.Minute {
+					t.Errorf("Expected due date around %s, got %s", expectedDueAt, arg.DueAt)
+				}
+				return testBorrow, nil
+			},
 		}
 
 		apiConfig := BookBorrowAPIConfig{APIConfig: common.APIConfig{DB: mockQueries}}
@@ -122,6 +417,66 @@ func TestIssueBook(tTesting *testing.T) {
 		}
 	})
 
+	// 1b. Success: borrower asks for a shorter loan period.
+	tTesting.Run("LoanPeriodOverride", func(t *testing.T) {
+		mockQueries := &MockQueries{
+			BaseMock: base,
+			GetOpenBookBorrowRequestFunc: approvedBookBorrowRequest,
+			GetBookFunc: func(ctx context.Context, id uuid.UUID) (database.Book, error) {
+				return testBook, nil
+			},
+			IssueBookFunc: func(ctx context.Context, arg database.IssueBookParams) (database.BookBorrow, error) {
+				expectedDueAt := time.Now().UTC().AddDate(0, 0, 7)
+
+				if arg.DueAt.Sub(expectedDueAt).Abs() > time.Minute {
+					t.Errorf("Expected due date around %s, got %s", expectedDueAt, arg.DueAt)
+				}
+				return testBorrow, nil
+			},
+		}
+
+		apiConfig := BookBorrowAPIConfig{APIConfig: common.APIConfig{DB: mockQueries}}
+		request := httptest.NewRequest(http.MethodPost, fmt.Sprintf("/api/v1/book_borrows/issue/%s", testBook.ID), strings.NewReader(`{"loan_period_days": 7}`))
+
+		vars := map[string]string{"bookId": testBook.ID.String()}
+		request = mux.SetURLVars(request, vars)
+		recorder := httptest.NewRecorder()
+
+		apiConfig.IssueBook(recorder, request, borrowerID)
+
+		if recorder.Code != http.StatusCreated {
+			t.Errorf("Expected status %d, got %d. Body: %s", http.StatusCreated, recorder.Code, recorder.Body.String())
+		}
+	})
+
+	// 1c. Failure: override is longer than the book's loan period.
+	tTesting.Run("LoanPeriodOverrideTooLong", func(t *testing.T) {
+		mockQueries := &MockQueries{
+			BaseMock: base,
+			GetOpenBookBorrowRequestFunc: approvedBookBorrowRequest,
+			GetBookFunc: func(ctx context.Context, id uuid.UUID) (database.Book, error) {
+				return testBook, nil
+			},
+			IssueBookFunc: func(ctx context.Context, arg database.IssueBookParams) (database.BookBorrow, error) {
+				t.Fatal("IssueBook should not be called when the loan period override is invalid")
+				return database.BookBorrow{}, nil
+			},
+		}
+
+		apiConfig := BookBorrowAPIConfig{APIConfig: common.APIConfig{DB: mockQueries}}
+		request := httptest.NewRequest(http.MethodPost, fmt.Sprintf("/api/v1/book_borrows/issue/%s", testBook.ID), strings.NewReader(`{"loan_period_days": 30}`))
+
+		vars := map[string]string{"bookId": testBook.ID.String()}
+		request = mux.SetURLVars(request, vars)
+		recorder := httptest.NewRecorder()
+
+		apiConfig.IssueBook(recorder, request, borrowerID)
+
+		if recorder.Code != http.StatusBadRequest {
+			t.Errorf("Expected status %d, got %d. Body: %s", http.StatusBadRequest, recorder.Code, recorder.Body.String())
+		}
+	})
+
 	// 2. Failure: invalid book ID format
 	tTesting.Run("InvalidBookIDFormat", func(t *testing.T) {
 		mockQueries := &MockQueries{BaseMock: base}
@@ -213,149 +568,486 @@ func TestIssueBook(tTesting *testing.T) {
 		}
 	})
 
-	// 6. Failure: book already issued
-	tTesting.Run("BookAlreadyIssued", func(t *testing.T) {
+	// 5a. Failure: the book's lending policy leaves the borrower out, even with an approved request
+	tTesting.Run("LendingPolicy", func(t *testing.T) {
+		notLendable := newTestBook(bookUserId)
+		notLendable.Lendable = false
+
+		subscribersOnly := newTestBook(bookUserId)
+		subscribersOnly.BorrowerPolicy = "subscribers"
+
+		privateBook := newTestBook(bookUserId)
+		privateBook.Visibility = "private"
+
+		for book, expectedStatus := range map[*database.Book]int{&notLendable: http.StatusForbidden, &subscribersOnly: http.StatusForbidden, &privateBook: http.StatusNotFound} {
+			mockQueries := &MockQueries{
+				BaseMock: base,
+				GetOpenBookBorrowRequestFunc: approvedBookBorrowRequest,
+				GetBookFunc: func(ctx context.Context, id uuid.UUID) (database.Book, error) {
+					return *book, nil
+				},
+				IssueBookFunc: func(ctx context.Context, arg database.IssueBookParams) (database.BookBorrow, error) {
+					t.Fatal("IssueBook should not be called when the lending policy leaves the borrower out")
+					return database.BookBorrow{}, nil
+				},
+			}
+
+			apiConfig := BookBorrowAPIConfig{APIConfig: common.APIConfig{DB: mockQueries}}
+			request := httptest.NewRequest(http.MethodPost, fmt.Sprintf("/api/v1/book_borrows/issue/%s", book.ID), nil)
+			request = mux.SetURLVars(request, map[string]string{"bookId": book.ID.String()})
+			recorder := httptest.NewRecorder()
+
+			apiConfig.IssueBook(recorder, request, borrowerID)
+
+			if recorder.Code != expectedStatus {
+				t.Errorf("Expected status %d, got %d. Body: %s", expectedStatus, recorder.Code, recorder.Body.String())
+			}
+		}
+	})
+
+	// 5b. Success: subscriber-only books are issued to the owner's subscribers
+	tTesting.Run("SubscriberBorrower", func(t *testing.T) {
+		subscribersOnly := newTestBook(bookUserId)
+		subscribersOnly.BorrowerPolicy = "subscribers"
+
 		mockQueries := &MockQueries{
 			BaseMock: base,
+			GetOpenBookBorrowRequestFunc: approvedBookBorrowRequest,
 			GetBookFunc: func(ctx context.Context, id uuid.UUID) (database.Book, error) {
-				return testBook, nil
+				return subscribersOnly, nil
 			},
-			GetBookBorrowFunc: func(ctx context.Context, bookID uuid.UUID) (database.BookBorrow, error) {
-				return newTestBookBorrow(bookID, newTestUserID()), nil
+			GetMemberRelationshipFunc: func(ctx context.Context, arg database.GetMemberRelationshipParams) (database.GetMemberRelationshipRow, error) {
+				return database.GetMemberRelationshipRow{IsSubscriber: arg.OwnerID == bookUserId && arg.MemberID == borrowerID}, nil
 			},
 			IssueBookFunc: func(ctx context.Context, arg database.IssueBookParams) (database.BookBorrow, error) {
-				t.Fatal("IssueBook should not be called when book is already issued")
-				return database.BookBorrow{}, nil
+				return testBorrow, nil
 			},
 		}
 
 		apiConfig := BookBorrowAPIConfig{APIConfig: common.APIConfig{DB: mockQueries}}
-		request := httptest.NewRequest(http.MethodPost, fmt.Sprintf("/api/v1/book_borrows/issue/%s", testBook.ID), nil)
-
-		vars := map[string]string{"bookId": testBook.ID.String()}
-		request = mux.SetURLVars(request, vars)
+		request := httptest.NewRequest(http.MethodPost, fmt.Sprintf("/api/v1/book_borrows/issue/%s", subscribersOnly.ID), nil)
+		request = mux.SetURLVars(request, map[string]string{"bookId": subscribersOnly.ID.String()})
 		recorder := httptest.NewRecorder()
 
 		apiConfig.IssueBook(recorder, request, borrowerID)
 
-		if recorder.Code != http.StatusConflict {
-			t.Errorf("Expected status %d, got %d. Body: %s", http.StatusConflict, recorder.Code, recorder.Body.String())
+		if recorder.Code != http.StatusCreated {
+			t.Errorf("Expected status %d, got %d. Body: %s", http.StatusCreated, recorder.Code, recorder.Body.String())
 		}
 	})
 
-	// 7. Failure: internal error on IssueBook creation
-	tTesting.Run("IssueBookDBError", func(t *testing.T) {
+	// 5c. Failure: the borrower's reputation is below the book's minimum, checked before anything is held
+	tTesting.Run("BelowMinBorrowerReputation", func(t *testing.T) {
+		demandingBook := newTestBook(bookUserId)
+		demandingBook.MinBorrowerReputation = 80
+
 		mockQueries := &MockQueries{
 			BaseMock: base,
+			GetOpenBookBorrowRequestFunc: approvedBookBorrowRequest,
 			GetBookFunc: func(ctx context.Context, id uuid.UUID) (database.Book, error) {
-				return testBook, nil
+				return demandingBook, nil
 			},
-			GetBookBorrowFunc: func(ctx context.Context, bookID uuid.UUID) (database.BookBorrow, error) {
-				return database.BookBorrow{}, sql.ErrNoRows
+			GetBorrowerReputationStatsFunc: func(ctx context.Context, borrowerId uuid.UUID) (database.BorrowerReputationStat, error) {
+				if borrowerId != borrowerID {
+					t.Errorf("Expected the reputation of borrower %s, got %s", borrowerID, borrowerId)
+				}
+				return database.BorrowerReputationStat{BorrowerID: borrowerId, ScoredLoans: 2, OnTimeReturns: 1, LostBooks: 1}, nil
 			},
-			IssueBookFunc: func(ctx context.Context, arg database.IssueBookParams) (database.BookBorrow, error) {
-				return database.BookBorrow{}, errors.New("simulated DB error on issue book")
+			GetOfferedBookReservationsFunc: func(ctx context.Context, bookID uuid.UUID) ([]database.BookReservation, error) {
+				t.Fatal("No copy should be held for a borrower below the minimum reputation")
+				return nil, nil
 			},
 		}
 
 		apiConfig := BookBorrowAPIConfig{APIConfig: common.APIConfig{DB: mockQueries}}
-		request := httptest.NewRequest(http.MethodPost, fmt.Sprintf("/api/v1/book_borrows/issue/%s", testBook.ID), nil)
-
-		vars := map[string]string{"bookId": testBook.ID.String()}
-		request = mux.SetURLVars(request, vars)
+		request := httptest.NewRequest(http.MethodPost, fmt.Sprintf("/api/v1/book_borrows/issue/%s", demandingBook.ID), nil)
+		request = mux.SetURLVars(request, map[string]string{"bookId": demandingBook.ID.String()})
 		recorder := httptest.NewRecorder()
 
 		apiConfig.IssueBook(recorder, request, borrowerID)
 
-		if recorder.Code != http.StatusInternalServerError {
-			t.Errorf("Expected status %d, got %d. Body: %s", http.StatusInternalServerError, recorder.Code, recorder.Body.String())
+		if recorder.Code != http.StatusForbidden {
+			t.Errorf("Expected status %d, got %d. Body: %s", http.StatusForbidden, recorder.Code, recorder.Body.String())
 		}
 	})
-}
-
-func TestReturnBook(tTesting *testing.T) {
-	borrowerID := newTestUserID()
-	bookID := uuid.New()
-	testBorrowID := uuid.New()
-	returnedBorrow := newTestBookBorrow(bookID, borrowerID)
-	returnedBorrow.ReturnedAt.Valid = true
-	returnedBorrow.ReturnedAt.Time = time.Now()
 
-	base := common.NewBaseMock()
+	// 5d. Success: borrowers at or above the minimum are issued the book
+	tTesting.Run("MeetsMinBorrowerReputation", func(t *testing.T) {
+		demandingBook := newTestBook(bookUserId)
+		demandingBook.MinBorrowerReputation = 80
 
-	// 1. Success: book is successfully returned.
-	tTesting.Run("Success", func(t *testing.T) {
 		mockQueries := &MockQueries{
 			BaseMock: base,
-			ReturnBookFunc: func(ctx context.Context, arg database.ReturnBookParams) (database.BookBorrow, error) {
-				if arg.ID != testBorrowID || arg.BorrowerID != borrowerID {
-					t.Fatalf("ReturnBook called with wrong IDs")
-				}
-				return returnedBorrow, nil
+			GetOpenBookBorrowRequestFunc: approvedBookBorrowRequest,
+			GetBookFunc: func(ctx context.Context, id uuid.UUID) (database.Book, error) {
+				return demandingBook, nil
+			},
+			GetBorrowerReputationStatsFunc: func(ctx context.Context, borrowerId uuid.UUID) (database.BorrowerReputationStat, error) {
+				return database.BorrowerReputationStat{BorrowerID: borrowerId, ScoredLoans: 8, OnTimeReturns: 8}, nil
+			},
+			IssueBookFunc: func(ctx context.Context, arg database.IssueBookParams) (database.BookBorrow, error) {
+				return testBorrow, nil
 			},
 		}
 
 		apiConfig := BookBorrowAPIConfig{APIConfig: common.APIConfig{DB: mockQueries}}
-		request := httptest.NewRequest(http.MethodPatch, fmt.Sprintf("/api/v1/book_borrows/return/%s", testBorrowID), nil)
-
-		vars := map[string]string{"bookBorrowId": testBorrowID.String()}
-		request = mux.SetURLVars(request, vars)
+		request := httptest.NewRequest(http.MethodPost, fmt.Sprintf("/api/v1/book_borrows/issue/%s", demandingBook.ID), nil)
+		request = mux.SetURLVars(request, map[string]string{"bookId": demandingBook.ID.String()})
 		recorder := httptest.NewRecorder()
 
-		apiConfig.ReturnBook(recorder, request, borrowerID)
+		apiConfig.IssueBook(recorder, request, borrowerID)
 
-		if recorder.Code != http.StatusOK {
-			t.Errorf("Expected status %d, got %d. Body: %s", http.StatusOK, recorder.Code, recorder.Body.String())
+		if recorder.Code != http.StatusCreated {
+			t.Errorf("Expected status %d, got %d. Body: %s", http.StatusCreated, recorder.Code, recorder.Body.String())
 		}
 	})
 
-	// 2. Failure: invalid borrow ID format
-	tTesting.Run("InvalidBorrowIDFormat", func(t *testing.T) {
-		mockQueries := &MockQueries{BaseMock: base}
+	// 6. Failure: every copy of the book is already issued
+	tTesting.Run("BookAlreadyIssued", func(t *testing.T) {
+		mockQueries := &MockQueries{
+			BaseMock: base,
+			GetOpenBookBorrowRequestFunc: approvedBookBorrowRequest,
+			GetBookFunc: func(ctx context.Context, id uuid.UUID) (database.Book, error) {
+				return testBook, nil
+			},
+			CountAvailableBookCopiesFunc: func(ctx context.Context, bookID uuid.UUID) (int64, error) {
+				return 0, nil
+			},
+			IssueBookFunc: func(ctx context.Context, arg database.IssueBookParams) (database.BookBorrow, error) {
+				t.Fatal("IssueBook should not be called when book is already issued")
+				return database.BookBorrow{}, nil
+			},
+		}
+
 		apiConfig := BookBorrowAPIConfig{APIConfig: common.APIConfig{DB: mockQueries}}
+		request := httptest.NewRequest(http.MethodPost, fmt.Sprintf("/api/v1/book_borrows/issue/%s", testBook.ID), nil)
 
-		request := httptest.NewRequest(http.MethodPatch, "/api/v1/book_borrows/return/not-a-uuid", nil)
-		vars := map[string]string{"bookBorrowId": "not-a-uuid"}
+		vars := map[string]string{"bookId": testBook.ID.String()}
 		request = mux.SetURLVars(request, vars)
 		recorder := httptest.NewRecorder()
 
-		apiConfig.ReturnBook(recorder, request, borrowerID)
+		apiConfig.IssueBook(recorder, request, borrowerID)
 
-		if recorder.Code != http.StatusBadRequest {
-			t.Errorf("Expected status %d, got %d. Body: %s", http.StatusBadRequest, recorder.Code, recorder.Body.String())
+		if recorder.Code != http.StatusConflict {
+			t.Errorf("Expected status %d, got %d. Body: %s", http.StatusConflict, recorder.Code, recorder.Body.String())
 		}
 	})
 
-	// 3. Failure: not found, unauthorized, or already returned (sql.ErrNoRows)
-	tTesting.Run("NotFoundOrUnauthorized", func(t *testing.T) {
+	// 6a. Failure: book is held for someone else in the waitlist
+	tTesting.Run("HeldForAnotherBorrower", func(t *testing.T) {
 		mockQueries := &MockQueries{
 			BaseMock: base,
-			ReturnBookFunc: func(ctx context.Context, arg database.ReturnBookParams) (database.BookBorrow, error) {
+			GetBookFunc: func(ctx context.Context, id uuid.UUID) (database.Book, error) {
+				return testBook, nil
+			},
+			GetOfferedBookReservationsFunc: func(ctx context.Context, bookID uuid.UUID) ([]database.BookReservation, error) {
+				return []database.BookReservation{{ID: uuid.New(), Status: "offered", BookID: bookID, UserID: newTestUserID()}}, nil
+			},
+			GetOpenBookBorrowRequestFunc: approvedBookBorrowRequest,
+			IssueBookFunc: func(ctx context.Context, arg database.IssueBookParams) (database.BookBorrow, error) {
+				t.Fatal("IssueBook should not be called when the book is held for someone else")
+				return database.BookBorrow{}, nil
+			},
+		}
+
+		apiConfig := BookBorrowAPIConfig{APIConfig: common.APIConfig{DB: mockQueries}}
+		request := httptest.NewRequest(http.MethodPost, fmt.Sprintf("/api/v1/book_borrows/issue/%s", testBook.ID), nil)
+
+		vars := map[string]string{"bookId": testBook.ID.String()}
+		request = mux.SetURLVars(request, vars)
+		recorder := httptest.NewRecorder()
+
+		apiConfig.IssueBook(recorder, request, borrowerID)
+
+		if recorder.Code != http.StatusConflict {
+			t.Errorf("Expected status %d, got %d. Body: %s", http.StatusConflict, recorder.Code, recorder.Body.String())
+		}
+	})
+
+	// 6b. Failure: borrower has no borrow request for the book, nothing is held for them
+	tTesting.Run("NoBorrowRequest", func(t *testing.T) {
+		mockQueries := &MockQueries{
+			BaseMock: base,
+			GetBookFunc: func(ctx context.Context, id uuid.UUID) (database.Book, error) {
+				return testBook, nil
+			},
+			GetOfferedBookReservationsFunc: func(ctx context.Context, bookID uuid.UUID) ([]database.BookReservation, error) {
+				t.Fatal("the book should not be held without an approved borrow request")
+				return nil, nil
+			},
+			IssueBookFunc: func(ctx context.Context, arg database.IssueBookParams) (database.BookBorrow, error) {
+				t.Fatal("IssueBook should not be called without an approved borrow request")
+				return database.BookBorrow{}, nil
+			},
+		}
+
+		apiConfig := BookBorrowAPIConfig{APIConfig: common.APIConfig{DB: mockQueries}}
+		request := httptest.NewRequest(http.MethodPost, fmt.Sprintf("/api/v1/book_borrows/issue/%s", testBook.ID), nil)
+
+		vars := map[string]string{"bookId": testBook.ID.String()}
+		request = mux.SetURLVars(request, vars)
+		recorder := httptest.NewRecorder()
+
+		apiConfig.IssueBook(recorder, request, borrowerID)
+
+		if recorder.Code != http.StatusForbidden {
+			t.Errorf("Expected status %d, got %d. Body: %s", http.StatusForbidden, recorder.Code, recorder.Body.String())
+		}
+	})
+
+	// 6c. Failure: borrow request is still waiting for the owner
+	tTesting.Run("BorrowRequestNotApproved", func(t *testing.T) {
+		mockQueries := &MockQueries{
+			BaseMock: base,
+			GetBookFunc: func(ctx context.Context, id uuid.UUID) (database.Book, error) {
+				return testBook, nil
+			},
+			GetOpenBookBorrowRequestFunc: func(ctx context.Context, arg database.GetOpenBookBorrowRequestParams) (database.BookBorrowRequest, error) {
+				return newTestBookBorrowRequest(arg.BookID, arg.RequesterID, BookBorrowRequestStatusRequested), nil
+			},
+			IssueBookFunc: func(ctx context.Context, arg database.IssueBookParams) (database.BookBorrow, error) {
+				t.Fatal("IssueBook should not be called when the borrow request is not approved")
+				return database.BookBorrow{}, nil
+			},
+		}
+
+		apiConfig := BookBorrowAPIConfig{APIConfig: common.APIConfig{DB: mockQueries}}
+		request := httptest.NewRequest(http.MethodPost, fmt.Sprintf("/api/v1/book_borrows/issue/%s", testBook.ID), nil)
+
+		vars := map[string]string{"bookId": testBook.ID.String()}
+		request = mux.SetURLVars(request, vars)
+		recorder := httptest.NewRecorder()
+
+		apiConfig.IssueBook(recorder, request, borrowerID)
+
+		if recorder.Code != http.StatusForbidden {
+			t.Errorf("Expected status %d, got %d. Body: %s", http.StatusForbidden, recorder.Code, recorder.Body.String())
+		}
+	})
+
+	// 6d. Success: another copy is free while one is held for someone else in the waitlist
+	tTesting.Run("AnotherCopyAvailable", func(t *testing.T) {
+		mockQueries := &MockQueries{
+			BaseMock: base,
+			GetOpenBookBorrowRequestFunc: approvedBookBorrowRequest,
+			GetBookFunc: func(ctx context.Context, id uuid.UUID) (database.Book, error) {
+				return testBook, nil
+			},
+			CountAvailableBookCopiesFunc: func(ctx context.Context, bookID uuid.UUID) (int64, error) {
+				return 2, nil
+			},
+			GetOfferedBookReservationsFunc: func(ctx context.Context, bookID uuid.UUID) ([]database.BookReservation, error) {
+				return []database.BookReservation{{ID: uuid.New(), Status: "offered", BookID: bookID, UserID: newTestUserID()}}, nil
+			},
+			IssueBookFunc: func(ctx context.Context, arg database.IssueBookParams) (database.BookBorrow, error) {
+				return testBorrow, nil
+			},
+			FulfillBookReservationFunc: func(ctx context.Context, id uuid.UUID) error {
+				t.Fatal("FulfillBookReservation should not be called for someone else's reservation")
+				return nil
+			},
+		}
+
+		apiConfig := BookBorrowAPIConfig{APIConfig: common.APIConfig{DB: mockQueries}}
+		request := httptest.NewRequest(http.MethodPost, fmt.Sprintf("/api/v1/book_borrows/issue/%s", testBook.ID), nil)
+
+		vars := map[string]string{"bookId": testBook.ID.String()}
+		request = mux.SetURLVars(request, vars)
+		recorder := httptest.NewRecorder()
+
+		apiConfig.IssueBook(recorder, request, borrowerID)
+
+		if recorder.Code != http.StatusCreated {
+			t.Errorf("Expected status %d, got %d. Body: %s", http.StatusCreated, recorder.Code, recorder.Body.String())
+		}
+	})
+
+	// 6e. Success: the copy held for the borrower fulfils their reservation
+	tTesting.Run("HeldForBorrower", func(t *testing.T) {
+		heldBookReservation := database.BookReservation{ID: uuid.New(), Status: "offered", BookID: testBook.ID, UserID: borrowerID}
+		fulfilled := false
+
+		mockQueries := &MockQueries{
+			BaseMock: base,
+			GetOpenBookBorrowRequestFunc: approvedBookBorrowRequest,
+			GetBookFunc: func(ctx context.Context, id uuid.UUID) (database.Book, error) {
+				return testBook, nil
+			},
+			GetOfferedBookReservationsFunc: func(ctx context.Context, bookID uuid.UUID) ([]database.BookReservation, error) {
+				return []database.BookReservation{heldBookReservation}, nil
+			},
+			IssueBookFunc: func(ctx context.Context, arg database.IssueBookParams) (database.BookBorrow, error) {
+				return testBorrow, nil
+			},
+			FulfillBookReservationFunc: func(ctx context.Context, id uuid.UUID) error {
+				fulfilled = id == heldBookReservation.ID
+				return nil
+			},
+		}
+
+		apiConfig := BookBorrowAPIConfig{APIConfig: common.APIConfig{DB: mockQueries}}
+		request := httptest.NewRequest(http.MethodPost, fmt.Sprintf("/api/v1/book_borrows/issue/%s", testBook.ID), nil)
+
+		vars := map[string]string{"bookId": testBook.ID.String()}
+		request = mux.SetURLVars(request, vars)
+		recorder := httptest.NewRecorder()
+
+		apiConfig.IssueBook(recorder, request, borrowerID)
+
+		if recorder.Code != http.StatusCreated {
+			t.Errorf("Expected status %d, got %d. Body: %s", http.StatusCreated, recorder.Code, recorder.Body.String())
+		}
+
+		if !fulfilled {
+			t.Error("Expected the borrower's reservation to be fulfilled")
+		}
+	})
+
+	// 6f. Failure: the last free copy was issued to someone else in the meantime
+	tTesting.Run("NoCopyLeft", func(t *testing.T) {
+		mockQueries := &MockQueries{
+			BaseMock: base,
+			GetOpenBookBorrowRequestFunc: approvedBookBorrowRequest,
+			GetBookFunc: func(ctx context.Context, id uuid.UUID) (database.Book, error) {
+				return testBook, nil
+			},
+			IssueBookFunc: func(ctx context.Context, arg database.IssueBookParams) (database.BookBorrow, error) {
 				return database.BookBorrow{}, sql.ErrNoRows
 			},
 		}
 
 		apiConfig := BookBorrowAPIConfig{APIConfig: common.APIConfig{DB: mockQueries}}
-		request := httptest.NewRequest(http.MethodPatch, fmt.Sprintf("/api/v1/book_borrows/return/%s", testBorrowID), nil)
+		request := httptest.NewRequest(http.MethodPost, fmt.Sprintf("/api/v1/book_borrows/issue/%s", testBook.ID), nil)
 
-		vars := map[string]string{"bookBorrowId": testBorrowID.String()}
+		vars := map[string]string{"bookId": testBook.ID.String()}
 		request = mux.SetURLVars(request, vars)
 		recorder := httptest.NewRecorder()
 
-		// Note: The handler returns 400 Bad Request for this specific DB error
-		apiConfig.ReturnBook(recorder, request, borrowerID)
+		apiConfig.IssueBook(recorder, request, borrowerID)
 
-		if recorder.Code != http.StatusBadRequest {
-			t.Errorf("Expected status %d, got %d. Body: %s", http.StatusBadRequest, recorder.Code, recorder.Body.String())
+		if recorder.Code != http.StatusConflict {
+			t.Errorf("Expected status %d, got %d. Body: %s", http.StatusConflict, recorder.Code, recorder.Body.String())
 		}
 	})
 
-	// 4. Failure: Internal Error on ReturnBook
-	tTesting.Run("ReturnBookDBError", func(t *testing.T) {
+	// 7. Failure: internal error on IssueBook creation
+	tTesting.Run("IssueBookDBError", func(t *testing.T) {
+		mockQueries := &MockQueries{
+			BaseMock: base,
+			GetOpenBookBorrowRequestFunc: approvedBookBorrowRequest,
+			GetBookFunc: func(ctx context.Context, id uuid.UUID) (database.Book, error) {
+				return testBook, nil
+			},
+			IssueBookFunc: func(ctx context.Context, arg database.IssueBookParams) (database.BookBorrow, error) {
+				return database.BookBorrow{}, errors.New("simulated DB error on issue book")
+			},
+		}
+
+		apiConfig := BookBorrowAPIConfig{APIConfig: common.APIConfig{DB: mockQueries}}
+		request := httptest.NewRequest(http.MethodPost, fmt.Sprintf("/api/v1/book_borrows/issue/%s", testBook.ID), nil)
+
+		vars := map[string]string{"bookId": testBook.ID.String()}
+		request = mux.SetURLVars(request, vars)
+		recorder := httptest.NewRecorder()
+
+		apiConfig.IssueBook(recorder, request, borrowerID)
+
+		if recorder.Code != http.StatusInternalServerError {
+			t.Errorf("Expected status %d, got %d. Body: %s", http.StatusInternalServerError, recorder.Code, recorder.Body.String())
+		}
+	})
+
+	// 7a. Failure: the approval was already used for a loan, so the issue is rolled back
+	// 7b. Failure: the approval can't be updated, so the issue fails with it
+	markFailures := []struct {
+		name                        string
+		markBookBorrowRequestIssued func(ctx context.Context, arg database.MarkBookBorrowRequestIssuedParams) (int64, error)
+		expectedStatus              int
+	}{
+		{"BorrowRequestAlreadyIssued", func(ctx context.Context, arg database.MarkBookBorrowRequestIssuedParams) (int64, error) {
+			return 0, nil
+		}, http.StatusConflict},
+		{"MarkBorrowRequestDBError", func(ctx context.Context, arg database.MarkBookBorrowRequestIssuedParams) (int64, error) {
+			return 0, errors.New("simulated DB error on mark borrow request")
+		}, http.StatusInternalServerError},
+	}
+
+	for _, markFailure := range markFailures {
+		tTesting.Run(markFailure.name, func(t *testing.T) {
+			mockQueries := &MockQueries{
+				BaseMock: base,
+				GetOpenBookBorrowRequestFunc: approvedBookBorrowRequest,
+				GetBookFunc: func(ctx context.Context, id uuid.UUID) (database.Book, error) {
+					return testBook, nil
+				},
+				IssueBookFunc: func(ctx context.Context, arg database.IssueBookParams) (database.BookBorrow, error) {
+					return testBorrow, nil
+				},
+				MarkBookBorrowRequestIssuedFunc: markFailure.markBookBorrowRequestIssued,
+			}
+
+			apiConfig := BookBorrowAPIConfig{APIConfig: common.APIConfig{DB: mockQueries}}
+			request := httptest.NewRequest(http.MethodPost, fmt.Sprintf("/api/v1/book_borrows/issue/%s", testBook.ID), nil)
+			request = mux.SetURLVars(request, map[string]string{"bookId": testBook.ID.String()})
+			recorder := httptest.NewRecorder()
+
+			apiConfig.IssueBook(recorder, request, borrowerID)
+
+			if recorder.Code != markFailure.expectedStatus {
+				t.Errorf("Expected status %d, got %d. Body: %s", markFailure.expectedStatus, recorder.Code, recorder.Body.String())
+			}
+		})
+	}
+
+	// 7c. Failure: the book can't be locked for the issue
+	tTesting.Run("LockBookDBError", func(t *testing.T) {
+		mockQueries := &MockQueries{
+			BaseMock: base,
+			GetOpenBookBorrowRequestFunc: approvedBookBorrowRequest,
+			GetBookFunc: func(ctx context.Context, id uuid.UUID) (database.Book, error) {
+				return testBook, nil
+			},
+			LockBookForIssueFunc: func(ctx context.Context, id uuid.UUID) error {
+				return errors.New("simulated DB error on lock book")
+			},
+			IssueBookFunc: func(ctx context.Context, arg database.IssueBookParams) (database.BookBorrow, error) {
+				t.Fatal("IssueBook should not be called when the book can't be locked")
+				return database.BookBorrow{}, nil
+			},
+		}
+
+		apiConfig := BookBorrowAPIConfig{APIConfig: common.APIConfig{DB: mockQueries}}
+		request := httptest.NewRequest(http.MethodPost, fmt.Sprintf("/api/v1/book_borrows/issue/%s", testBook.ID), nil)
+		request = mux.SetURLVars(request, map[string]string{"bookId": testBook.ID.String()})
+		recorder := httptest.NewRecorder()
+
+		apiConfig.IssueBook(recorder, request, borrowerID)
+
+		if recorder.Code != http.StatusInternalServerError {
+			t.Errorf("Expected status %d, got %d. Body: %s", http.StatusInternalServerError, recorder.Code, recorder.Body.String())
+		}
+	})
+}
+
+func TestReturnBook(tTesting *testing.T) {
+	borrowerID := newTestUserID()
+	bookID := uuid.New()
+	testBorrowID := uuid.New()
+	returnedBorrow := newTestBookBorrow(bookID, borrowerID)
+	returnedBorrow.ReturnedAt.Valid = true
+	returnedBorrow.ReturnedAt.Time = time.Now()
+
+	base := common.NewBaseMock()
+
+	// 1. Success: book is successfully returned.
+	tTesting.Run("Success", func(t *testing.T) {
 		mockQueries := &MockQueries{
 			BaseMock: base,
 			ReturnBookFunc: func(ctx context.Context, arg database.ReturnBookParams) (database.BookBorrow, error) {
-				return database.BookBorrow{}, errors.New("simulated DB error on return book") // DB error
+				if arg.ID != testBorrowID || arg.BorrowerID != borrowerID {
+					t.Fatalf("ReturnBook called with wrong IDs")
+				}
+				return returnedBorrow, nil
 			},
 		}
 
@@ -368,8 +1060,1079 @@ func TestReturnBook(tTesting *testing.T) {
 
 		apiConfig.ReturnBook(recorder, request, borrowerID)
 
-		if recorder.Code != http.StatusInternalServerError {
-			t.Errorf("Expected status %d, got %d. Body: %s", http.StatusInternalServerError, recorder.Code, recorder.Body.String())
+		if recorder.Code != http.StatusOK {
+			t.Errorf("Expected status %d, got %d. Body: %s", http.StatusOK, recorder.Code, recorder.Body.String())
+		}
+	})
+
+	// 2. Failure: invalid borrow ID format
+	tTesting.Run("InvalidBorrowIDFormat", func(t *testing.T) {
+		mockQueries := &MockQueries{BaseMock: base}
+		apiConfig := BookBorrowAPIConfig{APIConfig: common.APIConfig{DB: mockQueries}}
+
+		request := httptest.NewRequest(http.MethodPatch, "/api/v1/book_borrows/return/not-a-uuid", nil)
+		vars := map[string]string{"bookBorrowId": "not-a-uuid"}
+		request = mux.SetURLVars(request, vars)
+		recorder := httptest.NewRecorder()
+
+		apiConfig.ReturnBook(recorder, request, borrowerID)
+
+		if recorder.Code != http.StatusBadRequest {
+			t.Errorf("Expected status %d, got %d. Body: %s", http.StatusBadRequest, recorder.Code, recorder.Body.String())
+		}
+	})
+
+	// 3. Failure: not found, unauthorized, or already returned (sql.ErrNoRows)
+	tTesting.Run("NotFoundOrUnauthorized", func(t *testing.T) {
+		mockQueries := &MockQueries{
+			BaseMock: base,
+			ReturnBookFunc: func(ctx context.Context, arg database.ReturnBookParams) (database.BookBorrow, error) {
+				return database.BookBorrow{}, sql.ErrNoRows
+			},
+		}
+
+		apiConfig := BookBorrowAPIConfig{APIConfig: common.APIConfig{DB: mockQueries}}
+		request := httptest.NewRequest(http.MethodPatch, fmt.Sprintf("/api/v1/book_borrows/return/%s", testBorrowID), nil)
+
+		vars := map[string]string{"bookBorrowId": testBorrowID.String()}
+		request = mux.SetURLVars(request, vars)
+		recorder := httptest.NewRecorder()
+
+		// Note: The handler returns 400 Bad Request for this specific DB error
+		apiConfig.ReturnBook(recorder, request, borrowerID)
+
+		if recorder.Code != http.StatusBadRequest {
+			t.Errorf("Expected status %d, got %d. Body: %s", http.StatusBadRequest, recorder.Code, recorder.Body.String())
+		}
+	})
+
+	// 4. Failure: Internal Error on ReturnBook
+	tTesting.Run("ReturnBookDBError", func(t *testing.T) {
+		mockQueries := &MockQueries{
+			BaseMock: base,
+			ReturnBookFunc: func(ctx context.Context, arg database.ReturnBookParams) (database.BookBorrow, error) {
+				return database.BookBorrow{}, errors.New("simulated DB error on return book") // DB error
+			},
+		}
+
+		apiConfig := BookBorrowAPIConfig{APIConfig: common.APIConfig{DB: mockQueries}}
+		request := httptest.NewRequest(http.MethodPatch, fmt.Sprintf("/api/v1/book_borrows/return/%s", testBorrowID), nil)
+
+		vars := map[string]string{"bookBorrowId": testBorrowID.String()}
+		request = mux.SetURLVars(request, vars)
+		recorder := httptest.NewRecorder()
+
+		apiConfig.ReturnBook(recorder, request, borrowerID)
+
+		if recorder.Code != http.StatusInternalServerError {
+			t.Errorf("Expected status %d, got %d. Body: %s", http.StatusInternalServerError, recorder.Code, recorder.Body.String())
+		}
+	})
+}
+
+func TestIsBookBorrowOverdue(tTesting *testing.T) {
+	now := time.Now().UTC()
+
+	tTesting.Run("ActivePastDueDate", func(t *testing.T) {
+		bookBorrow := database.BookBorrow{DueAt: now.Add(-time.Hour)}
+
+		if !IsBookBorrowOverdue(bookBorrow, now) {
+			t.Error("Expected active borrow past its due date to be overdue")
+		}
+	})
+
+	tTesting.Run("ActiveBeforeDueDate", func(t *testing.T) {
+		bookBorrow := database.BookBorrow{DueAt: now.Add(time.Hour)}
+
+		if IsBookBorrowOverdue(bookBorrow, now) {
+			t.Error("Expected active borrow before its due date not to be overdue")
+		}
+	})
+
+	tTesting.Run("Returned", func(t *testing.T) {
+		bookBorrow := database.BookBorrow{
+			DueAt:      now.Add(-time.Hour),
+			ReturnedAt: sql.NullTime{Time: now, Valid: true},
+		}
+
+		if IsBookBorrowOverdue(bookBorrow, now) {
+			t.Error("Expected returned borrow not to be overdue")
+		}
+	})
+
+	tTesting.Run("ReturnDisputed", func(t *testing.T) {
+		bookBorrow := database.BookBorrow{
+			DueAt:        now.Add(-time.Hour),
+			ReturnedAt:   sql.NullTime{Time: now, Valid: true},
+			ReturnStatus: sql.NullString{String: BookBorrowReturnStatusDisputed, Valid: true},
+		}
+
+		if !IsBookBorrowOverdue(bookBorrow, now) {
+			t.Error("Expected borrow with a disputed return past its due date to be overdue")
+		}
+	})
+}
+
+func TestCreateBookBorrowRequest(tTesting *testing.T) {
+	bookUserId := newTestUserID()
+	borrowerID := newTestUserID()
+	testBook := newTestBook(bookUserId)
+
+	base := common.NewBaseMock()
+
+	// 1. Success: borrow request is created.
+	tTesting.Run("Success", func(t *testing.T) {
+		mockQueries := &MockQueries{
+			BaseMock: base,
+			GetBookFunc: func(ctx context.Context, id uuid.UUID) (database.Book, error) {
+				return testBook, nil
+			},
+			CreateBookBorrowRequestFunc: func(ctx context.Context, arg database.CreateBookBorrowRequestParams) (database.BookBorrowRequest, error) {
+				if arg.BookID != testBook.ID || arg.RequesterID != borrowerID {
+					t.Fatalf("CreateBookBorrowRequest called with wrong IDs")
+				}
+				return newTestBookBorrowRequest(arg.BookID, arg.RequesterID, BookBorrowRequestStatusRequested), nil
+			},
+		}
+
+		apiConfig := BookBorrowAPIConfig{APIConfig: common.APIConfig{DB: mockQueries}}
+		request := httptest.NewRequest(http.MethodPost, fmt.Sprintf("/api/v1/books/requests/%s", testBook.ID), nil)
+
+		vars := map[string]string{"bookId": testBook.ID.String()}
+		request = mux.SetURLVars(request, vars)
+		recorder := httptest.NewRecorder()
+
+		apiConfig.CreateBookBorrowRequest(recorder, request, borrowerID)
+
+		if recorder.Code != http.StatusCreated {
+			t.Errorf("Expected status %d, got %d. Body: %s", http.StatusCreated, recorder.Code, recorder.Body.String())
+		}
+	})
+
+	// 2. Failure: requester is the book owner
+	tTesting.Run("RequesterIsOwner", func(t *testing.T) {
+		mockQueries := &MockQueries{
+			BaseMock: base,
+			GetBookFunc: func(ctx context.Context, id uuid.UUID) (database.Book, error) {
+				return testBook, nil
+			},
+		}
+
+		apiConfig := BookBorrowAPIConfig{APIConfig: common.APIConfig{DB: mockQueries}}
+		request := httptest.NewRequest(http.MethodPost, fmt.Sprintf("/api/v1/books/requests/%s", testBook.ID), nil)
+
+		vars := map[string]string{"bookId": testBook.ID.String()}
+		request = mux.SetURLVars(request, vars)
+		recorder := httptest.NewRecorder()
+
+		apiConfig.CreateBookBorrowRequest(recorder, request, bookUserId)
+
+		if recorder.Code != http.StatusForbidden {
+			t.Errorf("Expected status %d, got %d. Body: %s", http.StatusForbidden, recorder.Code, recorder.Body.String())
+		}
+	})
+
+	// 3. Failure: borrower already has an open request
+	tTesting.Run("AlreadyRequested", func(t *testing.T) {
+		mockQueries := &MockQueries{
+			BaseMock: base,
+			GetBookFunc: func(ctx context.Context, id uuid.UUID) (database.Book, error) {
+				return testBook, nil
+			},
+			GetOpenBookBorrowRequestFunc: approvedBookBorrowRequest,
+		}
+
+		apiConfig := BookBorrowAPIConfig{APIConfig: common.APIConfig{DB: mockQueries}}
+		request := httptest.NewRequest(http.MethodPost, fmt.Sprintf("/api/v1/books/requests/%s", testBook.ID), nil)
+
+		vars := map[string]string{"bookId": testBook.ID.String()}
+		request = mux.SetURLVars(request, vars)
+		recorder := httptest.NewRecorder()
+
+		apiConfig.CreateBookBorrowRequest(recorder, request, borrowerID)
+
+		if recorder.Code != http.StatusConflict {
+			t.Errorf("Expected status %d, got %d. Body: %s", http.StatusConflict, recorder.Code, recorder.Body.String())
+		}
+	})
+
+	// 4. Failure: only approved borrowers can ask for the book
+	tTesting.Run("LendingPolicy", func(t *testing.T) {
+		approvedOnly := newTestBook(bookUserId)
+		approvedOnly.BorrowerPolicy = "approved"
+
+		mockQueries := &MockQueries{
+			BaseMock: base,
+			GetBookFunc: func(ctx context.Context, id uuid.UUID) (database.Book, error) {
+				return approvedOnly, nil
+			},
+			GetMemberRelationshipFunc: func(ctx context.Context, arg database.GetMemberRelationshipParams) (database.GetMemberRelationshipRow, error) {
+				return database.GetMemberRelationshipRow{IsSubscriber: true}, nil
+			},
+			CreateBookBorrowRequestFunc: func(ctx context.Context, arg database.CreateBookBorrowRequestParams) (database.BookBorrowRequest, error) {
+				t.Fatal("CreateBookBorrowRequest should not be called for borrowers the owner has not approved")
+				return database.BookBorrowRequest{}, nil
+			},
+		}
+
+		apiConfig := BookBorrowAPIConfig{APIConfig: common.APIConfig{DB: mockQueries}}
+		request := httptest.NewRequest(http.MethodPost, fmt.Sprintf("/api/v1/books/requests/%s", approvedOnly.ID), nil)
+		request = mux.SetURLVars(request, map[string]string{"bookId": approvedOnly.ID.String()})
+		recorder := httptest.NewRecorder()
+
+		apiConfig.CreateBookBorrowRequest(recorder, request, borrowerID)
+
+		if recorder.Code != http.StatusForbidden {
+			t.Errorf("Expected status %d, got %d. Body: %s", http.StatusForbidden, recorder.Code, recorder.Body.String())
+		}
+	})
+
+	// 4a. Failure: the requester's reputation is below the book's minimum
+	tTesting.Run("BelowMinBorrowerReputation", func(t *testing.T) {
+		demandingBook := newTestBook(bookUserId)
+		demandingBook.MinBorrowerReputation = 80
+
+		mockQueries := &MockQueries{
+			BaseMock: base,
+			GetBookFunc: func(ctx context.Context, id uuid.UUID) (database.Book, error) {
+				return demandingBook, nil
+			},
+			GetBorrowerReputationStatsFunc: func(ctx context.Context, borrowerId uuid.UUID) (database.BorrowerReputationStat, error) {
+				return database.BorrowerReputationStat{BorrowerID: borrowerId, ScoredLoans: 2, OnTimeReturns: 1, LostBooks: 1}, nil
+			},
+			CreateBookBorrowRequestFunc: func(ctx context.Context, arg database.CreateBookBorrowRequestParams) (database.BookBorrowRequest, error) {
+				t.Fatal("CreateBookBorrowRequest should not be called for borrowers below the minimum reputation")
+				return database.BookBorrowRequest{}, nil
+			},
+		}
+
+		apiConfig := BookBorrowAPIConfig{APIConfig: common.APIConfig{DB: mockQueries}}
+		request := httptest.NewRequest(http.MethodPost, fmt.Sprintf("/api/v1/books/requests/%s", demandingBook.ID), nil)
+		request = mux.SetURLVars(request, map[string]string{"bookId": demandingBook.ID.String()})
+		recorder := httptest.NewRecorder()
+
+		apiConfig.CreateBookBorrowRequest(recorder, request, borrowerID)
+
+		if recorder.Code != http.StatusForbidden {
+			t.Errorf("Expected status %d, got %d. Body: %s", http.StatusForbidden, recorder.Code, recorder.Body.String())
+		}
+	})
+}
+
+func TestApproveBookBorrowRequest(tTesting *testing.T) {
+	ownerID := newTestUserID()
+	bookBorrowRequest := newTestBookBorrowRequest(uuid.New(), newTestUserID(), BookBorrowRequestStatusApproved)
+
+	base := common.NewBaseMock()
+
+	// 1. Success: owner approves the request.
+	tTesting.Run("Success", func(t *testing.T) {
+		mockQueries := &MockQueries{
+			BaseMock: base,
+			RespondToBookBorrowRequestFunc: func(ctx context.Context, arg database.RespondToBookBorrowRequestParams) (database.BookBorrowRequest, error) {
+				if arg.Status != BookBorrowRequestStatusApproved || arg.UserID != ownerID {
+					t.Fatalf("RespondToBookBorrowRequest called with wrong arguments")
+				}
+				return bookBorrowRequest, nil
+			},
+		}
+
+		apiConfig := BookBorrowAPIConfig{APIConfig: common.APIConfig{DB: mockQueries}}
+		request := httptest.NewRequest(http.MethodPatch, fmt.Sprintf("/api/v1/books/requests/%s/approve", bookBorrowRequest.ID), nil)
+
+		vars := map[string]string{"bookBorrowRequestId": bookBorrowRequest.ID.String()}
+		request = mux.SetURLVars(request, vars)
+		recorder := httptest.NewRecorder()
+
+		apiConfig.ApproveBookBorrowRequest(recorder, request, ownerID)
+
+		if recorder.Code != http.StatusOK {
+			t.Errorf("Expected status %d, got %d. Body: %s", http.StatusOK, recorder.Code, recorder.Body.String())
+		}
+	})
+
+	// 2. Failure: not the owner or already responded (sql.ErrNoRows)
+	tTesting.Run("NotFoundOrUnauthorized", func(t *testing.T) {
+		mockQueries := &MockQueries{BaseMock: base}
+
+		apiConfig := BookBorrowAPIConfig{APIConfig: common.APIConfig{DB: mockQueries}}
+		request := httptest.NewRequest(http.MethodPatch, fmt.Sprintf("/api/v1/books/requests/%s/approve", bookBorrowRequest.ID), nil)
+
+		vars := map[string]string{"bookBorrowRequestId": bookBorrowRequest.ID.String()}
+		request = mux.SetURLVars(request, vars)
+		recorder := httptest.NewRecorder()
+
+		apiConfig.ApproveBookBorrowRequest(recorder, request, ownerID)
+
+		if recorder.Code != http.StatusBadRequest {
+			t.Errorf("Expected status %d, got %d. Body: %s", http.StatusBadRequest, recorder.Code, recorder.Body.String())
+		}
+	})
+}
+
+func TestCancelBookBorrowRequest(tTesting *testing.T) {
+	requesterID := newTestUserID()
+	bookBorrowRequest := newTestBookBorrowRequest(uuid.New(), requesterID, BookBorrowRequestStatusCancelled)
+
+	base := common.NewBaseMock()
+
+	// 1. Success: requester cancels the request.
+	tTesting.Run("Success", func(t *testing.T) {
+		mockQueries := &MockQueries{
+			BaseMock: base,
+			CancelBookBorrowRequestFunc: func(ctx context.Context, arg database.CancelBookBorrowRequestParams) (database.BookBorrowRequest, error) {
+				if arg.ID != bookBorrowRequest.ID || arg.RequesterID != requesterID {
+					t.Fatalf("CancelBookBorrowRequest called with wrong IDs")
+				}
+				return bookBorrowRequest, nil
+			},
+		}
+
+		apiConfig := BookBorrowAPIConfig{APIConfig: common.APIConfig{DB: mockQueries}}
+		request := httptest.NewRequest(http.MethodPatch, fmt.Sprintf("/api/v1/books/requests/%s/cancel", bookBorrowRequest.ID), nil)
+
+		vars := map[string]string{"bookBorrowRequestId": bookBorrowRequest.ID.String()}
+		request = mux.SetURLVars(request, vars)
+		recorder := httptest.NewRecorder()
+
+		apiConfig.CancelBookBorrowRequest(recorder, request, requesterID)
+
+		if recorder.Code != http.StatusOK {
+			t.Errorf("Expected status %d, got %d. Body: %s", http.StatusOK, recorder.Code, recorder.Body.String())
+		}
+	})
+
+	// 2. Failure: invalid request ID format
+	tTesting.Run("InvalidRequestIDFormat", func(t *testing.T) {
+		mockQueries := &MockQueries{BaseMock: base}
+
+		apiConfig := BookBorrowAPIConfig{APIConfig: common.APIConfig{DB: mockQueries}}
+		request := httptest.NewRequest(http.MethodPatch, "/api/v1/books/requests/not-a-uuid/cancel", nil)
+
+		vars := map[string]string{"bookBorrowRequestId": "not-a-uuid"}
+		request = mux.SetURLVars(request, vars)
+		recorder := httptest.NewRecorder()
+
+		apiConfig.CancelBookBorrowRequest(recorder, request, requesterID)
+
+		if recorder.Code != http.StatusBadRequest {
+			t.Errorf("Expected status %d, got %d. Body: %s", http.StatusBadRequest, recorder.Code, recorder.Body.String())
+		}
+	})
+}
+
+func TestGetIncomingBookBorrowRequests(tTesting *testing.T) {
+	ownerID := newTestUserID()
+	requesterID := newTestUserID()
+
+	base := common.NewBaseMock()
+
+	// 1. Success: status filter is passed through.
+	tTesting.Run("Success", func(t *testing.T) {
+		mockQueries := &MockQueries{
+			BaseMock: base,
+			GetIncomingBookBorrowRequestsFunc: func(ctx context.Context, arg database.GetIncomingBookBorrowRequestsParams) ([]database.GetIncomingBookBorrowRequestsRow, error) {
+				if arg.UserID != ownerID || arg.Status.String != BookBorrowRequestStatusRequested {
+					t.Fatalf("GetIncomingBookBorrowRequests called with wrong arguments")
+				}
+				return []database.GetIncomingBookBorrowRequestsRow{{
+					BookBorrowRequest:      database.BookBorrowRequest{ID: uuid.New(), Status: BookBorrowRequestStatusRequested, RequesterID: requesterID},
+					BookTitle:              "Dune",
+					RequesterFirstName:     "Ann",
+					RequesterLastName:      "Lee",
+					BorrowerReputationStat: database.BorrowerReputationStat{BorrowerID: requesterID, ScoredLoans: 1, OnTimeReturns: 1},
+				}}, nil
+			},
+		}
+
+		apiConfig := BookBorrowAPIConfig{APIConfig: common.APIConfig{DB: mockQueries}}
+		request := httptest.NewRequest(http.MethodGet, "/api/v1/books/requests/incoming?status=requested", nil)
+		recorder := httptest.NewRecorder()
+
+		apiConfig.GetIncomingBookBorrowRequests(recorder, request, ownerID)
+
+		if recorder.Code != http.StatusOK {
+			t.Fatalf("Expected status %d, got %d. Body: %s", http.StatusOK, recorder.Code, recorder.Body.String())
+		}
+
+		var incomingBookBorrowRequests []IncomingBookBorrowRequest
+		json.Unmarshal(recorder.Body.Bytes(), &incomingBookBorrowRequests)
+
+		if len(incomingBookBorrowRequests) != 1 || incomingBookBorrowRequests[0].RequesterReputation.Score != 67 || incomingBookBorrowRequests[0].RequesterReputation.OnTimeReturns != 1 {
+			t.Errorf("Expected the requester's reputation, got %+v", incomingBookBorrowRequests)
+		}
+	})
+
+	// 2. Failure: unknown status filter
+	tTesting.Run("InvalidStatus", func(t *testing.T) {
+		mockQueries := &MockQueries{BaseMock: base}
+
+		apiConfig := BookBorrowAPIConfig{APIConfig: common.APIConfig{DB: mockQueries}}
+		request := httptest.NewRequest(http.MethodGet, "/api/v1/books/requests/incoming?status=lost", nil)
+		recorder := httptest.NewRecorder()
+
+		apiConfig.GetIncomingBookBorrowRequests(recorder, request, ownerID)
+
+		if recorder.Code != http.StatusBadRequest {
+			t.Errorf("Expected status %d, got %d. Body: %s", http.StatusBadRequest, recorder.Code, recorder.Body.String())
+		}
+	})
+}
+
+func TestRenewBookBorrow(tTesting *testing.T) {
+	bookUserId := newTestUserID()
+	borrowerID := newTestUserID()
+	testBook := newTestBook(bookUserId)
+	testBorrow := newTestBookBorrow(testBook.ID, borrowerID)
+
+	base := common.NewBaseMock()
+
+	newRenewRequest := func() *http.Request {
+		request := httptest.NewRequest(http.MethodPatch, fmt.Sprintf("/api/v1/books/borrows/%s/renew", testBorrow.ID), nil)
+		vars := map[string]string{"bookBorrowId": testBorrow.ID.String()}
+
+		return mux.SetURLVars(request, vars)
+	}
+
+	getTestBorrow := func(ctx context.Context, id uuid.UUID) (database.BookBorrow, error) {
+		return testBorrow, nil
+	}
+
+	// 1. Success: due date is extended by the book's loan period.
+	tTesting.Run("Success", func(t *testing.T) {
+		mockQueries := &MockQueries{
+			BaseMock:              base,
+			GetBookBorrowByIDFunc: getTestBorrow,
+			GetBookFunc: func(ctx context.Context, id uuid.UUID) (database.Book, error) {
+				return testBook, nil
+			},
+			RenewBookBorrowFunc: func(ctx context.Context, arg database.RenewBookBorrowParams) (database.BookBorrowRenewal, error) {
+				expectedDueAt := testBorrow.DueAt.AddDate(0, 0, int(testBook.LoanPeriodDays))
+
+				if !arg.PreviousDueAt.Equal(testBorrow.DueAt) || !arg.NewDueAt.Equal(expectedDueAt) {
+					t.Errorf("Expected renewal from %s to %s, got %s to %s", testBorrow.DueAt, expectedDueAt, arg.PreviousDueAt, arg.NewDueAt)
+				}
+				return database.BookBorrowRenewal{ID: arg.ID, PreviousDueAt: arg.PreviousDueAt, NewDueAt: arg.NewDueAt, BookBorrowID: arg.BookBorrowID}, nil
+			},
+		}
+
+		apiConfig := BookBorrowAPIConfig{APIConfig: common.APIConfig{DB: mockQueries}}
+		recorder := httptest.NewRecorder()
+
+		apiConfig.RenewBookBorrow(recorder, newRenewRequest(), borrowerID)
+
+		if recorder.Code != http.StatusOK {
+			t.Errorf("Expected status %d, got %d. Body: %s", http.StatusOK, recorder.Code, recorder.Body.String())
+		}
+	})
+
+	// 2. Failure: someone else's loan
+	tTesting.Run("NotBorrower", func(t *testing.T) {
+		mockQueries := &MockQueries{
+			BaseMock:              base,
+			GetBookBorrowByIDFunc: getTestBorrow,
+		}
+
+		apiConfig := BookBorrowAPIConfig{APIConfig: common.APIConfig{DB: mockQueries}}
+		recorder := httptest.NewRecorder()
+
+		apiConfig.RenewBookBorrow(recorder, newRenewRequest(), newTestUserID())
+
+		if recorder.Code != http.StatusForbidden {
+			t.Errorf("Expected status %d, got %d. Body: %s", http.StatusForbidden, recorder.Code, recorder.Body.String())
+		}
+	})
+
+	// 3. Failure: someone holds a reservation on the book
+	tTesting.Run("BookReserved", func(t *testing.T) {
+		mockQueries := &MockQueries{
+			BaseMock:              base,
+			GetBookBorrowByIDFunc: getTestBorrow,
+			CountActiveBookReservationsFunc: func(ctx context.Context, bookID uuid.UUID) (int64, error) {
+				return 1, nil
+			},
+		}
+
+		apiConfig := BookBorrowAPIConfig{APIConfig: common.APIConfig{DB: mockQueries}}
+		recorder := httptest.NewRecorder()
+
+		apiConfig.RenewBookBorrow(recorder, newRenewRequest(), borrowerID)
+
+		if recorder.Code != http.StatusConflict {
+			t.Errorf("Expected status %d, got %d. Body: %s", http.StatusConflict, recorder.Code, recorder.Body.String())
+		}
+	})
+
+	// 4. Failure: renewal cap reached
+	tTesting.Run("MaxRenewalsReached", func(t *testing.T) {
+		mockQueries := &MockQueries{
+			BaseMock:              base,
+			GetBookBorrowByIDFunc: getTestBorrow,
+			CountBookBorrowRenewalsFunc: func(ctx context.Context, bookBorrowID uuid.UUID) (int64, error) {
+				return MaxBookBorrowRenewals, nil
+			},
+		}
+
+		apiConfig := BookBorrowAPIConfig{APIConfig: common.APIConfig{DB: mockQueries}}
+		recorder := httptest.NewRecorder()
+
+		apiConfig.RenewBookBorrow(recorder, newRenewRequest(), borrowerID)
+
+		if recorder.Code != http.StatusConflict {
+			t.Errorf("Expected status %d, got %d. Body: %s", http.StatusConflict, recorder.Code, recorder.Body.String())
+		}
+	})
+
+	// 5. Failure: book was already returned
+	tTesting.Run("AlreadyReturned", func(t *testing.T) {
+		mockQueries := &MockQueries{
+			BaseMock: base,
+			GetBookBorrowByIDFunc: func(ctx context.Context, id uuid.UUID) (database.BookBorrow, error) {
+				returnedBorrow := testBorrow
+				returnedBorrow.ReturnedAt = sql.NullTime{Time: time.Now(), Valid: true}
+
+				return returnedBorrow, nil
+			},
+		}
+
+		apiConfig := BookBorrowAPIConfig{APIConfig: common.APIConfig{DB: mockQueries}}
+		recorder := httptest.NewRecorder()
+
+		apiConfig.RenewBookBorrow(recorder, newRenewRequest(), borrowerID)
+
+		if recorder.Code != http.StatusBadRequest {
+			t.Errorf("Expected status %d, got %d. Body: %s", http.StatusBadRequest, recorder.Code, recorder.Body.String())
+		}
+	})
+}
+
+func TestGetBookBorrowRenewals(tTesting *testing.T) {
+	bookUserId := newTestUserID()
+	testBook := newTestBook(bookUserId)
+	testBorrow := newTestBookBorrow(testBook.ID, newTestUserID())
+
+	base := common.NewBaseMock()
+
+	newRenewalsRequest := func() *http.Request {
+		request := httptest.NewRequest(http.MethodGet, fmt.Sprintf("/api/v1/books/borrows/%s/renewals", testBorrow.ID), nil)
+		vars := map[string]string{"bookBorrowId": testBorrow.ID.String()}
+
+		return mux.SetURLVars(request, vars)
+	}
+
+	mockQueries := &MockQueries{
+		BaseMock: base,
+		GetBookBorrowWithOwnerFunc: func(ctx context.Context, id uuid.UUID) (database.GetBookBorrowWithOwnerRow, error) {
+			return database.GetBookBorrowWithOwnerRow{BookBorrow: testBorrow, OwnerID: testBook.UserID, BookTitle: testBook.Title}, nil
+		},
+		// The book is in the trash, the loan and its owner still are not.
+		GetBookFunc: func(ctx context.Context, id uuid.UUID) (database.Book, error) {
+			return database.Book{}, sql.ErrNoRows
+		},
+	}
+
+	// 1. Success: book owner can see the renewal history, even with the book in the trash.
+	tTesting.Run("Owner", func(t *testing.T) {
+		apiConfig := BookBorrowAPIConfig{APIConfig: common.APIConfig{DB: mockQueries}}
+		recorder := httptest.NewRecorder()
+
+		apiConfig.GetBookBorrowRenewals(recorder, newRenewalsRequest(), bookUserId)
+
+		if recorder.Code != http.StatusOK {
+			t.Errorf("Expected status %d, got %d. Body: %s", http.StatusOK, recorder.Code, recorder.Body.String())
+		}
+	})
+
+	// 2. Failure: other users cannot see the loan
+	tTesting.Run("Stranger", func(t *testing.T) {
+		apiConfig := BookBorrowAPIConfig{APIConfig: common.APIConfig{DB: mockQueries}}
+		recorder := httptest.NewRecorder()
+
+		apiConfig.GetBookBorrowRenewals(recorder, newRenewalsRequest(), newTestUserID())
+
+		if recorder.Code != http.StatusNotFound {
+			t.Errorf("Expected status %d, got %d. Body: %s", http.StatusNotFound, recorder.Code, recorder.Body.String())
+		}
+	})
+
+	// 3. Failure: loan not found
+	tTesting.Run("NotFound", func(t *testing.T) {
+		apiConfig := BookBorrowAPIConfig{APIConfig: common.APIConfig{DB: &MockQueries{BaseMock: base}}}
+		recorder := httptest.NewRecorder()
+
+		apiConfig.GetBookBorrowRenewals(recorder, newRenewalsRequest(), bookUserId)
+
+		if recorder.Code != http.StatusNotFound {
+			t.Errorf("Expected status %d, got %d. Body: %s", http.StatusNotFound, recorder.Code, recorder.Body.String())
+		}
+	})
+}
+
+func TestGetBorrowedBooks(tTesting *testing.T) {
+	borrowerID := newTestUserID()
+	testBook := newTestBook(newTestUserID())
+	testBorrow := newTestBookBorrow(testBook.ID, borrowerID)
+
+	base := common.NewBaseMock()
+
+	// 1. Success: overdue filter is passed through and the lender's name is joined in.
+	tTesting.Run("Success", func(t *testing.T) {
+		mockQueries := &MockQueries{
+			BaseMock: base,
+			GetBorrowedBooksFunc: func(ctx context.Context, arg database.GetBorrowedBooksParams) ([]database.GetBorrowedBooksRow, error) {
+				if arg.BorrowerID != borrowerID || arg.Status.String != BookBorrowStatusOverdue {
+					t.Fatalf("GetBorrowedBooks called with wrong arguments")
+				}
+				return []database.GetBorrowedBooksRow{{BookBorrow: testBorrow, BookTitle: testBook.Title, LenderFirstName: "Jane", LenderLastName: "Doe"}}, nil
+			},
+		}
+
+		apiConfig := BookBorrowAPIConfig{APIConfig: common.APIConfig{DB: mockQueries}}
+		request := httptest.NewRequest(http.MethodGet, "/api/v1/books/borrows?status=overdue", nil)
+		recorder := httptest.NewRecorder()
+
+		apiConfig.GetBorrowedBooks(recorder, request, borrowerID)
+
+		if recorder.Code != http.StatusOK {
+			t.Fatalf("Expected status %d, got %d. Body: %s", http.StatusOK, recorder.Code, recorder.Body.String())
+		}
+
+		var borrowedBooks common.Page[BorrowedBook]
+
+		if err := json.NewDecoder(recorder.Body).Decode(&borrowedBooks); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+
+		if len(borrowedBooks.Data) != 1 || borrowedBooks.Data[0].LenderName != "Jane Doe" || borrowedBooks.Data[0].BookTitle != testBook.Title || borrowedBooks.NextCursor != nil {
+			t.Errorf("Unexpected borrowed books: %+v", borrowedBooks)
+		}
+	})
+
+	// 2. Failure: unknown status filter
+	tTesting.Run("InvalidStatus", func(t *testing.T) {
+		mockQueries := &MockQueries{BaseMock: base}
+
+		apiConfig := BookBorrowAPIConfig{APIConfig: common.APIConfig{DB: mockQueries}}
+		request := httptest.NewRequest(http.MethodGet, "/api/v1/books/borrows?status=lost", nil)
+		recorder := httptest.NewRecorder()
+
+		apiConfig.GetBorrowedBooks(recorder, request, borrowerID)
+
+		if recorder.Code != http.StatusBadRequest {
+			t.Errorf("Expected status %d, got %d. Body: %s", http.StatusBadRequest, recorder.Code, recorder.Body.String())
+		}
+	})
+
+	// 2a. Failure: a cursor that isn't from this list.
+	tTesting.Run("InvalidCursor", func(t *testing.T) {
+		mockQueries := &MockQueries{BaseMock: base}
+
+		apiConfig := BookBorrowAPIConfig{APIConfig: common.APIConfig{DB: mockQueries}}
+		cursor := common.EncodeCursor(common.Cursor{Value: "not-a-time", ID: uuid.New()})
+		request := httptest.NewRequest(http.MethodGet, "/api/v1/books/borrows?cursor="+cursor, nil)
+		recorder := httptest.NewRecorder()
+
+		apiConfig.GetBorrowedBooks(recorder, request, borrowerID)
+
+		if recorder.Code != http.StatusBadRequest {
+			t.Errorf("Expected status %d, got %d. Body: %s", http.StatusBadRequest, recorder.Code, recorder.Body.String())
+		}
+	})
+}
+
+func TestGetLentBooks(tTesting *testing.T) {
+	ownerID := newTestUserID()
+
+	base := common.NewBaseMock()
+
+	// 1. Success: no filter lists every loan.
+	tTesting.Run("Success", func(t *testing.T) {
+		mockQueries := &MockQueries{
+			BaseMock: base,
+			GetLentBooksFunc: func(ctx context.Context, arg database.GetLentBooksParams) ([]database.GetLentBooksRow, error) {
+				if arg.UserID != ownerID || arg.Status.Valid {
+					t.Fatalf("GetLentBooks called with wrong arguments")
+				}
+				return []database.GetLentBooksRow{}, nil
+			},
+		}
+
+		apiConfig := BookBorrowAPIConfig{APIConfig: common.APIConfig{DB: mockQueries}}
+		request := httptest.NewRequest(http.MethodGet, "/api/v1/books/lent", nil)
+		recorder := httptest.NewRecorder()
+
+		apiConfig.GetLentBooks(recorder, request, ownerID)
+
+		if recorder.Code != http.StatusOK {
+			t.Errorf("Expected status %d, got %d. Body: %s", http.StatusOK, recorder.Code, recorder.Body.String())
+		}
+	})
+
+	// 1a. Success: loans are paged newest first and the next page starts after the cursor.
+	tTesting.Run("Paged", func(t *testing.T) {
+		testBook := newTestBook(ownerID)
+		var lentBookRows []database.GetLentBooksRow
+
+		for day := 0; day < 3; day++ {
+			testBorrow := newTestBookBorrow(testBook.ID, newTestUserID())
+			testBorrow.IssuedAt = time.Date(2026, 1, 10-day, 9, 0, 0, 0, time.UTC)
+			lentBookRows = append(lentBookRows, database.GetLentBooksRow{BookBorrow: testBorrow, BookTitle: testBook.Title})
+		}
+
+		var getLentBooksParams []database.GetLentBooksParams
+
+		mockQueries := &MockQueries{
+			BaseMock: base,
+			GetLentBooksFunc: func(ctx context.Context, arg database.GetLentBooksParams) ([]database.GetLentBooksRow, error) {
+				getLentBooksParams = append(getLentBooksParams, arg)
+
+				if arg.CursorID.Valid {
+					return lentBookRows[2:], nil
+				}
+				return lentBookRows[:int(arg.PageLimit)], nil
+			},
+		}
+
+		apiConfig := BookBorrowAPIConfig{APIConfig: common.APIConfig{DB: mockQueries}}
+		getLentBooks := func(target string) common.Page[LentBook] {
+			recorder := httptest.NewRecorder()
+
+			apiConfig.GetLentBooks(recorder, httptest.NewRequest(http.MethodGet, target, nil), ownerID)
+
+			var lentBooks common.Page[LentBook]
+
+			if recorder.Code != http.StatusOK || json.Unmarshal(recorder.Body.Bytes(), &lentBooks) != nil {
+				t.Fatalf("Unexpected response %d: %s", recorder.Code, recorder.Body.String())
+			}
+
+			return lentBooks
+		}
+
+		firstPage := getLentBooks("/api/v1/books/lent?limit=2")
+
+		if len(firstPage.Data) != 2 || firstPage.NextCursor == nil || getLentBooksParams[0].PageLimit != 3 {
+			t.Fatalf("Expected 2 loans and a next cursor, got %+v (%+v)", firstPage, getLentBooksParams[0])
+		}
+
+		secondPage := getLentBooks("/api/v1/books/lent?limit=2&cursor=" + *firstPage.NextCursor)
+		lastLoan := lentBookRows[1].BookBorrow
+
+		if len(secondPage.Data) != 1 || secondPage.NextCursor != nil {
+			t.Errorf("Expected the last loan and no next cursor, got %+v", secondPage)
+		}
+
+		if getLentBooksParams[1].CursorID.UUID != lastLoan.ID || !getLentBooksParams[1].CursorIssuedAt.Equal(lastLoan.IssuedAt) {
+			t.Errorf("Expected the page after loan %s issued %s, got %+v", lastLoan.ID, lastLoan.IssuedAt, getLentBooksParams[1])
+		}
+	})
+}
+
+func TestConfirmBookReturn(tTesting *testing.T) {
+	ownerID := newTestUserID()
+	testBook := newTestBook(ownerID)
+	returnedBorrow := newTestBookBorrow(testBook.ID, newTestUserID())
+	returnedBorrow.ReturnedAt = sql.NullTime{Time: time.Now(), Valid: true}
+
+	base := common.NewBaseMock()
+
+	newConfirmRequest := func() *http.Request {
+		request := httptest.NewRequest(http.MethodPatch, fmt.Sprintf("/api/v1/books/return/%s/confirm", returnedBorrow.ID), nil)
+		vars := map[string]string{"bookBorrowId": returnedBorrow.ID.String()}
+
+		return mux.SetURLVars(request, vars)
+	}
+
+	// 1. Success: owner confirms they got the book back.
+	tTesting.Run("Success", func(t *testing.T) {
+		mockQueries := &MockQueries{
+			BaseMock: base,
+			ConfirmBookReturnFunc: func(ctx context.Context, arg database.ConfirmBookReturnParams) (database.BookBorrow, error) {
+				if arg.ID != returnedBorrow.ID || arg.UserID != ownerID {
+					t.Fatalf("ConfirmBookReturn called with wrong IDs")
+				}
+
+				confirmedBorrow := returnedBorrow
+				confirmedBorrow.ReturnStatus = sql.NullString{String: BookBorrowReturnStatusConfirmed, Valid: true}
+				confirmedBorrow.ReturnConfirmedAt = sql.NullTime{Time: time.Now(), Valid: true}
+
+				return confirmedBorrow, nil
+			},
+		}
+
+		apiConfig := BookBorrowAPIConfig{APIConfig: common.APIConfig{DB: mockQueries}}
+		recorder := httptest.NewRecorder()
+
+		apiConfig.ConfirmBookReturn(recorder, newConfirmRequest(), ownerID)
+
+		if recorder.Code != http.StatusOK {
+			t.Fatalf("Expected status %d, got %d. Body: %s", http.StatusOK, recorder.Code, recorder.Body.String())
+		}
+
+		if !strings.Contains(recorder.Body.String(), `"return_status":"confirmed"`) {
+			t.Errorf("Expected confirmed return status in body, got %s", recorder.Body.String())
+		}
+	})
+
+	// 2. Failure: not the owner, or the borrower has not marked it returned
+	tTesting.Run("NotAwaitingConfirmation", func(t *testing.T) {
+		mockQueries := &MockQueries{
+			BaseMock: base,
+			ConfirmBookReturnFunc: func(ctx context.Context, arg database.ConfirmBookReturnParams) (database.BookBorrow, error) {
+				return database.BookBorrow{}, sql.ErrNoRows
+			},
+		}
+
+		apiConfig := BookBorrowAPIConfig{APIConfig: common.APIConfig{DB: mockQueries}}
+		recorder := httptest.NewRecorder()
+
+		apiConfig.ConfirmBookReturn(recorder, newConfirmRequest(), newTestUserID())
+
+		if recorder.Code != http.StatusBadRequest {
+			t.Errorf("Expected status %d, got %d. Body: %s", http.StatusBadRequest, recorder.Code, recorder.Body.String())
+		}
+	})
+}
+
+func TestDisputeBookReturn(tTesting *testing.T) {
+	ownerID := newTestUserID()
+	testBook := newTestBook(ownerID)
+	returnedBorrow := newTestBookBorrow(testBook.ID, newTestUserID())
+	returnedBorrow.ReturnedAt = sql.NullTime{Time: time.Now(), Valid: true}
+
+	base := common.NewBaseMock()
+
+	// 1. Success: owner disputes the return.
+	tTesting.Run("Success", func(t *testing.T) {
+		mockQueries := &MockQueries{
+			BaseMock: base,
+			DisputeBookReturnFunc: func(ctx context.Context, arg database.DisputeBookReturnParams) (database.BookBorrow, error) {
+				disputedBorrow := returnedBorrow
+				disputedBorrow.ReturnStatus = sql.NullString{String: BookBorrowReturnStatusDisputed, Valid: true}
+				disputedBorrow.ReturnDisputedAt = sql.NullTime{Time: time.Now(), Valid: true}
+
+				return disputedBorrow, nil
+			},
+		}
+
+		apiConfig := BookBorrowAPIConfig{APIConfig: common.APIConfig{DB: mockQueries}}
+		request := httptest.NewRequest(http.MethodPatch, fmt.Sprintf("/api/v1/books/return/%s/dispute", returnedBorrow.ID), nil)
+		request = mux.SetURLVars(request, map[string]string{"bookBorrowId": returnedBorrow.ID.String()})
+		recorder := httptest.NewRecorder()
+
+		apiConfig.DisputeBookReturn(recorder, request, ownerID)
+
+		if recorder.Code != http.StatusOK {
+			t.Fatalf("Expected status %d, got %d. Body: %s", http.StatusOK, recorder.Code, recorder.Body.String())
+		}
+
+		if !strings.Contains(recorder.Body.String(), `"return_status":"disputed"`) {
+			t.Errorf("Expected disputed return status in body, got %s", recorder.Body.String())
+		}
+	})
+}
+
+func TestSendBookBorrowReminders(tTesting *testing.T) {
+	now := time.Now().UTC()
+
+	base := common.NewBaseMock()
+
+	// 1. Success: every threshold is queried with its own window.
+	tTesting.Run("QueriesEveryThreshold", func(t *testing.T) {
+		var queriedParams []database.GetBookBorrowsDueForReminderParams
+
+		mockQueries := &MockQueries{
+			BaseMock: base,
+			GetBookBorrowsDueForReminderFunc: func(ctx context.Context, arg database.GetBookBorrowsDueForReminderParams) ([]database.GetBookBorrowsDueForReminderRow, error) {
+				queriedParams = append(queriedParams, arg)
+
+				return []database.GetBookBorrowsDueForReminderRow{}, nil
+			},
+		}
+
+		apiConfig := common.APIConfig{DB: mockQueries}
+
+		if err := SendBookBorrowReminders(context.Background(), &apiConfig, now); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		if len(queriedParams) != len(BookBorrowReminderThresholds) {
+			t.Fatalf("Expected %d queries, got %d", len(BookBorrowReminderThresholds), len(queriedParams))
+		}
+
+		dueSoon := queriedParams[0]
+
+		if dueSoon.Threshold != "due_soon" || !dueSoon.DueBefore.Equal(now.Add(48*time.Hour)) || !dueSoon.DueAfter.Time.Equal(now) {
+			t.Errorf("Unexpected due_soon window: %+v", dueSoon)
+		}
+
+		if queriedParams[len(queriedParams)-1].DueAfter.Valid {
+			t.Error("Expected the last threshold to have no lower bound")
+		}
+	})
+
+	// 2. Success: a reminder that was already claimed is not sent again.
+	tTesting.Run("AlreadySent", func(t *testing.T) {
+		mockQueries := &MockQueries{
+			BaseMock: base,
+			GetBookBorrowsDueForReminderFunc: func(ctx context.Context, arg database.GetBookBorrowsDueForReminderParams) ([]database.GetBookBorrowsDueForReminderRow, error) {
+				return []database.GetBookBorrowsDueForReminderRow{{BookBorrow: newTestBookBorrow(uuid.New(), newTestUserID())}}, nil
+			},
+			DeleteBookBorrowReminderFunc: func(ctx context.Context, id uuid.UUID) error {
+				t.Fatal("DeleteBookBorrowReminder should not be called for an unclaimed reminder")
+
+				return nil
+			},
+		}
+
+		apiConfig := common.APIConfig{DB: mockQueries, Templates: notification_templates.MustNewRenderer()}
+
+		if err := SendBookBorrowReminders(context.Background(), &apiConfig, now); err != nil {
+			t.Errorf("Expected no error, got %v", err)
+		}
+	})
+
+	// 3. Success: borrower gets the reminder and the lender gets a summary.
+	tTesting.Run("SendsReminderAndSummary", func(t *testing.T) {
+		recordingNotifier := &notifiers.RecordingNotifier{}
+
+		mockQueries := &MockQueries{
+			BaseMock: base,
+			GetBookBorrowsDueForReminderFunc: func(ctx context.Context, arg database.GetBookBorrowsDueForReminderParams) ([]database.GetBookBorrowsDueForReminderRow, error) {
+				if arg.Threshold != "overdue" {
+					return []database.GetBookBorrowsDueForReminderRow{}, nil
+				}
+				return []database.GetBookBorrowsDueForReminderRow{newTestReminderRow()}, nil
+			},
+			CreateBookBorrowReminderFunc: func(ctx context.Context, arg database.CreateBookBorrowReminderParams) (database.BookBorrowReminder, error) {
+				return database.BookBorrowReminder{ID: arg.ID, Threshold: arg.Threshold, BookBorrowID: arg.BookBorrowID}, nil
+			},
+		}
+
+		apiConfig := common.APIConfig{DB: mockQueries, Notifier: recordingNotifier, NotificationSender: "Co-Library <no-reply@example.com>", Templates: notification_templates.MustNewRenderer()}
+
+		if err := SendBookBorrowReminders(context.Background(), &apiConfig, now); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		notifications := recordingNotifier.Notifications()
+
+		if len(notifications) != 2 {
+			t.Fatalf("Expected 2 notifications, got %d", len(notifications))
+		}
+
+		if notifications[0].To != "John Doe <john@example.com>" || notifications[0].Subject != "A Book You Borrowed Is Overdue" {
+			t.Errorf("Unexpected borrower reminder: %+v", notifications[0])
+		}
+
+		if notifications[1].To != "Jane Doe <jane@example.com>" || !strings.Contains(notifications[1].Body, "Test Book") {
+			t.Errorf("Unexpected lender summary: %+v", notifications[1])
+		}
+	})
+
+	// 4. Failure: a reminder that could not be sent is released for the next run
+	tTesting.Run("SendFailureReleasesClaim", func(t *testing.T) {
+		released := false
+
+		mockQueries := &MockQueries{
+			BaseMock: base,
+			GetBookBorrowsDueForReminderFunc: func(ctx context.Context, arg database.GetBookBorrowsDueForReminderParams) ([]database.GetBookBorrowsDueForReminderRow, error) {
+				if arg.Threshold != "due_soon" {
+					return []database.GetBookBorrowsDueForReminderRow{}, nil
+				}
+				return []database.GetBookBorrowsDueForReminderRow{newTestReminderRow()}, nil
+			},
+			CreateBookBorrowReminderFunc: func(ctx context.Context, arg database.CreateBookBorrowReminderParams) (database.BookBorrowReminder, error) {
+				return database.BookBorrowReminder{ID: arg.ID}, nil
+			},
+			DeleteBookBorrowReminderFunc: func(ctx context.Context, id uuid.UUID) error {
+				released = true
+
+				return nil
+			},
+		}
+
+		apiConfig := common.APIConfig{DB: mockQueries, Notifier: failingNotifier{}, Templates: notification_templates.MustNewRenderer()}
+
+		if err := SendBookBorrowReminders(context.Background(), &apiConfig, now); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		if !released {
+			t.Error("Expected the reminder claim to be released")
+		}
+	})
+
+	// 4a. Success: a renewed loan is reminded again for its new due date
+	tTesting.Run("RenewedLoanRemindedAgain", func(t *testing.T) {
+		recordingNotifier := &notifiers.RecordingNotifier{}
+		reminderRow := newTestReminderRow()
+		reminderRow.BookBorrow.DueAt = now.Add(-24 * time.Hour)
+		sentReminders := map[string]bool{}
+
+		reminderKey := func(threshold string, dueAt time.Time) string {
+			return fmt.Sprintf("%s/%s/%s", reminderRow.BookBorrow.ID, threshold, dueAt)
+		}
+
+		// Mirrors the query: due in the threshold's window and not yet reminded for this due date.
+		mockQueries := &MockQueries{
+			BaseMock: base,
+			GetBookBorrowsDueForReminderFunc: func(ctx context.Context, arg database.GetBookBorrowsDueForReminderParams) ([]database.GetBookBorrowsDueForReminderRow, error) {
+				dueAt := reminderRow.BookBorrow.DueAt
+
+				if dueAt.After(arg.DueBefore) || (arg.DueAfter.Valid && !dueAt.After(arg.DueAfter.Time)) || sentReminders[reminderKey(arg.Threshold, dueAt)] {
+					return []database.GetBookBorrowsDueForReminderRow{}, nil
+				}
+				return []database.GetBookBorrowsDueForReminderRow{reminderRow}, nil
+			},
+			CreateBookBorrowReminderFunc: func(ctx context.Context, arg database.CreateBookBorrowReminderParams) (database.BookBorrowReminder, error) {
+				sentReminders[reminderKey(arg.Threshold, arg.DueAt)] = true
+
+				return database.BookBorrowReminder{ID: arg.ID, Threshold: arg.Threshold, BookBorrowID: arg.BookBorrowID, DueAt: arg.DueAt}, nil
+			},
+		}
+
+		apiConfig := common.APIConfig{DB: mockQueries, Notifier: recordingNotifier, Templates: notification_templates.MustNewRenderer()}
+
+		if err := SendBookBorrowReminders(context.Background(), &apiConfig, now); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		// The borrower renews the overdue loan, moving its due date a day out. The run after
+		// that has nothing new to remind about.
+		reminderRow.BookBorrow.DueAt = now.Add(24 * time.Hour)
+
+		for run := 0; run < 2; run++ {
+			if err := SendBookBorrowReminders(context.Background(), &apiConfig, now); err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+		}
+
+		borrowerReminders := []string{}
+
+		for _, notification := range recordingNotifier.Notifications() {
+			if notification.To == "John Doe <john@example.com>" {
+				borrowerReminders = append(borrowerReminders, notification.Subject)
+			}
+		}
+
+		if len(borrowerReminders) != 2 || borrowerReminders[0] != "A Book You Borrowed Is Overdue" {
+			t.Errorf("Expected an overdue reminder and one for the renewed due date, got %v", borrowerReminders)
+		}
+	})
+
+	// 5. Failure: query error is returned to the scheduler
+	tTesting.Run("QueryError", func(t *testing.T) {
+		mockQueries := &MockQueries{
+			BaseMock: base,
+			GetBookBorrowsDueForReminderFunc: func(ctx context.Context, arg database.GetBookBorrowsDueForReminderParams) ([]database.GetBookBorrowsDueForReminderRow, error) {
+				return nil, errors.New("db connection lost")
+			},
+		}
+
+		apiConfig := common.APIConfig{DB: mockQueries}
+
+		if err := SendBookBorrowReminders(context.Background(), &apiConfig, now); err == nil {
+			t.Error("Expected an error, got nil")
 		}
 	})
 }
\ No newline at end of file
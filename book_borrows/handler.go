@@ -1,7 +1,18 @@
 package book_borrows
 
 import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/elorenzorodz/co-library/borrower_reputation"
+	"github.com/elorenzorodz/co-library/common"
 	"github.com/elorenzorodz/co-library/internal/database"
+	"github.com/elorenzorodz/co-library/notification_templates"
+	"github.com/google/uuid"
 )
 
 func DatabaseBookBorrowToBookBorrowJSON(databaseBookBorrow database.BookBorrow) BookBorrow {
@@ -9,9 +20,305 @@ func DatabaseBookBorrowToBookBorrowJSON(databaseBookBorrow database.BookBorrow)
 		ID:        databaseBookBorrow.ID,
 		IssuedAt:     databaseBookBorrow.IssuedAt,
 		ReturnedAt:    databaseBookBorrow.ReturnedAt,
+		DueAt:     databaseBookBorrow.DueAt,
+		Overdue:   IsBookBorrowOverdue(databaseBookBorrow, time.Now().UTC()),
+		ReturnStatus: databaseBookBorrow.ReturnStatus.String,
+		ReturnConfirmedAt: databaseBookBorrow.ReturnConfirmedAt,
+		ReturnDisputedAt: databaseBookBorrow.ReturnDisputedAt,
+		LostAt: databaseBookBorrow.LostAt,
 		CreatedAt: databaseBookBorrow.CreatedAt,
 		UpdatedAt: databaseBookBorrow.UpdatedAt,
 		BookID:    databaseBookBorrow.BookID,
 		BorrowerID:    databaseBookBorrow.BorrowerID,
+		BookCopyID:    databaseBookBorrow.BookCopyID,
+	}
+}
+
+// A borrow is overdue while the book is still out past its due date. A disputed return
+// means the owner never got the book back, so it still counts, unless the loan was since
+// written off as lost.
+func IsBookBorrowOverdue(databaseBookBorrow database.BookBorrow, now time.Time) bool {
+	stillOut := !databaseBookBorrow.ReturnConfirmedAt.Valid &&
+		(!databaseBookBorrow.ReturnedAt.Valid || databaseBookBorrow.ReturnStatus.String == BookBorrowReturnStatusDisputed)
+
+	return stillOut && now.After(databaseBookBorrow.DueAt)
+}
+
+func LoanDueAt(issuedAt time.Time, loanPeriodDays int32) time.Time {
+	return issuedAt.AddDate(0, 0, int(loanPeriodDays))
+}
+
+func DatabaseBorrowedBooksToJSON(databaseRows []database.GetBorrowedBooksRow) []BorrowedBook {
+	borrowedBooks := []BorrowedBook{}
+
+	for _, databaseRow := range databaseRows {
+		borrowedBooks = append(borrowedBooks, BorrowedBook{
+			BookBorrow: DatabaseBookBorrowToBookBorrowJSON(databaseRow.BookBorrow),
+			BookTitle:  databaseRow.BookTitle,
+			LenderName: fmt.Sprintf("%s %s", databaseRow.LenderFirstName, databaseRow.LenderLastName),
+		})
+	}
+
+	return borrowedBooks
+}
+
+func DatabaseLentBooksToJSON(databaseRows []database.GetLentBooksRow) []LentBook {
+	lentBooks := []LentBook{}
+
+	for _, databaseRow := range databaseRows {
+		lentBooks = append(lentBooks, LentBook{
+			BookBorrow:   DatabaseBookBorrowToBookBorrowJSON(databaseRow.BookBorrow),
+			BookTitle:    databaseRow.BookTitle,
+			BorrowerName: fmt.Sprintf("%s %s", databaseRow.BorrowerFirstName, databaseRow.BorrowerLastName),
+		})
+	}
+
+	return lentBooks
+}
+
+// Borrowed and lent books are both paged newest loan first.
+func BorrowedBookCursor(databaseRow database.GetBorrowedBooksRow) common.Cursor {
+	return common.Cursor{Value: databaseRow.BookBorrow.IssuedAt.Format(time.RFC3339Nano), ID: databaseRow.BookBorrow.ID}
+}
+
+func LentBookCursor(databaseRow database.GetLentBooksRow) common.Cursor {
+	return common.Cursor{Value: databaseRow.BookBorrow.IssuedAt.Format(time.RFC3339Nano), ID: databaseRow.BookBorrow.ID}
+}
+
+// The issued_at of the last loan on the previous page, zero on the first page.
+func ParseBookBorrowCursor(pageParameters common.PageParameters) (time.Time, error) {
+	if pageParameters.Cursor == nil {
+		return time.Time{}, nil
+	}
+
+	cursorIssuedAt, parseError := time.Parse(time.RFC3339Nano, pageParameters.Cursor.Value)
+
+	if parseError != nil {
+		return time.Time{}, errors.New("invalid cursor")
+	}
+
+	return cursorIssuedAt, nil
+}
+
+// Turns the optional ?status= query value into a borrow history filter.
+func ParseBookBorrowStatusFilter(status string) (sql.NullString, error) {
+	switch status {
+	case "":
+		return sql.NullString{}, nil
+	case BookBorrowStatusActive, BookBorrowStatusReturned, BookBorrowStatusOverdue,
+		BookBorrowReturnStatusPending, BookBorrowReturnStatusDisputed:
+		return sql.NullString{String: status, Valid: true}, nil
 	}
+
+	return sql.NullString{}, fmt.Errorf("invalid status: %s", status)
+}
+
+func DatabaseBookBorrowRenewalToBookBorrowRenewalJSON(databaseBookBorrowRenewal database.BookBorrowRenewal) BookBorrowRenewal {
+	return BookBorrowRenewal{
+		ID:            databaseBookBorrowRenewal.ID,
+		PreviousDueAt: databaseBookBorrowRenewal.PreviousDueAt,
+		NewDueAt:      databaseBookBorrowRenewal.NewDueAt,
+		CreatedAt:     databaseBookBorrowRenewal.CreatedAt,
+		BookBorrowID:  databaseBookBorrowRenewal.BookBorrowID,
+	}
+}
+
+func DatabaseBookBorrowRenewalsToBookBorrowRenewalsJSON(databaseBookBorrowRenewals []database.BookBorrowRenewal) []BookBorrowRenewal {
+	bookBorrowRenewals := []BookBorrowRenewal{}
+
+	for _, databaseBookBorrowRenewal := range databaseBookBorrowRenewals {
+		bookBorrowRenewals = append(bookBorrowRenewals, DatabaseBookBorrowRenewalToBookBorrowRenewalJSON(databaseBookBorrowRenewal))
+	}
+
+	return bookBorrowRenewals
+}
+
+func DatabaseBookBorrowRequestToBookBorrowRequestJSON(databaseBookBorrowRequest database.BookBorrowRequest) BookBorrowRequest {
+	return BookBorrowRequest{
+		ID:           databaseBookBorrowRequest.ID,
+		Status:       databaseBookBorrowRequest.Status,
+		RespondedAt:  databaseBookBorrowRequest.RespondedAt,
+		CreatedAt:    databaseBookBorrowRequest.CreatedAt,
+		UpdatedAt:    databaseBookBorrowRequest.UpdatedAt,
+		BookID:       databaseBookBorrowRequest.BookID,
+		RequesterID:  databaseBookBorrowRequest.RequesterID,
+		BookBorrowID: databaseBookBorrowRequest.BookBorrowID,
+	}
+}
+
+func DatabaseIncomingBookBorrowRequestsToJSON(databaseRows []database.GetIncomingBookBorrowRequestsRow) []IncomingBookBorrowRequest {
+	incomingBookBorrowRequests := []IncomingBookBorrowRequest{}
+
+	for _, databaseRow := range databaseRows {
+		incomingBookBorrowRequests = append(incomingBookBorrowRequests, IncomingBookBorrowRequest{
+			BookBorrowRequest: DatabaseBookBorrowRequestToBookBorrowRequestJSON(databaseRow.BookBorrowRequest),
+			BookTitle:         databaseRow.BookTitle,
+			RequesterName:     fmt.Sprintf("%s %s", databaseRow.RequesterFirstName, databaseRow.RequesterLastName),
+			// Shown so the owner can see how the requester handled past loans before approving.
+			RequesterReputation: borrower_reputation.DatabaseBorrowerReputationStatToBorrowerReputationJSON(databaseRow.BorrowerReputationStat),
+		})
+	}
+
+	return incomingBookBorrowRequests
+}
+
+func DatabaseOutgoingBookBorrowRequestsToJSON(databaseRows []database.GetOutgoingBookBorrowRequestsRow) []OutgoingBookBorrowRequest {
+	outgoingBookBorrowRequests := []OutgoingBookBorrowRequest{}
+
+	for _, databaseRow := range databaseRows {
+		outgoingBookBorrowRequests = append(outgoingBookBorrowRequests, OutgoingBookBorrowRequest{
+			BookBorrowRequest: DatabaseBookBorrowRequestToBookBorrowRequestJSON(databaseRow.BookBorrowRequest),
+			BookTitle:         databaseRow.BookTitle,
+			OwnerName:         fmt.Sprintf("%s %s", databaseRow.OwnerFirstName, databaseRow.OwnerLastName),
+		})
+	}
+
+	return outgoingBookBorrowRequests
+}
+
+func IsBookBorrowRequestStatusValid(status string) bool {
+	switch status {
+	case BookBorrowRequestStatusRequested, BookBorrowRequestStatusApproved, BookBorrowRequestStatusDeclined,
+		BookBorrowRequestStatusIssued, BookBorrowRequestStatusReturned, BookBorrowRequestStatusCancelled:
+		return true
+	}
+
+	return false
+}
+
+// Turns the optional ?status= query value into a list filter.
+func ParseBookBorrowRequestStatusFilter(status string) (sql.NullString, error) {
+	if status == "" {
+		return sql.NullString{}, nil
+	}
+
+	if !IsBookBorrowRequestStatusValid(status) {
+		return sql.NullString{}, fmt.Errorf("invalid status: %s", status)
+	}
+
+	return sql.NullString{String: status, Valid: true}, nil
+}
+
+// Sends the reminders that are due at now for every threshold, then one summary per lender.
+// Each reminder is claimed in book_borrow_reminders before it is sent, so it goes out once
+// even if the job runs again or on several servers at the same time.
+func SendBookBorrowReminders(ctx context.Context, apiConfig *common.APIConfig, now time.Time) error {
+	lenderSummaries := map[uuid.UUID][]notification_templates.BookBorrowReminderSummaryItem{}
+	lenders := map[uuid.UUID]database.GetBookBorrowsDueForReminderRow{}
+
+	for _, threshold := range BookBorrowReminderThresholds {
+		getBookBorrowsDueForReminderParams := database.GetBookBorrowsDueForReminderParams{
+			DueBefore: now.Add(threshold.DueBefore),
+			Threshold: threshold.Name,
+		}
+
+		if !threshold.Unbounded {
+			getBookBorrowsDueForReminderParams.DueAfter = sql.NullTime{Time: now.Add(threshold.DueAfter), Valid: true}
+		}
+
+		bookBorrows, getBookBorrowsError := apiConfig.DB.GetBookBorrowsDueForReminder(ctx, getBookBorrowsDueForReminderParams)
+
+		if getBookBorrowsError != nil {
+			return fmt.Errorf("error getting borrows due for %s reminder: %w", threshold.Name, getBookBorrowsError)
+		}
+
+		for _, bookBorrow := range bookBorrows {
+			if !sendBookBorrowReminder(ctx, apiConfig, threshold, bookBorrow) {
+				continue
+			}
+
+			summaryItem := notification_templates.BookBorrowReminderSummaryItem{
+				BookTitle:    bookBorrow.BookTitle,
+				BorrowerName: fmt.Sprintf("%s %s", bookBorrow.BorrowerFirstName, bookBorrow.BorrowerLastName),
+				DueAt:        bookBorrow.BookBorrow.DueAt,
+				Threshold:    threshold.Name,
+			}
+
+			lenderSummaries[bookBorrow.OwnerID] = append(lenderSummaries[bookBorrow.OwnerID], summaryItem)
+			lenders[bookBorrow.OwnerID] = bookBorrow
+		}
+	}
+
+	for ownerID, summaryItems := range lenderSummaries {
+		lender := lenders[ownerID]
+		lenderName := fmt.Sprintf("%s %s", lender.OwnerFirstName, lender.OwnerLastName)
+
+		reminderSummaryData := notification_templates.BookBorrowReminderSummaryData{
+			LenderName: lenderName,
+			Reminders:  summaryItems,
+		}
+
+		renderedSummary, renderSummaryError := apiConfig.Templates.Render(notification_templates.BookBorrowReminderSummaryTemplate, lender.OwnerLocale, reminderSummaryData)
+
+		if renderSummaryError != nil {
+			log.Printf("failed to render reminder summary for %s: %s", lender.OwnerEmail, renderSummaryError)
+
+			continue
+		}
+
+		sendSummaryError := apiConfig.Notifier.Send(ctx, renderedSummary.Notification(apiConfig.NotificationSender, fmt.Sprintf("%s <%s>", lenderName, lender.OwnerEmail)))
+
+		if sendSummaryError != nil {
+			log.Printf("failed to send reminder summary to %s: %s", lender.OwnerEmail, sendSummaryError)
+		}
+	}
+
+	return nil
+}
+
+// Returns true when the reminder was claimed and sent.
+func sendBookBorrowReminder(ctx context.Context, apiConfig *common.APIConfig, threshold BookBorrowReminderThreshold, bookBorrow database.GetBookBorrowsDueForReminderRow) bool {
+	ownerName := fmt.Sprintf("%s %s", bookBorrow.OwnerFirstName, bookBorrow.OwnerLastName)
+	borrowerName := fmt.Sprintf("%s %s", bookBorrow.BorrowerFirstName, bookBorrow.BorrowerLastName)
+
+	bookBorrowReminderData := notification_templates.BookBorrowReminderData{
+		OwnerName:    ownerName,
+		BorrowerName: borrowerName,
+		BookTitle:    bookBorrow.BookTitle,
+		DueAt:        bookBorrow.BookBorrow.DueAt,
+	}
+
+	// Render before claiming so a broken template doesn't mark the reminder as sent.
+	renderedReminder, renderError := apiConfig.Templates.Render(threshold.Template, bookBorrow.BorrowerLocale, bookBorrowReminderData)
+
+	if renderError != nil {
+		log.Printf("failed to render %s reminder for book borrow %s: %s", threshold.Name, bookBorrow.BookBorrow.ID, renderError)
+
+		return false
+	}
+
+	createBookBorrowReminderParams := database.CreateBookBorrowReminderParams{
+		ID:           uuid.New(),
+		Threshold:    threshold.Name,
+		BookBorrowID: bookBorrow.BookBorrow.ID,
+		DueAt:        bookBorrow.BookBorrow.DueAt,
+	}
+
+	bookBorrowReminder, createBookBorrowReminderError := apiConfig.DB.CreateBookBorrowReminder(ctx, createBookBorrowReminderParams)
+
+	if createBookBorrowReminderError != nil {
+		// sql.ErrNoRows means the reminder was already claimed.
+		if createBookBorrowReminderError != sql.ErrNoRows {
+			log.Printf("failed to record %s reminder for book borrow %s: %s", threshold.Name, bookBorrow.BookBorrow.ID, createBookBorrowReminderError)
+		}
+
+		return false
+	}
+
+	sendError := apiConfig.Notifier.Send(ctx, renderedReminder.Notification(fmt.Sprintf("%s <%s>", ownerName, bookBorrow.OwnerEmail), fmt.Sprintf("%s <%s>", borrowerName, bookBorrow.BorrowerEmail)))
+
+	if sendError != nil {
+		log.Printf("failed to send %s reminder for book borrow %s: %s", threshold.Name, bookBorrow.BookBorrow.ID, sendError)
+
+		// Release the claim so the next run tries again.
+		deleteBookBorrowReminderError := apiConfig.DB.DeleteBookBorrowReminder(ctx, bookBorrowReminder.ID)
+
+		if deleteBookBorrowReminderError != nil {
+			log.Printf("failed to release %s reminder for book borrow %s: %s", threshold.Name, bookBorrow.BookBorrow.ID, deleteBookBorrowReminderError)
+		}
+
+		return false
+	}
+
+	return true
 }
\ No newline at end of file
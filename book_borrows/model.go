@@ -4,7 +4,9 @@ import (
 	"database/sql"
 	"time"
 
+	"github.com/elorenzorodz/co-library/borrower_reputation"
 	"github.com/elorenzorodz/co-library/common"
+	"github.com/elorenzorodz/co-library/notification_templates"
 	"github.com/google/uuid"
 )
 
@@ -16,8 +18,128 @@ type BookBorrow struct {
 	ID uuid.UUID `json:"id"`
 	IssuedAt time.Time `json:"issuedAt"`
 	ReturnedAt sql.NullTime `json:"returnedAt"`
+	DueAt time.Time `json:"dueAt"`
+	Overdue bool `json:"overdue"`
+	ReturnStatus string `json:"return_status"`
+	ReturnConfirmedAt sql.NullTime `json:"returnConfirmedAt"`
+	ReturnDisputedAt sql.NullTime `json:"returnDisputedAt"`
+	// Set while the book is reported lost, until the claim about it is settled.
+	LostAt sql.NullTime `json:"lostAt"`
 	CreatedAt time.Time `json:"createdAt"`
 	UpdatedAt time.Time `json:"updatedAt"`
 	BookID uuid.UUID `json:"book_id"`
 	BorrowerID uuid.UUID `json:"borrower_id"`
+	BookCopyID uuid.UUID `json:"book_copy_id"`
 }
+
+// How many times a borrower can extend the same loan.
+const MaxBookBorrowRenewals = 2
+
+type BookBorrowRenewal struct {
+	ID            uuid.UUID `json:"id"`
+	PreviousDueAt time.Time `json:"previousDueAt"`
+	NewDueAt      time.Time `json:"newDueAt"`
+	CreatedAt     time.Time `json:"createdAt"`
+	BookBorrowID  uuid.UUID `json:"book_borrow_id"`
+}
+
+// A return is pending until the book owner confirms or disputes the handoff. Loans
+// written off after the book was lost are closed as lost instead.
+const (
+	BookBorrowReturnStatusPending   = "pending"
+	BookBorrowReturnStatusConfirmed = "confirmed"
+	BookBorrowReturnStatusDisputed  = "disputed"
+	BookBorrowReturnStatusLost      = "lost"
+)
+
+// Filters accepted by the borrow history endpoints, along with the pending and disputed return statuses.
+const (
+	BookBorrowStatusActive   = "active"
+	BookBorrowStatusReturned = "returned"
+	BookBorrowStatusOverdue  = "overdue"
+)
+
+type BorrowedBook struct {
+	BookBorrow
+	BookTitle  string `json:"book_title"`
+	LenderName string `json:"lender_name"`
+}
+
+type LentBook struct {
+	BookBorrow
+	BookTitle    string `json:"book_title"`
+	BorrowerName string `json:"borrower_name"`
+}
+
+// How often the scheduler looks for borrows that need a reminder.
+const BookBorrowReminderInterval = time.Hour
+
+// A borrow gets the reminder for a threshold once its due date falls between
+// DueAfter and DueBefore, both relative to when the reminder job runs.
+type BookBorrowReminderThreshold struct {
+	Name      string
+	Template  string
+	DueAfter  time.Duration
+	DueBefore time.Duration
+	// The last threshold has no lower bound so nothing slips past it.
+	Unbounded bool
+}
+
+var BookBorrowReminderThresholds = []BookBorrowReminderThreshold{
+	{
+		Name:      "due_soon",
+		Template:  notification_templates.BookBorrowDueSoonTemplate,
+		DueAfter:  0,
+		DueBefore: 48 * time.Hour,
+	},
+	{
+		Name:      "overdue",
+		Template:  notification_templates.BookBorrowOverdueTemplate,
+		DueAfter:  -7 * 24 * time.Hour,
+		DueBefore: 0,
+	},
+	{
+		Name:      "overdue_week",
+		Template:  notification_templates.BookBorrowOverdueWeekTemplate,
+		DueBefore: -7 * 24 * time.Hour,
+		Unbounded: true,
+	},
+}
+
+type IssueBookParameters struct {
+	LoanPeriodDays int32 `json:"loan_period_days"`
+}
+
+
+const (
+	BookBorrowRequestStatusRequested = "requested"
+	BookBorrowRequestStatusApproved  = "approved"
+	BookBorrowRequestStatusDeclined  = "declined"
+	BookBorrowRequestStatusIssued    = "issued"
+	BookBorrowRequestStatusReturned  = "returned"
+	BookBorrowRequestStatusCancelled = "cancelled"
+)
+
+type BookBorrowRequest struct {
+	ID           uuid.UUID     `json:"id"`
+	Status       string        `json:"status"`
+	RespondedAt  sql.NullTime  `json:"respondedAt"`
+	CreatedAt    time.Time     `json:"createdAt"`
+	UpdatedAt    time.Time     `json:"updatedAt"`
+	BookID       uuid.UUID     `json:"book_id"`
+	RequesterID  uuid.UUID     `json:"requester_id"`
+	BookBorrowID uuid.NullUUID `json:"book_borrow_id"`
+}
+
+type IncomingBookBorrowRequest struct {
+	BookBorrowRequest
+	BookTitle           string                                 `json:"book_title"`
+	RequesterName       string                                 `json:"requester_name"`
+	RequesterReputation borrower_reputation.BorrowerReputation `json:"requester_reputation"`
+}
+
+type OutgoingBookBorrowRequest struct {
+	BookBorrowRequest
+	BookTitle string `json:"book_title"`
+	OwnerName string `json:"owner_name"`
+}
\ No newline at end of file
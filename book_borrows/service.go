@@ -2,15 +2,28 @@ package book_borrows
 
 import (
 	"database/sql"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"log"
 	"net/http"
+	"time"
 
+	"github.com/elorenzorodz/co-library/book_policies"
+	"github.com/elorenzorodz/co-library/book_reservations"
+	"github.com/elorenzorodz/co-library/borrower_reputation"
 	"github.com/elorenzorodz/co-library/common"
 	"github.com/elorenzorodz/co-library/internal/database"
 	"github.com/google/uuid"
 	"github.com/gorilla/mux"
 )
 
+var (
+	errBookCopiesIssued      = errors.New("book copies issued")
+	errBookBorrowRequestUsed = errors.New("book borrow request used")
+)
+
 func (bookBorrowAPIConfig *BookBorrowAPIConfig) IssueBook(writer http.ResponseWriter, request *http.Request, userId uuid.UUID) {
 	vars := mux.Vars(request)
 	bookId, parseBookIdError := uuid.Parse(vars["bookId"])
@@ -21,6 +34,18 @@ func (bookBorrowAPIConfig *BookBorrowAPIConfig) IssueBook(writer http.ResponseWr
 		return
 	}
 
+	// The request body is optional, borrowers only send it to ask for a shorter loan.
+	issueBookParameters := IssueBookParameters{}
+
+	decoder := json.NewDecoder(request.Body)
+	decoderError := decoder.Decode(&issueBookParameters)
+
+	if decoderError != nil && !errors.Is(decoderError, io.EOF) {
+		common.ErrorResponse(writer, http.StatusBadRequest, fmt.Sprintf("error parsing JSON: %s", decoderError))
+
+		return
+	}
+
 	getBook, getBookError := bookBorrowAPIConfig.DB.GetBook(request.Context(), bookId)
 
 	if getBookError != nil {
@@ -40,28 +65,150 @@ func (bookBorrowAPIConfig *BookBorrowAPIConfig) IssueBook(writer http.ResponseWr
 		return
 	}
 
-	_, getBookBorrowError := bookBorrowAPIConfig.DB.GetBookBorrow(request.Context(), bookId)
+	// The owner may have changed who can borrow the book since the request was approved.
+	checkBorrowerStatus, checkBorrowerError := book_policies.CheckBookBorrower(request.Context(), bookBorrowAPIConfig.DB, getBook, userId)
+
+	if checkBorrowerError != nil {
+		common.ErrorResponse(writer, checkBorrowerStatus, checkBorrowerError.Error())
+
+		return
+	}
+
+	// The borrower's history may also have changed, checked before a copy is held for them.
+	checkReputationStatus, checkReputationError := borrower_reputation.CheckBorrowerReputation(request.Context(), bookBorrowAPIConfig.DB, getBook, userId)
+
+	if checkReputationError != nil {
+		common.ErrorResponse(writer, checkReputationStatus, checkReputationError.Error())
+
+		return
+	}
+
+	// Only borrowers whose request was approved by the book owner can take the book.
+	getOpenBookBorrowRequestParams := database.GetOpenBookBorrowRequestParams{
+		BookID:      bookId,
+		RequesterID: userId,
+	}
+
+	openBookBorrowRequest, getOpenBookBorrowRequestError := bookBorrowAPIConfig.DB.GetOpenBookBorrowRequest(request.Context(), getOpenBookBorrowRequestParams)
+
+	if getOpenBookBorrowRequestError != nil {
+		if getOpenBookBorrowRequestError == sql.ErrNoRows {
+			common.ErrorResponse(writer, http.StatusForbidden, "you need an approved borrow request from the book owner")
+		} else {
+			common.ErrorResponse(writer, http.StatusInternalServerError, fmt.Sprintf("error issuing book: %s", getOpenBookBorrowRequestError))
+		}
+
+		return
+	}
 
-	if getBookBorrowError != nil && getBookBorrowError != sql.ErrNoRows {
-		common.ErrorResponse(writer, http.StatusInternalServerError, fmt.Sprintf("error issuing book: %s", getBookBorrowError))
+	if openBookBorrowRequest.Status != BookBorrowRequestStatusApproved {
+		common.ErrorResponse(writer, http.StatusForbidden, fmt.Sprintf("your borrow request is %s, the book owner has to approve it first", openBookBorrowRequest.Status))
 
 		return
-	} else if getBookBorrowError == nil {
-		common.ErrorResponse(writer, http.StatusConflict, "book is currently issued to another borrower")
+	}
+
+	// When people are waiting for the book, the free copies go to them first.
+	heldBookReservations, holdBookError := book_reservations.HoldBookForNextInLine(request.Context(), &bookBorrowAPIConfig.APIConfig, bookId)
+
+	if holdBookError != nil {
+		common.ErrorResponse(writer, http.StatusInternalServerError, fmt.Sprintf("error issuing book: %s", holdBookError))
 
 		return
 	}
 
+	heldBookReservation, heldForBorrower := book_reservations.FindBookReservationForUser(heldBookReservations, userId)
+
+	if !heldForBorrower {
+		availableBookCopies, countAvailableBookCopiesError := bookBorrowAPIConfig.DB.CountAvailableBookCopies(request.Context(), bookId)
+
+		if countAvailableBookCopiesError != nil {
+			common.ErrorResponse(writer, http.StatusInternalServerError, fmt.Sprintf("error issuing book: %s", countAvailableBookCopiesError))
+
+			return
+		}
+
+		if availableBookCopies == 0 {
+			common.ErrorResponse(writer, http.StatusConflict, "all copies of this book are currently issued")
+
+			return
+		} else if availableBookCopies <= int64(len(heldBookReservations)) {
+			common.ErrorResponse(writer, http.StatusConflict, "book is being held for the next borrower in the waitlist")
+
+			return
+		}
+	}
+
+	loanPeriodDays := getBook.LoanPeriodDays
+
+	if issueBookParameters.LoanPeriodDays != 0 {
+		if issueBookParameters.LoanPeriodDays < 0 || issueBookParameters.LoanPeriodDays > getBook.LoanPeriodDays {
+			common.ErrorResponse(writer, http.StatusBadRequest, fmt.Sprintf("loan_period_days must be between 1 and %d for this book", getBook.LoanPeriodDays))
+
+			return
+		}
+
+		loanPeriodDays = issueBookParameters.LoanPeriodDays
+	}
+
 	issueBookParams := database.IssueBookParams{
 		ID:         uuid.New(),
 		BookID:     getBook.ID,
 		BorrowerID: userId,
+		DueAt:      LoanDueAt(time.Now().UTC(), loanPeriodDays),
 	}
 
-	issueBook, issueBookError := bookBorrowAPIConfig.DB.IssueBook(request.Context(), issueBookParams)
+	var issueBook database.BookBorrow
+
+	// The loan, the approval it uses up and the waitlist offer it fulfills change together,
+	// so one approval can't be issued twice. The book stays locked until then, and the
+	// insert itself skips copies offered to others, so a copy held for the waitlist
+	// can't be taken between the check above and the loan.
+	issueBookError := bookBorrowAPIConfig.InTransaction(request.Context(), func(querier common.Querier) error {
+		lockError := querier.LockBookForIssue(request.Context(), getBook.ID)
+
+		if lockError != nil {
+			return lockError
+		}
+
+		var issueError error
+
+		issueBook, issueError = querier.IssueBook(request.Context(), issueBookParams)
+
+		if issueError == sql.ErrNoRows {
+			return errBookCopiesIssued
+		} else if issueError != nil {
+			return issueError
+		}
+
+		markBookBorrowRequestIssuedParams := database.MarkBookBorrowRequestIssuedParams{
+			BookBorrowID: issueBook.ID,
+			ID:           openBookBorrowRequest.ID,
+		}
+
+		markedRequests, markError := querier.MarkBookBorrowRequestIssued(request.Context(), markBookBorrowRequestIssuedParams)
+
+		if markError != nil {
+			return markError
+		} else if markedRequests == 0 {
+			return errBookBorrowRequestUsed
+		}
+
+		if heldForBorrower {
+			return querier.FulfillBookReservation(request.Context(), heldBookReservation.ID)
+		}
+
+		return nil
+	})
 
 	if issueBookError != nil {
-		common.ErrorResponse(writer, http.StatusInternalServerError, fmt.Sprintf("error issuing book: %s", issueBookError))
+		switch {
+		case errors.Is(issueBookError, errBookCopiesIssued):
+			common.ErrorResponse(writer, http.StatusConflict, "all copies of this book are currently issued")
+		case errors.Is(issueBookError, errBookBorrowRequestUsed):
+			common.ErrorResponse(writer, http.StatusConflict, "your approved borrow request was already used for a loan of this book")
+		default:
+			common.ErrorResponse(writer, http.StatusInternalServerError, fmt.Sprintf("error issuing book: %s", issueBookError))
+		}
 
 		return
 	}
@@ -96,5 +243,506 @@ func (bookBorrowAPIConfig *BookBorrowAPIConfig) ReturnBook(writer http.ResponseW
 		return
 	}
 
+	// The loan stays open until the book owner confirms they got the book back.
 	common.JSONResponse(writer, http.StatusOK, DatabaseBookBorrowToBookBorrowJSON(returnBook))
+}
+
+func (bookBorrowAPIConfig *BookBorrowAPIConfig) ConfirmBookReturn(writer http.ResponseWriter, request *http.Request, userId uuid.UUID) {
+	vars := mux.Vars(request)
+	bookBorrowId, parseBookBorrowIdError := uuid.Parse(vars["bookBorrowId"])
+
+	if parseBookBorrowIdError != nil {
+		common.ErrorResponse(writer, http.StatusBadRequest, "invalid book borrow id")
+
+		return
+	}
+
+	confirmBookReturnParams := database.ConfirmBookReturnParams{
+		ID:     bookBorrowId,
+		UserID: userId,
+	}
+
+	confirmedBookBorrow, confirmBookReturnError := bookBorrowAPIConfig.DB.ConfirmBookReturn(request.Context(), confirmBookReturnParams)
+
+	if confirmBookReturnError != nil {
+		if confirmBookReturnError == sql.ErrNoRows {
+			common.ErrorResponse(writer, http.StatusBadRequest, "failed to confirm return: record not found, unauthorized, or not marked as returned")
+		} else {
+			common.ErrorResponse(writer, http.StatusInternalServerError, "failed to confirm return, please try again in a few minutes")
+		}
+
+		return
+	}
+
+	markBookBorrowRequestReturnedError := bookBorrowAPIConfig.DB.MarkBookBorrowRequestReturned(request.Context(), confirmedBookBorrow.ID)
+
+	if markBookBorrowRequestReturnedError != nil {
+		log.Printf("failed to mark borrow request of book borrow %s as returned: %s", confirmedBookBorrow.ID, markBookBorrowRequestReturnedError)
+	}
+
+	// Offer the copy to the next person in the waitlist.
+	_, holdBookError := book_reservations.HoldBookForNextInLine(request.Context(), &bookBorrowAPIConfig.APIConfig, confirmedBookBorrow.BookID)
+
+	if holdBookError != nil {
+		log.Printf("failed to offer book %s to the next reservation: %s", confirmedBookBorrow.BookID, holdBookError)
+	}
+
+	common.JSONResponse(writer, http.StatusOK, DatabaseBookBorrowToBookBorrowJSON(confirmedBookBorrow))
+}
+
+func (bookBorrowAPIConfig *BookBorrowAPIConfig) DisputeBookReturn(writer http.ResponseWriter, request *http.Request, userId uuid.UUID) {
+	vars := mux.Vars(request)
+	bookBorrowId, parseBookBorrowIdError := uuid.Parse(vars["bookBorrowId"])
+
+	if parseBookBorrowIdError != nil {
+		common.ErrorResponse(writer, http.StatusBadRequest, "invalid book borrow id")
+
+		return
+	}
+
+	disputeBookReturnParams := database.DisputeBookReturnParams{
+		ID:     bookBorrowId,
+		UserID: userId,
+	}
+
+	disputedBookBorrow, disputeBookReturnError := bookBorrowAPIConfig.DB.DisputeBookReturn(request.Context(), disputeBookReturnParams)
+
+	if disputeBookReturnError != nil {
+		if disputeBookReturnError == sql.ErrNoRows {
+			common.ErrorResponse(writer, http.StatusBadRequest, "failed to dispute return: record not found, unauthorized, or not awaiting confirmation")
+		} else {
+			common.ErrorResponse(writer, http.StatusInternalServerError, "failed to dispute return, please try again in a few minutes")
+		}
+
+		return
+	}
+
+	common.JSONResponse(writer, http.StatusOK, DatabaseBookBorrowToBookBorrowJSON(disputedBookBorrow))
+}
+
+func (bookBorrowAPIConfig *BookBorrowAPIConfig) CreateBookBorrowRequest(writer http.ResponseWriter, request *http.Request, userId uuid.UUID) {
+	vars := mux.Vars(request)
+	bookId, parseBookIdError := uuid.Parse(vars["bookId"])
+
+	if parseBookIdError != nil {
+		common.ErrorResponse(writer, http.StatusBadRequest, "invalid book id")
+
+		return
+	}
+
+	getBook, getBookError := bookBorrowAPIConfig.DB.GetBook(request.Context(), bookId)
+
+	if getBookError != nil {
+		if getBookError == sql.ErrNoRows {
+			common.ErrorResponse(writer, http.StatusNotFound, "book not found")
+		} else {
+			common.ErrorResponse(writer, http.StatusInternalServerError, "failed to get book details, please try again in a few minutes")
+		}
+
+		return
+	}
+
+	if userId == getBook.UserID {
+		common.ErrorResponse(writer, http.StatusForbidden, "you cannot borrow your own book")
+
+		return
+	}
+
+	checkBorrowerStatus, checkBorrowerError := book_policies.CheckBookBorrower(request.Context(), bookBorrowAPIConfig.DB, getBook, userId)
+
+	if checkBorrowerError != nil {
+		common.ErrorResponse(writer, checkBorrowerStatus, checkBorrowerError.Error())
+
+		return
+	}
+
+	// Owners who set a minimum reputation don't get requests from borrowers below it.
+	checkReputationStatus, checkReputationError := borrower_reputation.CheckBorrowerReputation(request.Context(), bookBorrowAPIConfig.DB, getBook, userId)
+
+	if checkReputationError != nil {
+		common.ErrorResponse(writer, checkReputationStatus, checkReputationError.Error())
+
+		return
+	}
+
+	// Check if the borrower already has an open request for this book.
+	getOpenBookBorrowRequestParams := database.GetOpenBookBorrowRequestParams{
+		BookID:      bookId,
+		RequesterID: userId,
+	}
+
+	_, getOpenBookBorrowRequestError := bookBorrowAPIConfig.DB.GetOpenBookBorrowRequest(request.Context(), getOpenBookBorrowRequestParams)
+
+	if getOpenBookBorrowRequestError != nil && getOpenBookBorrowRequestError != sql.ErrNoRows {
+		common.ErrorResponse(writer, http.StatusInternalServerError, fmt.Sprintf("error requesting book: %s", getOpenBookBorrowRequestError))
+
+		return
+	} else if getOpenBookBorrowRequestError == nil {
+		common.ErrorResponse(writer, http.StatusConflict, "you already have an open borrow request for this book")
+
+		return
+	}
+
+	createBookBorrowRequestParams := database.CreateBookBorrowRequestParams{
+		ID:          uuid.New(),
+		BookID:      bookId,
+		RequesterID: userId,
+	}
+
+	newBookBorrowRequest, createBookBorrowRequestError := bookBorrowAPIConfig.DB.CreateBookBorrowRequest(request.Context(), createBookBorrowRequestParams)
+
+	if createBookBorrowRequestError != nil {
+		common.ErrorResponse(writer, http.StatusInternalServerError, fmt.Sprintf("error requesting book: %s", createBookBorrowRequestError))
+
+		return
+	}
+
+	common.JSONResponse(writer, http.StatusCreated, DatabaseBookBorrowRequestToBookBorrowRequestJSON(newBookBorrowRequest))
+}
+
+func (bookBorrowAPIConfig *BookBorrowAPIConfig) GetBorrowedBooks(writer http.ResponseWriter, request *http.Request, userId uuid.UUID) {
+	status, parseStatusError := ParseBookBorrowStatusFilter(request.URL.Query().Get("status"))
+
+	if parseStatusError != nil {
+		common.ErrorResponse(writer, http.StatusBadRequest, parseStatusError.Error())
+
+		return
+	}
+
+	pageParameters, parsePageError := common.ParsePageParameters(request.URL.Query())
+
+	if parsePageError != nil {
+		common.ErrorResponse(writer, http.StatusBadRequest, parsePageError.Error())
+
+		return
+	}
+
+	cursorIssuedAt, parseCursorError := ParseBookBorrowCursor(pageParameters)
+
+	if parseCursorError != nil {
+		common.ErrorResponse(writer, http.StatusBadRequest, parseCursorError.Error())
+
+		return
+	}
+
+	getBorrowedBooksParams := database.GetBorrowedBooksParams{
+		BorrowerID:     userId,
+		Status:         status,
+		CursorIssuedAt: cursorIssuedAt,
+		CursorID:       pageParameters.CursorID(),
+		PageLimit:      pageParameters.QueryLimit(),
+	}
+
+	borrowedBooks, getBorrowedBooksError := bookBorrowAPIConfig.DB.GetBorrowedBooks(request.Context(), getBorrowedBooksParams)
+
+	if getBorrowedBooksError != nil {
+		common.ErrorResponse(writer, http.StatusInternalServerError, fmt.Sprintf("error getting borrowed books: %s", getBorrowedBooksError))
+
+		return
+	}
+
+	borrowedBooks, nextCursor := common.SplitPage(borrowedBooks, pageParameters, BorrowedBookCursor)
+
+	common.JSONResponse(writer, http.StatusOK, common.Page[BorrowedBook]{Data: DatabaseBorrowedBooksToJSON(borrowedBooks), NextCursor: nextCursor})
+}
+
+func (bookBorrowAPIConfig *BookBorrowAPIConfig) GetLentBooks(writer http.ResponseWriter, request *http.Request, userId uuid.UUID) {
+	status, parseStatusError := ParseBookBorrowStatusFilter(request.URL.Query().Get("status"))
+
+	if parseStatusError != nil {
+		common.ErrorResponse(writer, http.StatusBadRequest, parseStatusError.Error())
+
+		return
+	}
+
+	pageParameters, parsePageError := common.ParsePageParameters(request.URL.Query())
+
+	if parsePageError != nil {
+		common.ErrorResponse(writer, http.StatusBadRequest, parsePageError.Error())
+
+		return
+	}
+
+	cursorIssuedAt, parseCursorError := ParseBookBorrowCursor(pageParameters)
+
+	if parseCursorError != nil {
+		common.ErrorResponse(writer, http.StatusBadRequest, parseCursorError.Error())
+
+		return
+	}
+
+	getLentBooksParams := database.GetLentBooksParams{
+		UserID:         userId,
+		Status:         status,
+		CursorIssuedAt: cursorIssuedAt,
+		CursorID:       pageParameters.CursorID(),
+		PageLimit:      pageParameters.QueryLimit(),
+	}
+
+	lentBooks, getLentBooksError := bookBorrowAPIConfig.DB.GetLentBooks(request.Context(), getLentBooksParams)
+
+	if getLentBooksError != nil {
+		common.ErrorResponse(writer, http.StatusInternalServerError, fmt.Sprintf("error getting lent books: %s", getLentBooksError))
+
+		return
+	}
+
+	lentBooks, nextCursor := common.SplitPage(lentBooks, pageParameters, LentBookCursor)
+
+	common.JSONResponse(writer, http.StatusOK, common.Page[LentBook]{Data: DatabaseLentBooksToJSON(lentBooks), NextCursor: nextCursor})
+}
+
+func (bookBorrowAPIConfig *BookBorrowAPIConfig) GetIncomingBookBorrowRequests(writer http.ResponseWriter, request *http.Request, userId uuid.UUID) {
+	status, parseStatusError := ParseBookBorrowRequestStatusFilter(request.URL.Query().Get("status"))
+
+	if parseStatusError != nil {
+		common.ErrorResponse(writer, http.StatusBadRequest, parseStatusError.Error())
+
+		return
+	}
+
+	getIncomingBookBorrowRequestsParams := database.GetIncomingBookBorrowRequestsParams{
+		UserID: userId,
+		Status: status,
+	}
+
+	incomingBookBorrowRequests, getIncomingBookBorrowRequestsError := bookBorrowAPIConfig.DB.GetIncomingBookBorrowRequests(request.Context(), getIncomingBookBorrowRequestsParams)
+
+	if getIncomingBookBorrowRequestsError != nil {
+		common.ErrorResponse(writer, http.StatusInternalServerError, fmt.Sprintf("error getting borrow requests: %s", getIncomingBookBorrowRequestsError))
+
+		return
+	}
+
+	common.JSONResponse(writer, http.StatusOK, DatabaseIncomingBookBorrowRequestsToJSON(incomingBookBorrowRequests))
+}
+
+func (bookBorrowAPIConfig *BookBorrowAPIConfig) GetOutgoingBookBorrowRequests(writer http.ResponseWriter, request *http.Request, userId uuid.UUID) {
+	status, parseStatusError := ParseBookBorrowRequestStatusFilter(request.URL.Query().Get("status"))
+
+	if parseStatusError != nil {
+		common.ErrorResponse(writer, http.StatusBadRequest, parseStatusError.Error())
+
+		return
+	}
+
+	getOutgoingBookBorrowRequestsParams := database.GetOutgoingBookBorrowRequestsParams{
+		RequesterID: userId,
+		Status:      status,
+	}
+
+	outgoingBookBorrowRequests, getOutgoingBookBorrowRequestsError := bookBorrowAPIConfig.DB.GetOutgoingBookBorrowRequests(request.Context(), getOutgoingBookBorrowRequestsParams)
+
+	if getOutgoingBookBorrowRequestsError != nil {
+		common.ErrorResponse(writer, http.StatusInternalServerError, fmt.Sprintf("error getting borrow requests: %s", getOutgoingBookBorrowRequestsError))
+
+		return
+	}
+
+	common.JSONResponse(writer, http.StatusOK, DatabaseOutgoingBookBorrowRequestsToJSON(outgoingBookBorrowRequests))
+}
+
+func (bookBorrowAPIConfig *BookBorrowAPIConfig) ApproveBookBorrowRequest(writer http.ResponseWriter, request *http.Request, userId uuid.UUID) {
+	bookBorrowAPIConfig.respondToBookBorrowRequest(writer, request, userId, BookBorrowRequestStatusApproved)
+}
+
+func (bookBorrowAPIConfig *BookBorrowAPIConfig) DeclineBookBorrowRequest(writer http.ResponseWriter, request *http.Request, userId uuid.UUID) {
+	bookBorrowAPIConfig.respondToBookBorrowRequest(writer, request, userId, BookBorrowRequestStatusDeclined)
+}
+
+func (bookBorrowAPIConfig *BookBorrowAPIConfig) respondToBookBorrowRequest(writer http.ResponseWriter, request *http.Request, userId uuid.UUID, status string) {
+	vars := mux.Vars(request)
+	bookBorrowRequestId, parseBookBorrowRequestIdError := uuid.Parse(vars["bookBorrowRequestId"])
+
+	if parseBookBorrowRequestIdError != nil {
+		common.ErrorResponse(writer, http.StatusBadRequest, "invalid book borrow request id")
+
+		return
+	}
+
+	// Only the book owner can respond, and only to requests that are still waiting.
+	respondToBookBorrowRequestParams := database.RespondToBookBorrowRequestParams{
+		Status: status,
+		ID:     bookBorrowRequestId,
+		UserID: userId,
+	}
+
+	bookBorrowRequest, respondToBookBorrowRequestError := bookBorrowAPIConfig.DB.RespondToBookBorrowRequest(request.Context(), respondToBookBorrowRequestParams)
+
+	if respondToBookBorrowRequestError != nil {
+		if respondToBookBorrowRequestError == sql.ErrNoRows {
+			common.ErrorResponse(writer, http.StatusBadRequest, "failed to respond to borrow request: record not found, unauthorized, or already responded")
+		} else {
+			common.ErrorResponse(writer, http.StatusInternalServerError, "failed to respond to borrow request, please try again in a few minutes")
+		}
+
+		return
+	}
+
+	common.JSONResponse(writer, http.StatusOK, DatabaseBookBorrowRequestToBookBorrowRequestJSON(bookBorrowRequest))
+}
+
+func (bookBorrowAPIConfig *BookBorrowAPIConfig) CancelBookBorrowRequest(writer http.ResponseWriter, request *http.Request, userId uuid.UUID) {
+	vars := mux.Vars(request)
+	bookBorrowRequestId, parseBookBorrowRequestIdError := uuid.Parse(vars["bookBorrowRequestId"])
+
+	if parseBookBorrowRequestIdError != nil {
+		common.ErrorResponse(writer, http.StatusBadRequest, "invalid book borrow request id")
+
+		return
+	}
+
+	cancelBookBorrowRequestParams := database.CancelBookBorrowRequestParams{
+		ID:          bookBorrowRequestId,
+		RequesterID: userId,
+	}
+
+	bookBorrowRequest, cancelBookBorrowRequestError := bookBorrowAPIConfig.DB.CancelBookBorrowRequest(request.Context(), cancelBookBorrowRequestParams)
+
+	if cancelBookBorrowRequestError != nil {
+		if cancelBookBorrowRequestError == sql.ErrNoRows {
+			common.ErrorResponse(writer, http.StatusBadRequest, "failed to cancel borrow request: record not found, unauthorized, or already closed")
+		} else {
+			common.ErrorResponse(writer, http.StatusInternalServerError, "failed to cancel borrow request, please try again in a few minutes")
+		}
+
+		return
+	}
+
+	common.JSONResponse(writer, http.StatusOK, DatabaseBookBorrowRequestToBookBorrowRequestJSON(bookBorrowRequest))
+}
+
+func (bookBorrowAPIConfig *BookBorrowAPIConfig) RenewBookBorrow(writer http.ResponseWriter, request *http.Request, userId uuid.UUID) {
+	vars := mux.Vars(request)
+	bookBorrowId, parseBookBorrowIdError := uuid.Parse(vars["bookBorrowId"])
+
+	if parseBookBorrowIdError != nil {
+		common.ErrorResponse(writer, http.StatusBadRequest, "invalid book borrow id")
+
+		return
+	}
+
+	getBookBorrow, getBookBorrowError := bookBorrowAPIConfig.DB.GetBookBorrowByID(request.Context(), bookBorrowId)
+
+	if getBookBorrowError != nil {
+		if getBookBorrowError == sql.ErrNoRows {
+			common.ErrorResponse(writer, http.StatusNotFound, "book borrow not found")
+		} else {
+			common.ErrorResponse(writer, http.StatusInternalServerError, "failed to get book borrow details, please try again in a few minutes")
+		}
+
+		return
+	}
+
+	if getBookBorrow.BorrowerID != userId {
+		common.ErrorResponse(writer, http.StatusForbidden, "you can only renew your own loans")
+
+		return
+	}
+
+	if getBookBorrow.ReturnedAt.Valid {
+		common.ErrorResponse(writer, http.StatusBadRequest, "book has already been returned")
+
+		return
+	}
+
+	// Renewing would skip the people waiting for the book.
+	activeBookReservations, countActiveBookReservationsError := bookBorrowAPIConfig.DB.CountActiveBookReservations(request.Context(), getBookBorrow.BookID)
+
+	if countActiveBookReservationsError != nil {
+		common.ErrorResponse(writer, http.StatusInternalServerError, fmt.Sprintf("error renewing loan: %s", countActiveBookReservationsError))
+
+		return
+	}
+
+	if activeBookReservations > 0 {
+		common.ErrorResponse(writer, http.StatusConflict, "other borrowers are waiting for this book, please return it on time")
+
+		return
+	}
+
+	bookBorrowRenewals, countBookBorrowRenewalsError := bookBorrowAPIConfig.DB.CountBookBorrowRenewals(request.Context(), bookBorrowId)
+
+	if countBookBorrowRenewalsError != nil {
+		common.ErrorResponse(writer, http.StatusInternalServerError, fmt.Sprintf("error renewing loan: %s", countBookBorrowRenewalsError))
+
+		return
+	}
+
+	if bookBorrowRenewals >= MaxBookBorrowRenewals {
+		common.ErrorResponse(writer, http.StatusConflict, fmt.Sprintf("loan has already been renewed the maximum of %d times", MaxBookBorrowRenewals))
+
+		return
+	}
+
+	getBook, getBookError := bookBorrowAPIConfig.DB.GetBook(request.Context(), getBookBorrow.BookID)
+
+	if getBookError != nil {
+		common.ErrorResponse(writer, http.StatusInternalServerError, "failed to get book details, please try again in a few minutes")
+
+		return
+	}
+
+	renewBookBorrowParams := database.RenewBookBorrowParams{
+		ID:            uuid.New(),
+		PreviousDueAt: getBookBorrow.DueAt,
+		NewDueAt:      LoanDueAt(getBookBorrow.DueAt, getBook.LoanPeriodDays),
+		BookBorrowID:  bookBorrowId,
+		BorrowerID:    userId,
+	}
+
+	bookBorrowRenewal, renewBookBorrowError := bookBorrowAPIConfig.DB.RenewBookBorrow(request.Context(), renewBookBorrowParams)
+
+	if renewBookBorrowError != nil {
+		if renewBookBorrowError == sql.ErrNoRows {
+			common.ErrorResponse(writer, http.StatusConflict, "loan was changed while renewing, please try again")
+		} else {
+			common.ErrorResponse(writer, http.StatusInternalServerError, "failed to renew loan, please try again in a few minutes")
+		}
+
+		return
+	}
+
+	common.JSONResponse(writer, http.StatusOK, DatabaseBookBorrowRenewalToBookBorrowRenewalJSON(bookBorrowRenewal))
+}
+
+func (bookBorrowAPIConfig *BookBorrowAPIConfig) GetBookBorrowRenewals(writer http.ResponseWriter, request *http.Request, userId uuid.UUID) {
+	vars := mux.Vars(request)
+	bookBorrowId, parseBookBorrowIdError := uuid.Parse(vars["bookBorrowId"])
+
+	if parseBookBorrowIdError != nil {
+		common.ErrorResponse(writer, http.StatusBadRequest, "invalid book borrow id")
+
+		return
+	}
+
+	// The owner is looked up with the loan, so it still shows once the book is in the trash.
+	getBookBorrow, getBookBorrowError := bookBorrowAPIConfig.DB.GetBookBorrowWithOwner(request.Context(), bookBorrowId)
+
+	if getBookBorrowError != nil {
+		if getBookBorrowError == sql.ErrNoRows {
+			common.ErrorResponse(writer, http.StatusNotFound, "book borrow not found")
+		} else {
+			common.ErrorResponse(writer, http.StatusInternalServerError, "failed to get book borrow details, please try again in a few minutes")
+		}
+
+		return
+	}
+
+	// Only the borrower and the book owner can see the renewal history.
+	if getBookBorrow.BookBorrow.BorrowerID != userId && getBookBorrow.OwnerID != userId {
+		common.ErrorResponse(writer, http.StatusNotFound, "book borrow not found")
+
+		return
+	}
+
+	bookBorrowRenewals, getBookBorrowRenewalsError := bookBorrowAPIConfig.DB.GetBookBorrowRenewals(request.Context(), bookBorrowId)
+
+	if getBookBorrowRenewalsError != nil {
+		common.ErrorResponse(writer, http.StatusInternalServerError, fmt.Sprintf("error getting loan renewals: %s", getBookBorrowRenewalsError))
+
+		return
+	}
+
+	common.JSONResponse(writer, http.StatusOK, DatabaseBookBorrowRenewalsToBookBorrowRenewalsJSON(bookBorrowRenewals))
 }
\ No newline at end of file
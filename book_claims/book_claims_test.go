@@ -0,0 +1,750 @@
+package book_claims
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/png"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/elorenzorodz/co-library/common"
+	"github.com/elorenzorodz/co-library/internal/database"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+type MockQueries struct {
+	*common.BaseMock
+
+	GetBookBorrowWithOwnerFunc        func(ctx context.Context, id uuid.UUID) (database.GetBookBorrowWithOwnerRow, error)
+	CreateBookConditionReportFunc     func(ctx context.Context, arg database.CreateBookConditionReportParams) (database.BookConditionReport, error)
+	ReportBookBorrowLostFunc          func(ctx context.Context, id uuid.UUID) (database.BookBorrow, error)
+	CreateBookClaimFunc               func(ctx context.Context, arg database.CreateBookClaimParams) (database.BookClaim, error)
+	GetBookClaimFunc                  func(ctx context.Context, id uuid.UUID) (database.GetBookClaimRow, error)
+	ResolveBookClaimFunc              func(ctx context.Context, arg database.ResolveBookClaimParams) (database.BookClaim, error)
+	ClearBookBorrowLostFunc           func(ctx context.Context, id uuid.UUID) error
+	CloseLostBookBorrowFunc           func(ctx context.Context, id uuid.UUID) (int64, error)
+	MarkBookCopyLostFunc              func(ctx context.Context, id uuid.UUID) error
+	MarkBookBorrowRequestReturnedFunc func(ctx context.Context, bookBorrowID uuid.UUID) error
+	CreateBookClaimCommentFunc        func(ctx context.Context, arg database.CreateBookClaimCommentParams) (database.BookClaimComment, error)
+}
+
+func (mockQueries *MockQueries) GetBookBorrowWithOwner(ctx context.Context, id uuid.UUID) (database.GetBookBorrowWithOwnerRow, error) {
+	if mockQueries.GetBookBorrowWithOwnerFunc != nil {
+		return mockQueries.GetBookBorrowWithOwnerFunc(ctx, id)
+	}
+
+	return mockQueries.BaseMock.GetBookBorrowWithOwner(ctx, id)
+}
+
+func (mockQueries *MockQueries) CreateBookConditionReport(ctx context.Context, arg database.CreateBookConditionReportParams) (database.BookConditionReport, error) {
+	if mockQueries.CreateBookConditionReportFunc != nil {
+		return mockQueries.CreateBookConditionReportFunc(ctx, arg)
+	}
+
+	return mockQueries.BaseMock.CreateBookConditionReport(ctx, arg)
+}
+
+func (mockQueries *MockQueries) ReportBookBorrowLost(ctx context.Context, id uuid.UUID) (database.BookBorrow, error) {
+	if mockQueries.ReportBookBorrowLostFunc != nil {
+		return mockQueries.ReportBookBorrowLostFunc(ctx, id)
+	}
+
+	return mockQueries.BaseMock.ReportBookBorrowLost(ctx, id)
+}
+
+func (mockQueries *MockQueries) CreateBookClaim(ctx context.Context, arg database.CreateBookClaimParams) (database.BookClaim, error) {
+	if mockQueries.CreateBookClaimFunc != nil {
+		return mockQueries.CreateBookClaimFunc(ctx, arg)
+	}
+
+	return mockQueries.BaseMock.CreateBookClaim(ctx, arg)
+}
+
+func (mockQueries *MockQueries) GetBookClaim(ctx context.Context, id uuid.UUID) (database.GetBookClaimRow, error) {
+	if mockQueries.GetBookClaimFunc != nil {
+		return mockQueries.GetBookClaimFunc(ctx, id)
+	}
+
+	return mockQueries.BaseMock.GetBookClaim(ctx, id)
+}
+
+func (mockQueries *MockQueries) ResolveBookClaim(ctx context.Context, arg database.ResolveBookClaimParams) (database.BookClaim, error) {
+	if mockQueries.ResolveBookClaimFunc != nil {
+		return mockQueries.ResolveBookClaimFunc(ctx, arg)
+	}
+
+	return mockQueries.BaseMock.ResolveBookClaim(ctx, arg)
+}
+
+func (mockQueries *MockQueries) ClearBookBorrowLost(ctx context.Context, id uuid.UUID) error {
+	if mockQueries.ClearBookBorrowLostFunc != nil {
+		return mockQueries.ClearBookBorrowLostFunc(ctx, id)
+	}
+
+	return mockQueries.BaseMock.ClearBookBorrowLost(ctx, id)
+}
+
+func (mockQueries *MockQueries) CloseLostBookBorrow(ctx context.Context, id uuid.UUID) (int64, error) {
+	if mockQueries.CloseLostBookBorrowFunc != nil {
+		return mockQueries.CloseLostBookBorrowFunc(ctx, id)
+	}
+
+	return mockQueries.BaseMock.CloseLostBookBorrow(ctx, id)
+}
+
+func (mockQueries *MockQueries) MarkBookCopyLost(ctx context.Context, id uuid.UUID) error {
+	if mockQueries.MarkBookCopyLostFunc != nil {
+		return mockQueries.MarkBookCopyLostFunc(ctx, id)
+	}
+
+	return mockQueries.BaseMock.MarkBookCopyLost(ctx, id)
+}
+
+func (mockQueries *MockQueries) MarkBookBorrowRequestReturned(ctx context.Context, bookBorrowID uuid.UUID) error {
+	if mockQueries.MarkBookBorrowRequestReturnedFunc != nil {
+		return mockQueries.MarkBookBorrowRequestReturnedFunc(ctx, bookBorrowID)
+	}
+
+	return mockQueries.BaseMock.MarkBookBorrowRequestReturned(ctx, bookBorrowID)
+}
+
+func (mockQueries *MockQueries) CreateBookClaimComment(ctx context.Context, arg database.CreateBookClaimCommentParams) (database.BookClaimComment, error) {
+	if mockQueries.CreateBookClaimCommentFunc != nil {
+		return mockQueries.CreateBookClaimCommentFunc(ctx, arg)
+	}
+
+	return mockQueries.BaseMock.CreateBookClaimComment(ctx, arg)
+}
+
+// Keeps what was stored and remembers what was deleted.
+type memoryBlobStore struct {
+	blobs       map[string][]byte
+	deletedKeys []string
+}
+
+func (memoryBlobStore *memoryBlobStore) Put(ctx context.Context, key string, contentType string, content []byte) (string, error) {
+	memoryBlobStore.blobs[key] = content
+
+	return "https://cdn.example.com/" + key, nil
+}
+
+func (memoryBlobStore *memoryBlobStore) Delete(ctx context.Context, key string) error {
+	delete(memoryBlobStore.blobs, key)
+	memoryBlobStore.deletedKeys = append(memoryBlobStore.deletedKeys, key)
+
+	return nil
+}
+
+type testLoan struct {
+	ownerID    uuid.UUID
+	borrowerID uuid.UUID
+	row        database.GetBookBorrowWithOwnerRow
+}
+
+func newTestLoan(returned bool) testLoan {
+	ownerID, borrowerID := uuid.New(), uuid.New()
+	issuedAt := time.Now().Add(-72 * time.Hour)
+
+	bookBorrow := database.BookBorrow{
+		ID:         uuid.New(),
+		IssuedAt:   issuedAt,
+		DueAt:      issuedAt.AddDate(0, 0, 14),
+		BookID:     uuid.New(),
+		BorrowerID: borrowerID,
+		BookCopyID: uuid.New(),
+	}
+
+	if returned {
+		bookBorrow.ReturnedAt = sql.NullTime{Time: time.Now(), Valid: true}
+		bookBorrow.ReturnStatus = sql.NullString{String: "pending", Valid: true}
+	}
+
+	return testLoan{
+		ownerID:    ownerID,
+		borrowerID: borrowerID,
+		row:        database.GetBookBorrowWithOwnerRow{BookBorrow: bookBorrow, OwnerID: ownerID, BookTitle: "Dune"},
+	}
+}
+
+func (loan testLoan) getBookBorrowWithOwner(ctx context.Context, id uuid.UUID) (database.GetBookBorrowWithOwnerRow, error) {
+	if id != loan.row.BookBorrow.ID {
+		return database.GetBookBorrowWithOwnerRow{}, sql.ErrNoRows
+	}
+
+	return loan.row, nil
+}
+
+func (loan testLoan) claim(kind string) database.GetBookClaimRow {
+	return database.GetBookClaimRow{
+		BookClaim: database.BookClaim{
+			ID:           uuid.New(),
+			Kind:         kind,
+			Status:       BookClaimStatusOpen,
+			CreatedAt:    time.Now(),
+			UpdatedAt:    time.Now(),
+			BookBorrowID: loan.row.BookBorrow.ID,
+			OpenedBy:     loan.ownerID,
+		},
+		BorrowerID: loan.borrowerID,
+		BookCopyID: loan.row.BookBorrow.BookCopyID,
+		OwnerID:    loan.ownerID,
+		BookTitle:  "Dune",
+	}
+}
+
+func createBookConditionReport(arg database.CreateBookConditionReportParams) (database.BookConditionReport, error) {
+	return database.BookConditionReport{
+		ID:           arg.ID,
+		Stage:        arg.Stage,
+		Condition:    arg.Condition,
+		Notes:        arg.Notes,
+		PhotoUrl:     arg.PhotoUrl,
+		PhotoKey:     arg.PhotoKey,
+		CreatedAt:    time.Now(),
+		BookBorrowID: arg.BookBorrowID,
+		ReporterID:   arg.ReporterID,
+	}, nil
+}
+
+func createBookClaim(ctx context.Context, arg database.CreateBookClaimParams) (database.BookClaim, error) {
+	return database.BookClaim{
+		ID:           arg.ID,
+		Kind:         arg.Kind,
+		Status:       BookClaimStatusOpen,
+		Description:  arg.Description,
+		CreatedAt:    time.Now(),
+		UpdatedAt:    time.Now(),
+		BookBorrowID: arg.BookBorrowID,
+		OpenedBy:     arg.OpenedBy,
+	}, nil
+}
+
+func jsonRequest(method string, target string, body string, vars map[string]string) *http.Request {
+	request := httptest.NewRequest(method, target, strings.NewReader(body))
+
+	return mux.SetURLVars(request, vars)
+}
+
+func photoRequest(t *testing.T, bookBorrowId uuid.UUID, fields map[string]string, photo []byte) *http.Request {
+	t.Helper()
+
+	var body bytes.Buffer
+	multipartWriter := multipart.NewWriter(&body)
+
+	for name, value := range fields {
+		multipartWriter.WriteField(name, value)
+	}
+
+	formFile, _ := multipartWriter.CreateFormFile(BookConditionPhotoFormField, "photo.bin")
+	formFile.Write(photo)
+	multipartWriter.Close()
+
+	request := httptest.NewRequest(http.MethodPost, fmt.Sprintf("/api/v1/books/borrows/%s/condition", bookBorrowId), &body)
+	request.Header.Set("Content-Type", multipartWriter.FormDataContentType())
+
+	return mux.SetURLVars(request, map[string]string{"bookBorrowId": bookBorrowId.String()})
+}
+
+func encodeTestPhoto(t *testing.T) []byte {
+	t.Helper()
+
+	var photoBuffer bytes.Buffer
+
+	if err := png.Encode(&photoBuffer, image.NewGray(image.Rect(0, 0, 40, 30))); err != nil {
+		t.Fatal(err)
+	}
+
+	return photoBuffer.Bytes()
+}
+
+func TestCreateBookConditionReport(tTesting *testing.T) {
+	// 1. Success: the borrower notes the condition as the book is handed over.
+	tTesting.Run("IssueStage", func(t *testing.T) {
+		loan := newTestLoan(false)
+
+		mockQueries := &MockQueries{
+			BaseMock:                   common.NewBaseMock(),
+			GetBookBorrowWithOwnerFunc: loan.getBookBorrowWithOwner,
+			CreateBookConditionReportFunc: func(ctx context.Context, arg database.CreateBookConditionReportParams) (database.BookConditionReport, error) {
+				if arg.ReporterID != loan.borrowerID || arg.Notes != "Spine creased" || arg.PhotoKey != "" {
+					t.Errorf("Unexpected report: %+v", arg)
+				}
+
+				return createBookConditionReport(arg)
+			},
+		}
+
+		apiConfig := BookClaimAPIConfig{APIConfig: common.APIConfig{DB: mockQueries}}
+		request := jsonRequest(http.MethodPost, "/api/v1/books/borrows/x/condition", `{"stage": "issue", "condition": "fair", "notes": " Spine creased "}`,
+			map[string]string{"bookBorrowId": loan.row.BookBorrow.ID.String()})
+		recorder := httptest.NewRecorder()
+
+		apiConfig.CreateBookConditionReport(recorder, request, loan.borrowerID)
+
+		if recorder.Code != http.StatusCreated {
+			t.Fatalf("Expected status %d, got %d. Body: %s", http.StatusCreated, recorder.Code, recorder.Body.String())
+		}
+
+		var bookConditionReport BookConditionReport
+		json.Unmarshal(recorder.Body.Bytes(), &bookConditionReport)
+
+		if bookConditionReport.Stage != BookConditionStageIssue || bookConditionReport.Condition != "fair" {
+			t.Errorf("Unexpected report: %+v", bookConditionReport)
+		}
+	})
+
+	// 1a. Success: the owner reports damage on return with a photo.
+	tTesting.Run("ReturnStageWithPhoto", func(t *testing.T) {
+		loan := newTestLoan(true)
+		blobs := &memoryBlobStore{blobs: map[string][]byte{}}
+
+		mockQueries := &MockQueries{
+			BaseMock:                   common.NewBaseMock(),
+			GetBookBorrowWithOwnerFunc: loan.getBookBorrowWithOwner,
+			CreateBookConditionReportFunc: func(ctx context.Context, arg database.CreateBookConditionReportParams) (database.BookConditionReport, error) {
+				return createBookConditionReport(arg)
+			},
+		}
+
+		apiConfig := BookClaimAPIConfig{APIConfig: common.APIConfig{DB: mockQueries, Blobs: blobs}}
+		request := photoRequest(t, loan.row.BookBorrow.ID, map[string]string{"stage": "return", "condition": "damaged", "notes": "Water damage"}, encodeTestPhoto(t))
+		recorder := httptest.NewRecorder()
+
+		apiConfig.CreateBookConditionReport(recorder, request, loan.ownerID)
+
+		if recorder.Code != http.StatusCreated {
+			t.Fatalf("Expected status %d, got %d. Body: %s", http.StatusCreated, recorder.Code, recorder.Body.String())
+		}
+
+		var bookConditionReport BookConditionReport
+		json.Unmarshal(recorder.Body.Bytes(), &bookConditionReport)
+
+		photoPrefix := fmt.Sprintf("condition-reports/%s/", loan.row.BookBorrow.ID)
+
+		if len(blobs.blobs) != 1 || !strings.Contains(bookConditionReport.PhotoURL, photoPrefix) || !strings.HasSuffix(bookConditionReport.PhotoURL, ".png") ||
+			bookConditionReport.Condition != BookConditionDamaged {
+			t.Errorf("Expected the photo under %s, got %+v", photoPrefix, bookConditionReport)
+		}
+	})
+
+	// 2. Failure: the condition at return can't be reported while the book is still out.
+	tTesting.Run("ReturnStageTooEarly", func(t *testing.T) {
+		loan := newTestLoan(false)
+		mockQueries := &MockQueries{BaseMock: common.NewBaseMock(), GetBookBorrowWithOwnerFunc: loan.getBookBorrowWithOwner}
+
+		apiConfig := BookClaimAPIConfig{APIConfig: common.APIConfig{DB: mockQueries}}
+		request := jsonRequest(http.MethodPost, "/api/v1/books/borrows/x/condition", `{"stage": "return", "condition": "good"}`,
+			map[string]string{"bookBorrowId": loan.row.BookBorrow.ID.String()})
+		recorder := httptest.NewRecorder()
+
+		apiConfig.CreateBookConditionReport(recorder, request, loan.ownerID)
+
+		if recorder.Code != http.StatusConflict {
+			t.Errorf("Expected status %d, got %d", http.StatusConflict, recorder.Code)
+		}
+	})
+
+	// 3. Failure: someone else's loan is not found.
+	tTesting.Run("NotAParty", func(t *testing.T) {
+		loan := newTestLoan(false)
+		mockQueries := &MockQueries{BaseMock: common.NewBaseMock(), GetBookBorrowWithOwnerFunc: loan.getBookBorrowWithOwner}
+
+		apiConfig := BookClaimAPIConfig{APIConfig: common.APIConfig{DB: mockQueries}}
+		request := jsonRequest(http.MethodPost, "/api/v1/books/borrows/x/condition", `{"stage": "issue", "condition": "good"}`,
+			map[string]string{"bookBorrowId": loan.row.BookBorrow.ID.String()})
+		recorder := httptest.NewRecorder()
+
+		apiConfig.CreateBookConditionReport(recorder, request, uuid.New())
+
+		if recorder.Code != http.StatusNotFound {
+			t.Errorf("Expected status %d, got %d", http.StatusNotFound, recorder.Code)
+		}
+	})
+
+	// 4. Failure: a second report for the same stage is turned away and its photo removed.
+	tTesting.Run("Duplicate", func(t *testing.T) {
+		loan := newTestLoan(true)
+		blobs := &memoryBlobStore{blobs: map[string][]byte{}}
+
+		mockQueries := &MockQueries{
+			BaseMock:                   common.NewBaseMock(),
+			GetBookBorrowWithOwnerFunc: loan.getBookBorrowWithOwner,
+			CreateBookConditionReportFunc: func(ctx context.Context, arg database.CreateBookConditionReportParams) (database.BookConditionReport, error) {
+				return database.BookConditionReport{}, sql.ErrNoRows
+			},
+		}
+
+		apiConfig := BookClaimAPIConfig{APIConfig: common.APIConfig{DB: mockQueries, Blobs: blobs}}
+		request := photoRequest(t, loan.row.BookBorrow.ID, map[string]string{"stage": "return", "condition": "good"}, encodeTestPhoto(t))
+		recorder := httptest.NewRecorder()
+
+		apiConfig.CreateBookConditionReport(recorder, request, loan.borrowerID)
+
+		if recorder.Code != http.StatusConflict || len(blobs.blobs) != 0 || len(blobs.deletedKeys) != 1 {
+			t.Errorf("Expected status %d and the photo removed, got %d and %v", http.StatusConflict, recorder.Code, blobs.blobs)
+		}
+	})
+
+	// 5. Failure: conditions outside the list, and lost, are rejected.
+	tTesting.Run("InvalidCondition", func(t *testing.T) {
+		apiConfig := BookClaimAPIConfig{APIConfig: common.APIConfig{DB: &MockQueries{BaseMock: common.NewBaseMock()}}}
+		request := jsonRequest(http.MethodPost, "/api/v1/books/borrows/x/condition", `{"stage": "issue", "condition": "lost"}`,
+			map[string]string{"bookBorrowId": uuid.NewString()})
+		recorder := httptest.NewRecorder()
+
+		apiConfig.CreateBookConditionReport(recorder, request, uuid.New())
+
+		if recorder.Code != http.StatusBadRequest {
+			t.Errorf("Expected status %d, got %d", http.StatusBadRequest, recorder.Code)
+		}
+	})
+
+	// 6. Failure: anything but an image is refused.
+	tTesting.Run("NotAnImage", func(t *testing.T) {
+		loan := newTestLoan(false)
+		apiConfig := BookClaimAPIConfig{APIConfig: common.APIConfig{DB: &MockQueries{BaseMock: common.NewBaseMock()}, Blobs: &memoryBlobStore{blobs: map[string][]byte{}}}}
+		request := photoRequest(t, loan.row.BookBorrow.ID, map[string]string{"stage": "issue", "condition": "good"}, []byte("%PDF-1.4"))
+		recorder := httptest.NewRecorder()
+
+		apiConfig.CreateBookConditionReport(recorder, request, loan.borrowerID)
+
+		if recorder.Code != http.StatusUnsupportedMediaType {
+			t.Errorf("Expected status %d, got %d", http.StatusUnsupportedMediaType, recorder.Code)
+		}
+	})
+}
+
+func TestReportBookLost(tTesting *testing.T) {
+	reportBookLost := func(mockQueries *MockQueries, loan testLoan, userId uuid.UUID, body string) *httptest.ResponseRecorder {
+		apiConfig := BookClaimAPIConfig{APIConfig: common.APIConfig{DB: mockQueries}}
+		request := jsonRequest(http.MethodPatch, "/api/v1/books/borrows/x/lost", body, map[string]string{"bookBorrowId": loan.row.BookBorrow.ID.String()})
+		recorder := httptest.NewRecorder()
+
+		apiConfig.ReportBookLost(recorder, request, userId)
+
+		return recorder
+	}
+
+	// 1. Success: the borrower reports the book lost and a loss claim is opened.
+	tTesting.Run("Success", func(t *testing.T) {
+		loan := newTestLoan(false)
+		var reportedLost bool
+
+		mockQueries := &MockQueries{
+			BaseMock:                   common.NewBaseMock(),
+			GetBookBorrowWithOwnerFunc: loan.getBookBorrowWithOwner,
+			ReportBookBorrowLostFunc: func(ctx context.Context, id uuid.UUID) (database.BookBorrow, error) {
+				reportedLost = true
+
+				return loan.row.BookBorrow, nil
+			},
+			CreateBookClaimFunc: createBookClaim,
+		}
+
+		recorder := reportBookLost(mockQueries, loan, loan.borrowerID, `{"description": "Left it on the train"}`)
+
+		if recorder.Code != http.StatusCreated {
+			t.Fatalf("Expected status %d, got %d. Body: %s", http.StatusCreated, recorder.Code, recorder.Body.String())
+		}
+
+		var bookClaim BookClaim
+		json.Unmarshal(recorder.Body.Bytes(), &bookClaim)
+
+		if !reportedLost || bookClaim.Kind != BookClaimKindLoss || bookClaim.OpenedBy != loan.borrowerID || bookClaim.OwnerID != loan.ownerID ||
+			bookClaim.Description != "Left it on the train" {
+			t.Errorf("Unexpected claim: %+v", bookClaim)
+		}
+	})
+
+	// 2. Failure: a book that is back, or already lost, can't be reported lost.
+	tTesting.Run("NotOut", func(t *testing.T) {
+		loan := newTestLoan(true)
+
+		mockQueries := &MockQueries{
+			BaseMock:                   common.NewBaseMock(),
+			GetBookBorrowWithOwnerFunc: loan.getBookBorrowWithOwner,
+			ReportBookBorrowLostFunc: func(ctx context.Context, id uuid.UUID) (database.BookBorrow, error) {
+				return database.BookBorrow{}, sql.ErrNoRows
+			},
+		}
+
+		if recorder := reportBookLost(mockQueries, loan, loan.ownerID, ""); recorder.Code != http.StatusConflict {
+			t.Errorf("Expected status %d, got %d", http.StatusConflict, recorder.Code)
+		}
+	})
+
+	// 3. Failure: the loan already has an open claim.
+	tTesting.Run("OpenClaim", func(t *testing.T) {
+		loan := newTestLoan(false)
+
+		mockQueries := &MockQueries{
+			BaseMock:                   common.NewBaseMock(),
+			GetBookBorrowWithOwnerFunc: loan.getBookBorrowWithOwner,
+			ReportBookBorrowLostFunc: func(ctx context.Context, id uuid.UUID) (database.BookBorrow, error) {
+				return loan.row.BookBorrow, nil
+			},
+			CreateBookClaimFunc: func(ctx context.Context, arg database.CreateBookClaimParams) (database.BookClaim, error) {
+				return database.BookClaim{}, sql.ErrNoRows
+			},
+		}
+
+		if recorder := reportBookLost(mockQueries, loan, loan.ownerID, ""); recorder.Code != http.StatusConflict {
+			t.Errorf("Expected status %d, got %d", http.StatusConflict, recorder.Code)
+		}
+	})
+}
+
+func TestCreateBookClaim(tTesting *testing.T) {
+	createClaim := func(mockQueries *MockQueries, loan testLoan, userId uuid.UUID) *httptest.ResponseRecorder {
+		apiConfig := BookClaimAPIConfig{APIConfig: common.APIConfig{DB: mockQueries}}
+		request := jsonRequest(http.MethodPost, "/api/v1/books/claims/x", `{"description": "Coffee stains on every page"}`,
+			map[string]string{"bookBorrowId": loan.row.BookBorrow.ID.String()})
+		recorder := httptest.NewRecorder()
+
+		apiConfig.CreateBookClaim(recorder, request, userId)
+
+		return recorder
+	}
+
+	// 1. Success: the owner opens a damage claim on a returned book.
+	tTesting.Run("Success", func(t *testing.T) {
+		loan := newTestLoan(true)
+		mockQueries := &MockQueries{BaseMock: common.NewBaseMock(), GetBookBorrowWithOwnerFunc: loan.getBookBorrowWithOwner, CreateBookClaimFunc: createBookClaim}
+
+		recorder := createClaim(mockQueries, loan, loan.ownerID)
+
+		var bookClaim BookClaim
+		json.Unmarshal(recorder.Body.Bytes(), &bookClaim)
+
+		if recorder.Code != http.StatusCreated || bookClaim.Kind != BookClaimKindDamage || bookClaim.BorrowerID != loan.borrowerID {
+			t.Errorf("Expected a damage claim, got %d %s", recorder.Code, recorder.Body.String())
+		}
+	})
+
+	// 2. Failure: the borrower can't open a damage claim.
+	tTesting.Run("Borrower", func(t *testing.T) {
+		loan := newTestLoan(true)
+		mockQueries := &MockQueries{BaseMock: common.NewBaseMock(), GetBookBorrowWithOwnerFunc: loan.getBookBorrowWithOwner}
+
+		if recorder := createClaim(mockQueries, loan, loan.borrowerID); recorder.Code != http.StatusForbidden {
+			t.Errorf("Expected status %d, got %d", http.StatusForbidden, recorder.Code)
+		}
+	})
+
+	// 3. Failure: the book is still out.
+	tTesting.Run("NotReturned", func(t *testing.T) {
+		loan := newTestLoan(false)
+		mockQueries := &MockQueries{BaseMock: common.NewBaseMock(), GetBookBorrowWithOwnerFunc: loan.getBookBorrowWithOwner}
+
+		if recorder := createClaim(mockQueries, loan, loan.ownerID); recorder.Code != http.StatusConflict {
+			t.Errorf("Expected status %d, got %d", http.StatusConflict, recorder.Code)
+		}
+	})
+}
+
+func TestCreateBookClaimComment(tTesting *testing.T) {
+	loan := newTestLoan(true)
+	getBookClaim := loan.claim(BookClaimKindDamage)
+
+	createComment := func(mockQueries *MockQueries, userId uuid.UUID) *httptest.ResponseRecorder {
+		mockQueries.GetBookClaimFunc = func(ctx context.Context, id uuid.UUID) (database.GetBookClaimRow, error) {
+			return getBookClaim, nil
+		}
+
+		apiConfig := BookClaimAPIConfig{APIConfig: common.APIConfig{DB: mockQueries}}
+		request := jsonRequest(http.MethodPost, "/api/v1/books/claims/x/comments", `{"body": "They were there before I borrowed it"}`,
+			map[string]string{"bookClaimId": getBookClaim.BookClaim.ID.String()})
+		recorder := httptest.NewRecorder()
+
+		apiConfig.CreateBookClaimComment(recorder, request, userId)
+
+		return recorder
+	}
+
+	// 1. Success: the borrower answers the claim.
+	tTesting.Run("Success", func(t *testing.T) {
+		mockQueries := &MockQueries{
+			BaseMock: common.NewBaseMock(),
+			CreateBookClaimCommentFunc: func(ctx context.Context, arg database.CreateBookClaimCommentParams) (database.BookClaimComment, error) {
+				return database.BookClaimComment{ID: arg.ID, Body: arg.Body, CreatedAt: time.Now(), BookClaimID: arg.BookClaimID, AuthorID: arg.AuthorID}, nil
+			},
+		}
+
+		recorder := createComment(mockQueries, loan.borrowerID)
+
+		var bookClaimComment BookClaimComment
+		json.Unmarshal(recorder.Body.Bytes(), &bookClaimComment)
+
+		if recorder.Code != http.StatusCreated || bookClaimComment.AuthorID != loan.borrowerID {
+			t.Errorf("Expected the comment, got %d %s", recorder.Code, recorder.Body.String())
+		}
+	})
+
+	// 2. Failure: a resolved claim takes no more comments.
+	tTesting.Run("Resolved", func(t *testing.T) {
+		mockQueries := &MockQueries{
+			BaseMock: common.NewBaseMock(),
+			CreateBookClaimCommentFunc: func(ctx context.Context, arg database.CreateBookClaimCommentParams) (database.BookClaimComment, error) {
+				return database.BookClaimComment{}, sql.ErrNoRows
+			},
+		}
+
+		if recorder := createComment(mockQueries, loan.ownerID); recorder.Code != http.StatusConflict {
+			t.Errorf("Expected status %d, got %d", http.StatusConflict, recorder.Code)
+		}
+	})
+
+	// 3. Failure: outsiders don't see the claim.
+	tTesting.Run("NotAParty", func(t *testing.T) {
+		if recorder := createComment(&MockQueries{BaseMock: common.NewBaseMock()}, uuid.New()); recorder.Code != http.StatusNotFound {
+			t.Errorf("Expected status %d, got %d", http.StatusNotFound, recorder.Code)
+		}
+	})
+}
+
+func TestResolveBookClaim(tTesting *testing.T) {
+	resolveClaim := func(mockQueries *MockQueries, getBookClaim database.GetBookClaimRow, userId uuid.UUID, body string) *httptest.ResponseRecorder {
+		mockQueries.GetBookClaimFunc = func(ctx context.Context, id uuid.UUID) (database.GetBookClaimRow, error) {
+			return getBookClaim, nil
+		}
+
+		if mockQueries.ResolveBookClaimFunc == nil {
+			mockQueries.ResolveBookClaimFunc = func(ctx context.Context, arg database.ResolveBookClaimParams) (database.BookClaim, error) {
+				resolvedBookClaim := getBookClaim.BookClaim
+				resolvedBookClaim.Status = BookClaimStatusResolved
+				resolvedBookClaim.Resolution = arg.Resolution
+				resolvedBookClaim.ResolutionNote = arg.ResolutionNote
+				resolvedBookClaim.ResolvedAt = sql.NullTime{Time: time.Now(), Valid: true}
+
+				return resolvedBookClaim, nil
+			}
+		}
+
+		apiConfig := BookClaimAPIConfig{APIConfig: common.APIConfig{DB: mockQueries}}
+		request := jsonRequest(http.MethodPatch, "/api/v1/books/claims/x/resolve", body, map[string]string{"bookClaimId": getBookClaim.BookClaim.ID.String()})
+		recorder := httptest.NewRecorder()
+
+		apiConfig.ResolveBookClaim(recorder, request, userId)
+
+		return recorder
+	}
+
+	// 1. Success: a lost book written off closes the loan and the copy.
+	tTesting.Run("LossCompensated", func(t *testing.T) {
+		loan := newTestLoan(false)
+		getBookClaim := loan.claim(BookClaimKindLoss)
+		var closedBorrowID, lostCopyID, returnedRequestBorrowID uuid.UUID
+
+		mockQueries := &MockQueries{
+			BaseMock: common.NewBaseMock(),
+			CloseLostBookBorrowFunc: func(ctx context.Context, id uuid.UUID) (int64, error) {
+				closedBorrowID = id
+
+				return 1, nil
+			},
+			MarkBookCopyLostFunc: func(ctx context.Context, id uuid.UUID) error {
+				lostCopyID = id
+
+				return nil
+			},
+			MarkBookBorrowRequestReturnedFunc: func(ctx context.Context, bookBorrowID uuid.UUID) error {
+				returnedRequestBorrowID = bookBorrowID
+
+				return nil
+			},
+		}
+
+		recorder := resolveClaim(mockQueries, getBookClaim, loan.ownerID, `{"resolution": "compensated", "note": "Paid for a new copy"}`)
+
+		if recorder.Code != http.StatusOK {
+			t.Fatalf("Expected status %d, got %d. Body: %s", http.StatusOK, recorder.Code, recorder.Body.String())
+		}
+
+		var bookClaim BookClaim
+		json.Unmarshal(recorder.Body.Bytes(), &bookClaim)
+
+		if bookClaim.Status != BookClaimStatusResolved || bookClaim.Resolution != BookClaimResolutionCompensated || bookClaim.ResolutionNote != "Paid for a new copy" {
+			t.Errorf("Unexpected claim: %+v", bookClaim)
+		}
+
+		if closedBorrowID != loan.row.BookBorrow.ID || lostCopyID != loan.row.BookBorrow.BookCopyID || returnedRequestBorrowID != loan.row.BookBorrow.ID {
+			t.Errorf("Expected the loan closed and the copy written off, got %s %s %s", closedBorrowID, lostCopyID, returnedRequestBorrowID)
+		}
+	})
+
+	// 1a. Success: a lost book that turns up goes back to being an ordinary loan.
+	tTesting.Run("LossFound", func(t *testing.T) {
+		loan := newTestLoan(false)
+		var clearedBorrowID uuid.UUID
+
+		mockQueries := &MockQueries{
+			BaseMock: common.NewBaseMock(),
+			ClearBookBorrowLostFunc: func(ctx context.Context, id uuid.UUID) error {
+				clearedBorrowID = id
+
+				return nil
+			},
+			CloseLostBookBorrowFunc: func(ctx context.Context, id uuid.UUID) (int64, error) {
+				t.Error("Expected the loan to stay open")
+
+				return 0, nil
+			},
+		}
+
+		if recorder := resolveClaim(mockQueries, loan.claim(BookClaimKindLoss), loan.ownerID, `{"resolution": "found"}`); recorder.Code != http.StatusOK || clearedBorrowID != loan.row.BookBorrow.ID {
+			t.Errorf("Expected status %d and the loss cleared, got %d", http.StatusOK, recorder.Code)
+		}
+	})
+
+	// 1b. Success: settling a damage claim leaves the loan alone.
+	tTesting.Run("DamageWaived", func(t *testing.T) {
+		loan := newTestLoan(true)
+
+		if recorder := resolveClaim(&MockQueries{BaseMock: common.NewBaseMock()}, loan.claim(BookClaimKindDamage), loan.ownerID, `{"resolution": "waived"}`); recorder.Code != http.StatusOK {
+			t.Errorf("Expected status %d, got %d", http.StatusOK, recorder.Code)
+		}
+	})
+
+	// 2. Failure: only the owner resolves claims.
+	tTesting.Run("Borrower", func(t *testing.T) {
+		loan := newTestLoan(true)
+
+		if recorder := resolveClaim(&MockQueries{BaseMock: common.NewBaseMock()}, loan.claim(BookClaimKindDamage), loan.borrowerID, `{"resolution": "waived"}`); recorder.Code != http.StatusForbidden {
+			t.Errorf("Expected status %d, got %d", http.StatusForbidden, recorder.Code)
+		}
+	})
+
+	// 3. Failure: damage can't be found.
+	tTesting.Run("FoundDamage", func(t *testing.T) {
+		loan := newTestLoan(true)
+
+		if recorder := resolveClaim(&MockQueries{BaseMock: common.NewBaseMock()}, loan.claim(BookClaimKindDamage), loan.ownerID, `{"resolution": "found"}`); recorder.Code != http.StatusBadRequest {
+			t.Errorf("Expected status %d, got %d", http.StatusBadRequest, recorder.Code)
+		}
+	})
+
+	// 4. Failure: the claim was already resolved.
+	tTesting.Run("AlreadyResolved", func(t *testing.T) {
+		loan := newTestLoan(true)
+
+		mockQueries := &MockQueries{
+			BaseMock: common.NewBaseMock(),
+			ResolveBookClaimFunc: func(ctx context.Context, arg database.ResolveBookClaimParams) (database.BookClaim, error) {
+				return database.BookClaim{}, sql.ErrNoRows
+			},
+		}
+
+		if recorder := resolveClaim(mockQueries, loan.claim(BookClaimKindDamage), loan.ownerID, `{"resolution": "waived"}`); recorder.Code != http.StatusConflict {
+			t.Errorf("Expected status %d, got %d", http.StatusConflict, recorder.Code)
+		}
+	})
+}
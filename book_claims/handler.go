@@ -0,0 +1,270 @@
+package book_claims
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/elorenzorodz/co-library/common"
+	"github.com/elorenzorodz/co-library/internal/database"
+	"github.com/google/uuid"
+)
+
+func DatabaseBookConditionReportToJSON(databaseBookConditionReport database.BookConditionReport) BookConditionReport {
+	return BookConditionReport{
+		ID:           databaseBookConditionReport.ID,
+		Stage:        databaseBookConditionReport.Stage,
+		Condition:    databaseBookConditionReport.Condition,
+		Notes:        databaseBookConditionReport.Notes,
+		PhotoURL:     databaseBookConditionReport.PhotoUrl,
+		CreatedAt:    databaseBookConditionReport.CreatedAt,
+		BookBorrowID: databaseBookConditionReport.BookBorrowID,
+		ReporterID:   databaseBookConditionReport.ReporterID,
+	}
+}
+
+func DatabaseBookConditionReportsToJSON(databaseBookConditionReports []database.BookConditionReport) []BookConditionReport {
+	bookConditionReports := []BookConditionReport{}
+
+	for _, databaseBookConditionReport := range databaseBookConditionReports {
+		bookConditionReports = append(bookConditionReports, DatabaseBookConditionReportToJSON(databaseBookConditionReport))
+	}
+
+	return bookConditionReports
+}
+
+func DatabaseBookClaimToJSON(databaseBookClaim database.BookClaim) BookClaim {
+	return BookClaim{
+		ID:             databaseBookClaim.ID,
+		Kind:           databaseBookClaim.Kind,
+		Status:         databaseBookClaim.Status,
+		Description:    databaseBookClaim.Description,
+		Resolution:     databaseBookClaim.Resolution.String,
+		ResolutionNote: databaseBookClaim.ResolutionNote,
+		ResolvedAt:     databaseBookClaim.ResolvedAt,
+		CreatedAt:      databaseBookClaim.CreatedAt,
+		UpdatedAt:      databaseBookClaim.UpdatedAt,
+		BookBorrowID:   databaseBookClaim.BookBorrowID,
+		OpenedBy:       databaseBookClaim.OpenedBy,
+	}
+}
+
+func DatabaseGetBookClaimRowToJSON(getBookClaim database.GetBookClaimRow) BookClaim {
+	bookClaim := DatabaseBookClaimToJSON(getBookClaim.BookClaim)
+	bookClaim.BookTitle = getBookClaim.BookTitle
+	bookClaim.OwnerID = getBookClaim.OwnerID
+	bookClaim.BorrowerID = getBookClaim.BorrowerID
+
+	return bookClaim
+}
+
+// Claims only carry the loan id, the parties come from the loan it was opened on.
+func BookClaimWithParties(databaseBookClaim database.BookClaim, getBookBorrow database.GetBookBorrowWithOwnerRow) BookClaim {
+	bookClaim := DatabaseBookClaimToJSON(databaseBookClaim)
+	bookClaim.BookTitle = getBookBorrow.BookTitle
+	bookClaim.OwnerID = getBookBorrow.OwnerID
+	bookClaim.BorrowerID = getBookBorrow.BookBorrow.BorrowerID
+
+	return bookClaim
+}
+
+func DatabaseGetUserBookClaimsRowsToJSON(databaseRows []database.GetUserBookClaimsRow) []BookClaim {
+	bookClaims := []BookClaim{}
+
+	for _, databaseRow := range databaseRows {
+		bookClaim := DatabaseBookClaimToJSON(databaseRow.BookClaim)
+		bookClaim.BookTitle = databaseRow.BookTitle
+		bookClaim.OwnerID = databaseRow.OwnerID
+		bookClaim.BorrowerID = databaseRow.BorrowerID
+
+		bookClaims = append(bookClaims, bookClaim)
+	}
+
+	return bookClaims
+}
+
+// Comments read back with the claim carry their author's name, a new one is returned without it.
+func DatabaseBookClaimCommentToJSON(databaseBookClaimComment database.BookClaimComment) BookClaimComment {
+	return BookClaimComment{
+		ID:        databaseBookClaimComment.ID,
+		Body:      databaseBookClaimComment.Body,
+		CreatedAt: databaseBookClaimComment.CreatedAt,
+		AuthorID:  databaseBookClaimComment.AuthorID,
+	}
+}
+
+func DatabaseGetBookClaimCommentsRowsToJSON(databaseRows []database.GetBookClaimCommentsRow) []BookClaimComment {
+	bookClaimComments := []BookClaimComment{}
+
+	for _, databaseRow := range databaseRows {
+		bookClaimComment := DatabaseBookClaimCommentToJSON(databaseRow.BookClaimComment)
+		bookClaimComment.AuthorName = fmt.Sprintf("%s %s", databaseRow.AuthorFirstName, databaseRow.AuthorLastName)
+
+		bookClaimComments = append(bookClaimComments, bookClaimComment)
+	}
+
+	return bookClaimComments
+}
+
+// Only the borrower and the book owner get to see or add to what is on record for a loan.
+func IsBookBorrowParty(userId uuid.UUID, borrowerId uuid.UUID, ownerId uuid.UUID) bool {
+	return userId == borrowerId || userId == ownerId
+}
+
+func ValidateBookConditionReport(createBookConditionReportParameters CreateBookConditionReportParameters) error {
+	if !slices.Contains(BookConditionStages, createBookConditionReportParameters.Stage) {
+		return fmt.Errorf("invalid stage: %s, must be one of: %s", createBookConditionReportParameters.Stage, strings.Join(BookConditionStages, ", "))
+	}
+
+	if !slices.Contains(BookConditionReportConditions, createBookConditionReportParameters.Condition) {
+		return fmt.Errorf("invalid condition: %s, must be one of: %s", createBookConditionReportParameters.Condition, strings.Join(BookConditionReportConditions, ", "))
+	}
+
+	if len(createBookConditionReportParameters.Notes) > MaxBookConditionNotesLength {
+		return fmt.Errorf("notes must be at most %d characters", MaxBookConditionNotesLength)
+	}
+
+	return nil
+}
+
+// Reports come as JSON, or as a multipart form when there is a photo to go with them.
+// The photo is nil when none was sent.
+func ReadBookConditionReport(writer http.ResponseWriter, request *http.Request) (CreateBookConditionReportParameters, *BookConditionPhoto, int, error) {
+	createBookConditionReportParameters := CreateBookConditionReportParameters{}
+	mediaType, _, _ := mime.ParseMediaType(request.Header.Get("Content-Type"))
+
+	if mediaType != "multipart/form-data" {
+		decoder := json.NewDecoder(request.Body)
+
+		if decoderError := decoder.Decode(&createBookConditionReportParameters); decoderError != nil {
+			return createBookConditionReportParameters, nil, http.StatusBadRequest, fmt.Errorf("error parsing JSON: %s", decoderError)
+		}
+
+		return createBookConditionReportParameters, nil, http.StatusOK, nil
+	}
+
+	// Leaves room for the other fields and the multipart boundaries and headers.
+	request.Body = http.MaxBytesReader(writer, request.Body, MaxBookConditionPhotoSize+(64<<10))
+
+	if parseFormError := request.ParseMultipartForm(MaxBookConditionPhotoSize); parseFormError != nil {
+		var maxBytesError *http.MaxBytesError
+
+		if errors.As(parseFormError, &maxBytesError) {
+			return createBookConditionReportParameters, nil, http.StatusRequestEntityTooLarge, fmt.Errorf("photo must be at most %d MB", MaxBookConditionPhotoSize>>20)
+		}
+
+		return createBookConditionReportParameters, nil, http.StatusBadRequest, fmt.Errorf("error parsing form: %s", parseFormError)
+	}
+
+	createBookConditionReportParameters.Stage = request.FormValue("stage")
+	createBookConditionReportParameters.Condition = request.FormValue("condition")
+	createBookConditionReportParameters.Notes = request.FormValue("notes")
+
+	photoFile, _, formFileError := request.FormFile(BookConditionPhotoFormField)
+
+	if formFileError != nil {
+		if errors.Is(formFileError, http.ErrMissingFile) {
+			return createBookConditionReportParameters, nil, http.StatusOK, nil
+		}
+
+		return createBookConditionReportParameters, nil, http.StatusBadRequest, fmt.Errorf("error reading photo: %s", formFileError)
+	}
+
+	defer photoFile.Close()
+
+	content, readError := io.ReadAll(io.LimitReader(photoFile, MaxBookConditionPhotoSize+1))
+
+	if readError != nil {
+		return createBookConditionReportParameters, nil, http.StatusBadRequest, fmt.Errorf("error reading photo: %s", readError)
+	}
+
+	if len(content) > MaxBookConditionPhotoSize {
+		return createBookConditionReportParameters, nil, http.StatusRequestEntityTooLarge, fmt.Errorf("photo must be at most %d MB", MaxBookConditionPhotoSize>>20)
+	}
+
+	bookConditionPhoto, status, checkPhotoError := CheckBookConditionPhoto(content)
+
+	if checkPhotoError != nil {
+		return createBookConditionReportParameters, nil, status, checkPhotoError
+	}
+
+	return createBookConditionReportParameters, &bookConditionPhoto, http.StatusOK, nil
+}
+
+// Photos are stored as they were sent, so only the type and header are checked.
+// The content type is sniffed, whatever the client claims is ignored.
+func CheckBookConditionPhoto(content []byte) (BookConditionPhoto, int, error) {
+	contentType := http.DetectContentType(content)
+
+	if _, allowed := BookConditionPhotoExtensions[contentType]; !allowed {
+		return BookConditionPhoto{}, http.StatusUnsupportedMediaType, errors.New("photo must be a JPEG, PNG or GIF")
+	}
+
+	imageConfig, _, decodeConfigError := image.DecodeConfig(bytes.NewReader(content))
+
+	if decodeConfigError != nil {
+		return BookConditionPhoto{}, http.StatusBadRequest, errors.New("photo is corrupt or incomplete")
+	}
+
+	if imageConfig.Width > MaxBookConditionPhotoPixels || imageConfig.Height > MaxBookConditionPhotoPixels {
+		return BookConditionPhoto{}, http.StatusBadRequest, fmt.Errorf("photo must be at most %dx%d pixels", MaxBookConditionPhotoPixels, MaxBookConditionPhotoPixels)
+	}
+
+	return BookConditionPhoto{Content: content, ContentType: contentType}, http.StatusOK, nil
+}
+
+func BookConditionPhotoKey(bookBorrowId uuid.UUID, contentType string) string {
+	return fmt.Sprintf("condition-reports/%s/%s%s", bookBorrowId, uuid.New(), BookConditionPhotoExtensions[contentType])
+}
+
+// Cleanup of photos nothing points at anymore, failures are only logged.
+func DeleteBookConditionPhotoBlobs(ctx context.Context, blobs common.BlobStore, keys []string) {
+	for _, key := range keys {
+		if key == "" {
+			continue
+		}
+
+		if deleteError := blobs.Delete(ctx, key); deleteError != nil {
+			log.Printf("error deleting condition photo blob %s: %s", key, deleteError)
+		}
+	}
+}
+
+// Loss claims follow the loan: written off, the loan is closed and the copy marked lost,
+// found, it goes back to being an ordinary loan.
+func ResolveBookLossClaim(ctx context.Context, querier common.Querier, getBookClaim database.GetBookClaimRow, resolution string) error {
+	bookBorrowId := getBookClaim.BookClaim.BookBorrowID
+
+	if resolution == BookClaimResolutionFound {
+		return querier.ClearBookBorrowLost(ctx, bookBorrowId)
+	}
+
+	closedBookBorrows, closeLostBookBorrowError := querier.CloseLostBookBorrow(ctx, bookBorrowId)
+
+	if closeLostBookBorrowError != nil {
+		return closeLostBookBorrowError
+	}
+
+	// Already closed when the owner confirmed a return before settling the claim.
+	if closedBookBorrows == 0 {
+		return nil
+	}
+
+	if markBookCopyLostError := querier.MarkBookCopyLost(ctx, getBookClaim.BookCopyID); markBookCopyLostError != nil {
+		return markBookCopyLostError
+	}
+
+	return querier.MarkBookBorrowRequestReturned(ctx, bookBorrowId)
+}
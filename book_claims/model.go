@@ -0,0 +1,140 @@
+package book_claims
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/elorenzorodz/co-library/book_copies"
+	"github.com/elorenzorodz/co-library/common"
+	"github.com/google/uuid"
+)
+
+type BookClaimAPIConfig struct {
+	common.APIConfig
+}
+
+// When a condition report is taken: as the book changes hands at the start of the loan, or at its end.
+const (
+	BookConditionStageIssue  = "issue"
+	BookConditionStageReturn = "return"
+)
+
+var BookConditionStages = []string{BookConditionStageIssue, BookConditionStageReturn}
+
+// Reports use the copy conditions, plus damaged for harm done while the book was out.
+const BookConditionDamaged = "damaged"
+
+var BookConditionReportConditions = []string{
+	book_copies.BookCopyConditionNew,
+	book_copies.BookCopyConditionGood,
+	book_copies.BookCopyConditionFair,
+	book_copies.BookCopyConditionPoor,
+	BookConditionDamaged,
+}
+
+const (
+	// Name of the multipart form field holding the optional photo.
+	BookConditionPhotoFormField = "photo"
+	MaxBookConditionPhotoSize   = 5 << 20
+	MaxBookConditionPhotoPixels = 6000
+	MaxBookConditionNotesLength = 2000
+	MaxBookClaimTextLength      = 2000
+)
+
+// Accepted photo types, by sniffed content type, and the extension they are stored with.
+var BookConditionPhotoExtensions = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/gif":  ".gif",
+}
+
+// Damage claims are opened by the owner once the book is back, loss claims by
+// either side when it goes missing.
+const (
+	BookClaimKindDamage = "damage"
+	BookClaimKindLoss   = "loss"
+)
+
+const (
+	BookClaimStatusOpen     = "open"
+	BookClaimStatusResolved = "resolved"
+)
+
+// How the owner settles a claim. Found only applies to loss claims and puts the loan back
+// as it was, the others write the copy off.
+const (
+	BookClaimResolutionCompensated = "compensated"
+	BookClaimResolutionWaived      = "waived"
+	BookClaimResolutionFound       = "found"
+)
+
+var BookClaimResolutions = []string{BookClaimResolutionCompensated, BookClaimResolutionWaived, BookClaimResolutionFound}
+
+type BookConditionReport struct {
+	ID           uuid.UUID `json:"id"`
+	Stage        string    `json:"stage"`
+	Condition    string    `json:"condition"`
+	Notes        string    `json:"notes"`
+	PhotoURL     string    `json:"photo_url"`
+	CreatedAt    time.Time `json:"createdAt"`
+	BookBorrowID uuid.UUID `json:"book_borrow_id"`
+	ReporterID   uuid.UUID `json:"reporter_id"`
+}
+
+type BookClaim struct {
+	ID             uuid.UUID    `json:"id"`
+	Kind           string       `json:"kind"`
+	Status         string       `json:"status"`
+	Description    string       `json:"description"`
+	Resolution     string       `json:"resolution"`
+	ResolutionNote string       `json:"resolution_note"`
+	ResolvedAt     sql.NullTime `json:"resolvedAt"`
+	CreatedAt      time.Time    `json:"createdAt"`
+	UpdatedAt      time.Time    `json:"updatedAt"`
+	BookBorrowID   uuid.UUID    `json:"book_borrow_id"`
+	OpenedBy       uuid.UUID    `json:"opened_by"`
+	// Only filled in by the endpoints that look up the loan.
+	BookTitle  string    `json:"book_title,omitempty"`
+	OwnerID    uuid.UUID `json:"owner_id"`
+	BorrowerID uuid.UUID `json:"borrower_id"`
+}
+
+type BookClaimComment struct {
+	ID         uuid.UUID `json:"id"`
+	Body       string    `json:"body"`
+	CreatedAt  time.Time `json:"createdAt"`
+	AuthorID   uuid.UUID `json:"author_id"`
+	AuthorName string    `json:"author_name"`
+}
+
+// A claim with what both sides reported about the loan and said since.
+type BookClaimDetails struct {
+	BookClaim
+	ConditionReports []BookConditionReport `json:"condition_reports"`
+	Comments         []BookClaimComment    `json:"comments"`
+}
+
+type CreateBookConditionReportParameters struct {
+	Stage     string `json:"stage"`
+	Condition string `json:"condition"`
+	Notes     string `json:"notes"`
+}
+
+type BookConditionPhoto struct {
+	Content     []byte
+	ContentType string
+}
+
+// Used to open a damage claim and, optionally, to say what happened when reporting a book lost.
+type CreateBookClaimParameters struct {
+	Description string `json:"description"`
+}
+
+type CreateBookClaimCommentParameters struct {
+	Body string `json:"body"`
+}
+
+type ResolveBookClaimParameters struct {
+	Resolution string `json:"resolution"`
+	Note       string `json:"note"`
+}
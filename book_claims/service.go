@@ -0,0 +1,581 @@
+package book_claims
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/elorenzorodz/co-library/common"
+	"github.com/elorenzorodz/co-library/internal/database"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+var errBookClaimConflict = errors.New("book claim conflict")
+
+func (bookClaimAPIConfig *BookClaimAPIConfig) CreateBookConditionReport(writer http.ResponseWriter, request *http.Request, userId uuid.UUID) {
+	vars := mux.Vars(request)
+	bookBorrowId, parseBookBorrowIdError := uuid.Parse(vars["bookBorrowId"])
+
+	if parseBookBorrowIdError != nil {
+		common.ErrorResponse(writer, http.StatusBadRequest, "invalid book borrow id")
+
+		return
+	}
+
+	createBookConditionReportParameters, bookConditionPhoto, readStatus, readError := ReadBookConditionReport(writer, request)
+
+	if readError != nil {
+		common.ErrorResponse(writer, readStatus, readError.Error())
+
+		return
+	}
+
+	createBookConditionReportParameters.Notes = strings.TrimSpace(createBookConditionReportParameters.Notes)
+
+	if validateError := ValidateBookConditionReport(createBookConditionReportParameters); validateError != nil {
+		common.ErrorResponse(writer, http.StatusBadRequest, validateError.Error())
+
+		return
+	}
+
+	getBookBorrow, getBookBorrowError := bookClaimAPIConfig.DB.GetBookBorrowWithOwner(request.Context(), bookBorrowId)
+
+	if getBookBorrowError != nil {
+		if getBookBorrowError == sql.ErrNoRows {
+			common.ErrorResponse(writer, http.StatusNotFound, "book borrow not found")
+		} else {
+			common.ErrorResponse(writer, http.StatusInternalServerError, "failed to get book borrow details, please try again in a few minutes")
+		}
+
+		return
+	}
+
+	if !IsBookBorrowParty(userId, getBookBorrow.BookBorrow.BorrowerID, getBookBorrow.OwnerID) {
+		common.ErrorResponse(writer, http.StatusNotFound, "book borrow not found")
+
+		return
+	}
+
+	// Each snapshot is only taken while the book is changing hands.
+	returnedAt := getBookBorrow.BookBorrow.ReturnedAt
+
+	if createBookConditionReportParameters.Stage == BookConditionStageIssue && returnedAt.Valid {
+		common.ErrorResponse(writer, http.StatusConflict, "the book was already returned, report its condition at return instead")
+
+		return
+	}
+
+	if createBookConditionReportParameters.Stage == BookConditionStageReturn && !returnedAt.Valid {
+		common.ErrorResponse(writer, http.StatusConflict, "the condition at return can be reported once the book is returned")
+
+		return
+	}
+
+	createBookConditionReportParams := database.CreateBookConditionReportParams{
+		ID:           uuid.New(),
+		Stage:        createBookConditionReportParameters.Stage,
+		Condition:    createBookConditionReportParameters.Condition,
+		Notes:        createBookConditionReportParameters.Notes,
+		BookBorrowID: bookBorrowId,
+		ReporterID:   userId,
+	}
+
+	if bookConditionPhoto != nil {
+		if bookClaimAPIConfig.Blobs == nil {
+			common.ErrorResponse(writer, http.StatusServiceUnavailable, "photo uploads are turned off")
+
+			return
+		}
+
+		createBookConditionReportParams.PhotoKey = BookConditionPhotoKey(bookBorrowId, bookConditionPhoto.ContentType)
+
+		photoURL, putPhotoError := bookClaimAPIConfig.Blobs.Put(request.Context(), createBookConditionReportParams.PhotoKey, bookConditionPhoto.ContentType, bookConditionPhoto.Content)
+
+		if putPhotoError != nil {
+			common.ErrorResponse(writer, http.StatusInternalServerError, fmt.Sprintf("error storing photo: %s", putPhotoError))
+
+			return
+		}
+
+		createBookConditionReportParams.PhotoUrl = photoURL
+	}
+
+	bookConditionReport, createBookConditionReportError := bookClaimAPIConfig.DB.CreateBookConditionReport(request.Context(), createBookConditionReportParams)
+
+	if createBookConditionReportError != nil {
+		if bookConditionPhoto != nil {
+			DeleteBookConditionPhotoBlobs(request.Context(), bookClaimAPIConfig.Blobs, []string{createBookConditionReportParams.PhotoKey})
+		}
+
+		if createBookConditionReportError == sql.ErrNoRows {
+			common.ErrorResponse(writer, http.StatusConflict, fmt.Sprintf("you already reported the condition at %s", createBookConditionReportParams.Stage))
+		} else {
+			common.ErrorResponse(writer, http.StatusInternalServerError, "error creating condition report, please try again in a few minutes")
+		}
+
+		return
+	}
+
+	common.JSONResponse(writer, http.StatusCreated, DatabaseBookConditionReportToJSON(bookConditionReport))
+}
+
+func (bookClaimAPIConfig *BookClaimAPIConfig) GetBookConditionReports(writer http.ResponseWriter, request *http.Request, userId uuid.UUID) {
+	vars := mux.Vars(request)
+	bookBorrowId, parseBookBorrowIdError := uuid.Parse(vars["bookBorrowId"])
+
+	if parseBookBorrowIdError != nil {
+		common.ErrorResponse(writer, http.StatusBadRequest, "invalid book borrow id")
+
+		return
+	}
+
+	getBookBorrow, getBookBorrowError := bookClaimAPIConfig.DB.GetBookBorrowWithOwner(request.Context(), bookBorrowId)
+
+	if getBookBorrowError != nil {
+		if getBookBorrowError == sql.ErrNoRows {
+			common.ErrorResponse(writer, http.StatusNotFound, "book borrow not found")
+		} else {
+			common.ErrorResponse(writer, http.StatusInternalServerError, "failed to get book borrow details, please try again in a few minutes")
+		}
+
+		return
+	}
+
+	if !IsBookBorrowParty(userId, getBookBorrow.BookBorrow.BorrowerID, getBookBorrow.OwnerID) {
+		common.ErrorResponse(writer, http.StatusNotFound, "book borrow not found")
+
+		return
+	}
+
+	getBookConditionReports, getBookConditionReportsError := bookClaimAPIConfig.DB.GetBookConditionReports(request.Context(), bookBorrowId)
+
+	if getBookConditionReportsError != nil {
+		common.ErrorResponse(writer, http.StatusInternalServerError, "failed to get condition reports, please try again in a few minutes")
+
+		return
+	}
+
+	common.JSONResponse(writer, http.StatusOK, DatabaseBookConditionReportsToJSON(getBookConditionReports))
+}
+
+// Either side can say the book went missing. The loan stays open, and the copy off the
+// shelf, until the owner settles the loss claim this opens.
+func (bookClaimAPIConfig *BookClaimAPIConfig) ReportBookLost(writer http.ResponseWriter, request *http.Request, userId uuid.UUID) {
+	vars := mux.Vars(request)
+	bookBorrowId, parseBookBorrowIdError := uuid.Parse(vars["bookBorrowId"])
+
+	if parseBookBorrowIdError != nil {
+		common.ErrorResponse(writer, http.StatusBadRequest, "invalid book borrow id")
+
+		return
+	}
+
+	// The request body is optional, it only carries what happened to the book.
+	createBookClaimParameters := CreateBookClaimParameters{}
+
+	decoder := json.NewDecoder(request.Body)
+	decoderError := decoder.Decode(&createBookClaimParameters)
+
+	if decoderError != nil && !errors.Is(decoderError, io.EOF) {
+		common.ErrorResponse(writer, http.StatusBadRequest, fmt.Sprintf("error parsing JSON: %s", decoderError))
+
+		return
+	}
+
+	createBookClaimParameters.Description = strings.TrimSpace(createBookClaimParameters.Description)
+
+	if len(createBookClaimParameters.Description) > MaxBookClaimTextLength {
+		common.ErrorResponse(writer, http.StatusBadRequest, fmt.Sprintf("description must be at most %d characters", MaxBookClaimTextLength))
+
+		return
+	}
+
+	getBookBorrow, getBookBorrowError := bookClaimAPIConfig.DB.GetBookBorrowWithOwner(request.Context(), bookBorrowId)
+
+	if getBookBorrowError != nil {
+		if getBookBorrowError == sql.ErrNoRows {
+			common.ErrorResponse(writer, http.StatusNotFound, "book borrow not found")
+		} else {
+			common.ErrorResponse(writer, http.StatusInternalServerError, "failed to get book borrow details, please try again in a few minutes")
+		}
+
+		return
+	}
+
+	if !IsBookBorrowParty(userId, getBookBorrow.BookBorrow.BorrowerID, getBookBorrow.OwnerID) {
+		common.ErrorResponse(writer, http.StatusNotFound, "book borrow not found")
+
+		return
+	}
+
+	var conflictMessage string
+	var bookClaim database.BookClaim
+
+	transactionError := bookClaimAPIConfig.InTransaction(request.Context(), func(querier common.Querier) error {
+		_, reportBookBorrowLostError := querier.ReportBookBorrowLost(request.Context(), bookBorrowId)
+
+		if reportBookBorrowLostError == sql.ErrNoRows {
+			conflictMessage = "only a book that is still out on loan can be reported lost"
+
+			return errBookClaimConflict
+		} else if reportBookBorrowLostError != nil {
+			return reportBookBorrowLostError
+		}
+
+		createBookClaimParams := database.CreateBookClaimParams{
+			ID:           uuid.New(),
+			Kind:         BookClaimKindLoss,
+			Description:  createBookClaimParameters.Description,
+			BookBorrowID: bookBorrowId,
+			OpenedBy:     userId,
+		}
+
+		var createBookClaimError error
+
+		bookClaim, createBookClaimError = querier.CreateBookClaim(request.Context(), createBookClaimParams)
+
+		if createBookClaimError == sql.ErrNoRows {
+			conflictMessage = "this loan already has an open claim, settle it first"
+
+			return errBookClaimConflict
+		}
+
+		return createBookClaimError
+	})
+
+	if transactionError != nil {
+		if errors.Is(transactionError, errBookClaimConflict) {
+			common.ErrorResponse(writer, http.StatusConflict, conflictMessage)
+		} else {
+			common.ErrorResponse(writer, http.StatusInternalServerError, "error reporting book lost, please try again in a few minutes")
+		}
+
+		return
+	}
+
+	common.JSONResponse(writer, http.StatusCreated, BookClaimWithParties(bookClaim, getBookBorrow))
+}
+
+// Damage claims are the owner's to open, once the book is back in their hands.
+func (bookClaimAPIConfig *BookClaimAPIConfig) CreateBookClaim(writer http.ResponseWriter, request *http.Request, userId uuid.UUID) {
+	vars := mux.Vars(request)
+	bookBorrowId, parseBookBorrowIdError := uuid.Parse(vars["bookBorrowId"])
+
+	if parseBookBorrowIdError != nil {
+		common.ErrorResponse(writer, http.StatusBadRequest, "invalid book borrow id")
+
+		return
+	}
+
+	createBookClaimParameters := CreateBookClaimParameters{}
+
+	decoder := json.NewDecoder(request.Body)
+	decoderError := decoder.Decode(&createBookClaimParameters)
+
+	if decoderError != nil {
+		common.ErrorResponse(writer, http.StatusBadRequest, fmt.Sprintf("error parsing JSON: %s", decoderError))
+
+		return
+	}
+
+	createBookClaimParameters.Description = strings.TrimSpace(createBookClaimParameters.Description)
+
+	if createBookClaimParameters.Description == "" {
+		common.ErrorResponse(writer, http.StatusBadRequest, "description is required, say what was damaged")
+
+		return
+	}
+
+	if len(createBookClaimParameters.Description) > MaxBookClaimTextLength {
+		common.ErrorResponse(writer, http.StatusBadRequest, fmt.Sprintf("description must be at most %d characters", MaxBookClaimTextLength))
+
+		return
+	}
+
+	getBookBorrow, getBookBorrowError := bookClaimAPIConfig.DB.GetBookBorrowWithOwner(request.Context(), bookBorrowId)
+
+	if getBookBorrowError != nil {
+		if getBookBorrowError == sql.ErrNoRows {
+			common.ErrorResponse(writer, http.StatusNotFound, "book borrow not found")
+		} else {
+			common.ErrorResponse(writer, http.StatusInternalServerError, "failed to get book borrow details, please try again in a few minutes")
+		}
+
+		return
+	}
+
+	if !IsBookBorrowParty(userId, getBookBorrow.BookBorrow.BorrowerID, getBookBorrow.OwnerID) {
+		common.ErrorResponse(writer, http.StatusNotFound, "book borrow not found")
+
+		return
+	}
+
+	if userId != getBookBorrow.OwnerID {
+		common.ErrorResponse(writer, http.StatusForbidden, "only the book owner can open a damage claim")
+
+		return
+	}
+
+	if !getBookBorrow.BookBorrow.ReturnedAt.Valid {
+		common.ErrorResponse(writer, http.StatusConflict, "a damage claim can be opened once the book is returned")
+
+		return
+	}
+
+	createBookClaimParams := database.CreateBookClaimParams{
+		ID:           uuid.New(),
+		Kind:         BookClaimKindDamage,
+		Description:  createBookClaimParameters.Description,
+		BookBorrowID: bookBorrowId,
+		OpenedBy:     userId,
+	}
+
+	bookClaim, createBookClaimError := bookClaimAPIConfig.DB.CreateBookClaim(request.Context(), createBookClaimParams)
+
+	if createBookClaimError != nil {
+		if createBookClaimError == sql.ErrNoRows {
+			common.ErrorResponse(writer, http.StatusConflict, "this loan already has an open claim, settle it first")
+		} else {
+			common.ErrorResponse(writer, http.StatusInternalServerError, "error creating claim, please try again in a few minutes")
+		}
+
+		return
+	}
+
+	common.JSONResponse(writer, http.StatusCreated, BookClaimWithParties(bookClaim, getBookBorrow))
+}
+
+func (bookClaimAPIConfig *BookClaimAPIConfig) GetBookClaims(writer http.ResponseWriter, request *http.Request, userId uuid.UUID) {
+	getUserBookClaimsParams := database.GetUserBookClaimsParams{UserID: userId}
+
+	if status := request.URL.Query().Get("status"); status != "" {
+		if status != BookClaimStatusOpen && status != BookClaimStatusResolved {
+			common.ErrorResponse(writer, http.StatusBadRequest, fmt.Sprintf("invalid status: %s, must be one of: %s, %s", status, BookClaimStatusOpen, BookClaimStatusResolved))
+
+			return
+		}
+
+		getUserBookClaimsParams.Status = sql.NullString{String: status, Valid: true}
+	}
+
+	getUserBookClaims, getUserBookClaimsError := bookClaimAPIConfig.DB.GetUserBookClaims(request.Context(), getUserBookClaimsParams)
+
+	if getUserBookClaimsError != nil {
+		common.ErrorResponse(writer, http.StatusInternalServerError, "failed to get claims, please try again in a few minutes")
+
+		return
+	}
+
+	common.JSONResponse(writer, http.StatusOK, DatabaseGetUserBookClaimsRowsToJSON(getUserBookClaims))
+}
+
+func (bookClaimAPIConfig *BookClaimAPIConfig) GetBookClaim(writer http.ResponseWriter, request *http.Request, userId uuid.UUID) {
+	getBookClaim, found := bookClaimAPIConfig.getPartyBookClaim(writer, request, userId)
+
+	if !found {
+		return
+	}
+
+	getBookConditionReports, getBookConditionReportsError := bookClaimAPIConfig.DB.GetBookConditionReports(request.Context(), getBookClaim.BookClaim.BookBorrowID)
+
+	if getBookConditionReportsError != nil {
+		common.ErrorResponse(writer, http.StatusInternalServerError, "failed to get condition reports, please try again in a few minutes")
+
+		return
+	}
+
+	getBookClaimComments, getBookClaimCommentsError := bookClaimAPIConfig.DB.GetBookClaimComments(request.Context(), getBookClaim.BookClaim.ID)
+
+	if getBookClaimCommentsError != nil {
+		common.ErrorResponse(writer, http.StatusInternalServerError, "failed to get claim comments, please try again in a few minutes")
+
+		return
+	}
+
+	bookClaimDetails := BookClaimDetails{
+		BookClaim:        DatabaseGetBookClaimRowToJSON(getBookClaim),
+		ConditionReports: DatabaseBookConditionReportsToJSON(getBookConditionReports),
+		Comments:         DatabaseGetBookClaimCommentsRowsToJSON(getBookClaimComments),
+	}
+
+	common.JSONResponse(writer, http.StatusOK, bookClaimDetails)
+}
+
+func (bookClaimAPIConfig *BookClaimAPIConfig) CreateBookClaimComment(writer http.ResponseWriter, request *http.Request, userId uuid.UUID) {
+	createBookClaimCommentParameters := CreateBookClaimCommentParameters{}
+
+	decoder := json.NewDecoder(request.Body)
+	decoderError := decoder.Decode(&createBookClaimCommentParameters)
+
+	if decoderError != nil {
+		common.ErrorResponse(writer, http.StatusBadRequest, fmt.Sprintf("error parsing JSON: %s", decoderError))
+
+		return
+	}
+
+	createBookClaimCommentParameters.Body = strings.TrimSpace(createBookClaimCommentParameters.Body)
+
+	if createBookClaimCommentParameters.Body == "" {
+		common.ErrorResponse(writer, http.StatusBadRequest, "comment body is required")
+
+		return
+	}
+
+	if len(createBookClaimCommentParameters.Body) > MaxBookClaimTextLength {
+		common.ErrorResponse(writer, http.StatusBadRequest, fmt.Sprintf("comment must be at most %d characters", MaxBookClaimTextLength))
+
+		return
+	}
+
+	getBookClaim, found := bookClaimAPIConfig.getPartyBookClaim(writer, request, userId)
+
+	if !found {
+		return
+	}
+
+	createBookClaimCommentParams := database.CreateBookClaimCommentParams{
+		ID:          uuid.New(),
+		Body:        createBookClaimCommentParameters.Body,
+		AuthorID:    userId,
+		BookClaimID: getBookClaim.BookClaim.ID,
+	}
+
+	bookClaimComment, createBookClaimCommentError := bookClaimAPIConfig.DB.CreateBookClaimComment(request.Context(), createBookClaimCommentParams)
+
+	if createBookClaimCommentError != nil {
+		if createBookClaimCommentError == sql.ErrNoRows {
+			common.ErrorResponse(writer, http.StatusConflict, "the claim is resolved and closed to comments")
+		} else {
+			common.ErrorResponse(writer, http.StatusInternalServerError, "error adding comment, please try again in a few minutes")
+		}
+
+		return
+	}
+
+	common.JSONResponse(writer, http.StatusCreated, DatabaseBookClaimCommentToJSON(bookClaimComment))
+}
+
+// Only the owner settles a claim, it's their book and their call what the borrower owes.
+func (bookClaimAPIConfig *BookClaimAPIConfig) ResolveBookClaim(writer http.ResponseWriter, request *http.Request, userId uuid.UUID) {
+	resolveBookClaimParameters := ResolveBookClaimParameters{}
+
+	decoder := json.NewDecoder(request.Body)
+	decoderError := decoder.Decode(&resolveBookClaimParameters)
+
+	if decoderError != nil {
+		common.ErrorResponse(writer, http.StatusBadRequest, fmt.Sprintf("error parsing JSON: %s", decoderError))
+
+		return
+	}
+
+	if !slices.Contains(BookClaimResolutions, resolveBookClaimParameters.Resolution) {
+		common.ErrorResponse(writer, http.StatusBadRequest, fmt.Sprintf("invalid resolution: %s, must be one of: %s", resolveBookClaimParameters.Resolution, strings.Join(BookClaimResolutions, ", ")))
+
+		return
+	}
+
+	resolveBookClaimParameters.Note = strings.TrimSpace(resolveBookClaimParameters.Note)
+
+	if len(resolveBookClaimParameters.Note) > MaxBookClaimTextLength {
+		common.ErrorResponse(writer, http.StatusBadRequest, fmt.Sprintf("note must be at most %d characters", MaxBookClaimTextLength))
+
+		return
+	}
+
+	getBookClaim, found := bookClaimAPIConfig.getPartyBookClaim(writer, request, userId)
+
+	if !found {
+		return
+	}
+
+	if userId != getBookClaim.OwnerID {
+		common.ErrorResponse(writer, http.StatusForbidden, "only the book owner can resolve a claim")
+
+		return
+	}
+
+	if resolveBookClaimParameters.Resolution == BookClaimResolutionFound && getBookClaim.BookClaim.Kind != BookClaimKindLoss {
+		common.ErrorResponse(writer, http.StatusBadRequest, "only a loss claim can be resolved as found")
+
+		return
+	}
+
+	var resolvedBookClaim database.BookClaim
+
+	transactionError := bookClaimAPIConfig.InTransaction(request.Context(), func(querier common.Querier) error {
+		resolveBookClaimParams := database.ResolveBookClaimParams{
+			Resolution:     sql.NullString{String: resolveBookClaimParameters.Resolution, Valid: true},
+			ResolutionNote: resolveBookClaimParameters.Note,
+			ID:             getBookClaim.BookClaim.ID,
+		}
+
+		var resolveBookClaimError error
+
+		resolvedBookClaim, resolveBookClaimError = querier.ResolveBookClaim(request.Context(), resolveBookClaimParams)
+
+		if resolveBookClaimError == sql.ErrNoRows {
+			return errBookClaimConflict
+		} else if resolveBookClaimError != nil {
+			return resolveBookClaimError
+		}
+
+		if resolvedBookClaim.Kind != BookClaimKindLoss {
+			return nil
+		}
+
+		return ResolveBookLossClaim(request.Context(), querier, getBookClaim, resolveBookClaimParameters.Resolution)
+	})
+
+	if transactionError != nil {
+		if errors.Is(transactionError, errBookClaimConflict) {
+			common.ErrorResponse(writer, http.StatusConflict, "the claim is already resolved")
+		} else {
+			common.ErrorResponse(writer, http.StatusInternalServerError, "error resolving claim, please try again in a few minutes")
+		}
+
+		return
+	}
+
+	getBookClaim.BookClaim = resolvedBookClaim
+
+	common.JSONResponse(writer, http.StatusOK, DatabaseGetBookClaimRowToJSON(getBookClaim))
+}
+
+// Looks up the claim in the path and answers for the caller when it's missing or
+// not theirs to see. A claim on someone else's loan is reported as not found.
+func (bookClaimAPIConfig *BookClaimAPIConfig) getPartyBookClaim(writer http.ResponseWriter, request *http.Request, userId uuid.UUID) (database.GetBookClaimRow, bool) {
+	vars := mux.Vars(request)
+	bookClaimId, parseBookClaimIdError := uuid.Parse(vars["bookClaimId"])
+
+	if parseBookClaimIdError != nil {
+		common.ErrorResponse(writer, http.StatusBadRequest, "invalid book claim id")
+
+		return database.GetBookClaimRow{}, false
+	}
+
+	getBookClaim, getBookClaimError := bookClaimAPIConfig.DB.GetBookClaim(request.Context(), bookClaimId)
+
+	if getBookClaimError != nil {
+		if getBookClaimError == sql.ErrNoRows {
+			common.ErrorResponse(writer, http.StatusNotFound, "claim not found")
+		} else {
+			common.ErrorResponse(writer, http.StatusInternalServerError, "failed to get claim details, please try again in a few minutes")
+		}
+
+		return database.GetBookClaimRow{}, false
+	}
+
+	if !IsBookBorrowParty(userId, getBookClaim.BorrowerID, getBookClaim.OwnerID) {
+		common.ErrorResponse(writer, http.StatusNotFound, "claim not found")
+
+		return database.GetBookClaimRow{}, false
+	}
+
+	return getBookClaim, true
+}
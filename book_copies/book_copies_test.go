@@ -0,0 +1,418 @@
+package book_copies
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/elorenzorodz/co-library/common"
+	"github.com/elorenzorodz/co-library/internal/database"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+type MockQueries struct {
+	*common.BaseMock
+
+	GetBookFunc        func(ctx context.Context, id uuid.UUID) (database.Book, error)
+	CreateBookCopyFunc func(ctx context.Context, arg database.CreateBookCopyParams) (database.BookCopy, error)
+	GetBookCopiesFunc  func(ctx context.Context, bookID uuid.UUID) ([]database.GetBookCopiesRow, error)
+	UpdateBookCopyFunc func(ctx context.Context, arg database.UpdateBookCopyParams) (database.BookCopy, error)
+	DeleteBookCopyFunc func(ctx context.Context, arg database.DeleteBookCopyParams) (int64, error)
+	RetireBookCopyFunc func(ctx context.Context, arg database.RetireBookCopyParams) (int64, error)
+}
+
+func (mockQueries *MockQueries) GetBook(ctx context.Context, id uuid.UUID) (database.Book, error) {
+	if mockQueries.GetBookFunc != nil {
+		return mockQueries.GetBookFunc(ctx, id)
+	}
+
+	return mockQueries.BaseMock.GetBook(ctx, id)
+}
+
+func (mockQueries *MockQueries) CreateBookCopy(ctx context.Context, arg database.CreateBookCopyParams) (database.BookCopy, error) {
+	if mockQueries.CreateBookCopyFunc != nil {
+		return mockQueries.CreateBookCopyFunc(ctx, arg)
+	}
+
+	return mockQueries.BaseMock.CreateBookCopy(ctx, arg)
+}
+
+func (mockQueries *MockQueries) GetBookCopies(ctx context.Context, bookID uuid.UUID) ([]database.GetBookCopiesRow, error) {
+	if mockQueries.GetBookCopiesFunc != nil {
+		return mockQueries.GetBookCopiesFunc(ctx, bookID)
+	}
+
+	return mockQueries.BaseMock.GetBookCopies(ctx, bookID)
+}
+
+func (mockQueries *MockQueries) UpdateBookCopy(ctx context.Context, arg database.UpdateBookCopyParams) (database.BookCopy, error) {
+	if mockQueries.UpdateBookCopyFunc != nil {
+		return mockQueries.UpdateBookCopyFunc(ctx, arg)
+	}
+
+	return mockQueries.BaseMock.UpdateBookCopy(ctx, arg)
+}
+
+func (mockQueries *MockQueries) DeleteBookCopy(ctx context.Context, arg database.DeleteBookCopyParams) (int64, error) {
+	if mockQueries.DeleteBookCopyFunc != nil {
+		return mockQueries.DeleteBookCopyFunc(ctx, arg)
+	}
+
+	return mockQueries.BaseMock.DeleteBookCopy(ctx, arg)
+}
+
+func (mockQueries *MockQueries) RetireBookCopy(ctx context.Context, arg database.RetireBookCopyParams) (int64, error) {
+	if mockQueries.RetireBookCopyFunc != nil {
+		return mockQueries.RetireBookCopyFunc(ctx, arg)
+	}
+
+	return mockQueries.BaseMock.RetireBookCopy(ctx, arg)
+}
+
+func newTestBook(userId uuid.UUID) database.Book {
+	return database.Book{
+		ID:             uuid.New(),
+		Title:          "Dune",
+		Author:         "Frank Herbert",
+		UserID:         userId,
+		CreatedAt:      time.Now(),
+		UpdatedAt:      time.Now(),
+		LoanPeriodDays: 14,
+	}
+}
+
+func newTestBookCopy(bookID uuid.UUID, condition string) database.BookCopy {
+	return database.BookCopy{
+		ID:        uuid.New(),
+		Condition: condition,
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+		BookID:    bookID,
+	}
+}
+
+func TestCreateBookCopy(tTesting *testing.T) {
+	ownerID := uuid.New()
+	testBook := newTestBook(ownerID)
+
+	base := common.NewBaseMock()
+
+	createBookCopy := func(apiConfig BookCopyAPIConfig, body string, userId uuid.UUID) *httptest.ResponseRecorder {
+		request := httptest.NewRequest(http.MethodPost, fmt.Sprintf("/api/v1/books/copies/%s", testBook.ID), strings.NewReader(body))
+		request = mux.SetURLVars(request, map[string]string{"bookId": testBook.ID.String()})
+		recorder := httptest.NewRecorder()
+
+		apiConfig.CreateBookCopy(recorder, request, userId)
+
+		return recorder
+	}
+
+	// 1. Success: copies are in good condition unless the owner says otherwise.
+	tTesting.Run("Success", func(t *testing.T) {
+		for body, expectedCondition := range map[string]string{"": BookCopyConditionGood, `{"condition": "poor"}`: BookCopyConditionPoor} {
+			mockQueries := &MockQueries{
+				BaseMock: base,
+				GetBookFunc: func(ctx context.Context, id uuid.UUID) (database.Book, error) {
+					return testBook, nil
+				},
+				CreateBookCopyFunc: func(ctx context.Context, arg database.CreateBookCopyParams) (database.BookCopy, error) {
+					return newTestBookCopy(arg.BookID, arg.Condition), nil
+				},
+			}
+
+			recorder := createBookCopy(BookCopyAPIConfig{APIConfig: common.APIConfig{DB: mockQueries}}, body, ownerID)
+
+			if recorder.Code != http.StatusCreated {
+				t.Fatalf("Expected status %d, got %d. Body: %s", http.StatusCreated, recorder.Code, recorder.Body.String())
+			}
+
+			var bookCopy BookCopy
+			json.Unmarshal(recorder.Body.Bytes(), &bookCopy)
+
+			if bookCopy.Condition != expectedCondition || !bookCopy.Available {
+				t.Errorf("Expected an available copy in %s condition, got %+v", expectedCondition, bookCopy)
+			}
+		}
+	})
+
+	// 2. Failure: only the owner can add copies.
+	tTesting.Run("NotOwner", func(t *testing.T) {
+		mockQueries := &MockQueries{
+			BaseMock: base,
+			GetBookFunc: func(ctx context.Context, id uuid.UUID) (database.Book, error) {
+				return testBook, nil
+			},
+		}
+
+		recorder := createBookCopy(BookCopyAPIConfig{APIConfig: common.APIConfig{DB: mockQueries}}, "", uuid.New())
+
+		if recorder.Code != http.StatusForbidden {
+			t.Errorf("Expected status %d, got %d. Body: %s", http.StatusForbidden, recorder.Code, recorder.Body.String())
+		}
+	})
+
+	// 3. Failure: unknown condition.
+	tTesting.Run("InvalidCondition", func(t *testing.T) {
+		mockQueries := &MockQueries{BaseMock: base}
+
+		recorder := createBookCopy(BookCopyAPIConfig{APIConfig: common.APIConfig{DB: mockQueries}}, `{"condition": "mint"}`, ownerID)
+
+		if recorder.Code != http.StatusBadRequest {
+			t.Errorf("Expected status %d, got %d. Body: %s", http.StatusBadRequest, recorder.Code, recorder.Body.String())
+		}
+	})
+
+	// 4. Failure: book not found.
+	tTesting.Run("BookNotFound", func(t *testing.T) {
+		mockQueries := &MockQueries{BaseMock: base}
+
+		recorder := createBookCopy(BookCopyAPIConfig{APIConfig: common.APIConfig{DB: mockQueries}}, "", ownerID)
+
+		if recorder.Code != http.StatusNotFound {
+			t.Errorf("Expected status %d, got %d. Body: %s", http.StatusNotFound, recorder.Code, recorder.Body.String())
+		}
+	})
+}
+
+func TestGetBookCopies(tTesting *testing.T) {
+	testBook := newTestBook(uuid.New())
+
+	base := common.NewBaseMock()
+
+	// 1. Success: copies out on loan show when they are due back.
+	tTesting.Run("Success", func(t *testing.T) {
+		dueAt := time.Now().AddDate(0, 0, 7).UTC().Truncate(time.Second)
+
+		mockQueries := &MockQueries{
+			BaseMock: base,
+			GetBookFunc: func(ctx context.Context, id uuid.UUID) (database.Book, error) {
+				return testBook, nil
+			},
+			GetBookCopiesFunc: func(ctx context.Context, bookID uuid.UUID) ([]database.GetBookCopiesRow, error) {
+				return []database.GetBookCopiesRow{
+					{BookCopy: newTestBookCopy(bookID, BookCopyConditionGood)},
+					{BookCopy: newTestBookCopy(bookID, BookCopyConditionFair), BookBorrowID: uuid.NullUUID{UUID: uuid.New(), Valid: true}, DueAt: sql.NullTime{Time: dueAt, Valid: true}},
+				}, nil
+			},
+		}
+
+		apiConfig := BookCopyAPIConfig{APIConfig: common.APIConfig{DB: mockQueries}}
+		request := httptest.NewRequest(http.MethodGet, fmt.Sprintf("/api/v1/books/copies/%s", testBook.ID), nil)
+		request = mux.SetURLVars(request, map[string]string{"bookId": testBook.ID.String()})
+		recorder := httptest.NewRecorder()
+
+		apiConfig.GetBookCopies(recorder, request, uuid.New())
+
+		if recorder.Code != http.StatusOK {
+			t.Fatalf("Expected status %d, got %d. Body: %s", http.StatusOK, recorder.Code, recorder.Body.String())
+		}
+
+		var bookCopies []BookCopy
+		json.Unmarshal(recorder.Body.Bytes(), &bookCopies)
+
+		if len(bookCopies) != 2 || !bookCopies[0].Available || bookCopies[1].Available || !bookCopies[1].DueAt.Time.Equal(dueAt) {
+			t.Errorf("Unexpected copies: %+v", bookCopies)
+		}
+	})
+
+	// 2. Failure: book not found.
+	tTesting.Run("BookNotFound", func(t *testing.T) {
+		mockQueries := &MockQueries{BaseMock: base}
+
+		apiConfig := BookCopyAPIConfig{APIConfig: common.APIConfig{DB: mockQueries}}
+		request := httptest.NewRequest(http.MethodGet, fmt.Sprintf("/api/v1/books/copies/%s", testBook.ID), nil)
+		request = mux.SetURLVars(request, map[string]string{"bookId": testBook.ID.String()})
+		recorder := httptest.NewRecorder()
+
+		apiConfig.GetBookCopies(recorder, request, uuid.New())
+
+		if recorder.Code != http.StatusNotFound {
+			t.Errorf("Expected status %d, got %d. Body: %s", http.StatusNotFound, recorder.Code, recorder.Body.String())
+		}
+	})
+
+	// 2a. Failure: private books are not found for anyone but their owner.
+	tTesting.Run("PrivateBook", func(t *testing.T) {
+		privateBook := newTestBook(uuid.New())
+		privateBook.Visibility = "private"
+
+		mockQueries := &MockQueries{
+			BaseMock: base,
+			GetBookFunc: func(ctx context.Context, id uuid.UUID) (database.Book, error) {
+				return privateBook, nil
+			},
+			GetBookCopiesFunc: func(ctx context.Context, bookID uuid.UUID) ([]database.GetBookCopiesRow, error) {
+				t.Fatal("GetBookCopies should not be called for a book the viewer can't see")
+				return nil, nil
+			},
+		}
+
+		apiConfig := BookCopyAPIConfig{APIConfig: common.APIConfig{DB: mockQueries}}
+		request := httptest.NewRequest(http.MethodGet, fmt.Sprintf("/api/v1/books/copies/%s", privateBook.ID), nil)
+		request = mux.SetURLVars(request, map[string]string{"bookId": privateBook.ID.String()})
+		recorder := httptest.NewRecorder()
+
+		apiConfig.GetBookCopies(recorder, request, uuid.New())
+
+		if recorder.Code != http.StatusNotFound {
+			t.Errorf("Expected status %d, got %d. Body: %s", http.StatusNotFound, recorder.Code, recorder.Body.String())
+		}
+	})
+}
+
+func TestUpdateBookCopy(tTesting *testing.T) {
+	ownerID := uuid.New()
+	bookCopyID := uuid.New()
+
+	base := common.NewBaseMock()
+
+	updateBookCopy := func(mockQueries *MockQueries, body string) *httptest.ResponseRecorder {
+		apiConfig := BookCopyAPIConfig{APIConfig: common.APIConfig{DB: mockQueries}}
+		request := httptest.NewRequest(http.MethodPatch, fmt.Sprintf("/api/v1/books/copies/%s", bookCopyID), strings.NewReader(body))
+		request = mux.SetURLVars(request, map[string]string{"bookCopyId": bookCopyID.String()})
+		recorder := httptest.NewRecorder()
+
+		apiConfig.UpdateBookCopy(recorder, request, ownerID)
+
+		return recorder
+	}
+
+	// 1. Success: the owner records the copy's condition.
+	tTesting.Run("Success", func(t *testing.T) {
+		mockQueries := &MockQueries{
+			BaseMock: base,
+			UpdateBookCopyFunc: func(ctx context.Context, arg database.UpdateBookCopyParams) (database.BookCopy, error) {
+				if arg.ID != bookCopyID || arg.UserID != ownerID || arg.Condition != BookCopyConditionFair {
+					t.Errorf("UpdateBookCopy called with %+v", arg)
+				}
+
+				return newTestBookCopy(uuid.New(), arg.Condition), nil
+			},
+		}
+
+		if recorder := updateBookCopy(mockQueries, `{"condition": "fair"}`); recorder.Code != http.StatusOK {
+			t.Errorf("Expected status %d, got %d. Body: %s", http.StatusOK, recorder.Code, recorder.Body.String())
+		}
+	})
+
+	// 2. Failure: the condition is required and must be known.
+	tTesting.Run("InvalidCondition", func(t *testing.T) {
+		for _, body := range []string{`{}`, `{"condition": "mint"}`} {
+			if recorder := updateBookCopy(&MockQueries{BaseMock: base}, body); recorder.Code != http.StatusBadRequest {
+				t.Errorf("Expected status %d for %s, got %d", http.StatusBadRequest, body, recorder.Code)
+			}
+		}
+	})
+
+	// 3. Failure: copy not found or not the owner's (sql.ErrNoRows).
+	tTesting.Run("NotFound", func(t *testing.T) {
+		mockQueries := &MockQueries{
+			BaseMock: base,
+			UpdateBookCopyFunc: func(ctx context.Context, arg database.UpdateBookCopyParams) (database.BookCopy, error) {
+				return database.BookCopy{}, sql.ErrNoRows
+			},
+		}
+
+		if recorder := updateBookCopy(mockQueries, `{"condition": "fair"}`); recorder.Code != http.StatusNotFound {
+			t.Errorf("Expected status %d, got %d. Body: %s", http.StatusNotFound, recorder.Code, recorder.Body.String())
+		}
+	})
+}
+
+func TestDeleteBookCopy(tTesting *testing.T) {
+	ownerID := uuid.New()
+	bookCopyID := uuid.New()
+
+	base := common.NewBaseMock()
+
+	deleteBookCopy := func(mockQueries *MockQueries) *httptest.ResponseRecorder {
+		apiConfig := BookCopyAPIConfig{APIConfig: common.APIConfig{DB: mockQueries}}
+		request := httptest.NewRequest(http.MethodDelete, fmt.Sprintf("/api/v1/books/copies/%s", bookCopyID), nil)
+		request = mux.SetURLVars(request, map[string]string{"bookCopyId": bookCopyID.String()})
+		recorder := httptest.NewRecorder()
+
+		apiConfig.DeleteBookCopy(recorder, request, ownerID)
+
+		return recorder
+	}
+
+	// 1. Success: copy removed.
+	tTesting.Run("Success", func(t *testing.T) {
+		mockQueries := &MockQueries{
+			BaseMock: base,
+			DeleteBookCopyFunc: func(ctx context.Context, arg database.DeleteBookCopyParams) (int64, error) {
+				return 1, nil
+			},
+			RetireBookCopyFunc: func(ctx context.Context, arg database.RetireBookCopyParams) (int64, error) {
+				t.Fatal("RetireBookCopy should not be called once the copy is deleted")
+				return 0, nil
+			},
+		}
+
+		if recorder := deleteBookCopy(mockQueries); recorder.Code != http.StatusOK {
+			t.Errorf("Expected status %d, got %d. Body: %s", http.StatusOK, recorder.Code, recorder.Body.String())
+		}
+	})
+
+	// 1a. Success: a copy that was lent out before is retired, keeping its loan history.
+	tTesting.Run("Retired", func(t *testing.T) {
+		mockQueries := &MockQueries{
+			BaseMock: base,
+			RetireBookCopyFunc: func(ctx context.Context, arg database.RetireBookCopyParams) (int64, error) {
+				if arg.ID != bookCopyID || arg.UserID != ownerID {
+					t.Errorf("Expected copy %s of owner %s to be retired, got %+v", bookCopyID, ownerID, arg)
+				}
+				return 1, nil
+			},
+		}
+
+		recorder := deleteBookCopy(mockQueries)
+
+		if recorder.Code != http.StatusOK || !strings.Contains(recorder.Body.String(), "retired") {
+			t.Errorf("Expected status %d with the copy retired, got %d. Body: %s", http.StatusOK, recorder.Code, recorder.Body.String())
+		}
+	})
+
+	// 2. Failure: not found, not the owner's, out on loan or the only copy.
+	tTesting.Run("NotDeleted", func(t *testing.T) {
+		if recorder := deleteBookCopy(&MockQueries{BaseMock: base}); recorder.Code != http.StatusBadRequest {
+			t.Errorf("Expected status %d, got %d. Body: %s", http.StatusBadRequest, recorder.Code, recorder.Body.String())
+		}
+	})
+
+	// 3. Failure: DB error.
+	tTesting.Run("DBError", func(t *testing.T) {
+		mockQueries := &MockQueries{
+			BaseMock: base,
+			DeleteBookCopyFunc: func(ctx context.Context, arg database.DeleteBookCopyParams) (int64, error) {
+				return 0, errors.New("simulated DB error on delete book copy")
+			},
+		}
+
+		if recorder := deleteBookCopy(mockQueries); recorder.Code != http.StatusInternalServerError {
+			t.Errorf("Expected status %d, got %d. Body: %s", http.StatusInternalServerError, recorder.Code, recorder.Body.String())
+		}
+	})
+
+	// 3a. Failure: DB error retiring the copy.
+	tTesting.Run("RetireDBError", func(t *testing.T) {
+		mockQueries := &MockQueries{
+			BaseMock: base,
+			RetireBookCopyFunc: func(ctx context.Context, arg database.RetireBookCopyParams) (int64, error) {
+				return 0, errors.New("simulated DB error on retire book copy")
+			},
+		}
+
+		if recorder := deleteBookCopy(mockQueries); recorder.Code != http.StatusInternalServerError {
+			t.Errorf("Expected status %d, got %d. Body: %s", http.StatusInternalServerError, recorder.Code, recorder.Body.String())
+		}
+	})
+}
@@ -0,0 +1,74 @@
+package book_copies
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/elorenzorodz/co-library/common"
+	"github.com/elorenzorodz/co-library/internal/database"
+	"github.com/google/uuid"
+)
+
+func DatabaseBookCopyToBookCopyJSON(databaseBookCopy database.BookCopy) BookCopy {
+	return BookCopy{
+		ID:        databaseBookCopy.ID,
+		Condition: databaseBookCopy.Condition,
+		Available: databaseBookCopy.Condition != BookCopyConditionLost,
+		CreatedAt: databaseBookCopy.CreatedAt,
+		UpdatedAt: databaseBookCopy.UpdatedAt,
+		BookID:    databaseBookCopy.BookID,
+	}
+}
+
+// A copy is available when it is not out with a borrower or written off.
+func DatabaseBookCopiesRowsToBookCopiesJSON(databaseRows []database.GetBookCopiesRow) []BookCopy {
+	bookCopies := []BookCopy{}
+
+	for _, databaseRow := range databaseRows {
+		bookCopy := DatabaseBookCopyToBookCopyJSON(databaseRow.BookCopy)
+		bookCopy.Available = bookCopy.Available && !databaseRow.BookBorrowID.Valid
+		bookCopy.DueAt = databaseRow.DueAt
+
+		bookCopies = append(bookCopies, bookCopy)
+	}
+
+	return bookCopies
+}
+
+// Defaults an empty condition to good and rejects the ones we don't know.
+func ParseBookCopyCondition(condition string) (string, error) {
+	if condition == "" {
+		return BookCopyConditionGood, nil
+	}
+
+	if !slices.Contains(BookCopyConditions, condition) {
+		return "", fmt.Errorf("invalid condition: %s, use one of %s", condition, strings.Join(BookCopyConditions, ", "))
+	}
+
+	return condition, nil
+}
+
+// Puts the given number of copies of a book on the shelf, all in the same condition.
+func CreateBookCopies(ctx context.Context, querier common.Querier, bookId uuid.UUID, copies int32, condition string) ([]database.BookCopy, error) {
+	bookCopies := []database.BookCopy{}
+
+	for copyNumber := int32(0); copyNumber < copies; copyNumber++ {
+		createBookCopyParams := database.CreateBookCopyParams{
+			ID:        uuid.New(),
+			Condition: condition,
+			BookID:    bookId,
+		}
+
+		bookCopy, createBookCopyError := querier.CreateBookCopy(ctx, createBookCopyParams)
+
+		if createBookCopyError != nil {
+			return nil, createBookCopyError
+		}
+
+		bookCopies = append(bookCopies, bookCopy)
+	}
+
+	return bookCopies, nil
+}
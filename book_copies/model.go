@@ -0,0 +1,44 @@
+package book_copies
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/elorenzorodz/co-library/common"
+	"github.com/google/uuid"
+)
+
+type BookCopyAPIConfig struct {
+	common.APIConfig
+}
+
+// How worn a copy is, as judged by its owner.
+const (
+	BookCopyConditionNew  = "new"
+	BookCopyConditionGood = "good"
+	BookCopyConditionFair = "fair"
+	BookCopyConditionPoor = "poor"
+)
+
+var BookCopyConditions = []string{BookCopyConditionNew, BookCopyConditionGood, BookCopyConditionFair, BookCopyConditionPoor}
+
+// Copies written off after a loss claim. Owners can't pick it, but can set a copy that
+// turns up back to one of the conditions above.
+const BookCopyConditionLost = "lost"
+
+// How many copies an owner can put on the shelf along with a new book.
+const MaxNewBookCopies = 20
+
+type BookCopy struct {
+	ID        uuid.UUID    `json:"id"`
+	Condition string       `json:"condition"`
+	Available bool         `json:"available"`
+	DueAt     sql.NullTime `json:"dueAt"`
+	CreatedAt time.Time    `json:"createdAt"`
+	UpdatedAt time.Time    `json:"updatedAt"`
+	BookID    uuid.UUID    `json:"book_id"`
+}
+
+type UpsertBookCopyParameters struct {
+	Condition string `json:"condition"`
+}
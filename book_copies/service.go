@@ -0,0 +1,238 @@
+package book_copies
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/elorenzorodz/co-library/book_policies"
+	"github.com/elorenzorodz/co-library/common"
+	"github.com/elorenzorodz/co-library/internal/database"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+func (bookCopyAPIConfig *BookCopyAPIConfig) CreateBookCopy(writer http.ResponseWriter, request *http.Request, userId uuid.UUID) {
+	vars := mux.Vars(request)
+	bookId, parseBookIdError := uuid.Parse(vars["bookId"])
+
+	if parseBookIdError != nil {
+		common.ErrorResponse(writer, http.StatusBadRequest, "invalid book id")
+
+		return
+	}
+
+	// The request body is optional, new copies are in good condition unless the owner says otherwise.
+	upsertBookCopyParameters := UpsertBookCopyParameters{}
+
+	decoder := json.NewDecoder(request.Body)
+	decoderError := decoder.Decode(&upsertBookCopyParameters)
+
+	if decoderError != nil && !errors.Is(decoderError, io.EOF) {
+		common.ErrorResponse(writer, http.StatusBadRequest, fmt.Sprintf("error parsing JSON: %s", decoderError))
+
+		return
+	}
+
+	condition, parseConditionError := ParseBookCopyCondition(upsertBookCopyParameters.Condition)
+
+	if parseConditionError != nil {
+		common.ErrorResponse(writer, http.StatusBadRequest, parseConditionError.Error())
+
+		return
+	}
+
+	getBook, getBookError := bookCopyAPIConfig.DB.GetBook(request.Context(), bookId)
+
+	if getBookError != nil {
+		if getBookError == sql.ErrNoRows {
+			common.ErrorResponse(writer, http.StatusNotFound, "book not found")
+		} else {
+			common.ErrorResponse(writer, http.StatusInternalServerError, "failed to get book details, please try again in a few minutes")
+		}
+
+		return
+	}
+
+	if getBook.UserID != userId {
+		common.ErrorResponse(writer, http.StatusForbidden, "you can only add copies of your own books")
+
+		return
+	}
+
+	createBookCopyParams := database.CreateBookCopyParams{
+		ID:        uuid.New(),
+		Condition: condition,
+		BookID:    bookId,
+	}
+
+	newBookCopy, createBookCopyError := bookCopyAPIConfig.DB.CreateBookCopy(request.Context(), createBookCopyParams)
+
+	if createBookCopyError != nil {
+		common.ErrorResponse(writer, http.StatusInternalServerError, fmt.Sprintf("error adding book copy: %s", createBookCopyError))
+
+		return
+	}
+
+	common.JSONResponse(writer, http.StatusCreated, DatabaseBookCopyToBookCopyJSON(newBookCopy))
+}
+
+func (bookCopyAPIConfig *BookCopyAPIConfig) GetBookCopies(writer http.ResponseWriter, request *http.Request, userId uuid.UUID) {
+	vars := mux.Vars(request)
+	bookId, parseBookIdError := uuid.Parse(vars["bookId"])
+
+	if parseBookIdError != nil {
+		common.ErrorResponse(writer, http.StatusBadRequest, "invalid book id")
+
+		return
+	}
+
+	getBook, getBookError := bookCopyAPIConfig.DB.GetBook(request.Context(), bookId)
+
+	if getBookError != nil {
+		if getBookError == sql.ErrNoRows {
+			common.ErrorResponse(writer, http.StatusNotFound, "book not found")
+		} else {
+			common.ErrorResponse(writer, http.StatusInternalServerError, "failed to get book details, please try again in a few minutes")
+		}
+
+		return
+	}
+
+	// Copies of a book the viewer can't see don't exist as far as they know.
+	canViewBook, checkViewerError := book_policies.CheckBookViewer(request.Context(), bookCopyAPIConfig.DB, getBook, userId)
+
+	if checkViewerError != nil {
+		common.ErrorResponse(writer, http.StatusInternalServerError, "failed to get book details, please try again in a few minutes")
+
+		return
+	}
+
+	if !canViewBook {
+		common.ErrorResponse(writer, http.StatusNotFound, "book not found")
+
+		return
+	}
+
+	bookCopies, getBookCopiesError := bookCopyAPIConfig.DB.GetBookCopies(request.Context(), bookId)
+
+	if getBookCopiesError != nil {
+		common.ErrorResponse(writer, http.StatusInternalServerError, fmt.Sprintf("error getting book copies: %s", getBookCopiesError))
+
+		return
+	}
+
+	common.JSONResponse(writer, http.StatusOK, DatabaseBookCopiesRowsToBookCopiesJSON(bookCopies))
+}
+
+func (bookCopyAPIConfig *BookCopyAPIConfig) UpdateBookCopy(writer http.ResponseWriter, request *http.Request, userId uuid.UUID) {
+	vars := mux.Vars(request)
+	bookCopyId, parseBookCopyIdError := uuid.Parse(vars["bookCopyId"])
+
+	if parseBookCopyIdError != nil {
+		common.ErrorResponse(writer, http.StatusBadRequest, "invalid book copy id")
+
+		return
+	}
+
+	upsertBookCopyParameters := UpsertBookCopyParameters{}
+
+	decoder := json.NewDecoder(request.Body)
+	decoderError := decoder.Decode(&upsertBookCopyParameters)
+
+	if decoderError != nil {
+		common.ErrorResponse(writer, http.StatusBadRequest, fmt.Sprintf("error parsing JSON: %s", decoderError))
+
+		return
+	}
+
+	if upsertBookCopyParameters.Condition == "" {
+		common.ErrorResponse(writer, http.StatusBadRequest, "condition is required")
+
+		return
+	}
+
+	condition, parseConditionError := ParseBookCopyCondition(upsertBookCopyParameters.Condition)
+
+	if parseConditionError != nil {
+		common.ErrorResponse(writer, http.StatusBadRequest, parseConditionError.Error())
+
+		return
+	}
+
+	updateBookCopyParams := database.UpdateBookCopyParams{
+		Condition: condition,
+		ID:        bookCopyId,
+		UserID:    userId,
+	}
+
+	updateBookCopy, updateBookCopyError := bookCopyAPIConfig.DB.UpdateBookCopy(request.Context(), updateBookCopyParams)
+
+	if updateBookCopyError != nil {
+		if updateBookCopyError == sql.ErrNoRows {
+			common.ErrorResponse(writer, http.StatusNotFound, "book copy not found")
+		} else {
+			common.ErrorResponse(writer, http.StatusInternalServerError, "error updating book copy, please try again in a few minutes")
+		}
+
+		return
+	}
+
+	common.JSONResponse(writer, http.StatusOK, DatabaseBookCopyToBookCopyJSON(updateBookCopy))
+}
+
+func (bookCopyAPIConfig *BookCopyAPIConfig) DeleteBookCopy(writer http.ResponseWriter, request *http.Request, userId uuid.UUID) {
+	vars := mux.Vars(request)
+	bookCopyId, parseBookCopyIdError := uuid.Parse(vars["bookCopyId"])
+
+	if parseBookCopyIdError != nil {
+		common.ErrorResponse(writer, http.StatusBadRequest, "invalid book copy id")
+
+		return
+	}
+
+	deleteBookCopyParams := database.DeleteBookCopyParams{
+		ID:     bookCopyId,
+		UserID: userId,
+	}
+
+	// Copies out on loan stay until they come back, and the last copy goes with its book.
+	rowsAffected, deleteBookCopyError := bookCopyAPIConfig.DB.DeleteBookCopy(request.Context(), deleteBookCopyParams)
+
+	if deleteBookCopyError != nil {
+		common.ErrorResponse(writer, http.StatusInternalServerError, fmt.Sprintf("error deleting book copy: %s", deleteBookCopyError))
+
+		return
+	}
+
+	if rowsAffected != 0 {
+		common.JSONResponse(writer, http.StatusOK, "book copy successfully deleted")
+
+		return
+	}
+
+	// A copy that was lent out before is retired instead, so its loan history stays.
+	retireBookCopyParams := database.RetireBookCopyParams{
+		ID:     bookCopyId,
+		UserID: userId,
+	}
+
+	rowsAffected, retireBookCopyError := bookCopyAPIConfig.DB.RetireBookCopy(request.Context(), retireBookCopyParams)
+
+	if retireBookCopyError != nil {
+		common.ErrorResponse(writer, http.StatusInternalServerError, fmt.Sprintf("error deleting book copy: %s", retireBookCopyError))
+
+		return
+	}
+
+	if rowsAffected == 0 {
+		common.ErrorResponse(writer, http.StatusBadRequest, "failed to delete book copy: record not found, unauthorized, out on loan, or the only copy of the book")
+
+		return
+	}
+
+	common.JSONResponse(writer, http.StatusOK, "book copy retired, its loan history is kept")
+}
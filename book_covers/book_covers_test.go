@@ -0,0 +1,412 @@
+package book_covers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/elorenzorodz/co-library/books"
+	"github.com/elorenzorodz/co-library/common"
+	"github.com/elorenzorodz/co-library/internal/database"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+type MockQueries struct {
+	*common.BaseMock
+
+	GetBookFunc      func(ctx context.Context, id uuid.UUID) (database.Book, error)
+	SetBookCoverFunc func(ctx context.Context, arg database.SetBookCoverParams) (database.Book, error)
+}
+
+func (mockQueries *MockQueries) GetBook(ctx context.Context, id uuid.UUID) (database.Book, error) {
+	if mockQueries.GetBookFunc != nil {
+		return mockQueries.GetBookFunc(ctx, id)
+	}
+
+	return mockQueries.BaseMock.GetBook(ctx, id)
+}
+
+func (mockQueries *MockQueries) SetBookCover(ctx context.Context, arg database.SetBookCoverParams) (database.Book, error) {
+	if mockQueries.SetBookCoverFunc != nil {
+		return mockQueries.SetBookCoverFunc(ctx, arg)
+	}
+
+	return mockQueries.BaseMock.SetBookCover(ctx, arg)
+}
+
+// Keeps blobs in memory, Put fails for keys listed in failKeys.
+type memoryBlobStore struct {
+	sync.Mutex
+	blobs    map[string][]byte
+	failKeys map[string]bool
+}
+
+func newMemoryBlobStore() *memoryBlobStore {
+	return &memoryBlobStore{blobs: map[string][]byte{}, failKeys: map[string]bool{}}
+}
+
+func (memoryBlobStore *memoryBlobStore) Put(ctx context.Context, key string, contentType string, content []byte) (string, error) {
+	memoryBlobStore.Lock()
+	defer memoryBlobStore.Unlock()
+
+	for failKey := range memoryBlobStore.failKeys {
+		if strings.HasSuffix(key, failKey) {
+			return "", errors.New("disk full")
+		}
+	}
+
+	memoryBlobStore.blobs[key] = content
+
+	return "https://cdn.example.com/" + key, nil
+}
+
+func (memoryBlobStore *memoryBlobStore) Delete(ctx context.Context, key string) error {
+	memoryBlobStore.Lock()
+	defer memoryBlobStore.Unlock()
+
+	delete(memoryBlobStore.blobs, key)
+
+	return nil
+}
+
+func newTestBook(userId uuid.UUID) database.Book {
+	return database.Book{
+		ID:             uuid.New(),
+		Title:          "Dune",
+		Author:         "Frank Herbert",
+		UserID:         userId,
+		CreatedAt:      time.Now(),
+		UpdatedAt:      time.Now(),
+		LoanPeriodDays: 14,
+		Visibility:     "public",
+		Lendable:       true,
+		BorrowerPolicy: "anyone",
+		CoverKeys:      []string{},
+	}
+}
+
+func encodeTestImage(t *testing.T, format string, width int, height int, fill color.Color) []byte {
+	t.Helper()
+
+	testImage := image.NewNRGBA(image.Rect(0, 0, width, height))
+
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			testImage.Set(x, y, fill)
+		}
+	}
+
+	var imageBuffer bytes.Buffer
+	var encodeError error
+
+	if format == "png" {
+		encodeError = png.Encode(&imageBuffer, testImage)
+	} else {
+		encodeError = jpeg.Encode(&imageBuffer, testImage, nil)
+	}
+
+	if encodeError != nil {
+		t.Fatal(encodeError)
+	}
+
+	return imageBuffer.Bytes()
+}
+
+func coverRequest(t *testing.T, bookId uuid.UUID, fieldName string, content []byte) *http.Request {
+	t.Helper()
+
+	var body bytes.Buffer
+	multipartWriter := multipart.NewWriter(&body)
+	formFile, _ := multipartWriter.CreateFormFile(fieldName, "cover.bin")
+	formFile.Write(content)
+	multipartWriter.Close()
+
+	request := httptest.NewRequest(http.MethodPost, fmt.Sprintf("/api/v1/books/%s/cover", bookId), &body)
+	request.Header.Set("Content-Type", multipartWriter.FormDataContentType())
+
+	return mux.SetURLVars(request, map[string]string{"bookId": bookId.String()})
+}
+
+func TestUploadBookCover(tTesting *testing.T) {
+	ownerID := uuid.New()
+	testBook := newTestBook(ownerID)
+
+	base := common.NewBaseMock()
+
+	setBookCover := func(ctx context.Context, arg database.SetBookCoverParams) (database.Book, error) {
+		updatedBook := testBook
+		updatedBook.CoverUrl = arg.CoverUrl
+		updatedBook.CoverThumbnailUrl = arg.CoverThumbnailUrl
+		updatedBook.CoverKeys = arg.CoverKeys
+
+		return updatedBook, nil
+	}
+
+	uploadBookCover := func(apiConfig BookCoverAPIConfig, request *http.Request, userId uuid.UUID) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+
+		apiConfig.UploadBookCover(recorder, request, userId)
+
+		return recorder
+	}
+
+	// 1. Success: the cover and its thumbnail are stored and the old upload is removed.
+	tTesting.Run("Success", func(t *testing.T) {
+		blobs := newMemoryBlobStore()
+		blobs.blobs["covers/old.png"] = []byte("old")
+		blobs.blobs["covers/old-thumbnail.jpg"] = []byte("old")
+
+		bookWithCover := testBook
+		bookWithCover.CoverKeys = []string{"covers/old.png", "covers/old-thumbnail.jpg"}
+
+		var setBookCoverParams database.SetBookCoverParams
+
+		mockQueries := &MockQueries{
+			BaseMock: base,
+			GetBookFunc: func(ctx context.Context, id uuid.UUID) (database.Book, error) {
+				return bookWithCover, nil
+			},
+			SetBookCoverFunc: func(ctx context.Context, arg database.SetBookCoverParams) (database.Book, error) {
+				setBookCoverParams = arg
+
+				return setBookCover(ctx, arg)
+			},
+		}
+
+		request := coverRequest(t, testBook.ID, BookCoverFormField, encodeTestImage(t, "png", 800, 600, color.NRGBA{R: 200, A: 255}))
+		recorder := uploadBookCover(BookCoverAPIConfig{APIConfig: common.APIConfig{DB: mockQueries, Blobs: blobs}}, request, ownerID)
+
+		if recorder.Code != http.StatusOK {
+			t.Fatalf("Expected status %d, got %d. Body: %s", http.StatusOK, recorder.Code, recorder.Body.String())
+		}
+
+		var book books.Book
+		json.Unmarshal(recorder.Body.Bytes(), &book)
+
+		coverKey := fmt.Sprintf("covers/%s/", testBook.ID)
+
+		if len(setBookCoverParams.CoverKeys) != 2 || !strings.HasPrefix(setBookCoverParams.CoverKeys[0], coverKey) || !strings.HasSuffix(setBookCoverParams.CoverKeys[0], ".png") ||
+			!strings.HasSuffix(setBookCoverParams.CoverKeys[1], "-thumbnail.jpg") {
+			t.Fatalf("Unexpected cover keys: %v", setBookCoverParams.CoverKeys)
+		}
+
+		if book.CoverURL != "https://cdn.example.com/"+setBookCoverParams.CoverKeys[0] || book.CoverThumbnailURL != "https://cdn.example.com/"+setBookCoverParams.CoverKeys[1] {
+			t.Errorf("Unexpected cover URLs: %q %q", book.CoverURL, book.CoverThumbnailURL)
+		}
+
+		thumbnailConfig, _, err := image.DecodeConfig(bytes.NewReader(blobs.blobs[setBookCoverParams.CoverKeys[1]]))
+
+		if err != nil || thumbnailConfig.Width != BookCoverThumbnailWidth || thumbnailConfig.Height != 150 {
+			t.Errorf("Expected a 200x150 thumbnail, got %+v (%v)", thumbnailConfig, err)
+		}
+
+		if _, found := blobs.blobs["covers/old.png"]; found || len(blobs.blobs) != 2 {
+			t.Errorf("Expected only the new blobs to be left, got %d", len(blobs.blobs))
+		}
+	})
+
+	// 2. Failure: only the owner can change the cover.
+	tTesting.Run("NotOwner", func(t *testing.T) {
+		mockQueries := &MockQueries{
+			BaseMock: base,
+			GetBookFunc: func(ctx context.Context, id uuid.UUID) (database.Book, error) {
+				return testBook, nil
+			},
+		}
+
+		request := coverRequest(t, testBook.ID, BookCoverFormField, encodeTestImage(t, "png", 10, 10, color.White))
+		recorder := uploadBookCover(BookCoverAPIConfig{APIConfig: common.APIConfig{DB: mockQueries, Blobs: newMemoryBlobStore()}}, request, uuid.New())
+
+		if recorder.Code != http.StatusForbidden {
+			t.Errorf("Expected status %d, got %d. Body: %s", http.StatusForbidden, recorder.Code, recorder.Body.String())
+		}
+	})
+
+	// 3. Failure: bad uploads are rejected before anything is stored.
+	tTesting.Run("InvalidUpload", func(t *testing.T) {
+		tests := []struct {
+			name           string
+			fieldName      string
+			content        []byte
+			expectedStatus int
+		}{
+			{"MissingField", "image", encodeTestImage(t, "png", 10, 10, color.White), http.StatusBadRequest},
+			{"NotAnImage", BookCoverFormField, []byte("%PDF-1.4 not a cover"), http.StatusUnsupportedMediaType},
+			{"Corrupt", BookCoverFormField, encodeTestImage(t, "png", 10, 10, color.White)[:40], http.StatusBadRequest},
+			{"TooLarge", BookCoverFormField, append(encodeTestImage(t, "png", 10, 10, color.White), make([]byte, MaxBookCoverSize)...), http.StatusRequestEntityTooLarge},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				blobs := newMemoryBlobStore()
+				mockQueries := &MockQueries{
+					BaseMock: base,
+					GetBookFunc: func(ctx context.Context, id uuid.UUID) (database.Book, error) {
+						return testBook, nil
+					},
+				}
+
+				recorder := uploadBookCover(BookCoverAPIConfig{APIConfig: common.APIConfig{DB: mockQueries, Blobs: blobs}}, coverRequest(t, testBook.ID, tt.fieldName, tt.content), ownerID)
+
+				if recorder.Code != tt.expectedStatus || len(blobs.blobs) != 0 {
+					t.Errorf("Expected status %d and no blobs, got %d (%d blobs). Body: %s", tt.expectedStatus, recorder.Code, len(blobs.blobs), recorder.Body.String())
+				}
+			})
+		}
+	})
+
+	// 4. Failure: the stored cover is cleaned up when the thumbnail cannot be stored.
+	tTesting.Run("StoreError", func(t *testing.T) {
+		blobs := newMemoryBlobStore()
+		blobs.failKeys["-thumbnail.jpg"] = true
+
+		mockQueries := &MockQueries{
+			BaseMock: base,
+			GetBookFunc: func(ctx context.Context, id uuid.UUID) (database.Book, error) {
+				return testBook, nil
+			},
+		}
+
+		request := coverRequest(t, testBook.ID, BookCoverFormField, encodeTestImage(t, "jpeg", 10, 10, color.White))
+		recorder := uploadBookCover(BookCoverAPIConfig{APIConfig: common.APIConfig{DB: mockQueries, Blobs: blobs}}, request, ownerID)
+
+		if recorder.Code != http.StatusInternalServerError || len(blobs.blobs) != 0 {
+			t.Errorf("Expected status %d and no blobs, got %d (%d blobs)", http.StatusInternalServerError, recorder.Code, len(blobs.blobs))
+		}
+	})
+
+	// 5. Failure: uploads are turned off.
+	tTesting.Run("NoBlobStore", func(t *testing.T) {
+		request := coverRequest(t, testBook.ID, BookCoverFormField, encodeTestImage(t, "png", 10, 10, color.White))
+		recorder := uploadBookCover(BookCoverAPIConfig{APIConfig: common.APIConfig{DB: &MockQueries{BaseMock: base}}}, request, ownerID)
+
+		if recorder.Code != http.StatusServiceUnavailable {
+			t.Errorf("Expected status %d, got %d", http.StatusServiceUnavailable, recorder.Code)
+		}
+	})
+}
+
+func TestDeleteBookCover(tTesting *testing.T) {
+	ownerID := uuid.New()
+	testBook := newTestBook(ownerID)
+	testBook.CoverUrl = "https://cdn.example.com/covers/cover.png"
+	testBook.CoverKeys = []string{"covers/cover.png", "covers/cover-thumbnail.jpg"}
+
+	base := common.NewBaseMock()
+
+	deleteBookCover := func(apiConfig BookCoverAPIConfig, userId uuid.UUID) *httptest.ResponseRecorder {
+		request := httptest.NewRequest(http.MethodDelete, fmt.Sprintf("/api/v1/books/%s/cover", testBook.ID), nil)
+		request = mux.SetURLVars(request, map[string]string{"bookId": testBook.ID.String()})
+		recorder := httptest.NewRecorder()
+
+		apiConfig.DeleteBookCover(recorder, request, userId)
+
+		return recorder
+	}
+
+	// 1. Success: the cover is cleared and its blobs removed.
+	tTesting.Run("Success", func(t *testing.T) {
+		blobs := newMemoryBlobStore()
+		blobs.blobs["covers/cover.png"] = []byte("cover")
+		blobs.blobs["covers/cover-thumbnail.jpg"] = []byte("thumbnail")
+
+		mockQueries := &MockQueries{
+			BaseMock: base,
+			GetBookFunc: func(ctx context.Context, id uuid.UUID) (database.Book, error) {
+				return testBook, nil
+			},
+			SetBookCoverFunc: func(ctx context.Context, arg database.SetBookCoverParams) (database.Book, error) {
+				if arg.CoverUrl != "" || arg.CoverThumbnailUrl != "" || len(arg.CoverKeys) != 0 {
+					t.Errorf("Expected the cover to be cleared, got %+v", arg)
+				}
+
+				return newTestBook(ownerID), nil
+			},
+		}
+
+		recorder := deleteBookCover(BookCoverAPIConfig{APIConfig: common.APIConfig{DB: mockQueries, Blobs: blobs}}, ownerID)
+
+		if recorder.Code != http.StatusOK || len(blobs.blobs) != 0 {
+			t.Errorf("Expected status %d and no blobs, got %d (%d blobs). Body: %s", http.StatusOK, recorder.Code, len(blobs.blobs), recorder.Body.String())
+		}
+	})
+
+	// 2. Failure: only the owner can remove the cover.
+	tTesting.Run("NotOwner", func(t *testing.T) {
+		mockQueries := &MockQueries{
+			BaseMock: base,
+			GetBookFunc: func(ctx context.Context, id uuid.UUID) (database.Book, error) {
+				return testBook, nil
+			},
+		}
+
+		if recorder := deleteBookCover(BookCoverAPIConfig{APIConfig: common.APIConfig{DB: mockQueries, Blobs: newMemoryBlobStore()}}, uuid.New()); recorder.Code != http.StatusForbidden {
+			t.Errorf("Expected status %d, got %d", http.StatusForbidden, recorder.Code)
+		}
+	})
+
+	// 3. Failure: book not found.
+	tTesting.Run("BookNotFound", func(t *testing.T) {
+		if recorder := deleteBookCover(BookCoverAPIConfig{APIConfig: common.APIConfig{DB: &MockQueries{BaseMock: base}}}, ownerID); recorder.Code != http.StatusNotFound {
+			t.Errorf("Expected status %d, got %d", http.StatusNotFound, recorder.Code)
+		}
+	})
+}
+
+func TestGenerateBookCoverThumbnail(tTesting *testing.T) {
+	// 1. Success: tall covers fit the box height and small covers keep their size.
+	tTesting.Run("Sizes", func(t *testing.T) {
+		for _, tt := range []struct{ width, height, expectedWidth, expectedHeight int }{
+			{1000, 3000, 100, 300},
+			{120, 90, 120, 90},
+		} {
+			thumbnail, err := GenerateBookCoverThumbnail(image.NewNRGBA(image.Rect(0, 0, tt.width, tt.height)))
+
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+
+			thumbnailConfig, _ := jpeg.DecodeConfig(bytes.NewReader(thumbnail))
+
+			if thumbnailConfig.Width != tt.expectedWidth || thumbnailConfig.Height != tt.expectedHeight {
+				t.Errorf("Expected %dx%d, got %dx%d", tt.expectedWidth, tt.expectedHeight, thumbnailConfig.Width, thumbnailConfig.Height)
+			}
+		}
+	})
+
+	// 2. Success: transparent covers are flattened onto white.
+	tTesting.Run("Transparent", func(t *testing.T) {
+		thumbnail, _ := GenerateBookCoverThumbnail(image.NewNRGBA(image.Rect(0, 0, 400, 600)))
+		thumbnailImage, _ := jpeg.Decode(bytes.NewReader(thumbnail))
+
+		if red, green, blue, _ := thumbnailImage.At(100, 150).RGBA(); red>>8 < 250 || green>>8 < 250 || blue>>8 < 250 {
+			t.Errorf("Expected a white pixel, got %d %d %d", red>>8, green>>8, blue>>8)
+		}
+	})
+}
+
+func TestDecodeBookCover(tTesting *testing.T) {
+	// 1. Failure: images above the pixel limit are rejected from their header.
+	tTesting.Run("TooManyPixels", func(t *testing.T) {
+		var imageBuffer bytes.Buffer
+		png.Encode(&imageBuffer, image.NewGray(image.Rect(0, 0, MaxBookCoverDimension+1, 1)))
+
+		if _, status, err := DecodeBookCover(imageBuffer.Bytes()); err == nil || status != http.StatusBadRequest {
+			t.Errorf("Expected status %d, got %d (%v)", http.StatusBadRequest, status, err)
+		}
+	})
+}
@@ -0,0 +1,181 @@
+package book_covers
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	_ "image/gif"
+	"image/jpeg"
+	_ "image/png"
+	"io"
+	"log"
+	"net/http"
+
+	"github.com/elorenzorodz/co-library/books"
+	"github.com/elorenzorodz/co-library/common"
+	"github.com/elorenzorodz/co-library/internal/database"
+	"github.com/google/uuid"
+)
+
+// Reads the cover out of the multipart form and checks its type and size.
+// The content type is sniffed, whatever the client claims is ignored.
+func ReadBookCover(writer http.ResponseWriter, request *http.Request) (BookCover, int, error) {
+	// Leaves room for the multipart boundaries and headers around the file.
+	request.Body = http.MaxBytesReader(writer, request.Body, MaxBookCoverSize+(64<<10))
+
+	coverFile, _, formFileError := request.FormFile(BookCoverFormField)
+
+	if formFileError != nil {
+		var maxBytesError *http.MaxBytesError
+
+		if errors.As(formFileError, &maxBytesError) {
+			return BookCover{}, http.StatusRequestEntityTooLarge, fmt.Errorf("cover image must be at most %d MB", MaxBookCoverSize>>20)
+		}
+
+		return BookCover{}, http.StatusBadRequest, fmt.Errorf("cover image is required in the %q form field", BookCoverFormField)
+	}
+
+	defer coverFile.Close()
+
+	content, readError := io.ReadAll(io.LimitReader(coverFile, MaxBookCoverSize+1))
+
+	if readError != nil {
+		return BookCover{}, http.StatusBadRequest, fmt.Errorf("error reading cover image: %s", readError)
+	}
+
+	if len(content) > MaxBookCoverSize {
+		return BookCover{}, http.StatusRequestEntityTooLarge, fmt.Errorf("cover image must be at most %d MB", MaxBookCoverSize>>20)
+	}
+
+	return DecodeBookCover(content)
+}
+
+func DecodeBookCover(content []byte) (BookCover, int, error) {
+	contentType := http.DetectContentType(content)
+
+	if _, allowed := BookCoverExtensions[contentType]; !allowed {
+		return BookCover{}, http.StatusUnsupportedMediaType, errors.New("cover image must be a JPEG, PNG or GIF")
+	}
+
+	// The header is checked first so huge images are turned away before they are decoded.
+	imageConfig, _, decodeConfigError := image.DecodeConfig(bytes.NewReader(content))
+
+	if decodeConfigError != nil {
+		return BookCover{}, http.StatusBadRequest, errors.New("cover image is corrupt or incomplete")
+	}
+
+	if imageConfig.Width > MaxBookCoverDimension || imageConfig.Height > MaxBookCoverDimension {
+		return BookCover{}, http.StatusBadRequest, fmt.Errorf("cover image must be at most %dx%d pixels", MaxBookCoverDimension, MaxBookCoverDimension)
+	}
+
+	coverImage, _, decodeError := image.Decode(bytes.NewReader(content))
+
+	if decodeError != nil {
+		return BookCover{}, http.StatusBadRequest, errors.New("cover image is corrupt or incomplete")
+	}
+
+	return BookCover{Content: content, ContentType: contentType, Image: coverImage}, http.StatusOK, nil
+}
+
+// Shrinks the cover to fit the thumbnail box, averaging the pixels each
+// thumbnail pixel covers. Transparent areas are flattened onto white since
+// thumbnails are always JPEGs.
+func GenerateBookCoverThumbnail(coverImage image.Image) ([]byte, error) {
+	bounds := coverImage.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+
+	if width == 0 || height == 0 {
+		return nil, errors.New("cover image is empty")
+	}
+
+	thumbnailWidth, thumbnailHeight := width, height
+
+	// Smaller covers are kept at their size, only larger ones are scaled down.
+	if width > BookCoverThumbnailWidth || height > BookCoverThumbnailHeight {
+		if width*BookCoverThumbnailHeight > height*BookCoverThumbnailWidth {
+			thumbnailWidth = BookCoverThumbnailWidth
+			thumbnailHeight = max(1, height*BookCoverThumbnailWidth/width)
+		} else {
+			thumbnailHeight = BookCoverThumbnailHeight
+			thumbnailWidth = max(1, width*BookCoverThumbnailHeight/height)
+		}
+	}
+
+	thumbnail := image.NewRGBA(image.Rect(0, 0, thumbnailWidth, thumbnailHeight))
+
+	for thumbnailY := 0; thumbnailY < thumbnailHeight; thumbnailY++ {
+		fromY := bounds.Min.Y + thumbnailY*height/thumbnailHeight
+		toY := max(fromY+1, bounds.Min.Y+(thumbnailY+1)*height/thumbnailHeight)
+
+		for thumbnailX := 0; thumbnailX < thumbnailWidth; thumbnailX++ {
+			fromX := bounds.Min.X + thumbnailX*width/thumbnailWidth
+			toX := max(fromX+1, bounds.Min.X+(thumbnailX+1)*width/thumbnailWidth)
+
+			var red, green, blue, alpha uint64
+
+			for y := fromY; y < toY; y++ {
+				for x := fromX; x < toX; x++ {
+					pixelRed, pixelGreen, pixelBlue, pixelAlpha := coverImage.At(x, y).RGBA()
+					red += uint64(pixelRed)
+					green += uint64(pixelGreen)
+					blue += uint64(pixelBlue)
+					alpha += uint64(pixelAlpha)
+				}
+			}
+
+			pixels := uint64((toX - fromX) * (toY - fromY))
+			// Colors are alpha premultiplied, so white shows through by whatever alpha is missing.
+			white := 0xffff - alpha/pixels
+
+			thumbnail.SetRGBA(thumbnailX, thumbnailY, color.RGBA{
+				R: uint8((red/pixels + white) >> 8),
+				G: uint8((green/pixels + white) >> 8),
+				B: uint8((blue/pixels + white) >> 8),
+				A: 0xff,
+			})
+		}
+	}
+
+	var thumbnailBuffer bytes.Buffer
+
+	if encodeError := jpeg.Encode(&thumbnailBuffer, thumbnail, &jpeg.Options{Quality: BookCoverThumbnailQuality}); encodeError != nil {
+		return nil, fmt.Errorf("error encoding cover thumbnail: %w", encodeError)
+	}
+
+	return thumbnailBuffer.Bytes(), nil
+}
+
+// Every upload gets new keys so cached copies of an old cover never show up for a new one.
+func BookCoverKeys(bookId uuid.UUID, contentType string) (string, string) {
+	uploadId := uuid.New()
+
+	return fmt.Sprintf("covers/%s/%s%s", bookId, uploadId, BookCoverExtensions[contentType]),
+		fmt.Sprintf("covers/%s/%s-thumbnail.jpg", bookId, uploadId)
+}
+
+// Cleanup of blobs nothing points at anymore, failures are only logged.
+func DeleteBookCoverBlobs(ctx context.Context, blobs common.BlobStore, keys []string) {
+	for _, key := range keys {
+		if deleteError := blobs.Delete(ctx, key); deleteError != nil {
+			log.Printf("error deleting cover blob %s: %s", key, deleteError)
+		}
+	}
+}
+
+// The book as the books endpoints show it to its owner.
+func GetBookWithGenresAndTags(ctx context.Context, querier common.Querier, databaseBook database.Book) (books.Book, error) {
+	bookGenresAndTags, getBookGenresAndTagsError := querier.GetBookGenresAndTags(ctx, databaseBook.ID)
+
+	if getBookGenresAndTagsError != nil {
+		return books.Book{}, getBookGenresAndTagsError
+	}
+
+	book := books.DatabaseBookToBookJSON(databaseBook)
+	book.Genres = append(book.Genres, bookGenresAndTags.Genres...)
+	book.Tags = append(book.Tags, bookGenresAndTags.Tags...)
+
+	return book, nil
+}
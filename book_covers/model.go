@@ -0,0 +1,36 @@
+package book_covers
+
+import (
+	"image"
+
+	"github.com/elorenzorodz/co-library/common"
+)
+
+type BookCoverAPIConfig struct {
+	common.APIConfig
+}
+
+const (
+	// Name of the multipart form field holding the image.
+	BookCoverFormField = "cover"
+	MaxBookCoverSize   = 5 << 20
+	// Larger images take too long to decode for a cover.
+	MaxBookCoverDimension = 6000
+	// Thumbnails fit in this box and keep the cover's aspect ratio.
+	BookCoverThumbnailWidth   = 200
+	BookCoverThumbnailHeight  = 300
+	BookCoverThumbnailQuality = 85
+)
+
+// Accepted cover types, by sniffed content type, and the extension they are stored with.
+var BookCoverExtensions = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/gif":  ".gif",
+}
+
+type BookCover struct {
+	Content     []byte
+	ContentType string
+	Image       image.Image
+}
@@ -0,0 +1,182 @@
+package book_covers
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+
+	"github.com/elorenzorodz/co-library/common"
+	"github.com/elorenzorodz/co-library/internal/database"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+func (bookCoverAPIConfig *BookCoverAPIConfig) UploadBookCover(writer http.ResponseWriter, request *http.Request, userId uuid.UUID) {
+	vars := mux.Vars(request)
+	bookId, parseBookIdError := uuid.Parse(vars["bookId"])
+
+	if parseBookIdError != nil {
+		common.ErrorResponse(writer, http.StatusBadRequest, "invalid book id")
+
+		return
+	}
+
+	if bookCoverAPIConfig.Blobs == nil {
+		common.ErrorResponse(writer, http.StatusServiceUnavailable, "cover uploads are turned off")
+
+		return
+	}
+
+	getBook, getBookError := bookCoverAPIConfig.DB.GetBook(request.Context(), bookId)
+
+	if getBookError != nil {
+		if getBookError == sql.ErrNoRows {
+			common.ErrorResponse(writer, http.StatusNotFound, "book not found")
+		} else {
+			common.ErrorResponse(writer, http.StatusInternalServerError, "failed to get book details, please try again in a few minutes")
+		}
+
+		return
+	}
+
+	if getBook.UserID != userId {
+		common.ErrorResponse(writer, http.StatusForbidden, "you can only change the cover of your own books")
+
+		return
+	}
+
+	bookCover, readStatus, readBookCoverError := ReadBookCover(writer, request)
+
+	if readBookCoverError != nil {
+		common.ErrorResponse(writer, readStatus, readBookCoverError.Error())
+
+		return
+	}
+
+	thumbnail, generateThumbnailError := GenerateBookCoverThumbnail(bookCover.Image)
+
+	if generateThumbnailError != nil {
+		common.ErrorResponse(writer, http.StatusBadRequest, generateThumbnailError.Error())
+
+		return
+	}
+
+	coverKey, thumbnailKey := BookCoverKeys(bookId, bookCover.ContentType)
+
+	coverURL, putCoverError := bookCoverAPIConfig.Blobs.Put(request.Context(), coverKey, bookCover.ContentType, bookCover.Content)
+
+	if putCoverError != nil {
+		common.ErrorResponse(writer, http.StatusInternalServerError, fmt.Sprintf("error storing cover image: %s", putCoverError))
+
+		return
+	}
+
+	thumbnailURL, putThumbnailError := bookCoverAPIConfig.Blobs.Put(request.Context(), thumbnailKey, "image/jpeg", thumbnail)
+
+	if putThumbnailError != nil {
+		DeleteBookCoverBlobs(request.Context(), bookCoverAPIConfig.Blobs, []string{coverKey})
+
+		common.ErrorResponse(writer, http.StatusInternalServerError, fmt.Sprintf("error storing cover thumbnail: %s", putThumbnailError))
+
+		return
+	}
+
+	setBookCoverParams := database.SetBookCoverParams{
+		CoverUrl:          coverURL,
+		CoverThumbnailUrl: thumbnailURL,
+		CoverKeys:         []string{coverKey, thumbnailKey},
+		ID:                bookId,
+		UserID:            userId,
+	}
+
+	updateBook, setBookCoverError := bookCoverAPIConfig.DB.SetBookCover(request.Context(), setBookCoverParams)
+
+	if setBookCoverError != nil {
+		DeleteBookCoverBlobs(request.Context(), bookCoverAPIConfig.Blobs, setBookCoverParams.CoverKeys)
+
+		if setBookCoverError == sql.ErrNoRows {
+			common.ErrorResponse(writer, http.StatusNotFound, "book not found")
+		} else {
+			common.ErrorResponse(writer, http.StatusInternalServerError, "error updating book cover, please try again in a few minutes")
+		}
+
+		return
+	}
+
+	// The previous upload is only removed once nothing points at it.
+	DeleteBookCoverBlobs(request.Context(), bookCoverAPIConfig.Blobs, getBook.CoverKeys)
+
+	book, getBookError := GetBookWithGenresAndTags(request.Context(), bookCoverAPIConfig.DB, updateBook)
+
+	if getBookError != nil {
+		common.ErrorResponse(writer, http.StatusInternalServerError, "failed to get book details, please try again in a few minutes")
+
+		return
+	}
+
+	common.JSONResponse(writer, http.StatusOK, book)
+}
+
+func (bookCoverAPIConfig *BookCoverAPIConfig) DeleteBookCover(writer http.ResponseWriter, request *http.Request, userId uuid.UUID) {
+	vars := mux.Vars(request)
+	bookId, parseBookIdError := uuid.Parse(vars["bookId"])
+
+	if parseBookIdError != nil {
+		common.ErrorResponse(writer, http.StatusBadRequest, "invalid book id")
+
+		return
+	}
+
+	getBook, getBookError := bookCoverAPIConfig.DB.GetBook(request.Context(), bookId)
+
+	if getBookError != nil {
+		if getBookError == sql.ErrNoRows {
+			common.ErrorResponse(writer, http.StatusNotFound, "book not found")
+		} else {
+			common.ErrorResponse(writer, http.StatusInternalServerError, "failed to get book details, please try again in a few minutes")
+		}
+
+		return
+	}
+
+	if getBook.UserID != userId {
+		common.ErrorResponse(writer, http.StatusForbidden, "you can only change the cover of your own books")
+
+		return
+	}
+
+	setBookCoverParams := database.SetBookCoverParams{
+		CoverUrl:          "",
+		CoverThumbnailUrl: "",
+		CoverKeys:         []string{},
+		ID:                bookId,
+		UserID:            userId,
+	}
+
+	updateBook, setBookCoverError := bookCoverAPIConfig.DB.SetBookCover(request.Context(), setBookCoverParams)
+
+	if setBookCoverError != nil {
+		if setBookCoverError == sql.ErrNoRows {
+			common.ErrorResponse(writer, http.StatusNotFound, "book not found")
+		} else {
+			common.ErrorResponse(writer, http.StatusInternalServerError, "error removing book cover, please try again in a few minutes")
+		}
+
+		return
+	}
+
+	// Covers set by URL or from the catalog have no blobs, and the store may since have been turned off.
+	if bookCoverAPIConfig.Blobs != nil {
+		DeleteBookCoverBlobs(request.Context(), bookCoverAPIConfig.Blobs, getBook.CoverKeys)
+	}
+
+	book, getBookError := GetBookWithGenresAndTags(request.Context(), bookCoverAPIConfig.DB, updateBook)
+
+	if getBookError != nil {
+		common.ErrorResponse(writer, http.StatusInternalServerError, "failed to get book details, please try again in a few minutes")
+
+		return
+	}
+
+	common.JSONResponse(writer, http.StatusOK, book)
+}
@@ -0,0 +1,250 @@
+package book_exports
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/elorenzorodz/co-library/books"
+	"github.com/elorenzorodz/co-library/common"
+	"github.com/elorenzorodz/co-library/internal/database"
+	"github.com/google/uuid"
+)
+
+type MockQueries struct {
+	*common.BaseMock
+
+	GetBooksFunc func(ctx context.Context, arg database.GetBooksParams) ([]database.GetBooksRow, error)
+}
+
+func (mockQueries *MockQueries) GetBooks(ctx context.Context, arg database.GetBooksParams) ([]database.GetBooksRow, error) {
+	if mockQueries.GetBooksFunc != nil {
+		return mockQueries.GetBooksFunc(ctx, arg)
+	}
+
+	return mockQueries.BaseMock.GetBooks(ctx, arg)
+}
+
+// Serves the rows the way the query does: ordered by title and id, after the cursor.
+func newTestLibrary(userId uuid.UUID, count int) func(ctx context.Context, arg database.GetBooksParams) ([]database.GetBooksRow, error) {
+	var getBooksRows []database.GetBooksRow
+
+	for index := 0; index < count; index++ {
+		getBooksRow := database.GetBooksRow{
+			Book: database.Book{
+				ID:             uuid.New(),
+				Title:          fmt.Sprintf("Book %04d", index),
+				Author:         "Frank Herbert",
+				CreatedAt:      time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC),
+				UserID:         userId,
+				LoanPeriodDays: 14,
+				Isbn:           "9780441172719",
+				PublishedYear:  1965,
+				PageCount:      535,
+				Visibility:     "public",
+				Lendable:       true,
+				BorrowerPolicy: "anyone",
+			},
+			BookAvailability: database.BookAvailability{TotalCopies: 1, AvailableCopies: 1},
+			Genres:           []string{"science-fiction"},
+			Tags:             []string{"classics", "space opera"},
+		}
+
+		if index == 0 {
+			getBooksRow.BookAvailability = database.BookAvailability{
+				TotalCopies:              1,
+				DueAt:                    sql.NullTime{Time: time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC), Valid: true},
+				CurrentBorrowerID:        uuid.NullUUID{UUID: uuid.New(), Valid: true},
+				CurrentBorrowerFirstName: sql.NullString{String: "Ann", Valid: true},
+				CurrentBorrowerLastName:  sql.NullString{String: "Lee", Valid: true},
+			}
+		}
+
+		getBooksRows = append(getBooksRows, getBooksRow)
+	}
+
+	return func(ctx context.Context, arg database.GetBooksParams) ([]database.GetBooksRow, error) {
+		start := 0
+
+		if arg.CursorID.Valid {
+			start = sort.Search(len(getBooksRows), func(index int) bool {
+				return getBooksRows[index].Book.Title > arg.CursorTitle
+			})
+		}
+
+		end := min(len(getBooksRows), start+int(arg.PageLimit))
+
+		return getBooksRows[start:end], nil
+	}
+}
+
+func exportBooks(t *testing.T, mockQueries *MockQueries, userId uuid.UUID, format string) *httptest.ResponseRecorder {
+	t.Helper()
+
+	apiConfig := BookExportAPIConfig{APIConfig: common.APIConfig{DB: mockQueries}}
+	request := httptest.NewRequest(http.MethodGet, "/api/v1/books/export?format="+format, nil)
+	recorder := httptest.NewRecorder()
+
+	apiConfig.ExportBooks(recorder, request, userId)
+
+	return recorder
+}
+
+func TestExportBooks(tTesting *testing.T) {
+	userId := uuid.New()
+	bookCount := BookExportBatchSize*2 + 50
+
+	// 1. Success: CSV rows for every book, read in batches, with the lending state.
+	tTesting.Run("CSV", func(t *testing.T) {
+		var batches int
+		getTestLibrary := newTestLibrary(userId, bookCount)
+
+		mockQueries := &MockQueries{
+			BaseMock: common.NewBaseMock(),
+			GetBooksFunc: func(ctx context.Context, arg database.GetBooksParams) ([]database.GetBooksRow, error) {
+				if arg.UserID != userId || arg.ViewerID != userId || arg.ShelfID.Valid {
+					t.Errorf("Expected all of the owner's books, got %+v", arg)
+				}
+
+				batches++
+
+				return getTestLibrary(ctx, arg)
+			},
+		}
+
+		recorder := exportBooks(t, mockQueries, userId, "")
+
+		if recorder.Code != http.StatusOK || !strings.HasPrefix(recorder.Header().Get("Content-Type"), "text/csv") ||
+			!strings.Contains(recorder.Header().Get("Content-Disposition"), ".csv") {
+			t.Fatalf("Expected a CSV attachment, got %d %v", recorder.Code, recorder.Header())
+		}
+
+		records, err := csv.NewReader(recorder.Body).ReadAll()
+
+		if err != nil || len(records) != bookCount+1 || batches != 3 {
+			t.Fatalf("Expected %d rows in 3 batches, got %d in %d (%v)", bookCount+1, len(records), batches, err)
+		}
+
+		column := map[string]int{}
+
+		for index, name := range records[0] {
+			column[name] = index
+		}
+
+		onLoan := records[1]
+
+		if onLoan[column["title"]] != "Book 0000" || onLoan[column["status"]] != "on loan" || onLoan[column["current_borrower"]] != "Ann Lee" ||
+			onLoan[column["due_at"]] != "2024-02-01T00:00:00Z" || onLoan[column["tags"]] != "classics;space opera" || onLoan[column["copies"]] != "1" {
+			t.Errorf("Unexpected row: %v", onLoan)
+		}
+
+		if records[len(records)-1][column["title"]] != fmt.Sprintf("Book %04d", bookCount-1) || records[2][column["status"]] != "available" {
+			t.Errorf("Expected every book in order, got %v", records[len(records)-1])
+		}
+	})
+
+	// 2. Success: a JSON array of books.
+	tTesting.Run("JSON", func(t *testing.T) {
+		recorder := exportBooks(t, &MockQueries{BaseMock: common.NewBaseMock(), GetBooksFunc: newTestLibrary(userId, bookCount)}, userId, BookExportFormatJSON)
+
+		var exportedBooks []books.Book
+
+		if err := json.Unmarshal(recorder.Body.Bytes(), &exportedBooks); err != nil || len(exportedBooks) != bookCount {
+			t.Fatalf("Expected %d books, got %d (%v)", bookCount, len(exportedBooks), err)
+		}
+
+		if exportedBooks[0].Availability == nil || exportedBooks[0].Availability.CurrentBorrower == nil || len(exportedBooks[0].Tags) != 2 {
+			t.Errorf("Expected the lending state and tags, got %+v", exportedBooks[0])
+		}
+	})
+
+	// 2a. Success: an empty library is an empty array.
+	tTesting.Run("EmptyJSON", func(t *testing.T) {
+		recorder := exportBooks(t, &MockQueries{BaseMock: common.NewBaseMock(), GetBooksFunc: newTestLibrary(userId, 0)}, userId, BookExportFormatJSON)
+
+		var exportedBooks []books.Book
+
+		if err := json.Unmarshal(recorder.Body.Bytes(), &exportedBooks); err != nil || exportedBooks == nil || len(exportedBooks) != 0 {
+			t.Errorf("Expected an empty array, got %q (%v)", recorder.Body.String(), err)
+		}
+	})
+
+	// 3. Success: one MARC record per book.
+	tTesting.Run("MARC", func(t *testing.T) {
+		recorder := exportBooks(t, &MockQueries{BaseMock: common.NewBaseMock(), GetBooksFunc: newTestLibrary(userId, 3)}, userId, BookExportFormatMARC)
+
+		marcRecords := bytes.SplitAfter(recorder.Body.Bytes(), []byte{marcRecordTerminator})
+
+		if len(marcRecords) != 4 || len(marcRecords[3]) != 0 {
+			t.Fatalf("Expected 3 records, got %d", len(marcRecords)-1)
+		}
+
+		marcFields := decodeTestMARCRecord(t, marcRecords[0])
+
+		if marcFields["245"] != "10\x1faBook 0000" || marcFields["020"] != "  \x1fa9780441172719" || marcFields["264"] != " 1\x1fc1965" ||
+			!strings.Contains(marcFields["590"], "borrowed by Ann Lee, due 2024-02-01") || marcFields["008"][6:11] != "s1965" {
+			t.Errorf("Unexpected fields: %q", marcFields)
+		}
+	})
+
+	// 4. Failure: unknown format.
+	tTesting.Run("InvalidFormat", func(t *testing.T) {
+		if recorder := exportBooks(t, &MockQueries{BaseMock: common.NewBaseMock()}, userId, "xlsx"); recorder.Code != http.StatusBadRequest {
+			t.Errorf("Expected status %d, got %d", http.StatusBadRequest, recorder.Code)
+		}
+	})
+
+	// 5. Failure: the books can't be read.
+	tTesting.Run("GetBooksError", func(t *testing.T) {
+		mockQueries := &MockQueries{
+			BaseMock: common.NewBaseMock(),
+			GetBooksFunc: func(ctx context.Context, arg database.GetBooksParams) ([]database.GetBooksRow, error) {
+				return nil, errors.New("connection reset")
+			},
+		}
+
+		if recorder := exportBooks(t, mockQueries, userId, BookExportFormatCSV); recorder.Code != http.StatusInternalServerError {
+			t.Errorf("Expected status %d, got %d", http.StatusInternalServerError, recorder.Code)
+		}
+	})
+}
+
+// Checks the leader and directory add up and returns the fields by tag.
+func decodeTestMARCRecord(t *testing.T, marcRecord []byte) map[string]string {
+	t.Helper()
+
+	recordLength, _ := strconv.Atoi(string(marcRecord[0:5]))
+	baseAddress, _ := strconv.Atoi(string(marcRecord[12:17]))
+
+	if recordLength != len(marcRecord) || string(marcRecord[5:10]) != "nam a" || string(marcRecord[20:24]) != "4500" {
+		t.Fatalf("Unexpected leader %q for a record of %d bytes", marcRecord[:24], len(marcRecord))
+	}
+
+	marcFields := map[string]string{}
+	directory := marcRecord[24 : baseAddress-1]
+
+	for entry := 0; entry < len(directory); entry += 12 {
+		fieldLength, _ := strconv.Atoi(string(directory[entry+3 : entry+7]))
+		fieldStart, _ := strconv.Atoi(string(directory[entry+7 : entry+12]))
+		field := marcRecord[baseAddress+fieldStart : baseAddress+fieldStart+fieldLength]
+
+		if field[len(field)-1] != marcFieldTerminator {
+			t.Fatalf("Field %s does not end with a field terminator", directory[entry:entry+3])
+		}
+
+		marcFields[string(directory[entry:entry+3])] = string(field[:len(field)-1])
+	}
+
+	return marcFields
+}
@@ -0,0 +1,337 @@
+package book_exports
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/elorenzorodz/co-library/books"
+)
+
+func NewBookExporter(format string, writer io.Writer) (BookExporter, error) {
+	switch format {
+	case "", BookExportFormatCSV:
+		return &CSVBookExporter{csvWriter: csv.NewWriter(writer)}, nil
+	case BookExportFormatJSON:
+		return &JSONBookExporter{writer: writer}, nil
+	case BookExportFormatMARC:
+		return &MARCBookExporter{writer: writer}, nil
+	}
+
+	return nil, fmt.Errorf("invalid format: %s, must be one of: %s", format, strings.Join(BookExportFormats, ", "))
+}
+
+// Lending state of a book in a few words, for formats without a place for the details.
+func BookLendingStatus(book books.Book) string {
+	if book.Availability == nil || book.Availability.TotalCopies == 0 {
+		return "no copies"
+	}
+
+	if book.Availability.Available {
+		return "available"
+	}
+
+	return "on loan"
+}
+
+var csvBookExportHeader = []string{
+	"id", "title", "author", "isbn", "publisher", "published_year", "page_count", "cover_url", "loan_period_days",
+	"visibility", "lendable", "borrower_policy", "genres", "tags", "copies", "available_copies", "status",
+	"current_borrower", "due_at", "queue_length", "created_at",
+}
+
+func (csvBookExporter *CSVBookExporter) ContentType() string {
+	return "text/csv; charset=utf-8"
+}
+
+func (csvBookExporter *CSVBookExporter) FileExtension() string {
+	return "csv"
+}
+
+func (csvBookExporter *CSVBookExporter) Begin() error {
+	return csvBookExporter.csvWriter.Write(csvBookExportHeader)
+}
+
+func (csvBookExporter *CSVBookExporter) WriteBook(book books.Book) error {
+	var copies, availableCopies, queueLength int64
+	var currentBorrower, dueAt string
+
+	if book.Availability != nil {
+		copies = book.Availability.TotalCopies
+		availableCopies = book.Availability.AvailableCopies
+		queueLength = book.Availability.QueueLength
+
+		if book.Availability.CurrentBorrower != nil {
+			currentBorrower = book.Availability.CurrentBorrower.Name
+		}
+
+		if book.Availability.DueAt.Valid {
+			dueAt = book.Availability.DueAt.Time.UTC().Format(time.RFC3339)
+		}
+	}
+
+	writeError := csvBookExporter.csvWriter.Write([]string{
+		book.ID.String(),
+		book.Title,
+		book.Author,
+		book.ISBN,
+		book.Publisher,
+		formatOptionalNumber(int64(book.PublishedYear)),
+		formatOptionalNumber(int64(book.PageCount)),
+		book.CoverURL,
+		strconv.Itoa(int(book.LoanPeriodDays)),
+		book.Visibility,
+		strconv.FormatBool(book.Lendable),
+		book.BorrowerPolicy,
+		strings.Join(book.Genres, ";"),
+		strings.Join(book.Tags, ";"),
+		strconv.FormatInt(copies, 10),
+		strconv.FormatInt(availableCopies, 10),
+		BookLendingStatus(book),
+		currentBorrower,
+		dueAt,
+		strconv.FormatInt(queueLength, 10),
+		book.CreatedAt.UTC().Format(time.RFC3339),
+	})
+
+	if writeError != nil {
+		return writeError
+	}
+
+	// Rows are handed to the response as they come instead of piling up in the csv writer's buffer.
+	csvBookExporter.csvWriter.Flush()
+
+	return csvBookExporter.csvWriter.Error()
+}
+
+func (csvBookExporter *CSVBookExporter) End() error {
+	csvBookExporter.csvWriter.Flush()
+
+	return csvBookExporter.csvWriter.Error()
+}
+
+func (jsonBookExporter *JSONBookExporter) ContentType() string {
+	return "application/json"
+}
+
+func (jsonBookExporter *JSONBookExporter) FileExtension() string {
+	return "json"
+}
+
+func (jsonBookExporter *JSONBookExporter) Begin() error {
+	_, writeError := io.WriteString(jsonBookExporter.writer, "[")
+
+	return writeError
+}
+
+func (jsonBookExporter *JSONBookExporter) WriteBook(book books.Book) error {
+	bookJSON, marshalError := json.Marshal(book)
+
+	if marshalError != nil {
+		return marshalError
+	}
+
+	if jsonBookExporter.wroteFirst {
+		bookJSON = append([]byte(",\n"), bookJSON...)
+	} else {
+		bookJSON = append([]byte("\n"), bookJSON...)
+	}
+
+	jsonBookExporter.wroteFirst = true
+
+	_, writeError := jsonBookExporter.writer.Write(bookJSON)
+
+	return writeError
+}
+
+func (jsonBookExporter *JSONBookExporter) End() error {
+	_, writeError := io.WriteString(jsonBookExporter.writer, "\n]\n")
+
+	return writeError
+}
+
+func (marcBookExporter *MARCBookExporter) ContentType() string {
+	return "application/marc"
+}
+
+func (marcBookExporter *MARCBookExporter) FileExtension() string {
+	return "mrc"
+}
+
+// MARC files are just records back to back.
+func (marcBookExporter *MARCBookExporter) Begin() error {
+	return nil
+}
+
+func (marcBookExporter *MARCBookExporter) WriteBook(book books.Book) error {
+	marcRecord, encodeError := EncodeMARCRecord(BookMARCFields(book))
+
+	if encodeError != nil {
+		return fmt.Errorf("error encoding book %s: %w", book.ID, encodeError)
+	}
+
+	_, writeError := marcBookExporter.writer.Write(marcRecord)
+
+	return writeError
+}
+
+func (marcBookExporter *MARCBookExporter) End() error {
+	return nil
+}
+
+// Maps a book to MARC 21 bibliographic fields. Copies and their lending state
+// go in a local note (590) since there is no standard place for them.
+func BookMARCFields(book books.Book) []marcField {
+	// 008 is 40 fixed positions: date entered, a single publication date and the language, left undetermined.
+	fixedLengthData := []byte(strings.Repeat(" ", 40))
+	copy(fixedLengthData[0:6], book.CreatedAt.UTC().Format("060102"))
+
+	if book.PublishedYear > 0 {
+		copy(fixedLengthData[6:11], fmt.Sprintf("s%04d", book.PublishedYear))
+	} else {
+		copy(fixedLengthData[6:11], "nuuuu")
+	}
+
+	copy(fixedLengthData[35:38], "und")
+
+	marcFields := []marcField{
+		{Tag: "001", Value: book.ID.String()},
+		{Tag: "008", Value: string(fixedLengthData)},
+	}
+
+	if book.ISBN != "" {
+		marcFields = append(marcFields, marcField{Tag: "020", Indicators: "  ", Subfields: [][2]string{{"a", book.ISBN}}})
+	}
+
+	marcFields = append(marcFields,
+		marcField{Tag: "100", Indicators: "1 ", Subfields: [][2]string{{"a", book.Author}}},
+		marcField{Tag: "245", Indicators: "10", Subfields: [][2]string{{"a", book.Title}}},
+	)
+
+	if book.Publisher != "" || book.PublishedYear > 0 {
+		var publication [][2]string
+
+		if book.Publisher != "" {
+			publication = append(publication, [2]string{"b", book.Publisher})
+		}
+
+		if book.PublishedYear > 0 {
+			publication = append(publication, [2]string{"c", strconv.Itoa(int(book.PublishedYear))})
+		}
+
+		marcFields = append(marcFields, marcField{Tag: "264", Indicators: " 1", Subfields: publication})
+	}
+
+	if book.PageCount > 0 {
+		marcFields = append(marcFields, marcField{Tag: "300", Indicators: "  ", Subfields: [][2]string{{"a", fmt.Sprintf("%d pages", book.PageCount)}}})
+	}
+
+	if book.Availability != nil {
+		lendingNote := fmt.Sprintf("Copies: %d, available: %d, status: %s", book.Availability.TotalCopies, book.Availability.AvailableCopies, BookLendingStatus(book))
+
+		if book.Availability.CurrentBorrower != nil {
+			lendingNote += ", borrowed by " + book.Availability.CurrentBorrower.Name
+		}
+
+		if book.Availability.DueAt.Valid {
+			lendingNote += ", due " + book.Availability.DueAt.Time.UTC().Format("2006-01-02")
+		}
+
+		marcFields = append(marcFields, marcField{Tag: "590", Indicators: "  ", Subfields: [][2]string{{"a", lendingNote}}})
+	}
+
+	for _, genre := range book.Genres {
+		marcFields = append(marcFields, marcField{Tag: "655", Indicators: " 4", Subfields: [][2]string{{"a", genre}}})
+	}
+
+	for _, tag := range book.Tags {
+		marcFields = append(marcFields, marcField{Tag: "653", Indicators: "  ", Subfields: [][2]string{{"a", tag}}})
+	}
+
+	if book.CoverURL != "" {
+		marcFields = append(marcFields, marcField{Tag: "856", Indicators: "42", Subfields: [][2]string{{"3", "Cover image"}, {"u", book.CoverURL}}})
+	}
+
+	return marcFields
+}
+
+const (
+	marcSubfieldDelimiter = 0x1F
+	marcFieldTerminator   = 0x1E
+	marcRecordTerminator  = 0x1D
+	marcMaxRecordLength   = 99999
+	marcMaxFieldLength    = 9999
+)
+
+// Encodes the fields as one ISO 2709 record: a 24 byte leader, a directory of
+// 12 byte entries (tag, length, offset) and then the fields themselves.
+func EncodeMARCRecord(marcFields []marcField) ([]byte, error) {
+	var directory, data bytes.Buffer
+
+	for _, marcField := range marcFields {
+		fieldStart := data.Len()
+
+		if marcField.Subfields == nil {
+			data.WriteString(cleanMARCValue(marcField.Value))
+		} else {
+			data.WriteString(marcField.Indicators)
+
+			for _, subfield := range marcField.Subfields {
+				data.WriteByte(marcSubfieldDelimiter)
+				data.WriteString(subfield[0])
+				data.WriteString(cleanMARCValue(subfield[1]))
+			}
+		}
+
+		data.WriteByte(marcFieldTerminator)
+
+		fieldLength := data.Len() - fieldStart
+
+		if fieldLength > marcMaxFieldLength {
+			return nil, fmt.Errorf("field %s is longer than %d bytes", marcField.Tag, marcMaxFieldLength)
+		}
+
+		fmt.Fprintf(&directory, "%s%04d%05d", marcField.Tag, fieldLength, fieldStart)
+	}
+
+	directory.WriteByte(marcFieldTerminator)
+	data.WriteByte(marcRecordTerminator)
+
+	baseAddress := 24 + directory.Len()
+	recordLength := baseAddress + data.Len()
+
+	if recordLength > marcMaxRecordLength {
+		return nil, fmt.Errorf("record is longer than %d bytes", marcMaxRecordLength)
+	}
+
+	// New record, language material, monograph, UTF-8, two indicators, one character subfield codes.
+	marcRecord := bytes.NewBufferString(fmt.Sprintf("%05dnam a22%05d i 4500", recordLength, baseAddress))
+	marcRecord.Write(directory.Bytes())
+	marcRecord.Write(data.Bytes())
+
+	return marcRecord.Bytes(), nil
+}
+
+// The delimiters can't appear in values.
+func cleanMARCValue(value string) string {
+	return strings.Map(func(character rune) rune {
+		if character == marcSubfieldDelimiter || character == marcFieldTerminator || character == marcRecordTerminator {
+			return ' '
+		}
+
+		return character
+	}, value)
+}
+
+func formatOptionalNumber(number int64) string {
+	if number == 0 {
+		return ""
+	}
+
+	return strconv.FormatInt(number, 10)
+}
@@ -0,0 +1,59 @@
+package book_exports
+
+import (
+	"encoding/csv"
+	"io"
+
+	"github.com/elorenzorodz/co-library/books"
+	"github.com/elorenzorodz/co-library/common"
+)
+
+type BookExportAPIConfig struct {
+	common.APIConfig
+}
+
+// Values accepted by ?format=, CSV being the default.
+const (
+	BookExportFormatCSV  = "csv"
+	BookExportFormatJSON = "json"
+	BookExportFormatMARC = "marc"
+)
+
+var BookExportFormats = []string{BookExportFormatCSV, BookExportFormatJSON, BookExportFormatMARC}
+
+// How many books are read from the database and written out at a time.
+const BookExportBatchSize = 200
+
+// Writes books out one at a time. Begin and End write whatever goes around them.
+type BookExporter interface {
+	ContentType() string
+	FileExtension() string
+	Begin() error
+	WriteBook(book books.Book) error
+	End() error
+}
+
+// Columns share their names with the import ones so an export can be imported again.
+type CSVBookExporter struct {
+	csvWriter *csv.Writer
+}
+
+// A JSON array of books as the books endpoints show them to their owner.
+type JSONBookExporter struct {
+	writer     io.Writer
+	wroteFirst bool
+}
+
+// MARC 21 bibliographic records in the ISO 2709 transmission format, read by most library systems.
+type MARCBookExporter struct {
+	writer io.Writer
+}
+
+// A MARC field, either a control field (tags below 010) holding only Value, or a
+// data field with indicators and subfields.
+type marcField struct {
+	Tag        string
+	Value      string
+	Indicators string
+	Subfields  [][2]string
+}
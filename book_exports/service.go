@@ -0,0 +1,84 @@
+package book_exports
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/elorenzorodz/co-library/books"
+	"github.com/elorenzorodz/co-library/common"
+	"github.com/elorenzorodz/co-library/internal/database"
+	"github.com/google/uuid"
+)
+
+func (bookExportAPIConfig *BookExportAPIConfig) ExportBooks(writer http.ResponseWriter, request *http.Request, userId uuid.UUID) {
+	bookExporter, newBookExporterError := NewBookExporter(request.URL.Query().Get("format"), writer)
+
+	if newBookExporterError != nil {
+		common.ErrorResponse(writer, http.StatusBadRequest, newBookExporterError.Error())
+
+		return
+	}
+
+	getBooksParams := database.GetBooksParams{
+		UserID:    userId,
+		ViewerID:  userId,
+		PageLimit: BookExportBatchSize,
+	}
+
+	// The first batch is read before anything is written so a failure can still get an error response.
+	getBooks, getBooksError := bookExportAPIConfig.DB.GetBooks(request.Context(), getBooksParams)
+
+	if getBooksError != nil {
+		common.ErrorResponse(writer, http.StatusInternalServerError, fmt.Sprintf("error getting books: %s", getBooksError))
+
+		return
+	}
+
+	writer.Header().Set("Content-Type", bookExporter.ContentType())
+	writer.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="library-%s.%s"`, time.Now().UTC().Format("20060102"), bookExporter.FileExtension()))
+	writer.WriteHeader(http.StatusOK)
+
+	// Once the body has started the status can't change, so failures are logged and the
+	// export is cut short, leaving a file that doesn't parse rather than one that looks complete.
+	if beginError := bookExporter.Begin(); beginError != nil {
+		log.Printf("error exporting books for user %s: %s", userId, beginError)
+
+		return
+	}
+
+	for {
+		for _, book := range books.DatabaseGetBooksRowsToBooksJSON(getBooks, userId) {
+			if writeBookError := bookExporter.WriteBook(book); writeBookError != nil {
+				log.Printf("error exporting books for user %s: %s", userId, writeBookError)
+
+				return
+			}
+		}
+
+		if flusher, isFlusher := writer.(http.Flusher); isFlusher {
+			flusher.Flush()
+		}
+
+		if len(getBooks) < BookExportBatchSize {
+			break
+		}
+
+		lastBook := getBooks[len(getBooks)-1].Book
+		getBooksParams.CursorTitle = lastBook.Title
+		getBooksParams.CursorID = uuid.NullUUID{UUID: lastBook.ID, Valid: true}
+
+		getBooks, getBooksError = bookExportAPIConfig.DB.GetBooks(request.Context(), getBooksParams)
+
+		if getBooksError != nil {
+			log.Printf("error exporting books for user %s: %s", userId, getBooksError)
+
+			return
+		}
+	}
+
+	if endError := bookExporter.End(); endError != nil {
+		log.Printf("error exporting books for user %s: %s", userId, endError)
+	}
+}
@@ -0,0 +1,436 @@
+package book_imports
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/elorenzorodz/co-library/common"
+	"github.com/elorenzorodz/co-library/internal/database"
+	"github.com/elorenzorodz/co-library/notification_templates"
+	"github.com/google/uuid"
+)
+
+type MockQueries struct {
+	*common.BaseMock
+
+	GetBookIdentitiesFunc        func(ctx context.Context, userID uuid.UUID) ([]database.GetBookIdentitiesRow, error)
+	CreateBookFunc               func(ctx context.Context, arg database.CreateBookParams) (database.Book, error)
+	CreateBookCopyFunc           func(ctx context.Context, arg database.CreateBookCopyParams) (database.BookCopy, error)
+	GetUsersBySubscriberIDFunc   func(ctx context.Context, userID uuid.UUID) ([]database.User, error)
+	GetUserByIDFunc              func(ctx context.Context, id uuid.UUID) (database.User, error)
+	CreateOutboxNotificationFunc func(ctx context.Context, arg database.CreateOutboxNotificationParams) (database.NotificationOutbox, error)
+}
+
+func (mockQueries *MockQueries) GetBookIdentities(ctx context.Context, userID uuid.UUID) ([]database.GetBookIdentitiesRow, error) {
+	if mockQueries.GetBookIdentitiesFunc != nil {
+		return mockQueries.GetBookIdentitiesFunc(ctx, userID)
+	}
+
+	return mockQueries.BaseMock.GetBookIdentities(ctx, userID)
+}
+
+func (mockQueries *MockQueries) CreateBook(ctx context.Context, arg database.CreateBookParams) (database.Book, error) {
+	if mockQueries.CreateBookFunc != nil {
+		return mockQueries.CreateBookFunc(ctx, arg)
+	}
+
+	return mockQueries.BaseMock.CreateBook(ctx, arg)
+}
+
+func (mockQueries *MockQueries) CreateBookCopy(ctx context.Context, arg database.CreateBookCopyParams) (database.BookCopy, error) {
+	if mockQueries.CreateBookCopyFunc != nil {
+		return mockQueries.CreateBookCopyFunc(ctx, arg)
+	}
+
+	return mockQueries.BaseMock.CreateBookCopy(ctx, arg)
+}
+
+func (mockQueries *MockQueries) GetUsersBySubscriberID(ctx context.Context, userID uuid.UUID) ([]database.User, error) {
+	if mockQueries.GetUsersBySubscriberIDFunc != nil {
+		return mockQueries.GetUsersBySubscriberIDFunc(ctx, userID)
+	}
+
+	return mockQueries.BaseMock.GetUsersBySubscriberID(ctx, userID)
+}
+
+func (mockQueries *MockQueries) GetUserByID(ctx context.Context, id uuid.UUID) (database.User, error) {
+	if mockQueries.GetUserByIDFunc != nil {
+		return mockQueries.GetUserByIDFunc(ctx, id)
+	}
+
+	return mockQueries.BaseMock.GetUserByID(ctx, id)
+}
+
+func (mockQueries *MockQueries) CreateOutboxNotification(ctx context.Context, arg database.CreateOutboxNotificationParams) (database.NotificationOutbox, error) {
+	if mockQueries.CreateOutboxNotificationFunc != nil {
+		return mockQueries.CreateOutboxNotificationFunc(ctx, arg)
+	}
+
+	return mockQueries.BaseMock.CreateOutboxNotification(ctx, arg)
+}
+
+func createTestBook(ctx context.Context, arg database.CreateBookParams) (database.Book, error) {
+	return database.Book{
+		ID:             arg.ID,
+		Title:          arg.Title,
+		Author:         arg.Author,
+		CreatedAt:      arg.CreatedAt,
+		UpdatedAt:      arg.UpdatedAt,
+		UserID:         arg.UserID,
+		LoanPeriodDays: arg.LoanPeriodDays,
+		Isbn:           arg.Isbn,
+		Visibility:     arg.Visibility,
+		Lendable:       arg.Lendable,
+		BorrowerPolicy: arg.BorrowerPolicy,
+	}, nil
+}
+
+func createTestBookCopy(ctx context.Context, arg database.CreateBookCopyParams) (database.BookCopy, error) {
+	return database.BookCopy{ID: arg.ID, Condition: arg.Condition, CreatedAt: time.Now(), UpdatedAt: time.Now(), BookID: arg.BookID}, nil
+}
+
+// Answers from books, and with err for any ISBN it doesn't have. Records what was asked.
+type testCatalogProvider struct {
+	mutex   sync.Mutex
+	books   map[string]common.BookMetadata
+	err     error
+	lookups []string
+}
+
+func (testCatalog *testCatalogProvider) LookupISBN(ctx context.Context, isbn string) (common.BookMetadata, error) {
+	testCatalog.mutex.Lock()
+	defer testCatalog.mutex.Unlock()
+
+	testCatalog.lookups = append(testCatalog.lookups, isbn)
+
+	if bookMetadata, found := testCatalog.books[isbn]; found {
+		return bookMetadata, nil
+	}
+
+	return common.BookMetadata{}, testCatalog.err
+}
+
+func importRequest(content string, query string) *http.Request {
+	request := httptest.NewRequest(http.MethodPost, "/api/v1/books/import"+query, strings.NewReader(content))
+	request.Header.Set("Content-Type", "text/csv")
+
+	return request
+}
+
+func decodeBookImportReport(t *testing.T, recorder *httptest.ResponseRecorder) BookImportReport {
+	t.Helper()
+
+	var bookImportReport BookImportReport
+
+	if err := json.Unmarshal(recorder.Body.Bytes(), &bookImportReport); err != nil {
+		t.Fatalf("Expected an import report, got %s", recorder.Body.String())
+	}
+
+	return bookImportReport
+}
+
+const goodreadsExport = `Book Id,Title,Author,Author l-f,Additional Authors,ISBN,ISBN13,My Rating,Average Rating,Publisher,Binding,Number of Pages,Year Published,Original Publication Year,Date Read,Date Added,Bookshelves,Bookshelves with positions,Exclusive Shelf,My Review,Spoiler,Private Notes,Read Count,Owned Copies
+234225,Dune,Frank Herbert,"Herbert, Frank",,"=""0441172717""","=""9780441172719""",5,4.25,Ace Books,Paperback,535,1990,1965,,2020/01/02,,,read,,,,1,0
+18765,Kindred,Octavia E. Butler,"Butler, Octavia E.",,"=""""","=""""",0,4.28,Beacon Press,Paperback,264,,1979,,2020/01/02,to-read,to-read (#1),to-read,,,,0,0
+`
+
+func TestParseBookImportCSV(tTesting *testing.T) {
+	// 1. Success: our own columns.
+	tTesting.Run("CSV", func(t *testing.T) {
+		bookImportRecords, err := ParseBookImportCSV([]byte("\xef\xbb\xbftitle,author,isbn,published_year,page_count,copies,tags,lendable\n" +
+			"Dune,Frank Herbert,978-0-441-17271-9,1965,535,2,\"classics;space opera\",false\n\n" +
+			"Piranesi,Susanna Clarke,,,,,,\n"))
+
+		if err != nil || len(bookImportRecords) != 2 {
+			t.Fatalf("Expected 2 records, got %d (%v)", len(bookImportRecords), err)
+		}
+
+		upsertBookParameters := bookImportRecords[0].UpsertBookParameters
+
+		if upsertBookParameters.Title != "Dune" || upsertBookParameters.ISBN != "978-0-441-17271-9" || upsertBookParameters.PublishedYear != 1965 ||
+			upsertBookParameters.PageCount != 535 || upsertBookParameters.Copies != 2 || len(upsertBookParameters.Tags) != 2 ||
+			upsertBookParameters.Lendable == nil || *upsertBookParameters.Lendable {
+			t.Errorf("Unexpected parameters: %+v", upsertBookParameters)
+		}
+
+		if bookImportRecords[1].Row != 4 || bookImportRecords[1].UpsertBookParameters.Lendable != nil {
+			t.Errorf("Expected the second book on row 4, got %+v", bookImportRecords[1])
+		}
+	})
+
+	// 2. Success: Goodreads exports, books only on the to-read shelf are skipped.
+	tTesting.Run("Goodreads", func(t *testing.T) {
+		bookImportRecords, err := ParseBookImportCSV([]byte(goodreadsExport))
+
+		if err != nil || len(bookImportRecords) != 2 {
+			t.Fatalf("Expected 2 records, got %d (%v)", len(bookImportRecords), err)
+		}
+
+		upsertBookParameters := bookImportRecords[0].UpsertBookParameters
+
+		if upsertBookParameters.ISBN != "9780441172719" || upsertBookParameters.Author != "Frank Herbert" || upsertBookParameters.PublishedYear != 1990 ||
+			upsertBookParameters.Publisher != "Ace Books" || upsertBookParameters.PageCount != 535 {
+			t.Errorf("Unexpected parameters: %+v", upsertBookParameters)
+		}
+
+		if bookImportRecords[1].SkipReason == "" || bookImportRecords[1].UpsertBookParameters.Title != "Kindred" {
+			t.Errorf("Expected the to-read book to be skipped, got %+v", bookImportRecords[1])
+		}
+	})
+
+	// 3. Success: LibraryThing exports.
+	tTesting.Run("LibraryThing", func(t *testing.T) {
+		bookImportRecords, err := ParseBookImportCSV([]byte("Book Id,Title,Primary Author,Date,ISBNs,Page Count\n" +
+			"1,Dune,Frank Herbert,1965,\"[0441172717, 9780441172719]\",535\n"))
+
+		if err != nil || len(bookImportRecords) != 1 {
+			t.Fatalf("Expected 1 record, got %d (%v)", len(bookImportRecords), err)
+		}
+
+		if upsertBookParameters := bookImportRecords[0].UpsertBookParameters; upsertBookParameters.ISBN != "0441172717" || upsertBookParameters.Author != "Frank Herbert" {
+			t.Errorf("Unexpected parameters: %+v", upsertBookParameters)
+		}
+	})
+
+	// 4. Failure: unreadable columns are kept on the row.
+	tTesting.Run("InvalidColumns", func(t *testing.T) {
+		bookImportRecords, err := ParseBookImportCSV([]byte("title,author,page_count,lendable\nDune,Frank Herbert,many,maybe\n"))
+
+		if err != nil || len(bookImportRecords) != 1 || bookImportRecords[0].Error == nil ||
+			!strings.Contains(bookImportRecords[0].Error.Error(), "page_count") || !strings.Contains(bookImportRecords[0].Error.Error(), "lendable") {
+			t.Errorf("Expected a row error, got %+v (%v)", bookImportRecords, err)
+		}
+	})
+
+	// 5. Failure: files that can't be imported at all.
+	tTesting.Run("InvalidFile", func(t *testing.T) {
+		for name, content := range map[string]string{
+			"Empty":    "",
+			"NoBooks":  "title,author\n",
+			"NoTitle":  "name,writer\nDune,Frank Herbert\n",
+			"TooLarge": "title\n" + strings.Repeat("Dune\n", MaxBookImportRows+1),
+		} {
+			if _, err := ParseBookImportCSV([]byte(content)); err == nil {
+				t.Errorf("%s: expected an error", name)
+			}
+		}
+	})
+}
+
+func TestImportBooks(tTesting *testing.T) {
+	userId := uuid.New()
+
+	// 1. Success: new books are added, duplicates and bad rows reported, and subscribers get one alert.
+	tTesting.Run("Success", func(t *testing.T) {
+		var createdTitles []string
+		var bodies []string
+
+		mockQueries := &MockQueries{
+			BaseMock: common.NewBaseMock(),
+			GetBookIdentitiesFunc: func(ctx context.Context, userID uuid.UUID) ([]database.GetBookIdentitiesRow, error) {
+				return []database.GetBookIdentitiesRow{{Isbn: "9780441172719", Title: "Dune (Ace)", Author: "Frank Herbert"}}, nil
+			},
+			CreateBookFunc: func(ctx context.Context, arg database.CreateBookParams) (database.Book, error) {
+				if arg.UserID != userId || arg.LoanPeriodDays != 0 || arg.BorrowerPolicy != "anyone" || !arg.Lendable {
+					t.Errorf("Expected the CreateBook defaults, got %+v", arg)
+				}
+
+				createdTitles = append(createdTitles, arg.Title)
+
+				return createTestBook(ctx, arg)
+			},
+			CreateBookCopyFunc: createTestBookCopy,
+			GetUsersBySubscriberIDFunc: func(ctx context.Context, userID uuid.UUID) ([]database.User, error) {
+				return []database.User{{ID: uuid.New(), FirstName: "Ann", LastName: "Lee", Email: "ann@example.com"}}, nil
+			},
+			GetUserByIDFunc: func(ctx context.Context, id uuid.UUID) (database.User, error) {
+				return database.User{ID: id, FirstName: "Jane", LastName: "Doe", Email: "jane@example.com"}, nil
+			},
+			CreateOutboxNotificationFunc: func(ctx context.Context, arg database.CreateOutboxNotificationParams) (database.NotificationOutbox, error) {
+				bodies = append(bodies, arg.Body)
+
+				return database.NotificationOutbox{ID: arg.ID}, nil
+			},
+		}
+
+		apiConfig := BookImportAPIConfig{APIConfig: common.APIConfig{DB: mockQueries, Templates: notification_templates.MustNewRenderer()}}
+
+		recorder := httptest.NewRecorder()
+		apiConfig.ImportBooks(recorder, importRequest("title,author,isbn,visibility\n"+
+			"Dune,Frank Herbert,0441172717,\n"+
+			"Kindred,Octavia E. Butler,,\n"+
+			"KINDRED,octavia e. butler,,\n"+
+			"Piranesi,Susanna Clarke,,\n"+
+			"Untitled,,,\n"+
+			"Diary,Me,,private\n", ""), userId)
+
+		if recorder.Code != http.StatusCreated {
+			t.Fatalf("Expected status %d, got %d. Body: %s", http.StatusCreated, recorder.Code, recorder.Body.String())
+		}
+
+		bookImportReport := decodeBookImportReport(t, recorder)
+
+		if bookImportReport.Created != 3 || bookImportReport.Duplicates != 2 || bookImportReport.Failed != 1 || len(bookImportReport.Rows) != 6 {
+			t.Fatalf("Unexpected report: %+v", bookImportReport)
+		}
+
+		if bookImportReport.Rows[0].Status != BookImportRowDuplicate || bookImportReport.Rows[1].BookID == nil || bookImportReport.Rows[4].Row != 6 || bookImportReport.Rows[4].Error == "" {
+			t.Errorf("Unexpected rows: %+v", bookImportReport.Rows)
+		}
+
+		if strings.Join(createdTitles, ",") != "Kindred,Piranesi,Diary" {
+			t.Errorf("Unexpected books created: %v", createdTitles)
+		}
+
+		// The private book is left out of the alert.
+		if len(bodies) != 1 || !strings.Contains(bodies[0], "2 new books") || !strings.Contains(bodies[0], "Piranesi") || strings.Contains(bodies[0], "Diary") {
+			t.Errorf("Expected one alert about two books, got %q", bodies)
+		}
+	})
+
+	// 1a. Success: multipart uploads of a Goodreads export.
+	tTesting.Run("GoodreadsUpload", func(t *testing.T) {
+		mockQueries := &MockQueries{
+			BaseMock:           common.NewBaseMock(),
+			CreateBookFunc:     createTestBook,
+			CreateBookCopyFunc: createTestBookCopy,
+		}
+
+		var body bytes.Buffer
+		multipartWriter := multipart.NewWriter(&body)
+		formFile, _ := multipartWriter.CreateFormFile(BookImportFormField, "goodreads_library_export.csv")
+		formFile.Write([]byte(goodreadsExport))
+		multipartWriter.Close()
+
+		request := httptest.NewRequest(http.MethodPost, "/api/v1/books/import", &body)
+		request.Header.Set("Content-Type", multipartWriter.FormDataContentType())
+		recorder := httptest.NewRecorder()
+
+		apiConfig := BookImportAPIConfig{APIConfig: common.APIConfig{DB: mockQueries, Templates: notification_templates.MustNewRenderer()}}
+		apiConfig.ImportBooks(recorder, request, userId)
+
+		if bookImportReport := decodeBookImportReport(t, recorder); recorder.Code != http.StatusCreated || bookImportReport.Created != 1 || bookImportReport.Skipped != 1 {
+			t.Errorf("Expected one book created and one skipped, got %d %+v", recorder.Code, bookImportReport)
+		}
+	})
+
+	// 1b. Success: only rows missing their title or author are looked up, and a failed
+	// lookup only fails its own row.
+	tTesting.Run("CatalogLookups", func(t *testing.T) {
+		testCatalog := &testCatalogProvider{
+			books: map[string]common.BookMetadata{"9780306406157": {Title: "Piranesi", Authors: []string{"Susanna Clarke"}, Publisher: "Bloomsbury"}},
+			err:   errors.New("catalog unavailable"),
+		}
+
+		var createdTitles []string
+
+		mockQueries := &MockQueries{
+			BaseMock: common.NewBaseMock(),
+			CreateBookFunc: func(ctx context.Context, arg database.CreateBookParams) (database.Book, error) {
+				createdTitles = append(createdTitles, arg.Title)
+
+				return createTestBook(ctx, arg)
+			},
+			CreateBookCopyFunc: createTestBookCopy,
+		}
+
+		apiConfig := BookImportAPIConfig{APIConfig: common.APIConfig{DB: mockQueries, Catalog: testCatalog, Templates: notification_templates.MustNewRenderer()}}
+
+		recorder := httptest.NewRecorder()
+		apiConfig.ImportBooks(recorder, importRequest("title,author,isbn\n"+
+			"Dune,Frank Herbert,9780441172719\n"+
+			",,9780306406157\n"+
+			"Kindred,,9781408855652\n", ""), userId)
+
+		if recorder.Code != http.StatusCreated {
+			t.Fatalf("Expected status %d, got %d. Body: %s", http.StatusCreated, recorder.Code, recorder.Body.String())
+		}
+
+		bookImportReport := decodeBookImportReport(t, recorder)
+
+		if bookImportReport.Created != 2 || bookImportReport.Failed != 1 || !strings.Contains(bookImportReport.Rows[2].Error, "could not look up the isbn") {
+			t.Errorf("Unexpected report: %+v", bookImportReport)
+		}
+
+		sort.Strings(testCatalog.lookups)
+
+		if strings.Join(testCatalog.lookups, ",") != "9780306406157,9781408855652" {
+			t.Errorf("Expected only the rows missing a title or author to be looked up, got %v", testCatalog.lookups)
+		}
+
+		if strings.Join(createdTitles, ",") != "Dune,Piranesi" {
+			t.Errorf("Unexpected books created: %v", createdTitles)
+		}
+	})
+
+	// 2. Failure: atomic imports add nothing when a row fails.
+	tTesting.Run("AtomicFailure", func(t *testing.T) {
+		mockQueries := &MockQueries{BaseMock: common.NewBaseMock()}
+
+		apiConfig := BookImportAPIConfig{APIConfig: common.APIConfig{DB: mockQueries}}
+
+		recorder := httptest.NewRecorder()
+		apiConfig.ImportBooks(recorder, importRequest("title,author,copies\nDune,Frank Herbert,1\nKindred,Octavia E. Butler,100\n", "?atomic=true"), userId)
+
+		if recorder.Code != http.StatusUnprocessableEntity {
+			t.Fatalf("Expected status %d, got %d. Body: %s", http.StatusUnprocessableEntity, recorder.Code, recorder.Body.String())
+		}
+
+		if bookImportReport := decodeBookImportReport(t, recorder); bookImportReport.Error == "" || bookImportReport.Created != 0 || bookImportReport.Failed != 1 {
+			t.Errorf("Unexpected report: %+v", bookImportReport)
+		}
+	})
+
+	// 3. Failure: a database error rolls back the whole import.
+	tTesting.Run("CreateError", func(t *testing.T) {
+		mockQueries := &MockQueries{
+			BaseMock: common.NewBaseMock(),
+			CreateBookFunc: func(ctx context.Context, arg database.CreateBookParams) (database.Book, error) {
+				return database.Book{}, errors.New("connection reset")
+			},
+		}
+
+		apiConfig := BookImportAPIConfig{APIConfig: common.APIConfig{DB: mockQueries}}
+
+		recorder := httptest.NewRecorder()
+		apiConfig.ImportBooks(recorder, importRequest("title,author\nDune,Frank Herbert\n", ""), userId)
+
+		if recorder.Code != http.StatusInternalServerError {
+			t.Errorf("Expected status %d, got %d", http.StatusInternalServerError, recorder.Code)
+		}
+	})
+
+	// 4. Failure: bad requests.
+	tTesting.Run("InvalidRequest", func(t *testing.T) {
+		apiConfig := BookImportAPIConfig{APIConfig: common.APIConfig{DB: &MockQueries{BaseMock: common.NewBaseMock()}}}
+
+		tests := []struct {
+			name           string
+			request        *http.Request
+			expectedStatus int
+		}{
+			{"InvalidAtomic", importRequest("title\nDune\n", "?atomic=sometimes"), http.StatusBadRequest},
+			{"NotCSV", importRequest("\x00\x01\x02", ""), http.StatusBadRequest},
+			{"TooLarge", importRequest("title\n"+strings.Repeat("x", MaxBookImportSize+(64<<10)), ""), http.StatusRequestEntityTooLarge},
+		}
+
+		for _, tt := range tests {
+			recorder := httptest.NewRecorder()
+			apiConfig.ImportBooks(recorder, tt.request, userId)
+
+			if recorder.Code != tt.expectedStatus {
+				t.Errorf("%s: expected status %d, got %d. Body: %s", tt.name, tt.expectedStatus, recorder.Code, recorder.Body.String())
+			}
+		}
+	})
+}
@@ -0,0 +1,363 @@
+package book_imports
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/elorenzorodz/co-library/books"
+	"github.com/elorenzorodz/co-library/common"
+)
+
+var publishedYearPattern = regexp.MustCompile(`\b\d{4}\b`)
+
+// Reads the file from the multipart form, or from the body when it is sent as is.
+func ReadBookImportFile(writer http.ResponseWriter, request *http.Request) ([]byte, int, error) {
+	// Leaves room for the multipart boundaries and headers around the file.
+	request.Body = http.MaxBytesReader(writer, request.Body, MaxBookImportSize+(64<<10))
+
+	var importFile io.Reader = request.Body
+
+	if mediaType, _, _ := mime.ParseMediaType(request.Header.Get("Content-Type")); mediaType == "multipart/form-data" {
+		formFile, _, formFileError := request.FormFile(BookImportFormField)
+
+		if formFileError != nil {
+			var maxBytesError *http.MaxBytesError
+
+			if errors.As(formFileError, &maxBytesError) {
+				return nil, http.StatusRequestEntityTooLarge, fmt.Errorf("import file must be at most %d MB", MaxBookImportSize>>20)
+			}
+
+			return nil, http.StatusBadRequest, fmt.Errorf("import file is required in the %q form field", BookImportFormField)
+		}
+
+		defer formFile.Close()
+
+		importFile = formFile
+	}
+
+	content, readError := io.ReadAll(io.LimitReader(importFile, MaxBookImportSize+1))
+
+	if readError != nil {
+		var maxBytesError *http.MaxBytesError
+
+		if errors.As(readError, &maxBytesError) {
+			return nil, http.StatusRequestEntityTooLarge, fmt.Errorf("import file must be at most %d MB", MaxBookImportSize>>20)
+		}
+
+		return nil, http.StatusBadRequest, fmt.Errorf("error reading import file: %s", readError)
+	}
+
+	if len(content) > MaxBookImportSize {
+		return nil, http.StatusRequestEntityTooLarge, fmt.Errorf("import file must be at most %d MB", MaxBookImportSize>>20)
+	}
+
+	return content, http.StatusOK, nil
+}
+
+// Maps each row to the parameters CreateBook takes. Problems with a single row
+// are kept on its record, the error is for files that can't be read at all.
+func ParseBookImportCSV(content []byte) ([]BookImportRecord, error) {
+	csvReader := csv.NewReader(bytes.NewReader(bytes.TrimPrefix(content, []byte("\xef\xbb\xbf"))))
+	csvReader.FieldsPerRecord = -1
+	csvReader.LazyQuotes = true
+
+	header, headerError := csvReader.Read()
+
+	if headerError != nil {
+		if headerError == io.EOF {
+			return nil, errors.New("import file is empty")
+		}
+
+		return nil, fmt.Errorf("error reading import file header: %s", headerError)
+	}
+
+	columns := map[string]int{}
+
+	for index, name := range header {
+		if field, found := BookImportColumns[strings.ToLower(strings.TrimSpace(name))]; found {
+			if _, taken := columns[field]; !taken {
+				columns[field] = index
+			}
+		}
+	}
+
+	_, hasTitle := columns[bookImportTitle]
+	_, hasISBN := columns[bookImportISBN]
+	_, hasISBN13 := columns[bookImportISBN13]
+
+	if !hasTitle && !hasISBN && !hasISBN13 {
+		return nil, errors.New("import file must have a title or isbn column")
+	}
+
+	var bookImportRecords []BookImportRecord
+
+	for {
+		record, readError := csvReader.Read()
+
+		if readError == io.EOF {
+			break
+		}
+
+		if readError != nil {
+			return nil, fmt.Errorf("error reading import file: %s", readError)
+		}
+
+		row, _ := csvReader.FieldPos(0)
+
+		if isBlankRecord(record) {
+			continue
+		}
+
+		if len(bookImportRecords) == MaxBookImportRows {
+			return nil, fmt.Errorf("import file can have at most %d books", MaxBookImportRows)
+		}
+
+		bookImportRecords = append(bookImportRecords, newBookImportRecord(row, columns, record))
+	}
+
+	if len(bookImportRecords) == 0 {
+		return nil, errors.New("import file has no books")
+	}
+
+	return bookImportRecords, nil
+}
+
+func newBookImportRecord(row int, columns map[string]int, record []string) BookImportRecord {
+	value := func(field string) string {
+		index, found := columns[field]
+
+		if !found || index >= len(record) {
+			return ""
+		}
+
+		return strings.TrimSpace(record[index])
+	}
+
+	bookImportRecord := BookImportRecord{Row: row}
+
+	if value(bookImportExclusiveShelf) == goodreadsToReadShelf {
+		bookImportRecord.UpsertBookParameters.Title = value(bookImportTitle)
+		bookImportRecord.SkipReason = "on the to-read shelf"
+
+		return bookImportRecord
+	}
+
+	upsertBookParameters := books.UpsertBookParameters{
+		Title:          value(bookImportTitle),
+		Author:         value(bookImportAuthor),
+		ISBN:           cleanImportISBN(value(bookImportISBN13)),
+		Publisher:      value(bookImportPublisher),
+		CoverURL:       value(bookImportCoverURL),
+		Genres:         splitImportList(value(bookImportGenres)),
+		Tags:           splitImportList(value(bookImportTags)),
+		Visibility:     value(bookImportVisibility),
+		BorrowerPolicy: value(bookImportBorrowerPolicy),
+	}
+
+	if upsertBookParameters.ISBN == "" {
+		upsertBookParameters.ISBN = cleanImportISBN(value(bookImportISBN))
+	}
+
+	publishedYear := value(bookImportPublishedYear)
+
+	if publishedYear == "" {
+		publishedYear = value(bookImportOriginalPublishedYear)
+	}
+
+	var parseErrors []string
+
+	if publishedYear != "" {
+		// Dates come as anything from "1965" to "August 1, 1965".
+		yearMatch := publishedYearPattern.FindString(publishedYear)
+
+		if yearMatch == "" {
+			parseErrors = append(parseErrors, fmt.Sprintf("invalid published year %q", publishedYear))
+		} else {
+			year, _ := strconv.ParseInt(yearMatch, 10, 32)
+			upsertBookParameters.PublishedYear = int32(year)
+		}
+	}
+
+	numberColumns := []struct {
+		field  string
+		target *int32
+	}{
+		{bookImportPageCount, &upsertBookParameters.PageCount},
+		{bookImportLoanPeriodDays, &upsertBookParameters.LoanPeriodDays},
+		{bookImportCopies, &upsertBookParameters.Copies},
+	}
+
+	for _, numberColumn := range numberColumns {
+		if number := value(numberColumn.field); number != "" {
+			parsedNumber, parseError := strconv.ParseInt(number, 10, 32)
+
+			if parseError != nil {
+				parseErrors = append(parseErrors, fmt.Sprintf("invalid %s %q", numberColumn.field, number))
+			} else {
+				*numberColumn.target = int32(parsedNumber)
+			}
+		}
+	}
+
+	if lendable := value(bookImportLendable); lendable != "" {
+		parsedLendable, parseError := strconv.ParseBool(lendable)
+
+		if parseError != nil {
+			parseErrors = append(parseErrors, fmt.Sprintf("invalid lendable %q", lendable))
+		} else {
+			upsertBookParameters.Lendable = &parsedLendable
+		}
+	}
+
+	bookImportRecord.UpsertBookParameters = upsertBookParameters
+
+	if len(parseErrors) > 0 {
+		bookImportRecord.Error = errors.New(strings.Join(parseErrors, "; "))
+	}
+
+	return bookImportRecord
+}
+
+// Goodreads wraps ISBNs as ="0441172717" and LibraryThing as [0441172717], the
+// latter sometimes listing several.
+func cleanImportISBN(isbn string) string {
+	isbn = strings.Trim(isbn, `=" []`)
+	isbn, _, _ = strings.Cut(isbn, ",")
+
+	return strings.TrimSpace(isbn)
+}
+
+// Lists are separated by commas or semicolons.
+func splitImportList(list string) []string {
+	if list == "" {
+		return nil
+	}
+
+	return strings.FieldsFunc(list, func(character rune) bool {
+		return character == ',' || character == ';'
+	})
+}
+
+func isBlankRecord(record []string) bool {
+	for _, field := range record {
+		if strings.TrimSpace(field) != "" {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Only rows with an ISBN that leave out their title or author need the catalog, the
+// rest are imported with the data they have.
+func NeedsCatalogLookup(upsertBookParameters books.UpsertBookParameters) bool {
+	if strings.TrimSpace(upsertBookParameters.Title) != "" && strings.TrimSpace(upsertBookParameters.Author) != "" {
+		return false
+	}
+
+	_, normalizeError := common.NormalizeISBN(upsertBookParameters.ISBN)
+
+	return strings.TrimSpace(upsertBookParameters.ISBN) != "" && normalizeError == nil
+}
+
+// Fills in the rows at indexes from the catalog, BookImportCatalogWorkers at a time and
+// all under BookImportCatalogTimeout. A row whose lookup fails or never runs keeps its
+// own data, and its error is returned at the row's index.
+func FillBookImportRecordsFromCatalog(ctx context.Context, catalog common.CatalogProvider, bookImportRecords []BookImportRecord, indexes []int) []error {
+	lookupErrors := make([]error, len(bookImportRecords))
+
+	if catalog == nil || len(indexes) == 0 {
+		return lookupErrors
+	}
+
+	lookupContext, cancel := context.WithTimeout(ctx, BookImportCatalogTimeout)
+	defer cancel()
+
+	for _, index := range indexes {
+		bookImportRecords[index].UpsertBookParameters.ISBN, _ = common.NormalizeISBN(bookImportRecords[index].UpsertBookParameters.ISBN)
+	}
+
+	lookupIndexes := make(chan int)
+	var waitGroup sync.WaitGroup
+
+	// Each worker only touches the rows it is handed, so they need no locking.
+	for worker := 0; worker < BookImportCatalogWorkers; worker++ {
+		waitGroup.Add(1)
+
+		go func() {
+			defer waitGroup.Done()
+
+			for index := range lookupIndexes {
+				upsertBookParameters := bookImportRecords[index].UpsertBookParameters
+				lookupError := books.FillBookFromCatalog(lookupContext, catalog, &upsertBookParameters)
+
+				if lookupError != nil {
+					if !errors.Is(lookupError, common.ErrCatalogBookNotFound) {
+						log.Printf("catalog lookup for isbn %s failed: %s", upsertBookParameters.ISBN, lookupError)
+					}
+
+					lookupErrors[index] = lookupError
+
+					continue
+				}
+
+				bookImportRecords[index].UpsertBookParameters = upsertBookParameters
+			}
+		}()
+	}
+
+	for _, index := range indexes {
+		select {
+		case lookupIndexes <- index:
+		case <-lookupContext.Done():
+			lookupErrors[index] = lookupContext.Err()
+		}
+	}
+
+	close(lookupIndexes)
+	waitGroup.Wait()
+
+	return lookupErrors
+}
+
+// A book is the same as another when the ISBNs match, or when the titles and
+// authors do regardless of case.
+func BookIdentityKeys(isbn string, title string, author string) []string {
+	bookIdentityKeys := []string{"title:" + strings.ToLower(strings.TrimSpace(title)) + "\x00" + strings.ToLower(strings.TrimSpace(author))}
+
+	if isbn != "" {
+		bookIdentityKeys = append(bookIdentityKeys, "isbn:"+isbn)
+	}
+
+	return bookIdentityKeys
+}
+
+func newBookImportReport(bookImportRows []BookImportRow) BookImportReport {
+	bookImportReport := BookImportReport{Rows: bookImportRows}
+
+	for _, bookImportRow := range bookImportRows {
+		switch bookImportRow.Status {
+		case BookImportRowCreated:
+			bookImportReport.Created++
+		case BookImportRowDuplicate:
+			bookImportReport.Duplicates++
+		case BookImportRowSkipped:
+			bookImportReport.Skipped++
+		case BookImportRowFailed:
+			bookImportReport.Failed++
+		}
+	}
+
+	return bookImportReport
+}
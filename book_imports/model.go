@@ -0,0 +1,113 @@
+package book_imports
+
+import (
+	"time"
+
+	"github.com/elorenzorodz/co-library/books"
+	"github.com/elorenzorodz/co-library/common"
+	"github.com/google/uuid"
+)
+
+type BookImportAPIConfig struct {
+	common.APIConfig
+}
+
+const (
+	// Name of the multipart form field holding the file, the file can also be sent as the request body.
+	BookImportFormField = "file"
+	MaxBookImportSize   = 2 << 20
+	MaxBookImportRows   = 500
+	// Rows missing their title or author are looked up a few at a time, and the lookups
+	// for the whole file have to finish within the timeout.
+	BookImportCatalogWorkers = 4
+	BookImportCatalogTimeout = 30 * time.Second
+)
+
+// What happened to each row of an import.
+const (
+	BookImportRowCreated   = "created"
+	BookImportRowDuplicate = "duplicate"
+	BookImportRowSkipped   = "skipped"
+	BookImportRowFailed    = "failed"
+)
+
+// Book fields the import columns map to.
+const (
+	bookImportTitle                 = "title"
+	bookImportAuthor                = "author"
+	bookImportISBN                  = "isbn"
+	bookImportISBN13                = "isbn13"
+	bookImportPublisher             = "publisher"
+	bookImportPublishedYear         = "published_year"
+	bookImportOriginalPublishedYear = "original_published_year"
+	bookImportPageCount             = "page_count"
+	bookImportCoverURL              = "cover_url"
+	bookImportLoanPeriodDays        = "loan_period_days"
+	bookImportCopies                = "copies"
+	bookImportGenres                = "genres"
+	bookImportTags                  = "tags"
+	bookImportVisibility            = "visibility"
+	bookImportLendable              = "lendable"
+	bookImportBorrowerPolicy        = "borrower_policy"
+	bookImportExclusiveShelf        = "exclusive_shelf"
+)
+
+// Header names, lowercased, of our own CSV columns and of the Goodreads and
+// LibraryThing exports. Unknown columns are ignored.
+var BookImportColumns = map[string]string{
+	"title":                     bookImportTitle,
+	"author":                    bookImportAuthor,
+	"primary author":            bookImportAuthor,
+	"isbn":                      bookImportISBN,
+	"isbns":                     bookImportISBN,
+	"isbn13":                    bookImportISBN13,
+	"publisher":                 bookImportPublisher,
+	"published_year":            bookImportPublishedYear,
+	"year published":            bookImportPublishedYear,
+	"date":                      bookImportPublishedYear,
+	"original publication year": bookImportOriginalPublishedYear,
+	"page_count":                bookImportPageCount,
+	"number of pages":           bookImportPageCount,
+	"page count":                bookImportPageCount,
+	"pages":                     bookImportPageCount,
+	"cover_url":                 bookImportCoverURL,
+	"loan_period_days":          bookImportLoanPeriodDays,
+	"copies":                    bookImportCopies,
+	"genres":                    bookImportGenres,
+	"tags":                      bookImportTags,
+	"visibility":                bookImportVisibility,
+	"lendable":                  bookImportLendable,
+	"borrower_policy":           bookImportBorrowerPolicy,
+	"exclusive shelf":           bookImportExclusiveShelf,
+}
+
+// Goodreads exports include the books a member only wants to read.
+const goodreadsToReadShelf = "to-read"
+
+// A data row of the file. Row is the line it starts on, the header being line 1.
+type BookImportRecord struct {
+	Row                  int
+	UpsertBookParameters books.UpsertBookParameters
+	// Set when the row is left out on purpose.
+	SkipReason string
+	// Set when a column could not be read.
+	Error error
+}
+
+type BookImportReport struct {
+	// Only set when nothing was imported because of failed rows.
+	Error      string          `json:"error,omitempty"`
+	Created    int             `json:"created"`
+	Duplicates int             `json:"duplicates"`
+	Skipped    int             `json:"skipped"`
+	Failed     int             `json:"failed"`
+	Rows       []BookImportRow `json:"rows"`
+}
+
+type BookImportRow struct {
+	Row    int        `json:"row"`
+	Status string     `json:"status"`
+	Title  string     `json:"title"`
+	BookID *uuid.UUID `json:"book_id,omitempty"`
+	Error  string     `json:"error,omitempty"`
+}
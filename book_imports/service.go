@@ -0,0 +1,179 @@
+package book_imports
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/elorenzorodz/co-library/books"
+	"github.com/elorenzorodz/co-library/common"
+	"github.com/elorenzorodz/co-library/internal/database"
+	"github.com/google/uuid"
+)
+
+func (bookImportAPIConfig *BookImportAPIConfig) ImportBooks(writer http.ResponseWriter, request *http.Request, userId uuid.UUID) {
+	// Atomic imports add nothing when any row fails, otherwise the rows that pass are added.
+	atomic := false
+
+	if atomicQuery := request.URL.Query().Get("atomic"); atomicQuery != "" {
+		parsedAtomic, parseAtomicError := strconv.ParseBool(atomicQuery)
+
+		if parseAtomicError != nil {
+			common.ErrorResponse(writer, http.StatusBadRequest, "atomic must be true or false")
+
+			return
+		}
+
+		atomic = parsedAtomic
+	}
+
+	content, readStatus, readImportFileError := ReadBookImportFile(writer, request)
+
+	if readImportFileError != nil {
+		common.ErrorResponse(writer, readStatus, readImportFileError.Error())
+
+		return
+	}
+
+	bookImportRecords, parseError := ParseBookImportCSV(content)
+
+	if parseError != nil {
+		common.ErrorResponse(writer, http.StatusBadRequest, parseError.Error())
+
+		return
+	}
+
+	bookIdentities, getBookIdentitiesError := bookImportAPIConfig.DB.GetBookIdentities(request.Context(), userId)
+
+	if getBookIdentitiesError != nil {
+		common.ErrorResponse(writer, http.StatusInternalServerError, fmt.Sprintf("error getting books: %s", getBookIdentitiesError))
+
+		return
+	}
+
+	knownBooks := map[string]bool{}
+
+	for _, bookIdentity := range bookIdentities {
+		for _, bookIdentityKey := range BookIdentityKeys(bookIdentity.Isbn, bookIdentity.Title, bookIdentity.Author) {
+			knownBooks[bookIdentityKey] = true
+		}
+	}
+
+	isKnownBook := func(upsertBookParameters books.UpsertBookParameters) bool {
+		isbn, _ := common.NormalizeISBN(upsertBookParameters.ISBN)
+
+		if isbn != "" && knownBooks["isbn:"+isbn] {
+			return true
+		}
+
+		return upsertBookParameters.Title != "" && upsertBookParameters.Author != "" &&
+			knownBooks[BookIdentityKeys("", upsertBookParameters.Title, upsertBookParameters.Author)[0]]
+	}
+
+	// Only rows missing their title or author go to the catalog, and those lookups run side by
+	// side under one deadline, so a large file can't hold the request for long.
+	var catalogLookupIndexes []int
+
+	for index, bookImportRecord := range bookImportRecords {
+		if bookImportRecord.SkipReason == "" && bookImportRecord.Error == nil && !isKnownBook(bookImportRecord.UpsertBookParameters) && NeedsCatalogLookup(bookImportRecord.UpsertBookParameters) {
+			catalogLookupIndexes = append(catalogLookupIndexes, index)
+		}
+	}
+
+	catalogLookupErrors := FillBookImportRecordsFromCatalog(request.Context(), bookImportAPIConfig.Catalog, bookImportRecords, catalogLookupIndexes)
+
+	bookImportRows := make([]BookImportRow, len(bookImportRecords))
+	var newBookIndexes []int
+
+	// Each row is checked the way CreateBook checks a book. Rows already in the library, or
+	// earlier in the file, are caught before and after the catalog fills in what they leave out.
+	for index := range bookImportRecords {
+		bookImportRecord := &bookImportRecords[index]
+		bookImportRow := &bookImportRows[index]
+		bookImportRow.Row = bookImportRecord.Row
+
+		switch {
+		case bookImportRecord.SkipReason != "":
+			bookImportRow.Status = BookImportRowSkipped
+			bookImportRow.Error = bookImportRecord.SkipReason
+		case bookImportRecord.Error != nil:
+			bookImportRow.Status = BookImportRowFailed
+			bookImportRow.Error = bookImportRecord.Error.Error()
+		case isKnownBook(bookImportRecord.UpsertBookParameters):
+			bookImportRow.Status = BookImportRowDuplicate
+		default:
+			// The catalog was already asked above, what it didn't fill in stays blank.
+			_, resolveError := books.ResolveNewBookParameters(request.Context(), nil, bookImportAPIConfig.DB, &bookImportRecord.UpsertBookParameters)
+
+			if resolveError != nil {
+				bookImportRow.Status = BookImportRowFailed
+				bookImportRow.Error = resolveError.Error()
+
+				if catalogLookupErrors[index] != nil && !errors.Is(catalogLookupErrors[index], common.ErrCatalogBookNotFound) {
+					bookImportRow.Error = "could not look up the isbn right now, add the title and author to the row or try again later"
+				}
+			} else if isKnownBook(bookImportRecord.UpsertBookParameters) {
+				bookImportRow.Status = BookImportRowDuplicate
+			} else {
+				for _, bookIdentityKey := range BookIdentityKeys(bookImportRecord.UpsertBookParameters.ISBN, bookImportRecord.UpsertBookParameters.Title, bookImportRecord.UpsertBookParameters.Author) {
+					knownBooks[bookIdentityKey] = true
+				}
+
+				newBookIndexes = append(newBookIndexes, index)
+			}
+		}
+
+		bookImportRow.Title = bookImportRecord.UpsertBookParameters.Title
+	}
+
+	bookImportReport := newBookImportReport(bookImportRows)
+
+	if atomic && bookImportReport.Failed > 0 {
+		bookImportReport.Error = "no books were imported, fix the failed rows and try again"
+
+		common.JSONResponse(writer, http.StatusUnprocessableEntity, bookImportReport)
+
+		return
+	}
+
+	newBooks := make([]database.Book, 0, len(newBookIndexes))
+
+	// All the books go in one transaction, with a single alert to each subscriber about the whole batch.
+	importBooksError := bookImportAPIConfig.InTransaction(request.Context(), func(querier common.Querier) error {
+		for _, index := range newBookIndexes {
+			upsertBookParameters := bookImportRecords[index].UpsertBookParameters
+
+			newBook, createError := books.CreateBookWithCopies(request.Context(), querier, books.NewCreateBookParams(userId, upsertBookParameters), upsertBookParameters)
+
+			if createError != nil {
+				return fmt.Errorf("row %d: %w", bookImportRecords[index].Row, createError)
+			}
+
+			newBooks = append(newBooks, newBook)
+		}
+
+		return books.EnqueueNewBooksAlerts(request.Context(), querier, bookImportAPIConfig.Templates, userId, newBooks)
+	})
+
+	if importBooksError != nil {
+		common.ErrorResponse(writer, http.StatusInternalServerError, fmt.Sprintf("error importing books: %s", importBooksError))
+
+		return
+	}
+
+	for newBookIndex, index := range newBookIndexes {
+		bookImportRows[index].Status = BookImportRowCreated
+		bookImportRows[index].BookID = &newBooks[newBookIndex].ID
+	}
+
+	bookImportReport = newBookImportReport(bookImportRows)
+
+	responseStatus := http.StatusOK
+
+	if bookImportReport.Created > 0 {
+		responseStatus = http.StatusCreated
+	}
+
+	common.JSONResponse(writer, responseStatus, bookImportReport)
+}
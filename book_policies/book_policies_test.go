@@ -0,0 +1,378 @@
+package book_policies
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/elorenzorodz/co-library/common"
+	"github.com/elorenzorodz/co-library/internal/database"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+type MockQueries struct {
+	*common.BaseMock
+
+	GetUserByIDFunc            func(ctx context.Context, id uuid.UUID) (database.User, error)
+	GetMemberRelationshipFunc  func(ctx context.Context, arg database.GetMemberRelationshipParams) (database.GetMemberRelationshipRow, error)
+	CreateApprovedBorrowerFunc func(ctx context.Context, arg database.CreateApprovedBorrowerParams) (database.ApprovedBorrower, error)
+	GetApprovedBorrowerFunc    func(ctx context.Context, arg database.GetApprovedBorrowerParams) (database.ApprovedBorrower, error)
+	GetApprovedBorrowersFunc   func(ctx context.Context, arg database.GetApprovedBorrowersParams) ([]database.GetApprovedBorrowersRow, error)
+	DeleteApprovedBorrowerFunc func(ctx context.Context, arg database.DeleteApprovedBorrowerParams) (int64, error)
+}
+
+func (mockQueries *MockQueries) GetUserByID(ctx context.Context, id uuid.UUID) (database.User, error) {
+	if mockQueries.GetUserByIDFunc != nil {
+		return mockQueries.GetUserByIDFunc(ctx, id)
+	}
+
+	return mockQueries.BaseMock.GetUserByID(ctx, id)
+}
+
+func (mockQueries *MockQueries) GetMemberRelationship(ctx context.Context, arg database.GetMemberRelationshipParams) (database.GetMemberRelationshipRow, error) {
+	if mockQueries.GetMemberRelationshipFunc != nil {
+		return mockQueries.GetMemberRelationshipFunc(ctx, arg)
+	}
+
+	return mockQueries.BaseMock.GetMemberRelationship(ctx, arg)
+}
+
+func (mockQueries *MockQueries) CreateApprovedBorrower(ctx context.Context, arg database.CreateApprovedBorrowerParams) (database.ApprovedBorrower, error) {
+	if mockQueries.CreateApprovedBorrowerFunc != nil {
+		return mockQueries.CreateApprovedBorrowerFunc(ctx, arg)
+	}
+
+	return mockQueries.BaseMock.CreateApprovedBorrower(ctx, arg)
+}
+
+func (mockQueries *MockQueries) GetApprovedBorrower(ctx context.Context, arg database.GetApprovedBorrowerParams) (database.ApprovedBorrower, error) {
+	if mockQueries.GetApprovedBorrowerFunc != nil {
+		return mockQueries.GetApprovedBorrowerFunc(ctx, arg)
+	}
+
+	return mockQueries.BaseMock.GetApprovedBorrower(ctx, arg)
+}
+
+func (mockQueries *MockQueries) GetApprovedBorrowers(ctx context.Context, arg database.GetApprovedBorrowersParams) ([]database.GetApprovedBorrowersRow, error) {
+	if mockQueries.GetApprovedBorrowersFunc != nil {
+		return mockQueries.GetApprovedBorrowersFunc(ctx, arg)
+	}
+
+	return mockQueries.BaseMock.GetApprovedBorrowers(ctx, arg)
+}
+
+func (mockQueries *MockQueries) DeleteApprovedBorrower(ctx context.Context, arg database.DeleteApprovedBorrowerParams) (int64, error) {
+	if mockQueries.DeleteApprovedBorrowerFunc != nil {
+		return mockQueries.DeleteApprovedBorrowerFunc(ctx, arg)
+	}
+
+	return mockQueries.BaseMock.DeleteApprovedBorrower(ctx, arg)
+}
+
+func newTestBook(userId uuid.UUID, visibility string, lendable bool, borrowerPolicy string) database.Book {
+	return database.Book{
+		ID:             uuid.New(),
+		Title:          "The Dispossessed",
+		UserID:         userId,
+		Visibility:     visibility,
+		Lendable:       lendable,
+		BorrowerPolicy: borrowerPolicy,
+	}
+}
+
+func TestValidateBookPolicies(tTesting *testing.T) {
+	// 1. Success: known values, and empty ones for the caller to fill in.
+	tTesting.Run("Valid", func(t *testing.T) {
+		for _, visibility := range append(BookVisibilities, "") {
+			for _, borrowerPolicy := range append(BookBorrowerPolicies, "") {
+				if err := ValidateBookPolicies(visibility, borrowerPolicy); err != nil {
+					t.Errorf("%q/%q: expected no error, got %v", visibility, borrowerPolicy, err)
+				}
+			}
+		}
+	})
+
+	// 2. Failure: unknown values.
+	tTesting.Run("Invalid", func(t *testing.T) {
+		if err := ValidateBookPolicies("friends", ""); err == nil {
+			t.Error("Expected an error for an unknown visibility")
+		}
+
+		if err := ValidateBookPolicies("", "Anyone"); err == nil {
+			t.Error("Expected an error for an unknown borrower_policy")
+		}
+	})
+}
+
+func TestCheckBookLendingPolicy(tTesting *testing.T) {
+	ownerId := uuid.New()
+	borrowerId := uuid.New()
+	stranger := database.GetMemberRelationshipRow{}
+	subscriber := database.GetMemberRelationshipRow{IsSubscriber: true}
+	approvedBorrower := database.GetMemberRelationshipRow{IsApprovedBorrower: true}
+
+	testCases := []struct {
+		name           string
+		book           database.Book
+		relationship   database.GetMemberRelationshipRow
+		expectedStatus int
+	}{
+		{"PublicToAnyone", newTestBook(ownerId, BookVisibilityPublic, true, BookBorrowerPolicyAnyone), stranger, 0},
+		{"NotLendable", newTestBook(ownerId, BookVisibilityPublic, false, BookBorrowerPolicyAnyone), subscriber, http.StatusForbidden},
+		{"SubscribersOnly", newTestBook(ownerId, BookVisibilityPublic, true, BookBorrowerPolicySubscribers), stranger, http.StatusForbidden},
+		{"SubscribersOnlySubscriber", newTestBook(ownerId, BookVisibilityPublic, true, BookBorrowerPolicySubscribers), subscriber, 0},
+		{"ApprovedOnly", newTestBook(ownerId, BookVisibilityPublic, true, BookBorrowerPolicyApproved), subscriber, http.StatusForbidden},
+		{"ApprovedOnlyApproved", newTestBook(ownerId, BookVisibilityPublic, true, BookBorrowerPolicyApproved), approvedBorrower, 0},
+		{"HiddenFromNonSubscriber", newTestBook(ownerId, BookVisibilitySubscribers, true, BookBorrowerPolicyApproved), approvedBorrower, http.StatusNotFound},
+		{"Private", newTestBook(ownerId, BookVisibilityPrivate, true, BookBorrowerPolicyAnyone), subscriber, http.StatusNotFound},
+	}
+
+	// 1. Each visibility and borrower policy against how the borrower stands with the owner.
+	for _, testCase := range testCases {
+		tTesting.Run(testCase.name, func(t *testing.T) {
+			status, err := CheckBookLendingPolicy(testCase.book, borrowerId, testCase.relationship)
+
+			if status != testCase.expectedStatus || (err == nil) != (testCase.expectedStatus == 0) {
+				t.Errorf("Expected status %d, got %d (%v)", testCase.expectedStatus, status, err)
+			}
+		})
+	}
+}
+
+func TestCheckBookViewer(tTesting *testing.T) {
+	ownerId := uuid.New()
+
+	// 1. Success: public books and owners don't need the relationship looked up.
+	tTesting.Run("NoLookup", func(t *testing.T) {
+		mockQueries := &MockQueries{
+			BaseMock: common.NewBaseMock(),
+			GetMemberRelationshipFunc: func(ctx context.Context, arg database.GetMemberRelationshipParams) (database.GetMemberRelationshipRow, error) {
+				t.Fatal("Expected no relationship lookup")
+
+				return database.GetMemberRelationshipRow{}, nil
+			},
+		}
+
+		publicBook := newTestBook(ownerId, BookVisibilityPublic, true, BookBorrowerPolicyAnyone)
+		privateBook := newTestBook(ownerId, BookVisibilityPrivate, true, BookBorrowerPolicyAnyone)
+
+		if canView, err := CheckBookViewer(context.Background(), mockQueries, publicBook, uuid.New()); !canView || err != nil {
+			t.Errorf("Expected the public book to be visible, got %v (%v)", canView, err)
+		}
+
+		if canView, err := CheckBookViewer(context.Background(), mockQueries, privateBook, ownerId); !canView || err != nil {
+			t.Errorf("Expected the owner to see their private book, got %v (%v)", canView, err)
+		}
+	})
+
+	// 2. Success: subscriber-only books are looked up against the owner.
+	tTesting.Run("Subscribers", func(t *testing.T) {
+		subscriberId := uuid.New()
+		subscribersBook := newTestBook(ownerId, BookVisibilitySubscribers, true, BookBorrowerPolicyAnyone)
+
+		mockQueries := &MockQueries{
+			BaseMock: common.NewBaseMock(),
+			GetMemberRelationshipFunc: func(ctx context.Context, arg database.GetMemberRelationshipParams) (database.GetMemberRelationshipRow, error) {
+				if arg.OwnerID != ownerId {
+					t.Errorf("Expected owner %s, got %s", ownerId, arg.OwnerID)
+				}
+
+				return database.GetMemberRelationshipRow{IsSubscriber: arg.MemberID == subscriberId}, nil
+			},
+		}
+
+		if canView, _ := CheckBookViewer(context.Background(), mockQueries, subscribersBook, subscriberId); !canView {
+			t.Error("Expected subscribers to see the book")
+		}
+
+		if canView, _ := CheckBookViewer(context.Background(), mockQueries, subscribersBook, uuid.New()); canView {
+			t.Error("Expected the book hidden from other members")
+		}
+	})
+
+	// 3. Failure: database error.
+	tTesting.Run("InternalDBError", func(t *testing.T) {
+		mockQueries := &MockQueries{
+			BaseMock: common.NewBaseMock(),
+			GetMemberRelationshipFunc: func(ctx context.Context, arg database.GetMemberRelationshipParams) (database.GetMemberRelationshipRow, error) {
+				return database.GetMemberRelationshipRow{}, errors.New("simulated DB connection failure")
+			},
+		}
+
+		if status, err := CheckBookBorrower(context.Background(), mockQueries, newTestBook(ownerId, BookVisibilityPrivate, true, BookBorrowerPolicyAnyone), uuid.New()); status != http.StatusInternalServerError || err == nil {
+			t.Errorf("Expected status %d, got %d (%v)", http.StatusInternalServerError, status, err)
+		}
+	})
+}
+
+func TestCreateApprovedBorrower(tTesting *testing.T) {
+	userId := uuid.New()
+	borrower := database.User{ID: uuid.New(), FirstName: "Shevek", LastName: "Anarres"}
+
+	createApprovedBorrower := func(mockQueries *MockQueries, borrowerId string) *httptest.ResponseRecorder {
+		apiConfig := BookPolicyAPIConfig{APIConfig: common.APIConfig{DB: mockQueries}}
+		request := httptest.NewRequest(http.MethodPost, "/api/v1/books/approved-borrowers/"+borrowerId, nil)
+		request = mux.SetURLVars(request, map[string]string{"borrowerId": borrowerId})
+		recorder := httptest.NewRecorder()
+
+		apiConfig.CreateApprovedBorrower(recorder, request, userId)
+
+		return recorder
+	}
+
+	getBorrower := func(ctx context.Context, id uuid.UUID) (database.User, error) {
+		if id == borrower.ID {
+			return borrower, nil
+		}
+
+		return database.User{}, sql.ErrNoRows
+	}
+
+	// 1. Success test case
+	tTesting.Run("Success", func(t *testing.T) {
+		mockQueries := &MockQueries{
+			BaseMock:        common.NewBaseMock(),
+			GetUserByIDFunc: getBorrower,
+			CreateApprovedBorrowerFunc: func(ctx context.Context, arg database.CreateApprovedBorrowerParams) (database.ApprovedBorrower, error) {
+				return database.ApprovedBorrower{UserID: arg.UserID, BorrowerID: arg.BorrowerID, CreatedAt: time.Now().UTC()}, nil
+			},
+		}
+
+		recorder := createApprovedBorrower(mockQueries, borrower.ID.String())
+
+		var approvedBorrower ApprovedBorrower
+		json.Unmarshal(recorder.Body.Bytes(), &approvedBorrower)
+
+		if recorder.Code != http.StatusCreated || approvedBorrower.UserID != userId || approvedBorrower.BorrowerName != "Shevek Anarres" {
+			t.Errorf("Unexpected response %d: %s", recorder.Code, recorder.Body.String())
+		}
+	})
+
+	// 2. Failure: already approved.
+	tTesting.Run("AlreadyApproved", func(t *testing.T) {
+		mockQueries := &MockQueries{
+			BaseMock:        common.NewBaseMock(),
+			GetUserByIDFunc: getBorrower,
+			GetApprovedBorrowerFunc: func(ctx context.Context, arg database.GetApprovedBorrowerParams) (database.ApprovedBorrower, error) {
+				return database.ApprovedBorrower{UserID: arg.UserID, BorrowerID: arg.BorrowerID}, nil
+			},
+		}
+
+		if recorder := createApprovedBorrower(mockQueries, borrower.ID.String()); recorder.Code != http.StatusConflict {
+			t.Errorf("Expected status %d, got %d", http.StatusConflict, recorder.Code)
+		}
+	})
+
+	// 3. Failure: unknown member, yourself or an invalid id.
+	tTesting.Run("InvalidBorrower", func(t *testing.T) {
+		testCases := map[string]int{
+			uuid.New().String(): http.StatusNotFound,
+			userId.String():     http.StatusBadRequest,
+			"not-a-uuid":        http.StatusBadRequest,
+		}
+
+		for borrowerId, expectedStatus := range testCases {
+			mockQueries := &MockQueries{BaseMock: common.NewBaseMock(), GetUserByIDFunc: getBorrower}
+
+			if recorder := createApprovedBorrower(mockQueries, borrowerId); recorder.Code != expectedStatus {
+				t.Errorf("%s: expected status %d, got %d", borrowerId, expectedStatus, recorder.Code)
+			}
+		}
+	})
+}
+
+func TestGetApprovedBorrowers(tTesting *testing.T) {
+	userId := uuid.New()
+	createdAt := time.Now().UTC()
+	approvedBorrowers := []database.GetApprovedBorrowersRow{
+		{ApprovedBorrower: database.ApprovedBorrower{UserID: userId, BorrowerID: uuid.New(), CreatedAt: createdAt}, BorrowerFirstName: "Takver", BorrowerLastName: "Anarres"},
+		{ApprovedBorrower: database.ApprovedBorrower{UserID: userId, BorrowerID: uuid.New(), CreatedAt: createdAt.Add(-time.Hour)}, BorrowerFirstName: "Bedap", BorrowerLastName: "Anarres"},
+	}
+
+	// 1. Success: newest first, with the next cursor after the last one on the page.
+	tTesting.Run("Paginates", func(t *testing.T) {
+		var getApprovedBorrowersParams database.GetApprovedBorrowersParams
+
+		mockQueries := &MockQueries{
+			BaseMock: common.NewBaseMock(),
+			GetApprovedBorrowersFunc: func(ctx context.Context, arg database.GetApprovedBorrowersParams) ([]database.GetApprovedBorrowersRow, error) {
+				getApprovedBorrowersParams = arg
+
+				return approvedBorrowers, nil
+			},
+		}
+
+		apiConfig := BookPolicyAPIConfig{APIConfig: common.APIConfig{DB: mockQueries}}
+		request := httptest.NewRequest(http.MethodGet, "/api/v1/books/approved-borrowers?limit=1", nil)
+		recorder := httptest.NewRecorder()
+
+		apiConfig.GetApprovedBorrowers(recorder, request, userId)
+
+		var page common.Page[ApprovedBorrower]
+		json.Unmarshal(recorder.Body.Bytes(), &page)
+
+		if recorder.Code != http.StatusOK || len(page.Data) != 1 || page.NextCursor == nil || page.Data[0].BorrowerName != "Takver Anarres" {
+			t.Fatalf("Unexpected response %d: %s", recorder.Code, recorder.Body.String())
+		}
+
+		if getApprovedBorrowersParams.UserID != userId || getApprovedBorrowersParams.PageLimit != 2 {
+			t.Errorf("Unexpected query: %+v", getApprovedBorrowersParams)
+		}
+	})
+
+	// 2. Failure: invalid cursor.
+	tTesting.Run("InvalidCursor", func(t *testing.T) {
+		apiConfig := BookPolicyAPIConfig{APIConfig: common.APIConfig{DB: &MockQueries{BaseMock: common.NewBaseMock()}}}
+		request := httptest.NewRequest(http.MethodGet, "/api/v1/books/approved-borrowers?cursor=not-a-cursor", nil)
+		recorder := httptest.NewRecorder()
+
+		apiConfig.GetApprovedBorrowers(recorder, request, userId)
+
+		if recorder.Code != http.StatusBadRequest {
+			t.Errorf("Expected status %d, got %d", http.StatusBadRequest, recorder.Code)
+		}
+	})
+}
+
+func TestDeleteApprovedBorrower(tTesting *testing.T) {
+	userId := uuid.New()
+	borrowerId := uuid.New()
+
+	deleteApprovedBorrower := func(rowsAffected int64) *httptest.ResponseRecorder {
+		mockQueries := &MockQueries{
+			BaseMock: common.NewBaseMock(),
+			DeleteApprovedBorrowerFunc: func(ctx context.Context, arg database.DeleteApprovedBorrowerParams) (int64, error) {
+				return rowsAffected, nil
+			},
+		}
+
+		apiConfig := BookPolicyAPIConfig{APIConfig: common.APIConfig{DB: mockQueries}}
+		request := httptest.NewRequest(http.MethodDelete, "/api/v1/books/approved-borrowers/"+borrowerId.String(), nil)
+		request = mux.SetURLVars(request, map[string]string{"borrowerId": borrowerId.String()})
+		recorder := httptest.NewRecorder()
+
+		apiConfig.DeleteApprovedBorrower(recorder, request, userId)
+
+		return recorder
+	}
+
+	// 1. Success test case
+	tTesting.Run("Success", func(t *testing.T) {
+		if recorder := deleteApprovedBorrower(1); recorder.Code != http.StatusOK {
+			t.Errorf("Expected status %d, got %d", http.StatusOK, recorder.Code)
+		}
+	})
+
+	// 2. Failure: not on the list.
+	tTesting.Run("NotFound", func(t *testing.T) {
+		if recorder := deleteApprovedBorrower(0); recorder.Code != http.StatusNotFound {
+			t.Errorf("Expected status %d, got %d", http.StatusNotFound, recorder.Code)
+		}
+	})
+}
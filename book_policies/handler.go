@@ -0,0 +1,140 @@
+package book_policies
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/elorenzorodz/co-library/common"
+	"github.com/elorenzorodz/co-library/internal/database"
+	"github.com/google/uuid"
+)
+
+func DatabaseApprovedBorrowerToApprovedBorrowerJSON(databaseApprovedBorrower database.ApprovedBorrower) ApprovedBorrower {
+	return ApprovedBorrower{
+		UserID:     databaseApprovedBorrower.UserID,
+		BorrowerID: databaseApprovedBorrower.BorrowerID,
+		CreatedAt:  databaseApprovedBorrower.CreatedAt,
+	}
+}
+
+func DatabaseApprovedBorrowersRowsToApprovedBorrowersJSON(databaseRows []database.GetApprovedBorrowersRow) []ApprovedBorrower {
+	approvedBorrowers := []ApprovedBorrower{}
+
+	for _, databaseRow := range databaseRows {
+		approvedBorrower := DatabaseApprovedBorrowerToApprovedBorrowerJSON(databaseRow.ApprovedBorrower)
+		approvedBorrower.BorrowerName = fmt.Sprintf("%s %s", databaseRow.BorrowerFirstName, databaseRow.BorrowerLastName)
+
+		approvedBorrowers = append(approvedBorrowers, approvedBorrower)
+	}
+
+	return approvedBorrowers
+}
+
+// Approved borrowers are paged newest first.
+func ApprovedBorrowerCursor(databaseRow database.GetApprovedBorrowersRow) common.Cursor {
+	return common.Cursor{Value: databaseRow.ApprovedBorrower.CreatedAt.Format(time.RFC3339Nano), ID: databaseRow.ApprovedBorrower.BorrowerID}
+}
+
+// The created_at of the last approved borrower on the previous page, zero on the first page.
+func ParseApprovedBorrowerCursor(pageParameters common.PageParameters) (time.Time, error) {
+	if pageParameters.Cursor == nil {
+		return time.Time{}, nil
+	}
+
+	cursorCreatedAt, parseError := time.Parse(time.RFC3339Nano, pageParameters.Cursor.Value)
+
+	if parseError != nil {
+		return time.Time{}, errors.New("invalid cursor")
+	}
+
+	return cursorCreatedAt, nil
+}
+
+// Empty values are left for the caller to default or keep.
+func ValidateBookPolicies(visibility string, borrowerPolicy string) error {
+	if visibility != "" && !slices.Contains(BookVisibilities, visibility) {
+		return fmt.Errorf("invalid visibility: %s, use one of %s", visibility, strings.Join(BookVisibilities, ", "))
+	}
+
+	if borrowerPolicy != "" && !slices.Contains(BookBorrowerPolicies, borrowerPolicy) {
+		return fmt.Errorf("invalid borrower_policy: %s, use one of %s", borrowerPolicy, strings.Join(BookBorrowerPolicies, ", "))
+	}
+
+	return nil
+}
+
+// Looks up how memberId stands with the book owner, skipping the query when the
+// book's rules don't depend on it.
+func GetMemberRelationship(ctx context.Context, querier common.Querier, book database.Book, memberId uuid.UUID) (database.GetMemberRelationshipRow, error) {
+	if memberId == book.UserID || (book.Visibility == BookVisibilityPublic && book.BorrowerPolicy == BookBorrowerPolicyAnyone) {
+		return database.GetMemberRelationshipRow{}, nil
+	}
+
+	getMemberRelationshipParams := database.GetMemberRelationshipParams{
+		OwnerID:  book.UserID,
+		MemberID: memberId,
+	}
+
+	return querier.GetMemberRelationship(ctx, getMemberRelationshipParams)
+}
+
+func CanViewBook(book database.Book, viewerId uuid.UUID, relationship database.GetMemberRelationshipRow) bool {
+	switch {
+	case viewerId == book.UserID:
+		return true
+	case book.Visibility == BookVisibilitySubscribers:
+		return relationship.IsSubscriber
+	case book.Visibility == BookVisibilityPrivate:
+		return false
+	}
+
+	return true
+}
+
+// Returns the status and reason to respond with when borrowerId can't borrow the
+// book. Books the borrower can't see are not found.
+func CheckBookLendingPolicy(book database.Book, borrowerId uuid.UUID, relationship database.GetMemberRelationshipRow) (int, error) {
+	if !CanViewBook(book, borrowerId, relationship) {
+		return http.StatusNotFound, errors.New("book not found")
+	}
+
+	if !book.Lendable {
+		return http.StatusForbidden, errors.New("this book is not available for lending")
+	}
+
+	if book.BorrowerPolicy == BookBorrowerPolicySubscribers && !relationship.IsSubscriber {
+		return http.StatusForbidden, errors.New("only subscribers of the book owner can borrow this book")
+	}
+
+	if book.BorrowerPolicy == BookBorrowerPolicyApproved && !relationship.IsApprovedBorrower {
+		return http.StatusForbidden, errors.New("only borrowers approved by the book owner can borrow this book")
+	}
+
+	return 0, nil
+}
+
+func CheckBookViewer(ctx context.Context, querier common.Querier, book database.Book, viewerId uuid.UUID) (bool, error) {
+	relationship, getRelationshipError := GetMemberRelationship(ctx, querier, book, viewerId)
+
+	if getRelationshipError != nil {
+		return false, getRelationshipError
+	}
+
+	return CanViewBook(book, viewerId, relationship), nil
+}
+
+// Used by every step of a loan, from the borrow request and waitlist to the hand-over.
+func CheckBookBorrower(ctx context.Context, querier common.Querier, book database.Book, borrowerId uuid.UUID) (int, error) {
+	relationship, getRelationshipError := GetMemberRelationship(ctx, querier, book, borrowerId)
+
+	if getRelationshipError != nil {
+		return http.StatusInternalServerError, errors.New("failed to check the book's lending policy, please try again in a few minutes")
+	}
+
+	return CheckBookLendingPolicy(book, borrowerId, relationship)
+}
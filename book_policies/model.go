@@ -0,0 +1,38 @@
+package book_policies
+
+import (
+	"time"
+
+	"github.com/elorenzorodz/co-library/common"
+	"github.com/google/uuid"
+)
+
+type BookPolicyAPIConfig struct {
+	common.APIConfig
+}
+
+// Who can find a book, owners always see their own.
+const (
+	BookVisibilityPublic      = "public"
+	BookVisibilitySubscribers = "subscribers"
+	BookVisibilityPrivate     = "private"
+)
+
+var BookVisibilities = []string{BookVisibilityPublic, BookVisibilitySubscribers, BookVisibilityPrivate}
+
+// Who can borrow a lendable book.
+const (
+	BookBorrowerPolicyAnyone      = "anyone"
+	BookBorrowerPolicySubscribers = "subscribers"
+	BookBorrowerPolicyApproved    = "approved"
+)
+
+var BookBorrowerPolicies = []string{BookBorrowerPolicyAnyone, BookBorrowerPolicySubscribers, BookBorrowerPolicyApproved}
+
+// A member the owner trusts with the books that only lend to approved borrowers.
+type ApprovedBorrower struct {
+	UserID       uuid.UUID `json:"user_id"`
+	BorrowerID   uuid.UUID `json:"borrower_id"`
+	BorrowerName string    `json:"borrower_name"`
+	CreatedAt    time.Time `json:"createdAt"`
+}
@@ -0,0 +1,145 @@
+package book_policies
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+
+	"github.com/elorenzorodz/co-library/common"
+	"github.com/elorenzorodz/co-library/internal/database"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+func (bookPolicyAPIConfig *BookPolicyAPIConfig) CreateApprovedBorrower(writer http.ResponseWriter, request *http.Request, userId uuid.UUID) {
+	vars := mux.Vars(request)
+	borrowerId, parseBorrowerIdError := uuid.Parse(vars["borrowerId"])
+
+	if parseBorrowerIdError != nil {
+		common.ErrorResponse(writer, http.StatusBadRequest, "invalid borrower id")
+
+		return
+	}
+
+	if borrowerId == userId {
+		common.ErrorResponse(writer, http.StatusBadRequest, "cannot approve yourself as a borrower")
+
+		return
+	}
+
+	borrower, getUserByIDError := bookPolicyAPIConfig.DB.GetUserByID(request.Context(), borrowerId)
+
+	if getUserByIDError != nil {
+		if getUserByIDError == sql.ErrNoRows {
+			common.ErrorResponse(writer, http.StatusNotFound, "borrower not found")
+		} else {
+			common.ErrorResponse(writer, http.StatusInternalServerError, "failed to approve borrower, please try again in a few minutes")
+		}
+
+		return
+	}
+
+	getApprovedBorrowerParams := database.GetApprovedBorrowerParams{
+		UserID:     userId,
+		BorrowerID: borrowerId,
+	}
+
+	_, getApprovedBorrowerError := bookPolicyAPIConfig.DB.GetApprovedBorrower(request.Context(), getApprovedBorrowerParams)
+
+	if getApprovedBorrowerError != nil && getApprovedBorrowerError != sql.ErrNoRows {
+		common.ErrorResponse(writer, http.StatusInternalServerError, "failed to approve borrower, please try again in a few minutes")
+
+		return
+	} else if getApprovedBorrowerError == nil {
+		common.ErrorResponse(writer, http.StatusConflict, "borrower is already approved")
+
+		return
+	}
+
+	createApprovedBorrowerParams := database.CreateApprovedBorrowerParams{
+		UserID:     userId,
+		BorrowerID: borrowerId,
+	}
+
+	newApprovedBorrower, createApprovedBorrowerError := bookPolicyAPIConfig.DB.CreateApprovedBorrower(request.Context(), createApprovedBorrowerParams)
+
+	if createApprovedBorrowerError != nil {
+		common.ErrorResponse(writer, http.StatusInternalServerError, fmt.Sprintf("error approving borrower: %s", createApprovedBorrowerError))
+
+		return
+	}
+
+	approvedBorrower := DatabaseApprovedBorrowerToApprovedBorrowerJSON(newApprovedBorrower)
+	approvedBorrower.BorrowerName = fmt.Sprintf("%s %s", borrower.FirstName, borrower.LastName)
+
+	common.JSONResponse(writer, http.StatusCreated, approvedBorrower)
+}
+
+func (bookPolicyAPIConfig *BookPolicyAPIConfig) GetApprovedBorrowers(writer http.ResponseWriter, request *http.Request, userId uuid.UUID) {
+	pageParameters, parsePageError := common.ParsePageParameters(request.URL.Query())
+
+	if parsePageError != nil {
+		common.ErrorResponse(writer, http.StatusBadRequest, parsePageError.Error())
+
+		return
+	}
+
+	cursorCreatedAt, parseCursorError := ParseApprovedBorrowerCursor(pageParameters)
+
+	if parseCursorError != nil {
+		common.ErrorResponse(writer, http.StatusBadRequest, parseCursorError.Error())
+
+		return
+	}
+
+	getApprovedBorrowersParams := database.GetApprovedBorrowersParams{
+		UserID:          userId,
+		CursorID:        pageParameters.CursorID(),
+		CursorCreatedAt: cursorCreatedAt,
+		PageLimit:       pageParameters.QueryLimit(),
+	}
+
+	approvedBorrowers, getApprovedBorrowersError := bookPolicyAPIConfig.DB.GetApprovedBorrowers(request.Context(), getApprovedBorrowersParams)
+
+	if getApprovedBorrowersError != nil {
+		common.ErrorResponse(writer, http.StatusInternalServerError, fmt.Sprintf("error getting approved borrowers: %s", getApprovedBorrowersError))
+
+		return
+	}
+
+	approvedBorrowers, nextCursor := common.SplitPage(approvedBorrowers, pageParameters, ApprovedBorrowerCursor)
+
+	common.JSONResponse(writer, http.StatusOK, common.Page[ApprovedBorrower]{Data: DatabaseApprovedBorrowersRowsToApprovedBorrowersJSON(approvedBorrowers), NextCursor: nextCursor})
+}
+
+func (bookPolicyAPIConfig *BookPolicyAPIConfig) DeleteApprovedBorrower(writer http.ResponseWriter, request *http.Request, userId uuid.UUID) {
+	vars := mux.Vars(request)
+	borrowerId, parseBorrowerIdError := uuid.Parse(vars["borrowerId"])
+
+	if parseBorrowerIdError != nil {
+		common.ErrorResponse(writer, http.StatusBadRequest, "invalid borrower id")
+
+		return
+	}
+
+	deleteApprovedBorrowerParams := database.DeleteApprovedBorrowerParams{
+		UserID:     userId,
+		BorrowerID: borrowerId,
+	}
+
+	rowsAffected, deleteApprovedBorrowerError := bookPolicyAPIConfig.DB.DeleteApprovedBorrower(request.Context(), deleteApprovedBorrowerParams)
+
+	if deleteApprovedBorrowerError != nil {
+		common.ErrorResponse(writer, http.StatusInternalServerError, fmt.Sprintf("error removing approved borrower: %s", deleteApprovedBorrowerError))
+
+		return
+	}
+
+	if rowsAffected == 0 {
+		common.ErrorResponse(writer, http.StatusNotFound, "approved borrower not found")
+
+		return
+	}
+
+	common.JSONResponse(writer, http.StatusOK, "approved borrower successfully removed")
+}
@@ -0,0 +1,659 @@
+package book_reservations
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/elorenzorodz/co-library/common"
+	"github.com/elorenzorodz/co-library/internal/database"
+	"github.com/elorenzorodz/co-library/notification_templates"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+type MockQueries struct {
+	*common.BaseMock
+
+	GetBookFunc                     func(ctx context.Context, id uuid.UUID) (database.Book, error)
+	GetUserByIDFunc                 func(ctx context.Context, id uuid.UUID) (database.User, error)
+	CountAvailableBookCopiesFunc    func(ctx context.Context, bookID uuid.UUID) (int64, error)
+	CreateBookReservationFunc       func(ctx context.Context, arg database.CreateBookReservationParams) (database.BookReservation, error)
+	GetActiveBookReservationFunc    func(ctx context.Context, arg database.GetActiveBookReservationParams) (database.BookReservation, error)
+	CountBookReservationsAheadFunc  func(ctx context.Context, arg database.CountBookReservationsAheadParams) (int64, error)
+	CancelBookReservationFunc       func(ctx context.Context, arg database.CancelBookReservationParams) (database.BookReservation, error)
+	GetOfferedBookReservationsFunc  func(ctx context.Context, bookID uuid.UUID) ([]database.BookReservation, error)
+	OfferNextBookReservationFunc    func(ctx context.Context, arg database.OfferNextBookReservationParams) (database.BookReservation, error)
+	ExpireBookReservationOffersFunc func(ctx context.Context, bookID uuid.UUID) error
+
+	GetBooksWithLapsedBookReservationOffersFunc func(ctx context.Context, expiredBefore time.Time) ([]uuid.UUID, error)
+	CreateOutboxNotificationFunc                func(ctx context.Context, arg database.CreateOutboxNotificationParams) (database.NotificationOutbox, error)
+}
+
+func (mockQueries *MockQueries) GetBook(ctx context.Context, id uuid.UUID) (database.Book, error) {
+	if mockQueries.GetBookFunc != nil {
+		return mockQueries.GetBookFunc(ctx, id)
+	}
+
+	return mockQueries.BaseMock.GetBook(ctx, id)
+}
+
+func (mockQueries *MockQueries) GetUserByID(ctx context.Context, id uuid.UUID) (database.User, error) {
+	if mockQueries.GetUserByIDFunc != nil {
+		return mockQueries.GetUserByIDFunc(ctx, id)
+	}
+
+	return mockQueries.BaseMock.GetUserByID(ctx, id)
+}
+
+func (mockQueries *MockQueries) CountAvailableBookCopies(ctx context.Context, bookID uuid.UUID) (int64, error) {
+	if mockQueries.CountAvailableBookCopiesFunc != nil {
+		return mockQueries.CountAvailableBookCopiesFunc(ctx, bookID)
+	}
+
+	return mockQueries.BaseMock.CountAvailableBookCopies(ctx, bookID)
+}
+
+func (mockQueries *MockQueries) CreateBookReservation(ctx context.Context, arg database.CreateBookReservationParams) (database.BookReservation, error) {
+	if mockQueries.CreateBookReservationFunc != nil {
+		return mockQueries.CreateBookReservationFunc(ctx, arg)
+	}
+
+	return mockQueries.BaseMock.CreateBookReservation(ctx, arg)
+}
+
+func (mockQueries *MockQueries) GetActiveBookReservation(ctx context.Context, arg database.GetActiveBookReservationParams) (database.BookReservation, error) {
+	if mockQueries.GetActiveBookReservationFunc != nil {
+		return mockQueries.GetActiveBookReservationFunc(ctx, arg)
+	}
+
+	return mockQueries.BaseMock.GetActiveBookReservation(ctx, arg)
+}
+
+func (mockQueries *MockQueries) CountBookReservationsAhead(ctx context.Context, arg database.CountBookReservationsAheadParams) (int64, error) {
+	if mockQueries.CountBookReservationsAheadFunc != nil {
+		return mockQueries.CountBookReservationsAheadFunc(ctx, arg)
+	}
+
+	return mockQueries.BaseMock.CountBookReservationsAhead(ctx, arg)
+}
+
+func (mockQueries *MockQueries) CancelBookReservation(ctx context.Context, arg database.CancelBookReservationParams) (database.BookReservation, error) {
+	if mockQueries.CancelBookReservationFunc != nil {
+		return mockQueries.CancelBookReservationFunc(ctx, arg)
+	}
+
+	return mockQueries.BaseMock.CancelBookReservation(ctx, arg)
+}
+
+func (mockQueries *MockQueries) GetOfferedBookReservations(ctx context.Context, bookID uuid.UUID) ([]database.BookReservation, error) {
+	if mockQueries.GetOfferedBookReservationsFunc != nil {
+		return mockQueries.GetOfferedBookReservationsFunc(ctx, bookID)
+	}
+
+	return mockQueries.BaseMock.GetOfferedBookReservations(ctx, bookID)
+}
+
+func (mockQueries *MockQueries) OfferNextBookReservation(ctx context.Context, arg database.OfferNextBookReservationParams) (database.BookReservation, error) {
+	if mockQueries.OfferNextBookReservationFunc != nil {
+		return mockQueries.OfferNextBookReservationFunc(ctx, arg)
+	}
+
+	return mockQueries.BaseMock.OfferNextBookReservation(ctx, arg)
+}
+
+func (mockQueries *MockQueries) ExpireBookReservationOffers(ctx context.Context, bookID uuid.UUID) error {
+	if mockQueries.ExpireBookReservationOffersFunc != nil {
+		return mockQueries.ExpireBookReservationOffersFunc(ctx, bookID)
+	}
+
+	return mockQueries.BaseMock.ExpireBookReservationOffers(ctx, bookID)
+}
+
+func (mockQueries *MockQueries) GetBooksWithLapsedBookReservationOffers(ctx context.Context, expiredBefore time.Time) ([]uuid.UUID, error) {
+	if mockQueries.GetBooksWithLapsedBookReservationOffersFunc != nil {
+		return mockQueries.GetBooksWithLapsedBookReservationOffersFunc(ctx, expiredBefore)
+	}
+
+	return mockQueries.BaseMock.GetBooksWithLapsedBookReservationOffers(ctx, expiredBefore)
+}
+
+func (mockQueries *MockQueries) CreateOutboxNotification(ctx context.Context, arg database.CreateOutboxNotificationParams) (database.NotificationOutbox, error) {
+	if mockQueries.CreateOutboxNotificationFunc != nil {
+		return mockQueries.CreateOutboxNotificationFunc(ctx, arg)
+	}
+
+	return mockQueries.BaseMock.CreateOutboxNotification(ctx, arg)
+}
+
+func newTestUserID() uuid.UUID {
+	return uuid.New()
+}
+
+func newTestBook(userId uuid.UUID) database.Book {
+	return database.Book{
+		ID:             uuid.New(),
+		Title:          "The Go Waitlist",
+		Author:         "Test Author",
+		UserID:         userId,
+		CreatedAt:      time.Now(),
+		UpdatedAt:      time.Now(),
+		LoanPeriodDays: 14,
+		Visibility:     "public",
+		Lendable:       true,
+		BorrowerPolicy: "anyone",
+	}
+}
+
+func newTestBookReservation(bookID, userID uuid.UUID, status string) database.BookReservation {
+	return database.BookReservation{
+		ID:        uuid.New(),
+		Status:    status,
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+		BookID:    bookID,
+		UserID:    userID,
+	}
+}
+
+func findTestBook(ctx context.Context, id uuid.UUID) (database.Book, error) {
+	return newTestBook(newTestUserID()), nil
+}
+
+func findTestUser(ctx context.Context, id uuid.UUID) (database.User, error) {
+	return database.User{ID: id, FirstName: "Test", LastName: "Reader", Email: fmt.Sprintf("%s@example.com", id), Locale: "en"}, nil
+}
+
+func queueOutboxNotification(ctx context.Context, arg database.CreateOutboxNotificationParams) (database.NotificationOutbox, error) {
+	return database.NotificationOutbox{ID: arg.ID}, nil
+}
+
+func allCopiesOnLoan(ctx context.Context, bookID uuid.UUID) (int64, error) {
+	return 0, nil
+}
+
+func TestCreateBookReservation(tTesting *testing.T) {
+	ownerID := newTestUserID()
+	userID := newTestUserID()
+	testBook := newTestBook(ownerID)
+
+	base := common.NewBaseMock()
+
+	// 1. Success: user joins the waitlist behind two others.
+	tTesting.Run("Success", func(t *testing.T) {
+		mockQueries := &MockQueries{
+			BaseMock: base,
+			GetBookFunc: func(ctx context.Context, id uuid.UUID) (database.Book, error) {
+				return testBook, nil
+			},
+			CountAvailableBookCopiesFunc: allCopiesOnLoan,
+			CreateBookReservationFunc: func(ctx context.Context, arg database.CreateBookReservationParams) (database.BookReservation, error) {
+				if arg.BookID != testBook.ID || arg.UserID != userID {
+					t.Fatalf("CreateBookReservation called with wrong IDs")
+				}
+				return newTestBookReservation(arg.BookID, arg.UserID, BookReservationStatusWaiting), nil
+			},
+			CountBookReservationsAheadFunc: func(ctx context.Context, arg database.CountBookReservationsAheadParams) (int64, error) {
+				return 2, nil
+			},
+		}
+
+		apiConfig := BookReservationAPIConfig{APIConfig: common.APIConfig{DB: mockQueries}}
+		request := httptest.NewRequest(http.MethodPost, fmt.Sprintf("/api/v1/books/reservations/%s", testBook.ID), nil)
+
+		vars := map[string]string{"bookId": testBook.ID.String()}
+		request = mux.SetURLVars(request, vars)
+		recorder := httptest.NewRecorder()
+
+		apiConfig.CreateBookReservation(recorder, request, userID)
+
+		if recorder.Code != http.StatusCreated {
+			t.Fatalf("Expected status %d, got %d. Body: %s", http.StatusCreated, recorder.Code, recorder.Body.String())
+		}
+
+		var bookReservation BookReservation
+		json.NewDecoder(recorder.Body).Decode(&bookReservation)
+
+		if bookReservation.QueuePosition != 3 {
+			t.Errorf("Expected queue position 3, got %d", bookReservation.QueuePosition)
+		}
+	})
+
+	// 2. Failure: user reserves their own book
+	tTesting.Run("OwnBook", func(t *testing.T) {
+		mockQueries := &MockQueries{
+			BaseMock: base,
+			GetBookFunc: func(ctx context.Context, id uuid.UUID) (database.Book, error) {
+				return testBook, nil
+			},
+		}
+
+		apiConfig := BookReservationAPIConfig{APIConfig: common.APIConfig{DB: mockQueries}}
+		request := httptest.NewRequest(http.MethodPost, fmt.Sprintf("/api/v1/books/reservations/%s", testBook.ID), nil)
+
+		vars := map[string]string{"bookId": testBook.ID.String()}
+		request = mux.SetURLVars(request, vars)
+		recorder := httptest.NewRecorder()
+
+		apiConfig.CreateBookReservation(recorder, request, ownerID)
+
+		if recorder.Code != http.StatusForbidden {
+			t.Errorf("Expected status %d, got %d. Body: %s", http.StatusForbidden, recorder.Code, recorder.Body.String())
+		}
+	})
+
+	// 2a. Failure: the book is not lent out, so there is no waitlist to join
+	tTesting.Run("NotLendable", func(t *testing.T) {
+		notLendable := newTestBook(ownerID)
+		notLendable.Lendable = false
+
+		mockQueries := &MockQueries{
+			BaseMock: base,
+			GetBookFunc: func(ctx context.Context, id uuid.UUID) (database.Book, error) {
+				return notLendable, nil
+			},
+			CountAvailableBookCopiesFunc: allCopiesOnLoan,
+		}
+
+		apiConfig := BookReservationAPIConfig{APIConfig: common.APIConfig{DB: mockQueries}}
+		request := httptest.NewRequest(http.MethodPost, fmt.Sprintf("/api/v1/books/reservations/%s", notLendable.ID), nil)
+		request = mux.SetURLVars(request, map[string]string{"bookId": notLendable.ID.String()})
+		recorder := httptest.NewRecorder()
+
+		apiConfig.CreateBookReservation(recorder, request, userID)
+
+		if recorder.Code != http.StatusForbidden {
+			t.Errorf("Expected status %d, got %d. Body: %s", http.StatusForbidden, recorder.Code, recorder.Body.String())
+		}
+	})
+
+	// 3. Failure: book is available, nothing to wait for
+	tTesting.Run("BookAvailable", func(t *testing.T) {
+		mockQueries := &MockQueries{
+			BaseMock: base,
+			GetBookFunc: func(ctx context.Context, id uuid.UUID) (database.Book, error) {
+				return testBook, nil
+			},
+		}
+
+		apiConfig := BookReservationAPIConfig{APIConfig: common.APIConfig{DB: mockQueries}}
+		request := httptest.NewRequest(http.MethodPost, fmt.Sprintf("/api/v1/books/reservations/%s", testBook.ID), nil)
+
+		vars := map[string]string{"bookId": testBook.ID.String()}
+		request = mux.SetURLVars(request, vars)
+		recorder := httptest.NewRecorder()
+
+		apiConfig.CreateBookReservation(recorder, request, userID)
+
+		if recorder.Code != http.StatusConflict {
+			t.Errorf("Expected status %d, got %d. Body: %s", http.StatusConflict, recorder.Code, recorder.Body.String())
+		}
+	})
+
+	// 3a. Success: the only free copy is held for someone else
+	tTesting.Run("FreeCopyHeld", func(t *testing.T) {
+		mockQueries := &MockQueries{
+			BaseMock: base,
+			GetBookFunc: func(ctx context.Context, id uuid.UUID) (database.Book, error) {
+				return testBook, nil
+			},
+			GetOfferedBookReservationsFunc: func(ctx context.Context, bookID uuid.UUID) ([]database.BookReservation, error) {
+				return []database.BookReservation{newTestBookReservation(bookID, newTestUserID(), BookReservationStatusOffered)}, nil
+			},
+			CreateBookReservationFunc: func(ctx context.Context, arg database.CreateBookReservationParams) (database.BookReservation, error) {
+				return newTestBookReservation(arg.BookID, arg.UserID, BookReservationStatusWaiting), nil
+			},
+		}
+
+		apiConfig := BookReservationAPIConfig{APIConfig: common.APIConfig{DB: mockQueries}}
+		request := httptest.NewRequest(http.MethodPost, fmt.Sprintf("/api/v1/books/reservations/%s", testBook.ID), nil)
+
+		vars := map[string]string{"bookId": testBook.ID.String()}
+		request = mux.SetURLVars(request, vars)
+		recorder := httptest.NewRecorder()
+
+		apiConfig.CreateBookReservation(recorder, request, userID)
+
+		if recorder.Code != http.StatusCreated {
+			t.Errorf("Expected status %d, got %d. Body: %s", http.StatusCreated, recorder.Code, recorder.Body.String())
+		}
+	})
+
+	// 4. Failure: user is already in the waitlist
+	tTesting.Run("AlreadyReserved", func(t *testing.T) {
+		mockQueries := &MockQueries{
+			BaseMock: base,
+			GetBookFunc: func(ctx context.Context, id uuid.UUID) (database.Book, error) {
+				return testBook, nil
+			},
+			CountAvailableBookCopiesFunc: allCopiesOnLoan,
+			GetActiveBookReservationFunc: func(ctx context.Context, arg database.GetActiveBookReservationParams) (database.BookReservation, error) {
+				return newTestBookReservation(arg.BookID, arg.UserID, BookReservationStatusWaiting), nil
+			},
+		}
+
+		apiConfig := BookReservationAPIConfig{APIConfig: common.APIConfig{DB: mockQueries}}
+		request := httptest.NewRequest(http.MethodPost, fmt.Sprintf("/api/v1/books/reservations/%s", testBook.ID), nil)
+
+		vars := map[string]string{"bookId": testBook.ID.String()}
+		request = mux.SetURLVars(request, vars)
+		recorder := httptest.NewRecorder()
+
+		apiConfig.CreateBookReservation(recorder, request, userID)
+
+		if recorder.Code != http.StatusConflict {
+			t.Errorf("Expected status %d, got %d. Body: %s", http.StatusConflict, recorder.Code, recorder.Body.String())
+		}
+	})
+}
+
+func TestCancelBookReservation(tTesting *testing.T) {
+	userID := newTestUserID()
+	bookID := uuid.New()
+
+	base := common.NewBaseMock()
+
+	// 1. Success: cancelling an offer passes the book to the next in line.
+	tTesting.Run("CancelOfferedReservation", func(t *testing.T) {
+		cancelledBookReservation := newTestBookReservation(bookID, userID, BookReservationStatusCancelled)
+		cancelledBookReservation.OfferedAt = sql.NullTime{Time: time.Now(), Valid: true}
+
+		offeredNext := false
+
+		mockQueries := &MockQueries{
+			BaseMock: base,
+			CancelBookReservationFunc: func(ctx context.Context, arg database.CancelBookReservationParams) (database.BookReservation, error) {
+				return cancelledBookReservation, nil
+			},
+			OfferNextBookReservationFunc: func(ctx context.Context, arg database.OfferNextBookReservationParams) (database.BookReservation, error) {
+				offeredNext = true
+				return database.BookReservation{}, sql.ErrNoRows
+			},
+		}
+
+		apiConfig := BookReservationAPIConfig{APIConfig: common.APIConfig{DB: mockQueries}}
+		request := httptest.NewRequest(http.MethodDelete, fmt.Sprintf("/api/v1/books/reservations/%s", cancelledBookReservation.ID), nil)
+
+		vars := map[string]string{"bookReservationId": cancelledBookReservation.ID.String()}
+		request = mux.SetURLVars(request, vars)
+		recorder := httptest.NewRecorder()
+
+		apiConfig.CancelBookReservation(recorder, request, userID)
+
+		if recorder.Code != http.StatusOK {
+			t.Errorf("Expected status %d, got %d. Body: %s", http.StatusOK, recorder.Code, recorder.Body.String())
+		}
+
+		if !offeredNext {
+			t.Error("Expected the book to be offered to the next reservation")
+		}
+	})
+
+	// 2. Failure: reservation not found (sql.ErrNoRows)
+	tTesting.Run("NotFound", func(t *testing.T) {
+		mockQueries := &MockQueries{BaseMock: base}
+
+		reservationID := uuid.New()
+		apiConfig := BookReservationAPIConfig{APIConfig: common.APIConfig{DB: mockQueries}}
+		request := httptest.NewRequest(http.MethodDelete, fmt.Sprintf("/api/v1/books/reservations/%s", reservationID), nil)
+
+		vars := map[string]string{"bookReservationId": reservationID.String()}
+		request = mux.SetURLVars(request, vars)
+		recorder := httptest.NewRecorder()
+
+		apiConfig.CancelBookReservation(recorder, request, userID)
+
+		if recorder.Code != http.StatusNotFound {
+			t.Errorf("Expected status %d, got %d. Body: %s", http.StatusNotFound, recorder.Code, recorder.Body.String())
+		}
+	})
+}
+
+func TestHoldBookForNextInLine(tTesting *testing.T) {
+	bookID := uuid.New()
+
+	base := common.NewBaseMock()
+
+	// 1. Existing offer is kept.
+	tTesting.Run("ExistingOffer", func(t *testing.T) {
+		offeredBookReservation := newTestBookReservation(bookID, newTestUserID(), BookReservationStatusOffered)
+
+		mockQueries := &MockQueries{
+			BaseMock: base,
+			GetOfferedBookReservationsFunc: func(ctx context.Context, bookID uuid.UUID) ([]database.BookReservation, error) {
+				return []database.BookReservation{offeredBookReservation}, nil
+			},
+			OfferNextBookReservationFunc: func(ctx context.Context, arg database.OfferNextBookReservationParams) (database.BookReservation, error) {
+				t.Fatal("OfferNextBookReservation should not be called while an offer is active")
+				return database.BookReservation{}, nil
+			},
+		}
+
+		apiConfig := common.APIConfig{DB: mockQueries}
+		heldBookReservations, holdBookError := HoldBookForNextInLine(context.Background(), &apiConfig, bookID)
+
+		if holdBookError != nil || len(heldBookReservations) != 1 || heldBookReservations[0].ID != offeredBookReservation.ID {
+			t.Errorf("Expected existing offer %s, got %v (%v)", offeredBookReservation.ID, heldBookReservations, holdBookError)
+		}
+	})
+
+	// 2. Next in line gets an offer with an expiry window and an alert in the outbox.
+	tTesting.Run("OfferNext", func(t *testing.T) {
+		borrowerID := newTestUserID()
+		var queuedAlerts []database.CreateOutboxNotificationParams
+
+		mockQueries := &MockQueries{
+			BaseMock:        base,
+			GetBookFunc:     findTestBook,
+			GetUserByIDFunc: findTestUser,
+			OfferNextBookReservationFunc: func(ctx context.Context, arg database.OfferNextBookReservationParams) (database.BookReservation, error) {
+				expectedExpiry := time.Now().UTC().Add(BookReservationOfferWindow)
+
+				if arg.OfferExpiresAt.Sub(expectedExpiry).Abs() > time.Minute {
+					t.Errorf("Expected offer to expire around %s, got %s", expectedExpiry, arg.OfferExpiresAt)
+				}
+				return newTestBookReservation(arg.BookID, borrowerID, BookReservationStatusOffered), nil
+			},
+			CreateOutboxNotificationFunc: func(ctx context.Context, arg database.CreateOutboxNotificationParams) (database.NotificationOutbox, error) {
+				queuedAlerts = append(queuedAlerts, arg)
+				return database.NotificationOutbox{ID: arg.ID}, nil
+			},
+		}
+
+		apiConfig := common.APIConfig{DB: mockQueries, Templates: notification_templates.MustNewRenderer()}
+		_, holdBookError := HoldBookForNextInLine(context.Background(), &apiConfig, bookID)
+
+		if holdBookError != nil {
+			t.Errorf("Expected no error, got %v", holdBookError)
+		}
+
+		if len(queuedAlerts) != 1 || !strings.Contains(queuedAlerts[0].Recipient, borrowerID.String()) {
+			t.Errorf("Expected one offer alert queued for borrower %s, got %v", borrowerID, queuedAlerts)
+		}
+	})
+
+	// 3. Empty waitlist.
+	tTesting.Run("EmptyWaitlist", func(t *testing.T) {
+		mockQueries := &MockQueries{BaseMock: base}
+
+		apiConfig := common.APIConfig{DB: mockQueries}
+		heldBookReservations, holdBookError := HoldBookForNextInLine(context.Background(), &apiConfig, bookID)
+
+		if holdBookError != nil || len(heldBookReservations) != 0 {
+			t.Errorf("Expected no held reservations, got %v (%v)", heldBookReservations, holdBookError)
+		}
+	})
+
+	// 3a. Every free copy nobody holds goes to the next person in line.
+	tTesting.Run("OfferEachFreeCopy", func(t *testing.T) {
+		offeredBookReservation := newTestBookReservation(bookID, newTestUserID(), BookReservationStatusOffered)
+		waitingBookReservations := 2
+
+		mockQueries := &MockQueries{
+			BaseMock: base,
+			CountAvailableBookCopiesFunc: func(ctx context.Context, bookID uuid.UUID) (int64, error) {
+				return 3, nil
+			},
+			GetOfferedBookReservationsFunc: func(ctx context.Context, bookID uuid.UUID) ([]database.BookReservation, error) {
+				return []database.BookReservation{offeredBookReservation}, nil
+			},
+			GetBookFunc:                  findTestBook,
+			GetUserByIDFunc:              findTestUser,
+			CreateOutboxNotificationFunc: queueOutboxNotification,
+			OfferNextBookReservationFunc: func(ctx context.Context, arg database.OfferNextBookReservationParams) (database.BookReservation, error) {
+				if waitingBookReservations == 0 {
+					t.Fatal("OfferNextBookReservation should not be called once every free copy is held")
+				}
+
+				waitingBookReservations--
+				return newTestBookReservation(arg.BookID, newTestUserID(), BookReservationStatusOffered), nil
+			},
+		}
+
+		apiConfig := common.APIConfig{DB: mockQueries, Templates: notification_templates.MustNewRenderer()}
+		heldBookReservations, holdBookError := HoldBookForNextInLine(context.Background(), &apiConfig, bookID)
+
+		if holdBookError != nil || len(heldBookReservations) != 3 {
+			t.Errorf("Expected 3 held reservations, got %d (%v)", len(heldBookReservations), holdBookError)
+		}
+	})
+
+	// 4. Failure: DB error expiring stale offers.
+	tTesting.Run("ExpireOffersDBError", func(t *testing.T) {
+		mockQueries := &MockQueries{
+			BaseMock: base,
+			ExpireBookReservationOffersFunc: func(ctx context.Context, bookID uuid.UUID) error {
+				return errors.New("simulated DB error on expire offers")
+			},
+		}
+
+		apiConfig := common.APIConfig{DB: mockQueries}
+		_, holdBookError := HoldBookForNextInLine(context.Background(), &apiConfig, bookID)
+
+		if holdBookError == nil {
+			t.Errorf("Expected DB error, got %v", holdBookError)
+		}
+	})
+
+	// 4a. Failure: the offer alert can't be queued, so the offer isn't kept either.
+	tTesting.Run("EnqueueOfferAlertDBError", func(t *testing.T) {
+		mockQueries := &MockQueries{
+			BaseMock:        base,
+			GetBookFunc:     findTestBook,
+			GetUserByIDFunc: findTestUser,
+			OfferNextBookReservationFunc: func(ctx context.Context, arg database.OfferNextBookReservationParams) (database.BookReservation, error) {
+				return newTestBookReservation(arg.BookID, newTestUserID(), BookReservationStatusOffered), nil
+			},
+			CreateOutboxNotificationFunc: func(ctx context.Context, arg database.CreateOutboxNotificationParams) (database.NotificationOutbox, error) {
+				return database.NotificationOutbox{}, errors.New("simulated DB error on create outbox notification")
+			},
+		}
+
+		apiConfig := common.APIConfig{DB: mockQueries, Templates: notification_templates.MustNewRenderer()}
+		heldBookReservations, holdBookError := HoldBookForNextInLine(context.Background(), &apiConfig, bookID)
+
+		if holdBookError == nil || len(heldBookReservations) != 0 {
+			t.Errorf("Expected DB error and no held reservations, got %v (%v)", heldBookReservations, holdBookError)
+		}
+	})
+}
+
+func TestOfferLapsedBookReservations(tTesting *testing.T) {
+	now := time.Now().UTC()
+
+	base := common.NewBaseMock()
+
+	// 1. Success: every book with a lapsed offer goes to the next person in line.
+	tTesting.Run("Success", func(t *testing.T) {
+		lapsedBookIDs := []uuid.UUID{uuid.New(), uuid.New()}
+		offeredBookIDs := map[uuid.UUID]bool{}
+
+		mockQueries := &MockQueries{
+			BaseMock: base,
+			GetBooksWithLapsedBookReservationOffersFunc: func(ctx context.Context, expiredBefore time.Time) ([]uuid.UUID, error) {
+				if !expiredBefore.Equal(now) {
+					t.Errorf("Expected offers lapsed before %s, got %s", now, expiredBefore)
+				}
+				return lapsedBookIDs, nil
+			},
+			GetBookFunc:                  findTestBook,
+			GetUserByIDFunc:              findTestUser,
+			CreateOutboxNotificationFunc: queueOutboxNotification,
+			OfferNextBookReservationFunc: func(ctx context.Context, arg database.OfferNextBookReservationParams) (database.BookReservation, error) {
+				offeredBookIDs[arg.BookID] = true
+				return newTestBookReservation(arg.BookID, newTestUserID(), BookReservationStatusOffered), nil
+			},
+		}
+
+		apiConfig := common.APIConfig{DB: mockQueries, Templates: notification_templates.MustNewRenderer()}
+		offerError := OfferLapsedBookReservations(context.Background(), &apiConfig, now)
+
+		if offerError != nil {
+			t.Errorf("Expected no error, got %v", offerError)
+		}
+
+		for _, lapsedBookID := range lapsedBookIDs {
+			if !offeredBookIDs[lapsedBookID] {
+				t.Errorf("Expected book %s to be offered to the next person in line", lapsedBookID)
+			}
+		}
+	})
+
+	// 1a. Success: a book that fails doesn't hold up the others.
+	tTesting.Run("BookFails", func(t *testing.T) {
+		failingBookID := uuid.New()
+		otherBookID := uuid.New()
+		otherBookOffered := false
+
+		mockQueries := &MockQueries{
+			BaseMock: base,
+			GetBooksWithLapsedBookReservationOffersFunc: func(ctx context.Context, expiredBefore time.Time) ([]uuid.UUID, error) {
+				return []uuid.UUID{failingBookID, otherBookID}, nil
+			},
+			GetBookFunc:                  findTestBook,
+			GetUserByIDFunc:              findTestUser,
+			CreateOutboxNotificationFunc: queueOutboxNotification,
+			ExpireBookReservationOffersFunc: func(ctx context.Context, bookID uuid.UUID) error {
+				if bookID == failingBookID {
+					return errors.New("simulated DB error on expire offers")
+				}
+				return nil
+			},
+			OfferNextBookReservationFunc: func(ctx context.Context, arg database.OfferNextBookReservationParams) (database.BookReservation, error) {
+				otherBookOffered = arg.BookID == otherBookID
+				return newTestBookReservation(arg.BookID, newTestUserID(), BookReservationStatusOffered), nil
+			},
+		}
+
+		apiConfig := common.APIConfig{DB: mockQueries, Templates: notification_templates.MustNewRenderer()}
+		offerError := OfferLapsedBookReservations(context.Background(), &apiConfig, now)
+
+		if offerError != nil || !otherBookOffered {
+			t.Errorf("Expected the other book to be offered without an error, got offered %v (%v)", otherBookOffered, offerError)
+		}
+	})
+
+	// 2. Failure: DB error getting the books with lapsed offers.
+	tTesting.Run("GetBooksDBError", func(t *testing.T) {
+		mockQueries := &MockQueries{
+			BaseMock: base,
+			GetBooksWithLapsedBookReservationOffersFunc: func(ctx context.Context, expiredBefore time.Time) ([]uuid.UUID, error) {
+				return nil, errors.New("simulated DB error on lapsed offers")
+			},
+		}
+
+		apiConfig := common.APIConfig{DB: mockQueries}
+		offerError := OfferLapsedBookReservations(context.Background(), &apiConfig, now)
+
+		if offerError == nil {
+			t.Errorf("Expected DB error, got %v", offerError)
+		}
+	})
+}
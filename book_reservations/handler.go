@@ -0,0 +1,166 @@
+package book_reservations
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/elorenzorodz/co-library/common"
+	"github.com/elorenzorodz/co-library/internal/database"
+	"github.com/elorenzorodz/co-library/notification_outbox"
+	"github.com/elorenzorodz/co-library/users"
+	"github.com/google/uuid"
+)
+
+func DatabaseBookReservationToBookReservationJSON(databaseBookReservation database.BookReservation, queuePosition int64) BookReservation {
+	return BookReservation{
+		ID:             databaseBookReservation.ID,
+		Status:         databaseBookReservation.Status,
+		QueuePosition:  queuePosition,
+		OfferedAt:      databaseBookReservation.OfferedAt,
+		OfferExpiresAt: databaseBookReservation.OfferExpiresAt,
+		CreatedAt:      databaseBookReservation.CreatedAt,
+		UpdatedAt:      databaseBookReservation.UpdatedAt,
+		BookID:         databaseBookReservation.BookID,
+		UserID:         databaseBookReservation.UserID,
+	}
+}
+
+func DatabaseUserBookReservationsToJSON(databaseRows []database.GetUserBookReservationsRow) []UserBookReservation {
+	userBookReservations := []UserBookReservation{}
+
+	for _, databaseRow := range databaseRows {
+		// Offered reservations are out of the waitlist, their turn has come.
+		queuePosition := databaseRow.QueuePosition
+
+		if databaseRow.BookReservation.Status == BookReservationStatusOffered {
+			queuePosition = 0
+		}
+
+		userBookReservations = append(userBookReservations, UserBookReservation{
+			BookReservation: DatabaseBookReservationToBookReservationJSON(databaseRow.BookReservation, queuePosition),
+			BookTitle:       databaseRow.BookTitle,
+		})
+	}
+
+	return userBookReservations
+}
+
+// Returns the reservations the free copies of the book are currently held for. Stale
+// offers are expired first and every free copy nobody holds is offered to the next
+// person in the waitlist.
+func HoldBookForNextInLine(ctx context.Context, apiConfig *common.APIConfig, bookId uuid.UUID) ([]database.BookReservation, error) {
+	expireBookReservationOffersError := apiConfig.DB.ExpireBookReservationOffers(ctx, bookId)
+
+	if expireBookReservationOffersError != nil {
+		return nil, expireBookReservationOffersError
+	}
+
+	offeredBookReservations, getOfferedBookReservationsError := apiConfig.DB.GetOfferedBookReservations(ctx, bookId)
+
+	if getOfferedBookReservationsError != nil {
+		return nil, getOfferedBookReservationsError
+	}
+
+	availableBookCopies, countAvailableBookCopiesError := apiConfig.DB.CountAvailableBookCopies(ctx, bookId)
+
+	if countAvailableBookCopiesError != nil {
+		return nil, countAvailableBookCopiesError
+	}
+
+	for int64(len(offeredBookReservations)) < availableBookCopies {
+		offerNextBookReservationParams := database.OfferNextBookReservationParams{
+			OfferExpiresAt: time.Now().UTC().Add(BookReservationOfferWindow),
+			BookID:         bookId,
+		}
+
+		var nextBookReservation database.BookReservation
+
+		// The offer alert is queued with the offer, so neither is kept without the other.
+		offerNextBookReservationError := apiConfig.InTransaction(ctx, func(querier common.Querier) error {
+			var offerError error
+
+			nextBookReservation, offerError = querier.OfferNextBookReservation(ctx, offerNextBookReservationParams)
+
+			if offerError != nil {
+				return offerError
+			}
+
+			return EnqueueBookReservationOfferAlert(ctx, querier, apiConfig.Templates, nextBookReservation)
+		})
+
+		if offerNextBookReservationError == sql.ErrNoRows {
+			break
+		} else if offerNextBookReservationError != nil {
+			return nil, offerNextBookReservationError
+		}
+
+		offeredBookReservations = append(offeredBookReservations, nextBookReservation)
+	}
+
+	return offeredBookReservations, nil
+}
+
+// Expires the offers that lapsed by now and passes each book on to the next person in
+// line. Offers also lapse lazily whenever the book is touched, this keeps the waitlist
+// moving for books nobody touches. A book that fails is logged and retried next run.
+func OfferLapsedBookReservations(ctx context.Context, apiConfig *common.APIConfig, now time.Time) error {
+	bookIds, getBooksError := apiConfig.DB.GetBooksWithLapsedBookReservationOffers(ctx, now)
+
+	if getBooksError != nil {
+		return fmt.Errorf("error getting books with lapsed reservation offers: %w", getBooksError)
+	}
+
+	for _, bookId := range bookIds {
+		_, holdBookError := HoldBookForNextInLine(ctx, apiConfig, bookId)
+
+		if holdBookError != nil {
+			log.Printf("failed to offer book %s to the next person in line: %s", bookId, holdBookError)
+		}
+	}
+
+	return nil
+}
+
+// Returns the reservation held for the user, if any.
+func FindBookReservationForUser(bookReservations []database.BookReservation, userId uuid.UUID) (database.BookReservation, bool) {
+	for _, bookReservation := range bookReservations {
+		if bookReservation.UserID == userId {
+			return bookReservation, true
+		}
+	}
+
+	return database.BookReservation{}, false
+}
+
+// Queues the alert telling the borrower the book is held for them until the offer
+// expires. Pass the transaction's Querier that made the offer.
+func EnqueueBookReservationOfferAlert(ctx context.Context, querier common.Querier, templates common.TemplateRenderer, bookReservation database.BookReservation) error {
+	book, getBookError := querier.GetBook(ctx, bookReservation.BookID)
+
+	if getBookError != nil {
+		return fmt.Errorf("error getting book for reservation offer alert: %w", getBookError)
+	}
+
+	owner, getOwnerError := querier.GetUserByID(ctx, book.UserID)
+
+	if getOwnerError != nil {
+		return fmt.Errorf("error getting book owner for reservation offer alert: %w", getOwnerError)
+	}
+
+	borrower, getBorrowerError := querier.GetUserByID(ctx, bookReservation.UserID)
+
+	if getBorrowerError != nil {
+		return fmt.Errorf("error getting borrower for reservation offer alert: %w", getBorrowerError)
+	}
+
+	bookReservationOfferAlert, renderError := users.BookReservationOfferAlertNotification(templates, owner, borrower, book.Title, bookReservation.OfferExpiresAt.Time)
+
+	if renderError != nil {
+		return renderError
+	}
+
+	return notification_outbox.EnqueueNotification(ctx, querier, bookReservationOfferAlert)
+}
@@ -0,0 +1,45 @@
+package book_reservations
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/elorenzorodz/co-library/common"
+	"github.com/google/uuid"
+)
+
+type BookReservationAPIConfig struct {
+	common.APIConfig
+}
+
+const (
+	BookReservationStatusWaiting   = "waiting"
+	BookReservationStatusOffered   = "offered"
+	BookReservationStatusFulfilled = "fulfilled"
+	BookReservationStatusExpired   = "expired"
+	BookReservationStatusCancelled = "cancelled"
+)
+
+const (
+	// How long a book is held for the next person in line before it goes to the one after.
+	BookReservationOfferWindow = 48 * time.Hour
+	// How often the scheduler passes lapsed offers on to the next person in line.
+	BookReservationOfferInterval = 15 * time.Minute
+)
+
+type BookReservation struct {
+	ID             uuid.UUID    `json:"id"`
+	Status         string       `json:"status"`
+	QueuePosition  int64        `json:"queue_position"`
+	OfferedAt      sql.NullTime `json:"offeredAt"`
+	OfferExpiresAt sql.NullTime `json:"offerExpiresAt"`
+	CreatedAt      time.Time    `json:"createdAt"`
+	UpdatedAt      time.Time    `json:"updatedAt"`
+	BookID         uuid.UUID    `json:"book_id"`
+	UserID         uuid.UUID    `json:"user_id"`
+}
+
+type UserBookReservation struct {
+	BookReservation
+	BookTitle string `json:"book_title"`
+}
\ No newline at end of file
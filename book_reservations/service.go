@@ -0,0 +1,172 @@
+package book_reservations
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/elorenzorodz/co-library/book_policies"
+	"github.com/elorenzorodz/co-library/common"
+	"github.com/elorenzorodz/co-library/internal/database"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+func (bookReservationAPIConfig *BookReservationAPIConfig) CreateBookReservation(writer http.ResponseWriter, request *http.Request, userId uuid.UUID) {
+	vars := mux.Vars(request)
+	bookId, parseBookIdError := uuid.Parse(vars["bookId"])
+
+	if parseBookIdError != nil {
+		common.ErrorResponse(writer, http.StatusBadRequest, "invalid book id")
+
+		return
+	}
+
+	getBook, getBookError := bookReservationAPIConfig.DB.GetBook(request.Context(), bookId)
+
+	if getBookError != nil {
+		if getBookError == sql.ErrNoRows {
+			common.ErrorResponse(writer, http.StatusNotFound, "book not found")
+		} else {
+			common.ErrorResponse(writer, http.StatusInternalServerError, "failed to get book details, please try again in a few minutes")
+		}
+
+		return
+	}
+
+	if userId == getBook.UserID {
+		common.ErrorResponse(writer, http.StatusForbidden, "you cannot reserve your own book")
+
+		return
+	}
+
+	// The waitlist follows the same lending rules as the book itself.
+	checkBorrowerStatus, checkBorrowerError := book_policies.CheckBookBorrower(request.Context(), bookReservationAPIConfig.DB, getBook, userId)
+
+	if checkBorrowerError != nil {
+		common.ErrorResponse(writer, checkBorrowerStatus, checkBorrowerError.Error())
+
+		return
+	}
+
+	// The waitlist is only for books whose copies are all out on loan or held for someone else.
+	availableBookCopies, countAvailableBookCopiesError := bookReservationAPIConfig.DB.CountAvailableBookCopies(request.Context(), bookId)
+
+	if countAvailableBookCopiesError != nil {
+		common.ErrorResponse(writer, http.StatusInternalServerError, fmt.Sprintf("error reserving book: %s", countAvailableBookCopiesError))
+
+		return
+	}
+
+	offeredBookReservations, getOfferedBookReservationsError := bookReservationAPIConfig.DB.GetOfferedBookReservations(request.Context(), bookId)
+
+	if getOfferedBookReservationsError != nil {
+		common.ErrorResponse(writer, http.StatusInternalServerError, fmt.Sprintf("error reserving book: %s", getOfferedBookReservationsError))
+
+		return
+	}
+
+	if availableBookCopies > int64(len(offeredBookReservations)) {
+		common.ErrorResponse(writer, http.StatusConflict, "book is available, send a borrow request instead")
+
+		return
+	}
+
+	getActiveBookReservationParams := database.GetActiveBookReservationParams{
+		BookID: bookId,
+		UserID: userId,
+	}
+
+	_, getActiveBookReservationError := bookReservationAPIConfig.DB.GetActiveBookReservation(request.Context(), getActiveBookReservationParams)
+
+	if getActiveBookReservationError != nil && getActiveBookReservationError != sql.ErrNoRows {
+		common.ErrorResponse(writer, http.StatusInternalServerError, fmt.Sprintf("error reserving book: %s", getActiveBookReservationError))
+
+		return
+	} else if getActiveBookReservationError == nil {
+		common.ErrorResponse(writer, http.StatusConflict, "you are already in the waitlist for this book")
+
+		return
+	}
+
+	createBookReservationParams := database.CreateBookReservationParams{
+		ID:     uuid.New(),
+		BookID: bookId,
+		UserID: userId,
+	}
+
+	newBookReservation, createBookReservationError := bookReservationAPIConfig.DB.CreateBookReservation(request.Context(), createBookReservationParams)
+
+	if createBookReservationError != nil {
+		common.ErrorResponse(writer, http.StatusInternalServerError, fmt.Sprintf("error reserving book: %s", createBookReservationError))
+
+		return
+	}
+
+	countBookReservationsAheadParams := database.CountBookReservationsAheadParams{
+		BookID:    bookId,
+		CreatedAt: newBookReservation.CreatedAt,
+	}
+
+	reservationsAhead, countBookReservationsAheadError := bookReservationAPIConfig.DB.CountBookReservationsAhead(request.Context(), countBookReservationsAheadParams)
+
+	if countBookReservationsAheadError != nil {
+		common.ErrorResponse(writer, http.StatusInternalServerError, fmt.Sprintf("error getting waitlist position: %s", countBookReservationsAheadError))
+
+		return
+	}
+
+	common.JSONResponse(writer, http.StatusCreated, DatabaseBookReservationToBookReservationJSON(newBookReservation, reservationsAhead+1))
+}
+
+func (bookReservationAPIConfig *BookReservationAPIConfig) GetBookReservations(writer http.ResponseWriter, request *http.Request, userId uuid.UUID) {
+	userBookReservations, getUserBookReservationsError := bookReservationAPIConfig.DB.GetUserBookReservations(request.Context(), userId)
+
+	if getUserBookReservationsError != nil {
+		common.ErrorResponse(writer, http.StatusInternalServerError, fmt.Sprintf("error getting reservations: %s", getUserBookReservationsError))
+
+		return
+	}
+
+	common.JSONResponse(writer, http.StatusOK, DatabaseUserBookReservationsToJSON(userBookReservations))
+}
+
+func (bookReservationAPIConfig *BookReservationAPIConfig) CancelBookReservation(writer http.ResponseWriter, request *http.Request, userId uuid.UUID) {
+	vars := mux.Vars(request)
+	bookReservationId, parseBookReservationIdError := uuid.Parse(vars["bookReservationId"])
+
+	if parseBookReservationIdError != nil {
+		common.ErrorResponse(writer, http.StatusBadRequest, "invalid book reservation id")
+
+		return
+	}
+
+	cancelBookReservationParams := database.CancelBookReservationParams{
+		ID:     bookReservationId,
+		UserID: userId,
+	}
+
+	cancelBookReservation, cancelBookReservationError := bookReservationAPIConfig.DB.CancelBookReservation(request.Context(), cancelBookReservationParams)
+
+	if cancelBookReservationError != nil {
+		if cancelBookReservationError == sql.ErrNoRows {
+			common.ErrorResponse(writer, http.StatusNotFound, "book reservation not found")
+		} else {
+			common.ErrorResponse(writer, http.StatusInternalServerError, "failed to cancel book reservation, please try again in a few minutes")
+		}
+
+		return
+	}
+
+	// Turning down an offer passes the book on to the next person in line.
+	if cancelBookReservation.OfferedAt.Valid {
+		_, holdBookError := HoldBookForNextInLine(request.Context(), &bookReservationAPIConfig.APIConfig, cancelBookReservation.BookID)
+
+		if holdBookError != nil {
+			log.Printf("failed to offer book %s to the next reservation: %s", cancelBookReservation.BookID, holdBookError)
+		}
+	}
+
+	common.JSONResponse(writer, http.StatusOK, "book reservation successfully cancelled")
+}
\ No newline at end of file
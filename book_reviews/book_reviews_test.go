@@ -0,0 +1,452 @@
+package book_reviews
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/elorenzorodz/co-library/common"
+	"github.com/elorenzorodz/co-library/internal/database"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+type MockQueries struct {
+	*common.BaseMock
+
+	GetBookFunc                func(ctx context.Context, id uuid.UUID) (database.Book, error)
+	HasReturnedBookBorrowFunc  func(ctx context.Context, arg database.HasReturnedBookBorrowParams) (bool, error)
+	CreateBookReviewFunc       func(ctx context.Context, arg database.CreateBookReviewParams) (database.BookReview, error)
+	GetBookReviewForUpdateFunc func(ctx context.Context, id uuid.UUID) (database.BookReview, error)
+	UpdateBookReviewFunc       func(ctx context.Context, arg database.UpdateBookReviewParams) (database.BookReview, error)
+	DeleteBookReviewFunc       func(ctx context.Context, arg database.DeleteBookReviewParams) (database.BookReview, error)
+	ReplyToBookReviewFunc      func(ctx context.Context, arg database.ReplyToBookReviewParams) (database.BookReview, error)
+	GetBookReviewsFunc         func(ctx context.Context, arg database.GetBookReviewsParams) ([]database.GetBookReviewsRow, error)
+	AdjustBookRatingFunc       func(ctx context.Context, arg database.AdjustBookRatingParams) error
+}
+
+func (mockQueries *MockQueries) GetBook(ctx context.Context, id uuid.UUID) (database.Book, error) {
+	if mockQueries.GetBookFunc != nil {
+		return mockQueries.GetBookFunc(ctx, id)
+	}
+
+	return mockQueries.BaseMock.GetBook(ctx, id)
+}
+
+func (mockQueries *MockQueries) HasReturnedBookBorrow(ctx context.Context, arg database.HasReturnedBookBorrowParams) (bool, error) {
+	if mockQueries.HasReturnedBookBorrowFunc != nil {
+		return mockQueries.HasReturnedBookBorrowFunc(ctx, arg)
+	}
+
+	return mockQueries.BaseMock.HasReturnedBookBorrow(ctx, arg)
+}
+
+func (mockQueries *MockQueries) CreateBookReview(ctx context.Context, arg database.CreateBookReviewParams) (database.BookReview, error) {
+	if mockQueries.CreateBookReviewFunc != nil {
+		return mockQueries.CreateBookReviewFunc(ctx, arg)
+	}
+
+	return mockQueries.BaseMock.CreateBookReview(ctx, arg)
+}
+
+func (mockQueries *MockQueries) GetBookReviewForUpdate(ctx context.Context, id uuid.UUID) (database.BookReview, error) {
+	if mockQueries.GetBookReviewForUpdateFunc != nil {
+		return mockQueries.GetBookReviewForUpdateFunc(ctx, id)
+	}
+
+	return mockQueries.BaseMock.GetBookReviewForUpdate(ctx, id)
+}
+
+func (mockQueries *MockQueries) UpdateBookReview(ctx context.Context, arg database.UpdateBookReviewParams) (database.BookReview, error) {
+	if mockQueries.UpdateBookReviewFunc != nil {
+		return mockQueries.UpdateBookReviewFunc(ctx, arg)
+	}
+
+	return mockQueries.BaseMock.UpdateBookReview(ctx, arg)
+}
+
+func (mockQueries *MockQueries) DeleteBookReview(ctx context.Context, arg database.DeleteBookReviewParams) (database.BookReview, error) {
+	if mockQueries.DeleteBookReviewFunc != nil {
+		return mockQueries.DeleteBookReviewFunc(ctx, arg)
+	}
+
+	return mockQueries.BaseMock.DeleteBookReview(ctx, arg)
+}
+
+func (mockQueries *MockQueries) ReplyToBookReview(ctx context.Context, arg database.ReplyToBookReviewParams) (database.BookReview, error) {
+	if mockQueries.ReplyToBookReviewFunc != nil {
+		return mockQueries.ReplyToBookReviewFunc(ctx, arg)
+	}
+
+	return mockQueries.BaseMock.ReplyToBookReview(ctx, arg)
+}
+
+func (mockQueries *MockQueries) GetBookReviews(ctx context.Context, arg database.GetBookReviewsParams) ([]database.GetBookReviewsRow, error) {
+	if mockQueries.GetBookReviewsFunc != nil {
+		return mockQueries.GetBookReviewsFunc(ctx, arg)
+	}
+
+	return mockQueries.BaseMock.GetBookReviews(ctx, arg)
+}
+
+func (mockQueries *MockQueries) AdjustBookRating(ctx context.Context, arg database.AdjustBookRatingParams) error {
+	if mockQueries.AdjustBookRatingFunc != nil {
+		return mockQueries.AdjustBookRatingFunc(ctx, arg)
+	}
+
+	return mockQueries.BaseMock.AdjustBookRating(ctx, arg)
+}
+
+func newTestBook(userId uuid.UUID, visibility string) database.Book {
+	return database.Book{
+		ID:             uuid.New(),
+		Title:          "Dune",
+		Author:         "Frank Herbert",
+		UserID:         userId,
+		CreatedAt:      time.Now(),
+		UpdatedAt:      time.Now(),
+		LoanPeriodDays: 14,
+		Visibility:     visibility,
+		Lendable:       true,
+		BorrowerPolicy: "anyone",
+	}
+}
+
+func newTestReview(bookId uuid.UUID, reviewerId uuid.UUID, rating int32) database.BookReview {
+	return database.BookReview{
+		ID:         uuid.New(),
+		Rating:     rating,
+		Body:       "A classic",
+		CreatedAt:  time.Now(),
+		UpdatedAt:  time.Now(),
+		BookID:     bookId,
+		ReviewerID: reviewerId,
+	}
+}
+
+func reviewRequest(method string, body string, vars map[string]string) *http.Request {
+	request := httptest.NewRequest(method, "/api/v1/books/reviews", strings.NewReader(body))
+
+	return mux.SetURLVars(request, vars)
+}
+
+func TestCreateBookReview(tTesting *testing.T) {
+	ownerId, reviewerId := uuid.New(), uuid.New()
+	testBook := newTestBook(ownerId, "public")
+
+	createBookReview := func(mockQueries *MockQueries, book database.Book, userId uuid.UUID, body string) *httptest.ResponseRecorder {
+		if mockQueries.GetBookFunc == nil {
+			mockQueries.GetBookFunc = func(ctx context.Context, id uuid.UUID) (database.Book, error) {
+				return book, nil
+			}
+		}
+
+		apiConfig := BookReviewAPIConfig{APIConfig: common.APIConfig{DB: mockQueries}}
+		recorder := httptest.NewRecorder()
+
+		apiConfig.CreateBookReview(recorder, reviewRequest(http.MethodPost, body, map[string]string{"bookId": book.ID.String()}), userId)
+
+		return recorder
+	}
+
+	// 1. Success: a past borrower rates the book and its totals move with it.
+	tTesting.Run("Success", func(t *testing.T) {
+		var adjustBookRatingParams database.AdjustBookRatingParams
+
+		mockQueries := &MockQueries{
+			BaseMock: common.NewBaseMock(),
+			HasReturnedBookBorrowFunc: func(ctx context.Context, arg database.HasReturnedBookBorrowParams) (bool, error) {
+				return arg.BookID == testBook.ID && arg.BorrowerID == reviewerId, nil
+			},
+			CreateBookReviewFunc: func(ctx context.Context, arg database.CreateBookReviewParams) (database.BookReview, error) {
+				bookReview := newTestReview(arg.BookID, arg.ReviewerID, arg.Rating)
+				bookReview.ID = arg.ID
+				bookReview.Body = arg.Body
+
+				return bookReview, nil
+			},
+			AdjustBookRatingFunc: func(ctx context.Context, arg database.AdjustBookRatingParams) error {
+				adjustBookRatingParams = arg
+
+				return nil
+			},
+		}
+
+		recorder := createBookReview(mockQueries, testBook, reviewerId, `{"rating": 4, "body": " Slow start, great ending "}`)
+
+		if recorder.Code != http.StatusCreated {
+			t.Fatalf("Expected status %d, got %d. Body: %s", http.StatusCreated, recorder.Code, recorder.Body.String())
+		}
+
+		var bookReview BookReview
+		json.Unmarshal(recorder.Body.Bytes(), &bookReview)
+
+		if bookReview.Rating != 4 || bookReview.Body != "Slow start, great ending" || bookReview.ReviewerID != reviewerId {
+			t.Errorf("Unexpected review: %+v", bookReview)
+		}
+
+		expectedParams := database.AdjustBookRatingParams{CountDelta: 1, TotalDelta: 4, ID: testBook.ID}
+
+		if adjustBookRatingParams != expectedParams {
+			t.Errorf("Expected %+v, got %+v", expectedParams, adjustBookRatingParams)
+		}
+	})
+
+	// 2. Failure: members who never borrowed the book, or still have it, can't review it.
+	tTesting.Run("NotReturned", func(t *testing.T) {
+		if recorder := createBookReview(&MockQueries{BaseMock: common.NewBaseMock()}, testBook, reviewerId, `{"rating": 5}`); recorder.Code != http.StatusForbidden {
+			t.Errorf("Expected status %d, got %d", http.StatusForbidden, recorder.Code)
+		}
+	})
+
+	// 3. Failure: one review per member.
+	tTesting.Run("Duplicate", func(t *testing.T) {
+		mockQueries := &MockQueries{
+			BaseMock: common.NewBaseMock(),
+			HasReturnedBookBorrowFunc: func(ctx context.Context, arg database.HasReturnedBookBorrowParams) (bool, error) {
+				return true, nil
+			},
+			CreateBookReviewFunc: func(ctx context.Context, arg database.CreateBookReviewParams) (database.BookReview, error) {
+				return database.BookReview{}, sql.ErrNoRows
+			},
+			AdjustBookRatingFunc: func(ctx context.Context, arg database.AdjustBookRatingParams) error {
+				t.Error("Expected the totals to stay as they are")
+
+				return nil
+			},
+		}
+
+		if recorder := createBookReview(mockQueries, testBook, reviewerId, `{"rating": 5}`); recorder.Code != http.StatusConflict {
+			t.Errorf("Expected status %d, got %d", http.StatusConflict, recorder.Code)
+		}
+	})
+
+	// 4. Failure: ratings are 1 to 5 stars.
+	tTesting.Run("InvalidRating", func(t *testing.T) {
+		for _, body := range []string{`{"rating": 0}`, `{"rating": 6}`, `{"body": "No stars"}`} {
+			if recorder := createBookReview(&MockQueries{BaseMock: common.NewBaseMock()}, testBook, reviewerId, body); recorder.Code != http.StatusBadRequest {
+				t.Errorf("%s: expected status %d, got %d", body, http.StatusBadRequest, recorder.Code)
+			}
+		}
+	})
+
+	// 5. Failure: a book hidden from the member is not found.
+	tTesting.Run("HiddenBook", func(t *testing.T) {
+		if recorder := createBookReview(&MockQueries{BaseMock: common.NewBaseMock()}, newTestBook(ownerId, "private"), reviewerId, `{"rating": 3}`); recorder.Code != http.StatusNotFound {
+			t.Errorf("Expected status %d, got %d", http.StatusNotFound, recorder.Code)
+		}
+	})
+}
+
+func TestGetBookReviews(tTesting *testing.T) {
+	testBook := newTestBook(uuid.New(), "public")
+
+	// 1. Success: newest first with the reviewer's name, paged by creation time.
+	tTesting.Run("Success", func(t *testing.T) {
+		var getBookReviewsParams database.GetBookReviewsParams
+
+		newerReview := newTestReview(testBook.ID, uuid.New(), 5)
+		olderReview := newTestReview(testBook.ID, uuid.New(), 2)
+		olderReview.CreatedAt = newerReview.CreatedAt.Add(-time.Hour)
+
+		mockQueries := &MockQueries{
+			BaseMock: common.NewBaseMock(),
+			GetBookFunc: func(ctx context.Context, id uuid.UUID) (database.Book, error) {
+				return testBook, nil
+			},
+			GetBookReviewsFunc: func(ctx context.Context, arg database.GetBookReviewsParams) ([]database.GetBookReviewsRow, error) {
+				getBookReviewsParams = arg
+
+				return []database.GetBookReviewsRow{
+					{BookReview: newerReview, ReviewerFirstName: "Ann", ReviewerLastName: "Lee"},
+					{BookReview: olderReview, ReviewerFirstName: "Bo", ReviewerLastName: "Kim"},
+				}, nil
+			},
+		}
+
+		apiConfig := BookReviewAPIConfig{APIConfig: common.APIConfig{DB: mockQueries}}
+		request := mux.SetURLVars(httptest.NewRequest(http.MethodGet, "/api/v1/books/x/reviews?limit=1", nil), map[string]string{"bookId": testBook.ID.String()})
+		recorder := httptest.NewRecorder()
+
+		apiConfig.GetBookReviews(recorder, request, uuid.New())
+
+		var page common.Page[BookReview]
+		json.Unmarshal(recorder.Body.Bytes(), &page)
+
+		if recorder.Code != http.StatusOK || len(page.Data) != 1 || page.Data[0].ReviewerName != "Ann Lee" || page.NextCursor == nil {
+			t.Fatalf("Expected the newest review and a next cursor, got %d %s", recorder.Code, recorder.Body.String())
+		}
+
+		request = mux.SetURLVars(httptest.NewRequest(http.MethodGet, "/api/v1/books/x/reviews?limit=1&cursor="+*page.NextCursor, nil), map[string]string{"bookId": testBook.ID.String()})
+
+		apiConfig.GetBookReviews(httptest.NewRecorder(), request, uuid.New())
+
+		if !getBookReviewsParams.CursorCreatedAt.Equal(newerReview.CreatedAt) || getBookReviewsParams.CursorID.UUID != newerReview.ID {
+			t.Errorf("Expected the cursor of the newest review, got %+v", getBookReviewsParams)
+		}
+	})
+}
+
+func TestUpdateBookReview(tTesting *testing.T) {
+	reviewerId := uuid.New()
+	currentReview := newTestReview(uuid.New(), reviewerId, 2)
+
+	updateBookReview := func(mockQueries *MockQueries, userId uuid.UUID) *httptest.ResponseRecorder {
+		mockQueries.GetBookReviewForUpdateFunc = func(ctx context.Context, id uuid.UUID) (database.BookReview, error) {
+			return currentReview, nil
+		}
+
+		apiConfig := BookReviewAPIConfig{APIConfig: common.APIConfig{DB: mockQueries}}
+		recorder := httptest.NewRecorder()
+
+		apiConfig.UpdateBookReview(recorder, reviewRequest(http.MethodPatch, `{"rating": 5, "body": "Better the second time"}`, map[string]string{"bookReviewId": currentReview.ID.String()}), userId)
+
+		return recorder
+	}
+
+	// 1. Success: the book's total moves by the change in stars only.
+	tTesting.Run("Success", func(t *testing.T) {
+		var adjustBookRatingParams database.AdjustBookRatingParams
+
+		mockQueries := &MockQueries{
+			BaseMock: common.NewBaseMock(),
+			UpdateBookReviewFunc: func(ctx context.Context, arg database.UpdateBookReviewParams) (database.BookReview, error) {
+				updatedReview := currentReview
+				updatedReview.Rating = arg.Rating
+				updatedReview.Body = arg.Body
+
+				return updatedReview, nil
+			},
+			AdjustBookRatingFunc: func(ctx context.Context, arg database.AdjustBookRatingParams) error {
+				adjustBookRatingParams = arg
+
+				return nil
+			},
+		}
+
+		recorder := updateBookReview(mockQueries, reviewerId)
+		expectedParams := database.AdjustBookRatingParams{TotalDelta: 3, ID: currentReview.BookID}
+
+		if recorder.Code != http.StatusOK || adjustBookRatingParams != expectedParams {
+			t.Errorf("Expected status %d and %+v, got %d and %+v", http.StatusOK, expectedParams, recorder.Code, adjustBookRatingParams)
+		}
+	})
+
+	// 2. Failure: someone else's review is not found.
+	tTesting.Run("NotReviewer", func(t *testing.T) {
+		if recorder := updateBookReview(&MockQueries{BaseMock: common.NewBaseMock()}, uuid.New()); recorder.Code != http.StatusNotFound {
+			t.Errorf("Expected status %d, got %d", http.StatusNotFound, recorder.Code)
+		}
+	})
+}
+
+func TestDeleteBookReview(tTesting *testing.T) {
+	reviewerId := uuid.New()
+	deletedReview := newTestReview(uuid.New(), reviewerId, 4)
+
+	deleteBookReview := func(mockQueries *MockQueries) *httptest.ResponseRecorder {
+		apiConfig := BookReviewAPIConfig{APIConfig: common.APIConfig{DB: mockQueries}}
+		recorder := httptest.NewRecorder()
+
+		apiConfig.DeleteBookReview(recorder, reviewRequest(http.MethodDelete, "", map[string]string{"bookReviewId": deletedReview.ID.String()}), reviewerId)
+
+		return recorder
+	}
+
+	// 1. Success: the rating comes off the book's totals.
+	tTesting.Run("Success", func(t *testing.T) {
+		var adjustBookRatingParams database.AdjustBookRatingParams
+
+		mockQueries := &MockQueries{
+			BaseMock: common.NewBaseMock(),
+			DeleteBookReviewFunc: func(ctx context.Context, arg database.DeleteBookReviewParams) (database.BookReview, error) {
+				return deletedReview, nil
+			},
+			AdjustBookRatingFunc: func(ctx context.Context, arg database.AdjustBookRatingParams) error {
+				adjustBookRatingParams = arg
+
+				return nil
+			},
+		}
+
+		recorder := deleteBookReview(mockQueries)
+		expectedParams := database.AdjustBookRatingParams{CountDelta: -1, TotalDelta: -4, ID: deletedReview.BookID}
+
+		if recorder.Code != http.StatusOK || adjustBookRatingParams != expectedParams {
+			t.Errorf("Expected status %d and %+v, got %d and %+v", http.StatusOK, expectedParams, recorder.Code, adjustBookRatingParams)
+		}
+	})
+
+	// 2. Failure: the review is gone or not the member's.
+	tTesting.Run("NotFound", func(t *testing.T) {
+		mockQueries := &MockQueries{
+			BaseMock: common.NewBaseMock(),
+			DeleteBookReviewFunc: func(ctx context.Context, arg database.DeleteBookReviewParams) (database.BookReview, error) {
+				return database.BookReview{}, sql.ErrNoRows
+			},
+		}
+
+		if recorder := deleteBookReview(mockQueries); recorder.Code != http.StatusNotFound {
+			t.Errorf("Expected status %d, got %d", http.StatusNotFound, recorder.Code)
+		}
+	})
+}
+
+func TestReplyToBookReview(tTesting *testing.T) {
+	ownerId := uuid.New()
+	bookReview := newTestReview(uuid.New(), uuid.New(), 3)
+
+	// 1. Success: the owner's reply is saved with the review.
+	tTesting.Run("Success", func(t *testing.T) {
+		mockQueries := &MockQueries{
+			BaseMock: common.NewBaseMock(),
+			ReplyToBookReviewFunc: func(ctx context.Context, arg database.ReplyToBookReviewParams) (database.BookReview, error) {
+				if arg.UserID != ownerId || arg.Reply != "Glad you enjoyed it" {
+					t.Errorf("Unexpected reply: %+v", arg)
+				}
+
+				repliedReview := bookReview
+				repliedReview.Reply = arg.Reply
+				repliedReview.RepliedAt = sql.NullTime{Time: time.Now(), Valid: true}
+
+				return repliedReview, nil
+			},
+		}
+
+		apiConfig := BookReviewAPIConfig{APIConfig: common.APIConfig{DB: mockQueries}}
+		recorder := httptest.NewRecorder()
+
+		apiConfig.ReplyToBookReview(recorder, reviewRequest(http.MethodPatch, `{"reply": "Glad you enjoyed it "}`, map[string]string{"bookReviewId": bookReview.ID.String()}), ownerId)
+
+		var repliedReview BookReview
+		json.Unmarshal(recorder.Body.Bytes(), &repliedReview)
+
+		if recorder.Code != http.StatusOK || repliedReview.Reply != "Glad you enjoyed it" || !repliedReview.RepliedAt.Valid {
+			t.Errorf("Expected the reply, got %d %s", recorder.Code, recorder.Body.String())
+		}
+	})
+
+	// 2. Failure: only the book owner can reply.
+	tTesting.Run("NotOwner", func(t *testing.T) {
+		mockQueries := &MockQueries{
+			BaseMock: common.NewBaseMock(),
+			ReplyToBookReviewFunc: func(ctx context.Context, arg database.ReplyToBookReviewParams) (database.BookReview, error) {
+				return database.BookReview{}, sql.ErrNoRows
+			},
+		}
+
+		apiConfig := BookReviewAPIConfig{APIConfig: common.APIConfig{DB: mockQueries}}
+		recorder := httptest.NewRecorder()
+
+		apiConfig.ReplyToBookReview(recorder, reviewRequest(http.MethodPatch, `{"reply": "Thanks"}`, map[string]string{"bookReviewId": bookReview.ID.String()}), uuid.New())
+
+		if recorder.Code != http.StatusNotFound {
+			t.Errorf("Expected status %d, got %d", http.StatusNotFound, recorder.Code)
+		}
+	})
+}
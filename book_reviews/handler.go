@@ -0,0 +1,73 @@
+package book_reviews
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/elorenzorodz/co-library/common"
+	"github.com/elorenzorodz/co-library/internal/database"
+)
+
+func DatabaseBookReviewToBookReviewJSON(databaseBookReview database.BookReview) BookReview {
+	return BookReview{
+		ID:         databaseBookReview.ID,
+		Rating:     databaseBookReview.Rating,
+		Body:       databaseBookReview.Body,
+		Reply:      databaseBookReview.Reply,
+		RepliedAt:  databaseBookReview.RepliedAt,
+		CreatedAt:  databaseBookReview.CreatedAt,
+		UpdatedAt:  databaseBookReview.UpdatedAt,
+		BookID:     databaseBookReview.BookID,
+		ReviewerID: databaseBookReview.ReviewerID,
+	}
+}
+
+func DatabaseGetBookReviewsRowsToBookReviewsJSON(databaseRows []database.GetBookReviewsRow) []BookReview {
+	bookReviews := []BookReview{}
+
+	for _, databaseRow := range databaseRows {
+		bookReview := DatabaseBookReviewToBookReviewJSON(databaseRow.BookReview)
+		bookReview.ReviewerName = fmt.Sprintf("%s %s", databaseRow.ReviewerFirstName, databaseRow.ReviewerLastName)
+
+		bookReviews = append(bookReviews, bookReview)
+	}
+
+	return bookReviews
+}
+
+// Reviews are paged newest first.
+func BookReviewCursor(databaseRow database.GetBookReviewsRow) common.Cursor {
+	return common.Cursor{Value: databaseRow.BookReview.CreatedAt.Format(time.RFC3339Nano), ID: databaseRow.BookReview.ID}
+}
+
+// The created_at of the last review on the previous page, zero on the first page.
+func ParseBookReviewCursor(pageParameters common.PageParameters) (time.Time, error) {
+	if pageParameters.Cursor == nil {
+		return time.Time{}, nil
+	}
+
+	cursorCreatedAt, parseError := time.Parse(time.RFC3339Nano, pageParameters.Cursor.Value)
+
+	if parseError != nil {
+		return time.Time{}, errors.New("invalid cursor")
+	}
+
+	return cursorCreatedAt, nil
+}
+
+// Trims the review and checks it, a review can be just the stars.
+func ValidateBookReview(upsertBookReviewParameters *UpsertBookReviewParameters) error {
+	upsertBookReviewParameters.Body = strings.TrimSpace(upsertBookReviewParameters.Body)
+
+	if upsertBookReviewParameters.Rating < MinBookReviewRating || upsertBookReviewParameters.Rating > MaxBookReviewRating {
+		return fmt.Errorf("rating must be between %d and %d stars", MinBookReviewRating, MaxBookReviewRating)
+	}
+
+	if len(upsertBookReviewParameters.Body) > MaxBookReviewLength {
+		return fmt.Errorf("review must be at most %d characters", MaxBookReviewLength)
+	}
+
+	return nil
+}
@@ -0,0 +1,44 @@
+package book_reviews
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/elorenzorodz/co-library/common"
+	"github.com/google/uuid"
+)
+
+type BookReviewAPIConfig struct {
+	common.APIConfig
+}
+
+const (
+	MinBookReviewRating      = 1
+	MaxBookReviewRating      = 5
+	MaxBookReviewLength      = 4000
+	MaxBookReviewReplyLength = 2000
+)
+
+type BookReview struct {
+	ID         uuid.UUID    `json:"id"`
+	Rating     int32        `json:"rating"`
+	Body       string       `json:"body"`
+	Reply      string       `json:"reply"`
+	RepliedAt  sql.NullTime `json:"repliedAt"`
+	CreatedAt  time.Time    `json:"createdAt"`
+	UpdatedAt  time.Time    `json:"updatedAt"`
+	BookID     uuid.UUID    `json:"book_id"`
+	ReviewerID uuid.UUID    `json:"reviewer_id"`
+	// Only filled in when listing a book's reviews.
+	ReviewerName string `json:"reviewer_name,omitempty"`
+}
+
+// Used both to write a review and to edit it.
+type UpsertBookReviewParameters struct {
+	Rating int32  `json:"rating"`
+	Body   string `json:"body"`
+}
+
+type ReplyToBookReviewParameters struct {
+	Reply string `json:"reply"`
+}
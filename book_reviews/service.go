@@ -0,0 +1,366 @@
+package book_reviews
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/elorenzorodz/co-library/book_policies"
+	"github.com/elorenzorodz/co-library/common"
+	"github.com/elorenzorodz/co-library/internal/database"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+var (
+	errBookReviewExists   = errors.New("book review exists")
+	errBookReviewNotFound = errors.New("book review not found")
+)
+
+func (bookReviewAPIConfig *BookReviewAPIConfig) CreateBookReview(writer http.ResponseWriter, request *http.Request, userId uuid.UUID) {
+	vars := mux.Vars(request)
+	bookId, parseBookIdError := uuid.Parse(vars["bookId"])
+
+	if parseBookIdError != nil {
+		common.ErrorResponse(writer, http.StatusBadRequest, "invalid book id")
+
+		return
+	}
+
+	upsertBookReviewParameters := UpsertBookReviewParameters{}
+
+	decoder := json.NewDecoder(request.Body)
+	decoderError := decoder.Decode(&upsertBookReviewParameters)
+
+	if decoderError != nil {
+		common.ErrorResponse(writer, http.StatusBadRequest, fmt.Sprintf("error parsing JSON: %s", decoderError))
+
+		return
+	}
+
+	if validateError := ValidateBookReview(&upsertBookReviewParameters); validateError != nil {
+		common.ErrorResponse(writer, http.StatusBadRequest, validateError.Error())
+
+		return
+	}
+
+	if !bookReviewAPIConfig.checkBookViewer(writer, request, bookId, userId) {
+		return
+	}
+
+	hasReturnedBookBorrowParams := database.HasReturnedBookBorrowParams{
+		BookID:     bookId,
+		BorrowerID: userId,
+	}
+
+	hasReturned, hasReturnedError := bookReviewAPIConfig.DB.HasReturnedBookBorrow(request.Context(), hasReturnedBookBorrowParams)
+
+	if hasReturnedError != nil {
+		common.ErrorResponse(writer, http.StatusInternalServerError, "error checking your loans of this book, please try again in a few minutes")
+
+		return
+	}
+
+	if !hasReturned {
+		common.ErrorResponse(writer, http.StatusForbidden, "only members who borrowed and returned this book can review it")
+
+		return
+	}
+
+	var bookReview database.BookReview
+
+	// The review and the book's rating totals change together.
+	transactionError := bookReviewAPIConfig.InTransaction(request.Context(), func(querier common.Querier) error {
+		createBookReviewParams := database.CreateBookReviewParams{
+			ID:         uuid.New(),
+			Rating:     upsertBookReviewParameters.Rating,
+			Body:       upsertBookReviewParameters.Body,
+			BookID:     bookId,
+			ReviewerID: userId,
+		}
+
+		var createBookReviewError error
+
+		bookReview, createBookReviewError = querier.CreateBookReview(request.Context(), createBookReviewParams)
+
+		if createBookReviewError == sql.ErrNoRows {
+			return errBookReviewExists
+		} else if createBookReviewError != nil {
+			return createBookReviewError
+		}
+
+		return querier.AdjustBookRating(request.Context(), database.AdjustBookRatingParams{CountDelta: 1, TotalDelta: bookReview.Rating, ID: bookId})
+	})
+
+	if transactionError != nil {
+		if errors.Is(transactionError, errBookReviewExists) {
+			common.ErrorResponse(writer, http.StatusConflict, "you already reviewed this book, edit your review instead")
+		} else {
+			common.ErrorResponse(writer, http.StatusInternalServerError, "error creating review, please try again in a few minutes")
+		}
+
+		return
+	}
+
+	common.JSONResponse(writer, http.StatusCreated, DatabaseBookReviewToBookReviewJSON(bookReview))
+}
+
+func (bookReviewAPIConfig *BookReviewAPIConfig) GetBookReviews(writer http.ResponseWriter, request *http.Request, userId uuid.UUID) {
+	vars := mux.Vars(request)
+	bookId, parseBookIdError := uuid.Parse(vars["bookId"])
+
+	if parseBookIdError != nil {
+		common.ErrorResponse(writer, http.StatusBadRequest, "invalid book id")
+
+		return
+	}
+
+	pageParameters, parsePageError := common.ParsePageParameters(request.URL.Query())
+
+	if parsePageError != nil {
+		common.ErrorResponse(writer, http.StatusBadRequest, parsePageError.Error())
+
+		return
+	}
+
+	cursorCreatedAt, parseCursorError := ParseBookReviewCursor(pageParameters)
+
+	if parseCursorError != nil {
+		common.ErrorResponse(writer, http.StatusBadRequest, parseCursorError.Error())
+
+		return
+	}
+
+	if !bookReviewAPIConfig.checkBookViewer(writer, request, bookId, userId) {
+		return
+	}
+
+	getBookReviewsParams := database.GetBookReviewsParams{
+		BookID:          bookId,
+		CursorCreatedAt: cursorCreatedAt,
+		CursorID:        pageParameters.CursorID(),
+		PageLimit:       pageParameters.QueryLimit(),
+	}
+
+	getBookReviews, getBookReviewsError := bookReviewAPIConfig.DB.GetBookReviews(request.Context(), getBookReviewsParams)
+
+	if getBookReviewsError != nil {
+		common.ErrorResponse(writer, http.StatusInternalServerError, "failed to get reviews, please try again in a few minutes")
+
+		return
+	}
+
+	getBookReviews, nextCursor := common.SplitPage(getBookReviews, pageParameters, BookReviewCursor)
+
+	common.JSONResponse(writer, http.StatusOK, common.Page[BookReview]{Data: DatabaseGetBookReviewsRowsToBookReviewsJSON(getBookReviews), NextCursor: nextCursor})
+}
+
+func (bookReviewAPIConfig *BookReviewAPIConfig) UpdateBookReview(writer http.ResponseWriter, request *http.Request, userId uuid.UUID) {
+	vars := mux.Vars(request)
+	bookReviewId, parseBookReviewIdError := uuid.Parse(vars["bookReviewId"])
+
+	if parseBookReviewIdError != nil {
+		common.ErrorResponse(writer, http.StatusBadRequest, "invalid book review id")
+
+		return
+	}
+
+	upsertBookReviewParameters := UpsertBookReviewParameters{}
+
+	decoder := json.NewDecoder(request.Body)
+	decoderError := decoder.Decode(&upsertBookReviewParameters)
+
+	if decoderError != nil {
+		common.ErrorResponse(writer, http.StatusBadRequest, fmt.Sprintf("error parsing JSON: %s", decoderError))
+
+		return
+	}
+
+	if validateError := ValidateBookReview(&upsertBookReviewParameters); validateError != nil {
+		common.ErrorResponse(writer, http.StatusBadRequest, validateError.Error())
+
+		return
+	}
+
+	var bookReview database.BookReview
+
+	transactionError := bookReviewAPIConfig.InTransaction(request.Context(), func(querier common.Querier) error {
+		currentBookReview, getBookReviewError := querier.GetBookReviewForUpdate(request.Context(), bookReviewId)
+
+		if getBookReviewError == sql.ErrNoRows || (getBookReviewError == nil && currentBookReview.ReviewerID != userId) {
+			return errBookReviewNotFound
+		} else if getBookReviewError != nil {
+			return getBookReviewError
+		}
+
+		updateBookReviewParams := database.UpdateBookReviewParams{
+			Rating:     upsertBookReviewParameters.Rating,
+			Body:       upsertBookReviewParameters.Body,
+			ID:         bookReviewId,
+			ReviewerID: userId,
+		}
+
+		var updateBookReviewError error
+
+		bookReview, updateBookReviewError = querier.UpdateBookReview(request.Context(), updateBookReviewParams)
+
+		if updateBookReviewError != nil {
+			return updateBookReviewError
+		}
+
+		if bookReview.Rating == currentBookReview.Rating {
+			return nil
+		}
+
+		adjustBookRatingParams := database.AdjustBookRatingParams{
+			TotalDelta: bookReview.Rating - currentBookReview.Rating,
+			ID:         bookReview.BookID,
+		}
+
+		return querier.AdjustBookRating(request.Context(), adjustBookRatingParams)
+	})
+
+	if transactionError != nil {
+		if errors.Is(transactionError, errBookReviewNotFound) {
+			common.ErrorResponse(writer, http.StatusNotFound, "review not found")
+		} else {
+			common.ErrorResponse(writer, http.StatusInternalServerError, "error updating review, please try again in a few minutes")
+		}
+
+		return
+	}
+
+	common.JSONResponse(writer, http.StatusOK, DatabaseBookReviewToBookReviewJSON(bookReview))
+}
+
+func (bookReviewAPIConfig *BookReviewAPIConfig) DeleteBookReview(writer http.ResponseWriter, request *http.Request, userId uuid.UUID) {
+	vars := mux.Vars(request)
+	bookReviewId, parseBookReviewIdError := uuid.Parse(vars["bookReviewId"])
+
+	if parseBookReviewIdError != nil {
+		common.ErrorResponse(writer, http.StatusBadRequest, "invalid book review id")
+
+		return
+	}
+
+	transactionError := bookReviewAPIConfig.InTransaction(request.Context(), func(querier common.Querier) error {
+		deleteBookReviewParams := database.DeleteBookReviewParams{
+			ID:         bookReviewId,
+			ReviewerID: userId,
+		}
+
+		deletedBookReview, deleteBookReviewError := querier.DeleteBookReview(request.Context(), deleteBookReviewParams)
+
+		if deleteBookReviewError == sql.ErrNoRows {
+			return errBookReviewNotFound
+		} else if deleteBookReviewError != nil {
+			return deleteBookReviewError
+		}
+
+		adjustBookRatingParams := database.AdjustBookRatingParams{
+			CountDelta: -1,
+			TotalDelta: -deletedBookReview.Rating,
+			ID:         deletedBookReview.BookID,
+		}
+
+		return querier.AdjustBookRating(request.Context(), adjustBookRatingParams)
+	})
+
+	if transactionError != nil {
+		if errors.Is(transactionError, errBookReviewNotFound) {
+			common.ErrorResponse(writer, http.StatusNotFound, "review not found")
+		} else {
+			common.ErrorResponse(writer, http.StatusInternalServerError, "error deleting review, please try again in a few minutes")
+		}
+
+		return
+	}
+
+	common.JSONResponse(writer, http.StatusOK, "review deleted")
+}
+
+// Book owners get one public reply per review, sending it again replaces it.
+func (bookReviewAPIConfig *BookReviewAPIConfig) ReplyToBookReview(writer http.ResponseWriter, request *http.Request, userId uuid.UUID) {
+	vars := mux.Vars(request)
+	bookReviewId, parseBookReviewIdError := uuid.Parse(vars["bookReviewId"])
+
+	if parseBookReviewIdError != nil {
+		common.ErrorResponse(writer, http.StatusBadRequest, "invalid book review id")
+
+		return
+	}
+
+	replyToBookReviewParameters := ReplyToBookReviewParameters{}
+
+	decoder := json.NewDecoder(request.Body)
+	decoderError := decoder.Decode(&replyToBookReviewParameters)
+
+	if decoderError != nil {
+		common.ErrorResponse(writer, http.StatusBadRequest, fmt.Sprintf("error parsing JSON: %s", decoderError))
+
+		return
+	}
+
+	replyToBookReviewParameters.Reply = strings.TrimSpace(replyToBookReviewParameters.Reply)
+
+	if len(replyToBookReviewParameters.Reply) > MaxBookReviewReplyLength {
+		common.ErrorResponse(writer, http.StatusBadRequest, fmt.Sprintf("reply must be at most %d characters", MaxBookReviewReplyLength))
+
+		return
+	}
+
+	replyToBookReviewParams := database.ReplyToBookReviewParams{
+		Reply:  replyToBookReviewParameters.Reply,
+		ID:     bookReviewId,
+		UserID: userId,
+	}
+
+	bookReview, replyToBookReviewError := bookReviewAPIConfig.DB.ReplyToBookReview(request.Context(), replyToBookReviewParams)
+
+	if replyToBookReviewError != nil {
+		if replyToBookReviewError == sql.ErrNoRows {
+			common.ErrorResponse(writer, http.StatusNotFound, "review not found on any of your books")
+		} else {
+			common.ErrorResponse(writer, http.StatusInternalServerError, "error replying to review, please try again in a few minutes")
+		}
+
+		return
+	}
+
+	common.JSONResponse(writer, http.StatusOK, DatabaseBookReviewToBookReviewJSON(bookReview))
+}
+
+// Reviews are seen by whoever can see the book. Answers for the caller and returns false
+// when the book is missing or hidden from them.
+func (bookReviewAPIConfig *BookReviewAPIConfig) checkBookViewer(writer http.ResponseWriter, request *http.Request, bookId uuid.UUID, userId uuid.UUID) bool {
+	getBook, getBookError := bookReviewAPIConfig.DB.GetBook(request.Context(), bookId)
+
+	if getBookError != nil {
+		if getBookError == sql.ErrNoRows {
+			common.ErrorResponse(writer, http.StatusNotFound, "book not found")
+		} else {
+			common.ErrorResponse(writer, http.StatusInternalServerError, "failed to get book details, please try again in a few minutes")
+		}
+
+		return false
+	}
+
+	canViewBook, checkViewerError := book_policies.CheckBookViewer(request.Context(), bookReviewAPIConfig.DB, getBook, userId)
+
+	if checkViewerError != nil {
+		common.ErrorResponse(writer, http.StatusInternalServerError, "failed to get book details, please try again in a few minutes")
+
+		return false
+	}
+
+	if !canViewBook {
+		common.ErrorResponse(writer, http.StatusNotFound, "book not found")
+
+		return false
+	}
+
+	return true
+}
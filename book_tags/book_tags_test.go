@@ -0,0 +1,195 @@
+package book_tags
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/elorenzorodz/co-library/common"
+	"github.com/elorenzorodz/co-library/internal/database"
+	"github.com/google/uuid"
+)
+
+type MockQueries struct {
+	*common.BaseMock
+
+	GetGenresFunc          func(ctx context.Context, viewerID uuid.UUID) ([]database.GetGenresRow, error)
+	GetPopularBookTagsFunc func(ctx context.Context, arg database.GetPopularBookTagsParams) ([]database.GetPopularBookTagsRow, error)
+}
+
+func (mockQueries *MockQueries) GetGenres(ctx context.Context, viewerID uuid.UUID) ([]database.GetGenresRow, error) {
+	if mockQueries.GetGenresFunc != nil {
+		return mockQueries.GetGenresFunc(ctx, viewerID)
+	}
+
+	return mockQueries.BaseMock.GetGenres(ctx, viewerID)
+}
+
+func (mockQueries *MockQueries) GetPopularBookTags(ctx context.Context, arg database.GetPopularBookTagsParams) ([]database.GetPopularBookTagsRow, error) {
+	if mockQueries.GetPopularBookTagsFunc != nil {
+		return mockQueries.GetPopularBookTagsFunc(ctx, arg)
+	}
+
+	return mockQueries.BaseMock.GetPopularBookTags(ctx, arg)
+}
+
+func TestNormalizeBookTags(tTesting *testing.T) {
+	// 1. Success: tags are lower cased, hyphenated, deduplicated and sorted.
+	tTesting.Run("Normalizes", func(t *testing.T) {
+		tags, err := NormalizeBookTags([]string{"Space  Opera", "classic", "space-opera", "Ciencia Ficción"})
+
+		if err != nil || fmt.Sprint(tags) != "[ciencia-ficción classic space-opera]" {
+			t.Errorf("Unexpected tags: %v (%v)", tags, err)
+		}
+	})
+
+	// 2. Success: no tags is an empty list, not nil.
+	tTesting.Run("Empty", func(t *testing.T) {
+		if tags, err := NormalizeBookTags([]string{}); err != nil || tags == nil || len(tags) != 0 {
+			t.Errorf("Expected an empty list, got %v (%v)", tags, err)
+		}
+	})
+
+	// 3. Failure: empty, too long, odd characters and too many tags.
+	tTesting.Run("Invalid", func(t *testing.T) {
+		tooMany := []string{}
+
+		for tagNumber := 0; tagNumber <= MaxBookTags; tagNumber++ {
+			tooMany = append(tooMany, fmt.Sprintf("tag-%d", tagNumber))
+		}
+
+		for _, tags := range [][]string{{" "}, {"a-tag-that-goes-on-for-far-too-long"}, {"sci-fi!"}, {"c#"}, tooMany} {
+			if _, err := NormalizeBookTags(tags); err == nil {
+				t.Errorf("Expected an error for %v", tags)
+			}
+		}
+	})
+}
+
+func TestNormalizeBookGenres(tTesting *testing.T) {
+	curatedGenres := []database.GetGenresRow{{Slug: "fantasy"}, {Slug: "history"}, {Slug: "horror"}, {Slug: "science-fiction"}}
+
+	// 1. Success: curated genres in any case, deduplicated and sorted.
+	tTesting.Run("Curated", func(t *testing.T) {
+		genres, err := NormalizeBookGenres([]string{"Science-Fiction", "fantasy", "FANTASY"}, curatedGenres)
+
+		if err != nil || fmt.Sprint(genres) != "[fantasy science-fiction]" {
+			t.Errorf("Unexpected genres: %v (%v)", genres, err)
+		}
+	})
+
+	// 2. Failure: unknown genres and too many of them.
+	tTesting.Run("Invalid", func(t *testing.T) {
+		for _, genres := range [][]string{{"cyberpunk"}, {"fantasy", "history", "horror", "science-fiction"}} {
+			if _, err := NormalizeBookGenres(genres, curatedGenres); err == nil {
+				t.Errorf("Expected an error for %v", genres)
+			}
+		}
+	})
+}
+
+func TestGetGenres(tTesting *testing.T) {
+	// 1. Success: the curated list with counts of the books the viewer can see.
+	tTesting.Run("Success", func(t *testing.T) {
+		viewerID := uuid.New()
+
+		mockQueries := &MockQueries{
+			BaseMock: common.NewBaseMock(),
+			GetGenresFunc: func(ctx context.Context, viewerId uuid.UUID) ([]database.GetGenresRow, error) {
+				if viewerId != viewerID {
+					t.Errorf("Expected counts for viewer %s, got %s", viewerID, viewerId)
+				}
+
+				return []database.GetGenresRow{{Slug: "fantasy", Name: "Fantasy", BookCount: 4}}, nil
+			},
+		}
+
+		apiConfig := BookTagAPIConfig{APIConfig: common.APIConfig{DB: mockQueries}}
+		request := httptest.NewRequest(http.MethodGet, "/api/v1/books/genres", nil)
+		recorder := httptest.NewRecorder()
+
+		apiConfig.GetGenres(recorder, request, viewerID)
+
+		var genres []Genre
+		json.Unmarshal(recorder.Body.Bytes(), &genres)
+
+		if recorder.Code != http.StatusOK || len(genres) != 1 || genres[0].Slug != "fantasy" || genres[0].BookCount != 4 {
+			t.Errorf("Unexpected response %d: %s", recorder.Code, recorder.Body.String())
+		}
+	})
+
+	// 2. Failure: database error.
+	tTesting.Run("InternalDBError", func(t *testing.T) {
+		mockQueries := &MockQueries{
+			BaseMock: common.NewBaseMock(),
+			GetGenresFunc: func(ctx context.Context, viewerId uuid.UUID) ([]database.GetGenresRow, error) {
+				return nil, errors.New("simulated DB connection failure")
+			},
+		}
+
+		apiConfig := BookTagAPIConfig{APIConfig: common.APIConfig{DB: mockQueries}}
+		request := httptest.NewRequest(http.MethodGet, "/api/v1/books/genres", nil)
+		recorder := httptest.NewRecorder()
+
+		apiConfig.GetGenres(recorder, request, uuid.New())
+
+		if recorder.Code != http.StatusInternalServerError {
+			t.Errorf("Expected status %d, got %d", http.StatusInternalServerError, recorder.Code)
+		}
+	})
+}
+
+func TestGetPopularBookTags(tTesting *testing.T) {
+	// 1. Success: the default limit and counts of the books the viewer can see.
+	tTesting.Run("Success", func(t *testing.T) {
+		viewerID := uuid.New()
+
+		var popularBookTagsParams database.GetPopularBookTagsParams
+
+		mockQueries := &MockQueries{
+			BaseMock: common.NewBaseMock(),
+			GetPopularBookTagsFunc: func(ctx context.Context, arg database.GetPopularBookTagsParams) ([]database.GetPopularBookTagsRow, error) {
+				popularBookTagsParams = arg
+
+				return []database.GetPopularBookTagsRow{{Tag: "space-opera", BookCount: 7}, {Tag: "desert", BookCount: 2}}, nil
+			},
+		}
+
+		apiConfig := BookTagAPIConfig{APIConfig: common.APIConfig{DB: mockQueries}}
+		request := httptest.NewRequest(http.MethodGet, "/api/v1/books/tags", nil)
+		recorder := httptest.NewRecorder()
+
+		apiConfig.GetPopularBookTags(recorder, request, viewerID)
+
+		var popularBookTags []PopularBookTag
+		json.Unmarshal(recorder.Body.Bytes(), &popularBookTags)
+
+		if recorder.Code != http.StatusOK || len(popularBookTags) != 2 || popularBookTags[0].Tag != "space-opera" || popularBookTags[0].BookCount != 7 {
+			t.Errorf("Unexpected response %d: %s", recorder.Code, recorder.Body.String())
+		}
+
+		if popularBookTagsParams.TagLimit != DefaultPopularBookTagsLimit || popularBookTagsParams.ViewerID != viewerID {
+			t.Errorf("Expected limit %d for viewer %s, got %+v", DefaultPopularBookTagsLimit, viewerID, popularBookTagsParams)
+		}
+	})
+
+	// 2. Failure: limit out of range.
+	tTesting.Run("InvalidLimit", func(t *testing.T) {
+		for _, query := range []string{"?limit=0", "?limit=101", "?limit=ten"} {
+			mockQueries := &MockQueries{BaseMock: common.NewBaseMock()}
+			apiConfig := BookTagAPIConfig{APIConfig: common.APIConfig{DB: mockQueries}}
+			request := httptest.NewRequest(http.MethodGet, "/api/v1/books/tags"+query, nil)
+			recorder := httptest.NewRecorder()
+
+			apiConfig.GetPopularBookTags(recorder, request, uuid.New())
+
+			if recorder.Code != http.StatusBadRequest {
+				t.Errorf("%s: expected status %d, got %d", query, http.StatusBadRequest, recorder.Code)
+			}
+		}
+	})
+}
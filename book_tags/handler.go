@@ -0,0 +1,147 @@
+package book_tags
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"unicode"
+
+	"github.com/elorenzorodz/co-library/common"
+	"github.com/elorenzorodz/co-library/internal/database"
+	"github.com/google/uuid"
+)
+
+func DatabaseGenresRowsToGenresJSON(databaseRows []database.GetGenresRow) []Genre {
+	genres := []Genre{}
+
+	for _, databaseRow := range databaseRows {
+		genres = append(genres, Genre{Slug: databaseRow.Slug, Name: databaseRow.Name, BookCount: databaseRow.BookCount})
+	}
+
+	return genres
+}
+
+func DatabasePopularBookTagsRowsToPopularBookTagsJSON(databaseRows []database.GetPopularBookTagsRow) []PopularBookTag {
+	popularBookTags := []PopularBookTag{}
+
+	for _, databaseRow := range databaseRows {
+		popularBookTags = append(popularBookTags, PopularBookTag{Tag: databaseRow.Tag, BookCount: databaseRow.BookCount})
+	}
+
+	return popularBookTags
+}
+
+// Tags are lower case with words joined by hyphens, so "Science Fiction" and
+// "science-fiction" end up as the same tag.
+func NormalizeBookTag(tag string) (string, error) {
+	normalizedTag := strings.Join(strings.Fields(strings.ToLower(tag)), "-")
+
+	if normalizedTag == "" {
+		return "", errors.New("tags can't be empty")
+	}
+
+	if len([]rune(normalizedTag)) > MaxBookTagLength {
+		return "", fmt.Errorf("invalid tag: %s, tags can be at most %d characters long", tag, MaxBookTagLength)
+	}
+
+	for _, character := range normalizedTag {
+		if !unicode.IsLetter(character) && !unicode.IsDigit(character) && character != '-' {
+			return "", fmt.Errorf("invalid tag: %s, use letters, digits, spaces or hyphens", tag)
+		}
+	}
+
+	return normalizedTag, nil
+}
+
+// Drops duplicate tags and sorts the rest.
+func NormalizeBookTags(tags []string) ([]string, error) {
+	normalizedTags := []string{}
+
+	for _, tag := range tags {
+		normalizedTag, normalizeError := NormalizeBookTag(tag)
+
+		if normalizeError != nil {
+			return nil, normalizeError
+		}
+
+		if !slices.Contains(normalizedTags, normalizedTag) {
+			normalizedTags = append(normalizedTags, normalizedTag)
+		}
+	}
+
+	if len(normalizedTags) > MaxBookTags {
+		return nil, fmt.Errorf("a book can have at most %d tags", MaxBookTags)
+	}
+
+	slices.Sort(normalizedTags)
+
+	return normalizedTags, nil
+}
+
+// Checks the genres against the curated list and drops duplicates.
+func NormalizeBookGenres(genres []string, curatedGenres []database.GetGenresRow) ([]string, error) {
+	normalizedGenres := []string{}
+
+	for _, genre := range genres {
+		normalizedGenre := strings.ToLower(strings.TrimSpace(genre))
+
+		isCurated := slices.ContainsFunc(curatedGenres, func(curatedGenre database.GetGenresRow) bool {
+			return curatedGenre.Slug == normalizedGenre
+		})
+
+		if !isCurated {
+			return nil, fmt.Errorf("invalid genre: %s, pick one from the genres list", genre)
+		}
+
+		if !slices.Contains(normalizedGenres, normalizedGenre) {
+			normalizedGenres = append(normalizedGenres, normalizedGenre)
+		}
+	}
+
+	if len(normalizedGenres) > MaxBookGenres {
+		return nil, fmt.Errorf("a book can have at most %d genres", MaxBookGenres)
+	}
+
+	slices.Sort(normalizedGenres)
+
+	return normalizedGenres, nil
+}
+
+// Replaces the genres and tags of a book. A nil list leaves that side as it is.
+func SetBookGenresAndTags(ctx context.Context, querier common.Querier, bookId uuid.UUID, genres []string, tags []string) error {
+	if genres != nil {
+		deleteError := querier.DeleteBookGenres(ctx, bookId)
+
+		if deleteError != nil {
+			return deleteError
+		}
+
+		if len(genres) > 0 {
+			createError := querier.CreateBookGenres(ctx, database.CreateBookGenresParams{BookID: bookId, Genres: genres})
+
+			if createError != nil {
+				return createError
+			}
+		}
+	}
+
+	if tags != nil {
+		deleteError := querier.DeleteBookTags(ctx, bookId)
+
+		if deleteError != nil {
+			return deleteError
+		}
+
+		if len(tags) > 0 {
+			createError := querier.CreateBookTags(ctx, database.CreateBookTagsParams{BookID: bookId, Tags: tags})
+
+			if createError != nil {
+				return createError
+			}
+		}
+	}
+
+	return nil
+}
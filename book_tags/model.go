@@ -0,0 +1,33 @@
+package book_tags
+
+import (
+	"github.com/elorenzorodz/co-library/common"
+)
+
+type BookTagAPIConfig struct {
+	common.APIConfig
+}
+
+// Limits on how a single book can be classified.
+const (
+	MaxBookGenres    = 3
+	MaxBookTags      = 10
+	MaxBookTagLength = 32
+)
+
+// How many tags the popular tags endpoint returns unless ?limit= says otherwise.
+const (
+	DefaultPopularBookTagsLimit = 20
+	MaxPopularBookTagsLimit     = 100
+)
+
+type Genre struct {
+	Slug      string `json:"slug"`
+	Name      string `json:"name"`
+	BookCount int64  `json:"book_count"`
+}
+
+type PopularBookTag struct {
+	Tag       string `json:"tag"`
+	BookCount int64  `json:"book_count"`
+}
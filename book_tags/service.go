@@ -0,0 +1,54 @@
+package book_tags
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/elorenzorodz/co-library/common"
+	"github.com/elorenzorodz/co-library/internal/database"
+	"github.com/google/uuid"
+)
+
+func (bookTagAPIConfig *BookTagAPIConfig) GetGenres(writer http.ResponseWriter, request *http.Request, userId uuid.UUID) {
+	genres, getGenresError := bookTagAPIConfig.DB.GetGenres(request.Context(), userId)
+
+	if getGenresError != nil {
+		common.ErrorResponse(writer, http.StatusInternalServerError, fmt.Sprintf("error getting genres: %s", getGenresError))
+
+		return
+	}
+
+	common.JSONResponse(writer, http.StatusOK, DatabaseGenresRowsToGenresJSON(genres))
+}
+
+func (bookTagAPIConfig *BookTagAPIConfig) GetPopularBookTags(writer http.ResponseWriter, request *http.Request, userId uuid.UUID) {
+	limit := int64(DefaultPopularBookTagsLimit)
+
+	if rawLimit := request.URL.Query().Get("limit"); rawLimit != "" {
+		parsedLimit, parseLimitError := strconv.ParseInt(rawLimit, 10, 32)
+
+		if parseLimitError != nil || parsedLimit < 1 || parsedLimit > MaxPopularBookTagsLimit {
+			common.ErrorResponse(writer, http.StatusBadRequest, fmt.Sprintf("limit must be between 1 and %d", MaxPopularBookTagsLimit))
+
+			return
+		}
+
+		limit = parsedLimit
+	}
+
+	getPopularBookTagsParams := database.GetPopularBookTagsParams{
+		ViewerID: userId,
+		TagLimit: int32(limit),
+	}
+
+	popularBookTags, getPopularBookTagsError := bookTagAPIConfig.DB.GetPopularBookTags(request.Context(), getPopularBookTagsParams)
+
+	if getPopularBookTagsError != nil {
+		common.ErrorResponse(writer, http.StatusInternalServerError, fmt.Sprintf("error getting popular tags: %s", getPopularBookTagsError))
+
+		return
+	}
+
+	common.JSONResponse(writer, http.StatusOK, DatabasePopularBookTagsRowsToPopularBookTagsJSON(popularBookTags))
+}
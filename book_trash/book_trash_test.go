@@ -0,0 +1,290 @@
+package book_trash
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/elorenzorodz/co-library/common"
+	"github.com/elorenzorodz/co-library/internal/database"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+type MockQueries struct {
+	*common.BaseMock
+
+	GetDeletedBooksFunc   func(ctx context.Context, arg database.GetDeletedBooksParams) ([]database.GetDeletedBooksRow, error)
+	RestoreBookFunc       func(ctx context.Context, arg database.RestoreBookParams) (database.Book, error)
+	PurgeDeletedBooksFunc func(ctx context.Context, arg database.PurgeDeletedBooksParams) ([]database.PurgeDeletedBooksRow, error)
+}
+
+func (mockQueries *MockQueries) GetDeletedBooks(ctx context.Context, arg database.GetDeletedBooksParams) ([]database.GetDeletedBooksRow, error) {
+	if mockQueries.GetDeletedBooksFunc != nil {
+		return mockQueries.GetDeletedBooksFunc(ctx, arg)
+	}
+
+	return mockQueries.BaseMock.GetDeletedBooks(ctx, arg)
+}
+
+func (mockQueries *MockQueries) RestoreBook(ctx context.Context, arg database.RestoreBookParams) (database.Book, error) {
+	if mockQueries.RestoreBookFunc != nil {
+		return mockQueries.RestoreBookFunc(ctx, arg)
+	}
+
+	return mockQueries.BaseMock.RestoreBook(ctx, arg)
+}
+
+func (mockQueries *MockQueries) PurgeDeletedBooks(ctx context.Context, arg database.PurgeDeletedBooksParams) ([]database.PurgeDeletedBooksRow, error) {
+	if mockQueries.PurgeDeletedBooksFunc != nil {
+		return mockQueries.PurgeDeletedBooksFunc(ctx, arg)
+	}
+
+	return mockQueries.BaseMock.PurgeDeletedBooks(ctx, arg)
+}
+
+// Remembers the keys it was asked to delete.
+type recordingBlobStore struct {
+	deletedKeys []string
+}
+
+func (blobStore *recordingBlobStore) Put(ctx context.Context, key string, contentType string, content []byte) (string, error) {
+	return "", errors.New("not expected")
+}
+
+func (blobStore *recordingBlobStore) Delete(ctx context.Context, key string) error {
+	blobStore.deletedKeys = append(blobStore.deletedKeys, key)
+
+	return nil
+}
+
+func newTestDeletedBook(userId uuid.UUID, deletedAt time.Time) database.Book {
+	return database.Book{
+		ID:             uuid.New(),
+		Title:          "Dune",
+		Author:         "Frank Herbert",
+		CreatedAt:      deletedAt.Add(-time.Hour),
+		UpdatedAt:      deletedAt,
+		UserID:         userId,
+		LoanPeriodDays: 14,
+		Visibility:     "public",
+		Lendable:       true,
+		BorrowerPolicy: "anyone",
+		DeletedAt:      sql.NullTime{Time: deletedAt, Valid: true},
+	}
+}
+
+func TestGetDeletedBooks(tTesting *testing.T) {
+	userId := uuid.New()
+	deletedAt := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	// 1. Success: a page of the trash with when each book will be purged, and a cursor for the next one.
+	tTesting.Run("Success", func(t *testing.T) {
+		deletedBooks := []database.GetDeletedBooksRow{
+			{Book: newTestDeletedBook(userId, deletedAt), Genres: []string{"science-fiction"}, Tags: []string{}},
+			{Book: newTestDeletedBook(userId, deletedAt.Add(-time.Hour))},
+		}
+
+		mockQueries := &MockQueries{
+			BaseMock: common.NewBaseMock(),
+			GetDeletedBooksFunc: func(ctx context.Context, arg database.GetDeletedBooksParams) ([]database.GetDeletedBooksRow, error) {
+				if arg.UserID != userId || arg.PageLimit != 2 || arg.CursorID.Valid {
+					t.Errorf("Unexpected parameters: %+v", arg)
+				}
+
+				return deletedBooks, nil
+			},
+		}
+
+		apiConfig := BookTrashAPIConfig{APIConfig: common.APIConfig{DB: mockQueries}}
+		request := httptest.NewRequest(http.MethodGet, "/api/v1/books/trash?limit=1", nil)
+		recorder := httptest.NewRecorder()
+
+		apiConfig.GetDeletedBooks(recorder, request, userId)
+
+		if recorder.Code != http.StatusOK {
+			t.Fatalf("Expected status %d, got %d. Body: %s", http.StatusOK, recorder.Code, recorder.Body.String())
+		}
+
+		var page common.Page[DeletedBook]
+
+		if err := json.Unmarshal(recorder.Body.Bytes(), &page); err != nil {
+			t.Fatalf("Error decoding response: %v", err)
+		}
+
+		if len(page.Data) != 1 || page.NextCursor == nil || page.Data[0].ID != deletedBooks[0].Book.ID || len(page.Data[0].Genres) != 1 {
+			t.Fatalf("Unexpected page: %+v", page)
+		}
+
+		if !page.Data[0].DeletedAt.Equal(deletedAt) || !page.Data[0].PurgeAt.Equal(deletedAt.Add(BookTrashRetention)) {
+			t.Errorf("Unexpected deletion times: %v, %v", page.Data[0].DeletedAt, page.Data[0].PurgeAt)
+		}
+
+		// 1a. The cursor picks up after the last book shown.
+		mockQueries.GetDeletedBooksFunc = func(ctx context.Context, arg database.GetDeletedBooksParams) ([]database.GetDeletedBooksRow, error) {
+			if arg.CursorID.UUID != deletedBooks[0].Book.ID || !arg.CursorDeletedAt.Equal(deletedAt) {
+				t.Errorf("Unexpected cursor: %+v", arg)
+			}
+
+			return deletedBooks[1:], nil
+		}
+
+		request = httptest.NewRequest(http.MethodGet, "/api/v1/books/trash?limit=1&cursor="+*page.NextCursor, nil)
+		recorder = httptest.NewRecorder()
+
+		apiConfig.GetDeletedBooks(recorder, request, userId)
+
+		if recorder.Code != http.StatusOK {
+			t.Errorf("Expected status %d, got %d. Body: %s", http.StatusOK, recorder.Code, recorder.Body.String())
+		}
+	})
+
+	// 2. Failure: a cursor that doesn't hold a deletion time.
+	tTesting.Run("InvalidCursor", func(t *testing.T) {
+		apiConfig := BookTrashAPIConfig{APIConfig: common.APIConfig{DB: &MockQueries{BaseMock: common.NewBaseMock()}}}
+		cursor := common.EncodeCursor(common.Cursor{Value: "Dune", ID: uuid.New()})
+		request := httptest.NewRequest(http.MethodGet, "/api/v1/books/trash?cursor="+cursor, nil)
+		recorder := httptest.NewRecorder()
+
+		apiConfig.GetDeletedBooks(recorder, request, userId)
+
+		if recorder.Code != http.StatusBadRequest {
+			t.Errorf("Expected status %d, got %d. Body: %s", http.StatusBadRequest, recorder.Code, recorder.Body.String())
+		}
+	})
+}
+
+func TestRestoreBook(tTesting *testing.T) {
+	userId := uuid.New()
+	deletedBook := newTestDeletedBook(userId, time.Now().UTC())
+
+	// 1. Success: the book is back with its genres and tags.
+	tTesting.Run("Success", func(t *testing.T) {
+		mockQueries := &MockQueries{
+			BaseMock: common.NewBaseMock(),
+			RestoreBookFunc: func(ctx context.Context, arg database.RestoreBookParams) (database.Book, error) {
+				if arg.ID != deletedBook.ID || arg.UserID != userId {
+					t.Errorf("Unexpected parameters: %+v", arg)
+				}
+
+				restoredBook := deletedBook
+				restoredBook.DeletedAt = sql.NullTime{}
+
+				return restoredBook, nil
+			},
+		}
+
+		apiConfig := BookTrashAPIConfig{APIConfig: common.APIConfig{DB: mockQueries}}
+		request := httptest.NewRequest(http.MethodPatch, fmt.Sprintf("/api/v1/books/%s/restore", deletedBook.ID), nil)
+		request = mux.SetURLVars(request, map[string]string{"bookId": deletedBook.ID.String()})
+		recorder := httptest.NewRecorder()
+
+		apiConfig.RestoreBook(recorder, request, userId)
+
+		if recorder.Code != http.StatusOK {
+			t.Errorf("Expected status %d, got %d. Body: %s", http.StatusOK, recorder.Code, recorder.Body.String())
+		}
+	})
+
+	// 2. Failure: not in the caller's trash.
+	tTesting.Run("NotFound", func(t *testing.T) {
+		apiConfig := BookTrashAPIConfig{APIConfig: common.APIConfig{DB: &MockQueries{BaseMock: common.NewBaseMock()}}}
+		request := httptest.NewRequest(http.MethodPatch, fmt.Sprintf("/api/v1/books/%s/restore", deletedBook.ID), nil)
+		request = mux.SetURLVars(request, map[string]string{"bookId": deletedBook.ID.String()})
+		recorder := httptest.NewRecorder()
+
+		apiConfig.RestoreBook(recorder, request, uuid.New())
+
+		if recorder.Code != http.StatusNotFound {
+			t.Errorf("Expected status %d, got %d. Body: %s", http.StatusNotFound, recorder.Code, recorder.Body.String())
+		}
+	})
+
+	// 3. Failure: invalid book id.
+	tTesting.Run("InvalidBookID", func(t *testing.T) {
+		apiConfig := BookTrashAPIConfig{APIConfig: common.APIConfig{DB: &MockQueries{BaseMock: common.NewBaseMock()}}}
+		request := httptest.NewRequest(http.MethodPatch, "/api/v1/books/not-a-uuid/restore", nil)
+		request = mux.SetURLVars(request, map[string]string{"bookId": "not-a-uuid"})
+		recorder := httptest.NewRecorder()
+
+		apiConfig.RestoreBook(recorder, request, userId)
+
+		if recorder.Code != http.StatusBadRequest {
+			t.Errorf("Expected status %d, got %d. Body: %s", http.StatusBadRequest, recorder.Code, recorder.Body.String())
+		}
+	})
+}
+
+func TestPurgeDeletedBooks(tTesting *testing.T) {
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	// 1. Success: full batches are followed by another until one comes back short, and covers and condition photos are removed.
+	tTesting.Run("Success", func(t *testing.T) {
+		var batches int
+
+		mockQueries := &MockQueries{
+			BaseMock: common.NewBaseMock(),
+			PurgeDeletedBooksFunc: func(ctx context.Context, arg database.PurgeDeletedBooksParams) ([]database.PurgeDeletedBooksRow, error) {
+				if !arg.DeletedBefore.Equal(now.Add(-BookTrashRetention)) || arg.BatchSize != BookTrashPurgeBatchSize {
+					t.Errorf("Unexpected parameters: %+v", arg)
+				}
+
+				batches++
+
+				if batches == 1 {
+					purgedBooks := make([]database.PurgeDeletedBooksRow, BookTrashPurgeBatchSize)
+					purgedBooks[0].CoverKeys = []string{"covers/a.jpg", "covers/a-thumbnail.jpg"}
+
+					return purgedBooks, nil
+				}
+
+				return []database.PurgeDeletedBooksRow{{CoverKeys: []string{"covers/b.png"}, ConditionPhotoKeys: []string{"condition-reports/c.jpg"}}}, nil
+			},
+		}
+
+		blobStore := &recordingBlobStore{}
+		apiConfig := &common.APIConfig{DB: mockQueries, Blobs: blobStore}
+
+		if err := PurgeDeletedBooks(context.Background(), apiConfig, now); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		if batches != 2 || len(blobStore.deletedKeys) != 4 || blobStore.deletedKeys[2] != "covers/b.png" || blobStore.deletedKeys[3] != "condition-reports/c.jpg" {
+			t.Errorf("Expected 2 batches and 4 blobs removed, got %d and %v", batches, blobStore.deletedKeys)
+		}
+	})
+
+	// 1a. Success: without a blob store only the rows are purged.
+	tTesting.Run("NoBlobStore", func(t *testing.T) {
+		mockQueries := &MockQueries{
+			BaseMock: common.NewBaseMock(),
+			PurgeDeletedBooksFunc: func(ctx context.Context, arg database.PurgeDeletedBooksParams) ([]database.PurgeDeletedBooksRow, error) {
+				return []database.PurgeDeletedBooksRow{{CoverKeys: []string{"covers/a.jpg"}}}, nil
+			},
+		}
+
+		if err := PurgeDeletedBooks(context.Background(), &common.APIConfig{DB: mockQueries}, now); err != nil {
+			t.Errorf("Expected no error, got %v", err)
+		}
+	})
+
+	// 2. Failure: the purge query fails.
+	tTesting.Run("PurgeError", func(t *testing.T) {
+		mockQueries := &MockQueries{
+			BaseMock: common.NewBaseMock(),
+			PurgeDeletedBooksFunc: func(ctx context.Context, arg database.PurgeDeletedBooksParams) ([]database.PurgeDeletedBooksRow, error) {
+				return nil, errors.New("connection reset")
+			},
+		}
+
+		if err := PurgeDeletedBooks(context.Background(), &common.APIConfig{DB: mockQueries}, now); err == nil {
+			t.Error("Expected an error")
+		}
+	})
+}
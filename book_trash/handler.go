@@ -0,0 +1,89 @@
+package book_trash
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/elorenzorodz/co-library/book_claims"
+	"github.com/elorenzorodz/co-library/book_covers"
+	"github.com/elorenzorodz/co-library/books"
+	"github.com/elorenzorodz/co-library/common"
+	"github.com/elorenzorodz/co-library/internal/database"
+)
+
+func DatabaseGetDeletedBooksRowsToDeletedBooksJSON(databaseGetDeletedBooksRows []database.GetDeletedBooksRow) []DeletedBook {
+	deletedBooks := []DeletedBook{}
+
+	for _, databaseGetDeletedBooksRow := range databaseGetDeletedBooksRows {
+		book := books.DatabaseBookToBookJSON(databaseGetDeletedBooksRow.Book)
+		book.Genres = append(book.Genres, databaseGetDeletedBooksRow.Genres...)
+		book.Tags = append(book.Tags, databaseGetDeletedBooksRow.Tags...)
+
+		deletedAt := databaseGetDeletedBooksRow.Book.DeletedAt.Time
+
+		deletedBooks = append(deletedBooks, DeletedBook{
+			Book:      book,
+			DeletedAt: deletedAt,
+			PurgeAt:   deletedAt.Add(BookTrashRetention),
+		})
+	}
+
+	return deletedBooks
+}
+
+func DeletedBookCursor(databaseGetDeletedBooksRow database.GetDeletedBooksRow) common.Cursor {
+	return common.Cursor{
+		Value: databaseGetDeletedBooksRow.Book.DeletedAt.Time.Format(time.RFC3339Nano),
+		ID:    databaseGetDeletedBooksRow.Book.ID,
+	}
+}
+
+// Sets the keyset parameters from a cursor returned by an earlier page of the trash.
+func SetDeletedBooksCursor(getDeletedBooksParams *database.GetDeletedBooksParams, pageParameters common.PageParameters) error {
+	if pageParameters.Cursor == nil {
+		return nil
+	}
+
+	cursorDeletedAt, parseError := time.Parse(time.RFC3339Nano, pageParameters.Cursor.Value)
+
+	if parseError != nil {
+		return errors.New("invalid cursor")
+	}
+
+	getDeletedBooksParams.CursorID = pageParameters.CursorID()
+	getDeletedBooksParams.CursorDeletedAt = cursorDeletedAt
+
+	return nil
+}
+
+// Hard-deletes the books that have been in the trash longer than the retention window, in
+// batches, then removes their uploaded covers and condition report photos. A book that
+// somehow went out on loan again is left alone until it is back.
+func PurgeDeletedBooks(ctx context.Context, apiConfig *common.APIConfig, now time.Time) error {
+	purgeDeletedBooksParams := database.PurgeDeletedBooksParams{
+		DeletedBefore: now.Add(-BookTrashRetention),
+		BatchSize:     BookTrashPurgeBatchSize,
+	}
+
+	for {
+		purgedBooks, purgeError := apiConfig.DB.PurgeDeletedBooks(ctx, purgeDeletedBooksParams)
+
+		if purgeError != nil {
+			return fmt.Errorf("error purging deleted books: %w", purgeError)
+		}
+
+		// The rows are gone by now, so a blob that fails to delete is only logged.
+		if apiConfig.Blobs != nil {
+			for _, purgedBook := range purgedBooks {
+				book_covers.DeleteBookCoverBlobs(ctx, apiConfig.Blobs, purgedBook.CoverKeys)
+				book_claims.DeleteBookConditionPhotoBlobs(ctx, apiConfig.Blobs, purgedBook.ConditionPhotoKeys)
+			}
+		}
+
+		if len(purgedBooks) < BookTrashPurgeBatchSize {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,27 @@
+package book_trash
+
+import (
+	"time"
+
+	"github.com/elorenzorodz/co-library/books"
+	"github.com/elorenzorodz/co-library/common"
+)
+
+type BookTrashAPIConfig struct {
+	common.APIConfig
+}
+
+const (
+	// How long a deleted book can be restored before it is purged for good.
+	BookTrashRetention     = 30 * 24 * time.Hour
+	BookTrashPurgeInterval = time.Hour
+	// Books purged per statement, so a run never holds locks on many books at once.
+	BookTrashPurgeBatchSize = 100
+)
+
+type DeletedBook struct {
+	books.Book
+	DeletedAt time.Time `json:"deletedAt"`
+	// When the purge job removes the book along with its loan history.
+	PurgeAt time.Time `json:"purgeAt"`
+}
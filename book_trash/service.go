@@ -0,0 +1,84 @@
+package book_trash
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+
+	"github.com/elorenzorodz/co-library/book_covers"
+	"github.com/elorenzorodz/co-library/common"
+	"github.com/elorenzorodz/co-library/internal/database"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+func (bookTrashAPIConfig *BookTrashAPIConfig) GetDeletedBooks(writer http.ResponseWriter, request *http.Request, userId uuid.UUID) {
+	pageParameters, parsePageError := common.ParsePageParameters(request.URL.Query())
+
+	if parsePageError != nil {
+		common.ErrorResponse(writer, http.StatusBadRequest, parsePageError.Error())
+
+		return
+	}
+
+	getDeletedBooksParams := database.GetDeletedBooksParams{
+		UserID:    userId,
+		PageLimit: pageParameters.QueryLimit(),
+	}
+
+	if setCursorError := SetDeletedBooksCursor(&getDeletedBooksParams, pageParameters); setCursorError != nil {
+		common.ErrorResponse(writer, http.StatusBadRequest, setCursorError.Error())
+
+		return
+	}
+
+	getDeletedBooks, getDeletedBooksError := bookTrashAPIConfig.DB.GetDeletedBooks(request.Context(), getDeletedBooksParams)
+
+	if getDeletedBooksError != nil {
+		common.ErrorResponse(writer, http.StatusInternalServerError, fmt.Sprintf("error getting deleted books: %s", getDeletedBooksError))
+
+		return
+	}
+
+	getDeletedBooks, nextCursor := common.SplitPage(getDeletedBooks, pageParameters, DeletedBookCursor)
+
+	common.JSONResponse(writer, http.StatusOK, common.Page[DeletedBook]{Data: DatabaseGetDeletedBooksRowsToDeletedBooksJSON(getDeletedBooks), NextCursor: nextCursor})
+}
+
+func (bookTrashAPIConfig *BookTrashAPIConfig) RestoreBook(writer http.ResponseWriter, request *http.Request, userId uuid.UUID) {
+	vars := mux.Vars(request)
+	bookId, parseBookIdError := uuid.Parse(vars["bookId"])
+
+	if parseBookIdError != nil {
+		common.ErrorResponse(writer, http.StatusBadRequest, "invalid book id")
+
+		return
+	}
+
+	restoreBookParams := database.RestoreBookParams{
+		ID:     bookId,
+		UserID: userId,
+	}
+
+	restoredBook, restoreBookError := bookTrashAPIConfig.DB.RestoreBook(request.Context(), restoreBookParams)
+
+	if restoreBookError != nil {
+		if restoreBookError == sql.ErrNoRows {
+			common.ErrorResponse(writer, http.StatusNotFound, "book not found in trash")
+		} else {
+			common.ErrorResponse(writer, http.StatusInternalServerError, fmt.Sprintf("error restoring book: %s", restoreBookError))
+		}
+
+		return
+	}
+
+	book, getBookError := book_covers.GetBookWithGenresAndTags(request.Context(), bookTrashAPIConfig.DB, restoredBook)
+
+	if getBookError != nil {
+		common.ErrorResponse(writer, http.StatusInternalServerError, "failed to get book details, please try again in a few minutes")
+
+		return
+	}
+
+	common.JSONResponse(writer, http.StatusOK, book)
+}
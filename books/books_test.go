@@ -9,11 +9,14 @@ import (
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"testing"
 	"time"
 
+	"github.com/elorenzorodz/co-library/catalog_providers"
 	"github.com/elorenzorodz/co-library/common"
 	"github.com/elorenzorodz/co-library/internal/database"
+	"github.com/elorenzorodz/co-library/notification_templates"
 	"github.com/google/uuid"
 	"github.com/gorilla/mux"
 )
@@ -25,8 +28,51 @@ type MockQueries struct {
 	GetBookFunc     func(ctx context.Context, id uuid.UUID) (database.Book, error)
 	UpdateBookFunc  func(ctx context.Context, arg database.UpdateBookParams) (database.Book, error)
 	DeleteBookFunc  func(ctx context.Context, arg database.DeleteBookParams) (int64, error)
-	GetBooksFunc    func(ctx context.Context, userID uuid.UUID) ([]database.Book, error)
-	BrowseBooksFunc func(ctx context.Context) ([]database.Book, error)
+	GetBooksFunc    func(ctx context.Context, arg database.GetBooksParams) ([]database.GetBooksRow, error)
+	BrowseBooksFunc func(ctx context.Context, arg database.BrowseBooksParams) ([]database.BrowseBooksRow, error)
+
+	CreateBookCopyFunc func(ctx context.Context, arg database.CreateBookCopyParams) (database.BookCopy, error)
+
+	GetBookWithAvailabilityFunc func(ctx context.Context, id uuid.UUID) (database.GetBookWithAvailabilityRow, error)
+
+	CreateBookGenresFunc     func(ctx context.Context, arg database.CreateBookGenresParams) error
+	CreateBookTagsFunc       func(ctx context.Context, arg database.CreateBookTagsParams) error
+	GetBookGenresAndTagsFunc func(ctx context.Context, id uuid.UUID) (database.GetBookGenresAndTagsRow, error)
+
+	GetShelfFunc func(ctx context.Context, arg database.GetShelfParams) (database.GetShelfRow, error)
+
+	DeclineOpenBookBorrowRequestsFunc func(ctx context.Context, bookID uuid.UUID) error
+	CancelBookReservationsFunc        func(ctx context.Context, bookID uuid.UUID) error
+
+	GetMemberRelationshipFunc func(ctx context.Context, arg database.GetMemberRelationshipParams) (database.GetMemberRelationshipRow, error)
+
+	GetUsersBySubscriberIDFunc   func(ctx context.Context, userID uuid.UUID) ([]database.User, error)
+	GetUserByIDFunc              func(ctx context.Context, id uuid.UUID) (database.User, error)
+	CreateOutboxNotificationFunc func(ctx context.Context, arg database.CreateOutboxNotificationParams) (database.NotificationOutbox, error)
+}
+
+func (mockQueries *MockQueries) GetUsersBySubscriberID(ctx context.Context, userID uuid.UUID) ([]database.User, error) {
+	if mockQueries.GetUsersBySubscriberIDFunc != nil {
+		return mockQueries.GetUsersBySubscriberIDFunc(ctx, userID)
+	}
+
+	return mockQueries.BaseMock.GetUsersBySubscriberID(ctx, userID)
+}
+
+func (mockQueries *MockQueries) GetUserByID(ctx context.Context, id uuid.UUID) (database.User, error) {
+	if mockQueries.GetUserByIDFunc != nil {
+		return mockQueries.GetUserByIDFunc(ctx, id)
+	}
+
+	return mockQueries.BaseMock.GetUserByID(ctx, id)
+}
+
+func (mockQueries *MockQueries) CreateOutboxNotification(ctx context.Context, arg database.CreateOutboxNotificationParams) (database.NotificationOutbox, error) {
+	if mockQueries.CreateOutboxNotificationFunc != nil {
+		return mockQueries.CreateOutboxNotificationFunc(ctx, arg)
+	}
+
+	return mockQueries.BaseMock.CreateOutboxNotification(ctx, arg)
 }
 
 func (mockQueries *MockQueries) CreateBook(ctx context.Context, arg database.CreateBookParams) (database.Book, error) {
@@ -37,6 +83,38 @@ func (mockQueries *MockQueries) CreateBook(ctx context.Context, arg database.Cre
 	return mockQueries.BaseMock.CreateBook(ctx, arg)
 }
 
+func (mockQueries *MockQueries) CreateBookGenres(ctx context.Context, arg database.CreateBookGenresParams) error {
+	if mockQueries.CreateBookGenresFunc != nil {
+		return mockQueries.CreateBookGenresFunc(ctx, arg)
+	}
+
+	return mockQueries.BaseMock.CreateBookGenres(ctx, arg)
+}
+
+func (mockQueries *MockQueries) CreateBookTags(ctx context.Context, arg database.CreateBookTagsParams) error {
+	if mockQueries.CreateBookTagsFunc != nil {
+		return mockQueries.CreateBookTagsFunc(ctx, arg)
+	}
+
+	return mockQueries.BaseMock.CreateBookTags(ctx, arg)
+}
+
+func (mockQueries *MockQueries) GetBookGenresAndTags(ctx context.Context, id uuid.UUID) (database.GetBookGenresAndTagsRow, error) {
+	if mockQueries.GetBookGenresAndTagsFunc != nil {
+		return mockQueries.GetBookGenresAndTagsFunc(ctx, id)
+	}
+
+	return mockQueries.BaseMock.GetBookGenresAndTags(ctx, id)
+}
+
+func (mockQueries *MockQueries) CreateBookCopy(ctx context.Context, arg database.CreateBookCopyParams) (database.BookCopy, error) {
+	if mockQueries.CreateBookCopyFunc != nil {
+		return mockQueries.CreateBookCopyFunc(ctx, arg)
+	}
+
+	return mockQueries.BaseMock.CreateBookCopy(ctx, arg)
+}
+
 func (mockQueries *MockQueries) GetBook(ctx context.Context, id uuid.UUID) (database.Book, error) {
 	if mockQueries.GetBookFunc != nil {
 		return mockQueries.GetBookFunc(ctx, id)
@@ -61,20 +139,60 @@ func (mockQueries *MockQueries) DeleteBook(ctx context.Context, arg database.Del
 	return mockQueries.BaseMock.DeleteBook(ctx, arg)
 }
 
-func (mockQueries *MockQueries) GetBooks(ctx context.Context, userID uuid.UUID) ([]database.Book, error) {
+func (mockQueries *MockQueries) DeclineOpenBookBorrowRequests(ctx context.Context, bookID uuid.UUID) error {
+	if mockQueries.DeclineOpenBookBorrowRequestsFunc != nil {
+		return mockQueries.DeclineOpenBookBorrowRequestsFunc(ctx, bookID)
+	}
+
+	return mockQueries.BaseMock.DeclineOpenBookBorrowRequests(ctx, bookID)
+}
+
+func (mockQueries *MockQueries) CancelBookReservations(ctx context.Context, bookID uuid.UUID) error {
+	if mockQueries.CancelBookReservationsFunc != nil {
+		return mockQueries.CancelBookReservationsFunc(ctx, bookID)
+	}
+
+	return mockQueries.BaseMock.CancelBookReservations(ctx, bookID)
+}
+
+func (mockQueries *MockQueries) GetBooks(ctx context.Context, arg database.GetBooksParams) ([]database.GetBooksRow, error) {
 	if mockQueries.GetBooksFunc != nil {
-		return mockQueries.GetBooksFunc(ctx, userID)
+		return mockQueries.GetBooksFunc(ctx, arg)
+	}
+
+	return mockQueries.BaseMock.GetBooks(ctx, arg)
+}
+
+func (mockQueries *MockQueries) GetBookWithAvailability(ctx context.Context, id uuid.UUID) (database.GetBookWithAvailabilityRow, error) {
+	if mockQueries.GetBookWithAvailabilityFunc != nil {
+		return mockQueries.GetBookWithAvailabilityFunc(ctx, id)
 	}
 
-	return mockQueries.BaseMock.GetBooks(ctx, userID)
+	return mockQueries.BaseMock.GetBookWithAvailability(ctx, id)
 }
 
-func (mockQueries *MockQueries) BrowseBooks(ctx context.Context) ([]database.Book, error) {
+func (mockQueries *MockQueries) BrowseBooks(ctx context.Context, arg database.BrowseBooksParams) ([]database.BrowseBooksRow, error) {
 	if mockQueries.BrowseBooksFunc != nil {
-		return mockQueries.BrowseBooksFunc(ctx)
+		return mockQueries.BrowseBooksFunc(ctx, arg)
+	}
+
+	return mockQueries.BaseMock.BrowseBooks(ctx, arg)
+}
+
+func (mockQueries *MockQueries) GetShelf(ctx context.Context, arg database.GetShelfParams) (database.GetShelfRow, error) {
+	if mockQueries.GetShelfFunc != nil {
+		return mockQueries.GetShelfFunc(ctx, arg)
 	}
 
-	return mockQueries.BaseMock.BrowseBooks(ctx)
+	return mockQueries.BaseMock.GetShelf(ctx, arg)
+}
+
+func (mockQueries *MockQueries) GetMemberRelationship(ctx context.Context, arg database.GetMemberRelationshipParams) (database.GetMemberRelationshipRow, error) {
+	if mockQueries.GetMemberRelationshipFunc != nil {
+		return mockQueries.GetMemberRelationshipFunc(ctx, arg)
+	}
+
+	return mockQueries.BaseMock.GetMemberRelationship(ctx, arg)
 }
 
 func newTestUserID() uuid.UUID {
@@ -89,9 +207,22 @@ func newTestBook(userId uuid.UUID) database.Book {
 		UserID:    userId,
 		CreatedAt: time.Now(),
 		UpdatedAt: time.Now(),
+		Visibility:     "public",
+		Lendable:       true,
+		BorrowerPolicy: "anyone",
 	}
 }
 
+func createTestBookCopy(ctx context.Context, arg database.CreateBookCopyParams) (database.BookCopy, error) {
+	return database.BookCopy{ID: arg.ID, Condition: arg.Condition, BookID: arg.BookID}, nil
+}
+
+type failingCatalogProvider struct{}
+
+func (failingCatalogProvider) LookupISBN(ctx context.Context, isbn string) (common.BookMetadata, error) {
+	return common.BookMetadata{}, errors.New("catalog unreachable")
+}
+
 func TestCreateBook(tTesting *testing.T) {
 	userId := newTestUserID()
 	testBook := newTestBook(userId)
@@ -105,16 +236,246 @@ func TestCreateBook(tTesting *testing.T) {
 	tTesting.Run("Success", func(t *testing.T) {
 		mockQueries := &MockQueries{
 			BaseMock: common.NewBaseMock(),
+			CreateBookCopyFunc: createTestBookCopy,
 			CreateBookFunc: func(ctx context.Context, arg database.CreateBookParams) (database.Book, error) {
 				if arg.UserID != userId {
 					t.Errorf("Expected UserID %s, got %s", userId, arg.UserID)
 				}
 
+				// 0 is filled in with the owner's default loan period by the database.
+				if arg.LoanPeriodDays != 0 {
+					t.Errorf("Expected the owner's default loan period, got %d", arg.LoanPeriodDays)
+				}
+
+				if arg.Visibility != "public" || !arg.Lendable || arg.BorrowerPolicy != "anyone" {
+					t.Errorf("Expected a public book anyone can borrow, got %q, %t, %q", arg.Visibility, arg.Lendable, arg.BorrowerPolicy)
+				}
+
+				return testBook, nil
+			},
+		}
+
+		apiConfig := BookAPIConfig{APIConfig: common.APIConfig{DB: mockQueries}}
+
+		requestBody, _ := json.Marshal(createBookRequest{
+			Title:  testBook.Title,
+			Author: testBook.Author,
+		})
+
+		request := httptest.NewRequest(http.MethodPost, "/api/v1/books", bytes.NewBuffer(requestBody))
+
+		recorder := httptest.NewRecorder()
+
+		apiConfig.CreateBook(recorder, request, userId)
+
+		if recorder.Code != http.StatusCreated {
+			t.Errorf("Expected status %d, got %d. Body: %s", http.StatusCreated, recorder.Code, recorder.Body.String())
+		}
+	})
+
+	// 1a. Success: the owner puts several copies on the shelf at once.
+	tTesting.Run("Copies", func(t *testing.T) {
+		var conditions []string
+
+		mockQueries := &MockQueries{
+			BaseMock: common.NewBaseMock(),
+			CreateBookFunc: func(ctx context.Context, arg database.CreateBookParams) (database.Book, error) {
+				return testBook, nil
+			},
+			CreateBookCopyFunc: func(ctx context.Context, arg database.CreateBookCopyParams) (database.BookCopy, error) {
+				if arg.BookID != testBook.ID {
+					t.Errorf("Expected copy of book %s, got %s", testBook.ID, arg.BookID)
+				}
+
+				conditions = append(conditions, arg.Condition)
+
+				return createTestBookCopy(ctx, arg)
+			},
+		}
+
+		apiConfig := BookAPIConfig{APIConfig: common.APIConfig{DB: mockQueries}}
+		request := httptest.NewRequest(http.MethodPost, "/api/v1/books", bytes.NewBufferString(`{"title": "Dune", "author": "Frank Herbert", "copies": 3}`))
+		recorder := httptest.NewRecorder()
+
+		apiConfig.CreateBook(recorder, request, userId)
+
+		if recorder.Code != http.StatusCreated {
+			t.Fatalf("Expected status %d, got %d. Body: %s", http.StatusCreated, recorder.Code, recorder.Body.String())
+		}
+
+		var book Book
+		json.Unmarshal(recorder.Body.Bytes(), &book)
+
+		if len(conditions) != 3 || conditions[0] != "good" || book.Availability == nil || book.Availability.TotalCopies != 3 || book.Availability.AvailableCopies != 3 {
+			t.Errorf("Expected 3 copies in good condition, got %v and %+v", conditions, book.Availability)
+		}
+	})
+
+	// 1aa. Failure: copies out of range.
+	tTesting.Run("InvalidCopies", func(t *testing.T) {
+		for _, copies := range []string{"-1", "21"} {
+			mockQueries := &MockQueries{BaseMock: common.NewBaseMock()}
+
+			apiConfig := BookAPIConfig{APIConfig: common.APIConfig{DB: mockQueries}}
+			request := httptest.NewRequest(http.MethodPost, "/api/v1/books", bytes.NewBufferString(`{"title": "Dune", "author": "Frank Herbert", "copies": `+copies+`}`))
+			recorder := httptest.NewRecorder()
+
+			apiConfig.CreateBook(recorder, request, userId)
+
+			if recorder.Code != http.StatusBadRequest {
+				t.Errorf("Expected status %d for %s copies, got %d", http.StatusBadRequest, copies, recorder.Code)
+			}
+		}
+	})
+
+	// 1ab. Success: genres from the curated list and normalized tags.
+	tTesting.Run("GenresAndTags", func(t *testing.T) {
+		var createBookGenresParams database.CreateBookGenresParams
+		var createBookTagsParams database.CreateBookTagsParams
+
+		mockQueries := &MockQueries{
+			BaseMock:           common.NewBaseMock(),
+			CreateBookCopyFunc: createTestBookCopy,
+			CreateBookFunc: func(ctx context.Context, arg database.CreateBookParams) (database.Book, error) {
 				return testBook, nil
 			},
+			CreateBookGenresFunc: func(ctx context.Context, arg database.CreateBookGenresParams) error {
+				createBookGenresParams = arg
+
+				return nil
+			},
+			CreateBookTagsFunc: func(ctx context.Context, arg database.CreateBookTagsParams) error {
+				createBookTagsParams = arg
+
+				return nil
+			},
+		}
+
+		apiConfig := BookAPIConfig{APIConfig: common.APIConfig{DB: mockQueries}}
+		request := httptest.NewRequest(http.MethodPost, "/api/v1/books", bytes.NewBufferString(`{"title": "Dune", "author": "Frank Herbert", "genres": ["Science-Fiction"], "tags": ["Space Opera", "desert", "space-opera"]}`))
+		recorder := httptest.NewRecorder()
+
+		apiConfig.CreateBook(recorder, request, userId)
+
+		if recorder.Code != http.StatusCreated {
+			t.Fatalf("Expected status %d, got %d. Body: %s", http.StatusCreated, recorder.Code, recorder.Body.String())
+		}
+
+		if createBookGenresParams.BookID != testBook.ID || fmt.Sprint(createBookGenresParams.Genres) != "[science-fiction]" {
+			t.Errorf("Unexpected genres: %+v", createBookGenresParams)
+		}
+
+		if createBookTagsParams.BookID != testBook.ID || fmt.Sprint(createBookTagsParams.Tags) != "[desert space-opera]" {
+			t.Errorf("Unexpected tags: %+v", createBookTagsParams)
+		}
+
+		var book Book
+		json.Unmarshal(recorder.Body.Bytes(), &book)
+
+		if fmt.Sprint(book.Genres) != "[science-fiction]" || fmt.Sprint(book.Tags) != "[desert space-opera]" {
+			t.Errorf("Expected the genres and tags in the response, got %+v and %+v", book.Genres, book.Tags)
+		}
+	})
+
+	// 1ac. Failure: genres outside the curated list and malformed tags.
+	tTesting.Run("InvalidGenresAndTags", func(t *testing.T) {
+		tooManyTags, _ := json.Marshal([]string{"a", "b", "c", "d", "e", "f", "g", "h", "i", "j", "k"})
+
+		for _, classification := range []string{`"genres": ["cyberpunk"]`, `"tags": ["sci-fi!"]`, `"tags": ["  "]`, `"tags": ` + string(tooManyTags)} {
+			mockQueries := &MockQueries{BaseMock: common.NewBaseMock()}
+			apiConfig := BookAPIConfig{APIConfig: common.APIConfig{DB: mockQueries}}
+			request := httptest.NewRequest(http.MethodPost, "/api/v1/books", bytes.NewBufferString(`{"title": "Dune", "author": "Frank Herbert", `+classification+`}`))
+			recorder := httptest.NewRecorder()
+
+			apiConfig.CreateBook(recorder, request, userId)
+
+			if recorder.Code != http.StatusBadRequest {
+				t.Errorf("%s: expected status %d, got %d", classification, http.StatusBadRequest, recorder.Code)
+			}
+		}
+	})
+
+	// 1ad. Success: the owner picks who can find and borrow the book.
+	tTesting.Run("Policies", func(t *testing.T) {
+		var createBookParams database.CreateBookParams
+
+		mockQueries := &MockQueries{
+			BaseMock: common.NewBaseMock(),
+			CreateBookCopyFunc: createTestBookCopy,
+			CreateBookFunc: func(ctx context.Context, arg database.CreateBookParams) (database.Book, error) {
+				createBookParams = arg
+
+				return database.Book{ID: arg.ID, Title: arg.Title, Author: arg.Author, UserID: arg.UserID, Visibility: arg.Visibility, Lendable: arg.Lendable, BorrowerPolicy: arg.BorrowerPolicy}, nil
+			},
 		}
 
 		apiConfig := BookAPIConfig{APIConfig: common.APIConfig{DB: mockQueries}}
+		request := httptest.NewRequest(http.MethodPost, "/api/v1/books", bytes.NewBufferString(`{"title": "Dune", "author": "Frank Herbert", "visibility": "subscribers", "lendable": false, "borrower_policy": "approved", "min_borrower_reputation": 70}`))
+		recorder := httptest.NewRecorder()
+
+		apiConfig.CreateBook(recorder, request, userId)
+
+		if recorder.Code != http.StatusCreated {
+			t.Fatalf("Expected status %d, got %d. Body: %s", http.StatusCreated, recorder.Code, recorder.Body.String())
+		}
+
+		var book Book
+		json.Unmarshal(recorder.Body.Bytes(), &book)
+
+		if createBookParams.Visibility != "subscribers" || createBookParams.Lendable || createBookParams.BorrowerPolicy != "approved" || book.Visibility != "subscribers" || book.BorrowerPolicy != "approved" {
+			t.Errorf("Unexpected policies: %+v, %+v", createBookParams, book)
+		}
+
+		if createBookParams.MinBorrowerReputation != 70 {
+			t.Errorf("Expected a minimum borrower reputation of 70, got %d", createBookParams.MinBorrowerReputation)
+		}
+	})
+
+	// 1ae. Failure: unknown visibility or borrower policy, or a minimum reputation out of range.
+	tTesting.Run("InvalidPolicies", func(t *testing.T) {
+		for _, policy := range []string{`"visibility": "friends"`, `"borrower_policy": "everyone"`, `"min_borrower_reputation": 101`, `"min_borrower_reputation": -5`} {
+			mockQueries := &MockQueries{BaseMock: common.NewBaseMock()}
+			apiConfig := BookAPIConfig{APIConfig: common.APIConfig{DB: mockQueries}}
+			request := httptest.NewRequest(http.MethodPost, "/api/v1/books", bytes.NewBufferString(`{"title": "Dune", "author": "Frank Herbert", `+policy+`}`))
+			recorder := httptest.NewRecorder()
+
+			apiConfig.CreateBook(recorder, request, userId)
+
+			if recorder.Code != http.StatusBadRequest {
+				t.Errorf("%s: expected status %d, got %d", policy, http.StatusBadRequest, recorder.Code)
+			}
+		}
+	})
+
+	// 1b. Success: an alert is queued in the outbox for every subscriber.
+	tTesting.Run("QueuesNewBookAlerts", func(t *testing.T) {
+		var recipients []string
+		var subjects []string
+
+		mockQueries := &MockQueries{
+			BaseMock: common.NewBaseMock(),
+			CreateBookCopyFunc: createTestBookCopy,
+			CreateBookFunc: func(ctx context.Context, arg database.CreateBookParams) (database.Book, error) {
+				return testBook, nil
+			},
+			GetUsersBySubscriberIDFunc: func(ctx context.Context, userID uuid.UUID) ([]database.User, error) {
+				return []database.User{
+					{ID: uuid.New(), FirstName: "Ann", LastName: "Lee", Email: "ann@example.com"},
+					{ID: uuid.New(), FirstName: "Bo", LastName: "Kim", Email: "bo@example.com", Locale: "es"},
+				}, nil
+			},
+			GetUserByIDFunc: func(ctx context.Context, id uuid.UUID) (database.User, error) {
+				return database.User{ID: id, FirstName: "Jane", LastName: "Doe", Email: "jane@example.com"}, nil
+			},
+			CreateOutboxNotificationFunc: func(ctx context.Context, arg database.CreateOutboxNotificationParams) (database.NotificationOutbox, error) {
+				recipients = append(recipients, arg.Recipient)
+				subjects = append(subjects, arg.Subject)
+
+				return database.NotificationOutbox{ID: arg.ID}, nil
+			},
+		}
+
+		apiConfig := BookAPIConfig{APIConfig: common.APIConfig{DB: mockQueries, Templates: notification_templates.MustNewRenderer()}}
 
 		requestBody, _ := json.Marshal(createBookRequest{
 			Title:  testBook.Title,
@@ -122,7 +483,40 @@ func TestCreateBook(tTesting *testing.T) {
 		})
 
 		request := httptest.NewRequest(http.MethodPost, "/api/v1/books", bytes.NewBuffer(requestBody))
+		recorder := httptest.NewRecorder()
+
+		apiConfig.CreateBook(recorder, request, userId)
+
+		if recorder.Code != http.StatusCreated {
+			t.Fatalf("Expected status %d, got %d. Body: %s", http.StatusCreated, recorder.Code, recorder.Body.String())
+		}
+
+		if len(recipients) != 2 || recipients[0] != "Ann Lee <ann@example.com>" {
+			t.Errorf("Expected alerts for both subscribers, got %v", recipients)
+		}
+
+		if len(subjects) != 2 || subjects[0] != "My Library Just Got Updated" || subjects[1] != "Mi biblioteca tiene novedades" {
+			t.Errorf("Expected alerts in each subscriber's locale, got %v", subjects)
+		}
+	})
+
+	// 1ba. Success: subscribers are not told about private books.
+	tTesting.Run("PrivateBookSkipsAlerts", func(t *testing.T) {
+		mockQueries := &MockQueries{
+			BaseMock: common.NewBaseMock(),
+			CreateBookCopyFunc: createTestBookCopy,
+			CreateBookFunc: func(ctx context.Context, arg database.CreateBookParams) (database.Book, error) {
+				return database.Book{ID: arg.ID, Title: arg.Title, UserID: arg.UserID, Visibility: arg.Visibility}, nil
+			},
+			GetUsersBySubscriberIDFunc: func(ctx context.Context, userID uuid.UUID) ([]database.User, error) {
+				t.Error("Expected no subscriber lookup for a private book")
 
+				return []database.User{}, nil
+			},
+		}
+
+		apiConfig := BookAPIConfig{APIConfig: common.APIConfig{DB: mockQueries, Templates: notification_templates.MustNewRenderer()}}
+		request := httptest.NewRequest(http.MethodPost, "/api/v1/books", bytes.NewBufferString(`{"title": "Diary", "author": "Jane Doe", "visibility": "private"}`))
 		recorder := httptest.NewRecorder()
 
 		apiConfig.CreateBook(recorder, request, userId)
@@ -132,10 +526,108 @@ func TestCreateBook(tTesting *testing.T) {
 		}
 	})
 
+	// 1c. Failure: the book is not created if its alerts cannot be queued
+	tTesting.Run("OutboxError", func(t *testing.T) {
+		mockQueries := &MockQueries{
+			BaseMock: common.NewBaseMock(),
+			CreateBookCopyFunc: createTestBookCopy,
+			CreateBookFunc: func(ctx context.Context, arg database.CreateBookParams) (database.Book, error) {
+				return testBook, nil
+			},
+			GetUsersBySubscriberIDFunc: func(ctx context.Context, userID uuid.UUID) ([]database.User, error) {
+				return []database.User{{ID: uuid.New(), Email: "ann@example.com"}}, nil
+			},
+			GetUserByIDFunc: func(ctx context.Context, id uuid.UUID) (database.User, error) {
+				return database.User{ID: id}, nil
+			},
+			CreateOutboxNotificationFunc: func(ctx context.Context, arg database.CreateOutboxNotificationParams) (database.NotificationOutbox, error) {
+				return database.NotificationOutbox{}, errors.New("db connection lost")
+			},
+		}
+
+		apiConfig := BookAPIConfig{APIConfig: common.APIConfig{DB: mockQueries, Templates: notification_templates.MustNewRenderer()}}
+
+		requestBody, _ := json.Marshal(createBookRequest{
+			Title:  testBook.Title,
+			Author: testBook.Author,
+		})
+
+		request := httptest.NewRequest(http.MethodPost, "/api/v1/books", bytes.NewBuffer(requestBody))
+		recorder := httptest.NewRecorder()
+
+		apiConfig.CreateBook(recorder, request, userId)
+
+		if recorder.Code != http.StatusInternalServerError {
+			t.Errorf("Expected status %d, got %d. Body: %s", http.StatusInternalServerError, recorder.Code, recorder.Body.String())
+		}
+	})
+
+	// 1d. Success: a book can be created from just its ISBN
+	tTesting.Run("OnlyISBN", func(t *testing.T) {
+		var createBookParams database.CreateBookParams
+
+		mockQueries := &MockQueries{
+			BaseMock: common.NewBaseMock(),
+			CreateBookCopyFunc: createTestBookCopy,
+			CreateBookFunc: func(ctx context.Context, arg database.CreateBookParams) (database.Book, error) {
+				createBookParams = arg
+
+				return testBook, nil
+			},
+		}
+
+		catalogProvider, _ := catalog_providers.NewFixtureCatalogProvider([]common.BookMetadata{
+			{ISBN: "9780441172719", Title: "Dune", Authors: []string{"Frank Herbert"}, Publisher: "Ace Books", PublishedYear: 1965, PageCount: 535},
+		})
+
+		apiConfig := BookAPIConfig{APIConfig: common.APIConfig{DB: mockQueries, Catalog: catalogProvider}}
+
+		request := httptest.NewRequest(http.MethodPost, "/api/v1/books", bytes.NewBufferString(`{"isbn": "0-441-17271-7"}`))
+		recorder := httptest.NewRecorder()
+
+		apiConfig.CreateBook(recorder, request, userId)
+
+		if recorder.Code != http.StatusCreated {
+			t.Fatalf("Expected status %d, got %d. Body: %s", http.StatusCreated, recorder.Code, recorder.Body.String())
+		}
+
+		if createBookParams.Isbn != "9780441172719" || createBookParams.Title != "Dune" || createBookParams.Author != "Frank Herbert" ||
+			createBookParams.Publisher != "Ace Books" || createBookParams.PublishedYear != 1965 || createBookParams.PageCount != 535 {
+			t.Errorf("Expected the book to be filled in from the catalog, got %+v", createBookParams)
+		}
+	})
+
+	// 1e. Failure: invalid ISBNs, ISBNs the catalog doesn't know and an unreachable catalog
+	tTesting.Run("ISBNErrors", func(t *testing.T) {
+		catalogProvider, _ := catalog_providers.NewFixtureCatalogProvider(nil)
+
+		for _, testCase := range []struct {
+			catalog        common.CatalogProvider
+			body           string
+			expectedStatus int
+		}{
+			{catalogProvider, `{"isbn": "0441172718"}`, http.StatusBadRequest},
+			{catalogProvider, `{"isbn": "9780441172719"}`, http.StatusBadRequest},
+			{failingCatalogProvider{}, `{"isbn": "9780441172719"}`, http.StatusBadGateway},
+		} {
+			apiConfig := BookAPIConfig{APIConfig: common.APIConfig{DB: &MockQueries{BaseMock: common.NewBaseMock()}, Catalog: testCase.catalog}}
+
+			request := httptest.NewRequest(http.MethodPost, "/api/v1/books", bytes.NewBufferString(testCase.body))
+			recorder := httptest.NewRecorder()
+
+			apiConfig.CreateBook(recorder, request, userId)
+
+			if recorder.Code != testCase.expectedStatus {
+				t.Errorf("%s: expected status %d, got %d. Body: %s", testCase.body, testCase.expectedStatus, recorder.Code, recorder.Body.String())
+			}
+		}
+	})
+
 	// 2. Invalid Input test case
 	tTesting.Run("InvalidInput", func(t *testing.T) {
 		mockQueries := &MockQueries{
 			BaseMock: common.NewBaseMock(),
+			CreateBookCopyFunc: createTestBookCopy,
 			CreateBookFunc: func(ctx context.Context, arg database.CreateBookParams) (database.Book, error) {
 				t.Fatal("FATAL: CreateBook should not have been called on Invalid Input.")
 				return database.Book{}, nil
@@ -158,10 +650,39 @@ func TestCreateBook(tTesting *testing.T) {
 		}
 	})
 
+	// 2a. Invalid loan period test case
+	tTesting.Run("InvalidLoanPeriod", func(t *testing.T) {
+		mockQueries := &MockQueries{
+			BaseMock: common.NewBaseMock(),
+			CreateBookCopyFunc: createTestBookCopy,
+			CreateBookFunc: func(ctx context.Context, arg database.CreateBookParams) (database.Book, error) {
+				t.Fatal("FATAL: CreateBook should not have been called on an invalid loan period.")
+				return database.Book{}, nil
+			},
+		}
+		apiConfig := BookAPIConfig{APIConfig: common.APIConfig{DB: mockQueries}}
+
+		requestBody, _ := json.Marshal(UpsertBookParameters{
+			Title:          testBook.Title,
+			Author:         testBook.Author,
+			LoanPeriodDays: common.MaxLoanPeriodDays + 1,
+		})
+
+		request := httptest.NewRequest(http.MethodPost, "/api/v1/books", bytes.NewBuffer(requestBody))
+		recorder := httptest.NewRecorder()
+
+		apiConfig.CreateBook(recorder, request, userId)
+
+		if recorder.Code != http.StatusBadRequest {
+			t.Errorf("Expected status %d, got %d. Body: %s", http.StatusBadRequest, recorder.Code, recorder.Body.String())
+		}
+	})
+
 	// 3. Database internal error test case
 	tTesting.Run("InternalDBError", func(t *testing.T) {
 		mockQueries := &MockQueries{
 			BaseMock: common.NewBaseMock(),
+			CreateBookCopyFunc: createTestBookCopy,
 			CreateBookFunc: func(ctx context.Context, arg database.CreateBookParams) (database.Book, error) {
 				return database.Book{}, errors.New("simulated DB connection failure")
 			},
@@ -169,47 +690,130 @@ func TestCreateBook(tTesting *testing.T) {
 
 		apiConfig := BookAPIConfig{APIConfig: common.APIConfig{DB: mockQueries}}
 
-		requestBody, _ := json.Marshal(createBookRequest{
-			Title:  testBook.Title,
-			Author: testBook.Author,
-		})
+		requestBody, _ := json.Marshal(createBookRequest{
+			Title:  testBook.Title,
+			Author: testBook.Author,
+		})
+
+		request := httptest.NewRequest(http.MethodPost, "/api/v1/books", bytes.NewBuffer(requestBody))
+		recorder := httptest.NewRecorder()
+
+		apiConfig.CreateBook(recorder, request, userId)
+
+		if recorder.Code != http.StatusInternalServerError {
+			t.Errorf("Expected status %d, got %d. Body: %s", http.StatusInternalServerError, recorder.Code, recorder.Body.String())
+		}
+	})
+}
+
+func TestGetBook(tTesting *testing.T) {
+	userId := newTestUserID()
+	testBook := newTestBook(userId)
+
+	// 1. Success test case
+	tTesting.Run("Success", func(t *testing.T) {
+		mockQueries := &MockQueries{
+			BaseMock: common.NewBaseMock(),
+			GetBookWithAvailabilityFunc: func(ctx context.Context, id uuid.UUID) (database.GetBookWithAvailabilityRow, error) {
+				return database.GetBookWithAvailabilityRow{Book: testBook}, nil
+			},
+		}
+
+		apiConfig := BookAPIConfig{APIConfig: common.APIConfig{DB: mockQueries}}
+		request := httptest.NewRequest(http.MethodGet, fmt.Sprintf("/api/v1/books/%s", testBook.ID), nil)
+		vars := map[string]string{
+			"bookId": testBook.ID.String(),
+		}
+		request = mux.SetURLVars(request, vars)
+		recorder := httptest.NewRecorder()
+
+		apiConfig.GetBook(recorder, request, userId)
+
+		if recorder.Code != http.StatusOK {
+			t.Fatalf("Expected status %d, got %d. Body: %s", http.StatusOK, recorder.Code, recorder.Body.String())
+		}
+	})
+
+	// 1a. Success: only the owner sees who has the book.
+	tTesting.Run("CurrentBorrower", func(t *testing.T) {
+		dueAt := time.Now().Add(7 * 24 * time.Hour).UTC().Truncate(time.Second)
+
+		mockQueries := &MockQueries{
+			BaseMock: common.NewBaseMock(),
+			GetBookWithAvailabilityFunc: func(ctx context.Context, id uuid.UUID) (database.GetBookWithAvailabilityRow, error) {
+				bookAvailability := database.BookAvailability{
+					BookID:                   testBook.ID,
+					TotalCopies:              1,
+					QueueLength:              2,
+					CurrentBorrowerID:        uuid.NullUUID{UUID: newTestUserID(), Valid: true},
+					CurrentBorrowerFirstName: sql.NullString{String: "Jane", Valid: true},
+					CurrentBorrowerLastName:  sql.NullString{String: "Doe", Valid: true},
+					DueAt:                    sql.NullTime{Time: dueAt, Valid: true},
+				}
+
+				return database.GetBookWithAvailabilityRow{Book: testBook, BookAvailability: bookAvailability}, nil
+			},
+		}
+
+		apiConfig := BookAPIConfig{APIConfig: common.APIConfig{DB: mockQueries}}
+
+		getBook := func(viewerId uuid.UUID) Book {
+			request := httptest.NewRequest(http.MethodGet, fmt.Sprintf("/api/v1/books/%s", testBook.ID), nil)
+			request = mux.SetURLVars(request, map[string]string{"bookId": testBook.ID.String()})
+			recorder := httptest.NewRecorder()
 
-		request := httptest.NewRequest(http.MethodPost, "/api/v1/books", bytes.NewBuffer(requestBody))
-		recorder := httptest.NewRecorder()
+			apiConfig.GetBook(recorder, request, viewerId)
 
-		apiConfig.CreateBook(recorder, request, userId)
+			if recorder.Code != http.StatusOK {
+				t.Fatalf("Expected status %d, got %d. Body: %s", http.StatusOK, recorder.Code, recorder.Body.String())
+			}
 
-		if recorder.Code != http.StatusInternalServerError {
-			t.Errorf("Expected status %d, got %d. Body: %s", http.StatusInternalServerError, recorder.Code, recorder.Body.String())
+			var book Book
+			json.Unmarshal(recorder.Body.Bytes(), &book)
+
+			return book
+		}
+
+		ownerView := getBook(userId)
+
+		if availability := ownerView.Availability; availability == nil || availability.Available || availability.QueueLength != 2 || !availability.DueAt.Time.Equal(dueAt) ||
+			availability.CurrentBorrower == nil || availability.CurrentBorrower.Name != "Jane Doe" {
+			t.Errorf("Expected the owner to see the borrower and waitlist, got %+v", availability)
+		}
+
+		if availability := getBook(newTestUserID()).Availability; availability == nil || availability.QueueLength != 2 || availability.CurrentBorrower != nil {
+			t.Errorf("Expected the borrower hidden from other users, got %+v", availability)
 		}
 	})
-}
 
-func TestGetBook(tTesting *testing.T) {
-	userId := newTestUserID()
-	testBook := newTestBook(userId)
+	// 1b. Failure: hidden books are not found for the members who can't see them.
+	tTesting.Run("HiddenBook", func(t *testing.T) {
+		subscriberId := uuid.New()
+		subscribersBook := newTestBook(userId)
+		subscribersBook.Visibility = "subscribers"
 
-	// 1. Success test case
-	tTesting.Run("Success", func(t *testing.T) {
 		mockQueries := &MockQueries{
 			BaseMock: common.NewBaseMock(),
-			GetBookFunc: func(ctx context.Context, id uuid.UUID) (database.Book, error) {
-				return testBook, nil
+			GetBookWithAvailabilityFunc: func(ctx context.Context, id uuid.UUID) (database.GetBookWithAvailabilityRow, error) {
+				return database.GetBookWithAvailabilityRow{Book: subscribersBook}, nil
+			},
+			GetMemberRelationshipFunc: func(ctx context.Context, arg database.GetMemberRelationshipParams) (database.GetMemberRelationshipRow, error) {
+				return database.GetMemberRelationshipRow{IsSubscriber: arg.OwnerID == userId && arg.MemberID == subscriberId}, nil
 			},
 		}
 
 		apiConfig := BookAPIConfig{APIConfig: common.APIConfig{DB: mockQueries}}
-		request := httptest.NewRequest(http.MethodGet, fmt.Sprintf("/api/v1/books/%s", testBook.ID), nil)
-		vars := map[string]string{
-			"bookId": testBook.ID.String(),
-		}
-		request = mux.SetURLVars(request, vars)
-		recorder := httptest.NewRecorder()
 
-		apiConfig.GetBook(recorder, request, userId)
+		for viewerId, expectedStatus := range map[uuid.UUID]int{userId: http.StatusOK, subscriberId: http.StatusOK, uuid.New(): http.StatusNotFound} {
+			request := httptest.NewRequest(http.MethodGet, fmt.Sprintf("/api/v1/books/%s", subscribersBook.ID), nil)
+			request = mux.SetURLVars(request, map[string]string{"bookId": subscribersBook.ID.String()})
+			recorder := httptest.NewRecorder()
 
-		if recorder.Code != http.StatusOK {
-			t.Fatalf("Expected status %d, got %d. Body: %s", http.StatusOK, recorder.Code, recorder.Body.String())
+			apiConfig.GetBook(recorder, request, viewerId)
+
+			if recorder.Code != expectedStatus {
+				t.Errorf("%s: expected status %d, got %d", viewerId, expectedStatus, recorder.Code)
+			}
 		}
 	})
 
@@ -217,8 +821,8 @@ func TestGetBook(tTesting *testing.T) {
 	tTesting.Run("BookNotFound", func(t *testing.T) {
 		mockQueries := &MockQueries{
 			BaseMock: common.NewBaseMock(),
-			GetBookFunc: func(ctx context.Context, id uuid.UUID) (database.Book, error) {
-				return database.Book{}, sql.ErrNoRows
+			GetBookWithAvailabilityFunc: func(ctx context.Context, id uuid.UUID) (database.GetBookWithAvailabilityRow, error) {
+				return database.GetBookWithAvailabilityRow{}, sql.ErrNoRows
 			},
 		}
 
@@ -261,8 +865,8 @@ func TestGetBook(tTesting *testing.T) {
 	tTesting.Run("InternalDBError", func(t *testing.T) {
 		mockQueries := &MockQueries{
 			BaseMock: common.NewBaseMock(),
-			GetBookFunc: func(ctx context.Context, id uuid.UUID) (database.Book, error) {
-				return database.Book{}, errors.New("simulated DB connection failure")
+			GetBookWithAvailabilityFunc: func(ctx context.Context, id uuid.UUID) (database.GetBookWithAvailabilityRow, error) {
+				return database.GetBookWithAvailabilityRow{}, errors.New("simulated DB connection failure")
 			},
 		}
 
@@ -328,6 +932,83 @@ func TestUpdateBook(tTesting *testing.T) {
 		}
 	})
 
+	// 1a. Success: tags sent on update replace the current ones, genres left out are kept.
+	tTesting.Run("GenresAndTags", func(t *testing.T) {
+		var createBookTagsParams database.CreateBookTagsParams
+
+		mockQueries := &MockQueries{
+			BaseMock: common.NewBaseMock(),
+			UpdateBookFunc: func(ctx context.Context, arg database.UpdateBookParams) (database.Book, error) {
+				return updatedBook, nil
+			},
+			CreateBookGenresFunc: func(ctx context.Context, arg database.CreateBookGenresParams) error {
+				t.Errorf("Expected the genres to be kept, got %+v", arg)
+
+				return nil
+			},
+			CreateBookTagsFunc: func(ctx context.Context, arg database.CreateBookTagsParams) error {
+				createBookTagsParams = arg
+
+				return nil
+			},
+			GetBookGenresAndTagsFunc: func(ctx context.Context, id uuid.UUID) (database.GetBookGenresAndTagsRow, error) {
+				return database.GetBookGenresAndTagsRow{Genres: []string{"fantasy"}, Tags: createBookTagsParams.Tags}, nil
+			},
+		}
+
+		apiConfig := BookAPIConfig{APIConfig: common.APIConfig{DB: mockQueries}}
+		request := httptest.NewRequest(http.MethodPatch, fmt.Sprintf("/api/v1/books/%s", testBook.ID), bytes.NewBufferString(`{"title": "Dune", "author": "Frank Herbert", "tags": ["Golang"]}`))
+		request = mux.SetURLVars(request, map[string]string{"bookId": testBook.ID.String()})
+		recorder := httptest.NewRecorder()
+
+		apiConfig.UpdateBook(recorder, request, userId)
+
+		if recorder.Code != http.StatusOK {
+			t.Fatalf("Expected status %d, got %d. Body: %s", http.StatusOK, recorder.Code, recorder.Body.String())
+		}
+
+		var book Book
+		json.Unmarshal(recorder.Body.Bytes(), &book)
+
+		if fmt.Sprint(book.Genres) != "[fantasy]" || fmt.Sprint(book.Tags) != "[golang]" {
+			t.Errorf("Expected the kept genre and new tag, got %+v and %+v", book.Genres, book.Tags)
+		}
+	})
+
+	// 1b. Success: policies sent on update replace the current ones, the rest are kept.
+	tTesting.Run("Policies", func(t *testing.T) {
+		var updateBookParams database.UpdateBookParams
+
+		mockQueries := &MockQueries{
+			BaseMock: common.NewBaseMock(),
+			UpdateBookFunc: func(ctx context.Context, arg database.UpdateBookParams) (database.Book, error) {
+				updateBookParams = arg
+
+				return updatedBook, nil
+			},
+		}
+
+		apiConfig := BookAPIConfig{APIConfig: common.APIConfig{DB: mockQueries}}
+		request := httptest.NewRequest(http.MethodPatch, fmt.Sprintf("/api/v1/books/%s", testBook.ID), bytes.NewBufferString(`{"title": "Dune", "author": "Frank Herbert", "lendable": false, "borrower_policy": "subscribers", "min_borrower_reputation": 0}`))
+		request = mux.SetURLVars(request, map[string]string{"bookId": testBook.ID.String()})
+		recorder := httptest.NewRecorder()
+
+		apiConfig.UpdateBook(recorder, request, userId)
+
+		if recorder.Code != http.StatusOK {
+			t.Fatalf("Expected status %d, got %d. Body: %s", http.StatusOK, recorder.Code, recorder.Body.String())
+		}
+
+		if updateBookParams.Visibility.Valid || updateBookParams.Lendable != (sql.NullBool{Bool: false, Valid: true}) || updateBookParams.BorrowerPolicy != (sql.NullString{String: "subscribers", Valid: true}) {
+			t.Errorf("Unexpected policies: %+v, %+v, %+v", updateBookParams.Visibility, updateBookParams.Lendable, updateBookParams.BorrowerPolicy)
+		}
+
+		// A minimum of 0 is sent to lift the current one, not left out.
+		if updateBookParams.MinBorrowerReputation != (sql.NullInt32{Int32: 0, Valid: true}) {
+			t.Errorf("Expected the minimum reputation to be cleared, got %+v", updateBookParams.MinBorrowerReputation)
+		}
+	})
+
 	// 2. Book Not Found / unauthorized test case
 	tTesting.Run("NotFoundOrUnauthorized", func(t *testing.T) {
 		mockQueries := &MockQueries{
@@ -378,8 +1059,10 @@ func TestDeleteBook(tTesting *testing.T) {
 	otherUserID := newTestUserID()
 	testBook := newTestBook(userId)
 
-	// 1. Success test case
+	// 1. Success test case: the book goes to the trash and nobody is left waiting for it.
 	tTesting.Run("Success", func(t *testing.T) {
+		var declinedBookID, cancelledBookID uuid.UUID
+
 		mockQueries := &MockQueries{
 			BaseMock: common.NewBaseMock(),
 			GetBookFunc: func(ctx context.Context, id uuid.UUID) (database.Book, error) {
@@ -388,6 +1071,16 @@ func TestDeleteBook(tTesting *testing.T) {
 			DeleteBookFunc: func(ctx context.Context, arg database.DeleteBookParams) (int64, error) {
 				return 1, nil
 			},
+			DeclineOpenBookBorrowRequestsFunc: func(ctx context.Context, bookID uuid.UUID) error {
+				declinedBookID = bookID
+
+				return nil
+			},
+			CancelBookReservationsFunc: func(ctx context.Context, bookID uuid.UUID) error {
+				cancelledBookID = bookID
+
+				return nil
+			},
 		}
 
 		apiConfig := BookAPIConfig{APIConfig: common.APIConfig{DB: mockQueries}}
@@ -402,6 +1095,10 @@ func TestDeleteBook(tTesting *testing.T) {
 		if recorder.Code != http.StatusOK {
 			t.Fatalf("Expected status %d, got %d. Body: %s", http.StatusOK, recorder.Code, recorder.Body.String())
 		}
+
+		if declinedBookID != testBook.ID || cancelledBookID != testBook.ID {
+			t.Errorf("Expected open requests and reservations for the book to be closed, got %s and %s", declinedBookID, cancelledBookID)
+		}
 	})
 
 	// 2. Unauthorized (User attempts to delete another user's book) or book not found
@@ -427,22 +1124,51 @@ func TestDeleteBook(tTesting *testing.T) {
 			t.Errorf("Expected status %d, got %d. Body: %s", http.StatusNotFound, recorder.Code, recorder.Body.String())
 		}
 	})
+
+	// 3. Conflict: a copy of the book is still out on loan.
+	tTesting.Run("OnLoan", func(t *testing.T) {
+		mockQueries := &MockQueries{
+			BaseMock: common.NewBaseMock(),
+			GetBookFunc: func(ctx context.Context, id uuid.UUID) (database.Book, error) {
+				return testBook, nil
+			},
+			DeleteBookFunc: func(ctx context.Context, arg database.DeleteBookParams) (int64, error) {
+				return 0, nil
+			},
+			CancelBookReservationsFunc: func(ctx context.Context, bookID uuid.UUID) error {
+				t.Error("Expected reservations to be kept for a book that wasn't deleted")
+
+				return nil
+			},
+		}
+
+		apiConfig := BookAPIConfig{APIConfig: common.APIConfig{DB: mockQueries}}
+		request := httptest.NewRequest(http.MethodDelete, fmt.Sprintf("/api/v1/books/%s", testBook.ID), nil)
+		request = mux.SetURLVars(request, map[string]string{"bookId": testBook.ID.String()})
+		recorder := httptest.NewRecorder()
+
+		apiConfig.DeleteBook(recorder, request, userId)
+
+		if recorder.Code != http.StatusConflict {
+			t.Errorf("Expected status %d, got %d. Body: %s", http.StatusConflict, recorder.Code, recorder.Body.String())
+		}
+	})
 }
 
 func TestGetBooks(tTesting *testing.T) {
 	userId := newTestUserID()
-	testBooks := []database.Book{
-		newTestBook(userId),
-		newTestBook(userId),
+	testBooks := []database.GetBooksRow{
+		{Book: newTestBook(userId)},
+		{Book: newTestBook(userId)},
 	}
 
 	// 1. Success test case
 	tTesting.Run("Success", func(t *testing.T) {
 		mockQueries := &MockQueries{
 			BaseMock: common.NewBaseMock(),
-			GetBooksFunc: func(ctx context.Context, id uuid.UUID) ([]database.Book, error) {
-				if id != userId {
-					t.Fatalf("Expected UserID %s, got %s", userId, id)
+			GetBooksFunc: func(ctx context.Context, arg database.GetBooksParams) ([]database.GetBooksRow, error) {
+				if arg.UserID != userId {
+					t.Fatalf("Expected UserID %s, got %s", userId, arg.UserID)
 				}
 				return testBooks, nil
 			},
@@ -459,11 +1185,11 @@ func TestGetBooks(tTesting *testing.T) {
 		}
         
         // Check content size
-		var response []database.Book
+		var response common.Page[Book]
 		json.Unmarshal(recorder.Body.Bytes(), &response)
 
-		if len(response) != len(testBooks) {
-			t.Errorf("Expected %d books, got %d", len(testBooks), len(response))
+		if len(response.Data) != len(testBooks) {
+			t.Errorf("Expected %d books, got %d", len(testBooks), len(response.Data))
 		}
 	})
 
@@ -471,8 +1197,8 @@ func TestGetBooks(tTesting *testing.T) {
 	tTesting.Run("NoBooksFound", func(t *testing.T) {
 		mockQueries := &MockQueries{
 			BaseMock: common.NewBaseMock(),
-			GetBooksFunc: func(ctx context.Context, id uuid.UUID) ([]database.Book, error) {
-				return []database.Book{}, nil
+			GetBooksFunc: func(ctx context.Context, arg database.GetBooksParams) ([]database.GetBooksRow, error) {
+				return []database.GetBooksRow{}, nil
 			},
 		}
 
@@ -487,11 +1213,11 @@ func TestGetBooks(tTesting *testing.T) {
 		}
         
         // Check content size (should be an empty array)
-		var response []database.Book
+		var response common.Page[Book]
 		json.Unmarshal(recorder.Body.Bytes(), &response)
 
-		if len(response) != 0 {
-			t.Errorf("Expected 0 books, got %d", len(response))
+		if len(response.Data) != 0 {
+			t.Errorf("Expected 0 books, got %d", len(response.Data))
 		}
 	})
 
@@ -499,7 +1225,7 @@ func TestGetBooks(tTesting *testing.T) {
 	tTesting.Run("InternalDBError", func(t *testing.T) {
 		mockQueries := &MockQueries{
 			BaseMock: common.NewBaseMock(),
-			GetBooksFunc: func(ctx context.Context, id uuid.UUID) ([]database.Book, error) {
+			GetBooksFunc: func(ctx context.Context, arg database.GetBooksParams) ([]database.GetBooksRow, error) {
 				return nil, errors.New("simulated DB connection failure")
 			},
 		}
@@ -514,12 +1240,76 @@ func TestGetBooks(tTesting *testing.T) {
 			t.Fatalf("Expected status %d, got %d. Body: %s", http.StatusInternalServerError, recorder.Code, recorder.Body.String())
 		}
 	})
+
+	// 4. Pagination: the next cursor picks up after the last book on the page
+	tTesting.Run("Paginates", func(t *testing.T) {
+		var getBooksParams []database.GetBooksParams
+
+		mockQueries := &MockQueries{
+			BaseMock: common.NewBaseMock(),
+			GetBooksFunc: func(ctx context.Context, arg database.GetBooksParams) ([]database.GetBooksRow, error) {
+				getBooksParams = append(getBooksParams, arg)
+
+				if arg.CursorID.Valid {
+					return testBooks[1:], nil
+				}
+
+				return testBooks, nil
+			},
+		}
+
+		apiConfig := BookAPIConfig{APIConfig: common.APIConfig{DB: mockQueries}}
+
+		request := httptest.NewRequest(http.MethodGet, "/api/v1/books?limit=1", nil)
+		recorder := httptest.NewRecorder()
+
+		apiConfig.GetBooks(recorder, request, userId)
+
+		var firstPage common.Page[Book]
+		json.Unmarshal(recorder.Body.Bytes(), &firstPage)
+
+		if len(firstPage.Data) != 1 || firstPage.NextCursor == nil {
+			t.Fatalf("Expected one book and a next cursor, got %s", recorder.Body.String())
+		}
+
+		request = httptest.NewRequest(http.MethodGet, "/api/v1/books?limit=1&cursor="+*firstPage.NextCursor, nil)
+		recorder = httptest.NewRecorder()
+
+		apiConfig.GetBooks(recorder, request, userId)
+
+		var secondPage common.Page[Book]
+		json.Unmarshal(recorder.Body.Bytes(), &secondPage)
+
+		if len(secondPage.Data) != 1 || secondPage.NextCursor != nil {
+			t.Fatalf("Expected the last book and no next cursor, got %s", recorder.Body.String())
+		}
+
+		if getBooksParams[0].PageLimit != 2 || getBooksParams[1].CursorTitle != testBooks[0].Book.Title || getBooksParams[1].CursorID.UUID != testBooks[0].Book.ID {
+			t.Errorf("Unexpected page queries: %+v", getBooksParams)
+		}
+	})
+
+	// 5. Invalid limit or cursor
+	tTesting.Run("InvalidPage", func(t *testing.T) {
+		for _, query := range []string{"?limit=0", "?limit=101", "?limit=ten", "?cursor=not-a-cursor"} {
+			mockQueries := &MockQueries{BaseMock: common.NewBaseMock()}
+			apiConfig := BookAPIConfig{APIConfig: common.APIConfig{DB: mockQueries}}
+			request := httptest.NewRequest(http.MethodGet, "/api/v1/books"+query, nil)
+			recorder := httptest.NewRecorder()
+
+			apiConfig.GetBooks(recorder, request, userId)
+
+			if recorder.Code != http.StatusBadRequest {
+				t.Errorf("%s: expected status %d, got %d", query, http.StatusBadRequest, recorder.Code)
+			}
+		}
+	})
 }
 
 func TestBrowseBooks(tTesting *testing.T) {
-	testBooks := []database.Book{
-		newTestBook(newTestUserID()),
-		newTestBook(newTestUserID()),
+	testBooks := []database.BrowseBooksRow{
+		{Book: newTestBook(newTestUserID()), BookAvailability: database.BookAvailability{TotalCopies: 3, AvailableCopies: 2}},
+		{Book: newTestBook(newTestUserID()), BookAvailability: database.BookAvailability{TotalCopies: 1}},
 	}
     
 	dummyUserID := newTestUserID() 
@@ -528,7 +1318,7 @@ func TestBrowseBooks(tTesting *testing.T) {
 	tTesting.Run("Success", func(t *testing.T) {
 		mockQueries := &MockQueries{
 			BaseMock: common.NewBaseMock(),
-			BrowseBooksFunc: func(ctx context.Context) ([]database.Book, error) {
+			BrowseBooksFunc: func(ctx context.Context, arg database.BrowseBooksParams) ([]database.BrowseBooksRow, error) {
 				return testBooks, nil
 			},
 		}
@@ -544,11 +1334,19 @@ func TestBrowseBooks(tTesting *testing.T) {
 		}
         
         // Check content size
-		var response []database.Book
+		var response common.Page[Book]
 		json.Unmarshal(recorder.Body.Bytes(), &response)
 
-		if len(response) != len(testBooks) {
-			t.Errorf("Expected %d books, got %d", len(testBooks), len(response))
+		if len(response.Data) != len(testBooks) {
+			t.Fatalf("Expected %d books, got %d", len(testBooks), len(response.Data))
+		}
+
+		if availability := response.Data[0].Availability; availability == nil || !availability.Available || availability.TotalCopies != 3 || availability.AvailableCopies != 2 {
+			t.Errorf("Expected 2 of 3 copies available, got %+v", availability)
+		}
+
+		if availability := response.Data[1].Availability; availability == nil || availability.Available {
+			t.Errorf("Expected the second book to be unavailable, got %+v", availability)
 		}
 	})
 
@@ -556,8 +1354,8 @@ func TestBrowseBooks(tTesting *testing.T) {
 	tTesting.Run("EmptyBrowse", func(t *testing.T) {
 		mockQueries := &MockQueries{
 			BaseMock: common.NewBaseMock(),
-			BrowseBooksFunc: func(ctx context.Context) ([]database.Book, error) {
-				return []database.Book{}, nil // Return empty slice
+			BrowseBooksFunc: func(ctx context.Context, arg database.BrowseBooksParams) ([]database.BrowseBooksRow, error) {
+				return []database.BrowseBooksRow{}, nil // Return empty slice
 			},
 		}
 
@@ -572,10 +1370,10 @@ func TestBrowseBooks(tTesting *testing.T) {
 		}
         
         // Check content size (should be an empty array)
-		var response []database.Book
+		var response common.Page[Book]
 		json.Unmarshal(recorder.Body.Bytes(), &response)
-		if len(response) != 0 {
-			t.Errorf("Expected 0 books, got %d", len(response))
+		if len(response.Data) != 0 {
+			t.Errorf("Expected 0 books, got %d", len(response.Data))
 		}
 	})
 
@@ -583,7 +1381,7 @@ func TestBrowseBooks(tTesting *testing.T) {
 	tTesting.Run("InternalDBError", func(t *testing.T) {
 		mockQueries := &MockQueries{
 			BaseMock: common.NewBaseMock(),
-			BrowseBooksFunc: func(ctx context.Context) ([]database.Book, error) {
+			BrowseBooksFunc: func(ctx context.Context, arg database.BrowseBooksParams) ([]database.BrowseBooksRow, error) {
 				return nil, errors.New("simulated DB connection failure")
 			},
 		}
@@ -598,23 +1396,184 @@ func TestBrowseBooks(tTesting *testing.T) {
 			t.Fatalf("Expected status %d, got %d. Body: %s", http.StatusInternalServerError, recorder.Code, recorder.Body.String())
 		}
 	})
+
+	// 4. Search, filters and sort are passed to the query
+	tTesting.Run("Filters", func(t *testing.T) {
+		var browseBooksParams database.BrowseBooksParams
+
+		mockQueries := &MockQueries{
+			BaseMock: common.NewBaseMock(),
+			BrowseBooksFunc: func(ctx context.Context, arg database.BrowseBooksParams) ([]database.BrowseBooksRow, error) {
+				browseBooksParams = arg
+
+				return testBooks, nil
+			},
+		}
+
+		apiConfig := BookAPIConfig{APIConfig: common.APIConfig{DB: mockQueries}}
+		request := httptest.NewRequest(http.MethodGet, "/api/v1/books/browse?q=left+hand&author=le+guin&available=true&genre=Fantasy&tag=Space+Opera&sort=created_at", nil)
+		recorder := httptest.NewRecorder()
+
+		apiConfig.BrowseBooks(recorder, request, dummyUserID)
+
+		if recorder.Code != http.StatusOK {
+			t.Fatalf("Expected status %d, got %d. Body: %s", http.StatusOK, recorder.Code, recorder.Body.String())
+		}
+
+		expectedParams := database.BrowseBooksParams{
+			Query:     sql.NullString{String: "left hand", Valid: true},
+			Author:    sql.NullString{String: "le guin", Valid: true},
+			Available: sql.NullBool{Bool: true, Valid: true},
+			Genre:     sql.NullString{String: "fantasy", Valid: true},
+			Tag:       sql.NullString{String: "space-opera", Valid: true},
+			ViewerID:  dummyUserID,
+			Sort:      BookSortCreatedAt,
+			PageLimit: common.DefaultPageLimit + 1,
+		}
+
+		if browseBooksParams != expectedParams {
+			t.Errorf("Expected %+v, got %+v", expectedParams, browseBooksParams)
+		}
+	})
+
+	// 5. Searches are ranked by relevance unless a sort is given
+	tTesting.Run("RelevanceByDefault", func(t *testing.T) {
+		browseBooksParams, err := ParseBrowseBooksFilters(url.Values{"q": {"dune"}})
+
+		if err != nil || browseBooksParams.Sort != BookSortRelevance {
+			t.Errorf("Expected relevance sort, got %+v (%v)", browseBooksParams, err)
+		}
+
+		browseBooksParams, _ = ParseBrowseBooksFilters(url.Values{})
+
+		if browseBooksParams.Sort != BookSortTitle || browseBooksParams.Query.Valid {
+			t.Errorf("Expected title sort without a search, got %+v", browseBooksParams)
+		}
+	})
+
+	// 6. Cursors carry the sort key of the last book on the page
+	tTesting.Run("Cursor", func(t *testing.T) {
+		var browseBooksParams database.BrowseBooksParams
+
+		mockQueries := &MockQueries{
+			BaseMock: common.NewBaseMock(),
+			BrowseBooksFunc: func(ctx context.Context, arg database.BrowseBooksParams) ([]database.BrowseBooksRow, error) {
+				browseBooksParams = arg
+
+				return []database.BrowseBooksRow{{Book: testBooks[0].Book, Rank: 0.25}, {Book: testBooks[1].Book, Rank: 0.1}}, nil
+			},
+		}
+
+		apiConfig := BookAPIConfig{APIConfig: common.APIConfig{DB: mockQueries}}
+		request := httptest.NewRequest(http.MethodGet, "/api/v1/books/browse?q=test&limit=1", nil)
+		recorder := httptest.NewRecorder()
+
+		apiConfig.BrowseBooks(recorder, request, dummyUserID)
+
+		var response common.Page[Book]
+		json.Unmarshal(recorder.Body.Bytes(), &response)
+
+		if response.NextCursor == nil {
+			t.Fatalf("Expected a next cursor, got %s", recorder.Body.String())
+		}
+
+		request = httptest.NewRequest(http.MethodGet, "/api/v1/books/browse?q=test&limit=1&cursor="+*response.NextCursor, nil)
+		recorder = httptest.NewRecorder()
+
+		apiConfig.BrowseBooks(recorder, request, dummyUserID)
+
+		if browseBooksParams.CursorRank != 0.25 || browseBooksParams.CursorID.UUID != testBooks[0].Book.ID {
+			t.Errorf("Expected the relevance cursor of the first book, got %+v", browseBooksParams)
+		}
+
+		// The same cursor cannot be used with another sort.
+		request = httptest.NewRequest(http.MethodGet, "/api/v1/books/browse?q=test&sort=title&cursor="+*response.NextCursor, nil)
+		recorder = httptest.NewRecorder()
+
+		apiConfig.BrowseBooks(recorder, request, dummyUserID)
+
+		if recorder.Code != http.StatusBadRequest {
+			t.Errorf("Expected status %d, got %d", http.StatusBadRequest, recorder.Code)
+		}
+	})
+
+	// 6a. Sorting by rating pages on the average and shows it rounded
+	tTesting.Run("RatingSort", func(t *testing.T) {
+		var browseBooksParams database.BrowseBooksParams
+
+		ratedBook := testBooks[0].Book
+		ratedBook.RatingCount = 3
+		ratedBook.RatingTotal = 14
+		ratedBook.RatingAverage = 14.0 / 3
+
+		mockQueries := &MockQueries{
+			BaseMock: common.NewBaseMock(),
+			BrowseBooksFunc: func(ctx context.Context, arg database.BrowseBooksParams) ([]database.BrowseBooksRow, error) {
+				browseBooksParams = arg
+
+				return []database.BrowseBooksRow{{Book: ratedBook}, {Book: testBooks[1].Book}}, nil
+			},
+		}
+
+		apiConfig := BookAPIConfig{APIConfig: common.APIConfig{DB: mockQueries}}
+		request := httptest.NewRequest(http.MethodGet, "/api/v1/books/browse?sort=rating&limit=1", nil)
+		recorder := httptest.NewRecorder()
+
+		apiConfig.BrowseBooks(recorder, request, dummyUserID)
+
+		var response common.Page[Book]
+		json.Unmarshal(recorder.Body.Bytes(), &response)
+
+		if browseBooksParams.Sort != BookSortRating || response.NextCursor == nil || len(response.Data) != 1 {
+			t.Fatalf("Expected a rating sorted page, got %s", recorder.Body.String())
+		}
+
+		if response.Data[0].RatingAverage != 4.67 || response.Data[0].RatingCount != 3 {
+			t.Errorf("Expected an average of 4.67 over 3 ratings, got %v over %d", response.Data[0].RatingAverage, response.Data[0].RatingCount)
+		}
+
+		request = httptest.NewRequest(http.MethodGet, "/api/v1/books/browse?sort=rating&limit=1&cursor="+*response.NextCursor, nil)
+		recorder = httptest.NewRecorder()
+
+		apiConfig.BrowseBooks(recorder, request, dummyUserID)
+
+		if browseBooksParams.CursorRating != ratedBook.RatingAverage || browseBooksParams.CursorID.UUID != ratedBook.ID {
+			t.Errorf("Expected the rating cursor of the first book, got %+v", browseBooksParams)
+		}
+	})
+
+	// 7. Invalid filters are rejected
+	tTesting.Run("InvalidFilters", func(t *testing.T) {
+		for _, query := range []string{"?available=maybe", "?sort=relevance", "?sort=author", "?tag=sci-fi%21"} {
+			mockQueries := &MockQueries{BaseMock: common.NewBaseMock()}
+			apiConfig := BookAPIConfig{APIConfig: common.APIConfig{DB: mockQueries}}
+			request := httptest.NewRequest(http.MethodGet, "/api/v1/books/browse"+query, nil)
+			recorder := httptest.NewRecorder()
+
+			apiConfig.BrowseBooks(recorder, request, dummyUserID)
+
+			if recorder.Code != http.StatusBadRequest {
+				t.Errorf("%s: expected status %d, got %d", query, http.StatusBadRequest, recorder.Code)
+			}
+		}
+	})
 }
 
 func TestBrowseBooksByUserID(tTesting *testing.T) {
 	targetUserID := newTestUserID()
 	dummyUserID := newTestUserID()
-	testBooks := []database.Book{
-		newTestBook(targetUserID),
-		newTestBook(targetUserID),
+	testBooks := []database.GetBooksRow{
+		{Book: newTestBook(targetUserID)},
+		{Book: newTestBook(targetUserID)},
 	}
 
 	// 1. Success test case (returns list of target user's books)
 	tTesting.Run("Success", func(t *testing.T) {
 		mockQueries := &MockQueries{
 			BaseMock: common.NewBaseMock(),
-			GetBooksFunc: func(ctx context.Context, id uuid.UUID) ([]database.Book, error) {
-				if id != targetUserID {
-					t.Fatalf("Expected UserID %s in GetBooks call, got %s", targetUserID, id)
+			GetBooksFunc: func(ctx context.Context, arg database.GetBooksParams) ([]database.GetBooksRow, error) {
+				if arg.UserID != targetUserID {
+					t.Fatalf("Expected UserID %s in GetBooks call, got %s", targetUserID, arg.UserID)
 				}
 				return testBooks, nil
 			},
@@ -638,7 +1597,7 @@ func TestBrowseBooksByUserID(tTesting *testing.T) {
 	tTesting.Run("UserNotFoundOrNoBooks", func(t *testing.T) {
 		mockQueries := &MockQueries{
 			BaseMock: common.NewBaseMock(),
-			GetBooksFunc: func(ctx context.Context, id uuid.UUID) ([]database.Book, error) {
+			GetBooksFunc: func(ctx context.Context, arg database.GetBooksParams) ([]database.GetBooksRow, error) {
 				return nil, sql.ErrNoRows 
 			},
 		}
@@ -679,7 +1638,7 @@ func TestBrowseBooksByUserID(tTesting *testing.T) {
 	tTesting.Run("InternalDBError", func(t *testing.T) {
 		mockQueries := &MockQueries{
 			BaseMock: common.NewBaseMock(),
-			GetBooksFunc: func(ctx context.Context, id uuid.UUID) ([]database.Book, error) {
+			GetBooksFunc: func(ctx context.Context, arg database.GetBooksParams) ([]database.GetBooksRow, error) {
 				return nil, errors.New("simulated DB connection failure") 
 			},
 		}
@@ -697,4 +1656,63 @@ func TestBrowseBooksByUserID(tTesting *testing.T) {
 			t.Fatalf("Expected status %d, got %d. Body: %s", http.StatusInternalServerError, recorder.Code, recorder.Body.String())
 		}
 	})
-}
\ No newline at end of file
+
+	// 5. Shelf filter: public shelves can be browsed, private ones only by their owner
+	tTesting.Run("ShelfFilter", func(t *testing.T) {
+		publicShelf := database.Shelf{ID: uuid.New(), IsPublic: true, UserID: targetUserID}
+		privateShelf := database.Shelf{ID: uuid.New(), UserID: targetUserID}
+		otherUserShelf := database.Shelf{ID: uuid.New(), IsPublic: true, UserID: dummyUserID}
+
+		testCases := []struct {
+			shelfId        string
+			viewerId       uuid.UUID
+			expectedStatus int
+		}{
+			{publicShelf.ID.String(), dummyUserID, http.StatusOK},
+			{privateShelf.ID.String(), targetUserID, http.StatusOK},
+			{privateShelf.ID.String(), dummyUserID, http.StatusNotFound},
+			{otherUserShelf.ID.String(), dummyUserID, http.StatusNotFound},
+			{uuid.New().String(), dummyUserID, http.StatusNotFound},
+			{"not-a-uuid", dummyUserID, http.StatusBadRequest},
+		}
+
+		for _, testCase := range testCases {
+			var getBooksParams database.GetBooksParams
+
+			mockQueries := &MockQueries{
+				BaseMock: common.NewBaseMock(),
+				GetShelfFunc: func(ctx context.Context, arg database.GetShelfParams) (database.GetShelfRow, error) {
+					for _, shelf := range []database.Shelf{publicShelf, privateShelf, otherUserShelf} {
+						if shelf.ID == arg.ID {
+							return database.GetShelfRow{Shelf: shelf}, nil
+						}
+					}
+
+					return database.GetShelfRow{}, sql.ErrNoRows
+				},
+				GetBooksFunc: func(ctx context.Context, arg database.GetBooksParams) ([]database.GetBooksRow, error) {
+					getBooksParams = arg
+
+					return testBooks, nil
+				},
+			}
+
+			apiConfig := BookAPIConfig{APIConfig: common.APIConfig{DB: mockQueries}}
+			request := httptest.NewRequest(http.MethodGet, fmt.Sprintf("/api/v1/books/browse/%s?shelf=%s", targetUserID, testCase.shelfId), nil)
+			request = mux.SetURLVars(request, map[string]string{"userId": targetUserID.String()})
+			recorder := httptest.NewRecorder()
+
+			apiConfig.BrowseBooksByUserID(recorder, request, testCase.viewerId)
+
+			if recorder.Code != testCase.expectedStatus {
+				t.Errorf("%s: expected status %d, got %d. Body: %s", testCase.shelfId, testCase.expectedStatus, recorder.Code, recorder.Body.String())
+
+				continue
+			}
+
+			if testCase.expectedStatus == http.StatusOK && getBooksParams.ShelfID.UUID.String() != testCase.shelfId {
+				t.Errorf("%s: expected the shelf filter, got %+v", testCase.shelfId, getBooksParams.ShelfID)
+			}
+		}
+	})
+}
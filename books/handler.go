@@ -1,26 +1,579 @@
 package books
 
 import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/elorenzorodz/co-library/book_copies"
+	"github.com/elorenzorodz/co-library/book_policies"
+	"github.com/elorenzorodz/co-library/book_tags"
+	"github.com/elorenzorodz/co-library/borrower_reputation"
+	"github.com/elorenzorodz/co-library/common"
 	"github.com/elorenzorodz/co-library/internal/database"
+	"github.com/elorenzorodz/co-library/notification_outbox"
+	"github.com/elorenzorodz/co-library/users"
+	"github.com/google/uuid"
 )
 
 func DatabaseBookToBookJSON(databaseBook database.Book) Book {
 	return Book{
-		ID:        databaseBook.ID,
-		Title:     databaseBook.Title,
-		Author:    databaseBook.Author,
-		CreatedAt: databaseBook.CreatedAt,
-		UpdatedAt: databaseBook.UpdatedAt,
-		UserID:    databaseBook.UserID,
+		ID:                    databaseBook.ID,
+		Title:                 databaseBook.Title,
+		Author:                databaseBook.Author,
+		CreatedAt:             databaseBook.CreatedAt,
+		UpdatedAt:             databaseBook.UpdatedAt,
+		UserID:                databaseBook.UserID,
+		LoanPeriodDays:        databaseBook.LoanPeriodDays,
+		ISBN:                  databaseBook.Isbn,
+		Publisher:             databaseBook.Publisher,
+		PublishedYear:         databaseBook.PublishedYear,
+		PageCount:             databaseBook.PageCount,
+		CoverURL:              databaseBook.CoverUrl,
+		CoverThumbnailURL:     databaseBook.CoverThumbnailUrl,
+		Genres:                []string{},
+		Tags:                  []string{},
+		Visibility:            databaseBook.Visibility,
+		Lendable:              databaseBook.Lendable,
+		BorrowerPolicy:        databaseBook.BorrowerPolicy,
+		MinBorrowerReputation: databaseBook.MinBorrowerReputation,
+		RatingAverage:         math.Round(databaseBook.RatingAverage*100) / 100,
+		RatingCount:           databaseBook.RatingCount,
+	}
+}
+
+// The current borrower is only shown to the book owner.
+func DatabaseBookAvailabilityToBookAvailabilityJSON(databaseBookAvailability database.BookAvailability, showBorrower bool) *BookAvailability {
+	bookAvailability := &BookAvailability{
+		Available:       databaseBookAvailability.AvailableCopies > 0,
+		TotalCopies:     databaseBookAvailability.TotalCopies,
+		AvailableCopies: databaseBookAvailability.AvailableCopies,
+		QueueLength:     databaseBookAvailability.QueueLength,
+		DueAt:           databaseBookAvailability.DueAt,
+	}
+
+	if showBorrower && databaseBookAvailability.CurrentBorrowerID.Valid {
+		bookAvailability.CurrentBorrower = &BookBorrower{
+			ID:   databaseBookAvailability.CurrentBorrowerID.UUID,
+			Name: fmt.Sprintf("%s %s", databaseBookAvailability.CurrentBorrowerFirstName.String, databaseBookAvailability.CurrentBorrowerLastName.String),
+		}
+	}
+
+	return bookAvailability
+}
+
+func DatabaseBookWithAvailabilityToBookJSON(databaseBook database.Book, databaseBookAvailability database.BookAvailability, viewerId uuid.UUID) Book {
+	book := DatabaseBookToBookJSON(databaseBook)
+	book.Availability = DatabaseBookAvailabilityToBookAvailabilityJSON(databaseBookAvailability, viewerId == databaseBook.UserID)
+
+	return book
+}
+
+func DatabaseGetBooksRowsToBooksJSON(databaseGetBooksRows []database.GetBooksRow, viewerId uuid.UUID) []Book {
+	books := []Book{}
+
+	for _, databaseGetBooksRow := range databaseGetBooksRows {
+		book := DatabaseBookWithAvailabilityToBookJSON(databaseGetBooksRow.Book, databaseGetBooksRow.BookAvailability, viewerId)
+		book.Genres = append(book.Genres, databaseGetBooksRow.Genres...)
+		book.Tags = append(book.Tags, databaseGetBooksRow.Tags...)
+
+		books = append(books, book)
 	}
+
+	return books
 }
 
-func DatabaseBooksToBooksJSON(databaseBooks []database.Book) []Book {
+func DatabaseBrowseBooksRowsToBooksJSON(databaseBrowseBooksRows []database.BrowseBooksRow, viewerId uuid.UUID) []Book {
 	books := []Book{}
 
-	for _, databaseBook := range databaseBooks {
-		books = append(books, DatabaseBookToBookJSON(databaseBook))
+	for _, databaseBrowseBooksRow := range databaseBrowseBooksRows {
+		book := DatabaseBookWithAvailabilityToBookJSON(databaseBrowseBooksRow.Book, databaseBrowseBooksRow.BookAvailability, viewerId)
+		book.Genres = append(book.Genres, databaseBrowseBooksRow.Genres...)
+		book.Tags = append(book.Tags, databaseBrowseBooksRow.Tags...)
+
+		books = append(books, book)
 	}
 
 	return books
-}
\ No newline at end of file
+}
+
+// Normalizes the ISBN, fills in whatever the owner left blank from the catalog and
+// checks that the book ends up with a title and author. The returned status is the
+// one to respond with when the parameters can't be used.
+func ResolveUpsertBookParameters(ctx context.Context, catalog common.CatalogProvider, upsertBookParameters *UpsertBookParameters) (int, error) {
+	var lookupError error
+
+	if strings.TrimSpace(upsertBookParameters.ISBN) != "" {
+		isbn, normalizeError := common.NormalizeISBN(upsertBookParameters.ISBN)
+
+		if normalizeError != nil {
+			return http.StatusBadRequest, normalizeError
+		}
+
+		upsertBookParameters.ISBN = isbn
+
+		if catalog != nil {
+			lookupError = FillBookFromCatalog(ctx, catalog, upsertBookParameters)
+
+			if lookupError != nil && !errors.Is(lookupError, common.ErrCatalogBookNotFound) {
+				log.Printf("catalog lookup for isbn %s failed: %s", isbn, lookupError)
+			}
+		}
+	}
+
+	if strings.TrimSpace(upsertBookParameters.Title) == "" || strings.TrimSpace(upsertBookParameters.Author) == "" {
+		if lookupError != nil && !errors.Is(lookupError, common.ErrCatalogBookNotFound) {
+			return http.StatusBadGateway, errors.New("could not look up the isbn right now, send the title and author or try again later")
+		}
+
+		if upsertBookParameters.ISBN != "" {
+			return http.StatusBadRequest, errors.New("title and author are required when the isbn is not in the catalog")
+		}
+
+		return http.StatusBadRequest, errors.New("title and author are required")
+	}
+
+	return 0, nil
+}
+
+// Checks a new book the way every path that adds books does and fills in the
+// defaults. The returned status is the one to respond with when the book can't be added.
+func ResolveNewBookParameters(ctx context.Context, catalog common.CatalogProvider, querier common.Querier, upsertBookParameters *UpsertBookParameters) (int, error) {
+	resolveStatus, resolveError := ResolveUpsertBookParameters(ctx, catalog, upsertBookParameters)
+
+	if resolveError == nil {
+		resolveStatus, resolveError = ResolveBookGenresAndTags(ctx, querier, upsertBookParameters)
+	}
+
+	if resolveError != nil {
+		return resolveStatus, resolveError
+	}
+
+	validatePoliciesError := book_policies.ValidateBookPolicies(upsertBookParameters.Visibility, upsertBookParameters.BorrowerPolicy)
+
+	if validatePoliciesError == nil {
+		validatePoliciesError = borrower_reputation.ValidateMinBorrowerReputation(upsertBookParameters.MinBorrowerReputation)
+	}
+
+	if validatePoliciesError != nil {
+		return http.StatusBadRequest, validatePoliciesError
+	}
+
+	// New books can be found and borrowed by anyone unless the owner says otherwise.
+	if upsertBookParameters.Visibility == "" {
+		upsertBookParameters.Visibility = book_policies.BookVisibilityPublic
+	}
+
+	if upsertBookParameters.BorrowerPolicy == "" {
+		upsertBookParameters.BorrowerPolicy = book_policies.BookBorrowerPolicyAnyone
+	}
+
+	// Left at 0, the book gets the owner's default loan period when it is created.
+	if upsertBookParameters.LoanPeriodDays != 0 && !common.IsLoanPeriodValid(upsertBookParameters.LoanPeriodDays) {
+		return http.StatusBadRequest, fmt.Errorf("loan_period_days must be between 1 and %d", common.MaxLoanPeriodDays)
+	}
+
+	if upsertBookParameters.Copies == 0 {
+		upsertBookParameters.Copies = 1
+	}
+
+	if upsertBookParameters.Copies < 0 || upsertBookParameters.Copies > book_copies.MaxNewBookCopies {
+		return http.StatusBadRequest, fmt.Errorf("copies must be between 1 and %d", book_copies.MaxNewBookCopies)
+	}
+
+	return 0, nil
+}
+
+// Expects parameters already checked by ResolveNewBookParameters.
+func NewCreateBookParams(userId uuid.UUID, upsertBookParameters UpsertBookParameters) database.CreateBookParams {
+	// Everyone can borrow a new book unless the owner sets a minimum reputation.
+	minBorrowerReputation := int32(0)
+
+	if upsertBookParameters.MinBorrowerReputation != nil {
+		minBorrowerReputation = *upsertBookParameters.MinBorrowerReputation
+	}
+
+	return database.CreateBookParams{
+		ID:                    uuid.New(),
+		Title:                 upsertBookParameters.Title,
+		Author:                upsertBookParameters.Author,
+		CreatedAt:             time.Now().UTC(),
+		UpdatedAt:             time.Now().UTC(),
+		UserID:                userId,
+		LoanPeriodDays:        upsertBookParameters.LoanPeriodDays,
+		Isbn:                  upsertBookParameters.ISBN,
+		Publisher:             upsertBookParameters.Publisher,
+		PublishedYear:         upsertBookParameters.PublishedYear,
+		PageCount:             upsertBookParameters.PageCount,
+		CoverUrl:              upsertBookParameters.CoverURL,
+		Visibility:            upsertBookParameters.Visibility,
+		Lendable:              upsertBookParameters.Lendable == nil || *upsertBookParameters.Lendable,
+		BorrowerPolicy:        upsertBookParameters.BorrowerPolicy,
+		MinBorrowerReputation: minBorrowerReputation,
+	}
+}
+
+// Adds the book with its copies, genres and tags. Meant to run in a transaction.
+func CreateBookWithCopies(ctx context.Context, querier common.Querier, createBookParams database.CreateBookParams, upsertBookParameters UpsertBookParameters) (database.Book, error) {
+	newBook, createError := querier.CreateBook(ctx, createBookParams)
+
+	if createError != nil {
+		return database.Book{}, createError
+	}
+
+	_, createError = book_copies.CreateBookCopies(ctx, querier, newBook.ID, upsertBookParameters.Copies, book_copies.BookCopyConditionGood)
+
+	if createError != nil {
+		return database.Book{}, createError
+	}
+
+	createError = book_tags.SetBookGenresAndTags(ctx, querier, newBook.ID, upsertBookParameters.Genres, upsertBookParameters.Tags)
+
+	if createError != nil {
+		return database.Book{}, createError
+	}
+
+	return newBook, nil
+}
+
+// Normalizes the tags and checks the genres against the curated list. Lists the
+// owner left out stay nil so updates keep the current ones.
+func ResolveBookGenresAndTags(ctx context.Context, querier common.Querier, upsertBookParameters *UpsertBookParameters) (int, error) {
+	if upsertBookParameters.Tags != nil {
+		tags, normalizeError := book_tags.NormalizeBookTags(upsertBookParameters.Tags)
+
+		if normalizeError != nil {
+			return http.StatusBadRequest, normalizeError
+		}
+
+		upsertBookParameters.Tags = tags
+	}
+
+	if len(upsertBookParameters.Genres) > 0 {
+		// Only the slugs are checked, so the book counts don't need a viewer.
+		curatedGenres, getGenresError := querier.GetGenres(ctx, uuid.Nil)
+
+		if getGenresError != nil {
+			return http.StatusInternalServerError, fmt.Errorf("error getting genres: %s", getGenresError)
+		}
+
+		genres, normalizeError := book_tags.NormalizeBookGenres(upsertBookParameters.Genres, curatedGenres)
+
+		if normalizeError != nil {
+			return http.StatusBadRequest, normalizeError
+		}
+
+		upsertBookParameters.Genres = genres
+	}
+
+	return 0, nil
+}
+
+// Only fills fields the owner left blank.
+func FillBookFromCatalog(ctx context.Context, catalog common.CatalogProvider, upsertBookParameters *UpsertBookParameters) error {
+	bookMetadata, lookupError := catalog.LookupISBN(ctx, upsertBookParameters.ISBN)
+
+	if lookupError != nil {
+		return lookupError
+	}
+
+	if strings.TrimSpace(upsertBookParameters.Title) == "" {
+		upsertBookParameters.Title = bookMetadata.Title
+	}
+
+	if strings.TrimSpace(upsertBookParameters.Author) == "" {
+		upsertBookParameters.Author = strings.Join(bookMetadata.Authors, ", ")
+	}
+
+	if upsertBookParameters.Publisher == "" {
+		upsertBookParameters.Publisher = bookMetadata.Publisher
+	}
+
+	if upsertBookParameters.PublishedYear == 0 {
+		upsertBookParameters.PublishedYear = bookMetadata.PublishedYear
+	}
+
+	if upsertBookParameters.PageCount == 0 {
+		upsertBookParameters.PageCount = bookMetadata.PageCount
+	}
+
+	if upsertBookParameters.CoverURL == "" {
+		upsertBookParameters.CoverURL = bookMetadata.CoverURL
+	}
+
+	return nil
+}
+
+// Books owned by a user are paged by title.
+func BookCursor(databaseGetBooksRow database.GetBooksRow) common.Cursor {
+	return common.Cursor{Value: databaseGetBooksRow.Book.Title, ID: databaseGetBooksRow.Book.ID}
+}
+
+// Reads the optional ?shelf= query value. The shelf has to belong to the owner of
+// the books, and other members can only filter by the public ones.
+func ResolveShelfFilter(ctx context.Context, querier common.Querier, query url.Values, userId uuid.UUID, viewerId uuid.UUID) (uuid.NullUUID, int, error) {
+	rawShelfId := query.Get("shelf")
+
+	if rawShelfId == "" {
+		return uuid.NullUUID{}, 0, nil
+	}
+
+	shelfId, parseShelfIdError := uuid.Parse(rawShelfId)
+
+	if parseShelfIdError != nil {
+		return uuid.NullUUID{}, http.StatusBadRequest, errors.New("invalid shelf id")
+	}
+
+	getShelf, getShelfError := querier.GetShelf(ctx, database.GetShelfParams{ID: shelfId, ViewerID: viewerId})
+
+	if getShelfError != nil && getShelfError != sql.ErrNoRows {
+		return uuid.NullUUID{}, http.StatusInternalServerError, errors.New("error getting shelf details, please try again in a few minutes")
+	}
+
+	if getShelfError == sql.ErrNoRows || getShelf.Shelf.UserID != userId || (!getShelf.Shelf.IsPublic && viewerId != userId) {
+		return uuid.NullUUID{}, http.StatusNotFound, errors.New("shelf not found")
+	}
+
+	return uuid.NullUUID{UUID: shelfId, Valid: true}, 0, nil
+}
+
+// Returns a page of the books owned by userId as seen by viewerId, only the ones
+// on shelfId when it is set.
+func GetBooksPage(ctx context.Context, querier common.Querier, userId uuid.UUID, viewerId uuid.UUID, shelfId uuid.NullUUID, pageParameters common.PageParameters) (common.Page[Book], error) {
+	getBooksParams := database.GetBooksParams{
+		UserID:    userId,
+		ViewerID:  viewerId,
+		ShelfID:   shelfId,
+		CursorID:  pageParameters.CursorID(),
+		PageLimit: pageParameters.QueryLimit(),
+	}
+
+	if pageParameters.Cursor != nil {
+		getBooksParams.CursorTitle = pageParameters.Cursor.Value
+	}
+
+	getBooks, getBooksError := querier.GetBooks(ctx, getBooksParams)
+
+	if getBooksError != nil {
+		return common.Page[Book]{}, getBooksError
+	}
+
+	getBooks, nextCursor := common.SplitPage(getBooks, pageParameters, BookCursor)
+
+	return common.Page[Book]{Data: DatabaseGetBooksRowsToBooksJSON(getBooks, viewerId), NextCursor: nextCursor}, nil
+}
+
+// Browsed books are paged by whichever key they are sorted on.
+func BrowseBookCursor(sort string) func(database.BrowseBooksRow) common.Cursor {
+	return func(databaseBrowseBooksRow database.BrowseBooksRow) common.Cursor {
+		cursor := common.Cursor{Sort: sort, ID: databaseBrowseBooksRow.Book.ID}
+
+		switch sort {
+		case BookSortTitle:
+			cursor.Value = databaseBrowseBooksRow.Book.Title
+		case BookSortCreatedAt:
+			cursor.Value = databaseBrowseBooksRow.Book.CreatedAt.Format(time.RFC3339Nano)
+		case BookSortRelevance:
+			cursor.Value = strconv.FormatFloat(float64(databaseBrowseBooksRow.Rank), 'g', -1, 32)
+		case BookSortRating:
+			cursor.Value = strconv.FormatFloat(databaseBrowseBooksRow.Book.RatingAverage, 'g', -1, 64)
+		}
+
+		return cursor
+	}
+}
+
+// Sets the keyset parameters from a cursor returned by an earlier page with the same sort.
+func SetBrowseBooksCursor(browseBooksParams *database.BrowseBooksParams, pageParameters common.PageParameters) error {
+	browseBooksParams.PageLimit = pageParameters.QueryLimit()
+
+	cursor := pageParameters.Cursor
+
+	if cursor == nil {
+		return nil
+	}
+
+	if cursor.Sort != browseBooksParams.Sort {
+		return errors.New("invalid cursor: it belongs to a different sort order")
+	}
+
+	switch cursor.Sort {
+	case BookSortTitle:
+		browseBooksParams.CursorTitle = cursor.Value
+	case BookSortCreatedAt:
+		cursorCreatedAt, parseError := time.Parse(time.RFC3339Nano, cursor.Value)
+
+		if parseError != nil {
+			return errors.New("invalid cursor")
+		}
+
+		browseBooksParams.CursorCreatedAt = cursorCreatedAt
+	case BookSortRelevance:
+		cursorRank, parseError := strconv.ParseFloat(cursor.Value, 32)
+
+		if parseError != nil {
+			return errors.New("invalid cursor")
+		}
+
+		browseBooksParams.CursorRank = float32(cursorRank)
+	case BookSortRating:
+		cursorRating, parseError := strconv.ParseFloat(cursor.Value, 64)
+
+		if parseError != nil {
+			return errors.New("invalid cursor")
+		}
+
+		browseBooksParams.CursorRating = cursorRating
+	}
+
+	browseBooksParams.CursorID = pageParameters.CursorID()
+
+	return nil
+}
+
+// Turns the ?q=, ?author=, ?available=, ?genre=, ?tag= and ?sort= query values into browse filters.
+// Sorting by rating puts the best rated first, with unrated books last.
+func ParseBrowseBooksFilters(query url.Values) (database.BrowseBooksParams, error) {
+	browseBooksParams := database.BrowseBooksParams{Sort: BookSortTitle}
+
+	if searchQuery := strings.TrimSpace(query.Get("q")); searchQuery != "" {
+		browseBooksParams.Query = sql.NullString{String: searchQuery, Valid: true}
+		browseBooksParams.Sort = BookSortRelevance
+	}
+
+	if author := strings.TrimSpace(query.Get("author")); author != "" {
+		browseBooksParams.Author = sql.NullString{String: author, Valid: true}
+	}
+
+	if available := query.Get("available"); available != "" {
+		isAvailable, parseAvailableError := strconv.ParseBool(available)
+
+		if parseAvailableError != nil {
+			return database.BrowseBooksParams{}, fmt.Errorf("invalid available: %s", available)
+		}
+
+		browseBooksParams.Available = sql.NullBool{Bool: isAvailable, Valid: true}
+	}
+
+	if genre := strings.TrimSpace(query.Get("genre")); genre != "" {
+		browseBooksParams.Genre = sql.NullString{String: strings.ToLower(genre), Valid: true}
+	}
+
+	if tag := query.Get("tag"); tag != "" {
+		normalizedTag, normalizeTagError := book_tags.NormalizeBookTag(tag)
+
+		if normalizeTagError != nil {
+			return database.BrowseBooksParams{}, normalizeTagError
+		}
+
+		browseBooksParams.Tag = sql.NullString{String: normalizedTag, Valid: true}
+	}
+
+	switch sort := query.Get("sort"); sort {
+	case "":
+	case BookSortTitle, BookSortCreatedAt, BookSortRating:
+		browseBooksParams.Sort = sort
+	default:
+		return database.BrowseBooksParams{}, fmt.Errorf("invalid sort: %s, use %s, %s or %s", sort, BookSortTitle, BookSortCreatedAt, BookSortRating)
+	}
+
+	return browseBooksParams, nil
+}
+
+// Queues a new book alert for each of the owner's subscribers. Private books are
+// skipped since subscribers can't see them.
+func EnqueueNewBookAlerts(ctx context.Context, querier common.Querier, templates common.TemplateRenderer, book database.Book) error {
+	if book.Visibility == book_policies.BookVisibilityPrivate {
+		return nil
+	}
+
+	subscribers, getSubscribersError := querier.GetUsersBySubscriberID(ctx, book.UserID)
+
+	if getSubscribersError != nil || len(subscribers) == 0 {
+		return getSubscribersError
+	}
+
+	owner, getOwnerError := querier.GetUserByID(ctx, book.UserID)
+
+	if getOwnerError != nil {
+		return getOwnerError
+	}
+
+	for _, subscriber := range subscribers {
+		newBookAlert, renderError := users.NewBookAlertNotification(templates, owner, subscriber, book.Title)
+
+		if renderError != nil {
+			return renderError
+		}
+
+		enqueueError := notification_outbox.EnqueueNotification(ctx, querier, newBookAlert)
+
+		if enqueueError != nil {
+			return enqueueError
+		}
+	}
+
+	return nil
+}
+
+// Queues one alert per subscriber for a batch of the owner's new books instead of
+// one per book. Private books are left out, a single book gets the usual alert.
+func EnqueueNewBooksAlerts(ctx context.Context, querier common.Querier, templates common.TemplateRenderer, ownerId uuid.UUID, newBooks []database.Book) error {
+	var visibleBooks []database.Book
+
+	for _, newBook := range newBooks {
+		if newBook.Visibility != book_policies.BookVisibilityPrivate {
+			visibleBooks = append(visibleBooks, newBook)
+		}
+	}
+
+	if len(visibleBooks) == 0 {
+		return nil
+	}
+
+	if len(visibleBooks) == 1 {
+		return EnqueueNewBookAlerts(ctx, querier, templates, visibleBooks[0])
+	}
+
+	subscribers, getSubscribersError := querier.GetUsersBySubscriberID(ctx, ownerId)
+
+	if getSubscribersError != nil || len(subscribers) == 0 {
+		return getSubscribersError
+	}
+
+	owner, getOwnerError := querier.GetUserByID(ctx, ownerId)
+
+	if getOwnerError != nil {
+		return getOwnerError
+	}
+
+	bookTitles := make([]string, 0, len(visibleBooks))
+
+	for _, visibleBook := range visibleBooks {
+		bookTitles = append(bookTitles, visibleBook.Title)
+	}
+
+	for _, subscriber := range subscribers {
+		newBooksAlert, renderError := users.NewBooksAlertNotification(templates, owner, subscriber, bookTitles, MaxNewBooksAlertTitles)
+
+		if renderError != nil {
+			return renderError
+		}
+
+		enqueueError := notification_outbox.EnqueueNotification(ctx, querier, newBooksAlert)
+
+		if enqueueError != nil {
+			return enqueueError
+		}
+	}
+
+	return nil
+}
@@ -1,26 +1,94 @@
 package books
 
 import (
+	"database/sql"
 	"time"
 
 	"github.com/elorenzorodz/co-library/common"
 	"github.com/google/uuid"
 )
 
+// Sort orders accepted by ?sort= when browsing. Searches are ranked by
+// relevance unless another order is asked for.
+const (
+	BookSortTitle     = "title"
+	BookSortCreatedAt = "created_at"
+	BookSortRelevance = "relevance"
+	BookSortRating    = "rating"
+)
+
+// How many titles an alert about a batch of new books lists before "and N more".
+const MaxNewBooksAlertTitles = 10
+
 type BookAPIConfig struct {
 	common.APIConfig
 }
 
 type Book struct {
-	ID        uuid.UUID `json:"id"`
-	Title     string    `json:"title"`
-	Author    string    `json:"author"`
-	CreatedAt time.Time `json:"createdAt"`
-	UpdatedAt time.Time `json:"updatedAt"`
-	UserID    uuid.UUID `json:"user_id"`
+	ID             uuid.UUID `json:"id"`
+	Title          string    `json:"title"`
+	Author         string    `json:"author"`
+	CreatedAt      time.Time `json:"createdAt"`
+	UpdatedAt      time.Time `json:"updatedAt"`
+	UserID         uuid.UUID `json:"user_id"`
+	LoanPeriodDays int32     `json:"loan_period_days"`
+	ISBN           string    `json:"isbn"`
+	Publisher      string    `json:"publisher"`
+	PublishedYear  int32     `json:"published_year"`
+	PageCount      int32     `json:"page_count"`
+	CoverURL       string    `json:"cover_url"`
+	// Only set for covers uploaded to the library.
+	CoverThumbnailURL string   `json:"cover_thumbnail_url"`
+	Genres            []string `json:"genres"`
+	Tags              []string `json:"tags"`
+	Visibility        string   `json:"visibility"`
+	Lendable          bool     `json:"lendable"`
+	BorrowerPolicy    string   `json:"borrower_policy"`
+	// Members below this borrower reputation can't be issued the book, 0 lends to everyone.
+	MinBorrowerReputation int32 `json:"min_borrower_reputation"`
+	// Average of the members' star ratings, 0 until the first review.
+	RatingAverage float64 `json:"rating_average"`
+	RatingCount   int32   `json:"rating_count"`
+	// Only filled in by the endpoints that look up the lending state.
+	Availability *BookAvailability `json:"availability,omitempty"`
+}
+
+type BookAvailability struct {
+	Available       bool  `json:"available"`
+	TotalCopies     int64 `json:"total_copies"`
+	AvailableCopies int64 `json:"available_copies"`
+	QueueLength     int64 `json:"queue_length"`
+	// When the next copy out on loan is due back.
+	DueAt sql.NullTime `json:"dueAt"`
+	// Only shown to the book owner.
+	CurrentBorrower *BookBorrower `json:"current_borrower,omitempty"`
+}
+
+type BookBorrower struct {
+	ID   uuid.UUID `json:"id"`
+	Name string    `json:"name"`
 }
 
+// Title and author can be left out when the ISBN is in the catalog.
 type UpsertBookParameters struct {
-	Title  string `json:"title"`
-	Author string `json:"author"`
-}
\ No newline at end of file
+	Title          string `json:"title"`
+	Author         string `json:"author"`
+	LoanPeriodDays int32  `json:"loan_period_days"`
+	ISBN           string `json:"isbn"`
+	Publisher      string `json:"publisher"`
+	PublishedYear  int32  `json:"published_year"`
+	PageCount      int32  `json:"page_count"`
+	CoverURL       string `json:"cover_url"`
+	// How many copies to put on the shelf with a new book, updates ignore it.
+	Copies int32 `json:"copies"`
+	// Slugs from the curated genres list and free-form tags. Left out on update
+	// to keep the current ones, an empty list clears them.
+	Genres []string `json:"genres"`
+	Tags   []string `json:"tags"`
+	// Who can find and borrow the book, left out to use the defaults on create
+	// and to keep the current ones on update.
+	Visibility            string `json:"visibility"`
+	Lendable              *bool  `json:"lendable"`
+	BorrowerPolicy        string `json:"borrower_policy"`
+	MinBorrowerReputation *int32 `json:"min_borrower_reputation"`
+}
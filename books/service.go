@@ -4,14 +4,13 @@ import (
 	"database/sql"
 	"encoding/json"
 	"fmt"
-	"log"
 	"net/http"
-	"strings"
-	"time"
 
+	"github.com/elorenzorodz/co-library/book_policies"
+	"github.com/elorenzorodz/co-library/book_tags"
+	"github.com/elorenzorodz/co-library/borrower_reputation"
 	"github.com/elorenzorodz/co-library/common"
 	"github.com/elorenzorodz/co-library/internal/database"
-	"github.com/elorenzorodz/co-library/users"
 	"github.com/google/uuid"
 	"github.com/gorilla/mux"
 )
@@ -28,22 +27,31 @@ func (bookAPIConfig *BookAPIConfig) CreateBook(writer http.ResponseWriter, reque
 		return
 	}
 
-	if strings.TrimSpace(upsertBookParameters.Title) == "" || strings.TrimSpace(upsertBookParameters.Author) == "" {
-		common.ErrorResponse(writer, http.StatusBadRequest, "title and author are required")
-		
+	resolveStatus, resolveError := ResolveNewBookParameters(request.Context(), bookAPIConfig.Catalog, bookAPIConfig.DB, &upsertBookParameters)
+
+	if resolveError != nil {
+		common.ErrorResponse(writer, resolveStatus, resolveError.Error())
+
 		return
 	}
 
-	createBookParams := database.CreateBookParams{
-		ID:        uuid.New(),
-		Title:     upsertBookParameters.Title,
-		Author:    upsertBookParameters.Author,
-		CreatedAt: time.Now().UTC(),
-		UpdatedAt: time.Now().UTC(),
-		UserID:    userId,
-	}
+	createBookParams := NewCreateBookParams(userId, upsertBookParameters)
+
+	var newBook database.Book
+
+	// The copies, genres, tags and subscriber alerts are written in the same transaction as the book, so they are
+	// delivered by the outbox worker exactly when the book exists.
+	createBookError := bookAPIConfig.InTransaction(request.Context(), func(querier common.Querier) error {
+		var createError error
+
+		newBook, createError = CreateBookWithCopies(request.Context(), querier, createBookParams, upsertBookParameters)
+
+		if createError != nil {
+			return createError
+		}
 
-	newBook, createBookError := bookAPIConfig.DB.CreateBook(request.Context(), createBookParams)
+		return EnqueueNewBookAlerts(request.Context(), querier, bookAPIConfig.Templates, newBook)
+	})
 
 	if createBookError != nil {
 		common.ErrorResponse(writer, http.StatusInternalServerError, fmt.Sprintf("error creating book: %s", createBookError))
@@ -51,26 +59,37 @@ func (bookAPIConfig *BookAPIConfig) CreateBook(writer http.ResponseWriter, reque
 		return
 	}
 
-	// Alert subscribers about the new book.
-	subscribers, getSubscribersErrors := bookAPIConfig.DB.GetUsersBySubscriberID(request.Context(), userId)
-
-	if getSubscribersErrors != nil {
-		log.Printf("failed to get subscribers for new book alert: %s", getSubscribersErrors)
-	} else {
-		senderUser, getUserError := bookAPIConfig.DB.GetUserByID(request.Context(), userId)
-
-		if getUserError != nil {
-			log.Printf("failed to get book owner details: %s", getUserError)
-		} else {
-			go users.DispatchNewBookAlertsSync(upsertBookParameters.Title, subscribers, senderUser, bookAPIConfig.APIConfig.MailgunAPIKey, bookAPIConfig.APIConfig.MailgunSendingDomain)
-		}
+	// A new book has nothing out on loan and nobody waiting for it yet.
+	book := DatabaseBookToBookJSON(newBook)
+	book.Genres = append(book.Genres, upsertBookParameters.Genres...)
+	book.Tags = append(book.Tags, upsertBookParameters.Tags...)
+	book.Availability = &BookAvailability{
+		Available:       true,
+		TotalCopies:     int64(upsertBookParameters.Copies),
+		AvailableCopies: int64(upsertBookParameters.Copies),
 	}
 
-	common.JSONResponse(writer, http.StatusCreated, DatabaseBookToBookJSON(newBook))
+	common.JSONResponse(writer, http.StatusCreated, book)
 }
 
 func (bookAPIConfig *BookAPIConfig) GetBooks(writer http.ResponseWriter, request *http.Request, userId uuid.UUID) {
-	getBooks, getBooksError := bookAPIConfig.DB.GetBooks(request.Context(), userId)
+	pageParameters, parsePageError := common.ParsePageParameters(request.URL.Query())
+
+	if parsePageError != nil {
+		common.ErrorResponse(writer, http.StatusBadRequest, parsePageError.Error())
+
+		return
+	}
+
+	shelfId, resolveShelfStatus, resolveShelfError := ResolveShelfFilter(request.Context(), bookAPIConfig.DB, request.URL.Query(), userId, userId)
+
+	if resolveShelfError != nil {
+		common.ErrorResponse(writer, resolveShelfStatus, resolveShelfError.Error())
+
+		return
+	}
+
+	booksPage, getBooksError := GetBooksPage(request.Context(), bookAPIConfig.DB, userId, userId, shelfId, pageParameters)
 
 	if getBooksError != nil {
 		common.ErrorResponse(writer, http.StatusInternalServerError, fmt.Sprintf("error getting books: %s", getBooksError))
@@ -78,7 +97,7 @@ func (bookAPIConfig *BookAPIConfig) GetBooks(writer http.ResponseWriter, request
 		return
 	}
 
-	common.JSONResponse(writer, http.StatusOK, DatabaseBooksToBooksJSON(getBooks))
+	common.JSONResponse(writer, http.StatusOK, booksPage)
 }
 
 func (bookAPIConfig *BookAPIConfig) GetBook(writer http.ResponseWriter, request *http.Request, userId uuid.UUID) {
@@ -91,7 +110,7 @@ func (bookAPIConfig *BookAPIConfig) GetBook(writer http.ResponseWriter, request
 		return
 	}
 
-	getBook, getBookError := bookAPIConfig.DB.GetBook(request.Context(), bookId)
+	getBook, getBookError := bookAPIConfig.DB.GetBookWithAvailability(request.Context(), bookId)
 
 	if getBookError != nil {
 		if getBookError == sql.ErrNoRows {
@@ -103,7 +122,26 @@ func (bookAPIConfig *BookAPIConfig) GetBook(writer http.ResponseWriter, request
 		return
 	}
 
-	common.JSONResponse(writer, http.StatusOK, DatabaseBookToBookJSON(getBook))
+	canViewBook, checkViewerError := book_policies.CheckBookViewer(request.Context(), bookAPIConfig.DB, getBook.Book, userId)
+
+	if checkViewerError != nil {
+		common.ErrorResponse(writer, http.StatusInternalServerError, "error getting book details, please try again in a few minutes")
+
+		return
+	}
+
+	// Books hidden from the viewer are not found, so they don't learn that it exists.
+	if !canViewBook {
+		common.ErrorResponse(writer, http.StatusNotFound, "book not found")
+
+		return
+	}
+
+	book := DatabaseBookWithAvailabilityToBookJSON(getBook.Book, getBook.BookAvailability, userId)
+	book.Genres = append(book.Genres, getBook.Genres...)
+	book.Tags = append(book.Tags, getBook.Tags...)
+
+	common.JSONResponse(writer, http.StatusOK, book)
 }
 
 func (bookAPIConfig *BookAPIConfig) UpdateBook(writer http.ResponseWriter, request *http.Request, userId uuid.UUID) {
@@ -127,20 +165,90 @@ func (bookAPIConfig *BookAPIConfig) UpdateBook(writer http.ResponseWriter, reque
 		return
 	}
 
-	if strings.TrimSpace(upsertBookParameters.Title) == "" || strings.TrimSpace(upsertBookParameters.Author) == "" {
-		common.ErrorResponse(writer, http.StatusBadRequest, "title and author are required")
-		
+	resolveStatus, resolveError := ResolveUpsertBookParameters(request.Context(), bookAPIConfig.Catalog, &upsertBookParameters)
+
+	if resolveError == nil {
+		resolveStatus, resolveError = ResolveBookGenresAndTags(request.Context(), bookAPIConfig.DB, &upsertBookParameters)
+	}
+
+	if resolveError != nil {
+		common.ErrorResponse(writer, resolveStatus, resolveError.Error())
+
+		return
+	}
+
+	validatePoliciesError := book_policies.ValidateBookPolicies(upsertBookParameters.Visibility, upsertBookParameters.BorrowerPolicy)
+
+	if validatePoliciesError == nil {
+		validatePoliciesError = borrower_reputation.ValidateMinBorrowerReputation(upsertBookParameters.MinBorrowerReputation)
+	}
+
+	if validatePoliciesError != nil {
+		common.ErrorResponse(writer, http.StatusBadRequest, validatePoliciesError.Error())
+
 		return
 	}
 
+	// Keep the current loan period when the owner didn't send a new one.
+	loanPeriodDays := sql.NullInt32{}
+
+	if upsertBookParameters.LoanPeriodDays != 0 {
+		if !common.IsLoanPeriodValid(upsertBookParameters.LoanPeriodDays) {
+			common.ErrorResponse(writer, http.StatusBadRequest, fmt.Sprintf("loan_period_days must be between 1 and %d", common.MaxLoanPeriodDays))
+
+			return
+		}
+
+		loanPeriodDays = sql.NullInt32{Int32: upsertBookParameters.LoanPeriodDays, Valid: true}
+	}
+
+	// Keep the current minimum reputation when the owner didn't send one.
+	minBorrowerReputation := sql.NullInt32{}
+
+	if upsertBookParameters.MinBorrowerReputation != nil {
+		minBorrowerReputation = sql.NullInt32{Int32: *upsertBookParameters.MinBorrowerReputation, Valid: true}
+	}
+
 	updateBookParams := database.UpdateBookParams{
-		Title:  upsertBookParameters.Title,
-		Author: upsertBookParameters.Author,
-		ID:     bookId,
-		UserID: userId,
+		Title:                 upsertBookParameters.Title,
+		Author:                upsertBookParameters.Author,
+		LoanPeriodDays:        loanPeriodDays,
+		Isbn:                  sql.NullString{String: upsertBookParameters.ISBN, Valid: upsertBookParameters.ISBN != ""},
+		Publisher:             sql.NullString{String: upsertBookParameters.Publisher, Valid: upsertBookParameters.Publisher != ""},
+		PublishedYear:         sql.NullInt32{Int32: upsertBookParameters.PublishedYear, Valid: upsertBookParameters.PublishedYear != 0},
+		PageCount:             sql.NullInt32{Int32: upsertBookParameters.PageCount, Valid: upsertBookParameters.PageCount != 0},
+		CoverUrl:              sql.NullString{String: upsertBookParameters.CoverURL, Valid: upsertBookParameters.CoverURL != ""},
+		Visibility:            sql.NullString{String: upsertBookParameters.Visibility, Valid: upsertBookParameters.Visibility != ""},
+		Lendable:              sql.NullBool{Bool: upsertBookParameters.Lendable != nil && *upsertBookParameters.Lendable, Valid: upsertBookParameters.Lendable != nil},
+		BorrowerPolicy:        sql.NullString{String: upsertBookParameters.BorrowerPolicy, Valid: upsertBookParameters.BorrowerPolicy != ""},
+		MinBorrowerReputation: minBorrowerReputation,
+		ID:                    bookId,
+		UserID:                userId,
 	}
 
-	updateBook, updateBookError := bookAPIConfig.DB.UpdateBook(request.Context(), updateBookParams)
+	var updateBook database.Book
+	var bookGenresAndTags database.GetBookGenresAndTagsRow
+
+	// The book is only found for its owner, so the genres and tags are replaced after it.
+	updateBookError := bookAPIConfig.InTransaction(request.Context(), func(querier common.Querier) error {
+		var updateError error
+
+		updateBook, updateError = querier.UpdateBook(request.Context(), updateBookParams)
+
+		if updateError != nil {
+			return updateError
+		}
+
+		updateError = book_tags.SetBookGenresAndTags(request.Context(), querier, bookId, upsertBookParameters.Genres, upsertBookParameters.Tags)
+
+		if updateError != nil {
+			return updateError
+		}
+
+		bookGenresAndTags, updateError = querier.GetBookGenresAndTags(request.Context(), bookId)
+
+		return updateError
+	})
 
 	if updateBookError != nil {
 		if updateBookError == sql.ErrNoRows {
@@ -152,7 +260,11 @@ func (bookAPIConfig *BookAPIConfig) UpdateBook(writer http.ResponseWriter, reque
 		return
 	}
 
-	common.JSONResponse(writer, http.StatusOK, DatabaseBookToBookJSON(updateBook))
+	book := DatabaseBookToBookJSON(updateBook)
+	book.Genres = append(book.Genres, bookGenresAndTags.Genres...)
+	book.Tags = append(book.Tags, bookGenresAndTags.Tags...)
+
+	common.JSONResponse(writer, http.StatusOK, book)
 }
 
 func (bookAPIConfig *BookAPIConfig) DeleteBook(writer http.ResponseWriter, request *http.Request, userId uuid.UUID) {
@@ -170,7 +282,25 @@ func (bookAPIConfig *BookAPIConfig) DeleteBook(writer http.ResponseWriter, reque
 		UserID: userId,
 	}
 
-	rowsAffected, deleteBookError := bookAPIConfig.DB.DeleteBook(request.Context(), deleteBookParams)
+	var rowsAffected int64
+
+	// The book goes to the trash rather than away, so its loan history survives. Whoever was
+	// asking for it or waiting in line is let go in the same transaction.
+	deleteBookError := bookAPIConfig.InTransaction(request.Context(), func(querier common.Querier) error {
+		var deleteError error
+
+		rowsAffected, deleteError = querier.DeleteBook(request.Context(), deleteBookParams)
+
+		if deleteError != nil || rowsAffected == 0 {
+			return deleteError
+		}
+
+		if declineError := querier.DeclineOpenBookBorrowRequests(request.Context(), bookId); declineError != nil {
+			return declineError
+		}
+
+		return querier.CancelBookReservations(request.Context(), bookId)
+	})
 
 	if deleteBookError != nil {
 		common.ErrorResponse(writer, http.StatusInternalServerError, fmt.Sprintf("error deleting book: %s", deleteBookError))
@@ -179,16 +309,54 @@ func (bookAPIConfig *BookAPIConfig) DeleteBook(writer http.ResponseWriter, reque
 	}
 
 	if rowsAffected == 0 {
-		common.ErrorResponse(writer, http.StatusNotFound, "book not found")
+		// Nothing was deleted either because the book isn't the caller's or because a copy is still out.
+		getBook, getBookError := bookAPIConfig.DB.GetBook(request.Context(), bookId)
+
+		if getBookError != nil && getBookError != sql.ErrNoRows {
+			common.ErrorResponse(writer, http.StatusInternalServerError, fmt.Sprintf("error deleting book: %s", getBookError))
+
+			return
+		}
+
+		if getBookError == sql.ErrNoRows || getBook.UserID != userId {
+			common.ErrorResponse(writer, http.StatusNotFound, "book not found")
+
+			return
+		}
 
-        return
+		common.ErrorResponse(writer, http.StatusConflict, "book is out on loan, it can be deleted once every copy is returned")
+
+		return
 	}
 
-	common.JSONResponse(writer, http.StatusOK, "book successfully deleted")
+	common.JSONResponse(writer, http.StatusOK, "book moved to trash")
 }
 
 func (bookAPIConfig *BookAPIConfig) BrowseBooks(writer http.ResponseWriter, request *http.Request, userId uuid.UUID) {
-	browseBooks, getBooksError := bookAPIConfig.DB.BrowseBooks(request.Context())
+	browseBooksParams, parseFiltersError := ParseBrowseBooksFilters(request.URL.Query())
+
+	if parseFiltersError != nil {
+		common.ErrorResponse(writer, http.StatusBadRequest, parseFiltersError.Error())
+
+		return
+	}
+
+	pageParameters, parsePageError := common.ParsePageParameters(request.URL.Query())
+
+	if parsePageError == nil {
+		parsePageError = SetBrowseBooksCursor(&browseBooksParams, pageParameters)
+	}
+
+	if parsePageError != nil {
+		common.ErrorResponse(writer, http.StatusBadRequest, parsePageError.Error())
+
+		return
+	}
+
+	// Only the books the member is allowed to see.
+	browseBooksParams.ViewerID = userId
+
+	browseBooks, getBooksError := bookAPIConfig.DB.BrowseBooks(request.Context(), browseBooksParams)
 
 	if getBooksError != nil {
 		common.ErrorResponse(writer, http.StatusInternalServerError, fmt.Sprintf("error getting books: %s", getBooksError))
@@ -196,7 +364,9 @@ func (bookAPIConfig *BookAPIConfig) BrowseBooks(writer http.ResponseWriter, requ
 		return
 	}
 
-	common.JSONResponse(writer, http.StatusOK, DatabaseBooksToBooksJSON(browseBooks))
+	browseBooks, nextCursor := common.SplitPage(browseBooks, pageParameters, BrowseBookCursor(browseBooksParams.Sort))
+
+	common.JSONResponse(writer, http.StatusOK, common.Page[Book]{Data: DatabaseBrowseBooksRowsToBooksJSON(browseBooks, userId), NextCursor: nextCursor})
 }
 
 func (bookAPIConfig *BookAPIConfig) BrowseBooksByUserID(writer http.ResponseWriter, request *http.Request, uId uuid.UUID) {
@@ -209,7 +379,24 @@ func (bookAPIConfig *BookAPIConfig) BrowseBooksByUserID(writer http.ResponseWrit
 		return
 	}
 
-	browseBooks, getBooksError := bookAPIConfig.DB.GetBooks(request.Context(), userId)
+	pageParameters, parsePageError := common.ParsePageParameters(request.URL.Query())
+
+	if parsePageError != nil {
+		common.ErrorResponse(writer, http.StatusBadRequest, parsePageError.Error())
+
+		return
+	}
+
+	// Private shelves can't be browsed by other members.
+	shelfId, resolveShelfStatus, resolveShelfError := ResolveShelfFilter(request.Context(), bookAPIConfig.DB, request.URL.Query(), userId, uId)
+
+	if resolveShelfError != nil {
+		common.ErrorResponse(writer, resolveShelfStatus, resolveShelfError.Error())
+
+		return
+	}
+
+	booksPage, getBooksError := GetBooksPage(request.Context(), bookAPIConfig.DB, userId, uId, shelfId, pageParameters)
 
 	if getBooksError != nil {
 		if getBooksError == sql.ErrNoRows {
@@ -221,5 +408,5 @@ func (bookAPIConfig *BookAPIConfig) BrowseBooksByUserID(writer http.ResponseWrit
 		return
 	}
 
-	common.JSONResponse(writer, http.StatusOK, DatabaseBooksToBooksJSON(browseBooks))
+	common.JSONResponse(writer, http.StatusOK, booksPage)
 }
\ No newline at end of file
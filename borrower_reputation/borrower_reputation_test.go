@@ -0,0 +1,320 @@
+package borrower_reputation
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/elorenzorodz/co-library/common"
+	"github.com/elorenzorodz/co-library/internal/database"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+type MockQueries struct {
+	*common.BaseMock
+
+	GetBookBorrowWithOwnerFunc     func(ctx context.Context, id uuid.UUID) (database.GetBookBorrowWithOwnerRow, error)
+	GetBorrowerReputationStatsFunc func(ctx context.Context, borrowerID uuid.UUID) (database.BorrowerReputationStat, error)
+	CreateBorrowerRatingFunc       func(ctx context.Context, arg database.CreateBorrowerRatingParams) (database.BorrowerRating, error)
+}
+
+func (mockQueries *MockQueries) GetBookBorrowWithOwner(ctx context.Context, id uuid.UUID) (database.GetBookBorrowWithOwnerRow, error) {
+	if mockQueries.GetBookBorrowWithOwnerFunc != nil {
+		return mockQueries.GetBookBorrowWithOwnerFunc(ctx, id)
+	}
+
+	return mockQueries.BaseMock.GetBookBorrowWithOwner(ctx, id)
+}
+
+func (mockQueries *MockQueries) GetBorrowerReputationStats(ctx context.Context, borrowerID uuid.UUID) (database.BorrowerReputationStat, error) {
+	if mockQueries.GetBorrowerReputationStatsFunc != nil {
+		return mockQueries.GetBorrowerReputationStatsFunc(ctx, borrowerID)
+	}
+
+	return mockQueries.BaseMock.GetBorrowerReputationStats(ctx, borrowerID)
+}
+
+func (mockQueries *MockQueries) CreateBorrowerRating(ctx context.Context, arg database.CreateBorrowerRatingParams) (database.BorrowerRating, error) {
+	if mockQueries.CreateBorrowerRatingFunc != nil {
+		return mockQueries.CreateBorrowerRatingFunc(ctx, arg)
+	}
+
+	return mockQueries.BaseMock.CreateBorrowerRating(ctx, arg)
+}
+
+func newTestBookBorrow(ownerId uuid.UUID, borrowerId uuid.UUID, returned bool) database.GetBookBorrowWithOwnerRow {
+	bookBorrow := database.BookBorrow{
+		ID:         uuid.New(),
+		IssuedAt:   time.Now().AddDate(0, 0, -20),
+		DueAt:      time.Now().AddDate(0, 0, -6),
+		BookID:     uuid.New(),
+		BorrowerID: borrowerId,
+		BookCopyID: uuid.New(),
+	}
+
+	if returned {
+		bookBorrow.ReturnedAt = sql.NullTime{Time: time.Now().AddDate(0, 0, -7), Valid: true}
+	}
+
+	return database.GetBookBorrowWithOwnerRow{BookBorrow: bookBorrow, OwnerID: ownerId, BookTitle: "Dune"}
+}
+
+func TestComputeBorrowerReputationScore(tTesting *testing.T) {
+	testCases := []struct {
+		name          string
+		stat          database.BorrowerReputationStat
+		expectedScore int32
+	}{
+		// 1. New borrowers start in the middle.
+		{"New", database.BorrowerReputationStat{}, NewBorrowerReputation},
+		// 2. Every on-time return raises the score, without reaching 100 at once.
+		{"OneOnTime", database.BorrowerReputationStat{ScoredLoans: 1, OnTimeReturns: 1}, 67},
+		{"TenOnTime", database.BorrowerReputationStat{ScoredLoans: 10, OnTimeReturns: 10}, 92},
+		// 3. Late days cost 5 points each, up to 20 days a loan.
+		{"Late", database.BorrowerReputationStat{ScoredLoans: 2, OnTimeReturns: 1, LateLoans: 1, OverdueDays: 4, CappedOverdueDays: 4}, 70},
+		{"VeryLate", database.BorrowerReputationStat{ScoredLoans: 1, LateLoans: 1, OverdueDays: 90, CappedOverdueDays: 20}, 33},
+		// 4. Disputed returns and lost books weigh more.
+		{"Disputed", database.BorrowerReputationStat{ScoredLoans: 1, DisputedReturns: 1}, 50},
+		{"Lost", database.BorrowerReputationStat{ScoredLoans: 1, LostBooks: 1}, 33},
+		// 5. Owner ratings count as much as loans, one star for 0 and five for 100.
+		{"FiveStars", database.BorrowerReputationStat{ScoredLoans: 1, OnTimeReturns: 1, RatingCount: 1, RatingAverage: 5}, 75},
+		{"OneStar", database.BorrowerReputationStat{ScoredLoans: 1, OnTimeReturns: 1, RatingCount: 1, RatingAverage: 1}, 50},
+		// 6. The score never goes below 0.
+		{"AllDisputedLate", database.BorrowerReputationStat{ScoredLoans: 20, LateLoans: 20, CappedOverdueDays: 400, DisputedReturns: 20}, 0},
+	}
+
+	for _, testCase := range testCases {
+		tTesting.Run(testCase.name, func(t *testing.T) {
+			if score := ComputeBorrowerReputationScore(testCase.stat); score != testCase.expectedScore {
+				t.Errorf("Expected score %d, got %d", testCase.expectedScore, score)
+			}
+		})
+	}
+
+	// 7. The JSON marks members without any history as new.
+	tTesting.Run("NewFlag", func(t *testing.T) {
+		if !DatabaseBorrowerReputationStatToBorrowerReputationJSON(database.BorrowerReputationStat{}).New {
+			t.Error("Expected a member without loans or ratings to be new")
+		}
+
+		if DatabaseBorrowerReputationStatToBorrowerReputationJSON(database.BorrowerReputationStat{RatingCount: 1, RatingAverage: 4}).New {
+			t.Error("Expected a rated member not to be new")
+		}
+	})
+}
+
+func TestCheckBorrowerReputation(tTesting *testing.T) {
+	borrowerId := uuid.New()
+	testBook := database.Book{ID: uuid.New(), UserID: uuid.New(), MinBorrowerReputation: 60}
+	goodBorrower := func(ctx context.Context, id uuid.UUID) (database.BorrowerReputationStat, error) {
+		return database.BorrowerReputationStat{BorrowerID: id, ScoredLoans: 3, OnTimeReturns: 3}, nil
+	}
+
+	// 1. Success: books without a minimum don't look the borrower up.
+	tTesting.Run("NoMinimum", func(t *testing.T) {
+		mockQueries := &MockQueries{
+			BaseMock: common.NewBaseMock(),
+			GetBorrowerReputationStatsFunc: func(ctx context.Context, borrowerID uuid.UUID) (database.BorrowerReputationStat, error) {
+				t.Error("Expected no reputation lookup")
+
+				return database.BorrowerReputationStat{}, nil
+			},
+		}
+
+		if status, err := CheckBorrowerReputation(context.Background(), mockQueries, database.Book{}, borrowerId); err != nil || status != 0 {
+			t.Errorf("Expected no error, got %d %v", status, err)
+		}
+	})
+
+	// 2. Success: the borrower meets the minimum.
+	tTesting.Run("AboveMinimum", func(t *testing.T) {
+		mockQueries := &MockQueries{BaseMock: common.NewBaseMock(), GetBorrowerReputationStatsFunc: goodBorrower}
+
+		if status, err := CheckBorrowerReputation(context.Background(), mockQueries, testBook, borrowerId); err != nil || status != 0 {
+			t.Errorf("Expected no error, got %d %v", status, err)
+		}
+	})
+
+	// 3. Failure: new borrowers are below a minimum above the starting score.
+	tTesting.Run("BelowMinimum", func(t *testing.T) {
+		mockQueries := &MockQueries{
+			BaseMock: common.NewBaseMock(),
+			GetBorrowerReputationStatsFunc: func(ctx context.Context, borrowerID uuid.UUID) (database.BorrowerReputationStat, error) {
+				return database.BorrowerReputationStat{BorrowerID: borrowerID}, nil
+			},
+		}
+
+		status, err := CheckBorrowerReputation(context.Background(), mockQueries, testBook, borrowerId)
+
+		if status != http.StatusForbidden || err == nil || !strings.Contains(err.Error(), "at least 60, yours is 50") {
+			t.Errorf("Expected status %d with the scores, got %d %v", http.StatusForbidden, status, err)
+		}
+	})
+
+	// 4. Failure: the stats can't be read.
+	tTesting.Run("StatsError", func(t *testing.T) {
+		mockQueries := &MockQueries{
+			BaseMock: common.NewBaseMock(),
+			GetBorrowerReputationStatsFunc: func(ctx context.Context, borrowerID uuid.UUID) (database.BorrowerReputationStat, error) {
+				return database.BorrowerReputationStat{}, errors.New("connection reset")
+			},
+		}
+
+		if status, _ := CheckBorrowerReputation(context.Background(), mockQueries, testBook, borrowerId); status != http.StatusInternalServerError {
+			t.Errorf("Expected status %d, got %d", http.StatusInternalServerError, status)
+		}
+	})
+}
+
+func TestValidateMinBorrowerReputation(tTesting *testing.T) {
+	valid, tooHigh, negative := int32(80), int32(101), int32(-1)
+
+	// 1. Success: left out, or from 0 to 100.
+	tTesting.Run("Valid", func(t *testing.T) {
+		if ValidateMinBorrowerReputation(nil) != nil || ValidateMinBorrowerReputation(&valid) != nil {
+			t.Error("Expected no error")
+		}
+	})
+
+	// 2. Failure: outside the score range.
+	tTesting.Run("Invalid", func(t *testing.T) {
+		if ValidateMinBorrowerReputation(&tooHigh) == nil || ValidateMinBorrowerReputation(&negative) == nil {
+			t.Error("Expected an error")
+		}
+	})
+}
+
+func TestCreateBorrowerRating(tTesting *testing.T) {
+	ownerId, borrowerId := uuid.New(), uuid.New()
+
+	createBorrowerRating := func(mockQueries *MockQueries, bookBorrow database.GetBookBorrowWithOwnerRow, userId uuid.UUID, body string) *httptest.ResponseRecorder {
+		if mockQueries.GetBookBorrowWithOwnerFunc == nil {
+			mockQueries.GetBookBorrowWithOwnerFunc = func(ctx context.Context, id uuid.UUID) (database.GetBookBorrowWithOwnerRow, error) {
+				return bookBorrow, nil
+			}
+		}
+
+		apiConfig := BorrowerReputationAPIConfig{APIConfig: common.APIConfig{DB: mockQueries}}
+		request := httptest.NewRequest(http.MethodPost, "/api/v1/books/borrows/borrower-rating", strings.NewReader(body))
+		request = mux.SetURLVars(request, map[string]string{"bookBorrowId": bookBorrow.BookBorrow.ID.String()})
+		recorder := httptest.NewRecorder()
+
+		apiConfig.CreateBorrowerRating(recorder, request, userId)
+
+		return recorder
+	}
+
+	// 1. Success: the owner rates the borrower of a returned book.
+	tTesting.Run("Success", func(t *testing.T) {
+		bookBorrow := newTestBookBorrow(ownerId, borrowerId, true)
+
+		mockQueries := &MockQueries{
+			BaseMock: common.NewBaseMock(),
+			CreateBorrowerRatingFunc: func(ctx context.Context, arg database.CreateBorrowerRatingParams) (database.BorrowerRating, error) {
+				if arg.BookBorrowID != bookBorrow.BookBorrow.ID || arg.LenderID != ownerId || arg.BorrowerID != borrowerId {
+					t.Errorf("Unexpected params: %+v", arg)
+				}
+
+				return database.BorrowerRating{
+					ID:           arg.ID,
+					Rating:       arg.Rating,
+					Comment:      arg.Comment,
+					CreatedAt:    time.Now(),
+					BookBorrowID: arg.BookBorrowID,
+					LenderID:     arg.LenderID,
+					BorrowerID:   arg.BorrowerID,
+				}, nil
+			},
+		}
+
+		recorder := createBorrowerRating(mockQueries, bookBorrow, ownerId, `{"rating": 5, "comment": " Returned it like new "}`)
+
+		if recorder.Code != http.StatusCreated {
+			t.Fatalf("Expected status %d, got %d. Body: %s", http.StatusCreated, recorder.Code, recorder.Body.String())
+		}
+
+		var borrowerRating BorrowerRating
+		json.Unmarshal(recorder.Body.Bytes(), &borrowerRating)
+
+		if borrowerRating.Rating != 5 || borrowerRating.Comment != "Returned it like new" || borrowerRating.BorrowerID != borrowerId {
+			t.Errorf("Unexpected rating: %+v", borrowerRating)
+		}
+	})
+
+	// 1a. Success: a book reported lost can be rated too.
+	tTesting.Run("Lost", func(t *testing.T) {
+		bookBorrow := newTestBookBorrow(ownerId, borrowerId, false)
+		bookBorrow.BookBorrow.LostAt = sql.NullTime{Time: time.Now(), Valid: true}
+
+		mockQueries := &MockQueries{
+			BaseMock: common.NewBaseMock(),
+			CreateBorrowerRatingFunc: func(ctx context.Context, arg database.CreateBorrowerRatingParams) (database.BorrowerRating, error) {
+				return database.BorrowerRating{ID: arg.ID, Rating: arg.Rating}, nil
+			},
+		}
+
+		if recorder := createBorrowerRating(mockQueries, bookBorrow, ownerId, `{"rating": 1}`); recorder.Code != http.StatusCreated {
+			t.Errorf("Expected status %d, got %d", http.StatusCreated, recorder.Code)
+		}
+	})
+
+	// 2. Failure: only the owner rates the borrower.
+	tTesting.Run("NotOwner", func(t *testing.T) {
+		bookBorrow := newTestBookBorrow(ownerId, borrowerId, true)
+
+		if recorder := createBorrowerRating(&MockQueries{BaseMock: common.NewBaseMock()}, bookBorrow, borrowerId, `{"rating": 5}`); recorder.Code != http.StatusNotFound {
+			t.Errorf("Expected status %d, got %d", http.StatusNotFound, recorder.Code)
+		}
+	})
+
+	// 3. Failure: the book is still out.
+	tTesting.Run("NotReturned", func(t *testing.T) {
+		bookBorrow := newTestBookBorrow(ownerId, borrowerId, false)
+
+		if recorder := createBorrowerRating(&MockQueries{BaseMock: common.NewBaseMock()}, bookBorrow, ownerId, `{"rating": 5}`); recorder.Code != http.StatusConflict {
+			t.Errorf("Expected status %d, got %d", http.StatusConflict, recorder.Code)
+		}
+	})
+
+	// 4. Failure: one rating per loan.
+	tTesting.Run("Duplicate", func(t *testing.T) {
+		mockQueries := &MockQueries{
+			BaseMock: common.NewBaseMock(),
+			CreateBorrowerRatingFunc: func(ctx context.Context, arg database.CreateBorrowerRatingParams) (database.BorrowerRating, error) {
+				return database.BorrowerRating{}, sql.ErrNoRows
+			},
+		}
+
+		if recorder := createBorrowerRating(mockQueries, newTestBookBorrow(ownerId, borrowerId, true), ownerId, `{"rating": 4}`); recorder.Code != http.StatusConflict {
+			t.Errorf("Expected status %d, got %d", http.StatusConflict, recorder.Code)
+		}
+	})
+
+	// 5. Failure: ratings are 1 to 5 stars.
+	tTesting.Run("InvalidRating", func(t *testing.T) {
+		if recorder := createBorrowerRating(&MockQueries{BaseMock: common.NewBaseMock()}, newTestBookBorrow(ownerId, borrowerId, true), ownerId, `{"rating": 0}`); recorder.Code != http.StatusBadRequest {
+			t.Errorf("Expected status %d, got %d", http.StatusBadRequest, recorder.Code)
+		}
+	})
+
+	// 6. Failure: unknown loan.
+	tTesting.Run("NotFound", func(t *testing.T) {
+		mockQueries := &MockQueries{
+			BaseMock: common.NewBaseMock(),
+			GetBookBorrowWithOwnerFunc: func(ctx context.Context, id uuid.UUID) (database.GetBookBorrowWithOwnerRow, error) {
+				return database.GetBookBorrowWithOwnerRow{}, sql.ErrNoRows
+			},
+		}
+
+		if recorder := createBorrowerRating(mockQueries, newTestBookBorrow(ownerId, borrowerId, true), ownerId, `{"rating": 4}`); recorder.Code != http.StatusNotFound {
+			t.Errorf("Expected status %d, got %d", http.StatusNotFound, recorder.Code)
+		}
+	})
+}
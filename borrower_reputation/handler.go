@@ -0,0 +1,115 @@
+package borrower_reputation
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strings"
+
+	"github.com/elorenzorodz/co-library/common"
+	"github.com/elorenzorodz/co-library/internal/database"
+	"github.com/google/uuid"
+)
+
+func DatabaseBorrowerReputationStatToBorrowerReputationJSON(databaseStat database.BorrowerReputationStat) BorrowerReputation {
+	return BorrowerReputation{
+		Score:           ComputeBorrowerReputationScore(databaseStat),
+		New:             databaseStat.ScoredLoans == 0 && databaseStat.RatingCount == 0,
+		Loans:           databaseStat.ScoredLoans,
+		OnTimeReturns:   databaseStat.OnTimeReturns,
+		LateReturns:     databaseStat.LateLoans,
+		OverdueDays:     databaseStat.OverdueDays,
+		DisputedReturns: databaseStat.DisputedReturns,
+		LostBooks:       databaseStat.LostBooks,
+		RatingAverage:   math.Round(databaseStat.RatingAverage*100) / 100,
+		RatingCount:     databaseStat.RatingCount,
+	}
+}
+
+func DatabaseBorrowerRatingToBorrowerRatingJSON(databaseBorrowerRating database.BorrowerRating) BorrowerRating {
+	return BorrowerRating{
+		ID:           databaseBorrowerRating.ID,
+		Rating:       databaseBorrowerRating.Rating,
+		Comment:      databaseBorrowerRating.Comment,
+		CreatedAt:    databaseBorrowerRating.CreatedAt,
+		BookBorrowID: databaseBorrowerRating.BookBorrowID,
+		LenderID:     databaseBorrowerRating.LenderID,
+		BorrowerID:   databaseBorrowerRating.BorrowerID,
+	}
+}
+
+// Every loan that is over or overdue scores 100, less 5 for each day it was late (at most 20
+// days a loan), 50 when the return was disputed and 100 when the book was lost. Every owner
+// rating scores 0 for one star up to 100 for five. The score is the average of all of them
+// together with the new borrower score, weighted as two loans.
+func ComputeBorrowerReputationScore(databaseStat database.BorrowerReputationStat) int32 {
+	loanPoints := float64(100*databaseStat.ScoredLoans -
+		borrowerReputationOverdueDayPenalty*databaseStat.CappedOverdueDays -
+		borrowerReputationDisputedReturnPenalty*databaseStat.DisputedReturns -
+		borrowerReputationLostBookPenalty*databaseStat.LostBooks)
+
+	ratingPoints := 0.0
+
+	if databaseStat.RatingCount > 0 {
+		ratingPoints = (databaseStat.RatingAverage - MinBorrowerRating) / (MaxBorrowerRating - MinBorrowerRating) * 100 * float64(databaseStat.RatingCount)
+	}
+
+	priorPoints := float64(NewBorrowerReputation * borrowerReputationPriorWeight)
+	weight := float64(borrowerReputationPriorWeight + databaseStat.ScoredLoans + databaseStat.RatingCount)
+	score := math.Round((priorPoints + loanPoints + ratingPoints) / weight)
+
+	return int32(max(0, min(MaxBorrowerReputation, score)))
+}
+
+// Left out keeps the current minimum on update and lends to everyone on create.
+func ValidateMinBorrowerReputation(minBorrowerReputation *int32) error {
+	if minBorrowerReputation != nil && (*minBorrowerReputation < 0 || *minBorrowerReputation > MaxBorrowerReputation) {
+		return fmt.Errorf("min_borrower_reputation must be between 0 and %d", MaxBorrowerReputation)
+	}
+
+	return nil
+}
+
+// Trims the comment and checks the rating, a rating can be just the stars.
+func ValidateBorrowerRating(createBorrowerRatingParameters *CreateBorrowerRatingParameters) error {
+	createBorrowerRatingParameters.Comment = strings.TrimSpace(createBorrowerRatingParameters.Comment)
+
+	if createBorrowerRatingParameters.Rating < MinBorrowerRating || createBorrowerRatingParameters.Rating > MaxBorrowerRating {
+		return fmt.Errorf("rating must be between %d and %d", MinBorrowerRating, MaxBorrowerRating)
+	}
+
+	if len(createBorrowerRatingParameters.Comment) > MaxBorrowerRatingComment {
+		return fmt.Errorf("comment must be at most %d characters", MaxBorrowerRatingComment)
+	}
+
+	return nil
+}
+
+// Returns the status and reason to respond with when the borrower's reputation is below
+// the minimum the owner set for the book. Books without a minimum skip the lookup.
+func CheckBorrowerReputation(ctx context.Context, querier common.Querier, book database.Book, borrowerId uuid.UUID) (int, error) {
+	if book.MinBorrowerReputation == 0 {
+		return 0, nil
+	}
+
+	getStats, getStatsError := querier.GetBorrowerReputationStats(ctx, borrowerId)
+
+	if getStatsError != nil {
+		if getStatsError == sql.ErrNoRows {
+			return http.StatusNotFound, errors.New("user not found")
+		}
+
+		return http.StatusInternalServerError, errors.New("failed to check your borrower reputation, please try again in a few minutes")
+	}
+
+	score := ComputeBorrowerReputationScore(getStats)
+
+	if score < book.MinBorrowerReputation {
+		return http.StatusForbidden, fmt.Errorf("the book owner only lends this book to borrowers with a reputation of at least %d, yours is %d", book.MinBorrowerReputation, score)
+	}
+
+	return 0, nil
+}
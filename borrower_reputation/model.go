@@ -0,0 +1,61 @@
+package borrower_reputation
+
+import (
+	"time"
+
+	"github.com/elorenzorodz/co-library/common"
+	"github.com/google/uuid"
+)
+
+type BorrowerReputationAPIConfig struct {
+	common.APIConfig
+}
+
+// Scores run from 0 to 100. Members who haven't borrowed yet start in the middle,
+// so owners who set a minimum above it only lend to borrowers with a good history.
+const (
+	MaxBorrowerReputation    = 100
+	NewBorrowerReputation    = 50
+	MinBorrowerRating        = 1
+	MaxBorrowerRating        = 5
+	MaxBorrowerRatingComment = 2000
+)
+
+// How much each thing in the history moves a loan's score away from a perfect 100.
+const (
+	borrowerReputationOverdueDayPenalty     = 5
+	borrowerReputationDisputedReturnPenalty = 50
+	borrowerReputationLostBookPenalty       = 100
+	// The starting score counts as this many loans, so one early loan can't swing it all the way.
+	borrowerReputationPriorWeight = 2
+)
+
+// A member's standing as a borrower, shown to owners deciding whether to lend to them.
+type BorrowerReputation struct {
+	Score           int32   `json:"score"`
+	New             bool    `json:"new"`
+	Loans           int32   `json:"loans"`
+	OnTimeReturns   int32   `json:"on_time_returns"`
+	LateReturns     int32   `json:"late_returns"`
+	OverdueDays     int32   `json:"overdue_days"`
+	DisputedReturns int32   `json:"disputed_returns"`
+	LostBooks       int32   `json:"lost_books"`
+	RatingAverage   float64 `json:"rating_average"`
+	RatingCount     int32   `json:"rating_count"`
+}
+
+// A book owner's rating of how the borrower handled a loan.
+type BorrowerRating struct {
+	ID           uuid.UUID `json:"id"`
+	Rating       int32     `json:"rating"`
+	Comment      string    `json:"comment"`
+	CreatedAt    time.Time `json:"createdAt"`
+	BookBorrowID uuid.UUID `json:"book_borrow_id"`
+	LenderID     uuid.UUID `json:"lender_id"`
+	BorrowerID   uuid.UUID `json:"borrower_id"`
+}
+
+type CreateBorrowerRatingParameters struct {
+	Rating  int32  `json:"rating"`
+	Comment string `json:"comment"`
+}
@@ -0,0 +1,89 @@
+package borrower_reputation
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/elorenzorodz/co-library/common"
+	"github.com/elorenzorodz/co-library/internal/database"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+func (borrowerReputationAPIConfig *BorrowerReputationAPIConfig) CreateBorrowerRating(writer http.ResponseWriter, request *http.Request, userId uuid.UUID) {
+	vars := mux.Vars(request)
+	bookBorrowId, parseBookBorrowIdError := uuid.Parse(vars["bookBorrowId"])
+
+	if parseBookBorrowIdError != nil {
+		common.ErrorResponse(writer, http.StatusBadRequest, "invalid book borrow id")
+
+		return
+	}
+
+	createBorrowerRatingParameters := CreateBorrowerRatingParameters{}
+
+	decoder := json.NewDecoder(request.Body)
+	decoderError := decoder.Decode(&createBorrowerRatingParameters)
+
+	if decoderError != nil {
+		common.ErrorResponse(writer, http.StatusBadRequest, fmt.Sprintf("error parsing JSON: %s", decoderError))
+
+		return
+	}
+
+	if validateError := ValidateBorrowerRating(&createBorrowerRatingParameters); validateError != nil {
+		common.ErrorResponse(writer, http.StatusBadRequest, validateError.Error())
+
+		return
+	}
+
+	getBookBorrow, getBookBorrowError := borrowerReputationAPIConfig.DB.GetBookBorrowWithOwner(request.Context(), bookBorrowId)
+
+	if getBookBorrowError != nil {
+		if getBookBorrowError == sql.ErrNoRows {
+			common.ErrorResponse(writer, http.StatusNotFound, "book borrow not found")
+		} else {
+			common.ErrorResponse(writer, http.StatusInternalServerError, "failed to get book borrow details, please try again in a few minutes")
+		}
+
+		return
+	}
+
+	// Only the owner rates the borrower, to anyone else the loan doesn't exist.
+	if getBookBorrow.OwnerID != userId {
+		common.ErrorResponse(writer, http.StatusNotFound, "book borrow not found")
+
+		return
+	}
+
+	if !getBookBorrow.BookBorrow.ReturnedAt.Valid && !getBookBorrow.BookBorrow.LostAt.Valid {
+		common.ErrorResponse(writer, http.StatusConflict, "the borrower can be rated once the book is returned or reported lost")
+
+		return
+	}
+
+	createBorrowerRatingParams := database.CreateBorrowerRatingParams{
+		ID:           uuid.New(),
+		Rating:       createBorrowerRatingParameters.Rating,
+		Comment:      createBorrowerRatingParameters.Comment,
+		BookBorrowID: bookBorrowId,
+		LenderID:     userId,
+		BorrowerID:   getBookBorrow.BookBorrow.BorrowerID,
+	}
+
+	borrowerRating, createBorrowerRatingError := borrowerReputationAPIConfig.DB.CreateBorrowerRating(request.Context(), createBorrowerRatingParams)
+
+	if createBorrowerRatingError != nil {
+		if createBorrowerRatingError == sql.ErrNoRows {
+			common.ErrorResponse(writer, http.StatusConflict, "you already rated the borrower for this loan")
+		} else {
+			common.ErrorResponse(writer, http.StatusInternalServerError, "error rating borrower, please try again in a few minutes")
+		}
+
+		return
+	}
+
+	common.JSONResponse(writer, http.StatusCreated, DatabaseBorrowerRatingToBorrowerRatingJSON(borrowerRating))
+}
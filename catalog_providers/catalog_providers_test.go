@@ -0,0 +1,122 @@
+package catalog_providers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/elorenzorodz/co-library/common"
+)
+
+const duneISBN = "9780441172719"
+
+func TestOpenLibraryCatalogProvider(tTesting *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		switch request.URL.Query().Get("bibkeys") {
+		case "ISBN:" + duneISBN:
+			writer.Write([]byte(`{"ISBN:9780441172719": {
+				"title": "Dune",
+				"authors": [{"name": "Frank Herbert"}],
+				"publishers": [{"name": "Ace Books"}, {"name": "Chilton"}],
+				"publish_date": "August 1, 1965",
+				"number_of_pages": 535,
+				"cover": {"medium": "https://covers.example.com/m.jpg", "large": "https://covers.example.com/l.jpg"}
+			}}`))
+		case "ISBN:9780000000002":
+			writer.WriteHeader(http.StatusServiceUnavailable)
+		default:
+			writer.Write([]byte(`{}`))
+		}
+	}))
+	defer server.Close()
+
+	catalogProvider := NewOpenLibraryCatalogProvider(server.URL + "/")
+
+	// 1. Success: the entry is mapped to book metadata.
+	tTesting.Run("Found", func(t *testing.T) {
+		bookMetadata, err := catalogProvider.LookupISBN(context.Background(), duneISBN)
+
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		if bookMetadata.Title != "Dune" || len(bookMetadata.Authors) != 1 || bookMetadata.Authors[0] != "Frank Herbert" ||
+			bookMetadata.Publisher != "Ace Books" || bookMetadata.PublishedYear != 1965 || bookMetadata.PageCount != 535 ||
+			bookMetadata.CoverURL != "https://covers.example.com/l.jpg" || bookMetadata.ISBN != duneISBN {
+			t.Errorf("Unexpected metadata: %+v", bookMetadata)
+		}
+	})
+
+	// 2. Failure: unknown ISBNs.
+	tTesting.Run("NotFound", func(t *testing.T) {
+		if _, err := catalogProvider.LookupISBN(context.Background(), "9780306406157"); !errors.Is(err, common.ErrCatalogBookNotFound) {
+			t.Errorf("Expected ErrCatalogBookNotFound, got %v", err)
+		}
+	})
+
+	// 3. Failure: the catalog is down.
+	tTesting.Run("ServerError", func(t *testing.T) {
+		_, err := catalogProvider.LookupISBN(context.Background(), "9780000000002")
+
+		if err == nil || errors.Is(err, common.ErrCatalogBookNotFound) {
+			t.Errorf("Expected a lookup error, got %v", err)
+		}
+	})
+}
+
+func TestFixtureCatalogProvider(tTesting *testing.T) {
+	// 1. Success: fixture ISBNs are normalized so any form can be looked up.
+	tTesting.Run("Load", func(t *testing.T) {
+		catalogProvider, err := LoadFixtureCatalogProvider("testdata/catalog_fixture.json")
+
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		bookMetadata, err := catalogProvider.LookupISBN(context.Background(), duneISBN)
+
+		if err != nil || bookMetadata.Title != "Dune" || bookMetadata.ISBN != duneISBN {
+			t.Errorf("Unexpected lookup: %+v (%v)", bookMetadata, err)
+		}
+
+		if _, err := catalogProvider.LookupISBN(context.Background(), "9780306406157"); !errors.Is(err, common.ErrCatalogBookNotFound) {
+			t.Errorf("Expected ErrCatalogBookNotFound, got %v", err)
+		}
+	})
+
+	// 2. Failure: invalid ISBNs in the fixture.
+	tTesting.Run("InvalidISBN", func(t *testing.T) {
+		if _, err := NewFixtureCatalogProvider([]common.BookMetadata{{ISBN: "123"}}); err == nil {
+			t.Error("Expected an error for an invalid fixture ISBN")
+		}
+	})
+}
+
+func TestNewCatalogProvider(tTesting *testing.T) {
+	// 1. Success: Open Library is the default.
+	tTesting.Run("Default", func(t *testing.T) {
+		catalogProvider, err := NewCatalogProvider(common.EnvConfig{})
+
+		if _, ok := catalogProvider.(*OpenLibraryCatalogProvider); err != nil || !ok {
+			t.Errorf("Expected the Open Library provider, got %T (%v)", catalogProvider, err)
+		}
+	})
+
+	// 2. Success: lookups can be turned off.
+	tTesting.Run("None", func(t *testing.T) {
+		if catalogProvider, err := NewCatalogProvider(common.EnvConfig{CatalogProvider: CatalogProviderNone}); err != nil || catalogProvider != nil {
+			t.Errorf("Expected no provider, got %T (%v)", catalogProvider, err)
+		}
+	})
+
+	// 3. Failure: the fixture provider needs a path and unknown providers are rejected.
+	tTesting.Run("Invalid", func(t *testing.T) {
+		for _, envConfig := range []common.EnvConfig{{CatalogProvider: CatalogProviderFixture}, {CatalogProvider: "amazon"}} {
+			if _, err := NewCatalogProvider(envConfig); err == nil {
+				t.Errorf("Expected an error for %+v", envConfig)
+			}
+		}
+	})
+}
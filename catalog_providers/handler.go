@@ -0,0 +1,157 @@
+package catalog_providers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/elorenzorodz/co-library/common"
+)
+
+var publishedYearPattern = regexp.MustCompile(`\b\d{4}\b`)
+
+// Returns nil when lookups are turned off with CATALOG_PROVIDER=none.
+func NewCatalogProvider(envConfig common.EnvConfig) (common.CatalogProvider, error) {
+	switch envConfig.CatalogProvider {
+	case "", CatalogProviderOpenLibrary:
+		return NewOpenLibraryCatalogProvider(envConfig.CatalogURL), nil
+	case CatalogProviderFixture:
+		if envConfig.CatalogFixturePath == "" {
+			return nil, fmt.Errorf("CATALOG_FIXTURE_PATH is required for the %s catalog provider", CatalogProviderFixture)
+		}
+
+		return LoadFixtureCatalogProvider(envConfig.CatalogFixturePath)
+	case CatalogProviderNone:
+		return nil, nil
+	}
+
+	return nil, fmt.Errorf("unknown catalog provider: %s", envConfig.CatalogProvider)
+}
+
+func NewOpenLibraryCatalogProvider(baseURL string) *OpenLibraryCatalogProvider {
+	if baseURL == "" {
+		baseURL = DefaultOpenLibraryURL
+	}
+
+	return &OpenLibraryCatalogProvider{
+		BaseURL: strings.TrimSuffix(baseURL, "/"),
+		Client:  &http.Client{Timeout: CatalogRequestTimeout},
+	}
+}
+
+func (openLibraryCatalogProvider *OpenLibraryCatalogProvider) LookupISBN(ctx context.Context, isbn string) (common.BookMetadata, error) {
+	bibKey := "ISBN:" + isbn
+	query := url.Values{"bibkeys": {bibKey}, "format": {"json"}, "jscmd": {"data"}}
+
+	request, requestError := http.NewRequestWithContext(ctx, http.MethodGet, openLibraryCatalogProvider.BaseURL+"/api/books?"+query.Encode(), nil)
+
+	if requestError != nil {
+		return common.BookMetadata{}, requestError
+	}
+
+	response, responseError := openLibraryCatalogProvider.Client.Do(request)
+
+	if responseError != nil {
+		return common.BookMetadata{}, fmt.Errorf("error looking up isbn %s: %w", isbn, responseError)
+	}
+
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return common.BookMetadata{}, fmt.Errorf("error looking up isbn %s: catalog responded with %s", isbn, response.Status)
+	}
+
+	// The response is keyed by bibkey and is an empty object for unknown ISBNs.
+	openLibraryBooks := map[string]openLibraryBook{}
+
+	if decodeError := json.NewDecoder(response.Body).Decode(&openLibraryBooks); decodeError != nil {
+		return common.BookMetadata{}, fmt.Errorf("error decoding catalog response for isbn %s: %w", isbn, decodeError)
+	}
+
+	book, found := openLibraryBooks[bibKey]
+
+	if !found {
+		return common.BookMetadata{}, common.ErrCatalogBookNotFound
+	}
+
+	return openLibraryBookToBookMetadata(isbn, book), nil
+}
+
+func openLibraryBookToBookMetadata(isbn string, book openLibraryBook) common.BookMetadata {
+	bookMetadata := common.BookMetadata{
+		ISBN:      isbn,
+		Title:     book.Title,
+		PageCount: book.NumberOfPages,
+		CoverURL:  book.Cover.Large,
+	}
+
+	if bookMetadata.CoverURL == "" {
+		bookMetadata.CoverURL = book.Cover.Medium
+	}
+
+	for _, author := range book.Authors {
+		bookMetadata.Authors = append(bookMetadata.Authors, author.Name)
+	}
+
+	if len(book.Publishers) > 0 {
+		bookMetadata.Publisher = book.Publishers[0].Name
+	}
+
+	// Publish dates are free text such as "1965" or "August 1, 1965".
+	if year := publishedYearPattern.FindString(book.PublishDate); year != "" {
+		publishedYear, _ := strconv.Atoi(year)
+		bookMetadata.PublishedYear = int32(publishedYear)
+	}
+
+	return bookMetadata
+}
+
+func NewFixtureCatalogProvider(books []common.BookMetadata) (*FixtureCatalogProvider, error) {
+	fixtureCatalogProvider := &FixtureCatalogProvider{books: map[string]common.BookMetadata{}}
+
+	for _, book := range books {
+		isbn, normalizeError := common.NormalizeISBN(book.ISBN)
+
+		if normalizeError != nil {
+			return nil, normalizeError
+		}
+
+		book.ISBN = isbn
+		fixtureCatalogProvider.books[isbn] = book
+	}
+
+	return fixtureCatalogProvider, nil
+}
+
+// Reads a JSON array of book metadata.
+func LoadFixtureCatalogProvider(path string) (*FixtureCatalogProvider, error) {
+	fixture, readError := os.ReadFile(path)
+
+	if readError != nil {
+		return nil, fmt.Errorf("error reading catalog fixture: %w", readError)
+	}
+
+	books := []common.BookMetadata{}
+
+	if unmarshalError := json.Unmarshal(fixture, &books); unmarshalError != nil {
+		return nil, fmt.Errorf("error parsing catalog fixture %s: %w", path, unmarshalError)
+	}
+
+	return NewFixtureCatalogProvider(books)
+}
+
+func (fixtureCatalogProvider *FixtureCatalogProvider) LookupISBN(ctx context.Context, isbn string) (common.BookMetadata, error) {
+	book, found := fixtureCatalogProvider.books[isbn]
+
+	if !found {
+		return common.BookMetadata{}, common.ErrCatalogBookNotFound
+	}
+
+	return book, nil
+}
@@ -0,0 +1,48 @@
+package catalog_providers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/elorenzorodz/co-library/common"
+)
+
+// Values accepted by the CATALOG_PROVIDER environment variable.
+const (
+	CatalogProviderOpenLibrary = "openlibrary"
+	CatalogProviderFixture     = "fixture"
+	CatalogProviderNone        = "none"
+)
+
+const (
+	DefaultOpenLibraryURL = "https://openlibrary.org"
+	CatalogRequestTimeout = 5 * time.Second
+)
+
+// Looks books up through the Open Library books API.
+type OpenLibraryCatalogProvider struct {
+	BaseURL string
+	Client  *http.Client
+}
+
+// Answers lookups from a fixed set of books, for local development and offline tests.
+type FixtureCatalogProvider struct {
+	books map[string]common.BookMetadata
+}
+
+// The parts of an Open Library "jscmd=data" entry that map to book metadata.
+type openLibraryBook struct {
+	Title   string `json:"title"`
+	Authors []struct {
+		Name string `json:"name"`
+	} `json:"authors"`
+	Publishers []struct {
+		Name string `json:"name"`
+	} `json:"publishers"`
+	PublishDate   string `json:"publish_date"`
+	NumberOfPages int32  `json:"number_of_pages"`
+	Cover         struct {
+		Large  string `json:"large"`
+		Medium string `json:"medium"`
+	} `json:"cover"`
+}
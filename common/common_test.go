@@ -0,0 +1,101 @@
+package common
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestParsePageParameters(tTesting *testing.T) {
+	// 1. Success: defaults without a limit or cursor.
+	tTesting.Run("Defaults", func(t *testing.T) {
+		pageParameters, err := ParsePageParameters(url.Values{})
+
+		if err != nil || pageParameters.Limit != DefaultPageLimit || pageParameters.Cursor != nil || pageParameters.CursorID().Valid {
+			t.Errorf("Unexpected page parameters: %+v (%v)", pageParameters, err)
+		}
+	})
+
+	// 2. Success: the cursor round-trips.
+	tTesting.Run("Cursor", func(t *testing.T) {
+		cursor := Cursor{Sort: "title", Value: "Dune", ID: uuid.New()}
+
+		pageParameters, err := ParsePageParameters(url.Values{"limit": {"5"}, "cursor": {EncodeCursor(cursor)}})
+
+		if err != nil || pageParameters.Limit != 5 || *pageParameters.Cursor != cursor || pageParameters.CursorID().UUID != cursor.ID {
+			t.Errorf("Unexpected page parameters: %+v (%v)", pageParameters, err)
+		}
+	})
+
+	// 3. Failure: out of range limits and tampered cursors.
+	tTesting.Run("Invalid", func(t *testing.T) {
+		for _, query := range []url.Values{
+			{"limit": {"0"}},
+			{"limit": {"101"}},
+			{"limit": {"x"}},
+			{"cursor": {"%%%"}},
+			{"cursor": {EncodeCursor(Cursor{Value: "no id"})}},
+		} {
+			if _, err := ParsePageParameters(query); err == nil {
+				t.Errorf("Expected an error for %v", query)
+			}
+		}
+	})
+}
+
+func TestSplitPage(tTesting *testing.T) {
+	cursorOf := func(item string) Cursor {
+		return Cursor{Value: item, ID: uuid.MustParse("00000000-0000-0000-0000-000000000001")}
+	}
+
+	// 1. Success: the extra row is dropped and the cursor points at the last kept row.
+	tTesting.Run("MorePages", func(t *testing.T) {
+		items, nextCursor := SplitPage([]string{"a", "b", "c"}, PageParameters{Limit: 2}, cursorOf)
+
+		if len(items) != 2 || nextCursor == nil {
+			t.Fatalf("Expected two items and a next cursor, got %v %v", items, nextCursor)
+		}
+
+		cursor, _ := DecodeCursor(*nextCursor)
+
+		if cursor.Value != "b" {
+			t.Errorf("Expected the cursor of b, got %+v", cursor)
+		}
+	})
+
+	// 2. Success: no cursor on the last page.
+	tTesting.Run("LastPage", func(t *testing.T) {
+		items, nextCursor := SplitPage([]string{"a", "b"}, PageParameters{Limit: 2}, cursorOf)
+
+		if len(items) != 2 || nextCursor != nil {
+			t.Errorf("Expected two items and no cursor, got %v %v", items, nextCursor)
+		}
+	})
+}
+
+func TestNormalizeISBN(tTesting *testing.T) {
+	// 1. Success: ISBN-10s and ISBN-13s normalize to the same ISBN-13.
+	tTesting.Run("Valid", func(t *testing.T) {
+		for _, isbn := range []string{"0-441-17271-7", "0441172717", "978-0-441-17271-9", " 9780441172719 "} {
+			normalizedISBN, err := NormalizeISBN(isbn)
+
+			if err != nil || normalizedISBN != "9780441172719" {
+				t.Errorf("%q: expected 9780441172719, got %q (%v)", isbn, normalizedISBN, err)
+			}
+		}
+
+		if normalizedISBN, err := NormalizeISBN("0-8044-2957-X"); err != nil || normalizedISBN != "9780804429573" {
+			t.Errorf("Expected an X check digit to be accepted, got %q (%v)", normalizedISBN, err)
+		}
+	})
+
+	// 2. Failure: bad check digits, lengths and characters.
+	tTesting.Run("Invalid", func(t *testing.T) {
+		for _, isbn := range []string{"0441172718", "9780441172710", "12345", "978044117271X", "X441172717"} {
+			if _, err := NormalizeISBN(isbn); err == nil {
+				t.Errorf("Expected an error for %q", isbn)
+			}
+		}
+	})
+}
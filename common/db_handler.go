@@ -1,8 +1,11 @@
 package common
 
 import (
+	"context"
 	"database/sql"
 	"log"
+
+	"github.com/elorenzorodz/co-library/internal/database"
 )
 
 func OpenDBConnection(dbUrl string) *sql.DB {
@@ -19,4 +22,36 @@ func OpenDBConnection(dbUrl string) *sql.DB {
 	}
 
 	return connection
+}
+
+type SQLTransactor struct {
+	DB *sql.DB
+}
+
+func (sqlTransactor *SQLTransactor) WithTransaction(ctx context.Context, fn func(querier Querier) error) error {
+	transaction, beginError := sqlTransactor.DB.BeginTx(ctx, nil)
+
+	if beginError != nil {
+		return beginError
+	}
+
+	if fnError := fn(database.New(transaction)); fnError != nil {
+		if rollbackError := transaction.Rollback(); rollbackError != nil {
+			log.Printf("failed to roll back transaction: %s", rollbackError)
+		}
+
+		return fnError
+	}
+
+	return transaction.Commit()
+}
+
+// Runs fn in a transaction when a Transactor is configured. Without one, as in the
+// handler tests, fn runs directly against DB.
+func (apiConfig *APIConfig) InTransaction(ctx context.Context, fn func(querier Querier) error) error {
+	if apiConfig.Transactor == nil {
+		return fn(apiConfig.DB)
+	}
+
+	return apiConfig.Transactor.WithTransaction(ctx, fn)
 }
\ No newline at end of file
@@ -3,6 +3,7 @@ package common
 import (
 	"log"
 	"os"
+	"strings"
 )
 
 func GetEnvVariable(name string) string {
@@ -15,12 +16,54 @@ func GetEnvVariable(name string) string {
 	return envValue
 }
 
+func GetOptionalEnvVariable(name string, fallback string) string {
+	envValue := os.Getenv(name)
+
+	if envValue == "" {
+		return fallback
+	}
+
+	return envValue
+}
+
+// Splits a comma separated value, dropping empty entries.
+func ParseListEnvVariable(envValue string) []string {
+	values := []string{}
+
+	for _, value := range strings.Split(envValue, ",") {
+		if trimmedValue := strings.TrimSpace(value); trimmedValue != "" {
+			values = append(values, trimmedValue)
+		}
+	}
+
+	return values
+}
+
 func LoadEnvConfig() EnvConfig {
 	return EnvConfig{
 		APIVersion:           GetEnvVariable("API_VERSION"),
 		Port:                 GetEnvVariable("PORT"),
 		DBUrl:                GetEnvVariable("DB_URL"),
-		MailgunAPIKey:        GetEnvVariable("MAILGUN_API_KEY"),
-		MailgunSendingDomain: GetEnvVariable("MAILGUN_SENDING_DOMAIN"),
+		Notifier:             GetOptionalEnvVariable("NOTIFIER", ""),
+		NotificationSender:   GetOptionalEnvVariable("NOTIFICATION_SENDER", ""),
+		MailgunAPIKey:        GetOptionalEnvVariable("MAILGUN_API_KEY", ""),
+		MailgunSendingDomain: GetOptionalEnvVariable("MAILGUN_SENDING_DOMAIN", ""),
+		SMTPHost:             GetOptionalEnvVariable("SMTP_HOST", ""),
+		SMTPPort:             GetOptionalEnvVariable("SMTP_PORT", "587"),
+		SMTPUsername:         GetOptionalEnvVariable("SMTP_USERNAME", ""),
+		SMTPPassword:         GetOptionalEnvVariable("SMTP_PASSWORD", ""),
+		TemplatesDir:         GetOptionalEnvVariable("NOTIFICATION_TEMPLATES_DIR", ""),
+		AdminEmails:          ParseListEnvVariable(GetOptionalEnvVariable("ADMIN_EMAILS", "")),
+		CatalogProvider:      GetOptionalEnvVariable("CATALOG_PROVIDER", ""),
+		CatalogURL:           GetOptionalEnvVariable("CATALOG_URL", ""),
+		CatalogFixturePath:   GetOptionalEnvVariable("CATALOG_FIXTURE_PATH", ""),
+		BlobStore:            GetOptionalEnvVariable("BLOB_STORE", ""),
+		BlobStoreDir:         GetOptionalEnvVariable("BLOB_STORE_DIR", ""),
+		BlobStorePublicURL:   GetOptionalEnvVariable("BLOB_STORE_PUBLIC_URL", ""),
+		S3Endpoint:           GetOptionalEnvVariable("S3_ENDPOINT", ""),
+		S3Region:             GetOptionalEnvVariable("S3_REGION", ""),
+		S3Bucket:             GetOptionalEnvVariable("S3_BUCKET", ""),
+		S3AccessKeyID:        GetOptionalEnvVariable("S3_ACCESS_KEY_ID", ""),
+		S3SecretAccessKey:    GetOptionalEnvVariable("S3_SECRET_ACCESS_KEY", ""),
 	}
 }
\ No newline at end of file
@@ -3,6 +3,7 @@ package common
 import (
 	"context"
 	"database/sql"
+	"time"
 
 	"github.com/elorenzorodz/co-library/internal/database"
 	"github.com/google/uuid"
@@ -22,6 +23,18 @@ func (m *UserMock) GetUserByID(ctx context.Context, id uuid.UUID) (database.User
 	return database.User{}, sql.ErrNoRows
 }
 
+func (m *UserMock) UpdateUserLocale(ctx context.Context, arg database.UpdateUserLocaleParams) (database.User, error) {
+	panic("UpdateUserLocale not implemented for this test (BaseMock)")
+}
+
+func (m *UserMock) UpdateUserDefaultLoanPeriod(ctx context.Context, arg database.UpdateUserDefaultLoanPeriodParams) (database.User, error) {
+	panic("UpdateUserDefaultLoanPeriod not implemented for this test (BaseMock)")
+}
+
+func (m *UserMock) GetUserProfile(ctx context.Context, id uuid.UUID) (database.GetUserProfileRow, error) {
+	return database.GetUserProfileRow{}, sql.ErrNoRows
+}
+
 type BookMock struct{}
 
 func (m *BookMock) CreateBook(ctx context.Context, arg database.CreateBookParams) (database.Book, error) {
@@ -32,26 +45,177 @@ func (m *BookMock) GetBook(ctx context.Context, id uuid.UUID) (database.Book, er
 	return database.Book{}, sql.ErrNoRows
 }
 
-func (m *BookMock) GetBooks(ctx context.Context, userID uuid.UUID) ([]database.Book, error) {
-	return []database.Book{}, nil
+func (m *BookMock) GetBookWithAvailability(ctx context.Context, id uuid.UUID) (database.GetBookWithAvailabilityRow, error) {
+	return database.GetBookWithAvailabilityRow{}, sql.ErrNoRows
+}
+
+func (m *BookMock) GetBooks(ctx context.Context, arg database.GetBooksParams) ([]database.GetBooksRow, error) {
+	return []database.GetBooksRow{}, nil
 }
 
-func (m *BookMock) BrowseBooks(ctx context.Context) ([]database.Book, error) {
-	return []database.Book{}, nil
+func (m *BookMock) BrowseBooks(ctx context.Context, arg database.BrowseBooksParams) ([]database.BrowseBooksRow, error) {
+	return []database.BrowseBooksRow{}, nil
 }
 
 func (m *BookMock) UpdateBook(ctx context.Context, arg database.UpdateBookParams) (database.Book, error) {
 	panic("UpdateBook not implemented for this test (BaseMock)")
 }
 
+func (m *BookMock) SetBookCover(ctx context.Context, arg database.SetBookCoverParams) (database.Book, error) {
+	panic("SetBookCover not implemented for this test (BaseMock)")
+}
+
+func (m *BookMock) GetBookIdentities(ctx context.Context, userID uuid.UUID) ([]database.GetBookIdentitiesRow, error) {
+	return []database.GetBookIdentitiesRow{}, nil
+}
+
 func (m *BookMock) DeleteBook(ctx context.Context, arg database.DeleteBookParams) (int64, error) {
 	return 0, nil
 }
 
+func (m *BookMock) GetDeletedBooks(ctx context.Context, arg database.GetDeletedBooksParams) ([]database.GetDeletedBooksRow, error) {
+	return []database.GetDeletedBooksRow{}, nil
+}
+
+func (m *BookMock) RestoreBook(ctx context.Context, arg database.RestoreBookParams) (database.Book, error) {
+	return database.Book{}, sql.ErrNoRows
+}
+
+func (m *BookMock) PurgeDeletedBooks(ctx context.Context, arg database.PurgeDeletedBooksParams) ([]database.PurgeDeletedBooksRow, error) {
+	return []database.PurgeDeletedBooksRow{}, nil
+}
+
+type BookCopyMock struct{}
+
+func (m *BookCopyMock) CreateBookCopy(ctx context.Context, arg database.CreateBookCopyParams) (database.BookCopy, error) {
+	panic("CreateBookCopy not implemented for this test (BaseMock)")
+}
+
+func (m *BookCopyMock) GetBookCopies(ctx context.Context, bookID uuid.UUID) ([]database.GetBookCopiesRow, error) {
+	return []database.GetBookCopiesRow{}, nil
+}
+
+func (m *BookCopyMock) UpdateBookCopy(ctx context.Context, arg database.UpdateBookCopyParams) (database.BookCopy, error) {
+	panic("UpdateBookCopy not implemented for this test (BaseMock)")
+}
+
+func (m *BookCopyMock) DeleteBookCopy(ctx context.Context, arg database.DeleteBookCopyParams) (int64, error) {
+	return 0, nil
+}
+
+func (m *BookCopyMock) RetireBookCopy(ctx context.Context, arg database.RetireBookCopyParams) (int64, error) {
+	return 0, nil
+}
+
+// Books have a single copy on the shelf unless a test says otherwise.
+func (m *BookCopyMock) CountAvailableBookCopies(ctx context.Context, bookID uuid.UUID) (int64, error) {
+	return 1, nil
+}
+
+type BookTagMock struct{}
+
+// Only a couple of the curated genres, enough to tell valid ones from the rest.
+func (m *BookTagMock) GetGenres(ctx context.Context, viewerID uuid.UUID) ([]database.GetGenresRow, error) {
+	return []database.GetGenresRow{{Slug: "fantasy", Name: "Fantasy"}, {Slug: "science-fiction", Name: "Science Fiction"}}, nil
+}
+
+func (m *BookTagMock) GetPopularBookTags(ctx context.Context, arg database.GetPopularBookTagsParams) ([]database.GetPopularBookTagsRow, error) {
+	return []database.GetPopularBookTagsRow{}, nil
+}
+
+// Books have no genres or tags unless a test says otherwise.
+func (m *BookTagMock) GetBookGenresAndTags(ctx context.Context, id uuid.UUID) (database.GetBookGenresAndTagsRow, error) {
+	return database.GetBookGenresAndTagsRow{Genres: []string{}, Tags: []string{}}, nil
+}
+
+func (m *BookTagMock) DeleteBookGenres(ctx context.Context, bookID uuid.UUID) error {
+	return nil
+}
+
+func (m *BookTagMock) CreateBookGenres(ctx context.Context, arg database.CreateBookGenresParams) error {
+	return nil
+}
+
+func (m *BookTagMock) DeleteBookTags(ctx context.Context, bookID uuid.UUID) error {
+	return nil
+}
+
+func (m *BookTagMock) CreateBookTags(ctx context.Context, arg database.CreateBookTagsParams) error {
+	return nil
+}
+
+type ShelfMock struct{}
+
+func (m *ShelfMock) CreateShelf(ctx context.Context, arg database.CreateShelfParams) (database.Shelf, error) {
+	panic("CreateShelf not implemented for this test (BaseMock)")
+}
+
+func (m *ShelfMock) GetShelf(ctx context.Context, arg database.GetShelfParams) (database.GetShelfRow, error) {
+	return database.GetShelfRow{}, sql.ErrNoRows
+}
+
+func (m *ShelfMock) GetShelfByName(ctx context.Context, arg database.GetShelfByNameParams) (database.Shelf, error) {
+	return database.Shelf{}, sql.ErrNoRows
+}
+
+func (m *ShelfMock) GetShelvesByUserID(ctx context.Context, arg database.GetShelvesByUserIDParams) ([]database.GetShelvesByUserIDRow, error) {
+	return []database.GetShelvesByUserIDRow{}, nil
+}
+
+func (m *ShelfMock) UpdateShelf(ctx context.Context, arg database.UpdateShelfParams) (database.Shelf, error) {
+	panic("UpdateShelf not implemented for this test (BaseMock)")
+}
+
+func (m *ShelfMock) DeleteShelf(ctx context.Context, arg database.DeleteShelfParams) (int64, error) {
+	return 0, nil
+}
+
+func (m *ShelfMock) AddShelfBook(ctx context.Context, arg database.AddShelfBookParams) (database.ShelfBook, error) {
+	panic("AddShelfBook not implemented for this test (BaseMock)")
+}
+
+func (m *ShelfMock) RemoveShelfBook(ctx context.Context, arg database.RemoveShelfBookParams) (int64, error) {
+	return 0, nil
+}
+
+func (m *ShelfMock) GetShelfBooks(ctx context.Context, arg database.GetShelfBooksParams) ([]database.GetShelfBooksRow, error) {
+	return []database.GetShelfBooksRow{}, nil
+}
+
+func (m *ShelfMock) GetShelfBookIDs(ctx context.Context, shelfID uuid.UUID) ([]uuid.UUID, error) {
+	return []uuid.UUID{}, nil
+}
+
+func (m *ShelfMock) ReorderShelfBooks(ctx context.Context, arg database.ReorderShelfBooksParams) error {
+	return nil
+}
+
+type BookPolicyMock struct{}
+
+func (m *BookPolicyMock) GetMemberRelationship(ctx context.Context, arg database.GetMemberRelationshipParams) (database.GetMemberRelationshipRow, error) {
+	return database.GetMemberRelationshipRow{}, nil
+}
+
+func (m *BookPolicyMock) CreateApprovedBorrower(ctx context.Context, arg database.CreateApprovedBorrowerParams) (database.ApprovedBorrower, error) {
+	panic("CreateApprovedBorrower not implemented for this test (BaseMock)")
+}
+
+func (m *BookPolicyMock) GetApprovedBorrower(ctx context.Context, arg database.GetApprovedBorrowerParams) (database.ApprovedBorrower, error) {
+	return database.ApprovedBorrower{}, sql.ErrNoRows
+}
+
+func (m *BookPolicyMock) GetApprovedBorrowers(ctx context.Context, arg database.GetApprovedBorrowersParams) ([]database.GetApprovedBorrowersRow, error) {
+	return []database.GetApprovedBorrowersRow{}, nil
+}
+
+func (m *BookPolicyMock) DeleteApprovedBorrower(ctx context.Context, arg database.DeleteApprovedBorrowerParams) (int64, error) {
+	return 0, nil
+}
+
 type BookBorrowMock struct{}
 
-func (m *BookBorrowMock) GetBookBorrow(ctx context.Context, bookID uuid.UUID) (database.BookBorrow, error) {
-	return database.BookBorrow{}, sql.ErrNoRows
+func (m *BookBorrowMock) LockBookForIssue(ctx context.Context, id uuid.UUID) error {
+	return nil
 }
 
 func (m *BookBorrowMock) IssueBook(ctx context.Context, arg database.IssueBookParams) (database.BookBorrow, error) {
@@ -62,6 +226,234 @@ func (m *BookBorrowMock) ReturnBook(ctx context.Context, arg database.ReturnBook
 	panic("ReturnBook not implemented for this test (BaseMock)")
 }
 
+func (m *BookBorrowMock) GetBookBorrowByID(ctx context.Context, id uuid.UUID) (database.BookBorrow, error) {
+	return database.BookBorrow{}, sql.ErrNoRows
+}
+
+func (m *BookBorrowMock) RenewBookBorrow(ctx context.Context, arg database.RenewBookBorrowParams) (database.BookBorrowRenewal, error) {
+	panic("RenewBookBorrow not implemented for this test (BaseMock)")
+}
+
+func (m *BookBorrowMock) CountBookBorrowRenewals(ctx context.Context, bookBorrowID uuid.UUID) (int64, error) {
+	return 0, nil
+}
+
+func (m *BookBorrowMock) GetBookBorrowRenewals(ctx context.Context, bookBorrowID uuid.UUID) ([]database.BookBorrowRenewal, error) {
+	return []database.BookBorrowRenewal{}, nil
+}
+
+func (m *BookBorrowMock) ConfirmBookReturn(ctx context.Context, arg database.ConfirmBookReturnParams) (database.BookBorrow, error) {
+	panic("ConfirmBookReturn not implemented for this test (BaseMock)")
+}
+
+func (m *BookBorrowMock) DisputeBookReturn(ctx context.Context, arg database.DisputeBookReturnParams) (database.BookBorrow, error) {
+	panic("DisputeBookReturn not implemented for this test (BaseMock)")
+}
+
+func (m *BookBorrowMock) GetBookBorrowsDueForReminder(ctx context.Context, arg database.GetBookBorrowsDueForReminderParams) ([]database.GetBookBorrowsDueForReminderRow, error) {
+	return []database.GetBookBorrowsDueForReminderRow{}, nil
+}
+
+func (m *BookBorrowMock) CreateBookBorrowReminder(ctx context.Context, arg database.CreateBookBorrowReminderParams) (database.BookBorrowReminder, error) {
+	return database.BookBorrowReminder{}, sql.ErrNoRows
+}
+
+func (m *BookBorrowMock) DeleteBookBorrowReminder(ctx context.Context, id uuid.UUID) error {
+	return nil
+}
+
+func (m *BookBorrowMock) GetBorrowedBooks(ctx context.Context, arg database.GetBorrowedBooksParams) ([]database.GetBorrowedBooksRow, error) {
+	return []database.GetBorrowedBooksRow{}, nil
+}
+
+func (m *BookBorrowMock) GetLentBooks(ctx context.Context, arg database.GetLentBooksParams) ([]database.GetLentBooksRow, error) {
+	return []database.GetLentBooksRow{}, nil
+}
+
+func (m *BookBorrowMock) CreateBookBorrowRequest(ctx context.Context, arg database.CreateBookBorrowRequestParams) (database.BookBorrowRequest, error) {
+	panic("CreateBookBorrowRequest not implemented for this test (BaseMock)")
+}
+
+func (m *BookBorrowMock) GetOpenBookBorrowRequest(ctx context.Context, arg database.GetOpenBookBorrowRequestParams) (database.BookBorrowRequest, error) {
+	return database.BookBorrowRequest{}, sql.ErrNoRows
+}
+
+func (m *BookBorrowMock) RespondToBookBorrowRequest(ctx context.Context, arg database.RespondToBookBorrowRequestParams) (database.BookBorrowRequest, error) {
+	return database.BookBorrowRequest{}, sql.ErrNoRows
+}
+
+func (m *BookBorrowMock) CancelBookBorrowRequest(ctx context.Context, arg database.CancelBookBorrowRequestParams) (database.BookBorrowRequest, error) {
+	return database.BookBorrowRequest{}, sql.ErrNoRows
+}
+
+func (m *BookBorrowMock) MarkBookBorrowRequestIssued(ctx context.Context, arg database.MarkBookBorrowRequestIssuedParams) (int64, error) {
+	return 1, nil
+}
+
+func (m *BookBorrowMock) MarkBookBorrowRequestReturned(ctx context.Context, bookBorrowID uuid.UUID) error {
+	return nil
+}
+
+func (m *BookBorrowMock) GetIncomingBookBorrowRequests(ctx context.Context, arg database.GetIncomingBookBorrowRequestsParams) ([]database.GetIncomingBookBorrowRequestsRow, error) {
+	return []database.GetIncomingBookBorrowRequestsRow{}, nil
+}
+
+func (m *BookBorrowMock) GetOutgoingBookBorrowRequests(ctx context.Context, arg database.GetOutgoingBookBorrowRequestsParams) ([]database.GetOutgoingBookBorrowRequestsRow, error) {
+	return []database.GetOutgoingBookBorrowRequestsRow{}, nil
+}
+
+func (m *BookBorrowMock) DeclineOpenBookBorrowRequests(ctx context.Context, bookID uuid.UUID) error {
+	return nil
+}
+
+type BookClaimMock struct{}
+
+func (m *BookClaimMock) GetBookBorrowWithOwner(ctx context.Context, id uuid.UUID) (database.GetBookBorrowWithOwnerRow, error) {
+	return database.GetBookBorrowWithOwnerRow{}, sql.ErrNoRows
+}
+
+func (m *BookClaimMock) CreateBookConditionReport(ctx context.Context, arg database.CreateBookConditionReportParams) (database.BookConditionReport, error) {
+	panic("CreateBookConditionReport not implemented for this test (BaseMock)")
+}
+
+func (m *BookClaimMock) GetBookConditionReports(ctx context.Context, bookBorrowID uuid.UUID) ([]database.BookConditionReport, error) {
+	return []database.BookConditionReport{}, nil
+}
+
+func (m *BookClaimMock) ReportBookBorrowLost(ctx context.Context, id uuid.UUID) (database.BookBorrow, error) {
+	panic("ReportBookBorrowLost not implemented for this test (BaseMock)")
+}
+
+func (m *BookClaimMock) ClearBookBorrowLost(ctx context.Context, id uuid.UUID) error {
+	return nil
+}
+
+func (m *BookClaimMock) CloseLostBookBorrow(ctx context.Context, id uuid.UUID) (int64, error) {
+	return 0, nil
+}
+
+func (m *BookClaimMock) MarkBookCopyLost(ctx context.Context, id uuid.UUID) error {
+	return nil
+}
+
+func (m *BookClaimMock) CreateBookClaim(ctx context.Context, arg database.CreateBookClaimParams) (database.BookClaim, error) {
+	panic("CreateBookClaim not implemented for this test (BaseMock)")
+}
+
+func (m *BookClaimMock) GetBookClaim(ctx context.Context, id uuid.UUID) (database.GetBookClaimRow, error) {
+	return database.GetBookClaimRow{}, sql.ErrNoRows
+}
+
+func (m *BookClaimMock) GetUserBookClaims(ctx context.Context, arg database.GetUserBookClaimsParams) ([]database.GetUserBookClaimsRow, error) {
+	return []database.GetUserBookClaimsRow{}, nil
+}
+
+func (m *BookClaimMock) ResolveBookClaim(ctx context.Context, arg database.ResolveBookClaimParams) (database.BookClaim, error) {
+	panic("ResolveBookClaim not implemented for this test (BaseMock)")
+}
+
+func (m *BookClaimMock) CreateBookClaimComment(ctx context.Context, arg database.CreateBookClaimCommentParams) (database.BookClaimComment, error) {
+	panic("CreateBookClaimComment not implemented for this test (BaseMock)")
+}
+
+func (m *BookClaimMock) GetBookClaimComments(ctx context.Context, bookClaimID uuid.UUID) ([]database.GetBookClaimCommentsRow, error) {
+	return []database.GetBookClaimCommentsRow{}, nil
+}
+
+type BookReviewMock struct{}
+
+func (m *BookReviewMock) HasReturnedBookBorrow(ctx context.Context, arg database.HasReturnedBookBorrowParams) (bool, error) {
+	return false, nil
+}
+
+func (m *BookReviewMock) CreateBookReview(ctx context.Context, arg database.CreateBookReviewParams) (database.BookReview, error) {
+	panic("CreateBookReview not implemented for this test (BaseMock)")
+}
+
+func (m *BookReviewMock) GetBookReviewForUpdate(ctx context.Context, id uuid.UUID) (database.BookReview, error) {
+	return database.BookReview{}, sql.ErrNoRows
+}
+
+func (m *BookReviewMock) UpdateBookReview(ctx context.Context, arg database.UpdateBookReviewParams) (database.BookReview, error) {
+	panic("UpdateBookReview not implemented for this test (BaseMock)")
+}
+
+func (m *BookReviewMock) DeleteBookReview(ctx context.Context, arg database.DeleteBookReviewParams) (database.BookReview, error) {
+	panic("DeleteBookReview not implemented for this test (BaseMock)")
+}
+
+func (m *BookReviewMock) ReplyToBookReview(ctx context.Context, arg database.ReplyToBookReviewParams) (database.BookReview, error) {
+	panic("ReplyToBookReview not implemented for this test (BaseMock)")
+}
+
+func (m *BookReviewMock) GetBookReviews(ctx context.Context, arg database.GetBookReviewsParams) ([]database.GetBookReviewsRow, error) {
+	return []database.GetBookReviewsRow{}, nil
+}
+
+func (m *BookReviewMock) AdjustBookRating(ctx context.Context, arg database.AdjustBookRatingParams) error {
+	return nil
+}
+
+type BorrowerReputationMock struct{}
+
+func (m *BorrowerReputationMock) GetBorrowerReputationStats(ctx context.Context, borrowerID uuid.UUID) (database.BorrowerReputationStat, error) {
+	return database.BorrowerReputationStat{}, sql.ErrNoRows
+}
+
+func (m *BorrowerReputationMock) CreateBorrowerRating(ctx context.Context, arg database.CreateBorrowerRatingParams) (database.BorrowerRating, error) {
+	panic("CreateBorrowerRating not implemented for this test (BaseMock)")
+}
+
+type BookReservationMock struct{}
+
+func (m *BookReservationMock) CreateBookReservation(ctx context.Context, arg database.CreateBookReservationParams) (database.BookReservation, error) {
+	panic("CreateBookReservation not implemented for this test (BaseMock)")
+}
+
+func (m *BookReservationMock) GetActiveBookReservation(ctx context.Context, arg database.GetActiveBookReservationParams) (database.BookReservation, error) {
+	return database.BookReservation{}, sql.ErrNoRows
+}
+
+func (m *BookReservationMock) GetUserBookReservations(ctx context.Context, userID uuid.UUID) ([]database.GetUserBookReservationsRow, error) {
+	return []database.GetUserBookReservationsRow{}, nil
+}
+
+func (m *BookReservationMock) CountBookReservationsAhead(ctx context.Context, arg database.CountBookReservationsAheadParams) (int64, error) {
+	return 0, nil
+}
+
+func (m *BookReservationMock) CancelBookReservation(ctx context.Context, arg database.CancelBookReservationParams) (database.BookReservation, error) {
+	return database.BookReservation{}, sql.ErrNoRows
+}
+
+func (m *BookReservationMock) ExpireBookReservationOffers(ctx context.Context, bookID uuid.UUID) error {
+	return nil
+}
+
+func (m *BookReservationMock) GetBooksWithLapsedBookReservationOffers(ctx context.Context, expiredBefore time.Time) ([]uuid.UUID, error) {
+	return []uuid.UUID{}, nil
+}
+
+func (m *BookReservationMock) GetOfferedBookReservations(ctx context.Context, bookID uuid.UUID) ([]database.BookReservation, error) {
+	return []database.BookReservation{}, nil
+}
+
+func (m *BookReservationMock) OfferNextBookReservation(ctx context.Context, arg database.OfferNextBookReservationParams) (database.BookReservation, error) {
+	return database.BookReservation{}, sql.ErrNoRows
+}
+
+func (m *BookReservationMock) FulfillBookReservation(ctx context.Context, id uuid.UUID) error {
+	return nil
+}
+
+func (m *BookReservationMock) CountActiveBookReservations(ctx context.Context, bookID uuid.UUID) (int64, error) {
+	return 0, nil
+}
+
+func (m *BookReservationMock) CancelBookReservations(ctx context.Context, bookID uuid.UUID) error {
+	return nil
+}
+
 type UserSubscriberMock struct{}
 
 func (m *UserSubscriberMock) CreateUserSubscriber(ctx context.Context, arg database.CreateUserSubscriberParams) (database.UserSubscriber, error) {
@@ -72,11 +464,11 @@ func (m *UserSubscriberMock) GetUserSubscriber(ctx context.Context, arg database
 	return database.UserSubscriber{}, sql.ErrNoRows
 }
 
-func (m *UserSubscriberMock) GetUserSubscribers(ctx context.Context, userID uuid.UUID) ([]database.UserSubscriber, error) {
+func (m *UserSubscriberMock) GetUserSubscribers(ctx context.Context, arg database.GetUserSubscribersParams) ([]database.UserSubscriber, error) {
 	return []database.UserSubscriber{}, nil
 }
 
-func (m *UserSubscriberMock) GetUserSubscriptions(ctx context.Context, subscriberID uuid.UUID) ([]database.UserSubscriber, error) {
+func (m *UserSubscriberMock) GetUserSubscriptions(ctx context.Context, arg database.GetUserSubscriptionsParams) ([]database.UserSubscriber, error) {
 	return []database.UserSubscriber{}, nil
 }
 
@@ -88,18 +480,62 @@ func (m *UserSubscriberMock) DeleteUserSubscriber(ctx context.Context, arg datab
 	return 0, nil
 }
 
+type NotificationOutboxMock struct{}
+
+func (m *NotificationOutboxMock) CreateOutboxNotification(ctx context.Context, arg database.CreateOutboxNotificationParams) (database.NotificationOutbox, error) {
+	return database.NotificationOutbox{ID: arg.ID, Status: "pending", Sender: arg.Sender, Recipient: arg.Recipient, Subject: arg.Subject, Body: arg.Body, HtmlBody: arg.HtmlBody}, nil
+}
+
+func (m *NotificationOutboxMock) ClaimDueOutboxNotifications(ctx context.Context, arg database.ClaimDueOutboxNotificationsParams) ([]database.NotificationOutbox, error) {
+	return []database.NotificationOutbox{}, nil
+}
+
+func (m *NotificationOutboxMock) MarkOutboxNotificationSent(ctx context.Context, id uuid.UUID) error {
+	return nil
+}
+
+func (m *NotificationOutboxMock) RecordOutboxNotificationFailure(ctx context.Context, arg database.RecordOutboxNotificationFailureParams) (database.NotificationOutbox, error) {
+	panic("RecordOutboxNotificationFailure not implemented for this test (BaseMock)")
+}
+
+func (m *NotificationOutboxMock) GetOutboxNotifications(ctx context.Context, status sql.NullString) ([]database.NotificationOutbox, error) {
+	return []database.NotificationOutbox{}, nil
+}
+
+func (m *NotificationOutboxMock) ReplayOutboxNotification(ctx context.Context, id uuid.UUID) (database.NotificationOutbox, error) {
+	return database.NotificationOutbox{}, sql.ErrNoRows
+}
+
 type BaseMock struct {
 	*UserMock
 	*BookMock
+	*BookCopyMock
+	*BookTagMock
+	*ShelfMock
+	*BookPolicyMock
 	*BookBorrowMock
+	*BookClaimMock
+	*BookReviewMock
+	*BorrowerReputationMock
+	*BookReservationMock
 	*UserSubscriberMock
+	*NotificationOutboxMock
 }
 
 func NewBaseMock() *BaseMock {
 	return &BaseMock{
-		UserMock:           &UserMock{},
-		BookMock:           &BookMock{},
-		BookBorrowMock:     &BookBorrowMock{},
-		UserSubscriberMock: &UserSubscriberMock{},
+		UserMock:               &UserMock{},
+		BookMock:               &BookMock{},
+		BookCopyMock:           &BookCopyMock{},
+		BookTagMock:            &BookTagMock{},
+		ShelfMock:              &ShelfMock{},
+		BookPolicyMock:         &BookPolicyMock{},
+		BookBorrowMock:         &BookBorrowMock{},
+		BookClaimMock:          &BookClaimMock{},
+		BookReviewMock:         &BookReviewMock{},
+		BorrowerReputationMock: &BorrowerReputationMock{},
+		BookReservationMock:    &BookReservationMock{},
+		UserSubscriberMock:     &UserSubscriberMock{},
+		NotificationOutboxMock: &NotificationOutboxMock{},
 	}
 }
\ No newline at end of file
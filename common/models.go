@@ -2,6 +2,9 @@ package common
 
 import (
 	"context"
+	"database/sql"
+	"errors"
+	"time"
 
 	"github.com/elorenzorodz/co-library/internal/database"
 	"github.com/google/uuid"
@@ -11,38 +14,262 @@ type EnvConfig struct {
 	APIVersion           string
 	Port                 string
 	DBUrl                string
+	Notifier             string
+	NotificationSender   string
 	MailgunAPIKey        string
 	MailgunSendingDomain string
+	SMTPHost             string
+	SMTPPort             string
+	SMTPUsername         string
+	SMTPPassword         string
+	TemplatesDir         string
+	AdminEmails          []string
+	CatalogProvider      string
+	CatalogURL           string
+	CatalogFixturePath   string
+	BlobStore            string
+	BlobStoreDir         string
+	BlobStorePublicURL   string
+	S3Endpoint           string
+	S3Region             string
+	S3Bucket             string
+	S3AccessKeyID        string
+	S3SecretAccessKey    string
 }
 
 type APIConfig struct {
-	DB                   Querier
-	JWTValidationKey     interface{}
-	JWTSigningKey        interface{}
-	MailgunAPIKey        string
-	MailgunSendingDomain string
+	DB                 Querier
+	JWTValidationKey   interface{}
+	JWTSigningKey      interface{}
+	Transactor         Transactor
+	Notifier           Notifier
+	NotificationSender string
+	Templates          TemplateRenderer
+	Catalog            CatalogProvider
+	Blobs              BlobStore
+	AdminEmails        []string
+}
+
+// From and To are formatted as "Name <email>". HTMLBody is optional.
+type Notification struct {
+	From     string
+	To       string
+	Subject  string
+	Body     string
+	HTMLBody string
+}
+
+type Notifier interface {
+	Send(ctx context.Context, notification Notification) error
+}
+
+type RenderedTemplate struct {
+	Subject string
+	Text    string
+	HTML    string
+}
+
+type TemplateRenderer interface {
+	Render(templateName string, locale string, data any) (RenderedTemplate, error)
+}
+
+// What a catalog knows about an edition. Fields it doesn't know are left empty.
+type BookMetadata struct {
+	ISBN          string   `json:"isbn"`
+	Title         string   `json:"title"`
+	Authors       []string `json:"authors"`
+	Publisher     string   `json:"publisher"`
+	PublishedYear int32    `json:"published_year"`
+	PageCount     int32    `json:"page_count"`
+	CoverURL      string   `json:"cover_url"`
+}
+
+// Looks up book metadata by normalized ISBN-13. Returns ErrCatalogBookNotFound
+// when the catalog has no entry for it.
+type CatalogProvider interface {
+	LookupISBN(ctx context.Context, isbn string) (BookMetadata, error)
+}
+
+var ErrCatalogBookNotFound = errors.New("book not found in catalog")
+
+// Keeps uploaded files under slash separated keys. Put returns the URL clients
+// fetch the file from, deleting a key that is not there is not an error.
+type BlobStore interface {
+	Put(ctx context.Context, key string, contentType string, content []byte) (string, error)
+	Delete(ctx context.Context, key string) error
+}
+
+// Position of the last item on a page: its sort key and the id that breaks ties.
+// Clients only ever see it encoded.
+type Cursor struct {
+	Sort  string    `json:"s,omitempty"`
+	Value string    `json:"v"`
+	ID    uuid.UUID `json:"id"`
+}
+
+// Cursor is nil on the first page.
+type PageParameters struct {
+	Limit  int32
+	Cursor *Cursor
+}
+
+// NextCursor is null on the last page.
+type Page[T any] struct {
+	Data       []T     `json:"data"`
+	NextCursor *string `json:"next_cursor"`
+}
+
+// Loan periods in days. Members start with the default one, and new books get
+// their owner's default unless the book sets its own.
+const (
+	DefaultLoanPeriodDays = 14
+	MaxLoanPeriodDays     = 365
+)
+
+func (renderedTemplate RenderedTemplate) Notification(from string, to string) Notification {
+	return Notification{
+		From:     from,
+		To:       to,
+		Subject:  renderedTemplate.Subject,
+		Body:     renderedTemplate.Text,
+		HTMLBody: renderedTemplate.HTML,
+	}
 }
 
 type Querier interface {
 	CreateUser(ctx context.Context, arg database.CreateUserParams) (database.User, error)
 	GetUserByEmail(ctx context.Context, email string) (database.User, error)
 	GetUserByID(ctx context.Context, id uuid.UUID) (database.User, error)
+	UpdateUserLocale(ctx context.Context, arg database.UpdateUserLocaleParams) (database.User, error)
+	UpdateUserDefaultLoanPeriod(ctx context.Context, arg database.UpdateUserDefaultLoanPeriodParams) (database.User, error)
+	GetUserProfile(ctx context.Context, id uuid.UUID) (database.GetUserProfileRow, error)
 
 	CreateBook(ctx context.Context, arg database.CreateBookParams) (database.Book, error)
 	GetBook(ctx context.Context, id uuid.UUID) (database.Book, error)
-	GetBooks(ctx context.Context, userID uuid.UUID) ([]database.Book, error)
-	BrowseBooks(ctx context.Context) ([]database.Book, error)
+	GetBookWithAvailability(ctx context.Context, id uuid.UUID) (database.GetBookWithAvailabilityRow, error)
+	GetBooks(ctx context.Context, arg database.GetBooksParams) ([]database.GetBooksRow, error)
+	BrowseBooks(ctx context.Context, arg database.BrowseBooksParams) ([]database.BrowseBooksRow, error)
 	UpdateBook(ctx context.Context, arg database.UpdateBookParams) (database.Book, error)
+	SetBookCover(ctx context.Context, arg database.SetBookCoverParams) (database.Book, error)
+	GetBookIdentities(ctx context.Context, userID uuid.UUID) ([]database.GetBookIdentitiesRow, error)
 	DeleteBook(ctx context.Context, arg database.DeleteBookParams) (int64, error)
+	GetDeletedBooks(ctx context.Context, arg database.GetDeletedBooksParams) ([]database.GetDeletedBooksRow, error)
+	RestoreBook(ctx context.Context, arg database.RestoreBookParams) (database.Book, error)
+	PurgeDeletedBooks(ctx context.Context, arg database.PurgeDeletedBooksParams) ([]database.PurgeDeletedBooksRow, error)
+
+	CreateBookCopy(ctx context.Context, arg database.CreateBookCopyParams) (database.BookCopy, error)
+	GetBookCopies(ctx context.Context, bookID uuid.UUID) ([]database.GetBookCopiesRow, error)
+	UpdateBookCopy(ctx context.Context, arg database.UpdateBookCopyParams) (database.BookCopy, error)
+	DeleteBookCopy(ctx context.Context, arg database.DeleteBookCopyParams) (int64, error)
+	RetireBookCopy(ctx context.Context, arg database.RetireBookCopyParams) (int64, error)
+	CountAvailableBookCopies(ctx context.Context, bookID uuid.UUID) (int64, error)
+
+	GetGenres(ctx context.Context, viewerID uuid.UUID) ([]database.GetGenresRow, error)
+	GetPopularBookTags(ctx context.Context, arg database.GetPopularBookTagsParams) ([]database.GetPopularBookTagsRow, error)
+	GetBookGenresAndTags(ctx context.Context, id uuid.UUID) (database.GetBookGenresAndTagsRow, error)
+	DeleteBookGenres(ctx context.Context, bookID uuid.UUID) error
+	CreateBookGenres(ctx context.Context, arg database.CreateBookGenresParams) error
+	DeleteBookTags(ctx context.Context, bookID uuid.UUID) error
+	CreateBookTags(ctx context.Context, arg database.CreateBookTagsParams) error
+
+	CreateShelf(ctx context.Context, arg database.CreateShelfParams) (database.Shelf, error)
+	GetShelf(ctx context.Context, arg database.GetShelfParams) (database.GetShelfRow, error)
+	GetShelfByName(ctx context.Context, arg database.GetShelfByNameParams) (database.Shelf, error)
+	GetShelvesByUserID(ctx context.Context, arg database.GetShelvesByUserIDParams) ([]database.GetShelvesByUserIDRow, error)
+	UpdateShelf(ctx context.Context, arg database.UpdateShelfParams) (database.Shelf, error)
+	DeleteShelf(ctx context.Context, arg database.DeleteShelfParams) (int64, error)
+	AddShelfBook(ctx context.Context, arg database.AddShelfBookParams) (database.ShelfBook, error)
+	RemoveShelfBook(ctx context.Context, arg database.RemoveShelfBookParams) (int64, error)
+	GetShelfBooks(ctx context.Context, arg database.GetShelfBooksParams) ([]database.GetShelfBooksRow, error)
+	GetShelfBookIDs(ctx context.Context, shelfID uuid.UUID) ([]uuid.UUID, error)
+	ReorderShelfBooks(ctx context.Context, arg database.ReorderShelfBooksParams) error
 
-	GetBookBorrow(ctx context.Context, bookID uuid.UUID) (database.BookBorrow, error)
+	GetMemberRelationship(ctx context.Context, arg database.GetMemberRelationshipParams) (database.GetMemberRelationshipRow, error)
+	CreateApprovedBorrower(ctx context.Context, arg database.CreateApprovedBorrowerParams) (database.ApprovedBorrower, error)
+	GetApprovedBorrower(ctx context.Context, arg database.GetApprovedBorrowerParams) (database.ApprovedBorrower, error)
+	GetApprovedBorrowers(ctx context.Context, arg database.GetApprovedBorrowersParams) ([]database.GetApprovedBorrowersRow, error)
+	DeleteApprovedBorrower(ctx context.Context, arg database.DeleteApprovedBorrowerParams) (int64, error)
+
+	LockBookForIssue(ctx context.Context, id uuid.UUID) error
 	IssueBook(ctx context.Context, arg database.IssueBookParams) (database.BookBorrow, error)
 	ReturnBook(ctx context.Context, arg database.ReturnBookParams) (database.BookBorrow, error)
+	GetBookBorrowByID(ctx context.Context, id uuid.UUID) (database.BookBorrow, error)
+	RenewBookBorrow(ctx context.Context, arg database.RenewBookBorrowParams) (database.BookBorrowRenewal, error)
+	CountBookBorrowRenewals(ctx context.Context, bookBorrowID uuid.UUID) (int64, error)
+	GetBookBorrowRenewals(ctx context.Context, bookBorrowID uuid.UUID) ([]database.BookBorrowRenewal, error)
+	ConfirmBookReturn(ctx context.Context, arg database.ConfirmBookReturnParams) (database.BookBorrow, error)
+	DisputeBookReturn(ctx context.Context, arg database.DisputeBookReturnParams) (database.BookBorrow, error)
+	GetBookBorrowsDueForReminder(ctx context.Context, arg database.GetBookBorrowsDueForReminderParams) ([]database.GetBookBorrowsDueForReminderRow, error)
+	CreateBookBorrowReminder(ctx context.Context, arg database.CreateBookBorrowReminderParams) (database.BookBorrowReminder, error)
+	DeleteBookBorrowReminder(ctx context.Context, id uuid.UUID) error
+	GetBorrowedBooks(ctx context.Context, arg database.GetBorrowedBooksParams) ([]database.GetBorrowedBooksRow, error)
+	GetLentBooks(ctx context.Context, arg database.GetLentBooksParams) ([]database.GetLentBooksRow, error)
+
+	CreateBookBorrowRequest(ctx context.Context, arg database.CreateBookBorrowRequestParams) (database.BookBorrowRequest, error)
+	GetOpenBookBorrowRequest(ctx context.Context, arg database.GetOpenBookBorrowRequestParams) (database.BookBorrowRequest, error)
+	RespondToBookBorrowRequest(ctx context.Context, arg database.RespondToBookBorrowRequestParams) (database.BookBorrowRequest, error)
+	CancelBookBorrowRequest(ctx context.Context, arg database.CancelBookBorrowRequestParams) (database.BookBorrowRequest, error)
+	MarkBookBorrowRequestIssued(ctx context.Context, arg database.MarkBookBorrowRequestIssuedParams) (int64, error)
+	MarkBookBorrowRequestReturned(ctx context.Context, bookBorrowID uuid.UUID) error
+	GetIncomingBookBorrowRequests(ctx context.Context, arg database.GetIncomingBookBorrowRequestsParams) ([]database.GetIncomingBookBorrowRequestsRow, error)
+	GetOutgoingBookBorrowRequests(ctx context.Context, arg database.GetOutgoingBookBorrowRequestsParams) ([]database.GetOutgoingBookBorrowRequestsRow, error)
+	DeclineOpenBookBorrowRequests(ctx context.Context, bookID uuid.UUID) error
+
+	GetBookBorrowWithOwner(ctx context.Context, id uuid.UUID) (database.GetBookBorrowWithOwnerRow, error)
+	CreateBookConditionReport(ctx context.Context, arg database.CreateBookConditionReportParams) (database.BookConditionReport, error)
+	GetBookConditionReports(ctx context.Context, bookBorrowID uuid.UUID) ([]database.BookConditionReport, error)
+	ReportBookBorrowLost(ctx context.Context, id uuid.UUID) (database.BookBorrow, error)
+	ClearBookBorrowLost(ctx context.Context, id uuid.UUID) error
+	CloseLostBookBorrow(ctx context.Context, id uuid.UUID) (int64, error)
+	MarkBookCopyLost(ctx context.Context, id uuid.UUID) error
+	CreateBookClaim(ctx context.Context, arg database.CreateBookClaimParams) (database.BookClaim, error)
+	GetBookClaim(ctx context.Context, id uuid.UUID) (database.GetBookClaimRow, error)
+	GetUserBookClaims(ctx context.Context, arg database.GetUserBookClaimsParams) ([]database.GetUserBookClaimsRow, error)
+	ResolveBookClaim(ctx context.Context, arg database.ResolveBookClaimParams) (database.BookClaim, error)
+	CreateBookClaimComment(ctx context.Context, arg database.CreateBookClaimCommentParams) (database.BookClaimComment, error)
+	GetBookClaimComments(ctx context.Context, bookClaimID uuid.UUID) ([]database.GetBookClaimCommentsRow, error)
+
+	HasReturnedBookBorrow(ctx context.Context, arg database.HasReturnedBookBorrowParams) (bool, error)
+	CreateBookReview(ctx context.Context, arg database.CreateBookReviewParams) (database.BookReview, error)
+	GetBookReviewForUpdate(ctx context.Context, id uuid.UUID) (database.BookReview, error)
+	UpdateBookReview(ctx context.Context, arg database.UpdateBookReviewParams) (database.BookReview, error)
+	DeleteBookReview(ctx context.Context, arg database.DeleteBookReviewParams) (database.BookReview, error)
+	ReplyToBookReview(ctx context.Context, arg database.ReplyToBookReviewParams) (database.BookReview, error)
+	GetBookReviews(ctx context.Context, arg database.GetBookReviewsParams) ([]database.GetBookReviewsRow, error)
+	AdjustBookRating(ctx context.Context, arg database.AdjustBookRatingParams) error
+
+	GetBorrowerReputationStats(ctx context.Context, borrowerID uuid.UUID) (database.BorrowerReputationStat, error)
+	CreateBorrowerRating(ctx context.Context, arg database.CreateBorrowerRatingParams) (database.BorrowerRating, error)
+
+	CreateBookReservation(ctx context.Context, arg database.CreateBookReservationParams) (database.BookReservation, error)
+	GetActiveBookReservation(ctx context.Context, arg database.GetActiveBookReservationParams) (database.BookReservation, error)
+	GetUserBookReservations(ctx context.Context, userID uuid.UUID) ([]database.GetUserBookReservationsRow, error)
+	CountBookReservationsAhead(ctx context.Context, arg database.CountBookReservationsAheadParams) (int64, error)
+	CancelBookReservation(ctx context.Context, arg database.CancelBookReservationParams) (database.BookReservation, error)
+	ExpireBookReservationOffers(ctx context.Context, bookID uuid.UUID) error
+	GetBooksWithLapsedBookReservationOffers(ctx context.Context, expiredBefore time.Time) ([]uuid.UUID, error)
+	GetOfferedBookReservations(ctx context.Context, bookID uuid.UUID) ([]database.BookReservation, error)
+	OfferNextBookReservation(ctx context.Context, arg database.OfferNextBookReservationParams) (database.BookReservation, error)
+	FulfillBookReservation(ctx context.Context, id uuid.UUID) error
+	CountActiveBookReservations(ctx context.Context, bookID uuid.UUID) (int64, error)
+	CancelBookReservations(ctx context.Context, bookID uuid.UUID) error
 
 	CreateUserSubscriber(ctx context.Context, arg database.CreateUserSubscriberParams) (database.UserSubscriber, error)
 	GetUserSubscriber(ctx context.Context, arg database.GetUserSubscriberParams) (database.UserSubscriber, error)
-	GetUserSubscribers(ctx context.Context, userID uuid.UUID) ([]database.UserSubscriber, error)
-	GetUserSubscriptions(ctx context.Context, subscriberID uuid.UUID) ([]database.UserSubscriber, error)
+	GetUserSubscribers(ctx context.Context, arg database.GetUserSubscribersParams) ([]database.UserSubscriber, error)
+	GetUserSubscriptions(ctx context.Context, arg database.GetUserSubscriptionsParams) ([]database.UserSubscriber, error)
 	GetUsersBySubscriberID(ctx context.Context, userID uuid.UUID) ([]database.User, error)
 	DeleteUserSubscriber(ctx context.Context, arg database.DeleteUserSubscriberParams) (int64, error)
+
+	CreateOutboxNotification(ctx context.Context, arg database.CreateOutboxNotificationParams) (database.NotificationOutbox, error)
+	ClaimDueOutboxNotifications(ctx context.Context, arg database.ClaimDueOutboxNotificationsParams) ([]database.NotificationOutbox, error)
+	MarkOutboxNotificationSent(ctx context.Context, id uuid.UUID) error
+	RecordOutboxNotificationFailure(ctx context.Context, arg database.RecordOutboxNotificationFailureParams) (database.NotificationOutbox, error)
+	GetOutboxNotifications(ctx context.Context, status sql.NullString) ([]database.NotificationOutbox, error)
+	ReplayOutboxNotification(ctx context.Context, id uuid.UUID) (database.NotificationOutbox, error)
+}
+
+// Runs fn against a Querier bound to a single database transaction.
+type Transactor interface {
+	WithTransaction(ctx context.Context, fn func(querier Querier) error) error
 }
\ No newline at end of file
@@ -0,0 +1,89 @@
+package common
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+
+	"github.com/google/uuid"
+)
+
+const (
+	DefaultPageLimit = 20
+	MaxPageLimit     = 100
+)
+
+// Reads the optional ?limit= and ?cursor= query values.
+func ParsePageParameters(query url.Values) (PageParameters, error) {
+	pageParameters := PageParameters{Limit: DefaultPageLimit}
+
+	if limit := query.Get("limit"); limit != "" {
+		parsedLimit, parseLimitError := strconv.Atoi(limit)
+
+		if parseLimitError != nil || parsedLimit < 1 || parsedLimit > MaxPageLimit {
+			return PageParameters{}, fmt.Errorf("invalid limit: %s, must be between 1 and %d", limit, MaxPageLimit)
+		}
+
+		pageParameters.Limit = int32(parsedLimit)
+	}
+
+	if cursor := query.Get("cursor"); cursor != "" {
+		decodedCursor, decodeCursorError := DecodeCursor(cursor)
+
+		if decodeCursorError != nil {
+			return PageParameters{}, decodeCursorError
+		}
+
+		pageParameters.Cursor = &decodedCursor
+	}
+
+	return pageParameters, nil
+}
+
+// One more row than the page holds is fetched to tell whether there is a next page.
+func (pageParameters PageParameters) QueryLimit() int32 {
+	return pageParameters.Limit + 1
+}
+
+// The cursor id as a query parameter, null on the first page.
+func (pageParameters PageParameters) CursorID() uuid.NullUUID {
+	if pageParameters.Cursor == nil {
+		return uuid.NullUUID{}
+	}
+
+	return uuid.NullUUID{UUID: pageParameters.Cursor.ID, Valid: true}
+}
+
+func EncodeCursor(cursor Cursor) string {
+	encodedCursor, _ := json.Marshal(cursor)
+
+	return base64.RawURLEncoding.EncodeToString(encodedCursor)
+}
+
+func DecodeCursor(encodedCursor string) (Cursor, error) {
+	cursor := Cursor{}
+
+	decodedCursor, decodeError := base64.RawURLEncoding.DecodeString(encodedCursor)
+
+	if decodeError != nil || json.Unmarshal(decodedCursor, &cursor) != nil || cursor.ID == uuid.Nil {
+		return Cursor{}, errors.New("invalid cursor")
+	}
+
+	return cursor, nil
+}
+
+// Drops the extra row fetched with QueryLimit and returns the cursor of the
+// last row kept, or nil when this is the last page.
+func SplitPage[T any](rows []T, pageParameters PageParameters, cursorOf func(T) Cursor) ([]T, *string) {
+	if len(rows) <= int(pageParameters.Limit) {
+		return rows, nil
+	}
+
+	rows = rows[:pageParameters.Limit]
+	nextCursor := EncodeCursor(cursorOf(rows[len(rows)-1]))
+
+	return rows, &nextCursor
+}
@@ -5,6 +5,8 @@ import (
 	"fmt"
 	"log"
 	"regexp"
+	"strconv"
+	"strings"
 	"unicode"
 
 	"github.com/golang-jwt/jwt/v5"
@@ -81,4 +83,79 @@ func ValidateJWTAndGetEmailClaim(signedToken string, publicKey interface{}) (str
 	}
 
 	return "", errors.New("invalid token")
-}
\ No newline at end of file
+}
+
+func IsLoanPeriodValid(loanPeriodDays int32) bool {
+	return loanPeriodDays > 0 && loanPeriodDays <= MaxLoanPeriodDays
+}
+
+func IsAdminEmail(adminEmails []string, email string) bool {
+	for _, adminEmail := range adminEmails {
+		if strings.EqualFold(adminEmail, email) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Strips hyphens and spaces, checks the check digit and returns the ISBN-13 form.
+// ISBN-10s are converted by adding the 978 prefix.
+func NormalizeISBN(isbn string) (string, error) {
+	digits := strings.ToUpper(strings.NewReplacer("-", "", " ", "").Replace(strings.TrimSpace(isbn)))
+
+	switch len(digits) {
+	case 10:
+		sum := 0
+
+		for i, char := range digits {
+			value := int(char - '0')
+
+			if char == 'X' && i == 9 {
+				value = 10
+			} else if char < '0' || char > '9' {
+				return "", fmt.Errorf("invalid isbn: %s", isbn)
+			}
+
+			sum += value * (10 - i)
+		}
+
+		if sum%11 != 0 {
+			return "", fmt.Errorf("invalid isbn check digit: %s", isbn)
+		}
+
+		isbn13 := "978" + digits[:9]
+
+		return isbn13 + strconv.Itoa(isbn13CheckDigit(isbn13)), nil
+	case 13:
+		for _, char := range digits {
+			if char < '0' || char > '9' {
+				return "", fmt.Errorf("invalid isbn: %s", isbn)
+			}
+		}
+
+		if isbn13CheckDigit(digits[:12]) != int(digits[12]-'0') {
+			return "", fmt.Errorf("invalid isbn check digit: %s", isbn)
+		}
+
+		return digits, nil
+	}
+
+	return "", fmt.Errorf("invalid isbn: %s, must have 10 or 13 digits", isbn)
+}
+
+func isbn13CheckDigit(first12Digits string) int {
+	sum := 0
+
+	for i, char := range first12Digits {
+		weight := 1
+
+		if i%2 == 1 {
+			weight = 3
+		}
+
+		sum += int(char-'0') * weight
+	}
+
+	return (10 - sum%10) % 10
+}
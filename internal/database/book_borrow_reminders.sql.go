@@ -0,0 +1,142 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: book_borrow_reminders.sql
+
+package database
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const createBookBorrowReminder = `-- name: CreateBookBorrowReminder :one
+INSERT INTO book_borrow_reminders (id, threshold, sent_at, book_borrow_id, due_at)
+VALUES ($1, $2, NOW(), $3, $4)
+ON CONFLICT (book_borrow_id, threshold, due_at) DO NOTHING
+RETURNING id, threshold, sent_at, book_borrow_id, due_at
+`
+
+type CreateBookBorrowReminderParams struct {
+	ID           uuid.UUID
+	Threshold    string
+	BookBorrowID uuid.UUID
+	DueAt        time.Time
+}
+
+func (q *Queries) CreateBookBorrowReminder(ctx context.Context, arg CreateBookBorrowReminderParams) (BookBorrowReminder, error) {
+	row := q.db.QueryRowContext(ctx, createBookBorrowReminder,
+		arg.ID,
+		arg.Threshold,
+		arg.BookBorrowID,
+		arg.DueAt,
+	)
+	var i BookBorrowReminder
+	err := row.Scan(
+		&i.ID,
+		&i.Threshold,
+		&i.SentAt,
+		&i.BookBorrowID,
+		&i.DueAt,
+	)
+	return i, err
+}
+
+const deleteBookBorrowReminder = `-- name: DeleteBookBorrowReminder :exec
+DELETE FROM book_borrow_reminders WHERE id = $1
+`
+
+func (q *Queries) DeleteBookBorrowReminder(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, deleteBookBorrowReminder, id)
+	return err
+}
+
+const getBookBorrowsDueForReminder = `-- name: GetBookBorrowsDueForReminder :many
+SELECT bb.id, bb.issued_at, bb.returned_at, bb.created_at, bb.updated_at, bb.book_id, bb.borrower_id, bb.due_at, bb.return_status, bb.return_confirmed_at, bb.return_disputed_at, bb.book_copy_id, bb.lost_at, b.title AS book_title, b.user_id AS owner_id,
+    o.first_name AS owner_first_name, o.last_name AS owner_last_name, o.email AS owner_email, o.locale AS owner_locale,
+    u.first_name AS borrower_first_name, u.last_name AS borrower_last_name, u.email AS borrower_email, u.locale AS borrower_locale
+FROM book_borrows AS bb
+INNER JOIN books AS b ON b.id = bb.book_id
+INNER JOIN users AS o ON o.id = b.user_id
+INNER JOIN users AS u ON u.id = bb.borrower_id
+WHERE bb.return_confirmed_at IS NULL
+    AND (bb.returned_at IS NULL OR bb.return_status = 'disputed')
+    AND bb.lost_at IS NULL
+    AND bb.due_at <= $1::timestamp
+    AND ($2::timestamp IS NULL OR bb.due_at > $2::timestamp)
+    AND NOT EXISTS (
+        SELECT 1 FROM book_borrow_reminders AS bbr
+        WHERE bbr.book_borrow_id = bb.id AND bbr.threshold = $3::text AND bbr.due_at = bb.due_at
+    )
+ORDER BY b.user_id, bb.due_at
+`
+
+type GetBookBorrowsDueForReminderParams struct {
+	DueBefore time.Time
+	DueAfter  sql.NullTime
+	Threshold string
+}
+
+type GetBookBorrowsDueForReminderRow struct {
+	BookBorrow        BookBorrow
+	BookTitle         string
+	OwnerID           uuid.UUID
+	OwnerFirstName    string
+	OwnerLastName     string
+	OwnerEmail        string
+	OwnerLocale       string
+	BorrowerFirstName string
+	BorrowerLastName  string
+	BorrowerEmail     string
+	BorrowerLocale    string
+}
+
+func (q *Queries) GetBookBorrowsDueForReminder(ctx context.Context, arg GetBookBorrowsDueForReminderParams) ([]GetBookBorrowsDueForReminderRow, error) {
+	rows, err := q.db.QueryContext(ctx, getBookBorrowsDueForReminder, arg.DueBefore, arg.DueAfter, arg.Threshold)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetBookBorrowsDueForReminderRow
+	for rows.Next() {
+		var i GetBookBorrowsDueForReminderRow
+		if err := rows.Scan(
+			&i.BookBorrow.ID,
+			&i.BookBorrow.IssuedAt,
+			&i.BookBorrow.ReturnedAt,
+			&i.BookBorrow.CreatedAt,
+			&i.BookBorrow.UpdatedAt,
+			&i.BookBorrow.BookID,
+			&i.BookBorrow.BorrowerID,
+			&i.BookBorrow.DueAt,
+			&i.BookBorrow.ReturnStatus,
+			&i.BookBorrow.ReturnConfirmedAt,
+			&i.BookBorrow.ReturnDisputedAt,
+			&i.BookBorrow.BookCopyID,
+			&i.BookBorrow.LostAt,
+			&i.BookTitle,
+			&i.OwnerID,
+			&i.OwnerFirstName,
+			&i.OwnerLastName,
+			&i.OwnerEmail,
+			&i.OwnerLocale,
+			&i.BorrowerFirstName,
+			&i.BorrowerLastName,
+			&i.BorrowerEmail,
+			&i.BorrowerLocale,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
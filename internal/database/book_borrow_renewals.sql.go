@@ -0,0 +1,97 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: book_borrow_renewals.sql
+
+package database
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const countBookBorrowRenewals = `-- name: CountBookBorrowRenewals :one
+SELECT COUNT(*) FROM book_borrow_renewals WHERE book_borrow_id = $1
+`
+
+func (q *Queries) CountBookBorrowRenewals(ctx context.Context, bookBorrowID uuid.UUID) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countBookBorrowRenewals, bookBorrowID)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const getBookBorrowRenewals = `-- name: GetBookBorrowRenewals :many
+SELECT id, previous_due_at, new_due_at, created_at, book_borrow_id FROM book_borrow_renewals WHERE book_borrow_id = $1 ORDER BY created_at
+`
+
+func (q *Queries) GetBookBorrowRenewals(ctx context.Context, bookBorrowID uuid.UUID) ([]BookBorrowRenewal, error) {
+	rows, err := q.db.QueryContext(ctx, getBookBorrowRenewals, bookBorrowID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []BookBorrowRenewal
+	for rows.Next() {
+		var i BookBorrowRenewal
+		if err := rows.Scan(
+			&i.ID,
+			&i.PreviousDueAt,
+			&i.NewDueAt,
+			&i.CreatedAt,
+			&i.BookBorrowID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const renewBookBorrow = `-- name: RenewBookBorrow :one
+WITH renewed_book_borrow AS (
+    UPDATE book_borrows
+    SET due_at = $3::timestamp, updated_at = NOW()
+    WHERE book_borrows.id = $4 AND borrower_id = $5 AND returned_at IS NULL AND lost_at IS NULL AND due_at = $2::timestamp
+    RETURNING book_borrows.id, book_borrows.due_at
+)
+INSERT INTO book_borrow_renewals (id, previous_due_at, new_due_at, created_at, book_borrow_id)
+SELECT $1, $2::timestamp, renewed_book_borrow.due_at, NOW(), renewed_book_borrow.id
+FROM renewed_book_borrow
+RETURNING id, previous_due_at, new_due_at, created_at, book_borrow_id
+`
+
+type RenewBookBorrowParams struct {
+	ID            uuid.UUID
+	PreviousDueAt time.Time
+	NewDueAt      time.Time
+	BookBorrowID  uuid.UUID
+	BorrowerID    uuid.UUID
+}
+
+func (q *Queries) RenewBookBorrow(ctx context.Context, arg RenewBookBorrowParams) (BookBorrowRenewal, error) {
+	row := q.db.QueryRowContext(ctx, renewBookBorrow,
+		arg.ID,
+		arg.PreviousDueAt,
+		arg.NewDueAt,
+		arg.BookBorrowID,
+		arg.BorrowerID,
+	)
+	var i BookBorrowRenewal
+	err := row.Scan(
+		&i.ID,
+		&i.PreviousDueAt,
+		&i.NewDueAt,
+		&i.CreatedAt,
+		&i.BookBorrowID,
+	)
+	return i, err
+}
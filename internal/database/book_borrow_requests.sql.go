@@ -0,0 +1,291 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: book_borrow_requests.sql
+
+package database
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/google/uuid"
+)
+
+const cancelBookBorrowRequest = `-- name: CancelBookBorrowRequest :one
+UPDATE book_borrow_requests
+SET status = 'cancelled', updated_at = NOW()
+WHERE id = $1 AND requester_id = $2 AND status IN ('requested', 'approved')
+RETURNING id, status, responded_at, created_at, updated_at, book_id, requester_id, book_borrow_id
+`
+
+type CancelBookBorrowRequestParams struct {
+	ID          uuid.UUID
+	RequesterID uuid.UUID
+}
+
+func (q *Queries) CancelBookBorrowRequest(ctx context.Context, arg CancelBookBorrowRequestParams) (BookBorrowRequest, error) {
+	row := q.db.QueryRowContext(ctx, cancelBookBorrowRequest, arg.ID, arg.RequesterID)
+	var i BookBorrowRequest
+	err := row.Scan(
+		&i.ID,
+		&i.Status,
+		&i.RespondedAt,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.BookID,
+		&i.RequesterID,
+		&i.BookBorrowID,
+	)
+	return i, err
+}
+
+const createBookBorrowRequest = `-- name: CreateBookBorrowRequest :one
+INSERT INTO book_borrow_requests (id, status, created_at, updated_at, book_id, requester_id)
+VALUES ($1, 'requested', NOW(), NOW(), $2, $3)
+RETURNING id, status, responded_at, created_at, updated_at, book_id, requester_id, book_borrow_id
+`
+
+type CreateBookBorrowRequestParams struct {
+	ID          uuid.UUID
+	BookID      uuid.UUID
+	RequesterID uuid.UUID
+}
+
+func (q *Queries) CreateBookBorrowRequest(ctx context.Context, arg CreateBookBorrowRequestParams) (BookBorrowRequest, error) {
+	row := q.db.QueryRowContext(ctx, createBookBorrowRequest, arg.ID, arg.BookID, arg.RequesterID)
+	var i BookBorrowRequest
+	err := row.Scan(
+		&i.ID,
+		&i.Status,
+		&i.RespondedAt,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.BookID,
+		&i.RequesterID,
+		&i.BookBorrowID,
+	)
+	return i, err
+}
+
+const declineOpenBookBorrowRequests = `-- name: DeclineOpenBookBorrowRequests :exec
+UPDATE book_borrow_requests
+SET status = 'declined', responded_at = NOW(), updated_at = NOW()
+WHERE book_id = $1 AND status IN ('requested', 'approved')
+`
+
+// Open requests for a book going to the trash are turned down.
+func (q *Queries) DeclineOpenBookBorrowRequests(ctx context.Context, bookID uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, declineOpenBookBorrowRequests, bookID)
+	return err
+}
+
+const getIncomingBookBorrowRequests = `-- name: GetIncomingBookBorrowRequests :many
+SELECT bbr.id, bbr.status, bbr.responded_at, bbr.created_at, bbr.updated_at, bbr.book_id, bbr.requester_id, bbr.book_borrow_id, b.title AS book_title, u.first_name AS requester_first_name, u.last_name AS requester_last_name, brs.borrower_id, brs.scored_loans, brs.on_time_returns, brs.late_loans, brs.overdue_days, brs.capped_overdue_days, brs.disputed_returns, brs.lost_books, brs.rating_count, brs.rating_average
+FROM book_borrow_requests AS bbr
+INNER JOIN books AS b ON b.id = bbr.book_id
+INNER JOIN users AS u ON u.id = bbr.requester_id
+INNER JOIN borrower_reputation_stats AS brs ON brs.borrower_id = bbr.requester_id
+WHERE b.user_id = $1 AND ($2::text IS NULL OR bbr.status = $2::text)
+ORDER BY bbr.created_at DESC
+`
+
+type GetIncomingBookBorrowRequestsParams struct {
+	UserID uuid.UUID
+	Status sql.NullString
+}
+
+type GetIncomingBookBorrowRequestsRow struct {
+	BookBorrowRequest      BookBorrowRequest
+	BookTitle              string
+	RequesterFirstName     string
+	RequesterLastName      string
+	BorrowerReputationStat BorrowerReputationStat
+}
+
+func (q *Queries) GetIncomingBookBorrowRequests(ctx context.Context, arg GetIncomingBookBorrowRequestsParams) ([]GetIncomingBookBorrowRequestsRow, error) {
+	rows, err := q.db.QueryContext(ctx, getIncomingBookBorrowRequests, arg.UserID, arg.Status)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetIncomingBookBorrowRequestsRow
+	for rows.Next() {
+		var i GetIncomingBookBorrowRequestsRow
+		if err := rows.Scan(
+			&i.BookBorrowRequest.ID,
+			&i.BookBorrowRequest.Status,
+			&i.BookBorrowRequest.RespondedAt,
+			&i.BookBorrowRequest.CreatedAt,
+			&i.BookBorrowRequest.UpdatedAt,
+			&i.BookBorrowRequest.BookID,
+			&i.BookBorrowRequest.RequesterID,
+			&i.BookBorrowRequest.BookBorrowID,
+			&i.BookTitle,
+			&i.RequesterFirstName,
+			&i.RequesterLastName,
+			&i.BorrowerReputationStat.BorrowerID,
+			&i.BorrowerReputationStat.ScoredLoans,
+			&i.BorrowerReputationStat.OnTimeReturns,
+			&i.BorrowerReputationStat.LateLoans,
+			&i.BorrowerReputationStat.OverdueDays,
+			&i.BorrowerReputationStat.CappedOverdueDays,
+			&i.BorrowerReputationStat.DisputedReturns,
+			&i.BorrowerReputationStat.LostBooks,
+			&i.BorrowerReputationStat.RatingCount,
+			&i.BorrowerReputationStat.RatingAverage,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getOpenBookBorrowRequest = `-- name: GetOpenBookBorrowRequest :one
+SELECT id, status, responded_at, created_at, updated_at, book_id, requester_id, book_borrow_id FROM book_borrow_requests WHERE book_id = $1 AND requester_id = $2 AND status IN ('requested', 'approved', 'issued')
+`
+
+type GetOpenBookBorrowRequestParams struct {
+	BookID      uuid.UUID
+	RequesterID uuid.UUID
+}
+
+func (q *Queries) GetOpenBookBorrowRequest(ctx context.Context, arg GetOpenBookBorrowRequestParams) (BookBorrowRequest, error) {
+	row := q.db.QueryRowContext(ctx, getOpenBookBorrowRequest, arg.BookID, arg.RequesterID)
+	var i BookBorrowRequest
+	err := row.Scan(
+		&i.ID,
+		&i.Status,
+		&i.RespondedAt,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.BookID,
+		&i.RequesterID,
+		&i.BookBorrowID,
+	)
+	return i, err
+}
+
+const getOutgoingBookBorrowRequests = `-- name: GetOutgoingBookBorrowRequests :many
+SELECT bbr.id, bbr.status, bbr.responded_at, bbr.created_at, bbr.updated_at, bbr.book_id, bbr.requester_id, bbr.book_borrow_id, b.title AS book_title, u.first_name AS owner_first_name, u.last_name AS owner_last_name
+FROM book_borrow_requests AS bbr
+INNER JOIN books AS b ON b.id = bbr.book_id
+INNER JOIN users AS u ON u.id = b.user_id
+WHERE bbr.requester_id = $1 AND ($2::text IS NULL OR bbr.status = $2::text)
+ORDER BY bbr.created_at DESC
+`
+
+type GetOutgoingBookBorrowRequestsParams struct {
+	RequesterID uuid.UUID
+	Status      sql.NullString
+}
+
+type GetOutgoingBookBorrowRequestsRow struct {
+	BookBorrowRequest BookBorrowRequest
+	BookTitle         string
+	OwnerFirstName    string
+	OwnerLastName     string
+}
+
+func (q *Queries) GetOutgoingBookBorrowRequests(ctx context.Context, arg GetOutgoingBookBorrowRequestsParams) ([]GetOutgoingBookBorrowRequestsRow, error) {
+	rows, err := q.db.QueryContext(ctx, getOutgoingBookBorrowRequests, arg.RequesterID, arg.Status)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetOutgoingBookBorrowRequestsRow
+	for rows.Next() {
+		var i GetOutgoingBookBorrowRequestsRow
+		if err := rows.Scan(
+			&i.BookBorrowRequest.ID,
+			&i.BookBorrowRequest.Status,
+			&i.BookBorrowRequest.RespondedAt,
+			&i.BookBorrowRequest.CreatedAt,
+			&i.BookBorrowRequest.UpdatedAt,
+			&i.BookBorrowRequest.BookID,
+			&i.BookBorrowRequest.RequesterID,
+			&i.BookBorrowRequest.BookBorrowID,
+			&i.BookTitle,
+			&i.OwnerFirstName,
+			&i.OwnerLastName,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const markBookBorrowRequestIssued = `-- name: MarkBookBorrowRequestIssued :execrows
+UPDATE book_borrow_requests
+SET status = 'issued', book_borrow_id = $1::uuid, updated_at = NOW()
+WHERE id = $2 AND status = 'approved'
+`
+
+type MarkBookBorrowRequestIssuedParams struct {
+	BookBorrowID uuid.UUID
+	ID           uuid.UUID
+}
+
+// An approval is used up by the loan it is issued with, no rows means it already was.
+func (q *Queries) MarkBookBorrowRequestIssued(ctx context.Context, arg MarkBookBorrowRequestIssuedParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, markBookBorrowRequestIssued, arg.BookBorrowID, arg.ID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const markBookBorrowRequestReturned = `-- name: MarkBookBorrowRequestReturned :exec
+UPDATE book_borrow_requests
+SET status = 'returned', updated_at = NOW()
+WHERE book_borrow_id = $1::uuid AND status = 'issued'
+`
+
+func (q *Queries) MarkBookBorrowRequestReturned(ctx context.Context, bookBorrowID uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, markBookBorrowRequestReturned, bookBorrowID)
+	return err
+}
+
+const respondToBookBorrowRequest = `-- name: RespondToBookBorrowRequest :one
+UPDATE book_borrow_requests AS bbr
+SET status = $1, responded_at = NOW(), updated_at = NOW()
+FROM books AS b
+WHERE bbr.id = $2 AND bbr.book_id = b.id AND b.user_id = $3 AND bbr.status = 'requested'
+RETURNING bbr.id, bbr.status, bbr.responded_at, bbr.created_at, bbr.updated_at, bbr.book_id, bbr.requester_id, bbr.book_borrow_id
+`
+
+type RespondToBookBorrowRequestParams struct {
+	Status string
+	ID     uuid.UUID
+	UserID uuid.UUID
+}
+
+func (q *Queries) RespondToBookBorrowRequest(ctx context.Context, arg RespondToBookBorrowRequestParams) (BookBorrowRequest, error) {
+	row := q.db.QueryRowContext(ctx, respondToBookBorrowRequest, arg.Status, arg.ID, arg.UserID)
+	var i BookBorrowRequest
+	err := row.Scan(
+		&i.ID,
+		&i.Status,
+		&i.RespondedAt,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.BookID,
+		&i.RequesterID,
+		&i.BookBorrowID,
+	)
+	return i, err
+}
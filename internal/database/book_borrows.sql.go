@@ -7,16 +7,86 @@ package database
 
 import (
 	"context"
+	"database/sql"
+	"time"
 
 	"github.com/google/uuid"
 )
 
-const getBookBorrow = `-- name: GetBookBorrow :one
-SELECT id, issued_at, returned_at, created_at, updated_at, book_id, borrower_id FROM book_borrows WHERE book_id = $1 AND returned_at IS NULL
+const confirmBookReturn = `-- name: ConfirmBookReturn :one
+UPDATE book_borrows AS bb
+SET return_status = 'confirmed', return_confirmed_at = NOW(), updated_at = NOW()
+FROM books AS b
+WHERE bb.id = $1 AND b.id = bb.book_id AND b.user_id = $2 AND bb.return_status IN ('pending', 'disputed')
+RETURNING bb.id, bb.issued_at, bb.returned_at, bb.created_at, bb.updated_at, bb.book_id, bb.borrower_id, bb.due_at, bb.return_status, bb.return_confirmed_at, bb.return_disputed_at, bb.book_copy_id, bb.lost_at
 `
 
-func (q *Queries) GetBookBorrow(ctx context.Context, bookID uuid.UUID) (BookBorrow, error) {
-	row := q.db.QueryRowContext(ctx, getBookBorrow, bookID)
+type ConfirmBookReturnParams struct {
+	ID     uuid.UUID
+	UserID uuid.UUID
+}
+
+func (q *Queries) ConfirmBookReturn(ctx context.Context, arg ConfirmBookReturnParams) (BookBorrow, error) {
+	row := q.db.QueryRowContext(ctx, confirmBookReturn, arg.ID, arg.UserID)
+	var i BookBorrow
+	err := row.Scan(
+		&i.ID,
+		&i.IssuedAt,
+		&i.ReturnedAt,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.BookID,
+		&i.BorrowerID,
+		&i.DueAt,
+		&i.ReturnStatus,
+		&i.ReturnConfirmedAt,
+		&i.ReturnDisputedAt,
+		&i.BookCopyID,
+		&i.LostAt,
+	)
+	return i, err
+}
+
+const disputeBookReturn = `-- name: DisputeBookReturn :one
+UPDATE book_borrows AS bb
+SET return_status = 'disputed', return_disputed_at = NOW(), updated_at = NOW()
+FROM books AS b
+WHERE bb.id = $1 AND b.id = bb.book_id AND b.user_id = $2 AND bb.return_status = 'pending'
+RETURNING bb.id, bb.issued_at, bb.returned_at, bb.created_at, bb.updated_at, bb.book_id, bb.borrower_id, bb.due_at, bb.return_status, bb.return_confirmed_at, bb.return_disputed_at, bb.book_copy_id, bb.lost_at
+`
+
+type DisputeBookReturnParams struct {
+	ID     uuid.UUID
+	UserID uuid.UUID
+}
+
+func (q *Queries) DisputeBookReturn(ctx context.Context, arg DisputeBookReturnParams) (BookBorrow, error) {
+	row := q.db.QueryRowContext(ctx, disputeBookReturn, arg.ID, arg.UserID)
+	var i BookBorrow
+	err := row.Scan(
+		&i.ID,
+		&i.IssuedAt,
+		&i.ReturnedAt,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.BookID,
+		&i.BorrowerID,
+		&i.DueAt,
+		&i.ReturnStatus,
+		&i.ReturnConfirmedAt,
+		&i.ReturnDisputedAt,
+		&i.BookCopyID,
+		&i.LostAt,
+	)
+	return i, err
+}
+
+const getBookBorrowByID = `-- name: GetBookBorrowByID :one
+SELECT id, issued_at, returned_at, created_at, updated_at, book_id, borrower_id, due_at, return_status, return_confirmed_at, return_disputed_at, book_copy_id, lost_at FROM book_borrows WHERE id = $1
+`
+
+func (q *Queries) GetBookBorrowByID(ctx context.Context, id uuid.UUID) (BookBorrow, error) {
+	row := q.db.QueryRowContext(ctx, getBookBorrowByID, id)
 	var i BookBorrow
 	err := row.Scan(
 		&i.ID,
@@ -26,24 +96,210 @@ func (q *Queries) GetBookBorrow(ctx context.Context, bookID uuid.UUID) (BookBorr
 		&i.UpdatedAt,
 		&i.BookID,
 		&i.BorrowerID,
+		&i.DueAt,
+		&i.ReturnStatus,
+		&i.ReturnConfirmedAt,
+		&i.ReturnDisputedAt,
+		&i.BookCopyID,
+		&i.LostAt,
 	)
 	return i, err
 }
 
+const getBorrowedBooks = `-- name: GetBorrowedBooks :many
+SELECT bb.id, bb.issued_at, bb.returned_at, bb.created_at, bb.updated_at, bb.book_id, bb.borrower_id, bb.due_at, bb.return_status, bb.return_confirmed_at, bb.return_disputed_at, bb.book_copy_id, bb.lost_at, b.title AS book_title, u.first_name AS lender_first_name, u.last_name AS lender_last_name
+FROM book_borrows AS bb
+INNER JOIN books AS b ON b.id = bb.book_id
+INNER JOIN users AS u ON u.id = b.user_id
+WHERE bb.borrower_id = $1 AND (
+	$2::text IS NULL
+	OR ($2::text = 'active' AND bb.return_confirmed_at IS NULL)
+	OR ($2::text = 'returned' AND bb.return_confirmed_at IS NOT NULL)
+	OR ($2::text = 'overdue' AND bb.return_confirmed_at IS NULL AND (bb.returned_at IS NULL OR bb.return_status = 'disputed') AND bb.due_at < NOW())
+	OR ($2::text IN ('pending', 'disputed') AND bb.return_status = $2::text)
+)
+AND ($3::uuid IS NULL OR (bb.issued_at, bb.id) < ($4::timestamp, $3::uuid))
+ORDER BY bb.issued_at DESC, bb.id DESC
+LIMIT $5::int
+`
+
+type GetBorrowedBooksParams struct {
+	BorrowerID     uuid.UUID
+	Status         sql.NullString
+	CursorID       uuid.NullUUID
+	CursorIssuedAt time.Time
+	PageLimit      int32
+}
+
+type GetBorrowedBooksRow struct {
+	BookBorrow      BookBorrow
+	BookTitle       string
+	LenderFirstName string
+	LenderLastName  string
+}
+
+func (q *Queries) GetBorrowedBooks(ctx context.Context, arg GetBorrowedBooksParams) ([]GetBorrowedBooksRow, error) {
+	rows, err := q.db.QueryContext(ctx, getBorrowedBooks,
+		arg.BorrowerID,
+		arg.Status,
+		arg.CursorID,
+		arg.CursorIssuedAt,
+		arg.PageLimit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetBorrowedBooksRow
+	for rows.Next() {
+		var i GetBorrowedBooksRow
+		if err := rows.Scan(
+			&i.BookBorrow.ID,
+			&i.BookBorrow.IssuedAt,
+			&i.BookBorrow.ReturnedAt,
+			&i.BookBorrow.CreatedAt,
+			&i.BookBorrow.UpdatedAt,
+			&i.BookBorrow.BookID,
+			&i.BookBorrow.BorrowerID,
+			&i.BookBorrow.DueAt,
+			&i.BookBorrow.ReturnStatus,
+			&i.BookBorrow.ReturnConfirmedAt,
+			&i.BookBorrow.ReturnDisputedAt,
+			&i.BookBorrow.BookCopyID,
+			&i.BookBorrow.LostAt,
+			&i.BookTitle,
+			&i.LenderFirstName,
+			&i.LenderLastName,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getLentBooks = `-- name: GetLentBooks :many
+SELECT bb.id, bb.issued_at, bb.returned_at, bb.created_at, bb.updated_at, bb.book_id, bb.borrower_id, bb.due_at, bb.return_status, bb.return_confirmed_at, bb.return_disputed_at, bb.book_copy_id, bb.lost_at, b.title AS book_title, u.first_name AS borrower_first_name, u.last_name AS borrower_last_name
+FROM book_borrows AS bb
+INNER JOIN books AS b ON b.id = bb.book_id
+INNER JOIN users AS u ON u.id = bb.borrower_id
+WHERE b.user_id = $1 AND (
+	$2::text IS NULL
+	OR ($2::text = 'active' AND bb.return_confirmed_at IS NULL)
+	OR ($2::text = 'returned' AND bb.return_confirmed_at IS NOT NULL)
+	OR ($2::text = 'overdue' AND bb.return_confirmed_at IS NULL AND (bb.returned_at IS NULL OR bb.return_status = 'disputed') AND bb.due_at < NOW())
+	OR ($2::text IN ('pending', 'disputed') AND bb.return_status = $2::text)
+)
+AND ($3::uuid IS NULL OR (bb.issued_at, bb.id) < ($4::timestamp, $3::uuid))
+ORDER BY bb.issued_at DESC, bb.id DESC
+LIMIT $5::int
+`
+
+type GetLentBooksParams struct {
+	UserID         uuid.UUID
+	Status         sql.NullString
+	CursorID       uuid.NullUUID
+	CursorIssuedAt time.Time
+	PageLimit      int32
+}
+
+type GetLentBooksRow struct {
+	BookBorrow        BookBorrow
+	BookTitle         string
+	BorrowerFirstName string
+	BorrowerLastName  string
+}
+
+func (q *Queries) GetLentBooks(ctx context.Context, arg GetLentBooksParams) ([]GetLentBooksRow, error) {
+	rows, err := q.db.QueryContext(ctx, getLentBooks,
+		arg.UserID,
+		arg.Status,
+		arg.CursorID,
+		arg.CursorIssuedAt,
+		arg.PageLimit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetLentBooksRow
+	for rows.Next() {
+		var i GetLentBooksRow
+		if err := rows.Scan(
+			&i.BookBorrow.ID,
+			&i.BookBorrow.IssuedAt,
+			&i.BookBorrow.ReturnedAt,
+			&i.BookBorrow.CreatedAt,
+			&i.BookBorrow.UpdatedAt,
+			&i.BookBorrow.BookID,
+			&i.BookBorrow.BorrowerID,
+			&i.BookBorrow.DueAt,
+			&i.BookBorrow.ReturnStatus,
+			&i.BookBorrow.ReturnConfirmedAt,
+			&i.BookBorrow.ReturnDisputedAt,
+			&i.BookBorrow.BookCopyID,
+			&i.BookBorrow.LostAt,
+			&i.BookTitle,
+			&i.BorrowerFirstName,
+			&i.BorrowerLastName,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const issueBook = `-- name: IssueBook :one
-INSERT INTO book_borrows (id, issued_at, created_at, updated_at, book_id, borrower_id)
-VALUES ($1, NOW(), NOW(), NOW(), $2, $3)
-RETURNING id, issued_at, returned_at, created_at, updated_at, book_id, borrower_id
+INSERT INTO book_borrows (id, issued_at, created_at, updated_at, book_id, borrower_id, due_at, book_copy_id)
+SELECT $1, NOW(), NOW(), NOW(), bc.book_id, $2, $3, bc.id
+FROM book_copies AS bc
+WHERE bc.book_id = $4 AND bc.condition <> 'lost' AND bc.retired_at IS NULL
+AND NOT EXISTS (SELECT 1 FROM book_borrows AS bb WHERE bb.book_copy_id = bc.id AND bb.return_confirmed_at IS NULL)
+AND (
+    SELECT COUNT(*) FROM book_copies AS free
+    WHERE free.book_id = $4 AND free.condition <> 'lost' AND free.retired_at IS NULL
+    AND NOT EXISTS (SELECT 1 FROM book_borrows AS bb WHERE bb.book_copy_id = free.id AND bb.return_confirmed_at IS NULL)
+) > (
+    SELECT COUNT(*) FROM book_reservations AS br
+    WHERE br.book_id = $4 AND br.status = 'offered' AND br.offer_expires_at > NOW()
+    AND br.user_id <> $2
+)
+ORDER BY bc.created_at, bc.id
+LIMIT 1
+FOR UPDATE SKIP LOCKED
+RETURNING id, issued_at, returned_at, created_at, updated_at, book_id, borrower_id, due_at, return_status, return_confirmed_at, return_disputed_at, book_copy_id, lost_at
 `
 
 type IssueBookParams struct {
 	ID         uuid.UUID
-	BookID     uuid.UUID
 	BorrowerID uuid.UUID
+	DueAt      time.Time
+	BookID     uuid.UUID
 }
 
+// Lends out the oldest copy that is not with another borrower or written off. Copies
+// offered to someone else in the waitlist stay on the shelf for them. Returns no rows
+// when every copy is out or held.
 func (q *Queries) IssueBook(ctx context.Context, arg IssueBookParams) (BookBorrow, error) {
-	row := q.db.QueryRowContext(ctx, issueBook, arg.ID, arg.BookID, arg.BorrowerID)
+	row := q.db.QueryRowContext(ctx, issueBook,
+		arg.ID,
+		arg.BorrowerID,
+		arg.DueAt,
+		arg.BookID,
+	)
 	var i BookBorrow
 	err := row.Scan(
 		&i.ID,
@@ -53,15 +309,32 @@ func (q *Queries) IssueBook(ctx context.Context, arg IssueBookParams) (BookBorro
 		&i.UpdatedAt,
 		&i.BookID,
 		&i.BorrowerID,
+		&i.DueAt,
+		&i.ReturnStatus,
+		&i.ReturnConfirmedAt,
+		&i.ReturnDisputedAt,
+		&i.BookCopyID,
+		&i.LostAt,
 	)
 	return i, err
 }
 
+const lockBookForIssue = `-- name: LockBookForIssue :exec
+SELECT id FROM books WHERE id = $1 FOR NO KEY UPDATE
+`
+
+// Issues of the same book wait on each other, so the free copies counted for one can't
+// be taken by another.
+func (q *Queries) LockBookForIssue(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, lockBookForIssue, id)
+	return err
+}
+
 const returnBook = `-- name: ReturnBook :one
 UPDATE book_borrows 
-SET returned_at = NOW(), updated_at = NOW()
-WHERE id = $1 AND borrower_id = $2 AND returned_at IS NULL
-RETURNING id, issued_at, returned_at, created_at, updated_at, book_id, borrower_id
+SET returned_at = NOW(), return_status = 'pending', updated_at = NOW()
+WHERE id = $1 AND borrower_id = $2 AND (returned_at IS NULL OR return_status = 'disputed')
+RETURNING id, issued_at, returned_at, created_at, updated_at, book_id, borrower_id, due_at, return_status, return_confirmed_at, return_disputed_at, book_copy_id, lost_at
 `
 
 type ReturnBookParams struct {
@@ -80,6 +353,12 @@ func (q *Queries) ReturnBook(ctx context.Context, arg ReturnBookParams) (BookBor
 		&i.UpdatedAt,
 		&i.BookID,
 		&i.BorrowerID,
+		&i.DueAt,
+		&i.ReturnStatus,
+		&i.ReturnConfirmedAt,
+		&i.ReturnDisputedAt,
+		&i.BookCopyID,
+		&i.LostAt,
 	)
 	return i, err
 }
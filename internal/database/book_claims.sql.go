@@ -0,0 +1,446 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: book_claims.sql
+
+package database
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/google/uuid"
+)
+
+const clearBookBorrowLost = `-- name: ClearBookBorrowLost :exec
+UPDATE book_borrows SET lost_at = NULL, updated_at = NOW() WHERE id = $1
+`
+
+// The book turned up after all.
+func (q *Queries) ClearBookBorrowLost(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, clearBookBorrowLost, id)
+	return err
+}
+
+const closeLostBookBorrow = `-- name: CloseLostBookBorrow :execrows
+UPDATE book_borrows
+SET return_status = 'lost', return_confirmed_at = NOW(), updated_at = NOW()
+WHERE id = $1 AND lost_at IS NOT NULL AND return_confirmed_at IS NULL
+`
+
+// Closes a lost loan for good. The copy is written off separately.
+func (q *Queries) CloseLostBookBorrow(ctx context.Context, id uuid.UUID) (int64, error) {
+	result, err := q.db.ExecContext(ctx, closeLostBookBorrow, id)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const createBookClaim = `-- name: CreateBookClaim :one
+INSERT INTO book_claims (id, kind, status, description, created_at, updated_at, book_borrow_id, opened_by)
+VALUES ($1, $2, 'open', $3, NOW(), NOW(), $4, $5)
+ON CONFLICT (book_borrow_id) WHERE status = 'open' DO NOTHING
+RETURNING id, kind, status, description, resolution, resolution_note, resolved_at, created_at, updated_at, book_borrow_id, opened_by
+`
+
+type CreateBookClaimParams struct {
+	ID           uuid.UUID
+	Kind         string
+	Description  string
+	BookBorrowID uuid.UUID
+	OpenedBy     uuid.UUID
+}
+
+// A loan has at most one open claim, a second one returns no rows.
+func (q *Queries) CreateBookClaim(ctx context.Context, arg CreateBookClaimParams) (BookClaim, error) {
+	row := q.db.QueryRowContext(ctx, createBookClaim,
+		arg.ID,
+		arg.Kind,
+		arg.Description,
+		arg.BookBorrowID,
+		arg.OpenedBy,
+	)
+	var i BookClaim
+	err := row.Scan(
+		&i.ID,
+		&i.Kind,
+		&i.Status,
+		&i.Description,
+		&i.Resolution,
+		&i.ResolutionNote,
+		&i.ResolvedAt,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.BookBorrowID,
+		&i.OpenedBy,
+	)
+	return i, err
+}
+
+const createBookClaimComment = `-- name: CreateBookClaimComment :one
+INSERT INTO book_claim_comments (id, body, created_at, book_claim_id, author_id)
+SELECT $1, $2, NOW(), bc.id, $3
+FROM book_claims AS bc
+WHERE bc.id = $4 AND bc.status = 'open'
+RETURNING id, body, created_at, book_claim_id, author_id
+`
+
+type CreateBookClaimCommentParams struct {
+	ID          uuid.UUID
+	Body        string
+	AuthorID    uuid.UUID
+	BookClaimID uuid.UUID
+}
+
+// Comments are only taken while the claim is open.
+func (q *Queries) CreateBookClaimComment(ctx context.Context, arg CreateBookClaimCommentParams) (BookClaimComment, error) {
+	row := q.db.QueryRowContext(ctx, createBookClaimComment,
+		arg.ID,
+		arg.Body,
+		arg.AuthorID,
+		arg.BookClaimID,
+	)
+	var i BookClaimComment
+	err := row.Scan(
+		&i.ID,
+		&i.Body,
+		&i.CreatedAt,
+		&i.BookClaimID,
+		&i.AuthorID,
+	)
+	return i, err
+}
+
+const createBookConditionReport = `-- name: CreateBookConditionReport :one
+INSERT INTO book_condition_reports (id, stage, condition, notes, photo_url, photo_key, created_at, book_borrow_id, reporter_id)
+VALUES ($1, $2, $3, $4, $5, $6, NOW(), $7, $8)
+ON CONFLICT (book_borrow_id, stage, reporter_id) DO NOTHING
+RETURNING id, stage, condition, notes, photo_url, photo_key, created_at, book_borrow_id, reporter_id
+`
+
+type CreateBookConditionReportParams struct {
+	ID           uuid.UUID
+	Stage        string
+	Condition    string
+	Notes        string
+	PhotoUrl     string
+	PhotoKey     string
+	BookBorrowID uuid.UUID
+	ReporterID   uuid.UUID
+}
+
+func (q *Queries) CreateBookConditionReport(ctx context.Context, arg CreateBookConditionReportParams) (BookConditionReport, error) {
+	row := q.db.QueryRowContext(ctx, createBookConditionReport,
+		arg.ID,
+		arg.Stage,
+		arg.Condition,
+		arg.Notes,
+		arg.PhotoUrl,
+		arg.PhotoKey,
+		arg.BookBorrowID,
+		arg.ReporterID,
+	)
+	var i BookConditionReport
+	err := row.Scan(
+		&i.ID,
+		&i.Stage,
+		&i.Condition,
+		&i.Notes,
+		&i.PhotoUrl,
+		&i.PhotoKey,
+		&i.CreatedAt,
+		&i.BookBorrowID,
+		&i.ReporterID,
+	)
+	return i, err
+}
+
+const getBookBorrowWithOwner = `-- name: GetBookBorrowWithOwner :one
+SELECT bb.id, bb.issued_at, bb.returned_at, bb.created_at, bb.updated_at, bb.book_id, bb.borrower_id, bb.due_at, bb.return_status, bb.return_confirmed_at, bb.return_disputed_at, bb.book_copy_id, bb.lost_at, b.user_id AS owner_id, b.title AS book_title
+FROM book_borrows AS bb
+INNER JOIN books AS b ON b.id = bb.book_id
+WHERE bb.id = $1
+`
+
+type GetBookBorrowWithOwnerRow struct {
+	BookBorrow BookBorrow
+	OwnerID    uuid.UUID
+	BookTitle  string
+}
+
+// A loan with the book owner, whoever is on either side can report on it.
+func (q *Queries) GetBookBorrowWithOwner(ctx context.Context, id uuid.UUID) (GetBookBorrowWithOwnerRow, error) {
+	row := q.db.QueryRowContext(ctx, getBookBorrowWithOwner, id)
+	var i GetBookBorrowWithOwnerRow
+	err := row.Scan(
+		&i.BookBorrow.ID,
+		&i.BookBorrow.IssuedAt,
+		&i.BookBorrow.ReturnedAt,
+		&i.BookBorrow.CreatedAt,
+		&i.BookBorrow.UpdatedAt,
+		&i.BookBorrow.BookID,
+		&i.BookBorrow.BorrowerID,
+		&i.BookBorrow.DueAt,
+		&i.BookBorrow.ReturnStatus,
+		&i.BookBorrow.ReturnConfirmedAt,
+		&i.BookBorrow.ReturnDisputedAt,
+		&i.BookBorrow.BookCopyID,
+		&i.BookBorrow.LostAt,
+		&i.OwnerID,
+		&i.BookTitle,
+	)
+	return i, err
+}
+
+const getBookClaim = `-- name: GetBookClaim :one
+SELECT bc.id, bc.kind, bc.status, bc.description, bc.resolution, bc.resolution_note, bc.resolved_at, bc.created_at, bc.updated_at, bc.book_borrow_id, bc.opened_by, bb.borrower_id, bb.book_copy_id, b.user_id AS owner_id, b.title AS book_title
+FROM book_claims AS bc
+INNER JOIN book_borrows AS bb ON bb.id = bc.book_borrow_id
+INNER JOIN books AS b ON b.id = bb.book_id
+WHERE bc.id = $1
+`
+
+type GetBookClaimRow struct {
+	BookClaim  BookClaim
+	BorrowerID uuid.UUID
+	BookCopyID uuid.UUID
+	OwnerID    uuid.UUID
+	BookTitle  string
+}
+
+func (q *Queries) GetBookClaim(ctx context.Context, id uuid.UUID) (GetBookClaimRow, error) {
+	row := q.db.QueryRowContext(ctx, getBookClaim, id)
+	var i GetBookClaimRow
+	err := row.Scan(
+		&i.BookClaim.ID,
+		&i.BookClaim.Kind,
+		&i.BookClaim.Status,
+		&i.BookClaim.Description,
+		&i.BookClaim.Resolution,
+		&i.BookClaim.ResolutionNote,
+		&i.BookClaim.ResolvedAt,
+		&i.BookClaim.CreatedAt,
+		&i.BookClaim.UpdatedAt,
+		&i.BookClaim.BookBorrowID,
+		&i.BookClaim.OpenedBy,
+		&i.BorrowerID,
+		&i.BookCopyID,
+		&i.OwnerID,
+		&i.BookTitle,
+	)
+	return i, err
+}
+
+const getBookClaimComments = `-- name: GetBookClaimComments :many
+SELECT bcc.id, bcc.body, bcc.created_at, bcc.book_claim_id, bcc.author_id, u.first_name AS author_first_name, u.last_name AS author_last_name
+FROM book_claim_comments AS bcc
+INNER JOIN users AS u ON u.id = bcc.author_id
+WHERE bcc.book_claim_id = $1
+ORDER BY bcc.created_at, bcc.id
+`
+
+type GetBookClaimCommentsRow struct {
+	BookClaimComment BookClaimComment
+	AuthorFirstName  string
+	AuthorLastName   string
+}
+
+func (q *Queries) GetBookClaimComments(ctx context.Context, bookClaimID uuid.UUID) ([]GetBookClaimCommentsRow, error) {
+	rows, err := q.db.QueryContext(ctx, getBookClaimComments, bookClaimID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetBookClaimCommentsRow
+	for rows.Next() {
+		var i GetBookClaimCommentsRow
+		if err := rows.Scan(
+			&i.BookClaimComment.ID,
+			&i.BookClaimComment.Body,
+			&i.BookClaimComment.CreatedAt,
+			&i.BookClaimComment.BookClaimID,
+			&i.BookClaimComment.AuthorID,
+			&i.AuthorFirstName,
+			&i.AuthorLastName,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getBookConditionReports = `-- name: GetBookConditionReports :many
+SELECT id, stage, condition, notes, photo_url, photo_key, created_at, book_borrow_id, reporter_id FROM book_condition_reports WHERE book_borrow_id = $1 ORDER BY created_at, id
+`
+
+func (q *Queries) GetBookConditionReports(ctx context.Context, bookBorrowID uuid.UUID) ([]BookConditionReport, error) {
+	rows, err := q.db.QueryContext(ctx, getBookConditionReports, bookBorrowID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []BookConditionReport
+	for rows.Next() {
+		var i BookConditionReport
+		if err := rows.Scan(
+			&i.ID,
+			&i.Stage,
+			&i.Condition,
+			&i.Notes,
+			&i.PhotoUrl,
+			&i.PhotoKey,
+			&i.CreatedAt,
+			&i.BookBorrowID,
+			&i.ReporterID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getUserBookClaims = `-- name: GetUserBookClaims :many
+SELECT bc.id, bc.kind, bc.status, bc.description, bc.resolution, bc.resolution_note, bc.resolved_at, bc.created_at, bc.updated_at, bc.book_borrow_id, bc.opened_by, bb.borrower_id, b.user_id AS owner_id, b.title AS book_title
+FROM book_claims AS bc
+INNER JOIN book_borrows AS bb ON bb.id = bc.book_borrow_id
+INNER JOIN books AS b ON b.id = bb.book_id
+WHERE (bb.borrower_id = $1 OR b.user_id = $1)
+AND ($2::text IS NULL OR bc.status = $2::text)
+ORDER BY bc.created_at DESC, bc.id
+`
+
+type GetUserBookClaimsParams struct {
+	UserID uuid.UUID
+	Status sql.NullString
+}
+
+type GetUserBookClaimsRow struct {
+	BookClaim  BookClaim
+	BorrowerID uuid.UUID
+	OwnerID    uuid.UUID
+	BookTitle  string
+}
+
+// Claims on the member's loans, from either side.
+func (q *Queries) GetUserBookClaims(ctx context.Context, arg GetUserBookClaimsParams) ([]GetUserBookClaimsRow, error) {
+	rows, err := q.db.QueryContext(ctx, getUserBookClaims, arg.UserID, arg.Status)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetUserBookClaimsRow
+	for rows.Next() {
+		var i GetUserBookClaimsRow
+		if err := rows.Scan(
+			&i.BookClaim.ID,
+			&i.BookClaim.Kind,
+			&i.BookClaim.Status,
+			&i.BookClaim.Description,
+			&i.BookClaim.Resolution,
+			&i.BookClaim.ResolutionNote,
+			&i.BookClaim.ResolvedAt,
+			&i.BookClaim.CreatedAt,
+			&i.BookClaim.UpdatedAt,
+			&i.BookClaim.BookBorrowID,
+			&i.BookClaim.OpenedBy,
+			&i.BorrowerID,
+			&i.OwnerID,
+			&i.BookTitle,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const markBookCopyLost = `-- name: MarkBookCopyLost :exec
+UPDATE book_copies SET condition = 'lost', updated_at = NOW() WHERE id = $1
+`
+
+func (q *Queries) MarkBookCopyLost(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, markBookCopyLost, id)
+	return err
+}
+
+const reportBookBorrowLost = `-- name: ReportBookBorrowLost :one
+UPDATE book_borrows
+SET lost_at = NOW(), updated_at = NOW()
+WHERE id = $1 AND lost_at IS NULL AND return_confirmed_at IS NULL AND (returned_at IS NULL OR return_status = 'disputed')
+RETURNING id, issued_at, returned_at, created_at, updated_at, book_id, borrower_id, due_at, return_status, return_confirmed_at, return_disputed_at, book_copy_id, lost_at
+`
+
+// Only loans still out, or whose return the owner disputes, can go missing.
+func (q *Queries) ReportBookBorrowLost(ctx context.Context, id uuid.UUID) (BookBorrow, error) {
+	row := q.db.QueryRowContext(ctx, reportBookBorrowLost, id)
+	var i BookBorrow
+	err := row.Scan(
+		&i.ID,
+		&i.IssuedAt,
+		&i.ReturnedAt,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.BookID,
+		&i.BorrowerID,
+		&i.DueAt,
+		&i.ReturnStatus,
+		&i.ReturnConfirmedAt,
+		&i.ReturnDisputedAt,
+		&i.BookCopyID,
+		&i.LostAt,
+	)
+	return i, err
+}
+
+const resolveBookClaim = `-- name: ResolveBookClaim :one
+UPDATE book_claims
+SET status = 'resolved', resolution = $1, resolution_note = $2, resolved_at = NOW(), updated_at = NOW()
+WHERE id = $3 AND status = 'open'
+RETURNING id, kind, status, description, resolution, resolution_note, resolved_at, created_at, updated_at, book_borrow_id, opened_by
+`
+
+type ResolveBookClaimParams struct {
+	Resolution     sql.NullString
+	ResolutionNote string
+	ID             uuid.UUID
+}
+
+func (q *Queries) ResolveBookClaim(ctx context.Context, arg ResolveBookClaimParams) (BookClaim, error) {
+	row := q.db.QueryRowContext(ctx, resolveBookClaim, arg.Resolution, arg.ResolutionNote, arg.ID)
+	var i BookClaim
+	err := row.Scan(
+		&i.ID,
+		&i.Kind,
+		&i.Status,
+		&i.Description,
+		&i.Resolution,
+		&i.ResolutionNote,
+		&i.ResolvedAt,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.BookBorrowID,
+		&i.OpenedBy,
+	)
+	return i, err
+}
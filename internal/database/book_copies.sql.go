@@ -0,0 +1,171 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: book_copies.sql
+
+package database
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/google/uuid"
+)
+
+const countAvailableBookCopies = `-- name: CountAvailableBookCopies :one
+SELECT COUNT(*) FROM book_copies AS bc
+WHERE bc.book_id = $1 AND bc.condition <> 'lost' AND bc.retired_at IS NULL
+AND NOT EXISTS (SELECT 1 FROM book_borrows AS bb WHERE bb.book_copy_id = bc.id AND bb.return_confirmed_at IS NULL)
+`
+
+func (q *Queries) CountAvailableBookCopies(ctx context.Context, bookID uuid.UUID) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countAvailableBookCopies, bookID)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const createBookCopy = `-- name: CreateBookCopy :one
+INSERT INTO book_copies (id, condition, created_at, updated_at, book_id)
+VALUES ($1, $2, NOW(), NOW(), $3)
+RETURNING id, condition, created_at, updated_at, book_id, retired_at
+`
+
+type CreateBookCopyParams struct {
+	ID        uuid.UUID
+	Condition string
+	BookID    uuid.UUID
+}
+
+func (q *Queries) CreateBookCopy(ctx context.Context, arg CreateBookCopyParams) (BookCopy, error) {
+	row := q.db.QueryRowContext(ctx, createBookCopy, arg.ID, arg.Condition, arg.BookID)
+	var i BookCopy
+	err := row.Scan(
+		&i.ID,
+		&i.Condition,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.BookID,
+		&i.RetiredAt,
+	)
+	return i, err
+}
+
+const deleteBookCopy = `-- name: DeleteBookCopy :execrows
+DELETE FROM book_copies AS bc
+USING books AS b
+WHERE bc.id = $1 AND b.id = bc.book_id AND b.user_id = $2
+AND NOT EXISTS (SELECT 1 FROM book_borrows AS bb WHERE bb.book_copy_id = bc.id)
+AND EXISTS (SELECT 1 FROM book_copies AS other WHERE other.book_id = bc.book_id AND other.id <> bc.id AND other.retired_at IS NULL)
+`
+
+type DeleteBookCopyParams struct {
+	ID     uuid.UUID
+	UserID uuid.UUID
+}
+
+// Only copies that were never lent out can go for good, see RetireBookCopy for the rest.
+func (q *Queries) DeleteBookCopy(ctx context.Context, arg DeleteBookCopyParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteBookCopy, arg.ID, arg.UserID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const getBookCopies = `-- name: GetBookCopies :many
+SELECT bc.id, bc.condition, bc.created_at, bc.updated_at, bc.book_id, bc.retired_at, bb.id AS book_borrow_id, bb.due_at
+FROM book_copies AS bc
+LEFT JOIN book_borrows AS bb ON bb.book_copy_id = bc.id AND bb.return_confirmed_at IS NULL
+WHERE bc.book_id = $1 AND bc.retired_at IS NULL
+ORDER BY bc.created_at, bc.id
+`
+
+type GetBookCopiesRow struct {
+	BookCopy     BookCopy
+	BookBorrowID uuid.NullUUID
+	DueAt        sql.NullTime
+}
+
+func (q *Queries) GetBookCopies(ctx context.Context, bookID uuid.UUID) ([]GetBookCopiesRow, error) {
+	rows, err := q.db.QueryContext(ctx, getBookCopies, bookID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetBookCopiesRow
+	for rows.Next() {
+		var i GetBookCopiesRow
+		if err := rows.Scan(
+			&i.BookCopy.ID,
+			&i.BookCopy.Condition,
+			&i.BookCopy.CreatedAt,
+			&i.BookCopy.UpdatedAt,
+			&i.BookCopy.BookID,
+			&i.BookCopy.RetiredAt,
+			&i.BookBorrowID,
+			&i.DueAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const retireBookCopy = `-- name: RetireBookCopy :execrows
+UPDATE book_copies AS bc
+SET retired_at = NOW(), updated_at = NOW()
+FROM books AS b
+WHERE bc.id = $1 AND b.id = bc.book_id AND b.user_id = $2 AND bc.retired_at IS NULL
+AND NOT EXISTS (SELECT 1 FROM book_borrows AS bb WHERE bb.book_copy_id = bc.id AND bb.return_confirmed_at IS NULL)
+AND EXISTS (SELECT 1 FROM book_copies AS other WHERE other.book_id = bc.book_id AND other.id <> bc.id AND other.retired_at IS NULL)
+`
+
+type RetireBookCopyParams struct {
+	ID     uuid.UUID
+	UserID uuid.UUID
+}
+
+// Takes a copy with loan history off the shelf and keeps the history.
+func (q *Queries) RetireBookCopy(ctx context.Context, arg RetireBookCopyParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, retireBookCopy, arg.ID, arg.UserID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const updateBookCopy = `-- name: UpdateBookCopy :one
+UPDATE book_copies AS bc
+SET condition = $1, updated_at = NOW()
+FROM books AS b
+WHERE bc.id = $2 AND b.id = bc.book_id AND b.user_id = $3 AND bc.retired_at IS NULL
+RETURNING bc.id, bc.condition, bc.created_at, bc.updated_at, bc.book_id, bc.retired_at
+`
+
+type UpdateBookCopyParams struct {
+	Condition string
+	ID        uuid.UUID
+	UserID    uuid.UUID
+}
+
+func (q *Queries) UpdateBookCopy(ctx context.Context, arg UpdateBookCopyParams) (BookCopy, error) {
+	row := q.db.QueryRowContext(ctx, updateBookCopy, arg.Condition, arg.ID, arg.UserID)
+	var i BookCopy
+	err := row.Scan(
+		&i.ID,
+		&i.Condition,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.BookID,
+		&i.RetiredAt,
+	)
+	return i, err
+}
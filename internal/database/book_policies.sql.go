@@ -0,0 +1,145 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: book_policies.sql
+
+package database
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const createApprovedBorrower = `-- name: CreateApprovedBorrower :one
+INSERT INTO approved_borrowers (user_id, borrower_id, created_at)
+VALUES ($1, $2, NOW())
+RETURNING user_id, borrower_id, created_at
+`
+
+type CreateApprovedBorrowerParams struct {
+	UserID     uuid.UUID
+	BorrowerID uuid.UUID
+}
+
+func (q *Queries) CreateApprovedBorrower(ctx context.Context, arg CreateApprovedBorrowerParams) (ApprovedBorrower, error) {
+	row := q.db.QueryRowContext(ctx, createApprovedBorrower, arg.UserID, arg.BorrowerID)
+	var i ApprovedBorrower
+	err := row.Scan(&i.UserID, &i.BorrowerID, &i.CreatedAt)
+	return i, err
+}
+
+const deleteApprovedBorrower = `-- name: DeleteApprovedBorrower :execrows
+DELETE FROM approved_borrowers WHERE user_id = $1 AND borrower_id = $2
+`
+
+type DeleteApprovedBorrowerParams struct {
+	UserID     uuid.UUID
+	BorrowerID uuid.UUID
+}
+
+func (q *Queries) DeleteApprovedBorrower(ctx context.Context, arg DeleteApprovedBorrowerParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteApprovedBorrower, arg.UserID, arg.BorrowerID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const getApprovedBorrower = `-- name: GetApprovedBorrower :one
+SELECT user_id, borrower_id, created_at FROM approved_borrowers WHERE user_id = $1 AND borrower_id = $2
+`
+
+type GetApprovedBorrowerParams struct {
+	UserID     uuid.UUID
+	BorrowerID uuid.UUID
+}
+
+func (q *Queries) GetApprovedBorrower(ctx context.Context, arg GetApprovedBorrowerParams) (ApprovedBorrower, error) {
+	row := q.db.QueryRowContext(ctx, getApprovedBorrower, arg.UserID, arg.BorrowerID)
+	var i ApprovedBorrower
+	err := row.Scan(&i.UserID, &i.BorrowerID, &i.CreatedAt)
+	return i, err
+}
+
+const getApprovedBorrowers = `-- name: GetApprovedBorrowers :many
+SELECT approved_borrowers.user_id, approved_borrowers.borrower_id, approved_borrowers.created_at, users.first_name AS borrower_first_name, users.last_name AS borrower_last_name
+FROM approved_borrowers
+INNER JOIN users ON users.id = approved_borrowers.borrower_id
+WHERE approved_borrowers.user_id = $1
+AND ($2::uuid IS NULL OR (approved_borrowers.created_at, approved_borrowers.borrower_id) < ($3::timestamp, $2::uuid))
+ORDER BY approved_borrowers.created_at DESC, approved_borrowers.borrower_id DESC
+LIMIT $4::int
+`
+
+type GetApprovedBorrowersParams struct {
+	UserID          uuid.UUID
+	CursorID        uuid.NullUUID
+	CursorCreatedAt time.Time
+	PageLimit       int32
+}
+
+type GetApprovedBorrowersRow struct {
+	ApprovedBorrower  ApprovedBorrower
+	BorrowerFirstName string
+	BorrowerLastName  string
+}
+
+func (q *Queries) GetApprovedBorrowers(ctx context.Context, arg GetApprovedBorrowersParams) ([]GetApprovedBorrowersRow, error) {
+	rows, err := q.db.QueryContext(ctx, getApprovedBorrowers,
+		arg.UserID,
+		arg.CursorID,
+		arg.CursorCreatedAt,
+		arg.PageLimit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetApprovedBorrowersRow
+	for rows.Next() {
+		var i GetApprovedBorrowersRow
+		if err := rows.Scan(
+			&i.ApprovedBorrower.UserID,
+			&i.ApprovedBorrower.BorrowerID,
+			&i.ApprovedBorrower.CreatedAt,
+			&i.BorrowerFirstName,
+			&i.BorrowerLastName,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getMemberRelationship = `-- name: GetMemberRelationship :one
+SELECT
+    EXISTS (SELECT 1 FROM user_subscribers WHERE user_subscribers.user_id = $1::uuid AND user_subscribers.subscriber_id = $2::uuid)::boolean AS is_subscriber,
+    EXISTS (SELECT 1 FROM approved_borrowers WHERE approved_borrowers.user_id = $1::uuid AND approved_borrowers.borrower_id = $2::uuid)::boolean AS is_approved_borrower
+`
+
+type GetMemberRelationshipParams struct {
+	OwnerID  uuid.UUID
+	MemberID uuid.UUID
+}
+
+type GetMemberRelationshipRow struct {
+	IsSubscriber       bool
+	IsApprovedBorrower bool
+}
+
+// How a member stands with a book owner, for the visibility and lending rules.
+func (q *Queries) GetMemberRelationship(ctx context.Context, arg GetMemberRelationshipParams) (GetMemberRelationshipRow, error) {
+	row := q.db.QueryRowContext(ctx, getMemberRelationship, arg.OwnerID, arg.MemberID)
+	var i GetMemberRelationshipRow
+	err := row.Scan(&i.IsSubscriber, &i.IsApprovedBorrower)
+	return i, err
+}
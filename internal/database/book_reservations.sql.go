@@ -0,0 +1,306 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: book_reservations.sql
+
+package database
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const cancelBookReservation = `-- name: CancelBookReservation :one
+UPDATE book_reservations
+SET status = 'cancelled', updated_at = NOW()
+WHERE id = $1 AND user_id = $2 AND status IN ('waiting', 'offered')
+RETURNING id, status, offered_at, offer_expires_at, created_at, updated_at, book_id, user_id
+`
+
+type CancelBookReservationParams struct {
+	ID     uuid.UUID
+	UserID uuid.UUID
+}
+
+func (q *Queries) CancelBookReservation(ctx context.Context, arg CancelBookReservationParams) (BookReservation, error) {
+	row := q.db.QueryRowContext(ctx, cancelBookReservation, arg.ID, arg.UserID)
+	var i BookReservation
+	err := row.Scan(
+		&i.ID,
+		&i.Status,
+		&i.OfferedAt,
+		&i.OfferExpiresAt,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.BookID,
+		&i.UserID,
+	)
+	return i, err
+}
+
+const cancelBookReservations = `-- name: CancelBookReservations :exec
+UPDATE book_reservations
+SET status = 'cancelled', updated_at = NOW()
+WHERE book_id = $1 AND status IN ('waiting', 'offered')
+`
+
+// Nobody waits for a book in the trash.
+func (q *Queries) CancelBookReservations(ctx context.Context, bookID uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, cancelBookReservations, bookID)
+	return err
+}
+
+const countActiveBookReservations = `-- name: CountActiveBookReservations :one
+SELECT COUNT(*) FROM book_reservations WHERE book_id = $1 AND status IN ('waiting', 'offered') AND (offer_expires_at IS NULL OR offer_expires_at > NOW())
+`
+
+func (q *Queries) CountActiveBookReservations(ctx context.Context, bookID uuid.UUID) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countActiveBookReservations, bookID)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const countBookReservationsAhead = `-- name: CountBookReservationsAhead :one
+SELECT COUNT(*) FROM book_reservations WHERE book_id = $1 AND status = 'waiting' AND created_at < $2
+`
+
+type CountBookReservationsAheadParams struct {
+	BookID    uuid.UUID
+	CreatedAt time.Time
+}
+
+func (q *Queries) CountBookReservationsAhead(ctx context.Context, arg CountBookReservationsAheadParams) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countBookReservationsAhead, arg.BookID, arg.CreatedAt)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const createBookReservation = `-- name: CreateBookReservation :one
+INSERT INTO book_reservations (id, status, created_at, updated_at, book_id, user_id)
+VALUES ($1, 'waiting', NOW(), NOW(), $2, $3)
+RETURNING id, status, offered_at, offer_expires_at, created_at, updated_at, book_id, user_id
+`
+
+type CreateBookReservationParams struct {
+	ID     uuid.UUID
+	BookID uuid.UUID
+	UserID uuid.UUID
+}
+
+func (q *Queries) CreateBookReservation(ctx context.Context, arg CreateBookReservationParams) (BookReservation, error) {
+	row := q.db.QueryRowContext(ctx, createBookReservation, arg.ID, arg.BookID, arg.UserID)
+	var i BookReservation
+	err := row.Scan(
+		&i.ID,
+		&i.Status,
+		&i.OfferedAt,
+		&i.OfferExpiresAt,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.BookID,
+		&i.UserID,
+	)
+	return i, err
+}
+
+const expireBookReservationOffers = `-- name: ExpireBookReservationOffers :exec
+UPDATE book_reservations
+SET status = 'expired', updated_at = NOW()
+WHERE book_id = $1 AND status = 'offered' AND offer_expires_at <= NOW()
+`
+
+func (q *Queries) ExpireBookReservationOffers(ctx context.Context, bookID uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, expireBookReservationOffers, bookID)
+	return err
+}
+
+const fulfillBookReservation = `-- name: FulfillBookReservation :exec
+UPDATE book_reservations
+SET status = 'fulfilled', updated_at = NOW()
+WHERE id = $1 AND status = 'offered'
+`
+
+func (q *Queries) FulfillBookReservation(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, fulfillBookReservation, id)
+	return err
+}
+
+const getActiveBookReservation = `-- name: GetActiveBookReservation :one
+SELECT id, status, offered_at, offer_expires_at, created_at, updated_at, book_id, user_id FROM book_reservations WHERE book_id = $1 AND user_id = $2 AND status IN ('waiting', 'offered')
+`
+
+type GetActiveBookReservationParams struct {
+	BookID uuid.UUID
+	UserID uuid.UUID
+}
+
+func (q *Queries) GetActiveBookReservation(ctx context.Context, arg GetActiveBookReservationParams) (BookReservation, error) {
+	row := q.db.QueryRowContext(ctx, getActiveBookReservation, arg.BookID, arg.UserID)
+	var i BookReservation
+	err := row.Scan(
+		&i.ID,
+		&i.Status,
+		&i.OfferedAt,
+		&i.OfferExpiresAt,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.BookID,
+		&i.UserID,
+	)
+	return i, err
+}
+
+const getBooksWithLapsedBookReservationOffers = `-- name: GetBooksWithLapsedBookReservationOffers :many
+SELECT DISTINCT book_id FROM book_reservations
+WHERE status = 'offered' AND offer_expires_at <= $1::timestamp
+`
+
+func (q *Queries) GetBooksWithLapsedBookReservationOffers(ctx context.Context, expiredBefore time.Time) ([]uuid.UUID, error) {
+	rows, err := q.db.QueryContext(ctx, getBooksWithLapsedBookReservationOffers, expiredBefore)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []uuid.UUID
+	for rows.Next() {
+		var book_id uuid.UUID
+		if err := rows.Scan(&book_id); err != nil {
+			return nil, err
+		}
+		items = append(items, book_id)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getOfferedBookReservations = `-- name: GetOfferedBookReservations :many
+SELECT id, status, offered_at, offer_expires_at, created_at, updated_at, book_id, user_id FROM book_reservations WHERE book_id = $1 AND status = 'offered' AND offer_expires_at > NOW()
+ORDER BY offered_at, id
+`
+
+func (q *Queries) GetOfferedBookReservations(ctx context.Context, bookID uuid.UUID) ([]BookReservation, error) {
+	rows, err := q.db.QueryContext(ctx, getOfferedBookReservations, bookID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []BookReservation
+	for rows.Next() {
+		var i BookReservation
+		if err := rows.Scan(
+			&i.ID,
+			&i.Status,
+			&i.OfferedAt,
+			&i.OfferExpiresAt,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.BookID,
+			&i.UserID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getUserBookReservations = `-- name: GetUserBookReservations :many
+SELECT br.id, br.status, br.offered_at, br.offer_expires_at, br.created_at, br.updated_at, br.book_id, br.user_id, b.title AS book_title,
+    (
+        SELECT COUNT(*) FROM book_reservations AS queue
+        WHERE queue.book_id = br.book_id AND queue.status = 'waiting' AND queue.created_at <= br.created_at
+    ) AS queue_position
+FROM book_reservations AS br
+INNER JOIN books AS b ON b.id = br.book_id
+WHERE br.user_id = $1 AND br.status IN ('waiting', 'offered')
+ORDER BY br.created_at
+`
+
+type GetUserBookReservationsRow struct {
+	BookReservation BookReservation
+	BookTitle       string
+	QueuePosition   int64
+}
+
+func (q *Queries) GetUserBookReservations(ctx context.Context, userID uuid.UUID) ([]GetUserBookReservationsRow, error) {
+	rows, err := q.db.QueryContext(ctx, getUserBookReservations, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetUserBookReservationsRow
+	for rows.Next() {
+		var i GetUserBookReservationsRow
+		if err := rows.Scan(
+			&i.BookReservation.ID,
+			&i.BookReservation.Status,
+			&i.BookReservation.OfferedAt,
+			&i.BookReservation.OfferExpiresAt,
+			&i.BookReservation.CreatedAt,
+			&i.BookReservation.UpdatedAt,
+			&i.BookReservation.BookID,
+			&i.BookReservation.UserID,
+			&i.BookTitle,
+			&i.QueuePosition,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const offerNextBookReservation = `-- name: OfferNextBookReservation :one
+UPDATE book_reservations
+SET status = 'offered', offered_at = NOW(), offer_expires_at = $1::timestamp, updated_at = NOW()
+WHERE id = (
+    SELECT queue.id FROM book_reservations AS queue
+    WHERE queue.book_id = $2 AND queue.status = 'waiting'
+    ORDER BY queue.created_at, queue.id
+    LIMIT 1
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING id, status, offered_at, offer_expires_at, created_at, updated_at, book_id, user_id
+`
+
+type OfferNextBookReservationParams struct {
+	OfferExpiresAt time.Time
+	BookID         uuid.UUID
+}
+
+func (q *Queries) OfferNextBookReservation(ctx context.Context, arg OfferNextBookReservationParams) (BookReservation, error) {
+	row := q.db.QueryRowContext(ctx, offerNextBookReservation, arg.OfferExpiresAt, arg.BookID)
+	var i BookReservation
+	err := row.Scan(
+		&i.ID,
+		&i.Status,
+		&i.OfferedAt,
+		&i.OfferExpiresAt,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.BookID,
+		&i.UserID,
+	)
+	return i, err
+}
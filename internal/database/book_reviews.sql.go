@@ -0,0 +1,274 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: book_reviews.sql
+
+package database
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const adjustBookRating = `-- name: AdjustBookRating :exec
+UPDATE books
+SET rating_count = rating_count + $1::int, rating_total = rating_total + $2::int
+WHERE id = $3
+`
+
+type AdjustBookRatingParams struct {
+	CountDelta int32
+	TotalDelta int32
+	ID         uuid.UUID
+}
+
+// Moves the book's rating totals by the difference a review made. The row lock taken by
+// the update keeps concurrent reviews from losing each other's changes.
+func (q *Queries) AdjustBookRating(ctx context.Context, arg AdjustBookRatingParams) error {
+	_, err := q.db.ExecContext(ctx, adjustBookRating, arg.CountDelta, arg.TotalDelta, arg.ID)
+	return err
+}
+
+const createBookReview = `-- name: CreateBookReview :one
+INSERT INTO book_reviews (id, rating, body, created_at, updated_at, book_id, reviewer_id)
+VALUES ($1, $2, $3, NOW(), NOW(), $4, $5)
+ON CONFLICT (book_id, reviewer_id) DO NOTHING
+RETURNING id, rating, body, reply, replied_at, created_at, updated_at, book_id, reviewer_id
+`
+
+type CreateBookReviewParams struct {
+	ID         uuid.UUID
+	Rating     int32
+	Body       string
+	BookID     uuid.UUID
+	ReviewerID uuid.UUID
+}
+
+// A member reviews a book once, a second review returns no rows.
+func (q *Queries) CreateBookReview(ctx context.Context, arg CreateBookReviewParams) (BookReview, error) {
+	row := q.db.QueryRowContext(ctx, createBookReview,
+		arg.ID,
+		arg.Rating,
+		arg.Body,
+		arg.BookID,
+		arg.ReviewerID,
+	)
+	var i BookReview
+	err := row.Scan(
+		&i.ID,
+		&i.Rating,
+		&i.Body,
+		&i.Reply,
+		&i.RepliedAt,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.BookID,
+		&i.ReviewerID,
+	)
+	return i, err
+}
+
+const deleteBookReview = `-- name: DeleteBookReview :one
+DELETE FROM book_reviews WHERE id = $1 AND reviewer_id = $2
+RETURNING id, rating, body, reply, replied_at, created_at, updated_at, book_id, reviewer_id
+`
+
+type DeleteBookReviewParams struct {
+	ID         uuid.UUID
+	ReviewerID uuid.UUID
+}
+
+func (q *Queries) DeleteBookReview(ctx context.Context, arg DeleteBookReviewParams) (BookReview, error) {
+	row := q.db.QueryRowContext(ctx, deleteBookReview, arg.ID, arg.ReviewerID)
+	var i BookReview
+	err := row.Scan(
+		&i.ID,
+		&i.Rating,
+		&i.Body,
+		&i.Reply,
+		&i.RepliedAt,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.BookID,
+		&i.ReviewerID,
+	)
+	return i, err
+}
+
+const getBookReviewForUpdate = `-- name: GetBookReviewForUpdate :one
+SELECT id, rating, body, reply, replied_at, created_at, updated_at, book_id, reviewer_id FROM book_reviews WHERE id = $1 FOR UPDATE
+`
+
+// Locks the review so the rating it takes off the book's totals is the one being replaced.
+func (q *Queries) GetBookReviewForUpdate(ctx context.Context, id uuid.UUID) (BookReview, error) {
+	row := q.db.QueryRowContext(ctx, getBookReviewForUpdate, id)
+	var i BookReview
+	err := row.Scan(
+		&i.ID,
+		&i.Rating,
+		&i.Body,
+		&i.Reply,
+		&i.RepliedAt,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.BookID,
+		&i.ReviewerID,
+	)
+	return i, err
+}
+
+const getBookReviews = `-- name: GetBookReviews :many
+SELECT book_reviews.id, book_reviews.rating, book_reviews.body, book_reviews.reply, book_reviews.replied_at, book_reviews.created_at, book_reviews.updated_at, book_reviews.book_id, book_reviews.reviewer_id, users.first_name AS reviewer_first_name, users.last_name AS reviewer_last_name
+FROM book_reviews
+INNER JOIN users ON users.id = book_reviews.reviewer_id
+WHERE book_reviews.book_id = $1
+AND ($2::uuid IS NULL OR (book_reviews.created_at, book_reviews.id) < ($3::timestamp, $2::uuid))
+ORDER BY book_reviews.created_at DESC, book_reviews.id DESC
+LIMIT $4::int
+`
+
+type GetBookReviewsParams struct {
+	BookID          uuid.UUID
+	CursorID        uuid.NullUUID
+	CursorCreatedAt time.Time
+	PageLimit       int32
+}
+
+type GetBookReviewsRow struct {
+	BookReview        BookReview
+	ReviewerFirstName string
+	ReviewerLastName  string
+}
+
+// Newest first.
+func (q *Queries) GetBookReviews(ctx context.Context, arg GetBookReviewsParams) ([]GetBookReviewsRow, error) {
+	rows, err := q.db.QueryContext(ctx, getBookReviews,
+		arg.BookID,
+		arg.CursorID,
+		arg.CursorCreatedAt,
+		arg.PageLimit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetBookReviewsRow
+	for rows.Next() {
+		var i GetBookReviewsRow
+		if err := rows.Scan(
+			&i.BookReview.ID,
+			&i.BookReview.Rating,
+			&i.BookReview.Body,
+			&i.BookReview.Reply,
+			&i.BookReview.RepliedAt,
+			&i.BookReview.CreatedAt,
+			&i.BookReview.UpdatedAt,
+			&i.BookReview.BookID,
+			&i.BookReview.ReviewerID,
+			&i.ReviewerFirstName,
+			&i.ReviewerLastName,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const hasReturnedBookBorrow = `-- name: HasReturnedBookBorrow :one
+SELECT EXISTS (
+    SELECT 1 FROM book_borrows
+    WHERE book_id = $1 AND borrower_id = $2
+    AND returned_at IS NOT NULL AND return_status IS DISTINCT FROM 'disputed'
+)::boolean AS has_returned
+`
+
+type HasReturnedBookBorrowParams struct {
+	BookID     uuid.UUID
+	BorrowerID uuid.UUID
+}
+
+// Only members who borrowed the book and gave it back can review it. A return the owner
+// disputes doesn't count.
+func (q *Queries) HasReturnedBookBorrow(ctx context.Context, arg HasReturnedBookBorrowParams) (bool, error) {
+	row := q.db.QueryRowContext(ctx, hasReturnedBookBorrow, arg.BookID, arg.BorrowerID)
+	var has_returned bool
+	err := row.Scan(&has_returned)
+	return has_returned, err
+}
+
+const replyToBookReview = `-- name: ReplyToBookReview :one
+UPDATE book_reviews
+SET reply = $1, replied_at = CASE WHEN $1::text = '' THEN NULL ELSE NOW() END
+FROM books
+WHERE book_reviews.id = $2 AND books.id = book_reviews.book_id AND books.user_id = $3
+RETURNING book_reviews.id, book_reviews.rating, book_reviews.body, book_reviews.reply, book_reviews.replied_at, book_reviews.created_at, book_reviews.updated_at, book_reviews.book_id, book_reviews.reviewer_id
+`
+
+type ReplyToBookReviewParams struct {
+	Reply  string
+	ID     uuid.UUID
+	UserID uuid.UUID
+}
+
+// Only the book owner can reply, an empty reply takes it back.
+func (q *Queries) ReplyToBookReview(ctx context.Context, arg ReplyToBookReviewParams) (BookReview, error) {
+	row := q.db.QueryRowContext(ctx, replyToBookReview, arg.Reply, arg.ID, arg.UserID)
+	var i BookReview
+	err := row.Scan(
+		&i.ID,
+		&i.Rating,
+		&i.Body,
+		&i.Reply,
+		&i.RepliedAt,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.BookID,
+		&i.ReviewerID,
+	)
+	return i, err
+}
+
+const updateBookReview = `-- name: UpdateBookReview :one
+UPDATE book_reviews
+SET rating = $1, body = $2, updated_at = NOW()
+WHERE id = $3 AND reviewer_id = $4
+RETURNING id, rating, body, reply, replied_at, created_at, updated_at, book_id, reviewer_id
+`
+
+type UpdateBookReviewParams struct {
+	Rating     int32
+	Body       string
+	ID         uuid.UUID
+	ReviewerID uuid.UUID
+}
+
+func (q *Queries) UpdateBookReview(ctx context.Context, arg UpdateBookReviewParams) (BookReview, error) {
+	row := q.db.QueryRowContext(ctx, updateBookReview,
+		arg.Rating,
+		arg.Body,
+		arg.ID,
+		arg.ReviewerID,
+	)
+	var i BookReview
+	err := row.Scan(
+		&i.ID,
+		&i.Rating,
+		&i.Body,
+		&i.Reply,
+		&i.RepliedAt,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.BookID,
+		&i.ReviewerID,
+	)
+	return i, err
+}
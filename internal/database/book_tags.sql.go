@@ -0,0 +1,167 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: book_tags.sql
+
+package database
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+const createBookGenres = `-- name: CreateBookGenres :exec
+INSERT INTO book_genres (book_id, genre)
+SELECT $1, unnest($2::text[])
+`
+
+type CreateBookGenresParams struct {
+	BookID uuid.UUID
+	Genres []string
+}
+
+func (q *Queries) CreateBookGenres(ctx context.Context, arg CreateBookGenresParams) error {
+	_, err := q.db.ExecContext(ctx, createBookGenres, arg.BookID, pq.Array(arg.Genres))
+	return err
+}
+
+const createBookTags = `-- name: CreateBookTags :exec
+INSERT INTO book_tags (book_id, tag)
+SELECT $1, unnest($2::text[])
+`
+
+type CreateBookTagsParams struct {
+	BookID uuid.UUID
+	Tags   []string
+}
+
+func (q *Queries) CreateBookTags(ctx context.Context, arg CreateBookTagsParams) error {
+	_, err := q.db.ExecContext(ctx, createBookTags, arg.BookID, pq.Array(arg.Tags))
+	return err
+}
+
+const deleteBookGenres = `-- name: DeleteBookGenres :exec
+DELETE FROM book_genres WHERE book_id = $1
+`
+
+func (q *Queries) DeleteBookGenres(ctx context.Context, bookID uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, deleteBookGenres, bookID)
+	return err
+}
+
+const deleteBookTags = `-- name: DeleteBookTags :exec
+DELETE FROM book_tags WHERE book_id = $1
+`
+
+func (q *Queries) DeleteBookTags(ctx context.Context, bookID uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, deleteBookTags, bookID)
+	return err
+}
+
+const getBookGenresAndTags = `-- name: GetBookGenresAndTags :one
+SELECT ARRAY(SELECT genre FROM book_genres WHERE book_genres.book_id = books.id ORDER BY genre)::text[] AS genres,
+    ARRAY(SELECT tag FROM book_tags WHERE book_tags.book_id = books.id ORDER BY tag)::text[] AS tags
+FROM books
+WHERE books.id = $1
+`
+
+type GetBookGenresAndTagsRow struct {
+	Genres []string
+	Tags   []string
+}
+
+func (q *Queries) GetBookGenresAndTags(ctx context.Context, id uuid.UUID) (GetBookGenresAndTagsRow, error) {
+	row := q.db.QueryRowContext(ctx, getBookGenresAndTags, id)
+	var i GetBookGenresAndTagsRow
+	err := row.Scan(pq.Array(&i.Genres), pq.Array(&i.Tags))
+	return i, err
+}
+
+const getGenres = `-- name: GetGenres :many
+SELECT genres.slug, genres.name, COUNT(book_genres.book_id) AS book_count
+FROM genres
+LEFT JOIN book_genres ON book_genres.genre = genres.slug
+    AND EXISTS (
+        SELECT 1 FROM books WHERE books.id = book_genres.book_id AND books.deleted_at IS NULL
+        AND (books.visibility = 'public' OR books.user_id = $1::uuid OR (books.visibility = 'subscribers' AND EXISTS (SELECT 1 FROM user_subscribers WHERE user_subscribers.user_id = books.user_id AND user_subscribers.subscriber_id = $1::uuid)))
+    )
+GROUP BY genres.slug
+ORDER BY genres.name
+`
+
+type GetGenresRow struct {
+	Slug      string
+	Name      string
+	BookCount int64
+}
+
+// Books only count for viewers who can see them, the same as in GetBooks.
+func (q *Queries) GetGenres(ctx context.Context, viewerID uuid.UUID) ([]GetGenresRow, error) {
+	rows, err := q.db.QueryContext(ctx, getGenres, viewerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetGenresRow
+	for rows.Next() {
+		var i GetGenresRow
+		if err := rows.Scan(&i.Slug, &i.Name, &i.BookCount); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getPopularBookTags = `-- name: GetPopularBookTags :many
+SELECT tag, COUNT(*) AS book_count
+FROM book_tags
+INNER JOIN books ON books.id = book_tags.book_id
+WHERE books.deleted_at IS NULL
+AND (books.visibility = 'public' OR books.user_id = $1::uuid OR (books.visibility = 'subscribers' AND EXISTS (SELECT 1 FROM user_subscribers WHERE user_subscribers.user_id = books.user_id AND user_subscribers.subscriber_id = $1::uuid)))
+GROUP BY tag
+ORDER BY book_count DESC, tag
+LIMIT $2::int
+`
+
+type GetPopularBookTagsParams struct {
+	ViewerID uuid.UUID
+	TagLimit int32
+}
+
+type GetPopularBookTagsRow struct {
+	Tag       string
+	BookCount int64
+}
+
+// Books only count for viewers who can see them, the same as in GetBooks.
+func (q *Queries) GetPopularBookTags(ctx context.Context, arg GetPopularBookTagsParams) ([]GetPopularBookTagsRow, error) {
+	rows, err := q.db.QueryContext(ctx, getPopularBookTags, arg.ViewerID, arg.TagLimit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetPopularBookTagsRow
+	for rows.Next() {
+		var i GetPopularBookTagsRow
+		if err := rows.Scan(&i.Tag, &i.BookCount); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
@@ -7,31 +7,126 @@ package database
 
 import (
 	"context"
+	"database/sql"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
 const browseBooks = `-- name: BrowseBooks :many
-SELECT id, title, author, created_at, updated_at, user_id FROM books
+SELECT books.id, books.title, books.author, books.created_at, books.updated_at, books.user_id, books.loan_period_days, books.isbn, books.publisher, books.published_year, books.page_count, books.cover_url, books.visibility, books.lendable, books.borrower_policy, books.cover_thumbnail_url, books.cover_keys, books.deleted_at, books.rating_count, books.rating_total, books.rating_average, books.min_borrower_reputation, book_availability.book_id, book_availability.total_copies, book_availability.available_copies, book_availability.queue_length, book_availability.current_borrower_id, book_availability.current_borrower_first_name, book_availability.current_borrower_last_name, book_availability.due_at,
+    ARRAY(SELECT genre FROM book_genres WHERE book_genres.book_id = books.id ORDER BY genre)::text[] AS genres,
+    ARRAY(SELECT tag FROM book_tags WHERE book_tags.book_id = books.id ORDER BY tag)::text[] AS tags,
+    COALESCE(ts_rank(to_tsvector('english', title || ' ' || author), websearch_to_tsquery('english', $1::text)), 0)::real AS rank
+FROM books
+INNER JOIN book_availability ON book_availability.book_id = books.id
+WHERE books.deleted_at IS NULL
+AND ($1::text IS NULL OR to_tsvector('english', title || ' ' || author) @@ websearch_to_tsquery('english', $1::text))
+AND ($2::text IS NULL OR author ILIKE '%' || $2::text || '%')
+AND ($3::boolean IS NULL OR $3::boolean = (book_availability.available_copies > 0))
+AND ($4::text IS NULL OR EXISTS (SELECT 1 FROM book_genres WHERE book_genres.book_id = books.id AND book_genres.genre = $4::text))
+AND ($5::text IS NULL OR EXISTS (SELECT 1 FROM book_tags WHERE book_tags.book_id = books.id AND book_tags.tag = $5::text))
+AND (books.visibility = 'public' OR books.user_id = $6::uuid OR (books.visibility = 'subscribers' AND EXISTS (SELECT 1 FROM user_subscribers WHERE user_subscribers.user_id = books.user_id AND user_subscribers.subscriber_id = $6::uuid)))
+AND ($7::uuid IS NULL OR CASE $8::text
+    WHEN 'title' THEN (title, id) > ($9::text, $7::uuid)
+    WHEN 'created_at' THEN (created_at, id) < ($10::timestamp, $7::uuid)
+    WHEN 'rating' THEN (books.rating_average, id) < ($11::float8, $7::uuid)
+    ELSE (ts_rank(to_tsvector('english', title || ' ' || author), websearch_to_tsquery('english', $1::text)), id) < ($12::real, $7::uuid)
+END)
+ORDER BY
+    CASE WHEN $8::text = 'relevance' THEN ts_rank(to_tsvector('english', title || ' ' || author), websearch_to_tsquery('english', $1::text)) END DESC,
+    CASE WHEN $8::text = 'created_at' THEN created_at END DESC,
+    CASE WHEN $8::text = 'rating' THEN books.rating_average END DESC,
+    CASE WHEN $8::text = 'title' THEN title END,
+    CASE WHEN $8::text = 'title' THEN id END,
+    id DESC
+LIMIT $13::int
 `
 
-func (q *Queries) BrowseBooks(ctx context.Context) ([]Book, error) {
-	rows, err := q.db.QueryContext(ctx, browseBooks)
+type BrowseBooksParams struct {
+	Query           sql.NullString
+	Author          sql.NullString
+	Available       sql.NullBool
+	Genre           sql.NullString
+	Tag             sql.NullString
+	ViewerID        uuid.UUID
+	CursorID        uuid.NullUUID
+	Sort            string
+	CursorTitle     string
+	CursorCreatedAt time.Time
+	CursorRating    float64
+	CursorRank      float32
+	PageLimit       int32
+}
+
+type BrowseBooksRow struct {
+	Book             Book
+	BookAvailability BookAvailability
+	Genres           []string
+	Tags             []string
+	Rank             float32
+}
+
+// Private books are only listed for their owner, subscriber-only ones for the owner's subscribers too.
+// The cursor is the sort key and id of the last book on the previous page.
+func (q *Queries) BrowseBooks(ctx context.Context, arg BrowseBooksParams) ([]BrowseBooksRow, error) {
+	rows, err := q.db.QueryContext(ctx, browseBooks,
+		arg.Query,
+		arg.Author,
+		arg.Available,
+		arg.Genre,
+		arg.Tag,
+		arg.ViewerID,
+		arg.CursorID,
+		arg.Sort,
+		arg.CursorTitle,
+		arg.CursorCreatedAt,
+		arg.CursorRating,
+		arg.CursorRank,
+		arg.PageLimit,
+	)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var items []Book
+	var items []BrowseBooksRow
 	for rows.Next() {
-		var i Book
+		var i BrowseBooksRow
 		if err := rows.Scan(
-			&i.ID,
-			&i.Title,
-			&i.Author,
-			&i.CreatedAt,
-			&i.UpdatedAt,
-			&i.UserID,
+			&i.Book.ID,
+			&i.Book.Title,
+			&i.Book.Author,
+			&i.Book.CreatedAt,
+			&i.Book.UpdatedAt,
+			&i.Book.UserID,
+			&i.Book.LoanPeriodDays,
+			&i.Book.Isbn,
+			&i.Book.Publisher,
+			&i.Book.PublishedYear,
+			&i.Book.PageCount,
+			&i.Book.CoverUrl,
+			&i.Book.Visibility,
+			&i.Book.Lendable,
+			&i.Book.BorrowerPolicy,
+			&i.Book.CoverThumbnailUrl,
+			pq.Array(&i.Book.CoverKeys),
+			&i.Book.DeletedAt,
+			&i.Book.RatingCount,
+			&i.Book.RatingTotal,
+			&i.Book.RatingAverage,
+			&i.Book.MinBorrowerReputation,
+			&i.BookAvailability.BookID,
+			&i.BookAvailability.TotalCopies,
+			&i.BookAvailability.AvailableCopies,
+			&i.BookAvailability.QueueLength,
+			&i.BookAvailability.CurrentBorrowerID,
+			&i.BookAvailability.CurrentBorrowerFirstName,
+			&i.BookAvailability.CurrentBorrowerLastName,
+			&i.BookAvailability.DueAt,
+			pq.Array(&i.Genres),
+			pq.Array(&i.Tags),
+			&i.Rank,
 		); err != nil {
 			return nil, err
 		}
@@ -47,20 +142,36 @@ func (q *Queries) BrowseBooks(ctx context.Context) ([]Book, error) {
 }
 
 const createBook = `-- name: CreateBook :one
-INSERT INTO books (id, title, author, created_at, updated_at, user_id)
-VALUES ($1, $2, $3, $4, $5, $6)
-RETURNING id, title, author, created_at, updated_at, user_id
+INSERT INTO books (id, title, author, created_at, updated_at, user_id, loan_period_days, isbn, publisher, published_year, page_count, cover_url, visibility, lendable, borrower_policy, min_borrower_reputation)
+VALUES (
+    $1, $2, $3, $4, $5, $6,
+    COALESCE(NULLIF($7::integer, 0), (SELECT users.default_loan_period_days FROM users WHERE users.id = $6)),
+    $8, $9, $10, $11, $12,
+    $13, $14, $15, $16
+)
+RETURNING id, title, author, created_at, updated_at, user_id, loan_period_days, isbn, publisher, published_year, page_count, cover_url, visibility, lendable, borrower_policy, cover_thumbnail_url, cover_keys, deleted_at, rating_count, rating_total, rating_average, min_borrower_reputation
 `
 
 type CreateBookParams struct {
-	ID        uuid.UUID
-	Title     string
-	Author    string
-	CreatedAt time.Time
-	UpdatedAt time.Time
-	UserID    uuid.UUID
+	ID                    uuid.UUID
+	Title                 string
+	Author                string
+	CreatedAt             time.Time
+	UpdatedAt             time.Time
+	UserID                uuid.UUID
+	LoanPeriodDays        int32
+	Isbn                  string
+	Publisher             string
+	PublishedYear         int32
+	PageCount             int32
+	CoverUrl              string
+	Visibility            string
+	Lendable              bool
+	BorrowerPolicy        string
+	MinBorrowerReputation int32
 }
 
+// A loan period of 0 gives the book its owner's default one.
 func (q *Queries) CreateBook(ctx context.Context, arg CreateBookParams) (Book, error) {
 	row := q.db.QueryRowContext(ctx, createBook,
 		arg.ID,
@@ -69,6 +180,16 @@ func (q *Queries) CreateBook(ctx context.Context, arg CreateBookParams) (Book, e
 		arg.CreatedAt,
 		arg.UpdatedAt,
 		arg.UserID,
+		arg.LoanPeriodDays,
+		arg.Isbn,
+		arg.Publisher,
+		arg.PublishedYear,
+		arg.PageCount,
+		arg.CoverUrl,
+		arg.Visibility,
+		arg.Lendable,
+		arg.BorrowerPolicy,
+		arg.MinBorrowerReputation,
 	)
 	var i Book
 	err := row.Scan(
@@ -78,12 +199,30 @@ func (q *Queries) CreateBook(ctx context.Context, arg CreateBookParams) (Book, e
 		&i.CreatedAt,
 		&i.UpdatedAt,
 		&i.UserID,
+		&i.LoanPeriodDays,
+		&i.Isbn,
+		&i.Publisher,
+		&i.PublishedYear,
+		&i.PageCount,
+		&i.CoverUrl,
+		&i.Visibility,
+		&i.Lendable,
+		&i.BorrowerPolicy,
+		&i.CoverThumbnailUrl,
+		pq.Array(&i.CoverKeys),
+		&i.DeletedAt,
+		&i.RatingCount,
+		&i.RatingTotal,
+		&i.RatingAverage,
+		&i.MinBorrowerReputation,
 	)
 	return i, err
 }
 
 const deleteBook = `-- name: DeleteBook :execrows
-DELETE FROM books WHERE id = $1 AND user_id = $2
+UPDATE books SET deleted_at = NOW(), updated_at = NOW()
+WHERE books.id = $1 AND books.user_id = $2 AND books.deleted_at IS NULL
+AND NOT EXISTS (SELECT 1 FROM book_borrows WHERE book_borrows.book_id = books.id AND book_borrows.return_confirmed_at IS NULL)
 `
 
 type DeleteBookParams struct {
@@ -91,6 +230,8 @@ type DeleteBookParams struct {
 	UserID uuid.UUID
 }
 
+// Moves the book to the trash, unless a copy is still out on loan. The loans,
+// requests and reservations stay until the book is purged.
 func (q *Queries) DeleteBook(ctx context.Context, arg DeleteBookParams) (int64, error) {
 	result, err := q.db.ExecContext(ctx, deleteBook, arg.ID, arg.UserID)
 	if err != nil {
@@ -100,7 +241,7 @@ func (q *Queries) DeleteBook(ctx context.Context, arg DeleteBookParams) (int64,
 }
 
 const getBook = `-- name: GetBook :one
-SELECT id, title, author, created_at, updated_at, user_id FROM books WHERE id = $1
+SELECT id, title, author, created_at, updated_at, user_id, loan_period_days, isbn, publisher, published_year, page_count, cover_url, visibility, lendable, borrower_policy, cover_thumbnail_url, cover_keys, deleted_at, rating_count, rating_total, rating_average, min_borrower_reputation FROM books WHERE id = $1 AND deleted_at IS NULL
 `
 
 func (q *Queries) GetBook(ctx context.Context, id uuid.UUID) (Book, error) {
@@ -113,30 +254,274 @@ func (q *Queries) GetBook(ctx context.Context, id uuid.UUID) (Book, error) {
 		&i.CreatedAt,
 		&i.UpdatedAt,
 		&i.UserID,
+		&i.LoanPeriodDays,
+		&i.Isbn,
+		&i.Publisher,
+		&i.PublishedYear,
+		&i.PageCount,
+		&i.CoverUrl,
+		&i.Visibility,
+		&i.Lendable,
+		&i.BorrowerPolicy,
+		&i.CoverThumbnailUrl,
+		pq.Array(&i.CoverKeys),
+		&i.DeletedAt,
+		&i.RatingCount,
+		&i.RatingTotal,
+		&i.RatingAverage,
+		&i.MinBorrowerReputation,
+	)
+	return i, err
+}
+
+const getBookIdentities = `-- name: GetBookIdentities :many
+SELECT isbn, title, author FROM books WHERE user_id = $1 AND deleted_at IS NULL
+`
+
+type GetBookIdentitiesRow struct {
+	Isbn   string
+	Title  string
+	Author string
+}
+
+// What an import checks new books against to skip the ones the owner already has.
+func (q *Queries) GetBookIdentities(ctx context.Context, userID uuid.UUID) ([]GetBookIdentitiesRow, error) {
+	rows, err := q.db.QueryContext(ctx, getBookIdentities, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetBookIdentitiesRow
+	for rows.Next() {
+		var i GetBookIdentitiesRow
+		if err := rows.Scan(&i.Isbn, &i.Title, &i.Author); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getBookWithAvailability = `-- name: GetBookWithAvailability :one
+SELECT books.id, books.title, books.author, books.created_at, books.updated_at, books.user_id, books.loan_period_days, books.isbn, books.publisher, books.published_year, books.page_count, books.cover_url, books.visibility, books.lendable, books.borrower_policy, books.cover_thumbnail_url, books.cover_keys, books.deleted_at, books.rating_count, books.rating_total, books.rating_average, books.min_borrower_reputation, book_availability.book_id, book_availability.total_copies, book_availability.available_copies, book_availability.queue_length, book_availability.current_borrower_id, book_availability.current_borrower_first_name, book_availability.current_borrower_last_name, book_availability.due_at,
+    ARRAY(SELECT genre FROM book_genres WHERE book_genres.book_id = books.id ORDER BY genre)::text[] AS genres,
+    ARRAY(SELECT tag FROM book_tags WHERE book_tags.book_id = books.id ORDER BY tag)::text[] AS tags
+FROM books
+INNER JOIN book_availability ON book_availability.book_id = books.id
+WHERE books.id = $1 AND books.deleted_at IS NULL
+`
+
+type GetBookWithAvailabilityRow struct {
+	Book             Book
+	BookAvailability BookAvailability
+	Genres           []string
+	Tags             []string
+}
+
+func (q *Queries) GetBookWithAvailability(ctx context.Context, id uuid.UUID) (GetBookWithAvailabilityRow, error) {
+	row := q.db.QueryRowContext(ctx, getBookWithAvailability, id)
+	var i GetBookWithAvailabilityRow
+	err := row.Scan(
+		&i.Book.ID,
+		&i.Book.Title,
+		&i.Book.Author,
+		&i.Book.CreatedAt,
+		&i.Book.UpdatedAt,
+		&i.Book.UserID,
+		&i.Book.LoanPeriodDays,
+		&i.Book.Isbn,
+		&i.Book.Publisher,
+		&i.Book.PublishedYear,
+		&i.Book.PageCount,
+		&i.Book.CoverUrl,
+		&i.Book.Visibility,
+		&i.Book.Lendable,
+		&i.Book.BorrowerPolicy,
+		&i.Book.CoverThumbnailUrl,
+		pq.Array(&i.Book.CoverKeys),
+		&i.Book.DeletedAt,
+		&i.Book.RatingCount,
+		&i.Book.RatingTotal,
+		&i.Book.RatingAverage,
+		&i.Book.MinBorrowerReputation,
+		&i.BookAvailability.BookID,
+		&i.BookAvailability.TotalCopies,
+		&i.BookAvailability.AvailableCopies,
+		&i.BookAvailability.QueueLength,
+		&i.BookAvailability.CurrentBorrowerID,
+		&i.BookAvailability.CurrentBorrowerFirstName,
+		&i.BookAvailability.CurrentBorrowerLastName,
+		&i.BookAvailability.DueAt,
+		pq.Array(&i.Genres),
+		pq.Array(&i.Tags),
 	)
 	return i, err
 }
 
 const getBooks = `-- name: GetBooks :many
-SELECT id, title, author, created_at, updated_at, user_id FROM books WHERE user_id = $1
+SELECT books.id, books.title, books.author, books.created_at, books.updated_at, books.user_id, books.loan_period_days, books.isbn, books.publisher, books.published_year, books.page_count, books.cover_url, books.visibility, books.lendable, books.borrower_policy, books.cover_thumbnail_url, books.cover_keys, books.deleted_at, books.rating_count, books.rating_total, books.rating_average, books.min_borrower_reputation, book_availability.book_id, book_availability.total_copies, book_availability.available_copies, book_availability.queue_length, book_availability.current_borrower_id, book_availability.current_borrower_first_name, book_availability.current_borrower_last_name, book_availability.due_at,
+    ARRAY(SELECT genre FROM book_genres WHERE book_genres.book_id = books.id ORDER BY genre)::text[] AS genres,
+    ARRAY(SELECT tag FROM book_tags WHERE book_tags.book_id = books.id ORDER BY tag)::text[] AS tags
+FROM books
+INNER JOIN book_availability ON book_availability.book_id = books.id
+WHERE books.user_id = $1 AND books.deleted_at IS NULL
+AND (books.visibility = 'public' OR books.user_id = $2::uuid OR (books.visibility = 'subscribers' AND EXISTS (SELECT 1 FROM user_subscribers WHERE user_subscribers.user_id = books.user_id AND user_subscribers.subscriber_id = $2::uuid)))
+AND ($3::uuid IS NULL OR EXISTS (SELECT 1 FROM shelf_books WHERE shelf_books.shelf_id = $3::uuid AND shelf_books.book_id = books.id))
+AND ($4::uuid IS NULL OR (title, id) > ($5::text, $4::uuid))
+ORDER BY title, id
+LIMIT $6::int
 `
 
-func (q *Queries) GetBooks(ctx context.Context, userID uuid.UUID) ([]Book, error) {
-	rows, err := q.db.QueryContext(ctx, getBooks, userID)
+type GetBooksParams struct {
+	UserID      uuid.UUID
+	ViewerID    uuid.UUID
+	ShelfID     uuid.NullUUID
+	CursorID    uuid.NullUUID
+	CursorTitle string
+	PageLimit   int32
+}
+
+type GetBooksRow struct {
+	Book             Book
+	BookAvailability BookAvailability
+	Genres           []string
+	Tags             []string
+}
+
+// Private books are only listed for their owner, subscriber-only ones for the owner's subscribers too.
+func (q *Queries) GetBooks(ctx context.Context, arg GetBooksParams) ([]GetBooksRow, error) {
+	rows, err := q.db.QueryContext(ctx, getBooks,
+		arg.UserID,
+		arg.ViewerID,
+		arg.ShelfID,
+		arg.CursorID,
+		arg.CursorTitle,
+		arg.PageLimit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetBooksRow
+	for rows.Next() {
+		var i GetBooksRow
+		if err := rows.Scan(
+			&i.Book.ID,
+			&i.Book.Title,
+			&i.Book.Author,
+			&i.Book.CreatedAt,
+			&i.Book.UpdatedAt,
+			&i.Book.UserID,
+			&i.Book.LoanPeriodDays,
+			&i.Book.Isbn,
+			&i.Book.Publisher,
+			&i.Book.PublishedYear,
+			&i.Book.PageCount,
+			&i.Book.CoverUrl,
+			&i.Book.Visibility,
+			&i.Book.Lendable,
+			&i.Book.BorrowerPolicy,
+			&i.Book.CoverThumbnailUrl,
+			pq.Array(&i.Book.CoverKeys),
+			&i.Book.DeletedAt,
+			&i.Book.RatingCount,
+			&i.Book.RatingTotal,
+			&i.Book.RatingAverage,
+			&i.Book.MinBorrowerReputation,
+			&i.BookAvailability.BookID,
+			&i.BookAvailability.TotalCopies,
+			&i.BookAvailability.AvailableCopies,
+			&i.BookAvailability.QueueLength,
+			&i.BookAvailability.CurrentBorrowerID,
+			&i.BookAvailability.CurrentBorrowerFirstName,
+			&i.BookAvailability.CurrentBorrowerLastName,
+			&i.BookAvailability.DueAt,
+			pq.Array(&i.Genres),
+			pq.Array(&i.Tags),
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getDeletedBooks = `-- name: GetDeletedBooks :many
+SELECT books.id, books.title, books.author, books.created_at, books.updated_at, books.user_id, books.loan_period_days, books.isbn, books.publisher, books.published_year, books.page_count, books.cover_url, books.visibility, books.lendable, books.borrower_policy, books.cover_thumbnail_url, books.cover_keys, books.deleted_at, books.rating_count, books.rating_total, books.rating_average, books.min_borrower_reputation,
+    ARRAY(SELECT genre FROM book_genres WHERE book_genres.book_id = books.id ORDER BY genre)::text[] AS genres,
+    ARRAY(SELECT tag FROM book_tags WHERE book_tags.book_id = books.id ORDER BY tag)::text[] AS tags
+FROM books
+WHERE books.user_id = $1 AND books.deleted_at IS NOT NULL
+AND ($2::uuid IS NULL OR (books.deleted_at, books.id) < ($3::timestamp, $2::uuid))
+ORDER BY books.deleted_at DESC, books.id DESC
+LIMIT $4::int
+`
+
+type GetDeletedBooksParams struct {
+	UserID          uuid.UUID
+	CursorID        uuid.NullUUID
+	CursorDeletedAt time.Time
+	PageLimit       int32
+}
+
+type GetDeletedBooksRow struct {
+	Book   Book
+	Genres []string
+	Tags   []string
+}
+
+// The owner's trash, most recently deleted first.
+func (q *Queries) GetDeletedBooks(ctx context.Context, arg GetDeletedBooksParams) ([]GetDeletedBooksRow, error) {
+	rows, err := q.db.QueryContext(ctx, getDeletedBooks,
+		arg.UserID,
+		arg.CursorID,
+		arg.CursorDeletedAt,
+		arg.PageLimit,
+	)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var items []Book
+	var items []GetDeletedBooksRow
 	for rows.Next() {
-		var i Book
+		var i GetDeletedBooksRow
 		if err := rows.Scan(
-			&i.ID,
-			&i.Title,
-			&i.Author,
-			&i.CreatedAt,
-			&i.UpdatedAt,
-			&i.UserID,
+			&i.Book.ID,
+			&i.Book.Title,
+			&i.Book.Author,
+			&i.Book.CreatedAt,
+			&i.Book.UpdatedAt,
+			&i.Book.UserID,
+			&i.Book.LoanPeriodDays,
+			&i.Book.Isbn,
+			&i.Book.Publisher,
+			&i.Book.PublishedYear,
+			&i.Book.PageCount,
+			&i.Book.CoverUrl,
+			&i.Book.Visibility,
+			&i.Book.Lendable,
+			&i.Book.BorrowerPolicy,
+			&i.Book.CoverThumbnailUrl,
+			pq.Array(&i.Book.CoverKeys),
+			&i.Book.DeletedAt,
+			&i.Book.RatingCount,
+			&i.Book.RatingTotal,
+			&i.Book.RatingAverage,
+			&i.Book.MinBorrowerReputation,
+			pq.Array(&i.Genres),
+			pq.Array(&i.Tags),
 		); err != nil {
 			return nil, err
 		}
@@ -151,24 +536,194 @@ func (q *Queries) GetBooks(ctx context.Context, userID uuid.UUID) ([]Book, error
 	return items, nil
 }
 
+const purgeDeletedBooks = `-- name: PurgeDeletedBooks :many
+DELETE FROM books
+WHERE books.id IN (
+    SELECT trash.id FROM books AS trash
+    WHERE trash.deleted_at < $1::timestamp
+    AND NOT EXISTS (SELECT 1 FROM book_borrows WHERE book_borrows.book_id = trash.id AND book_borrows.return_confirmed_at IS NULL)
+    ORDER BY trash.deleted_at
+    LIMIT $2::int
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING books.id, books.cover_keys,
+    ARRAY(
+        SELECT bcr.photo_key FROM book_condition_reports AS bcr
+        INNER JOIN book_borrows AS bb ON bb.id = bcr.book_borrow_id
+        WHERE bb.book_id = books.id AND bcr.photo_key <> ''
+    )::text[] AS condition_photo_keys
+`
+
+type PurgeDeletedBooksParams struct {
+	DeletedBefore time.Time
+	BatchSize     int32
+}
+
+type PurgeDeletedBooksRow struct {
+	ID                 uuid.UUID
+	CoverKeys          []string
+	ConditionPhotoKeys []string
+}
+
+// Deletes a batch of books that have been in the trash since before the cutoff, along with
+// everything that cascades from them. Returns the cover and condition photo blobs left to remove.
+func (q *Queries) PurgeDeletedBooks(ctx context.Context, arg PurgeDeletedBooksParams) ([]PurgeDeletedBooksRow, error) {
+	rows, err := q.db.QueryContext(ctx, purgeDeletedBooks, arg.DeletedBefore, arg.BatchSize)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []PurgeDeletedBooksRow
+	for rows.Next() {
+		var i PurgeDeletedBooksRow
+		if err := rows.Scan(&i.ID, pq.Array(&i.CoverKeys), pq.Array(&i.ConditionPhotoKeys)); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const restoreBook = `-- name: RestoreBook :one
+UPDATE books SET deleted_at = NULL, updated_at = NOW()
+WHERE id = $1 AND user_id = $2 AND deleted_at IS NOT NULL
+RETURNING id, title, author, created_at, updated_at, user_id, loan_period_days, isbn, publisher, published_year, page_count, cover_url, visibility, lendable, borrower_policy, cover_thumbnail_url, cover_keys, deleted_at, rating_count, rating_total, rating_average, min_borrower_reputation
+`
+
+type RestoreBookParams struct {
+	ID     uuid.UUID
+	UserID uuid.UUID
+}
+
+func (q *Queries) RestoreBook(ctx context.Context, arg RestoreBookParams) (Book, error) {
+	row := q.db.QueryRowContext(ctx, restoreBook, arg.ID, arg.UserID)
+	var i Book
+	err := row.Scan(
+		&i.ID,
+		&i.Title,
+		&i.Author,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.UserID,
+		&i.LoanPeriodDays,
+		&i.Isbn,
+		&i.Publisher,
+		&i.PublishedYear,
+		&i.PageCount,
+		&i.CoverUrl,
+		&i.Visibility,
+		&i.Lendable,
+		&i.BorrowerPolicy,
+		&i.CoverThumbnailUrl,
+		pq.Array(&i.CoverKeys),
+		&i.DeletedAt,
+		&i.RatingCount,
+		&i.RatingTotal,
+		&i.RatingAverage,
+		&i.MinBorrowerReputation,
+	)
+	return i, err
+}
+
+const setBookCover = `-- name: SetBookCover :one
+UPDATE books
+SET cover_url = $1, cover_thumbnail_url = $2, cover_keys = $3::text[], updated_at = NOW()
+WHERE id = $4 AND user_id = $5 AND deleted_at IS NULL
+RETURNING id, title, author, created_at, updated_at, user_id, loan_period_days, isbn, publisher, published_year, page_count, cover_url, visibility, lendable, borrower_policy, cover_thumbnail_url, cover_keys, deleted_at, rating_count, rating_total, rating_average, min_borrower_reputation
+`
+
+type SetBookCoverParams struct {
+	CoverUrl          string
+	CoverThumbnailUrl string
+	CoverKeys         []string
+	ID                uuid.UUID
+	UserID            uuid.UUID
+}
+
+func (q *Queries) SetBookCover(ctx context.Context, arg SetBookCoverParams) (Book, error) {
+	row := q.db.QueryRowContext(ctx, setBookCover,
+		arg.CoverUrl,
+		arg.CoverThumbnailUrl,
+		pq.Array(arg.CoverKeys),
+		arg.ID,
+		arg.UserID,
+	)
+	var i Book
+	err := row.Scan(
+		&i.ID,
+		&i.Title,
+		&i.Author,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.UserID,
+		&i.LoanPeriodDays,
+		&i.Isbn,
+		&i.Publisher,
+		&i.PublishedYear,
+		&i.PageCount,
+		&i.CoverUrl,
+		&i.Visibility,
+		&i.Lendable,
+		&i.BorrowerPolicy,
+		&i.CoverThumbnailUrl,
+		pq.Array(&i.CoverKeys),
+		&i.DeletedAt,
+		&i.RatingCount,
+		&i.RatingTotal,
+		&i.RatingAverage,
+		&i.MinBorrowerReputation,
+	)
+	return i, err
+}
+
 const updateBook = `-- name: UpdateBook :one
 UPDATE books 
-SET title = $1, author = $2, updated_at = NOW() 
-WHERE id = $3 AND user_id = $4 
-RETURNING id, title, author, created_at, updated_at, user_id
+SET title = $1, author = $2, loan_period_days = COALESCE($3, loan_period_days),
+    isbn = COALESCE($4, isbn), publisher = COALESCE($5, publisher), published_year = COALESCE($6, published_year),
+    page_count = COALESCE($7, page_count), cover_url = COALESCE($8, cover_url),
+    visibility = COALESCE($9, visibility), lendable = COALESCE($10, lendable), borrower_policy = COALESCE($11, borrower_policy),
+    min_borrower_reputation = COALESCE($12, min_borrower_reputation), updated_at = NOW() 
+WHERE id = $13 AND user_id = $14 AND deleted_at IS NULL
+RETURNING id, title, author, created_at, updated_at, user_id, loan_period_days, isbn, publisher, published_year, page_count, cover_url, visibility, lendable, borrower_policy, cover_thumbnail_url, cover_keys, deleted_at, rating_count, rating_total, rating_average, min_borrower_reputation
 `
 
 type UpdateBookParams struct {
-	Title  string
-	Author string
-	ID     uuid.UUID
-	UserID uuid.UUID
+	Title                 string
+	Author                string
+	LoanPeriodDays        sql.NullInt32
+	Isbn                  sql.NullString
+	Publisher             sql.NullString
+	PublishedYear         sql.NullInt32
+	PageCount             sql.NullInt32
+	CoverUrl              sql.NullString
+	Visibility            sql.NullString
+	Lendable              sql.NullBool
+	BorrowerPolicy        sql.NullString
+	MinBorrowerReputation sql.NullInt32
+	ID                    uuid.UUID
+	UserID                uuid.UUID
 }
 
 func (q *Queries) UpdateBook(ctx context.Context, arg UpdateBookParams) (Book, error) {
 	row := q.db.QueryRowContext(ctx, updateBook,
 		arg.Title,
 		arg.Author,
+		arg.LoanPeriodDays,
+		arg.Isbn,
+		arg.Publisher,
+		arg.PublishedYear,
+		arg.PageCount,
+		arg.CoverUrl,
+		arg.Visibility,
+		arg.Lendable,
+		arg.BorrowerPolicy,
+		arg.MinBorrowerReputation,
 		arg.ID,
 		arg.UserID,
 	)
@@ -180,6 +735,22 @@ func (q *Queries) UpdateBook(ctx context.Context, arg UpdateBookParams) (Book, e
 		&i.CreatedAt,
 		&i.UpdatedAt,
 		&i.UserID,
+		&i.LoanPeriodDays,
+		&i.Isbn,
+		&i.Publisher,
+		&i.PublishedYear,
+		&i.PageCount,
+		&i.CoverUrl,
+		&i.Visibility,
+		&i.Lendable,
+		&i.BorrowerPolicy,
+		&i.CoverThumbnailUrl,
+		pq.Array(&i.CoverKeys),
+		&i.DeletedAt,
+		&i.RatingCount,
+		&i.RatingTotal,
+		&i.RatingAverage,
+		&i.MinBorrowerReputation,
 	)
 	return i, err
 }
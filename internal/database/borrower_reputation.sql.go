@@ -0,0 +1,73 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: borrower_reputation.sql
+
+package database
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+)
+
+const createBorrowerRating = `-- name: CreateBorrowerRating :one
+INSERT INTO borrower_ratings (id, rating, comment, created_at, book_borrow_id, lender_id, borrower_id)
+VALUES ($1, $2, $3, NOW(), $4, $5, $6)
+ON CONFLICT (book_borrow_id) DO NOTHING
+RETURNING id, rating, comment, created_at, book_borrow_id, lender_id, borrower_id
+`
+
+type CreateBorrowerRatingParams struct {
+	ID           uuid.UUID
+	Rating       int32
+	Comment      string
+	BookBorrowID uuid.UUID
+	LenderID     uuid.UUID
+	BorrowerID   uuid.UUID
+}
+
+// The owner rates the borrower once per loan, a second rating returns no rows.
+func (q *Queries) CreateBorrowerRating(ctx context.Context, arg CreateBorrowerRatingParams) (BorrowerRating, error) {
+	row := q.db.QueryRowContext(ctx, createBorrowerRating,
+		arg.ID,
+		arg.Rating,
+		arg.Comment,
+		arg.BookBorrowID,
+		arg.LenderID,
+		arg.BorrowerID,
+	)
+	var i BorrowerRating
+	err := row.Scan(
+		&i.ID,
+		&i.Rating,
+		&i.Comment,
+		&i.CreatedAt,
+		&i.BookBorrowID,
+		&i.LenderID,
+		&i.BorrowerID,
+	)
+	return i, err
+}
+
+const getBorrowerReputationStats = `-- name: GetBorrowerReputationStats :one
+SELECT borrower_id, scored_loans, on_time_returns, late_loans, overdue_days, capped_overdue_days, disputed_returns, lost_books, rating_count, rating_average FROM borrower_reputation_stats WHERE borrower_id = $1
+`
+
+func (q *Queries) GetBorrowerReputationStats(ctx context.Context, borrowerID uuid.UUID) (BorrowerReputationStat, error) {
+	row := q.db.QueryRowContext(ctx, getBorrowerReputationStats, borrowerID)
+	var i BorrowerReputationStat
+	err := row.Scan(
+		&i.BorrowerID,
+		&i.ScoredLoans,
+		&i.OnTimeReturns,
+		&i.LateLoans,
+		&i.OverdueDays,
+		&i.CappedOverdueDays,
+		&i.DisputedReturns,
+		&i.LostBooks,
+		&i.RatingCount,
+		&i.RatingAverage,
+	)
+	return i, err
+}
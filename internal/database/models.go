@@ -11,33 +11,238 @@ import (
 	"github.com/google/uuid"
 )
 
+type ApprovedBorrower struct {
+	UserID     uuid.UUID
+	BorrowerID uuid.UUID
+	CreatedAt  time.Time
+}
+
 type Book struct {
+	ID                    uuid.UUID
+	Title                 string
+	Author                string
+	CreatedAt             time.Time
+	UpdatedAt             time.Time
+	UserID                uuid.UUID
+	LoanPeriodDays        int32
+	Isbn                  string
+	Publisher             string
+	PublishedYear         int32
+	PageCount             int32
+	CoverUrl              string
+	Visibility            string
+	Lendable              bool
+	BorrowerPolicy        string
+	CoverThumbnailUrl     string
+	CoverKeys             []string
+	DeletedAt             sql.NullTime
+	RatingCount           int32
+	RatingTotal           int32
+	RatingAverage         float64
+	MinBorrowerReputation int32
+}
+
+type BookAvailability struct {
+	BookID                   uuid.UUID
+	TotalCopies              int64
+	AvailableCopies          int64
+	QueueLength              int64
+	CurrentBorrowerID        uuid.NullUUID
+	CurrentBorrowerFirstName sql.NullString
+	CurrentBorrowerLastName  sql.NullString
+	DueAt                    sql.NullTime
+}
+
+type BookBorrow struct {
+	ID                uuid.UUID
+	IssuedAt          time.Time
+	ReturnedAt        sql.NullTime
+	CreatedAt         time.Time
+	UpdatedAt         time.Time
+	BookID            uuid.UUID
+	BorrowerID        uuid.UUID
+	DueAt             time.Time
+	ReturnStatus      sql.NullString
+	ReturnConfirmedAt sql.NullTime
+	ReturnDisputedAt  sql.NullTime
+	BookCopyID        uuid.UUID
+	LostAt            sql.NullTime
+}
+
+type BookBorrowReminder struct {
+	ID           uuid.UUID
+	Threshold    string
+	SentAt       time.Time
+	BookBorrowID uuid.UUID
+	DueAt        time.Time
+}
+
+type BookBorrowRenewal struct {
+	ID            uuid.UUID
+	PreviousDueAt time.Time
+	NewDueAt      time.Time
+	CreatedAt     time.Time
+	BookBorrowID  uuid.UUID
+}
+
+type BookBorrowRequest struct {
+	ID           uuid.UUID
+	Status       string
+	RespondedAt  sql.NullTime
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+	BookID       uuid.UUID
+	RequesterID  uuid.UUID
+	BookBorrowID uuid.NullUUID
+}
+
+type BookClaim struct {
+	ID             uuid.UUID
+	Kind           string
+	Status         string
+	Description    string
+	Resolution     sql.NullString
+	ResolutionNote string
+	ResolvedAt     sql.NullTime
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+	BookBorrowID   uuid.UUID
+	OpenedBy       uuid.UUID
+}
+
+type BookClaimComment struct {
+	ID          uuid.UUID
+	Body        string
+	CreatedAt   time.Time
+	BookClaimID uuid.UUID
+	AuthorID    uuid.UUID
+}
+
+type BookConditionReport struct {
+	ID           uuid.UUID
+	Stage        string
+	Condition    string
+	Notes        string
+	PhotoUrl     string
+	PhotoKey     string
+	CreatedAt    time.Time
+	BookBorrowID uuid.UUID
+	ReporterID   uuid.UUID
+}
+
+type BookCopy struct {
 	ID        uuid.UUID
-	Title     string
-	Author    string
+	Condition string
 	CreatedAt time.Time
 	UpdatedAt time.Time
-	UserID    uuid.UUID
+	BookID    uuid.UUID
+	RetiredAt sql.NullTime
 }
 
-type BookBorrow struct {
+type BookGenre struct {
+	BookID uuid.UUID
+	Genre  string
+}
+
+type BookReservation struct {
+	ID             uuid.UUID
+	Status         string
+	OfferedAt      sql.NullTime
+	OfferExpiresAt sql.NullTime
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+	BookID         uuid.UUID
+	UserID         uuid.UUID
+}
+
+type BookReview struct {
 	ID         uuid.UUID
-	IssuedAt   time.Time
-	ReturnedAt sql.NullTime
+	Rating     int32
+	Body       string
+	Reply      string
+	RepliedAt  sql.NullTime
 	CreatedAt  time.Time
 	UpdatedAt  time.Time
 	BookID     uuid.UUID
-	BorrowerID uuid.UUID
+	ReviewerID uuid.UUID
+}
+
+type BookTag struct {
+	BookID uuid.UUID
+	Tag    string
+}
+
+type BorrowerRating struct {
+	ID           uuid.UUID
+	Rating       int32
+	Comment      string
+	CreatedAt    time.Time
+	BookBorrowID uuid.UUID
+	LenderID     uuid.UUID
+	BorrowerID   uuid.UUID
+}
+
+type BorrowerReputationStat struct {
+	BorrowerID        uuid.UUID
+	ScoredLoans       int32
+	OnTimeReturns     int32
+	LateLoans         int32
+	OverdueDays       int32
+	CappedOverdueDays int32
+	DisputedReturns   int32
+	LostBooks         int32
+	RatingCount       int32
+	RatingAverage     float64
+}
+
+type Genre struct {
+	Slug string
+	Name string
+}
+
+type NotificationOutbox struct {
+	ID            uuid.UUID
+	Status        string
+	Sender        string
+	Recipient     string
+	Subject       string
+	Body          string
+	Attempts      int32
+	LastError     sql.NullString
+	NextAttemptAt time.Time
+	SentAt        sql.NullTime
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+	HtmlBody      string
+}
+
+type Shelf struct {
+	ID          uuid.UUID
+	Name        string
+	Description string
+	IsPublic    bool
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	UserID      uuid.UUID
+}
+
+type ShelfBook struct {
+	ShelfID  uuid.UUID
+	BookID   uuid.UUID
+	Position int32
+	AddedAt  time.Time
 }
 
 type User struct {
-	ID        uuid.UUID
-	FirstName string
-	LastName  string
-	Email     string
-	Password  string
-	CreatedAt time.Time
-	UpdatedAt time.Time
+	ID                    uuid.UUID
+	FirstName             string
+	LastName              string
+	Email                 string
+	Password              string
+	CreatedAt             time.Time
+	UpdatedAt             time.Time
+	Locale                string
+	DefaultLoanPeriodDays int32
 }
 
 type UserSubscriber struct {
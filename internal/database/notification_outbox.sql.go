@@ -0,0 +1,237 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: notification_outbox.sql
+
+package database
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const claimDueOutboxNotifications = `-- name: ClaimDueOutboxNotifications :many
+UPDATE notification_outbox
+SET next_attempt_at = $1::timestamp, updated_at = NOW()
+WHERE id IN (
+    SELECT id FROM notification_outbox
+    WHERE status = 'pending' AND next_attempt_at <= NOW()
+    ORDER BY next_attempt_at
+    LIMIT $2::int
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING id, status, sender, recipient, subject, body, attempts, last_error, next_attempt_at, sent_at, created_at, updated_at, html_body
+`
+
+type ClaimDueOutboxNotificationsParams struct {
+	LeaseUntil time.Time
+	BatchSize  int32
+}
+
+// Leases due notifications to one worker by pushing next_attempt_at out, so another
+// worker polling at the same time skips them.
+func (q *Queries) ClaimDueOutboxNotifications(ctx context.Context, arg ClaimDueOutboxNotificationsParams) ([]NotificationOutbox, error) {
+	rows, err := q.db.QueryContext(ctx, claimDueOutboxNotifications, arg.LeaseUntil, arg.BatchSize)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []NotificationOutbox
+	for rows.Next() {
+		var i NotificationOutbox
+		if err := rows.Scan(
+			&i.ID,
+			&i.Status,
+			&i.Sender,
+			&i.Recipient,
+			&i.Subject,
+			&i.Body,
+			&i.Attempts,
+			&i.LastError,
+			&i.NextAttemptAt,
+			&i.SentAt,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.HtmlBody,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const createOutboxNotification = `-- name: CreateOutboxNotification :one
+INSERT INTO notification_outbox (id, sender, recipient, subject, body, html_body, next_attempt_at, created_at, updated_at)
+VALUES ($1, $2, $3, $4, $5, $6, NOW(), NOW(), NOW())
+RETURNING id, status, sender, recipient, subject, body, attempts, last_error, next_attempt_at, sent_at, created_at, updated_at, html_body
+`
+
+type CreateOutboxNotificationParams struct {
+	ID        uuid.UUID
+	Sender    string
+	Recipient string
+	Subject   string
+	Body      string
+	HtmlBody  string
+}
+
+func (q *Queries) CreateOutboxNotification(ctx context.Context, arg CreateOutboxNotificationParams) (NotificationOutbox, error) {
+	row := q.db.QueryRowContext(ctx, createOutboxNotification,
+		arg.ID,
+		arg.Sender,
+		arg.Recipient,
+		arg.Subject,
+		arg.Body,
+		arg.HtmlBody,
+	)
+	var i NotificationOutbox
+	err := row.Scan(
+		&i.ID,
+		&i.Status,
+		&i.Sender,
+		&i.Recipient,
+		&i.Subject,
+		&i.Body,
+		&i.Attempts,
+		&i.LastError,
+		&i.NextAttemptAt,
+		&i.SentAt,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.HtmlBody,
+	)
+	return i, err
+}
+
+const getOutboxNotifications = `-- name: GetOutboxNotifications :many
+SELECT id, status, sender, recipient, subject, body, attempts, last_error, next_attempt_at, sent_at, created_at, updated_at, html_body FROM notification_outbox
+WHERE $1::text IS NULL OR status = $1::text
+ORDER BY created_at DESC
+LIMIT 100
+`
+
+func (q *Queries) GetOutboxNotifications(ctx context.Context, status sql.NullString) ([]NotificationOutbox, error) {
+	rows, err := q.db.QueryContext(ctx, getOutboxNotifications, status)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []NotificationOutbox
+	for rows.Next() {
+		var i NotificationOutbox
+		if err := rows.Scan(
+			&i.ID,
+			&i.Status,
+			&i.Sender,
+			&i.Recipient,
+			&i.Subject,
+			&i.Body,
+			&i.Attempts,
+			&i.LastError,
+			&i.NextAttemptAt,
+			&i.SentAt,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.HtmlBody,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const markOutboxNotificationSent = `-- name: MarkOutboxNotificationSent :exec
+UPDATE notification_outbox
+SET status = 'sent', attempts = attempts + 1, last_error = NULL, sent_at = NOW(), updated_at = NOW()
+WHERE id = $1
+`
+
+func (q *Queries) MarkOutboxNotificationSent(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, markOutboxNotificationSent, id)
+	return err
+}
+
+const recordOutboxNotificationFailure = `-- name: RecordOutboxNotificationFailure :one
+UPDATE notification_outbox
+SET status = $1, attempts = attempts + 1, last_error = $2, next_attempt_at = $3, updated_at = NOW()
+WHERE id = $4
+RETURNING id, status, sender, recipient, subject, body, attempts, last_error, next_attempt_at, sent_at, created_at, updated_at, html_body
+`
+
+type RecordOutboxNotificationFailureParams struct {
+	Status        string
+	LastError     sql.NullString
+	NextAttemptAt time.Time
+	ID            uuid.UUID
+}
+
+func (q *Queries) RecordOutboxNotificationFailure(ctx context.Context, arg RecordOutboxNotificationFailureParams) (NotificationOutbox, error) {
+	row := q.db.QueryRowContext(ctx, recordOutboxNotificationFailure,
+		arg.Status,
+		arg.LastError,
+		arg.NextAttemptAt,
+		arg.ID,
+	)
+	var i NotificationOutbox
+	err := row.Scan(
+		&i.ID,
+		&i.Status,
+		&i.Sender,
+		&i.Recipient,
+		&i.Subject,
+		&i.Body,
+		&i.Attempts,
+		&i.LastError,
+		&i.NextAttemptAt,
+		&i.SentAt,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.HtmlBody,
+	)
+	return i, err
+}
+
+const replayOutboxNotification = `-- name: ReplayOutboxNotification :one
+UPDATE notification_outbox
+SET status = 'pending', attempts = 0, last_error = NULL, next_attempt_at = NOW(), updated_at = NOW()
+WHERE id = $1 AND status = 'dead'
+RETURNING id, status, sender, recipient, subject, body, attempts, last_error, next_attempt_at, sent_at, created_at, updated_at, html_body
+`
+
+func (q *Queries) ReplayOutboxNotification(ctx context.Context, id uuid.UUID) (NotificationOutbox, error) {
+	row := q.db.QueryRowContext(ctx, replayOutboxNotification, id)
+	var i NotificationOutbox
+	err := row.Scan(
+		&i.ID,
+		&i.Status,
+		&i.Sender,
+		&i.Recipient,
+		&i.Subject,
+		&i.Body,
+		&i.Attempts,
+		&i.LastError,
+		&i.NextAttemptAt,
+		&i.SentAt,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.HtmlBody,
+	)
+	return i, err
+}
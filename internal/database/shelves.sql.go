@@ -0,0 +1,398 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: shelves.sql
+
+package database
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+const addShelfBook = `-- name: AddShelfBook :one
+INSERT INTO shelf_books (shelf_id, book_id, position, added_at)
+SELECT shelves.id, books.id, COALESCE((SELECT MAX(position) FROM shelf_books WHERE shelf_books.shelf_id = shelves.id), 0) + 1, NOW()
+FROM shelves
+INNER JOIN books ON books.user_id = shelves.user_id
+WHERE shelves.id = $1 AND books.id = $2 AND shelves.user_id = $3 AND books.deleted_at IS NULL
+ON CONFLICT (shelf_id, book_id) DO UPDATE SET position = shelf_books.position
+RETURNING shelf_id, book_id, position, added_at
+`
+
+type AddShelfBookParams struct {
+	ShelfID uuid.UUID
+	BookID  uuid.UUID
+	UserID  uuid.UUID
+}
+
+// Only the shelf owner's own books go on it, new ones at the end. Adding a
+// book that is already there leaves it where it is.
+func (q *Queries) AddShelfBook(ctx context.Context, arg AddShelfBookParams) (ShelfBook, error) {
+	row := q.db.QueryRowContext(ctx, addShelfBook, arg.ShelfID, arg.BookID, arg.UserID)
+	var i ShelfBook
+	err := row.Scan(
+		&i.ShelfID,
+		&i.BookID,
+		&i.Position,
+		&i.AddedAt,
+	)
+	return i, err
+}
+
+const createShelf = `-- name: CreateShelf :one
+INSERT INTO shelves (id, name, description, is_public, created_at, updated_at, user_id)
+VALUES ($1, $2, $3, $4, NOW(), NOW(), $5)
+RETURNING id, name, description, is_public, created_at, updated_at, user_id
+`
+
+type CreateShelfParams struct {
+	ID          uuid.UUID
+	Name        string
+	Description string
+	IsPublic    bool
+	UserID      uuid.UUID
+}
+
+func (q *Queries) CreateShelf(ctx context.Context, arg CreateShelfParams) (Shelf, error) {
+	row := q.db.QueryRowContext(ctx, createShelf,
+		arg.ID,
+		arg.Name,
+		arg.Description,
+		arg.IsPublic,
+		arg.UserID,
+	)
+	var i Shelf
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.Description,
+		&i.IsPublic,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.UserID,
+	)
+	return i, err
+}
+
+const deleteShelf = `-- name: DeleteShelf :execrows
+DELETE FROM shelves WHERE id = $1 AND user_id = $2
+`
+
+type DeleteShelfParams struct {
+	ID     uuid.UUID
+	UserID uuid.UUID
+}
+
+func (q *Queries) DeleteShelf(ctx context.Context, arg DeleteShelfParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteShelf, arg.ID, arg.UserID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const getShelf = `-- name: GetShelf :one
+SELECT shelves.id, shelves.name, shelves.description, shelves.is_public, shelves.created_at, shelves.updated_at, shelves.user_id, (SELECT COUNT(*) FROM shelf_books INNER JOIN books ON books.id = shelf_books.book_id WHERE shelf_books.shelf_id = shelves.id AND books.deleted_at IS NULL
+    AND (books.visibility = 'public' OR books.user_id = $1::uuid OR (books.visibility = 'subscribers' AND EXISTS (SELECT 1 FROM user_subscribers WHERE user_subscribers.user_id = books.user_id AND user_subscribers.subscriber_id = $1::uuid)))) AS book_count
+FROM shelves
+WHERE shelves.id = $2
+`
+
+type GetShelfParams struct {
+	ViewerID uuid.UUID
+	ID       uuid.UUID
+}
+
+type GetShelfRow struct {
+	Shelf     Shelf
+	BookCount int64
+}
+
+// The book count only takes in the books the viewer can see, like GetShelfBooks.
+func (q *Queries) GetShelf(ctx context.Context, arg GetShelfParams) (GetShelfRow, error) {
+	row := q.db.QueryRowContext(ctx, getShelf, arg.ViewerID, arg.ID)
+	var i GetShelfRow
+	err := row.Scan(
+		&i.Shelf.ID,
+		&i.Shelf.Name,
+		&i.Shelf.Description,
+		&i.Shelf.IsPublic,
+		&i.Shelf.CreatedAt,
+		&i.Shelf.UpdatedAt,
+		&i.Shelf.UserID,
+		&i.BookCount,
+	)
+	return i, err
+}
+
+const getShelfBookIDs = `-- name: GetShelfBookIDs :many
+SELECT shelf_books.book_id FROM shelf_books
+INNER JOIN books ON books.id = shelf_books.book_id
+WHERE shelf_books.shelf_id = $1 AND books.deleted_at IS NULL
+`
+
+// Books in the trash keep their place but are left out of the order.
+func (q *Queries) GetShelfBookIDs(ctx context.Context, shelfID uuid.UUID) ([]uuid.UUID, error) {
+	rows, err := q.db.QueryContext(ctx, getShelfBookIDs, shelfID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []uuid.UUID
+	for rows.Next() {
+		var book_id uuid.UUID
+		if err := rows.Scan(&book_id); err != nil {
+			return nil, err
+		}
+		items = append(items, book_id)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getShelfBooks = `-- name: GetShelfBooks :many
+SELECT shelf_books.shelf_id, shelf_books.book_id, shelf_books.position, shelf_books.added_at, books.id, books.title, books.author, books.created_at, books.updated_at, books.user_id, books.loan_period_days, books.isbn, books.publisher, books.published_year, books.page_count, books.cover_url, books.visibility, books.lendable, books.borrower_policy, books.cover_thumbnail_url, books.cover_keys, books.deleted_at, books.rating_count, books.rating_total, books.rating_average, books.min_borrower_reputation,
+    ARRAY(SELECT genre FROM book_genres WHERE book_genres.book_id = books.id ORDER BY genre)::text[] AS genres,
+    ARRAY(SELECT tag FROM book_tags WHERE book_tags.book_id = books.id ORDER BY tag)::text[] AS tags
+FROM shelf_books
+INNER JOIN books ON books.id = shelf_books.book_id
+WHERE shelf_books.shelf_id = $1 AND books.deleted_at IS NULL
+AND (books.visibility = 'public' OR books.user_id = $2::uuid OR (books.visibility = 'subscribers' AND EXISTS (SELECT 1 FROM user_subscribers WHERE user_subscribers.user_id = books.user_id AND user_subscribers.subscriber_id = $2::uuid)))
+AND ($3::uuid IS NULL OR (shelf_books.position, books.id) > ($4::int, $3::uuid))
+ORDER BY shelf_books.position, books.id
+LIMIT $5::int
+`
+
+type GetShelfBooksParams struct {
+	ShelfID        uuid.UUID
+	ViewerID       uuid.UUID
+	CursorID       uuid.NullUUID
+	CursorPosition int32
+	PageLimit      int32
+}
+
+type GetShelfBooksRow struct {
+	ShelfBook ShelfBook
+	Book      Book
+	Genres    []string
+	Tags      []string
+}
+
+// Private books are only listed for their owner, subscriber-only ones for the owner's subscribers too.
+func (q *Queries) GetShelfBooks(ctx context.Context, arg GetShelfBooksParams) ([]GetShelfBooksRow, error) {
+	rows, err := q.db.QueryContext(ctx, getShelfBooks,
+		arg.ShelfID,
+		arg.ViewerID,
+		arg.CursorID,
+		arg.CursorPosition,
+		arg.PageLimit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetShelfBooksRow
+	for rows.Next() {
+		var i GetShelfBooksRow
+		if err := rows.Scan(
+			&i.ShelfBook.ShelfID,
+			&i.ShelfBook.BookID,
+			&i.ShelfBook.Position,
+			&i.ShelfBook.AddedAt,
+			&i.Book.ID,
+			&i.Book.Title,
+			&i.Book.Author,
+			&i.Book.CreatedAt,
+			&i.Book.UpdatedAt,
+			&i.Book.UserID,
+			&i.Book.LoanPeriodDays,
+			&i.Book.Isbn,
+			&i.Book.Publisher,
+			&i.Book.PublishedYear,
+			&i.Book.PageCount,
+			&i.Book.CoverUrl,
+			&i.Book.Visibility,
+			&i.Book.Lendable,
+			&i.Book.BorrowerPolicy,
+			&i.Book.CoverThumbnailUrl,
+			pq.Array(&i.Book.CoverKeys),
+			&i.Book.DeletedAt,
+			&i.Book.RatingCount,
+			&i.Book.RatingTotal,
+			&i.Book.RatingAverage,
+			&i.Book.MinBorrowerReputation,
+			pq.Array(&i.Genres),
+			pq.Array(&i.Tags),
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getShelfByName = `-- name: GetShelfByName :one
+SELECT id, name, description, is_public, created_at, updated_at, user_id FROM shelves WHERE user_id = $1 AND lower(name) = lower($2)
+`
+
+type GetShelfByNameParams struct {
+	UserID uuid.UUID
+	Name   string
+}
+
+func (q *Queries) GetShelfByName(ctx context.Context, arg GetShelfByNameParams) (Shelf, error) {
+	row := q.db.QueryRowContext(ctx, getShelfByName, arg.UserID, arg.Name)
+	var i Shelf
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.Description,
+		&i.IsPublic,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.UserID,
+	)
+	return i, err
+}
+
+const getShelvesByUserID = `-- name: GetShelvesByUserID :many
+SELECT shelves.id, shelves.name, shelves.description, shelves.is_public, shelves.created_at, shelves.updated_at, shelves.user_id, (SELECT COUNT(*) FROM shelf_books INNER JOIN books ON books.id = shelf_books.book_id WHERE shelf_books.shelf_id = shelves.id AND books.deleted_at IS NULL
+    AND (books.visibility = 'public' OR books.user_id = $1::uuid OR (books.visibility = 'subscribers' AND EXISTS (SELECT 1 FROM user_subscribers WHERE user_subscribers.user_id = books.user_id AND user_subscribers.subscriber_id = $1::uuid)))) AS book_count
+FROM shelves
+WHERE shelves.user_id = $2 AND ($3::boolean OR shelves.is_public)
+ORDER BY lower(shelves.name), shelves.id
+`
+
+type GetShelvesByUserIDParams struct {
+	ViewerID       uuid.UUID
+	UserID         uuid.UUID
+	IncludePrivate bool
+}
+
+type GetShelvesByUserIDRow struct {
+	Shelf     Shelf
+	BookCount int64
+}
+
+// Counted for the viewer, like GetShelf.
+func (q *Queries) GetShelvesByUserID(ctx context.Context, arg GetShelvesByUserIDParams) ([]GetShelvesByUserIDRow, error) {
+	rows, err := q.db.QueryContext(ctx, getShelvesByUserID, arg.ViewerID, arg.UserID, arg.IncludePrivate)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetShelvesByUserIDRow
+	for rows.Next() {
+		var i GetShelvesByUserIDRow
+		if err := rows.Scan(
+			&i.Shelf.ID,
+			&i.Shelf.Name,
+			&i.Shelf.Description,
+			&i.Shelf.IsPublic,
+			&i.Shelf.CreatedAt,
+			&i.Shelf.UpdatedAt,
+			&i.Shelf.UserID,
+			&i.BookCount,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const removeShelfBook = `-- name: RemoveShelfBook :execrows
+DELETE FROM shelf_books
+USING shelves
+WHERE shelf_books.shelf_id = shelves.id AND shelves.id = $1 AND shelf_books.book_id = $2 AND shelves.user_id = $3
+`
+
+type RemoveShelfBookParams struct {
+	ShelfID uuid.UUID
+	BookID  uuid.UUID
+	UserID  uuid.UUID
+}
+
+func (q *Queries) RemoveShelfBook(ctx context.Context, arg RemoveShelfBookParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, removeShelfBook, arg.ShelfID, arg.BookID, arg.UserID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const reorderShelfBooks = `-- name: ReorderShelfBooks :exec
+UPDATE shelf_books
+SET position = ordered_books.position
+FROM unnest($2::uuid[]) WITH ORDINALITY AS ordered_books(book_id, position)
+WHERE shelf_books.shelf_id = $1 AND shelf_books.book_id = ordered_books.book_id
+`
+
+type ReorderShelfBooksParams struct {
+	ShelfID uuid.UUID
+	BookIds []uuid.UUID
+}
+
+// Numbers the books 1, 2, 3... in the order given.
+func (q *Queries) ReorderShelfBooks(ctx context.Context, arg ReorderShelfBooksParams) error {
+	_, err := q.db.ExecContext(ctx, reorderShelfBooks, arg.ShelfID, pq.Array(arg.BookIds))
+	return err
+}
+
+const updateShelf = `-- name: UpdateShelf :one
+UPDATE shelves
+SET name = COALESCE($1, name), description = COALESCE($2, description),
+    is_public = COALESCE($3, is_public), updated_at = NOW()
+WHERE id = $4 AND user_id = $5
+RETURNING id, name, description, is_public, created_at, updated_at, user_id
+`
+
+type UpdateShelfParams struct {
+	Name        sql.NullString
+	Description sql.NullString
+	IsPublic    sql.NullBool
+	ID          uuid.UUID
+	UserID      uuid.UUID
+}
+
+func (q *Queries) UpdateShelf(ctx context.Context, arg UpdateShelfParams) (Shelf, error) {
+	row := q.db.QueryRowContext(ctx, updateShelf,
+		arg.Name,
+		arg.Description,
+		arg.IsPublic,
+		arg.ID,
+		arg.UserID,
+	)
+	var i Shelf
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.Description,
+		&i.IsPublic,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.UserID,
+	)
+	return i, err
+}
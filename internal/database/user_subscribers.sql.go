@@ -7,6 +7,7 @@ package database
 
 import (
 	"context"
+	"time"
 
 	"github.com/google/uuid"
 )
@@ -76,11 +77,27 @@ func (q *Queries) GetUserSubscriber(ctx context.Context, arg GetUserSubscriberPa
 }
 
 const getUserSubscribers = `-- name: GetUserSubscribers :many
-SELECT id, created_at, updated_at, user_id, subscriber_id FROM user_subscribers WHERE user_id = $1
+SELECT id, created_at, updated_at, user_id, subscriber_id FROM user_subscribers
+WHERE user_id = $1
+AND ($2::uuid IS NULL OR (created_at, id) < ($3::timestamp, $2::uuid))
+ORDER BY created_at DESC, id DESC
+LIMIT $4::int
 `
 
-func (q *Queries) GetUserSubscribers(ctx context.Context, userID uuid.UUID) ([]UserSubscriber, error) {
-	rows, err := q.db.QueryContext(ctx, getUserSubscribers, userID)
+type GetUserSubscribersParams struct {
+	UserID          uuid.UUID
+	CursorID        uuid.NullUUID
+	CursorCreatedAt time.Time
+	PageLimit       int32
+}
+
+func (q *Queries) GetUserSubscribers(ctx context.Context, arg GetUserSubscribersParams) ([]UserSubscriber, error) {
+	rows, err := q.db.QueryContext(ctx, getUserSubscribers,
+		arg.UserID,
+		arg.CursorID,
+		arg.CursorCreatedAt,
+		arg.PageLimit,
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -109,11 +126,27 @@ func (q *Queries) GetUserSubscribers(ctx context.Context, userID uuid.UUID) ([]U
 }
 
 const getUserSubscriptions = `-- name: GetUserSubscriptions :many
-SELECT id, created_at, updated_at, user_id, subscriber_id FROM user_subscribers WHERE subscriber_id = $1
+SELECT id, created_at, updated_at, user_id, subscriber_id FROM user_subscribers
+WHERE subscriber_id = $1
+AND ($2::uuid IS NULL OR (created_at, id) < ($3::timestamp, $2::uuid))
+ORDER BY created_at DESC, id DESC
+LIMIT $4::int
 `
 
-func (q *Queries) GetUserSubscriptions(ctx context.Context, subscriberID uuid.UUID) ([]UserSubscriber, error) {
-	rows, err := q.db.QueryContext(ctx, getUserSubscriptions, subscriberID)
+type GetUserSubscriptionsParams struct {
+	SubscriberID    uuid.UUID
+	CursorID        uuid.NullUUID
+	CursorCreatedAt time.Time
+	PageLimit       int32
+}
+
+func (q *Queries) GetUserSubscriptions(ctx context.Context, arg GetUserSubscriptionsParams) ([]UserSubscriber, error) {
+	rows, err := q.db.QueryContext(ctx, getUserSubscriptions,
+		arg.SubscriberID,
+		arg.CursorID,
+		arg.CursorCreatedAt,
+		arg.PageLimit,
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -13,9 +13,9 @@ import (
 )
 
 const createUser = `-- name: CreateUser :one
-INSERT INTO users (id, first_name, last_name, email, password, created_at, updated_at)
-VALUES ($1, $2, $3, $4, $5, $6, $7)
-RETURNING id, first_name, last_name, email, password, created_at, updated_at
+INSERT INTO users (id, first_name, last_name, email, password, created_at, updated_at, locale)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+RETURNING id, first_name, last_name, email, password, created_at, updated_at, locale, default_loan_period_days
 `
 
 type CreateUserParams struct {
@@ -26,6 +26,7 @@ type CreateUserParams struct {
 	Password  string
 	CreatedAt time.Time
 	UpdatedAt time.Time
+	Locale    string
 }
 
 func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, error) {
@@ -37,6 +38,7 @@ func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, e
 		arg.Password,
 		arg.CreatedAt,
 		arg.UpdatedAt,
+		arg.Locale,
 	)
 	var i User
 	err := row.Scan(
@@ -47,12 +49,14 @@ func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, e
 		&i.Password,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.Locale,
+		&i.DefaultLoanPeriodDays,
 	)
 	return i, err
 }
 
 const getUserByEmail = `-- name: GetUserByEmail :one
-SELECT id, first_name, last_name, email, password, created_at, updated_at FROM users WHERE email = $1
+SELECT id, first_name, last_name, email, password, created_at, updated_at, locale, default_loan_period_days FROM users WHERE email = $1
 `
 
 func (q *Queries) GetUserByEmail(ctx context.Context, email string) (User, error) {
@@ -66,12 +70,14 @@ func (q *Queries) GetUserByEmail(ctx context.Context, email string) (User, error
 		&i.Password,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.Locale,
+		&i.DefaultLoanPeriodDays,
 	)
 	return i, err
 }
 
 const getUserByID = `-- name: GetUserByID :one
-SELECT id, first_name, last_name, email, password, created_at, updated_at FROM users WHERE id = $1
+SELECT id, first_name, last_name, email, password, created_at, updated_at, locale, default_loan_period_days FROM users WHERE id = $1
 `
 
 func (q *Queries) GetUserByID(ctx context.Context, id uuid.UUID) (User, error) {
@@ -85,12 +91,53 @@ func (q *Queries) GetUserByID(ctx context.Context, id uuid.UUID) (User, error) {
 		&i.Password,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.Locale,
+		&i.DefaultLoanPeriodDays,
+	)
+	return i, err
+}
+
+const getUserProfile = `-- name: GetUserProfile :one
+SELECT users.id, users.first_name, users.last_name, users.email, users.password, users.created_at, users.updated_at, users.locale, users.default_loan_period_days, borrower_reputation_stats.borrower_id, borrower_reputation_stats.scored_loans, borrower_reputation_stats.on_time_returns, borrower_reputation_stats.late_loans, borrower_reputation_stats.overdue_days, borrower_reputation_stats.capped_overdue_days, borrower_reputation_stats.disputed_returns, borrower_reputation_stats.lost_books, borrower_reputation_stats.rating_count, borrower_reputation_stats.rating_average
+FROM users
+INNER JOIN borrower_reputation_stats ON borrower_reputation_stats.borrower_id = users.id
+WHERE users.id = $1
+`
+
+type GetUserProfileRow struct {
+	User                   User
+	BorrowerReputationStat BorrowerReputationStat
+}
+
+func (q *Queries) GetUserProfile(ctx context.Context, id uuid.UUID) (GetUserProfileRow, error) {
+	row := q.db.QueryRowContext(ctx, getUserProfile, id)
+	var i GetUserProfileRow
+	err := row.Scan(
+		&i.User.ID,
+		&i.User.FirstName,
+		&i.User.LastName,
+		&i.User.Email,
+		&i.User.Password,
+		&i.User.CreatedAt,
+		&i.User.UpdatedAt,
+		&i.User.Locale,
+		&i.User.DefaultLoanPeriodDays,
+		&i.BorrowerReputationStat.BorrowerID,
+		&i.BorrowerReputationStat.ScoredLoans,
+		&i.BorrowerReputationStat.OnTimeReturns,
+		&i.BorrowerReputationStat.LateLoans,
+		&i.BorrowerReputationStat.OverdueDays,
+		&i.BorrowerReputationStat.CappedOverdueDays,
+		&i.BorrowerReputationStat.DisputedReturns,
+		&i.BorrowerReputationStat.LostBooks,
+		&i.BorrowerReputationStat.RatingCount,
+		&i.BorrowerReputationStat.RatingAverage,
 	)
 	return i, err
 }
 
 const getUsersBySubscriberID = `-- name: GetUsersBySubscriberID :many
-SELECT u.id, u.first_name, u.last_name, u.email, u.password, u.created_at, u.updated_at
+SELECT u.id, u.first_name, u.last_name, u.email, u.password, u.created_at, u.updated_at, u.locale, u.default_loan_period_days
 FROM users AS u
 LEFT JOIN user_subscribers AS us
 ON us.subscriber_id = u.ID
@@ -114,6 +161,8 @@ func (q *Queries) GetUsersBySubscriberID(ctx context.Context, userID uuid.UUID)
 			&i.Password,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.Locale,
+			&i.DefaultLoanPeriodDays,
 		); err != nil {
 			return nil, err
 		}
@@ -127,3 +176,59 @@ func (q *Queries) GetUsersBySubscriberID(ctx context.Context, userID uuid.UUID)
 	}
 	return items, nil
 }
+
+const updateUserDefaultLoanPeriod = `-- name: UpdateUserDefaultLoanPeriod :one
+UPDATE users SET default_loan_period_days = $1, updated_at = NOW()
+WHERE id = $2
+RETURNING id, first_name, last_name, email, password, created_at, updated_at, locale, default_loan_period_days
+`
+
+type UpdateUserDefaultLoanPeriodParams struct {
+	DefaultLoanPeriodDays int32
+	ID                    uuid.UUID
+}
+
+func (q *Queries) UpdateUserDefaultLoanPeriod(ctx context.Context, arg UpdateUserDefaultLoanPeriodParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, updateUserDefaultLoanPeriod, arg.DefaultLoanPeriodDays, arg.ID)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.FirstName,
+		&i.LastName,
+		&i.Email,
+		&i.Password,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Locale,
+		&i.DefaultLoanPeriodDays,
+	)
+	return i, err
+}
+
+const updateUserLocale = `-- name: UpdateUserLocale :one
+UPDATE users SET locale = $1, updated_at = NOW()
+WHERE id = $2
+RETURNING id, first_name, last_name, email, password, created_at, updated_at, locale, default_loan_period_days
+`
+
+type UpdateUserLocaleParams struct {
+	Locale string
+	ID     uuid.UUID
+}
+
+func (q *Queries) UpdateUserLocale(ctx context.Context, arg UpdateUserLocaleParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, updateUserLocale, arg.Locale, arg.ID)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.FirstName,
+		&i.LastName,
+		&i.Email,
+		&i.Password,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Locale,
+		&i.DefaultLoanPeriodDays,
+	)
+	return i, err
+}
@@ -1,15 +1,39 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
 
+	"github.com/elorenzorodz/co-library/blob_stores"
 	"github.com/elorenzorodz/co-library/book_borrows"
+	"github.com/elorenzorodz/co-library/book_claims"
+	"github.com/elorenzorodz/co-library/book_copies"
+	"github.com/elorenzorodz/co-library/book_covers"
+	"github.com/elorenzorodz/co-library/book_exports"
+	"github.com/elorenzorodz/co-library/book_imports"
+	"github.com/elorenzorodz/co-library/book_policies"
+	"github.com/elorenzorodz/co-library/book_reservations"
+	"github.com/elorenzorodz/co-library/book_reviews"
+	"github.com/elorenzorodz/co-library/book_tags"
+	"github.com/elorenzorodz/co-library/book_trash"
 	"github.com/elorenzorodz/co-library/books"
+	"github.com/elorenzorodz/co-library/borrower_reputation"
+	"github.com/elorenzorodz/co-library/catalog_providers"
 	"github.com/elorenzorodz/co-library/common"
 	"github.com/elorenzorodz/co-library/internal/database"
 	"github.com/elorenzorodz/co-library/middleware"
+	"github.com/elorenzorodz/co-library/notification_outbox"
+	"github.com/elorenzorodz/co-library/notification_templates"
+	"github.com/elorenzorodz/co-library/notifiers"
+	"github.com/elorenzorodz/co-library/scheduler"
+	"github.com/elorenzorodz/co-library/shelves"
 	"github.com/elorenzorodz/co-library/user_subscribers"
 	"github.com/elorenzorodz/co-library/users"
 	"github.com/gorilla/mux"
@@ -32,12 +56,41 @@ func main() {
 
 	database := database.New(dbConnection)
 
+	notifier, notifierError := notifiers.NewNotifier(envConfig)
+
+	if notifierError != nil {
+		log.Fatal("error setting up notifier:", notifierError)
+	}
+
+	templateRenderer, templateRendererError := notification_templates.NewRenderer(envConfig.TemplatesDir)
+
+	if templateRendererError != nil {
+		log.Fatal("error loading notification templates:", templateRendererError)
+	}
+
+	catalogProvider, catalogProviderError := catalog_providers.NewCatalogProvider(envConfig)
+
+	if catalogProviderError != nil {
+		log.Fatal("error setting up catalog provider:", catalogProviderError)
+	}
+
+	blobStore, blobStoreError := blob_stores.NewBlobStore(envConfig)
+
+	if blobStoreError != nil {
+		log.Fatal("error setting up blob store:", blobStoreError)
+	}
+
 	apiConfig := common.APIConfig {
 		DB: database,
+		Transactor: &common.SQLTransactor{DB: dbConnection},
 		JWTValidationKey: parsedPublicKey,
 		JWTSigningKey: parsedPrivateKey,
-		MailgunAPIKey: envConfig.MailgunAPIKey,
-		MailgunSendingDomain: envConfig.MailgunSendingDomain,
+		Notifier: notifier,
+		NotificationSender: notifiers.DefaultNotificationSender(envConfig),
+		AdminEmails: envConfig.AdminEmails,
+		Templates: templateRenderer,
+		Catalog: catalogProvider,
+		Blobs: blobStore,
 	}
 
 	muxRouter := mux.NewRouter()
@@ -51,6 +104,9 @@ func main() {
 
 	muxRouter.HandleFunc(routeAPIPrefix + "/user/register", userAPIConfig.CreateUser).Methods("POST")
 	muxRouter.HandleFunc(routeAPIPrefix + "/user/login", userAPIConfig.Login).Methods("POST")
+	muxRouter.HandleFunc(routeAPIPrefix + "/user/locale", middleware.Authorization(&userAPIConfig.APIConfig, userAPIConfig.UpdateUserLocale)).Methods("PATCH")
+	muxRouter.HandleFunc(routeAPIPrefix + "/user/loan-period", middleware.Authorization(&userAPIConfig.APIConfig, userAPIConfig.UpdateUserDefaultLoanPeriod)).Methods("PATCH")
+	muxRouter.HandleFunc(routeAPIPrefix + "/users/profile/{userId}", middleware.Authorization(&userAPIConfig.APIConfig, userAPIConfig.GetUserProfile)).Methods("GET")
 
 	// Books endpoints.
 	bookAPIConfig := books.BookAPIConfig {
@@ -62,9 +118,6 @@ func main() {
 	muxRouter.HandleFunc(routeAPIPrefix + "/books", middleware.Authorization(&bookAPIConfig.APIConfig, bookAPIConfig.GetBooks)).Methods("GET")
 	muxRouter.HandleFunc(routeAPIPrefix + "/books/browse", middleware.Authorization(&bookAPIConfig.APIConfig, bookAPIConfig.BrowseBooks)).Methods("GET")
 	muxRouter.HandleFunc(routeAPIPrefix + "/books/browse/{userId}", middleware.Authorization(&bookAPIConfig.APIConfig, bookAPIConfig.BrowseBooksByUserID)).Methods("GET")
-	muxRouter.HandleFunc(routeAPIPrefix + "/books/{bookId}", middleware.Authorization(&bookAPIConfig.APIConfig, bookAPIConfig.GetBook)).Methods("GET")
-	muxRouter.HandleFunc(routeAPIPrefix + "/books/{bookId}", middleware.Authorization(&bookAPIConfig.APIConfig, bookAPIConfig.UpdateBook)).Methods("PATCH")
-	muxRouter.HandleFunc(routeAPIPrefix + "/books/{bookId}", middleware.Authorization(&bookAPIConfig.APIConfig, bookAPIConfig.DeleteBook)).Methods("DELETE")
 
 	// Book borrows endpoints.
 	bookBorrowAPIConfig := book_borrows.BookBorrowAPIConfig {
@@ -74,6 +127,137 @@ func main() {
 
 	muxRouter.HandleFunc(routeAPIPrefix + "/books/issue/{bookId}", middleware.Authorization(&bookBorrowAPIConfig.APIConfig, bookBorrowAPIConfig.IssueBook)).Methods("POST")
 	muxRouter.HandleFunc(routeAPIPrefix + "/books/return/{bookBorrowId}", middleware.Authorization(&bookBorrowAPIConfig.APIConfig, bookBorrowAPIConfig.ReturnBook)).Methods("PATCH")
+	muxRouter.HandleFunc(routeAPIPrefix + "/books/return/{bookBorrowId}/confirm", middleware.Authorization(&bookBorrowAPIConfig.APIConfig, bookBorrowAPIConfig.ConfirmBookReturn)).Methods("PATCH")
+	muxRouter.HandleFunc(routeAPIPrefix + "/books/return/{bookBorrowId}/dispute", middleware.Authorization(&bookBorrowAPIConfig.APIConfig, bookBorrowAPIConfig.DisputeBookReturn)).Methods("PATCH")
+	muxRouter.HandleFunc(routeAPIPrefix + "/books/borrows", middleware.Authorization(&bookBorrowAPIConfig.APIConfig, bookBorrowAPIConfig.GetBorrowedBooks)).Methods("GET")
+	muxRouter.HandleFunc(routeAPIPrefix + "/books/lent", middleware.Authorization(&bookBorrowAPIConfig.APIConfig, bookBorrowAPIConfig.GetLentBooks)).Methods("GET")
+	muxRouter.HandleFunc(routeAPIPrefix + "/books/borrows/{bookBorrowId}/renew", middleware.Authorization(&bookBorrowAPIConfig.APIConfig, bookBorrowAPIConfig.RenewBookBorrow)).Methods("PATCH")
+	muxRouter.HandleFunc(routeAPIPrefix + "/books/borrows/{bookBorrowId}/renewals", middleware.Authorization(&bookBorrowAPIConfig.APIConfig, bookBorrowAPIConfig.GetBookBorrowRenewals)).Methods("GET")
+	muxRouter.HandleFunc(routeAPIPrefix + "/books/requests/incoming", middleware.Authorization(&bookBorrowAPIConfig.APIConfig, bookBorrowAPIConfig.GetIncomingBookBorrowRequests)).Methods("GET")
+	muxRouter.HandleFunc(routeAPIPrefix + "/books/requests/outgoing", middleware.Authorization(&bookBorrowAPIConfig.APIConfig, bookBorrowAPIConfig.GetOutgoingBookBorrowRequests)).Methods("GET")
+	muxRouter.HandleFunc(routeAPIPrefix + "/books/requests/{bookId}", middleware.Authorization(&bookBorrowAPIConfig.APIConfig, bookBorrowAPIConfig.CreateBookBorrowRequest)).Methods("POST")
+	muxRouter.HandleFunc(routeAPIPrefix + "/books/requests/{bookBorrowRequestId}/approve", middleware.Authorization(&bookBorrowAPIConfig.APIConfig, bookBorrowAPIConfig.ApproveBookBorrowRequest)).Methods("PATCH")
+	muxRouter.HandleFunc(routeAPIPrefix + "/books/requests/{bookBorrowRequestId}/decline", middleware.Authorization(&bookBorrowAPIConfig.APIConfig, bookBorrowAPIConfig.DeclineBookBorrowRequest)).Methods("PATCH")
+	muxRouter.HandleFunc(routeAPIPrefix + "/books/requests/{bookBorrowRequestId}/cancel", middleware.Authorization(&bookBorrowAPIConfig.APIConfig, bookBorrowAPIConfig.CancelBookBorrowRequest)).Methods("PATCH")
+
+	// Book reservations endpoints.
+	bookReservationAPIConfig := book_reservations.BookReservationAPIConfig {
+		APIConfig: apiConfig,
+	}
+	bookReservationAPIConfig.APIConfig.DB = database
+
+	muxRouter.HandleFunc(routeAPIPrefix + "/books/reservations", middleware.Authorization(&bookReservationAPIConfig.APIConfig, bookReservationAPIConfig.GetBookReservations)).Methods("GET")
+	muxRouter.HandleFunc(routeAPIPrefix + "/books/reservations/{bookId}", middleware.Authorization(&bookReservationAPIConfig.APIConfig, bookReservationAPIConfig.CreateBookReservation)).Methods("POST")
+	muxRouter.HandleFunc(routeAPIPrefix + "/books/reservations/{bookReservationId}", middleware.Authorization(&bookReservationAPIConfig.APIConfig, bookReservationAPIConfig.CancelBookReservation)).Methods("DELETE")
+
+	// Book copies endpoints.
+	bookCopyAPIConfig := book_copies.BookCopyAPIConfig {
+		APIConfig: apiConfig,
+	}
+	bookCopyAPIConfig.APIConfig.DB = database
+
+	muxRouter.HandleFunc(routeAPIPrefix + "/books/copies/{bookId}", middleware.Authorization(&bookCopyAPIConfig.APIConfig, bookCopyAPIConfig.CreateBookCopy)).Methods("POST")
+	muxRouter.HandleFunc(routeAPIPrefix + "/books/copies/{bookId}", middleware.Authorization(&bookCopyAPIConfig.APIConfig, bookCopyAPIConfig.GetBookCopies)).Methods("GET")
+	muxRouter.HandleFunc(routeAPIPrefix + "/books/copies/{bookCopyId}", middleware.Authorization(&bookCopyAPIConfig.APIConfig, bookCopyAPIConfig.UpdateBookCopy)).Methods("PATCH")
+	muxRouter.HandleFunc(routeAPIPrefix + "/books/copies/{bookCopyId}", middleware.Authorization(&bookCopyAPIConfig.APIConfig, bookCopyAPIConfig.DeleteBookCopy)).Methods("DELETE")
+
+	// Book genres and tags endpoints.
+	bookTagAPIConfig := book_tags.BookTagAPIConfig {
+		APIConfig: apiConfig,
+	}
+	bookTagAPIConfig.APIConfig.DB = database
+
+	muxRouter.HandleFunc(routeAPIPrefix + "/books/genres", middleware.Authorization(&bookTagAPIConfig.APIConfig, bookTagAPIConfig.GetGenres)).Methods("GET")
+	muxRouter.HandleFunc(routeAPIPrefix + "/books/tags", middleware.Authorization(&bookTagAPIConfig.APIConfig, bookTagAPIConfig.GetPopularBookTags)).Methods("GET")
+
+	// Approved borrowers endpoints.
+	bookPolicyAPIConfig := book_policies.BookPolicyAPIConfig {
+		APIConfig: apiConfig,
+	}
+	bookPolicyAPIConfig.APIConfig.DB = database
+
+	muxRouter.HandleFunc(routeAPIPrefix + "/books/approved-borrowers", middleware.Authorization(&bookPolicyAPIConfig.APIConfig, bookPolicyAPIConfig.GetApprovedBorrowers)).Methods("GET")
+	muxRouter.HandleFunc(routeAPIPrefix + "/books/approved-borrowers/{borrowerId}", middleware.Authorization(&bookPolicyAPIConfig.APIConfig, bookPolicyAPIConfig.CreateApprovedBorrower)).Methods("POST")
+	muxRouter.HandleFunc(routeAPIPrefix + "/books/approved-borrowers/{borrowerId}", middleware.Authorization(&bookPolicyAPIConfig.APIConfig, bookPolicyAPIConfig.DeleteApprovedBorrower)).Methods("DELETE")
+
+	// Book covers endpoints.
+	bookCoverAPIConfig := book_covers.BookCoverAPIConfig {
+		APIConfig: apiConfig,
+	}
+	bookCoverAPIConfig.APIConfig.DB = database
+
+	muxRouter.HandleFunc(routeAPIPrefix + "/books/{bookId}/cover", middleware.Authorization(&bookCoverAPIConfig.APIConfig, bookCoverAPIConfig.UploadBookCover)).Methods("POST")
+	muxRouter.HandleFunc(routeAPIPrefix + "/books/{bookId}/cover", middleware.Authorization(&bookCoverAPIConfig.APIConfig, bookCoverAPIConfig.DeleteBookCover)).Methods("DELETE")
+
+	// Book imports endpoints.
+	bookImportAPIConfig := book_imports.BookImportAPIConfig {
+		APIConfig: apiConfig,
+	}
+	bookImportAPIConfig.APIConfig.DB = database
+
+	muxRouter.HandleFunc(routeAPIPrefix + "/books/import", middleware.Authorization(&bookImportAPIConfig.APIConfig, bookImportAPIConfig.ImportBooks)).Methods("POST")
+
+	// Book exports endpoints.
+	bookExportAPIConfig := book_exports.BookExportAPIConfig {
+		APIConfig: apiConfig,
+	}
+	bookExportAPIConfig.APIConfig.DB = database
+
+	muxRouter.HandleFunc(routeAPIPrefix + "/books/export", middleware.Authorization(&bookExportAPIConfig.APIConfig, bookExportAPIConfig.ExportBooks)).Methods("GET")
+
+	// Book trash endpoints.
+	bookTrashAPIConfig := book_trash.BookTrashAPIConfig {
+		APIConfig: apiConfig,
+	}
+	bookTrashAPIConfig.APIConfig.DB = database
+
+	muxRouter.HandleFunc(routeAPIPrefix + "/books/trash", middleware.Authorization(&bookTrashAPIConfig.APIConfig, bookTrashAPIConfig.GetDeletedBooks)).Methods("GET")
+	muxRouter.HandleFunc(routeAPIPrefix + "/books/{bookId}/restore", middleware.Authorization(&bookTrashAPIConfig.APIConfig, bookTrashAPIConfig.RestoreBook)).Methods("PATCH")
+
+	// Book claims endpoints.
+	bookClaimAPIConfig := book_claims.BookClaimAPIConfig {
+		APIConfig: apiConfig,
+	}
+	bookClaimAPIConfig.APIConfig.DB = database
+
+	muxRouter.HandleFunc(routeAPIPrefix + "/books/borrows/{bookBorrowId}/condition", middleware.Authorization(&bookClaimAPIConfig.APIConfig, bookClaimAPIConfig.CreateBookConditionReport)).Methods("POST")
+	muxRouter.HandleFunc(routeAPIPrefix + "/books/borrows/{bookBorrowId}/condition", middleware.Authorization(&bookClaimAPIConfig.APIConfig, bookClaimAPIConfig.GetBookConditionReports)).Methods("GET")
+	muxRouter.HandleFunc(routeAPIPrefix + "/books/borrows/{bookBorrowId}/lost", middleware.Authorization(&bookClaimAPIConfig.APIConfig, bookClaimAPIConfig.ReportBookLost)).Methods("PATCH")
+	muxRouter.HandleFunc(routeAPIPrefix + "/books/claims", middleware.Authorization(&bookClaimAPIConfig.APIConfig, bookClaimAPIConfig.GetBookClaims)).Methods("GET")
+	muxRouter.HandleFunc(routeAPIPrefix + "/books/claims/{bookBorrowId}", middleware.Authorization(&bookClaimAPIConfig.APIConfig, bookClaimAPIConfig.CreateBookClaim)).Methods("POST")
+	muxRouter.HandleFunc(routeAPIPrefix + "/books/claims/{bookClaimId}", middleware.Authorization(&bookClaimAPIConfig.APIConfig, bookClaimAPIConfig.GetBookClaim)).Methods("GET")
+	muxRouter.HandleFunc(routeAPIPrefix + "/books/claims/{bookClaimId}/comments", middleware.Authorization(&bookClaimAPIConfig.APIConfig, bookClaimAPIConfig.CreateBookClaimComment)).Methods("POST")
+	muxRouter.HandleFunc(routeAPIPrefix + "/books/claims/{bookClaimId}/resolve", middleware.Authorization(&bookClaimAPIConfig.APIConfig, bookClaimAPIConfig.ResolveBookClaim)).Methods("PATCH")
+
+	// Book reviews endpoints.
+	bookReviewAPIConfig := book_reviews.BookReviewAPIConfig {
+		APIConfig: apiConfig,
+	}
+	bookReviewAPIConfig.APIConfig.DB = database
+
+	muxRouter.HandleFunc(routeAPIPrefix + "/books/reviews/{bookReviewId}", middleware.Authorization(&bookReviewAPIConfig.APIConfig, bookReviewAPIConfig.UpdateBookReview)).Methods("PATCH")
+	muxRouter.HandleFunc(routeAPIPrefix + "/books/reviews/{bookReviewId}", middleware.Authorization(&bookReviewAPIConfig.APIConfig, bookReviewAPIConfig.DeleteBookReview)).Methods("DELETE")
+	muxRouter.HandleFunc(routeAPIPrefix + "/books/reviews/{bookReviewId}/reply", middleware.Authorization(&bookReviewAPIConfig.APIConfig, bookReviewAPIConfig.ReplyToBookReview)).Methods("PATCH")
+	muxRouter.HandleFunc(routeAPIPrefix + "/books/{bookId}/reviews", middleware.Authorization(&bookReviewAPIConfig.APIConfig, bookReviewAPIConfig.CreateBookReview)).Methods("POST")
+	muxRouter.HandleFunc(routeAPIPrefix + "/books/{bookId}/reviews", middleware.Authorization(&bookReviewAPIConfig.APIConfig, bookReviewAPIConfig.GetBookReviews)).Methods("GET")
+
+	// Borrower reputation endpoints.
+	borrowerReputationAPIConfig := borrower_reputation.BorrowerReputationAPIConfig {
+		APIConfig: apiConfig,
+	}
+	borrowerReputationAPIConfig.APIConfig.DB = database
+
+	muxRouter.HandleFunc(routeAPIPrefix + "/books/borrows/{bookBorrowId}/borrower-rating", middleware.Authorization(&borrowerReputationAPIConfig.APIConfig, borrowerReputationAPIConfig.CreateBorrowerRating)).Methods("POST")
+
+	// Files kept on local disk are served by the API itself, unless they are published under another host.
+	if localBlobStore, isLocalBlobStore := blobStore.(*blob_stores.LocalBlobStore); isLocalBlobStore && strings.HasPrefix(localBlobStore.BaseURL, "/") {
+		muxRouter.PathPrefix(localBlobStore.BaseURL + "/").Handler(http.StripPrefix(localBlobStore.BaseURL, localBlobStore)).Methods("GET", "HEAD")
+	}
+
+	// Registered after the fixed /books/... paths so they are not taken as a book id.
+	muxRouter.HandleFunc(routeAPIPrefix + "/books/{bookId}", middleware.Authorization(&bookAPIConfig.APIConfig, bookAPIConfig.GetBook)).Methods("GET")
+	muxRouter.HandleFunc(routeAPIPrefix + "/books/{bookId}", middleware.Authorization(&bookAPIConfig.APIConfig, bookAPIConfig.UpdateBook)).Methods("PATCH")
+	muxRouter.HandleFunc(routeAPIPrefix + "/books/{bookId}", middleware.Authorization(&bookAPIConfig.APIConfig, bookAPIConfig.DeleteBook)).Methods("DELETE")
 
 	// User subscrbers endpoints.
 	userSubscriberAPIConfig := user_subscribers.UserSubscriberAPIConfig {
@@ -86,6 +270,72 @@ func main() {
 	muxRouter.HandleFunc(routeAPIPrefix + "/users/subscribers", middleware.Authorization(&userSubscriberAPIConfig.APIConfig, userSubscriberAPIConfig.GetUserSubscribers)).Methods("GET")
 	muxRouter.HandleFunc(routeAPIPrefix + "/users/subscriptions", middleware.Authorization(&userSubscriberAPIConfig.APIConfig, userSubscriberAPIConfig.GetUserSubscriptions)).Methods("GET")
 
+	// Shelves endpoints.
+	shelfAPIConfig := shelves.ShelfAPIConfig {
+		APIConfig: apiConfig,
+	}
+	shelfAPIConfig.APIConfig.DB = database
+
+	muxRouter.HandleFunc(routeAPIPrefix + "/shelves", middleware.Authorization(&shelfAPIConfig.APIConfig, shelfAPIConfig.CreateShelf)).Methods("POST")
+	muxRouter.HandleFunc(routeAPIPrefix + "/shelves", middleware.Authorization(&shelfAPIConfig.APIConfig, shelfAPIConfig.GetShelves)).Methods("GET")
+	muxRouter.HandleFunc(routeAPIPrefix + "/shelves/user/{userId}", middleware.Authorization(&shelfAPIConfig.APIConfig, shelfAPIConfig.GetUserShelves)).Methods("GET")
+	muxRouter.HandleFunc(routeAPIPrefix + "/shelves/{shelfId}", middleware.Authorization(&shelfAPIConfig.APIConfig, shelfAPIConfig.GetShelf)).Methods("GET")
+	muxRouter.HandleFunc(routeAPIPrefix + "/shelves/{shelfId}", middleware.Authorization(&shelfAPIConfig.APIConfig, shelfAPIConfig.UpdateShelf)).Methods("PATCH")
+	muxRouter.HandleFunc(routeAPIPrefix + "/shelves/{shelfId}", middleware.Authorization(&shelfAPIConfig.APIConfig, shelfAPIConfig.DeleteShelf)).Methods("DELETE")
+	muxRouter.HandleFunc(routeAPIPrefix + "/shelves/{shelfId}/books", middleware.Authorization(&shelfAPIConfig.APIConfig, shelfAPIConfig.GetShelfBooks)).Methods("GET")
+	muxRouter.HandleFunc(routeAPIPrefix + "/shelves/{shelfId}/books", middleware.Authorization(&shelfAPIConfig.APIConfig, shelfAPIConfig.ReorderShelfBooks)).Methods("PUT")
+	muxRouter.HandleFunc(routeAPIPrefix + "/shelves/{shelfId}/books/{bookId}", middleware.Authorization(&shelfAPIConfig.APIConfig, shelfAPIConfig.AddShelfBook)).Methods("POST")
+	muxRouter.HandleFunc(routeAPIPrefix + "/shelves/{shelfId}/books/{bookId}", middleware.Authorization(&shelfAPIConfig.APIConfig, shelfAPIConfig.RemoveShelfBook)).Methods("DELETE")
+
+	// Admin endpoints.
+	notificationOutboxAPIConfig := notification_outbox.NotificationOutboxAPIConfig {
+		APIConfig: apiConfig,
+	}
+	notificationOutboxAPIConfig.APIConfig.DB = database
+
+	muxRouter.HandleFunc(routeAPIPrefix + "/admin/notifications", middleware.AdminAuthorization(&notificationOutboxAPIConfig.APIConfig, notificationOutboxAPIConfig.GetOutboxNotifications)).Methods("GET")
+	notificationTemplateAPIConfig := notification_templates.NotificationTemplateAPIConfig {
+		APIConfig: apiConfig,
+		Renderer: templateRenderer,
+	}
+	notificationTemplateAPIConfig.APIConfig.DB = database
+
+	muxRouter.HandleFunc(routeAPIPrefix + "/admin/notifications/templates", middleware.AdminAuthorization(&notificationTemplateAPIConfig.APIConfig, notificationTemplateAPIConfig.GetNotificationTemplates)).Methods("GET")
+	muxRouter.HandleFunc(routeAPIPrefix + "/admin/notifications/templates/{templateName}/preview", middleware.AdminAuthorization(&notificationTemplateAPIConfig.APIConfig, notificationTemplateAPIConfig.PreviewNotificationTemplate)).Methods("GET")
+	muxRouter.HandleFunc(routeAPIPrefix + "/admin/notifications/{notificationId}/replay", middleware.AdminAuthorization(&notificationOutboxAPIConfig.APIConfig, notificationOutboxAPIConfig.ReplayOutboxNotification)).Methods("PATCH")
+
+	// Background jobs.
+	jobScheduler := scheduler.New()
+	jobScheduler.Register(scheduler.Job{
+		Name: "book borrow reminders",
+		Interval: book_borrows.BookBorrowReminderInterval,
+		Run: func(ctx context.Context) error {
+			return book_borrows.SendBookBorrowReminders(ctx, &apiConfig, time.Now().UTC())
+		},
+	})
+	jobScheduler.Register(scheduler.Job{
+		Name: "notification outbox",
+		Interval: notification_outbox.OutboxWorkerInterval,
+		Run: func(ctx context.Context) error {
+			return notification_outbox.DeliverOutboxNotifications(ctx, &apiConfig, time.Now().UTC())
+		},
+	})
+	jobScheduler.Register(scheduler.Job{
+		Name: "book reservation offers",
+		Interval: book_reservations.BookReservationOfferInterval,
+		Run: func(ctx context.Context) error {
+			return book_reservations.OfferLapsedBookReservations(ctx, &apiConfig, time.Now().UTC())
+		},
+	})
+	jobScheduler.Register(scheduler.Job{
+		Name: "book trash purge",
+		Interval: book_trash.BookTrashPurgeInterval,
+		Run: func(ctx context.Context) error {
+			return book_trash.PurgeDeletedBooks(ctx, &apiConfig, time.Now().UTC())
+		},
+	})
+	jobScheduler.Start(context.Background())
+
 	log.Printf("server starting on port %v", envConfig.Port)
 
 	server := &http.Server{
@@ -93,9 +343,26 @@ func main() {
 		Addr: ":" + envConfig.Port,
 	}
 
-	serverError := server.ListenAndServe()
+	go func() {
+		serverError := server.ListenAndServe()
 
-	if serverError != nil {
-		log.Fatal(serverError)
+		if serverError != nil && serverError != http.ErrServerClosed {
+			log.Fatal(serverError)
+		}
+	}()
+
+	shutdownSignal := make(chan os.Signal, 1)
+	signal.Notify(shutdownSignal, os.Interrupt, syscall.SIGTERM)
+	<-shutdownSignal
+
+	log.Print("server shutting down")
+
+	shutdownContext, cancelShutdown := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancelShutdown()
+
+	if shutdownError := server.Shutdown(shutdownContext); shutdownError != nil {
+		log.Printf("server shutdown error: %s", shutdownError)
 	}
+
+	jobScheduler.Stop()
 }
\ No newline at end of file
@@ -5,6 +5,7 @@ import (
 	"net/http"
 
 	"github.com/elorenzorodz/co-library/common"
+	"github.com/elorenzorodz/co-library/internal/database"
 	"github.com/google/uuid"
 )
 
@@ -12,30 +13,60 @@ type AuthHandler func(http.ResponseWriter, *http.Request, uuid.UUID)
 
 func Authorization(apiConfig *common.APIConfig, handler AuthHandler) http.HandlerFunc {
 	return func(writer http.ResponseWriter, request *http.Request) {
-		jwt, jwtError := common.GetJWT(request.Header)
-
-		if jwtError != nil {
-			common.ErrorResponse(writer, http.StatusForbidden, fmt.Sprintf("authentication error: %s", jwtError))
+		getUser, authenticated := authenticate(apiConfig, writer, request)
 
+		if !authenticated {
 			return
 		}
 
-		email, extractEmailClaimError := common.ValidateJWTAndGetEmailClaim(jwt, apiConfig.JWTValidationKey)
+		handler(writer, request, getUser.ID)
+	}
+}
 
-		if extractEmailClaimError != nil {
-			common.ErrorResponse(writer, http.StatusForbidden, fmt.Sprintf("authentication error: %s", extractEmailClaimError))
+// Like Authorization, but only lets through users listed in ADMIN_EMAILS.
+func AdminAuthorization(apiConfig *common.APIConfig, handler AuthHandler) http.HandlerFunc {
+	return func(writer http.ResponseWriter, request *http.Request) {
+		getUser, authenticated := authenticate(apiConfig, writer, request)
 
+		if !authenticated {
 			return
 		}
 
-		getUser, getUserError := apiConfig.DB.GetUserByEmail(request.Context(), email)
-
-		if getUserError != nil {
-			common.ErrorResponse(writer, http.StatusUnauthorized, fmt.Sprintf("authentication error: %s", getUserError))
+		if !common.IsAdminEmail(apiConfig.AdminEmails, getUser.Email) {
+			common.ErrorResponse(writer, http.StatusForbidden, "admin access required")
 
 			return
 		}
 
 		handler(writer, request, getUser.ID)
 	}
+}
+
+// Writes the error response itself when the request is not authenticated.
+func authenticate(apiConfig *common.APIConfig, writer http.ResponseWriter, request *http.Request) (database.User, bool) {
+	jwt, jwtError := common.GetJWT(request.Header)
+
+	if jwtError != nil {
+		common.ErrorResponse(writer, http.StatusForbidden, fmt.Sprintf("authentication error: %s", jwtError))
+
+		return database.User{}, false
+	}
+
+	email, extractEmailClaimError := common.ValidateJWTAndGetEmailClaim(jwt, apiConfig.JWTValidationKey)
+
+	if extractEmailClaimError != nil {
+		common.ErrorResponse(writer, http.StatusForbidden, fmt.Sprintf("authentication error: %s", extractEmailClaimError))
+
+		return database.User{}, false
+	}
+
+	getUser, getUserError := apiConfig.DB.GetUserByEmail(request.Context(), email)
+
+	if getUserError != nil {
+		common.ErrorResponse(writer, http.StatusUnauthorized, fmt.Sprintf("authentication error: %s", getUserError))
+
+		return database.User{}, false
+	}
+
+	return getUser, true
 }
\ No newline at end of file
@@ -0,0 +1,138 @@
+package notification_outbox
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/elorenzorodz/co-library/common"
+	"github.com/elorenzorodz/co-library/internal/database"
+	"github.com/google/uuid"
+)
+
+func DatabaseOutboxNotificationToOutboxNotificationJSON(databaseOutboxNotification database.NotificationOutbox) OutboxNotification {
+	return OutboxNotification{
+		ID:            databaseOutboxNotification.ID,
+		Status:        databaseOutboxNotification.Status,
+		Sender:        databaseOutboxNotification.Sender,
+		Recipient:     databaseOutboxNotification.Recipient,
+		Subject:       databaseOutboxNotification.Subject,
+		Body:          databaseOutboxNotification.Body,
+		HTMLBody:      databaseOutboxNotification.HtmlBody,
+		Attempts:      databaseOutboxNotification.Attempts,
+		LastError:     databaseOutboxNotification.LastError.String,
+		NextAttemptAt: databaseOutboxNotification.NextAttemptAt,
+		SentAt:        databaseOutboxNotification.SentAt,
+		CreatedAt:     databaseOutboxNotification.CreatedAt,
+		UpdatedAt:     databaseOutboxNotification.UpdatedAt,
+	}
+}
+
+func DatabaseOutboxNotificationsToOutboxNotificationsJSON(databaseOutboxNotifications []database.NotificationOutbox) []OutboxNotification {
+	outboxNotifications := []OutboxNotification{}
+
+	for _, databaseOutboxNotification := range databaseOutboxNotifications {
+		outboxNotifications = append(outboxNotifications, DatabaseOutboxNotificationToOutboxNotificationJSON(databaseOutboxNotification))
+	}
+
+	return outboxNotifications
+}
+
+// Queues a notification for the outbox worker. Pass the transaction's Querier so the
+// notification is only stored if the change that caused it is committed.
+func EnqueueNotification(ctx context.Context, querier common.Querier, notification common.Notification) error {
+	createOutboxNotificationParams := database.CreateOutboxNotificationParams{
+		ID:        uuid.New(),
+		Sender:    notification.From,
+		Recipient: notification.To,
+		Subject:   notification.Subject,
+		Body:      notification.Body,
+		HtmlBody:  notification.HTMLBody,
+	}
+
+	_, createOutboxNotificationError := querier.CreateOutboxNotification(ctx, createOutboxNotificationParams)
+
+	return createOutboxNotificationError
+}
+
+// Delay before the next delivery attempt, doubling with every failure.
+func OutboxBackoff(attempts int32) time.Duration {
+	backoff := OutboxBaseBackoff
+
+	for attempt := int32(1); attempt < attempts; attempt++ {
+		backoff *= 2
+
+		if backoff >= OutboxMaxBackoff {
+			return OutboxMaxBackoff
+		}
+	}
+
+	return backoff
+}
+
+// Sends one batch of due notifications. Failures are rescheduled with backoff and
+// dead-lettered after OutboxMaxAttempts.
+func DeliverOutboxNotifications(ctx context.Context, apiConfig *common.APIConfig, now time.Time) error {
+	claimDueOutboxNotificationsParams := database.ClaimDueOutboxNotificationsParams{
+		LeaseUntil: now.Add(OutboxLeaseDuration),
+		BatchSize:  OutboxBatchSize,
+	}
+
+	outboxNotifications, claimError := apiConfig.DB.ClaimDueOutboxNotifications(ctx, claimDueOutboxNotificationsParams)
+
+	if claimError != nil {
+		return fmt.Errorf("error claiming outbox notifications: %w", claimError)
+	}
+
+	for _, outboxNotification := range outboxNotifications {
+		sendError := apiConfig.Notifier.Send(ctx, common.Notification{
+			From:     outboxNotification.Sender,
+			To:       outboxNotification.Recipient,
+			Subject:  outboxNotification.Subject,
+			Body:     outboxNotification.Body,
+			HTMLBody: outboxNotification.HtmlBody,
+		})
+
+		if sendError == nil {
+			if markSentError := apiConfig.DB.MarkOutboxNotificationSent(ctx, outboxNotification.ID); markSentError != nil {
+				log.Printf("failed to mark outbox notification %s as sent: %s", outboxNotification.ID, markSentError)
+			}
+
+			continue
+		}
+
+		attempts := outboxNotification.Attempts + 1
+		recordOutboxNotificationFailureParams := database.RecordOutboxNotificationFailureParams{
+			Status:        OutboxStatusPending,
+			LastError:     sql.NullString{String: sendError.Error(), Valid: true},
+			NextAttemptAt: now.Add(OutboxBackoff(attempts)),
+			ID:            outboxNotification.ID,
+		}
+
+		if attempts >= OutboxMaxAttempts {
+			recordOutboxNotificationFailureParams.Status = OutboxStatusDead
+
+			log.Printf("outbox notification %s to %s dead-lettered after %v attempts: %s", outboxNotification.ID, outboxNotification.Recipient, attempts, sendError)
+		}
+
+		if _, recordFailureError := apiConfig.DB.RecordOutboxNotificationFailure(ctx, recordOutboxNotificationFailureParams); recordFailureError != nil {
+			log.Printf("failed to record delivery failure of outbox notification %s: %s", outboxNotification.ID, recordFailureError)
+		}
+	}
+
+	return nil
+}
+
+// Turns the optional ?status= query value into a list filter.
+func ParseOutboxStatusFilter(status string) (sql.NullString, error) {
+	switch status {
+	case "":
+		return sql.NullString{}, nil
+	case OutboxStatusPending, OutboxStatusSent, OutboxStatusDead:
+		return sql.NullString{String: status, Valid: true}, nil
+	}
+
+	return sql.NullString{}, fmt.Errorf("invalid status: %s", status)
+}
@@ -0,0 +1,47 @@
+package notification_outbox
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/elorenzorodz/co-library/common"
+	"github.com/google/uuid"
+)
+
+type NotificationOutboxAPIConfig struct {
+	common.APIConfig
+}
+
+const (
+	OutboxStatusPending = "pending"
+	OutboxStatusSent    = "sent"
+	OutboxStatusDead    = "dead"
+)
+
+const (
+	// How often the worker polls the outbox, and how many notifications it takes each time.
+	OutboxWorkerInterval = 30 * time.Second
+	OutboxBatchSize      = 50
+	// Claimed notifications are hidden from other workers for this long.
+	OutboxLeaseDuration = 5 * time.Minute
+	// A notification is dead-lettered after this many failed deliveries.
+	OutboxMaxAttempts = 8
+	OutboxBaseBackoff = time.Minute
+	OutboxMaxBackoff  = 6 * time.Hour
+)
+
+type OutboxNotification struct {
+	ID            uuid.UUID    `json:"id"`
+	Status        string       `json:"status"`
+	Sender        string       `json:"sender"`
+	Recipient     string       `json:"recipient"`
+	Subject       string       `json:"subject"`
+	Body          string       `json:"body"`
+	HTMLBody      string       `json:"html_body"`
+	Attempts      int32        `json:"attempts"`
+	LastError     string       `json:"last_error"`
+	NextAttemptAt time.Time    `json:"nextAttemptAt"`
+	SentAt        sql.NullTime `json:"sentAt"`
+	CreatedAt     time.Time    `json:"createdAt"`
+	UpdatedAt     time.Time    `json:"updatedAt"`
+}
@@ -0,0 +1,263 @@
+package notification_outbox
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/elorenzorodz/co-library/common"
+	"github.com/elorenzorodz/co-library/internal/database"
+	"github.com/elorenzorodz/co-library/notifiers"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+type MockQueries struct {
+	*common.BaseMock
+
+	ClaimDueOutboxNotificationsFunc     func(ctx context.Context, arg database.ClaimDueOutboxNotificationsParams) ([]database.NotificationOutbox, error)
+	MarkOutboxNotificationSentFunc      func(ctx context.Context, id uuid.UUID) error
+	RecordOutboxNotificationFailureFunc func(ctx context.Context, arg database.RecordOutboxNotificationFailureParams) (database.NotificationOutbox, error)
+	ReplayOutboxNotificationFunc        func(ctx context.Context, id uuid.UUID) (database.NotificationOutbox, error)
+}
+
+func (mockQueries *MockQueries) ClaimDueOutboxNotifications(ctx context.Context, arg database.ClaimDueOutboxNotificationsParams) ([]database.NotificationOutbox, error) {
+	if mockQueries.ClaimDueOutboxNotificationsFunc != nil {
+		return mockQueries.ClaimDueOutboxNotificationsFunc(ctx, arg)
+	}
+
+	return mockQueries.BaseMock.ClaimDueOutboxNotifications(ctx, arg)
+}
+
+func (mockQueries *MockQueries) MarkOutboxNotificationSent(ctx context.Context, id uuid.UUID) error {
+	if mockQueries.MarkOutboxNotificationSentFunc != nil {
+		return mockQueries.MarkOutboxNotificationSentFunc(ctx, id)
+	}
+
+	return mockQueries.BaseMock.MarkOutboxNotificationSent(ctx, id)
+}
+
+func (mockQueries *MockQueries) RecordOutboxNotificationFailure(ctx context.Context, arg database.RecordOutboxNotificationFailureParams) (database.NotificationOutbox, error) {
+	if mockQueries.RecordOutboxNotificationFailureFunc != nil {
+		return mockQueries.RecordOutboxNotificationFailureFunc(ctx, arg)
+	}
+
+	return mockQueries.BaseMock.RecordOutboxNotificationFailure(ctx, arg)
+}
+
+func (mockQueries *MockQueries) ReplayOutboxNotification(ctx context.Context, id uuid.UUID) (database.NotificationOutbox, error) {
+	if mockQueries.ReplayOutboxNotificationFunc != nil {
+		return mockQueries.ReplayOutboxNotificationFunc(ctx, id)
+	}
+
+	return mockQueries.BaseMock.ReplayOutboxNotification(ctx, id)
+}
+
+type failingNotifier struct{}
+
+func (failingNotifier) Send(ctx context.Context, notification common.Notification) error {
+	return errors.New("provider unavailable")
+}
+
+func newTestOutboxNotification(attempts int32) database.NotificationOutbox {
+	return database.NotificationOutbox{
+		ID:        uuid.New(),
+		Status:    OutboxStatusPending,
+		Sender:    "Jane Doe <jane@example.com>",
+		Recipient: "John Doe <john@example.com>",
+		Subject:   "My Library Just Got Updated",
+		Body:      "Hi John",
+		Attempts:  attempts,
+	}
+}
+
+func TestOutboxBackoff(tTesting *testing.T) {
+	expectedBackoffs := map[int32]time.Duration{
+		1:  OutboxBaseBackoff,
+		2:  2 * OutboxBaseBackoff,
+		3:  4 * OutboxBaseBackoff,
+		30: OutboxMaxBackoff,
+	}
+
+	for attempts, expectedBackoff := range expectedBackoffs {
+		if backoff := OutboxBackoff(attempts); backoff != expectedBackoff {
+			tTesting.Errorf("Expected backoff %s after %d attempts, got %s", expectedBackoff, attempts, backoff)
+		}
+	}
+}
+
+func TestDeliverOutboxNotifications(tTesting *testing.T) {
+	now := time.Now().UTC()
+
+	base := common.NewBaseMock()
+
+	// 1. Success: claimed notification is sent and marked as sent.
+	tTesting.Run("Sent", func(t *testing.T) {
+		testOutboxNotification := newTestOutboxNotification(0)
+		recordingNotifier := &notifiers.RecordingNotifier{}
+		markedSent := false
+
+		mockQueries := &MockQueries{
+			BaseMock: base,
+			ClaimDueOutboxNotificationsFunc: func(ctx context.Context, arg database.ClaimDueOutboxNotificationsParams) ([]database.NotificationOutbox, error) {
+				return []database.NotificationOutbox{testOutboxNotification}, nil
+			},
+			MarkOutboxNotificationSentFunc: func(ctx context.Context, id uuid.UUID) error {
+				markedSent = id == testOutboxNotification.ID
+
+				return nil
+			},
+		}
+
+		apiConfig := common.APIConfig{DB: mockQueries, Notifier: recordingNotifier}
+
+		if err := DeliverOutboxNotifications(context.Background(), &apiConfig, now); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		notifications := recordingNotifier.Notifications()
+
+		if len(notifications) != 1 || notifications[0].To != testOutboxNotification.Recipient {
+			t.Errorf("Expected the notification to be sent, got %+v", notifications)
+		}
+
+		if !markedSent {
+			t.Error("Expected the notification to be marked as sent")
+		}
+	})
+
+	// 2. Failure: send error reschedules with backoff
+	tTesting.Run("RetriesWithBackoff", func(t *testing.T) {
+		testOutboxNotification := newTestOutboxNotification(2)
+
+		mockQueries := &MockQueries{
+			BaseMock: base,
+			ClaimDueOutboxNotificationsFunc: func(ctx context.Context, arg database.ClaimDueOutboxNotificationsParams) ([]database.NotificationOutbox, error) {
+				return []database.NotificationOutbox{testOutboxNotification}, nil
+			},
+			RecordOutboxNotificationFailureFunc: func(ctx context.Context, arg database.RecordOutboxNotificationFailureParams) (database.NotificationOutbox, error) {
+				if arg.Status != OutboxStatusPending {
+					t.Errorf("Expected status %s, got %s", OutboxStatusPending, arg.Status)
+				}
+
+				if !arg.NextAttemptAt.Equal(now.Add(OutboxBackoff(3))) {
+					t.Errorf("Expected next attempt at %s, got %s", now.Add(OutboxBackoff(3)), arg.NextAttemptAt)
+				}
+
+				if arg.LastError.String != "provider unavailable" {
+					t.Errorf("Expected last error to be recorded, got %q", arg.LastError.String)
+				}
+				return database.NotificationOutbox{}, nil
+			},
+		}
+
+		apiConfig := common.APIConfig{DB: mockQueries, Notifier: failingNotifier{}}
+
+		if err := DeliverOutboxNotifications(context.Background(), &apiConfig, now); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+	})
+
+	// 3. Failure: last allowed attempt moves the notification to the dead letter state
+	tTesting.Run("DeadLetter", func(t *testing.T) {
+		testOutboxNotification := newTestOutboxNotification(OutboxMaxAttempts - 1)
+		deadLettered := false
+
+		mockQueries := &MockQueries{
+			BaseMock: base,
+			ClaimDueOutboxNotificationsFunc: func(ctx context.Context, arg database.ClaimDueOutboxNotificationsParams) ([]database.NotificationOutbox, error) {
+				return []database.NotificationOutbox{testOutboxNotification}, nil
+			},
+			RecordOutboxNotificationFailureFunc: func(ctx context.Context, arg database.RecordOutboxNotificationFailureParams) (database.NotificationOutbox, error) {
+				deadLettered = arg.Status == OutboxStatusDead
+
+				return database.NotificationOutbox{}, nil
+			},
+		}
+
+		apiConfig := common.APIConfig{DB: mockQueries, Notifier: failingNotifier{}}
+
+		if err := DeliverOutboxNotifications(context.Background(), &apiConfig, now); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		if !deadLettered {
+			t.Error("Expected the notification to be dead-lettered")
+		}
+	})
+
+	// 4. Failure: claim error is returned to the scheduler
+	tTesting.Run("ClaimError", func(t *testing.T) {
+		mockQueries := &MockQueries{
+			BaseMock: base,
+			ClaimDueOutboxNotificationsFunc: func(ctx context.Context, arg database.ClaimDueOutboxNotificationsParams) ([]database.NotificationOutbox, error) {
+				return nil, errors.New("db connection lost")
+			},
+		}
+
+		apiConfig := common.APIConfig{DB: mockQueries, Notifier: &notifiers.RecordingNotifier{}}
+
+		if err := DeliverOutboxNotifications(context.Background(), &apiConfig, now); err == nil {
+			t.Error("Expected an error, got nil")
+		}
+	})
+}
+
+func TestReplayOutboxNotification(tTesting *testing.T) {
+	testOutboxNotification := newTestOutboxNotification(OutboxMaxAttempts)
+
+	base := common.NewBaseMock()
+
+	newReplayRequest := func() *http.Request {
+		request := httptest.NewRequest(http.MethodPatch, fmt.Sprintf("/api/v1/admin/notifications/%s/replay", testOutboxNotification.ID), nil)
+		vars := map[string]string{"notificationId": testOutboxNotification.ID.String()}
+
+		return mux.SetURLVars(request, vars)
+	}
+
+	// 1. Success: dead-lettered notification is queued again.
+	tTesting.Run("Success", func(t *testing.T) {
+		mockQueries := &MockQueries{
+			BaseMock: base,
+			ReplayOutboxNotificationFunc: func(ctx context.Context, id uuid.UUID) (database.NotificationOutbox, error) {
+				replayedOutboxNotification := testOutboxNotification
+				replayedOutboxNotification.Attempts = 0
+
+				return replayedOutboxNotification, nil
+			},
+		}
+
+		apiConfig := NotificationOutboxAPIConfig{APIConfig: common.APIConfig{DB: mockQueries}}
+		recorder := httptest.NewRecorder()
+
+		apiConfig.ReplayOutboxNotification(recorder, newReplayRequest(), uuid.New())
+
+		if recorder.Code != http.StatusOK {
+			t.Errorf("Expected status %d, got %d. Body: %s", http.StatusOK, recorder.Code, recorder.Body.String())
+		}
+	})
+
+	// 2. Failure: notification is missing or not dead-lettered
+	tTesting.Run("NotDeadLettered", func(t *testing.T) {
+		mockQueries := &MockQueries{
+			BaseMock: base,
+			ReplayOutboxNotificationFunc: func(ctx context.Context, id uuid.UUID) (database.NotificationOutbox, error) {
+				return database.NotificationOutbox{}, sql.ErrNoRows
+			},
+		}
+
+		apiConfig := NotificationOutboxAPIConfig{APIConfig: common.APIConfig{DB: mockQueries}}
+		recorder := httptest.NewRecorder()
+
+		apiConfig.ReplayOutboxNotification(recorder, newReplayRequest(), uuid.New())
+
+		if recorder.Code != http.StatusNotFound {
+			t.Errorf("Expected status %d, got %d. Body: %s", http.StatusNotFound, recorder.Code, recorder.Body.String())
+		}
+	})
+}
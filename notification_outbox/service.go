@@ -0,0 +1,56 @@
+package notification_outbox
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+
+	"github.com/elorenzorodz/co-library/common"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+func (notificationOutboxAPIConfig *NotificationOutboxAPIConfig) GetOutboxNotifications(writer http.ResponseWriter, request *http.Request, userId uuid.UUID) {
+	status, parseStatusError := ParseOutboxStatusFilter(request.URL.Query().Get("status"))
+
+	if parseStatusError != nil {
+		common.ErrorResponse(writer, http.StatusBadRequest, parseStatusError.Error())
+
+		return
+	}
+
+	outboxNotifications, getOutboxNotificationsError := notificationOutboxAPIConfig.DB.GetOutboxNotifications(request.Context(), status)
+
+	if getOutboxNotificationsError != nil {
+		common.ErrorResponse(writer, http.StatusInternalServerError, fmt.Sprintf("error getting outbox notifications: %s", getOutboxNotificationsError))
+
+		return
+	}
+
+	common.JSONResponse(writer, http.StatusOK, DatabaseOutboxNotificationsToOutboxNotificationsJSON(outboxNotifications))
+}
+
+func (notificationOutboxAPIConfig *NotificationOutboxAPIConfig) ReplayOutboxNotification(writer http.ResponseWriter, request *http.Request, userId uuid.UUID) {
+	vars := mux.Vars(request)
+	notificationId, parseNotificationIdError := uuid.Parse(vars["notificationId"])
+
+	if parseNotificationIdError != nil {
+		common.ErrorResponse(writer, http.StatusBadRequest, "invalid notification id")
+
+		return
+	}
+
+	replayedOutboxNotification, replayError := notificationOutboxAPIConfig.DB.ReplayOutboxNotification(request.Context(), notificationId)
+
+	if replayError != nil {
+		if replayError == sql.ErrNoRows {
+			common.ErrorResponse(writer, http.StatusNotFound, "notification not found or not dead-lettered")
+		} else {
+			common.ErrorResponse(writer, http.StatusInternalServerError, fmt.Sprintf("error replaying notification: %s", replayError))
+		}
+
+		return
+	}
+
+	common.JSONResponse(writer, http.StatusOK, DatabaseOutboxNotificationToOutboxNotificationJSON(replayedOutboxNotification))
+}
@@ -0,0 +1,196 @@
+package notification_templates
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	htmltemplate "html/template"
+	"io/fs"
+	"os"
+	"path"
+	"slices"
+	"sort"
+	"strings"
+	texttemplate "text/template"
+	"time"
+
+	"github.com/elorenzorodz/co-library/common"
+)
+
+//go:embed templates
+var defaultTemplates embed.FS
+
+var templateFuncs = map[string]any{
+	"formatDate": func(date time.Time) string {
+		return date.Format("2006-01-02 15:04 MST")
+	},
+}
+
+// Parses the embedded templates, then the ones in overridesDir (laid out as
+// <locale>/<template name>.tmpl) when it is set.
+func NewRenderer(overridesDir string) (*Renderer, error) {
+	renderer := &Renderer{templates: map[string]map[string]parsedTemplate{}}
+
+	embeddedTemplates, subError := fs.Sub(defaultTemplates, "templates")
+
+	if subError != nil {
+		return nil, subError
+	}
+
+	if loadError := renderer.load(embeddedTemplates); loadError != nil {
+		return nil, fmt.Errorf("error loading default templates: %w", loadError)
+	}
+
+	if overridesDir != "" {
+		if loadError := renderer.load(os.DirFS(overridesDir)); loadError != nil {
+			return nil, fmt.Errorf("error loading template overrides from %s: %w", overridesDir, loadError)
+		}
+	}
+
+	return renderer, nil
+}
+
+// For tests and tools that only need the embedded templates, which are known to parse.
+func MustNewRenderer() *Renderer {
+	renderer, rendererError := NewRenderer("")
+
+	if rendererError != nil {
+		panic(rendererError)
+	}
+
+	return renderer
+}
+
+func (renderer *Renderer) load(templateFiles fs.FS) error {
+	for _, locale := range SupportedLocales {
+		fileNames, globError := fs.Glob(templateFiles, path.Join(locale, "*.tmpl"))
+
+		if globError != nil {
+			return globError
+		}
+
+		for _, fileName := range fileNames {
+			templateName := strings.TrimSuffix(path.Base(fileName), ".tmpl")
+
+			templateSource, readError := fs.ReadFile(templateFiles, fileName)
+
+			if readError != nil {
+				return readError
+			}
+
+			textTemplate, parseTextError := texttemplate.New(templateName).Funcs(templateFuncs).Parse(string(templateSource))
+
+			if parseTextError != nil {
+				return parseTextError
+			}
+
+			htmlTemplate, parseHTMLError := htmltemplate.New(templateName).Funcs(templateFuncs).Parse(string(templateSource))
+
+			if parseHTMLError != nil {
+				return parseHTMLError
+			}
+
+			if textTemplate.Lookup("subject") == nil || textTemplate.Lookup("text") == nil {
+				return fmt.Errorf("%s must define subject and text", fileName)
+			}
+
+			if renderer.templates[locale] == nil {
+				renderer.templates[locale] = map[string]parsedTemplate{}
+			}
+
+			renderer.templates[locale][templateName] = parsedTemplate{text: textTemplate, html: htmlTemplate}
+		}
+	}
+
+	return nil
+}
+
+// Renders templateName in locale, falling back to DefaultLocale when there is no
+// translation. HTML is left empty for templates that do not define it.
+func (renderer *Renderer) Render(templateName string, locale string, data any) (common.RenderedTemplate, error) {
+	template, found := renderer.templates[locale][templateName]
+
+	if !found {
+		template, found = renderer.templates[DefaultLocale][templateName]
+	}
+
+	if !found {
+		return common.RenderedTemplate{}, fmt.Errorf("unknown notification template: %s", templateName)
+	}
+
+	var subject, text, html bytes.Buffer
+
+	if executeError := template.text.ExecuteTemplate(&subject, "subject", data); executeError != nil {
+		return common.RenderedTemplate{}, executeError
+	}
+
+	if executeError := template.text.ExecuteTemplate(&text, "text", data); executeError != nil {
+		return common.RenderedTemplate{}, executeError
+	}
+
+	if template.html.Lookup("html") != nil {
+		if executeError := template.html.ExecuteTemplate(&html, "html", data); executeError != nil {
+			return common.RenderedTemplate{}, executeError
+		}
+	}
+
+	return common.RenderedTemplate{
+		Subject: strings.TrimSpace(subject.String()),
+		Text:    strings.TrimSpace(text.String()),
+		HTML:    strings.TrimSpace(html.String()),
+	}, nil
+}
+
+func (renderer *Renderer) Templates() []NotificationTemplate {
+	localesByTemplate := map[string][]string{}
+
+	for locale, templates := range renderer.templates {
+		for templateName := range templates {
+			localesByTemplate[templateName] = append(localesByTemplate[templateName], locale)
+		}
+	}
+
+	notificationTemplates := []NotificationTemplate{}
+
+	for templateName, locales := range localesByTemplate {
+		sort.Strings(locales)
+
+		notificationTemplates = append(notificationTemplates, NotificationTemplate{Name: templateName, Locales: locales})
+	}
+
+	sort.Slice(notificationTemplates, func(i, j int) bool {
+		return notificationTemplates[i].Name < notificationTemplates[j].Name
+	})
+
+	return notificationTemplates
+}
+
+func IsLocaleSupported(locale string) bool {
+	return slices.Contains(SupportedLocales, locale)
+}
+
+// Data used by the preview endpoint.
+func SampleData(templateName string) (any, bool) {
+	dueAt := time.Date(2025, time.March, 14, 17, 0, 0, 0, time.UTC)
+
+	switch templateName {
+	case NewBookAlertTemplate:
+		return NewBookAlertData{SenderName: "Jane Doe", SubscriberName: "John Smith", BookTitle: "The Left Hand of Darkness"}, true
+	case NewBooksAlertTemplate:
+		return NewBooksAlertData{SenderName: "Jane Doe", SubscriberName: "John Smith", BookCount: 15, BookTitles: []string{"The Left Hand of Darkness", "Kindred", "Piranesi"}, MoreBooks: 12}, true
+	case BookReservationOfferTemplate:
+		return BookReservationOfferData{OwnerName: "Jane Doe", BorrowerName: "John Smith", BookTitle: "The Left Hand of Darkness", OfferExpiresAt: dueAt}, true
+	case BookBorrowDueSoonTemplate, BookBorrowOverdueTemplate, BookBorrowOverdueWeekTemplate:
+		return BookBorrowReminderData{OwnerName: "Jane Doe", BorrowerName: "John Smith", BookTitle: "The Left Hand of Darkness", DueAt: dueAt}, true
+	case BookBorrowReminderSummaryTemplate:
+		return BookBorrowReminderSummaryData{
+			LenderName: "Jane Doe",
+			Reminders: []BookBorrowReminderSummaryItem{
+				{BookTitle: "The Left Hand of Darkness", BorrowerName: "John Smith", DueAt: dueAt, Threshold: "due_soon"},
+				{BookTitle: "Kindred", BorrowerName: "Ana Cruz", DueAt: dueAt.AddDate(0, 0, -9), Threshold: "overdue_week"},
+			},
+		}, true
+	}
+
+	return nil, false
+}
@@ -0,0 +1,94 @@
+package notification_templates
+
+import (
+	htmltemplate "html/template"
+	texttemplate "text/template"
+	"time"
+
+	"github.com/elorenzorodz/co-library/common"
+)
+
+type NotificationTemplateAPIConfig struct {
+	common.APIConfig
+	Renderer *Renderer
+}
+
+const DefaultLocale = "en"
+
+var SupportedLocales = []string{"en", "es"}
+
+// One template per notification event.
+const (
+	NewBookAlertTemplate              = "new_book_alert"
+	NewBooksAlertTemplate             = "new_books_alert"
+	BookReservationOfferTemplate      = "book_reservation_offer"
+	BookBorrowDueSoonTemplate         = "book_borrow_due_soon"
+	BookBorrowOverdueTemplate         = "book_borrow_overdue"
+	BookBorrowOverdueWeekTemplate     = "book_borrow_overdue_week"
+	BookBorrowReminderSummaryTemplate = "book_borrow_reminder_summary"
+)
+
+type NewBookAlertData struct {
+	SenderName     string
+	SubscriberName string
+	BookTitle      string
+}
+
+// Sent once for a batch of books added together, such as an import.
+type NewBooksAlertData struct {
+	SenderName     string
+	SubscriberName string
+	BookCount      int
+	// Long batches only list the first few titles, MoreBooks counts the rest.
+	BookTitles []string
+	MoreBooks  int
+}
+
+type BookReservationOfferData struct {
+	OwnerName      string
+	BorrowerName   string
+	BookTitle      string
+	OfferExpiresAt time.Time
+}
+
+type BookBorrowReminderData struct {
+	OwnerName    string
+	BorrowerName string
+	BookTitle    string
+	DueAt        time.Time
+}
+
+type BookBorrowReminderSummaryData struct {
+	LenderName string
+	Reminders  []BookBorrowReminderSummaryItem
+}
+
+type BookBorrowReminderSummaryItem struct {
+	BookTitle    string
+	BorrowerName string
+	DueAt        time.Time
+	Threshold    string
+}
+
+// Each template file defines "subject", "text" and "html". The same file is parsed
+// with text/template for the first two and html/template for the last.
+type parsedTemplate struct {
+	text *texttemplate.Template
+	html *htmltemplate.Template
+}
+
+// Renders the embedded templates, replaced file by file by any found in the override directory.
+type Renderer struct {
+	templates map[string]map[string]parsedTemplate
+}
+
+type NotificationTemplate struct {
+	Name    string   `json:"name"`
+	Locales []string `json:"locales"`
+}
+
+type NotificationTemplatePreview struct {
+	Subject string `json:"subject"`
+	Text    string `json:"text"`
+	HTML    string `json:"html"`
+}
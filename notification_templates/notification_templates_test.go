@@ -0,0 +1,198 @@
+package notification_templates
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/elorenzorodz/co-library/common"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+func writeTestTemplate(t *testing.T, dir string, locale string, templateName string, source string) {
+	t.Helper()
+
+	if err := os.MkdirAll(filepath.Join(dir, locale), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, locale, templateName+".tmpl"), []byte(source), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRender(tTesting *testing.T) {
+	renderer := MustNewRenderer()
+	newBookAlertData := NewBookAlertData{SenderName: "Jane Doe", SubscriberName: "John Smith", BookTitle: "Dune"}
+
+	// 1. Success: every template renders its sample data in every locale.
+	tTesting.Run("AllTemplates", func(t *testing.T) {
+		for _, notificationTemplate := range renderer.Templates() {
+			sampleData, found := SampleData(notificationTemplate.Name)
+
+			if !found {
+				t.Errorf("Expected sample data for %s", notificationTemplate.Name)
+
+				continue
+			}
+
+			for _, locale := range SupportedLocales {
+				renderedTemplate, err := renderer.Render(notificationTemplate.Name, locale, sampleData)
+
+				if err != nil {
+					t.Errorf("Expected %s/%s to render, got %v", locale, notificationTemplate.Name, err)
+
+					continue
+				}
+
+				if renderedTemplate.Subject == "" || renderedTemplate.Text == "" || renderedTemplate.HTML == "" {
+					t.Errorf("Expected %s/%s to have a subject, text and HTML, got %+v", locale, notificationTemplate.Name, renderedTemplate)
+				}
+			}
+		}
+	})
+
+	// 2. Success: the subscriber's locale picks the translation.
+	tTesting.Run("Locale", func(t *testing.T) {
+		english, _ := renderer.Render(NewBookAlertTemplate, "en", newBookAlertData)
+		spanish, _ := renderer.Render(NewBookAlertTemplate, "es", newBookAlertData)
+
+		if english.Subject != "My Library Just Got Updated" {
+			t.Errorf("Unexpected English subject: %q", english.Subject)
+		}
+
+		if spanish.Subject == english.Subject || !strings.Contains(spanish.Text, "Dune") {
+			t.Errorf("Expected a Spanish alert, got %+v", spanish)
+		}
+	})
+
+	// 3. Success: unknown locales fall back to the default one.
+	tTesting.Run("FallbackLocale", func(t *testing.T) {
+		english, _ := renderer.Render(NewBookAlertTemplate, "en", newBookAlertData)
+		fallback, err := renderer.Render(NewBookAlertTemplate, "fr", newBookAlertData)
+
+		if err != nil || fallback != english {
+			t.Errorf("Expected the English alert, got %+v (%v)", fallback, err)
+		}
+	})
+
+	// 4. Success: HTML parts are escaped, text parts are not.
+	tTesting.Run("EscapesHTML", func(t *testing.T) {
+		renderedTemplate, _ := renderer.Render(NewBookAlertTemplate, "en", NewBookAlertData{SenderName: "Jane", SubscriberName: "John", BookTitle: "<b>Dune</b>"})
+
+		if strings.Contains(renderedTemplate.HTML, "<b>Dune</b>") || !strings.Contains(renderedTemplate.Text, "<b>Dune</b>") {
+			t.Errorf("Expected the title escaped only in HTML, got %+v", renderedTemplate)
+		}
+	})
+
+	// 5. Failure: unknown templates return an error.
+	tTesting.Run("UnknownTemplate", func(t *testing.T) {
+		if _, err := renderer.Render("missing", "en", nil); err == nil {
+			t.Error("Expected an error for an unknown template")
+		}
+	})
+}
+
+func TestNewRenderer(tTesting *testing.T) {
+	// 1. Success: templates in the overrides directory replace the embedded ones.
+	tTesting.Run("Overrides", func(t *testing.T) {
+		overridesDir := t.TempDir()
+		writeTestTemplate(t, overridesDir, "es", NewBookAlertTemplate, `{{define "subject"}}Nuevo: {{.BookTitle}}{{end}}{{define "text"}}{{.BookTitle}}{{end}}`)
+
+		renderer, err := NewRenderer(overridesDir)
+
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		renderedTemplate, _ := renderer.Render(NewBookAlertTemplate, "es", NewBookAlertData{BookTitle: "Dune"})
+
+		if renderedTemplate.Subject != "Nuevo: Dune" || renderedTemplate.HTML != "" {
+			t.Errorf("Expected the override, got %+v", renderedTemplate)
+		}
+
+		english, _ := renderer.Render(NewBookAlertTemplate, "en", NewBookAlertData{BookTitle: "Dune"})
+
+		if english.Subject != "My Library Just Got Updated" {
+			t.Errorf("Expected the embedded English template, got %q", english.Subject)
+		}
+	})
+
+	// 2. Failure: templates must define a subject and text.
+	tTesting.Run("MissingSubject", func(t *testing.T) {
+		overridesDir := t.TempDir()
+		writeTestTemplate(t, overridesDir, "en", NewBookAlertTemplate, `{{define "text"}}{{.BookTitle}}{{end}}`)
+
+		if _, err := NewRenderer(overridesDir); err == nil {
+			t.Error("Expected an error for a template without a subject")
+		}
+	})
+
+	// 3. Failure: templates that do not parse.
+	tTesting.Run("ParseError", func(t *testing.T) {
+		overridesDir := t.TempDir()
+		writeTestTemplate(t, overridesDir, "en", NewBookAlertTemplate, `{{define "subject"}}{{.BookTitle}`)
+
+		if _, err := NewRenderer(overridesDir); err == nil {
+			t.Error("Expected a parse error")
+		}
+	})
+}
+
+func TestPreviewNotificationTemplate(tTesting *testing.T) {
+	apiConfig := NotificationTemplateAPIConfig{APIConfig: common.APIConfig{}, Renderer: MustNewRenderer()}
+
+	preview := func(templateName string, query string) *httptest.ResponseRecorder {
+		request := httptest.NewRequest(http.MethodGet, "/api/v1/admin/notifications/templates/"+templateName+"/preview"+query, nil)
+		request = mux.SetURLVars(request, map[string]string{"templateName": templateName})
+		recorder := httptest.NewRecorder()
+
+		apiConfig.PreviewNotificationTemplate(recorder, request, uuid.New())
+
+		return recorder
+	}
+
+	// 1. Success: JSON preview in the requested locale.
+	tTesting.Run("Success", func(t *testing.T) {
+		recorder := preview(BookBorrowReminderSummaryTemplate, "?locale=es")
+
+		if recorder.Code != http.StatusOK {
+			t.Fatalf("Expected status %d, got %d. Body: %s", http.StatusOK, recorder.Code, recorder.Body.String())
+		}
+
+		var notificationTemplatePreview NotificationTemplatePreview
+		json.Unmarshal(recorder.Body.Bytes(), &notificationTemplatePreview)
+
+		if !strings.Contains(notificationTemplatePreview.Text, "Kindred") || notificationTemplatePreview.HTML == "" {
+			t.Errorf("Unexpected preview: %+v", notificationTemplatePreview)
+		}
+	})
+
+	// 2. Success: the HTML part on its own.
+	tTesting.Run("HTMLFormat", func(t *testing.T) {
+		recorder := preview(NewBookAlertTemplate, "?format=html")
+
+		if recorder.Code != http.StatusOK || !strings.HasPrefix(recorder.Header().Get("Content-Type"), "text/html") {
+			t.Errorf("Expected an HTML page, got %d %s", recorder.Code, recorder.Header().Get("Content-Type"))
+		}
+	})
+
+	// 3. Failure: unknown template.
+	tTesting.Run("UnknownTemplate", func(t *testing.T) {
+		if recorder := preview("missing", ""); recorder.Code != http.StatusNotFound {
+			t.Errorf("Expected status %d, got %d", http.StatusNotFound, recorder.Code)
+		}
+	})
+
+	// 4. Failure: unsupported locale.
+	tTesting.Run("UnsupportedLocale", func(t *testing.T) {
+		if recorder := preview(NewBookAlertTemplate, "?locale=xx"); recorder.Code != http.StatusBadRequest {
+			t.Errorf("Expected status %d, got %d", http.StatusBadRequest, recorder.Code)
+		}
+	})
+}
@@ -0,0 +1,61 @@
+package notification_templates
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/elorenzorodz/co-library/common"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+func (notificationTemplateAPIConfig *NotificationTemplateAPIConfig) GetNotificationTemplates(writer http.ResponseWriter, request *http.Request, userId uuid.UUID) {
+	common.JSONResponse(writer, http.StatusOK, notificationTemplateAPIConfig.Renderer.Templates())
+}
+
+// Renders a template with sample data. ?locale= picks the translation and ?format=html
+// returns the HTML part as a page instead of JSON.
+func (notificationTemplateAPIConfig *NotificationTemplateAPIConfig) PreviewNotificationTemplate(writer http.ResponseWriter, request *http.Request, userId uuid.UUID) {
+	templateName := mux.Vars(request)["templateName"]
+	sampleData, found := SampleData(templateName)
+
+	if !found {
+		common.ErrorResponse(writer, http.StatusNotFound, fmt.Sprintf("unknown notification template: %s", templateName))
+
+		return
+	}
+
+	locale := request.URL.Query().Get("locale")
+
+	if locale == "" {
+		locale = DefaultLocale
+	}
+
+	if !IsLocaleSupported(locale) {
+		common.ErrorResponse(writer, http.StatusBadRequest, fmt.Sprintf("unsupported locale: %s", locale))
+
+		return
+	}
+
+	renderedTemplate, renderError := notificationTemplateAPIConfig.Renderer.Render(templateName, locale, sampleData)
+
+	if renderError != nil {
+		common.ErrorResponse(writer, http.StatusInternalServerError, fmt.Sprintf("error rendering template: %s", renderError))
+
+		return
+	}
+
+	if request.URL.Query().Get("format") == "html" {
+		writer.Header().Set("Content-Type", "text/html; charset=utf-8")
+		writer.WriteHeader(http.StatusOK)
+		writer.Write([]byte(renderedTemplate.HTML))
+
+		return
+	}
+
+	common.JSONResponse(writer, http.StatusOK, NotificationTemplatePreview{
+		Subject: renderedTemplate.Subject,
+		Text:    renderedTemplate.Text,
+		HTML:    renderedTemplate.HTML,
+	})
+}
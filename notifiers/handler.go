@@ -0,0 +1,277 @@
+package notifiers
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"log"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"strings"
+	"time"
+
+	"github.com/elorenzorodz/co-library/common"
+	"github.com/mailgun/mailgun-go/v4"
+)
+
+// Picks the notifier named by envConfig.Notifier. When none is named, Mailgun is used if
+// it is configured and the log notifier otherwise, so the server runs locally without keys.
+func NewNotifier(envConfig common.EnvConfig) (common.Notifier, error) {
+	notifierName := envConfig.Notifier
+
+	if notifierName == "" {
+		notifierName = NotifierLog
+
+		if envConfig.MailgunAPIKey != "" {
+			notifierName = NotifierMailgun
+		}
+	}
+
+	switch notifierName {
+	case NotifierMailgun:
+		if envConfig.MailgunAPIKey == "" || envConfig.MailgunSendingDomain == "" {
+			return nil, fmt.Errorf("MAILGUN_API_KEY and MAILGUN_SENDING_DOMAIN are required for the %s notifier", NotifierMailgun)
+		}
+
+		return NewMailgunNotifier(envConfig.MailgunSendingDomain, envConfig.MailgunAPIKey), nil
+	case NotifierSMTP:
+		if envConfig.SMTPHost == "" {
+			return nil, fmt.Errorf("SMTP_HOST is required for the %s notifier", NotifierSMTP)
+		}
+
+		return &SMTPNotifier{
+			Host:     envConfig.SMTPHost,
+			Port:     envConfig.SMTPPort,
+			Username: envConfig.SMTPUsername,
+			Password: envConfig.SMTPPassword,
+		}, nil
+	case NotifierLog:
+		return &LogNotifier{}, nil
+	case NotifierMemory:
+		return &RecordingNotifier{}, nil
+	}
+
+	return nil, fmt.Errorf("unknown notifier: %s", notifierName)
+}
+
+// The address used for messages that do not come from a particular user.
+func DefaultNotificationSender(envConfig common.EnvConfig) string {
+	if envConfig.NotificationSender != "" {
+		return envConfig.NotificationSender
+	}
+
+	domain := envConfig.MailgunSendingDomain
+
+	if domain == "" {
+		domain = "localhost"
+	}
+
+	return fmt.Sprintf("Co-Library <no-reply@%s>", domain)
+}
+
+func NewMailgunNotifier(mailGunSendingDomain, mailGunAPIKey string) *MailgunNotifier {
+	return &MailgunNotifier{
+		client: mailgun.NewMailgun(mailGunSendingDomain, mailGunAPIKey),
+	}
+}
+
+func (mailgunNotifier *MailgunNotifier) Send(ctx context.Context, notification common.Notification) error {
+	mailgunMessage := mailgun.NewMessage(
+		notification.From,
+		notification.Subject,
+		notification.Body,
+		notification.To,
+	)
+
+	if notification.HTMLBody != "" {
+		mailgunMessage.SetHTML(notification.HTMLBody)
+	}
+
+	sendContext, cancel := context.WithTimeout(ctx, time.Second*30)
+	defer cancel()
+
+	sendMessage, id, sendError := mailgunNotifier.client.Send(sendContext, mailgunMessage)
+
+	if sendError != nil {
+		log.Printf("Mailgun send error | ID: %s | Message: %s | Error: %s", id, sendMessage, sendError)
+	}
+
+	return sendError
+}
+
+func (smtpNotifier *SMTPNotifier) Send(ctx context.Context, notification common.Notification) error {
+	fromAddress, parseFromError := mail.ParseAddress(notification.From)
+
+	if parseFromError != nil {
+		return fmt.Errorf("invalid sender %q: %w", notification.From, parseFromError)
+	}
+
+	toAddress, parseToError := mail.ParseAddress(notification.To)
+
+	if parseToError != nil {
+		return fmt.Errorf("invalid recipient %q: %w", notification.To, parseToError)
+	}
+
+	address := net.JoinHostPort(smtpNotifier.Host, smtpNotifier.Port)
+	message := BuildSMTPMessage(fromAddress, toAddress, notification)
+
+	// smtp.SendMail has no timeout, so a server that stops answering would hold the send,
+	// and the job waiting on it, for good. The whole conversation gets ctx's deadline.
+	sendContext, cancel := context.WithTimeout(ctx, SMTPSendTimeout)
+	defer cancel()
+
+	dialer := net.Dialer{}
+	conn, dialError := dialer.DialContext(sendContext, "tcp", address)
+
+	if dialError != nil {
+		return fmt.Errorf("error connecting to SMTP server %s: %w", address, dialError)
+	}
+
+	deadline, _ := sendContext.Deadline()
+	conn.SetDeadline(deadline)
+
+	// Closing the connection also unblocks a read or write when ctx is cancelled early.
+	stopClosing := context.AfterFunc(sendContext, func() {
+		conn.Close()
+	})
+	defer stopClosing()
+
+	client, newClientError := smtp.NewClient(conn, smtpNotifier.Host)
+
+	if newClientError != nil {
+		conn.Close()
+
+		return fmt.Errorf("error greeting SMTP server %s: %w", address, newClientError)
+	}
+
+	defer client.Close()
+
+	sendError := smtpNotifier.sendMessage(client, fromAddress.Address, toAddress.Address, message)
+
+	if sendError != nil {
+		return fmt.Errorf("error sending through SMTP server %s: %w", address, sendError)
+	}
+
+	return nil
+}
+
+// Does what smtp.SendMail does, on a client whose connection already has a deadline.
+func (smtpNotifier *SMTPNotifier) sendMessage(client *smtp.Client, from, to string, message []byte) error {
+	if startTLS, _ := client.Extension("STARTTLS"); startTLS {
+		startTLSError := client.StartTLS(&tls.Config{ServerName: smtpNotifier.Host})
+
+		if startTLSError != nil {
+			return startTLSError
+		}
+	}
+
+	if smtpNotifier.Username != "" {
+		if supportsAuth, _ := client.Extension("AUTH"); !supportsAuth {
+			return errors.New("server doesn't support AUTH")
+		}
+
+		authError := client.Auth(smtp.PlainAuth("", smtpNotifier.Username, smtpNotifier.Password, smtpNotifier.Host))
+
+		if authError != nil {
+			return authError
+		}
+	}
+
+	mailError := client.Mail(from)
+
+	if mailError != nil {
+		return mailError
+	}
+
+	rcptError := client.Rcpt(to)
+
+	if rcptError != nil {
+		return rcptError
+	}
+
+	dataWriter, dataError := client.Data()
+
+	if dataError != nil {
+		return dataError
+	}
+
+	_, writeError := dataWriter.Write(message)
+
+	if writeError != nil {
+		return writeError
+	}
+
+	closeError := dataWriter.Close()
+
+	if closeError != nil {
+		return closeError
+	}
+
+	return client.Quit()
+}
+
+// Builds a plain text message, or a multipart/alternative one when the notification has an HTML body.
+func BuildSMTPMessage(fromAddress, toAddress *mail.Address, notification common.Notification) []byte {
+	var message strings.Builder
+
+	fmt.Fprintf(&message, "From: %s\r\n", fromAddress.String())
+	fmt.Fprintf(&message, "To: %s\r\n", toAddress.String())
+	fmt.Fprintf(&message, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", notification.Subject))
+	message.WriteString("MIME-Version: 1.0\r\n")
+
+	if notification.HTMLBody == "" {
+		message.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+		message.WriteString("\r\n")
+		message.WriteString(strings.ReplaceAll(notification.Body, "\n", "\r\n"))
+
+		return []byte(message.String())
+	}
+
+	var parts bytes.Buffer
+	partsWriter := multipart.NewWriter(&parts)
+
+	for _, part := range []struct{ contentType, body string }{
+		{"text/plain; charset=UTF-8", notification.Body},
+		{"text/html; charset=UTF-8", notification.HTMLBody},
+	} {
+		partWriter, _ := partsWriter.CreatePart(textproto.MIMEHeader{"Content-Type": {part.contentType}})
+		partWriter.Write([]byte(strings.ReplaceAll(part.body, "\n", "\r\n")))
+	}
+
+	partsWriter.Close()
+
+	fmt.Fprintf(&message, "Content-Type: multipart/alternative; boundary=%s\r\n", partsWriter.Boundary())
+	message.WriteString("\r\n")
+	message.Write(parts.Bytes())
+
+	return []byte(message.String())
+}
+
+func (logNotifier *LogNotifier) Send(ctx context.Context, notification common.Notification) error {
+	log.Printf("notification | From: %s | To: %s | Subject: %s\n%s", notification.From, notification.To, notification.Subject, notification.Body)
+
+	return nil
+}
+
+func (recordingNotifier *RecordingNotifier) Send(ctx context.Context, notification common.Notification) error {
+	recordingNotifier.mutex.Lock()
+	defer recordingNotifier.mutex.Unlock()
+
+	recordingNotifier.notifications = append(recordingNotifier.notifications, notification)
+
+	return nil
+}
+
+// Returns a copy of everything sent so far.
+func (recordingNotifier *RecordingNotifier) Notifications() []common.Notification {
+	recordingNotifier.mutex.Lock()
+	defer recordingNotifier.mutex.Unlock()
+
+	return append([]common.Notification{}, recordingNotifier.notifications...)
+}
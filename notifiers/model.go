@@ -0,0 +1,40 @@
+package notifiers
+
+import (
+	"sync"
+	"time"
+
+	"github.com/elorenzorodz/co-library/common"
+	"github.com/mailgun/mailgun-go/v4"
+)
+
+// Values accepted by the NOTIFIER environment variable.
+const (
+	NotifierMailgun = "mailgun"
+	NotifierSMTP    = "smtp"
+	NotifierLog     = "log"
+	NotifierMemory  = "memory"
+)
+
+type MailgunNotifier struct {
+	client *mailgun.MailgunImpl
+}
+
+// How long one message can take to go through the SMTP server, from dial to QUIT.
+const SMTPSendTimeout = 30 * time.Second
+
+type SMTPNotifier struct {
+	Host     string
+	Port     string
+	Username string
+	Password string
+}
+
+// Writes notifications to the server log instead of sending them, for local development.
+type LogNotifier struct{}
+
+// Keeps every notification in memory so tests can assert on what was sent.
+type RecordingNotifier struct {
+	mutex         sync.Mutex
+	notifications []common.Notification
+}
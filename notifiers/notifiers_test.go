@@ -0,0 +1,205 @@
+package notifiers
+
+import (
+	"context"
+	"net"
+	"net/mail"
+	"net/textproto"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/elorenzorodz/co-library/common"
+)
+
+func TestNewNotifier(tTesting *testing.T) {
+	// 1. Success: falls back to the log notifier when Mailgun is not configured.
+	tTesting.Run("DefaultsToLog", func(t *testing.T) {
+		notifier, err := NewNotifier(common.EnvConfig{})
+
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		if _, ok := notifier.(*LogNotifier); !ok {
+			t.Errorf("Expected *LogNotifier, got %T", notifier)
+		}
+	})
+
+	// 2. Success: Mailgun is used when its key is set.
+	tTesting.Run("DefaultsToMailgunWhenConfigured", func(t *testing.T) {
+		notifier, err := NewNotifier(common.EnvConfig{MailgunAPIKey: "key", MailgunSendingDomain: "example.com"})
+
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		if _, ok := notifier.(*MailgunNotifier); !ok {
+			t.Errorf("Expected *MailgunNotifier, got %T", notifier)
+		}
+	})
+
+	// 3. Success: notifier picked by name
+	tTesting.Run("SMTP", func(t *testing.T) {
+		notifier, err := NewNotifier(common.EnvConfig{Notifier: NotifierSMTP, SMTPHost: "localhost", SMTPPort: "1025"})
+
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		if _, ok := notifier.(*SMTPNotifier); !ok {
+			t.Errorf("Expected *SMTPNotifier, got %T", notifier)
+		}
+	})
+
+	// 4. Failure: Mailgun requested without a key
+	tTesting.Run("MailgunMissingKey", func(t *testing.T) {
+		if _, err := NewNotifier(common.EnvConfig{Notifier: NotifierMailgun}); err == nil {
+			t.Error("Expected an error, got nil")
+		}
+	})
+
+	// 5. Failure: unknown notifier
+	tTesting.Run("Unknown", func(t *testing.T) {
+		if _, err := NewNotifier(common.EnvConfig{Notifier: "pigeon"}); err == nil {
+			t.Error("Expected an error, got nil")
+		}
+	})
+}
+
+func TestRecordingNotifier(tTesting *testing.T) {
+	recordingNotifier := &RecordingNotifier{}
+	notification := common.Notification{From: "a <a@example.com>", To: "b <b@example.com>", Subject: "Hello", Body: "Hi"}
+
+	if err := recordingNotifier.Send(context.Background(), notification); err != nil {
+		tTesting.Fatalf("Expected no error, got %v", err)
+	}
+
+	notifications := recordingNotifier.Notifications()
+
+	if len(notifications) != 1 || notifications[0] != notification {
+		tTesting.Errorf("Expected the sent notification to be recorded, got %+v", notifications)
+	}
+}
+
+func TestBuildSMTPMessage(tTesting *testing.T) {
+	fromAddress := &mail.Address{Name: "Jane Doe", Address: "jane@example.com"}
+	toAddress := &mail.Address{Name: "John Doe", Address: "john@example.com"}
+
+	message := string(BuildSMTPMessage(fromAddress, toAddress, common.Notification{Subject: "Hello", Body: "Line one\nLine two"}))
+
+	for _, expected := range []string{"From: \"Jane Doe\" <jane@example.com>\r\n", "To: \"John Doe\" <john@example.com>\r\n", "Subject: Hello\r\n", "\r\n\r\nLine one\r\nLine two"} {
+		if !strings.Contains(message, expected) {
+			tTesting.Errorf("Expected message to contain %q, got %q", expected, message)
+		}
+	}
+}
+
+func TestBuildSMTPMessageWithHTML(tTesting *testing.T) {
+	fromAddress := &mail.Address{Name: "Jane Doe", Address: "jane@example.com"}
+	toAddress := &mail.Address{Name: "John Doe", Address: "john@example.com"}
+
+	message := string(BuildSMTPMessage(fromAddress, toAddress, common.Notification{Subject: "Hola señor", Body: "Plain", HTMLBody: "<p>Rich</p>"}))
+
+	for _, expected := range []string{"Subject: =?utf-8?q?Hola_se=C3=B1or?=\r\n", "Content-Type: multipart/alternative; boundary=", "Content-Type: text/plain; charset=UTF-8\r\n\r\nPlain", "Content-Type: text/html; charset=UTF-8\r\n\r\n<p>Rich</p>"} {
+		if !strings.Contains(message, expected) {
+			tTesting.Errorf("Expected message to contain %q, got %q", expected, message)
+		}
+	}
+}
+
+// Starts an SMTP server on a free local port that answers every command with handle.
+func startTestSMTPServer(t *testing.T, handle func(conn *textproto.Conn)) (string, string) {
+	listener, listenError := net.Listen("tcp", "127.0.0.1:0")
+
+	if listenError != nil {
+		t.Fatalf("Expected a listener, got %v", listenError)
+	}
+
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		conn, acceptError := listener.Accept()
+
+		if acceptError != nil {
+			return
+		}
+
+		defer conn.Close()
+
+		handle(textproto.NewConn(conn))
+	}()
+
+	host, port, _ := net.SplitHostPort(listener.Addr().String())
+
+	return host, port
+}
+
+func TestSMTPNotifierSend(tTesting *testing.T) {
+	notification := common.Notification{From: "Jane Doe <jane@example.com>", To: "John Doe <john@example.com>", Subject: "Hello", Body: "Hi"}
+
+	// 1. Success: the message goes through the whole conversation.
+	tTesting.Run("Success", func(t *testing.T) {
+		received := make(chan string, 1)
+
+		host, port := startTestSMTPServer(t, func(conn *textproto.Conn) {
+			conn.PrintfLine("220 test ESMTP")
+
+			for {
+				line, readError := conn.ReadLine()
+
+				if readError != nil {
+					return
+				}
+
+				switch {
+				case strings.HasPrefix(line, "EHLO"):
+					conn.PrintfLine("250 test")
+				case strings.HasPrefix(line, "DATA"):
+					conn.PrintfLine("354 go ahead")
+					data, _ := conn.ReadDotBytes()
+					received <- string(data)
+					conn.PrintfLine("250 queued")
+				case strings.HasPrefix(line, "QUIT"):
+					conn.PrintfLine("221 bye")
+					return
+				default:
+					conn.PrintfLine("250 ok")
+				}
+			}
+		})
+
+		smtpNotifier := &SMTPNotifier{Host: host, Port: port}
+
+		if err := smtpNotifier.Send(context.Background(), notification); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		if message := <-received; !strings.Contains(message, "Subject: Hello") {
+			t.Errorf("Expected the message to reach the server, got %q", message)
+		}
+	})
+
+	// 2. Failure: a server that never answers fails the send once ctx's deadline passes.
+	tTesting.Run("ServerNeverResponds", func(t *testing.T) {
+		host, port := startTestSMTPServer(t, func(conn *textproto.Conn) {
+			time.Sleep(5 * time.Second)
+		})
+
+		smtpNotifier := &SMTPNotifier{Host: host, Port: port}
+
+		sendContext, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+		defer cancel()
+
+		startedAt := time.Now()
+		err := smtpNotifier.Send(sendContext, notification)
+
+		if err == nil {
+			t.Fatal("Expected an error, got nil")
+		}
+
+		if elapsed := time.Since(startedAt); elapsed > 2*time.Second {
+			t.Errorf("Expected the send to give up at the deadline, it took %s", elapsed)
+		}
+	})
+}
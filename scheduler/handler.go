@@ -0,0 +1,59 @@
+package scheduler
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+func New() *Scheduler {
+	return &Scheduler{}
+}
+
+// Register must be called before Start.
+func (scheduler *Scheduler) Register(job Job) {
+	scheduler.jobs = append(scheduler.jobs, job)
+}
+
+func (scheduler *Scheduler) Start(ctx context.Context) {
+	schedulerContext, cancel := context.WithCancel(ctx)
+	scheduler.cancel = cancel
+
+	for _, job := range scheduler.jobs {
+		scheduler.waitGroup.Add(1)
+
+		go scheduler.runJob(schedulerContext, job)
+	}
+
+	log.Printf("scheduler started with %v jobs", len(scheduler.jobs))
+}
+
+// Stop cancels the running jobs and waits for them to finish.
+func (scheduler *Scheduler) Stop() {
+	if scheduler.cancel != nil {
+		scheduler.cancel()
+	}
+
+	scheduler.waitGroup.Wait()
+
+	log.Print("scheduler stopped")
+}
+
+func (scheduler *Scheduler) runJob(ctx context.Context, job Job) {
+	defer scheduler.waitGroup.Done()
+
+	ticker := time.NewTicker(job.Interval)
+	defer ticker.Stop()
+
+	for {
+		if runError := job.Run(ctx); runError != nil {
+			log.Printf("scheduled job %s failed: %s", job.Name, runError)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,21 @@
+package scheduler
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// A Job is run once when the scheduler starts and then on every tick of its interval.
+// Runs of the same job never overlap.
+type Job struct {
+	Name     string
+	Interval time.Duration
+	Run      func(ctx context.Context) error
+}
+
+type Scheduler struct {
+	jobs      []Job
+	cancel    context.CancelFunc
+	waitGroup sync.WaitGroup
+}
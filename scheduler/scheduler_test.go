@@ -0,0 +1,83 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestScheduler(tTesting *testing.T) {
+	// 1. Success: job runs on start and again on every tick.
+	tTesting.Run("RunsOnInterval", func(t *testing.T) {
+		var runs atomic.Int32
+
+		testScheduler := New()
+		testScheduler.Register(Job{
+			Name:     "counter",
+			Interval: 10 * time.Millisecond,
+			Run: func(ctx context.Context) error {
+				runs.Add(1)
+
+				return nil
+			},
+		})
+
+		testScheduler.Start(context.Background())
+		time.Sleep(55 * time.Millisecond)
+		testScheduler.Stop()
+
+		if runs.Load() < 2 {
+			t.Errorf("Expected job to run at least twice, ran %d times", runs.Load())
+		}
+	})
+
+	// 2. Success: a failing run does not stop the job.
+	tTesting.Run("KeepsRunningAfterError", func(t *testing.T) {
+		var runs atomic.Int32
+
+		testScheduler := New()
+		testScheduler.Register(Job{
+			Name:     "failing",
+			Interval: 10 * time.Millisecond,
+			Run: func(ctx context.Context) error {
+				runs.Add(1)
+
+				return errors.New("boom")
+			},
+		})
+
+		testScheduler.Start(context.Background())
+		time.Sleep(35 * time.Millisecond)
+		testScheduler.Stop()
+
+		if runs.Load() < 2 {
+			t.Errorf("Expected job to keep running after an error, ran %d times", runs.Load())
+		}
+	})
+
+	// 3. Success: Stop waits for the job and cancels its context.
+	tTesting.Run("StopCancelsJobs", func(t *testing.T) {
+		var cancelled atomic.Bool
+
+		testScheduler := New()
+		testScheduler.Register(Job{
+			Name:     "blocking",
+			Interval: time.Hour,
+			Run: func(ctx context.Context) error {
+				<-ctx.Done()
+				cancelled.Store(true)
+
+				return ctx.Err()
+			},
+		})
+
+		testScheduler.Start(context.Background())
+		testScheduler.Stop()
+
+		if !cancelled.Load() {
+			t.Error("Expected Stop to cancel the running job")
+		}
+	})
+}
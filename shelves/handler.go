@@ -0,0 +1,130 @@
+package shelves
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/elorenzorodz/co-library/books"
+	"github.com/elorenzorodz/co-library/common"
+	"github.com/elorenzorodz/co-library/internal/database"
+	"github.com/google/uuid"
+)
+
+func DatabaseShelfToShelfJSON(databaseShelf database.Shelf) Shelf {
+	return Shelf{
+		ID:          databaseShelf.ID,
+		Name:        databaseShelf.Name,
+		Description: databaseShelf.Description,
+		IsPublic:    databaseShelf.IsPublic,
+		CreatedAt:   databaseShelf.CreatedAt,
+		UpdatedAt:   databaseShelf.UpdatedAt,
+		UserID:      databaseShelf.UserID,
+	}
+}
+
+func DatabaseShelfWithBookCountToShelfJSON(databaseShelf database.Shelf, bookCount int64) Shelf {
+	shelf := DatabaseShelfToShelfJSON(databaseShelf)
+	shelf.BookCount = &bookCount
+
+	return shelf
+}
+
+func DatabaseShelvesRowsToShelvesJSON(databaseRows []database.GetShelvesByUserIDRow) []Shelf {
+	shelves := []Shelf{}
+
+	for _, databaseRow := range databaseRows {
+		shelves = append(shelves, DatabaseShelfWithBookCountToShelfJSON(databaseRow.Shelf, databaseRow.BookCount))
+	}
+
+	return shelves
+}
+
+func DatabaseShelfBookToShelfBookJSON(databaseShelfBook database.ShelfBook) ShelfBook {
+	return ShelfBook{
+		ShelfID:  databaseShelfBook.ShelfID,
+		BookID:   databaseShelfBook.BookID,
+		Position: databaseShelfBook.Position,
+		AddedAt:  databaseShelfBook.AddedAt,
+	}
+}
+
+func DatabaseShelfBooksRowsToShelfBooksJSON(databaseRows []database.GetShelfBooksRow) []ShelfBook {
+	shelfBooks := []ShelfBook{}
+
+	for _, databaseRow := range databaseRows {
+		book := books.DatabaseBookToBookJSON(databaseRow.Book)
+		book.Genres = append(book.Genres, databaseRow.Genres...)
+		book.Tags = append(book.Tags, databaseRow.Tags...)
+
+		shelfBook := DatabaseShelfBookToShelfBookJSON(databaseRow.ShelfBook)
+		shelfBook.Book = &book
+
+		shelfBooks = append(shelfBooks, shelfBook)
+	}
+
+	return shelfBooks
+}
+
+// Books on a shelf are paged in shelf order.
+func ShelfBookCursor(databaseRow database.GetShelfBooksRow) common.Cursor {
+	return common.Cursor{Value: strconv.Itoa(int(databaseRow.ShelfBook.Position)), ID: databaseRow.Book.ID}
+}
+
+// The position of the last book on the previous page, zero on the first page.
+func ParseShelfBookCursor(pageParameters common.PageParameters) (int32, error) {
+	if pageParameters.Cursor == nil {
+		return 0, nil
+	}
+
+	cursorPosition, parseError := strconv.ParseInt(pageParameters.Cursor.Value, 10, 32)
+
+	if parseError != nil {
+		return 0, errors.New("invalid cursor")
+	}
+
+	return int32(cursorPosition), nil
+}
+
+func ParseShelfName(name string) (string, error) {
+	name = strings.TrimSpace(name)
+
+	if name == "" {
+		return "", errors.New("name is required")
+	}
+
+	if len([]rune(name)) > MaxShelfNameLength {
+		return "", fmt.Errorf("name can be at most %d characters long", MaxShelfNameLength)
+	}
+
+	return name, nil
+}
+
+// Private shelves are only shown to their owner.
+func CanViewShelf(databaseShelf database.Shelf, viewerId uuid.UUID) bool {
+	return databaseShelf.IsPublic || databaseShelf.UserID == viewerId
+}
+
+// Whether bookIds lists every book on the shelf exactly once.
+func IsShelfOrder(bookIds []uuid.UUID, shelfBookIds []uuid.UUID) bool {
+	if len(bookIds) != len(shelfBookIds) {
+		return false
+	}
+
+	remaining := map[uuid.UUID]bool{}
+
+	for _, shelfBookId := range shelfBookIds {
+		remaining[shelfBookId] = true
+	}
+
+	for _, bookId := range bookIds {
+		if !remaining[bookId] {
+			return false
+		}
+
+		delete(remaining, bookId)
+	}
+
+	return true
+}
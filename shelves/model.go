@@ -0,0 +1,48 @@
+package shelves
+
+import (
+	"time"
+
+	"github.com/elorenzorodz/co-library/books"
+	"github.com/elorenzorodz/co-library/common"
+	"github.com/google/uuid"
+)
+
+type ShelfAPIConfig struct {
+	common.APIConfig
+}
+
+const MaxShelfNameLength = 64
+
+type Shelf struct {
+	ID          uuid.UUID `json:"id"`
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	IsPublic    bool      `json:"is_public"`
+	CreatedAt   time.Time `json:"createdAt"`
+	UpdatedAt   time.Time `json:"updatedAt"`
+	UserID      uuid.UUID `json:"user_id"`
+	// Only filled in by the endpoints that count the books on the shelf.
+	BookCount *int64 `json:"book_count,omitempty"`
+}
+
+type ShelfBook struct {
+	ShelfID  uuid.UUID `json:"shelf_id"`
+	BookID   uuid.UUID `json:"book_id"`
+	Position int32     `json:"position"`
+	AddedAt  time.Time `json:"addedAt"`
+	// Only filled in when listing the books on a shelf.
+	Book *books.Book `json:"book,omitempty"`
+}
+
+// Fields left out on update keep their current value.
+type UpsertShelfParameters struct {
+	Name        string  `json:"name"`
+	Description *string `json:"description"`
+	IsPublic    *bool   `json:"is_public"`
+}
+
+// Every book on the shelf, in the order they should be shown.
+type ReorderShelfBooksParameters struct {
+	BookIDs []uuid.UUID `json:"book_ids"`
+}
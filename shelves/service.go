@@ -0,0 +1,481 @@
+package shelves
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/elorenzorodz/co-library/common"
+	"github.com/elorenzorodz/co-library/internal/database"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+func (shelfAPIConfig *ShelfAPIConfig) CreateShelf(writer http.ResponseWriter, request *http.Request, userId uuid.UUID) {
+	upsertShelfParameters := UpsertShelfParameters{}
+
+	decoder := json.NewDecoder(request.Body)
+	decoderError := decoder.Decode(&upsertShelfParameters)
+
+	if decoderError != nil {
+		common.ErrorResponse(writer, http.StatusBadRequest, fmt.Sprintf("error parsing JSON: %s", decoderError))
+
+		return
+	}
+
+	name, parseNameError := ParseShelfName(upsertShelfParameters.Name)
+
+	if parseNameError != nil {
+		common.ErrorResponse(writer, http.StatusBadRequest, parseNameError.Error())
+
+		return
+	}
+
+	getShelfByNameParams := database.GetShelfByNameParams{
+		UserID: userId,
+		Name:   name,
+	}
+
+	_, getShelfByNameError := shelfAPIConfig.DB.GetShelfByName(request.Context(), getShelfByNameParams)
+
+	if getShelfByNameError != nil && getShelfByNameError != sql.ErrNoRows {
+		common.ErrorResponse(writer, http.StatusInternalServerError, "failed to create shelf, please try again in a few minutes")
+
+		return
+	} else if getShelfByNameError == nil {
+		common.ErrorResponse(writer, http.StatusConflict, fmt.Sprintf("you already have a shelf named %s", name))
+
+		return
+	}
+
+	createShelfParams := database.CreateShelfParams{
+		ID:     uuid.New(),
+		Name:   name,
+		UserID: userId,
+	}
+
+	if upsertShelfParameters.Description != nil {
+		createShelfParams.Description = *upsertShelfParameters.Description
+	}
+
+	if upsertShelfParameters.IsPublic != nil {
+		createShelfParams.IsPublic = *upsertShelfParameters.IsPublic
+	}
+
+	newShelf, createShelfError := shelfAPIConfig.DB.CreateShelf(request.Context(), createShelfParams)
+
+	if createShelfError != nil {
+		common.ErrorResponse(writer, http.StatusInternalServerError, fmt.Sprintf("error creating shelf: %s", createShelfError))
+
+		return
+	}
+
+	common.JSONResponse(writer, http.StatusCreated, DatabaseShelfWithBookCountToShelfJSON(newShelf, 0))
+}
+
+func (shelfAPIConfig *ShelfAPIConfig) GetShelves(writer http.ResponseWriter, request *http.Request, userId uuid.UUID) {
+	getShelvesParams := database.GetShelvesByUserIDParams{
+		ViewerID:       userId,
+		UserID:         userId,
+		IncludePrivate: true,
+	}
+
+	getShelves, getShelvesError := shelfAPIConfig.DB.GetShelvesByUserID(request.Context(), getShelvesParams)
+
+	if getShelvesError != nil {
+		common.ErrorResponse(writer, http.StatusInternalServerError, fmt.Sprintf("error getting shelves: %s", getShelvesError))
+
+		return
+	}
+
+	common.JSONResponse(writer, http.StatusOK, DatabaseShelvesRowsToShelvesJSON(getShelves))
+}
+
+// Other members only see the public shelves.
+func (shelfAPIConfig *ShelfAPIConfig) GetUserShelves(writer http.ResponseWriter, request *http.Request, viewerId uuid.UUID) {
+	vars := mux.Vars(request)
+	userId, parseUserIdError := uuid.Parse(vars["userId"])
+
+	if parseUserIdError != nil {
+		common.ErrorResponse(writer, http.StatusBadRequest, "invalid user id")
+
+		return
+	}
+
+	getShelvesParams := database.GetShelvesByUserIDParams{
+		ViewerID:       viewerId,
+		UserID:         userId,
+		IncludePrivate: userId == viewerId,
+	}
+
+	getShelves, getShelvesError := shelfAPIConfig.DB.GetShelvesByUserID(request.Context(), getShelvesParams)
+
+	if getShelvesError != nil {
+		common.ErrorResponse(writer, http.StatusInternalServerError, fmt.Sprintf("error getting shelves: %s", getShelvesError))
+
+		return
+	}
+
+	common.JSONResponse(writer, http.StatusOK, DatabaseShelvesRowsToShelvesJSON(getShelves))
+}
+
+func (shelfAPIConfig *ShelfAPIConfig) GetShelf(writer http.ResponseWriter, request *http.Request, viewerId uuid.UUID) {
+	vars := mux.Vars(request)
+	shelfId, parseShelfIdError := uuid.Parse(vars["shelfId"])
+
+	if parseShelfIdError != nil {
+		common.ErrorResponse(writer, http.StatusBadRequest, "invalid shelf id")
+
+		return
+	}
+
+	getShelf, getShelfError := shelfAPIConfig.DB.GetShelf(request.Context(), database.GetShelfParams{ID: shelfId, ViewerID: viewerId})
+
+	if getShelfError != nil && getShelfError != sql.ErrNoRows {
+		common.ErrorResponse(writer, http.StatusInternalServerError, "error getting shelf details, please try again in a few minutes")
+
+		return
+	}
+
+	if getShelfError == sql.ErrNoRows || !CanViewShelf(getShelf.Shelf, viewerId) {
+		common.ErrorResponse(writer, http.StatusNotFound, "shelf not found")
+
+		return
+	}
+
+	common.JSONResponse(writer, http.StatusOK, DatabaseShelfWithBookCountToShelfJSON(getShelf.Shelf, getShelf.BookCount))
+}
+
+func (shelfAPIConfig *ShelfAPIConfig) UpdateShelf(writer http.ResponseWriter, request *http.Request, userId uuid.UUID) {
+	vars := mux.Vars(request)
+	shelfId, parseShelfIdError := uuid.Parse(vars["shelfId"])
+
+	if parseShelfIdError != nil {
+		common.ErrorResponse(writer, http.StatusBadRequest, "invalid shelf id")
+
+		return
+	}
+
+	upsertShelfParameters := UpsertShelfParameters{}
+
+	decoder := json.NewDecoder(request.Body)
+	decoderError := decoder.Decode(&upsertShelfParameters)
+
+	if decoderError != nil {
+		common.ErrorResponse(writer, http.StatusBadRequest, fmt.Sprintf("error parsing JSON: %s", decoderError))
+
+		return
+	}
+
+	updateShelfParams := database.UpdateShelfParams{
+		ID:     shelfId,
+		UserID: userId,
+	}
+
+	if upsertShelfParameters.Name != "" {
+		name, parseNameError := ParseShelfName(upsertShelfParameters.Name)
+
+		if parseNameError != nil {
+			common.ErrorResponse(writer, http.StatusBadRequest, parseNameError.Error())
+
+			return
+		}
+
+		getShelfByNameParams := database.GetShelfByNameParams{
+			UserID: userId,
+			Name:   name,
+		}
+
+		// Renaming a shelf to its own name in another case is fine.
+		namedShelf, getShelfByNameError := shelfAPIConfig.DB.GetShelfByName(request.Context(), getShelfByNameParams)
+
+		if getShelfByNameError != nil && getShelfByNameError != sql.ErrNoRows {
+			common.ErrorResponse(writer, http.StatusInternalServerError, "error updating shelf, please try again in a few minutes")
+
+			return
+		} else if getShelfByNameError == nil && namedShelf.ID != shelfId {
+			common.ErrorResponse(writer, http.StatusConflict, fmt.Sprintf("you already have a shelf named %s", name))
+
+			return
+		}
+
+		updateShelfParams.Name = sql.NullString{String: name, Valid: true}
+	}
+
+	if upsertShelfParameters.Description != nil {
+		updateShelfParams.Description = sql.NullString{String: *upsertShelfParameters.Description, Valid: true}
+	}
+
+	if upsertShelfParameters.IsPublic != nil {
+		updateShelfParams.IsPublic = sql.NullBool{Bool: *upsertShelfParameters.IsPublic, Valid: true}
+	}
+
+	updateShelf, updateShelfError := shelfAPIConfig.DB.UpdateShelf(request.Context(), updateShelfParams)
+
+	if updateShelfError != nil {
+		if updateShelfError == sql.ErrNoRows {
+			common.ErrorResponse(writer, http.StatusNotFound, "shelf not found")
+		} else {
+			common.ErrorResponse(writer, http.StatusInternalServerError, "error updating shelf, please try again in a few minutes")
+		}
+
+		return
+	}
+
+	common.JSONResponse(writer, http.StatusOK, DatabaseShelfToShelfJSON(updateShelf))
+}
+
+func (shelfAPIConfig *ShelfAPIConfig) DeleteShelf(writer http.ResponseWriter, request *http.Request, userId uuid.UUID) {
+	vars := mux.Vars(request)
+	shelfId, parseShelfIdError := uuid.Parse(vars["shelfId"])
+
+	if parseShelfIdError != nil {
+		common.ErrorResponse(writer, http.StatusBadRequest, "invalid shelf id")
+
+		return
+	}
+
+	deleteShelfParams := database.DeleteShelfParams{
+		ID:     shelfId,
+		UserID: userId,
+	}
+
+	// The books themselves stay in the library, only the shelf goes.
+	rowsAffected, deleteShelfError := shelfAPIConfig.DB.DeleteShelf(request.Context(), deleteShelfParams)
+
+	if deleteShelfError != nil {
+		common.ErrorResponse(writer, http.StatusInternalServerError, fmt.Sprintf("error deleting shelf: %s", deleteShelfError))
+
+		return
+	}
+
+	if rowsAffected == 0 {
+		common.ErrorResponse(writer, http.StatusNotFound, "shelf not found")
+
+		return
+	}
+
+	common.JSONResponse(writer, http.StatusOK, "shelf successfully deleted")
+}
+
+func (shelfAPIConfig *ShelfAPIConfig) AddShelfBook(writer http.ResponseWriter, request *http.Request, userId uuid.UUID) {
+	vars := mux.Vars(request)
+	shelfId, parseShelfIdError := uuid.Parse(vars["shelfId"])
+
+	if parseShelfIdError != nil {
+		common.ErrorResponse(writer, http.StatusBadRequest, "invalid shelf id")
+
+		return
+	}
+
+	bookId, parseBookIdError := uuid.Parse(vars["bookId"])
+
+	if parseBookIdError != nil {
+		common.ErrorResponse(writer, http.StatusBadRequest, "invalid book id")
+
+		return
+	}
+
+	getShelf, getShelfError := shelfAPIConfig.DB.GetShelf(request.Context(), database.GetShelfParams{ID: shelfId, ViewerID: userId})
+
+	if getShelfError != nil && getShelfError != sql.ErrNoRows {
+		common.ErrorResponse(writer, http.StatusInternalServerError, "failed to add book to shelf, please try again in a few minutes")
+
+		return
+	}
+
+	if getShelfError == sql.ErrNoRows || getShelf.Shelf.UserID != userId {
+		common.ErrorResponse(writer, http.StatusNotFound, "shelf not found")
+
+		return
+	}
+
+	addShelfBookParams := database.AddShelfBookParams{
+		ShelfID: shelfId,
+		BookID:  bookId,
+		UserID:  userId,
+	}
+
+	shelfBook, addShelfBookError := shelfAPIConfig.DB.AddShelfBook(request.Context(), addShelfBookParams)
+
+	if addShelfBookError != nil {
+		if addShelfBookError == sql.ErrNoRows {
+			common.ErrorResponse(writer, http.StatusNotFound, "book not found, only your own books can go on your shelves")
+		} else {
+			common.ErrorResponse(writer, http.StatusInternalServerError, fmt.Sprintf("error adding book to shelf: %s", addShelfBookError))
+		}
+
+		return
+	}
+
+	common.JSONResponse(writer, http.StatusCreated, DatabaseShelfBookToShelfBookJSON(shelfBook))
+}
+
+func (shelfAPIConfig *ShelfAPIConfig) RemoveShelfBook(writer http.ResponseWriter, request *http.Request, userId uuid.UUID) {
+	vars := mux.Vars(request)
+	shelfId, parseShelfIdError := uuid.Parse(vars["shelfId"])
+
+	if parseShelfIdError != nil {
+		common.ErrorResponse(writer, http.StatusBadRequest, "invalid shelf id")
+
+		return
+	}
+
+	bookId, parseBookIdError := uuid.Parse(vars["bookId"])
+
+	if parseBookIdError != nil {
+		common.ErrorResponse(writer, http.StatusBadRequest, "invalid book id")
+
+		return
+	}
+
+	removeShelfBookParams := database.RemoveShelfBookParams{
+		ShelfID: shelfId,
+		BookID:  bookId,
+		UserID:  userId,
+	}
+
+	rowsAffected, removeShelfBookError := shelfAPIConfig.DB.RemoveShelfBook(request.Context(), removeShelfBookParams)
+
+	if removeShelfBookError != nil {
+		common.ErrorResponse(writer, http.StatusInternalServerError, fmt.Sprintf("error removing book from shelf: %s", removeShelfBookError))
+
+		return
+	}
+
+	if rowsAffected == 0 {
+		common.ErrorResponse(writer, http.StatusNotFound, "book not found on shelf")
+
+		return
+	}
+
+	common.JSONResponse(writer, http.StatusOK, "book successfully removed from shelf")
+}
+
+func (shelfAPIConfig *ShelfAPIConfig) GetShelfBooks(writer http.ResponseWriter, request *http.Request, viewerId uuid.UUID) {
+	vars := mux.Vars(request)
+	shelfId, parseShelfIdError := uuid.Parse(vars["shelfId"])
+
+	if parseShelfIdError != nil {
+		common.ErrorResponse(writer, http.StatusBadRequest, "invalid shelf id")
+
+		return
+	}
+
+	pageParameters, parsePageError := common.ParsePageParameters(request.URL.Query())
+
+	if parsePageError != nil {
+		common.ErrorResponse(writer, http.StatusBadRequest, parsePageError.Error())
+
+		return
+	}
+
+	cursorPosition, parseCursorError := ParseShelfBookCursor(pageParameters)
+
+	if parseCursorError != nil {
+		common.ErrorResponse(writer, http.StatusBadRequest, parseCursorError.Error())
+
+		return
+	}
+
+	getShelf, getShelfError := shelfAPIConfig.DB.GetShelf(request.Context(), database.GetShelfParams{ID: shelfId, ViewerID: viewerId})
+
+	if getShelfError != nil && getShelfError != sql.ErrNoRows {
+		common.ErrorResponse(writer, http.StatusInternalServerError, "error getting shelf details, please try again in a few minutes")
+
+		return
+	}
+
+	if getShelfError == sql.ErrNoRows || !CanViewShelf(getShelf.Shelf, viewerId) {
+		common.ErrorResponse(writer, http.StatusNotFound, "shelf not found")
+
+		return
+	}
+
+	// Public shelves can hold books the viewer is not allowed to see, those are left out.
+	getShelfBooksParams := database.GetShelfBooksParams{
+		ShelfID:        shelfId,
+		ViewerID:       viewerId,
+		CursorID:       pageParameters.CursorID(),
+		CursorPosition: cursorPosition,
+		PageLimit:      pageParameters.QueryLimit(),
+	}
+
+	getShelfBooks, getShelfBooksError := shelfAPIConfig.DB.GetShelfBooks(request.Context(), getShelfBooksParams)
+
+	if getShelfBooksError != nil {
+		common.ErrorResponse(writer, http.StatusInternalServerError, fmt.Sprintf("error getting shelf books: %s", getShelfBooksError))
+
+		return
+	}
+
+	getShelfBooks, nextCursor := common.SplitPage(getShelfBooks, pageParameters, ShelfBookCursor)
+
+	common.JSONResponse(writer, http.StatusOK, common.Page[ShelfBook]{Data: DatabaseShelfBooksRowsToShelfBooksJSON(getShelfBooks), NextCursor: nextCursor})
+}
+
+func (shelfAPIConfig *ShelfAPIConfig) ReorderShelfBooks(writer http.ResponseWriter, request *http.Request, userId uuid.UUID) {
+	vars := mux.Vars(request)
+	shelfId, parseShelfIdError := uuid.Parse(vars["shelfId"])
+
+	if parseShelfIdError != nil {
+		common.ErrorResponse(writer, http.StatusBadRequest, "invalid shelf id")
+
+		return
+	}
+
+	reorderShelfBooksParameters := ReorderShelfBooksParameters{}
+
+	decoder := json.NewDecoder(request.Body)
+	decoderError := decoder.Decode(&reorderShelfBooksParameters)
+
+	if decoderError != nil {
+		common.ErrorResponse(writer, http.StatusBadRequest, fmt.Sprintf("error parsing JSON: %s", decoderError))
+
+		return
+	}
+
+	getShelf, getShelfError := shelfAPIConfig.DB.GetShelf(request.Context(), database.GetShelfParams{ID: shelfId, ViewerID: userId})
+
+	if getShelfError != nil && getShelfError != sql.ErrNoRows {
+		common.ErrorResponse(writer, http.StatusInternalServerError, "failed to reorder shelf, please try again in a few minutes")
+
+		return
+	}
+
+	if getShelfError == sql.ErrNoRows || getShelf.Shelf.UserID != userId {
+		common.ErrorResponse(writer, http.StatusNotFound, "shelf not found")
+
+		return
+	}
+
+	shelfBookIds, getShelfBookIdsError := shelfAPIConfig.DB.GetShelfBookIDs(request.Context(), shelfId)
+
+	if getShelfBookIdsError != nil {
+		common.ErrorResponse(writer, http.StatusInternalServerError, fmt.Sprintf("error reordering shelf: %s", getShelfBookIdsError))
+
+		return
+	}
+
+	if !IsShelfOrder(reorderShelfBooksParameters.BookIDs, shelfBookIds) {
+		common.ErrorResponse(writer, http.StatusBadRequest, "book_ids must list every book on the shelf exactly once")
+
+		return
+	}
+
+	reorderShelfBooksParams := database.ReorderShelfBooksParams{
+		ShelfID: shelfId,
+		BookIds: reorderShelfBooksParameters.BookIDs,
+	}
+
+	reorderShelfBooksError := shelfAPIConfig.DB.ReorderShelfBooks(request.Context(), reorderShelfBooksParams)
+
+	if reorderShelfBooksError != nil {
+		common.ErrorResponse(writer, http.StatusInternalServerError, fmt.Sprintf("error reordering shelf: %s", reorderShelfBooksError))
+
+		return
+	}
+
+	common.JSONResponse(writer, http.StatusOK, "shelf successfully reordered")
+}
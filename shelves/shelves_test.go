@@ -0,0 +1,756 @@
+package shelves
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/elorenzorodz/co-library/common"
+	"github.com/elorenzorodz/co-library/internal/database"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+type MockQueries struct {
+	*common.BaseMock
+
+	CreateShelfFunc        func(ctx context.Context, arg database.CreateShelfParams) (database.Shelf, error)
+	GetShelfFunc           func(ctx context.Context, arg database.GetShelfParams) (database.GetShelfRow, error)
+	GetShelfByNameFunc     func(ctx context.Context, arg database.GetShelfByNameParams) (database.Shelf, error)
+	GetShelvesByUserIDFunc func(ctx context.Context, arg database.GetShelvesByUserIDParams) ([]database.GetShelvesByUserIDRow, error)
+	UpdateShelfFunc        func(ctx context.Context, arg database.UpdateShelfParams) (database.Shelf, error)
+	DeleteShelfFunc        func(ctx context.Context, arg database.DeleteShelfParams) (int64, error)
+	AddShelfBookFunc       func(ctx context.Context, arg database.AddShelfBookParams) (database.ShelfBook, error)
+	RemoveShelfBookFunc    func(ctx context.Context, arg database.RemoveShelfBookParams) (int64, error)
+	GetShelfBooksFunc      func(ctx context.Context, arg database.GetShelfBooksParams) ([]database.GetShelfBooksRow, error)
+	GetShelfBookIDsFunc    func(ctx context.Context, shelfID uuid.UUID) ([]uuid.UUID, error)
+	ReorderShelfBooksFunc  func(ctx context.Context, arg database.ReorderShelfBooksParams) error
+}
+
+func (mockQueries *MockQueries) CreateShelf(ctx context.Context, arg database.CreateShelfParams) (database.Shelf, error) {
+	if mockQueries.CreateShelfFunc != nil {
+		return mockQueries.CreateShelfFunc(ctx, arg)
+	}
+
+	return mockQueries.BaseMock.CreateShelf(ctx, arg)
+}
+
+func (mockQueries *MockQueries) GetShelf(ctx context.Context, arg database.GetShelfParams) (database.GetShelfRow, error) {
+	if mockQueries.GetShelfFunc != nil {
+		return mockQueries.GetShelfFunc(ctx, arg)
+	}
+
+	return mockQueries.BaseMock.GetShelf(ctx, arg)
+}
+
+func (mockQueries *MockQueries) GetShelfByName(ctx context.Context, arg database.GetShelfByNameParams) (database.Shelf, error) {
+	if mockQueries.GetShelfByNameFunc != nil {
+		return mockQueries.GetShelfByNameFunc(ctx, arg)
+	}
+
+	return mockQueries.BaseMock.GetShelfByName(ctx, arg)
+}
+
+func (mockQueries *MockQueries) GetShelvesByUserID(ctx context.Context, arg database.GetShelvesByUserIDParams) ([]database.GetShelvesByUserIDRow, error) {
+	if mockQueries.GetShelvesByUserIDFunc != nil {
+		return mockQueries.GetShelvesByUserIDFunc(ctx, arg)
+	}
+
+	return mockQueries.BaseMock.GetShelvesByUserID(ctx, arg)
+}
+
+func (mockQueries *MockQueries) UpdateShelf(ctx context.Context, arg database.UpdateShelfParams) (database.Shelf, error) {
+	if mockQueries.UpdateShelfFunc != nil {
+		return mockQueries.UpdateShelfFunc(ctx, arg)
+	}
+
+	return mockQueries.BaseMock.UpdateShelf(ctx, arg)
+}
+
+func (mockQueries *MockQueries) DeleteShelf(ctx context.Context, arg database.DeleteShelfParams) (int64, error) {
+	if mockQueries.DeleteShelfFunc != nil {
+		return mockQueries.DeleteShelfFunc(ctx, arg)
+	}
+
+	return mockQueries.BaseMock.DeleteShelf(ctx, arg)
+}
+
+func (mockQueries *MockQueries) AddShelfBook(ctx context.Context, arg database.AddShelfBookParams) (database.ShelfBook, error) {
+	if mockQueries.AddShelfBookFunc != nil {
+		return mockQueries.AddShelfBookFunc(ctx, arg)
+	}
+
+	return mockQueries.BaseMock.AddShelfBook(ctx, arg)
+}
+
+func (mockQueries *MockQueries) RemoveShelfBook(ctx context.Context, arg database.RemoveShelfBookParams) (int64, error) {
+	if mockQueries.RemoveShelfBookFunc != nil {
+		return mockQueries.RemoveShelfBookFunc(ctx, arg)
+	}
+
+	return mockQueries.BaseMock.RemoveShelfBook(ctx, arg)
+}
+
+func (mockQueries *MockQueries) GetShelfBooks(ctx context.Context, arg database.GetShelfBooksParams) ([]database.GetShelfBooksRow, error) {
+	if mockQueries.GetShelfBooksFunc != nil {
+		return mockQueries.GetShelfBooksFunc(ctx, arg)
+	}
+
+	return mockQueries.BaseMock.GetShelfBooks(ctx, arg)
+}
+
+func (mockQueries *MockQueries) GetShelfBookIDs(ctx context.Context, shelfID uuid.UUID) ([]uuid.UUID, error) {
+	if mockQueries.GetShelfBookIDsFunc != nil {
+		return mockQueries.GetShelfBookIDsFunc(ctx, shelfID)
+	}
+
+	return mockQueries.BaseMock.GetShelfBookIDs(ctx, shelfID)
+}
+
+func (mockQueries *MockQueries) ReorderShelfBooks(ctx context.Context, arg database.ReorderShelfBooksParams) error {
+	if mockQueries.ReorderShelfBooksFunc != nil {
+		return mockQueries.ReorderShelfBooksFunc(ctx, arg)
+	}
+
+	return mockQueries.BaseMock.ReorderShelfBooks(ctx, arg)
+}
+
+func newTestShelf(userId uuid.UUID, isPublic bool) database.Shelf {
+	return database.Shelf{
+		ID:        uuid.New(),
+		Name:      "Sci-fi",
+		IsPublic:  isPublic,
+		CreatedAt: time.Now().UTC(),
+		UpdatedAt: time.Now().UTC(),
+		UserID:    userId,
+	}
+}
+
+func getShelfReturning(shelf database.Shelf, bookCount int64) func(ctx context.Context, arg database.GetShelfParams) (database.GetShelfRow, error) {
+	return func(ctx context.Context, arg database.GetShelfParams) (database.GetShelfRow, error) {
+		if arg.ID != shelf.ID {
+			return database.GetShelfRow{}, sql.ErrNoRows
+		}
+
+		return database.GetShelfRow{Shelf: shelf, BookCount: bookCount}, nil
+	}
+}
+
+func shelfRequest(method string, target string, body string, vars map[string]string) *http.Request {
+	request := httptest.NewRequest(method, target, bytes.NewBufferString(body))
+
+	return mux.SetURLVars(request, vars)
+}
+
+func TestCreateShelf(tTesting *testing.T) {
+	userId := uuid.New()
+
+	// 1. Success: names are trimmed and shelves are private unless asked otherwise.
+	tTesting.Run("Success", func(t *testing.T) {
+		var createShelfParams database.CreateShelfParams
+
+		mockQueries := &MockQueries{
+			BaseMock: common.NewBaseMock(),
+			CreateShelfFunc: func(ctx context.Context, arg database.CreateShelfParams) (database.Shelf, error) {
+				createShelfParams = arg
+
+				return database.Shelf{ID: arg.ID, Name: arg.Name, Description: arg.Description, IsPublic: arg.IsPublic, UserID: arg.UserID}, nil
+			},
+		}
+
+		apiConfig := ShelfAPIConfig{APIConfig: common.APIConfig{DB: mockQueries}}
+		recorder := httptest.NewRecorder()
+
+		apiConfig.CreateShelf(recorder, shelfRequest(http.MethodPost, "/api/v1/shelves", `{"name": "  Kids  ", "description": "For the little ones"}`, nil), userId)
+
+		if recorder.Code != http.StatusCreated {
+			t.Fatalf("Expected status %d, got %d. Body: %s", http.StatusCreated, recorder.Code, recorder.Body.String())
+		}
+
+		if createShelfParams.Name != "Kids" || createShelfParams.Description != "For the little ones" || createShelfParams.IsPublic || createShelfParams.UserID != userId {
+			t.Errorf("Unexpected shelf: %+v", createShelfParams)
+		}
+
+		var shelf Shelf
+		json.Unmarshal(recorder.Body.Bytes(), &shelf)
+
+		if shelf.BookCount == nil || *shelf.BookCount != 0 {
+			t.Errorf("Expected an empty shelf, got %+v", shelf)
+		}
+	})
+
+	// 2. Failure: the member already has a shelf with that name.
+	tTesting.Run("DuplicateName", func(t *testing.T) {
+		mockQueries := &MockQueries{
+			BaseMock: common.NewBaseMock(),
+			GetShelfByNameFunc: func(ctx context.Context, arg database.GetShelfByNameParams) (database.Shelf, error) {
+				return newTestShelf(userId, false), nil
+			},
+		}
+
+		apiConfig := ShelfAPIConfig{APIConfig: common.APIConfig{DB: mockQueries}}
+		recorder := httptest.NewRecorder()
+
+		apiConfig.CreateShelf(recorder, shelfRequest(http.MethodPost, "/api/v1/shelves", `{"name": "sci-fi"}`, nil), userId)
+
+		if recorder.Code != http.StatusConflict {
+			t.Errorf("Expected status %d, got %d. Body: %s", http.StatusConflict, recorder.Code, recorder.Body.String())
+		}
+	})
+
+	// 3. Failure: missing or too long names.
+	tTesting.Run("InvalidName", func(t *testing.T) {
+		longName := fmt.Sprintf(`{"name": "%s"}`, bytes.Repeat([]byte("a"), MaxShelfNameLength+1))
+
+		for _, body := range []string{`{}`, `{"name": "   "}`, longName, `not json`} {
+			mockQueries := &MockQueries{BaseMock: common.NewBaseMock()}
+			apiConfig := ShelfAPIConfig{APIConfig: common.APIConfig{DB: mockQueries}}
+			recorder := httptest.NewRecorder()
+
+			apiConfig.CreateShelf(recorder, shelfRequest(http.MethodPost, "/api/v1/shelves", body, nil), userId)
+
+			if recorder.Code != http.StatusBadRequest {
+				t.Errorf("%s: expected status %d, got %d", body, http.StatusBadRequest, recorder.Code)
+			}
+		}
+	})
+}
+
+func TestGetUserShelves(tTesting *testing.T) {
+	userId := uuid.New()
+
+	getUserShelves := func(t *testing.T, viewerId uuid.UUID) database.GetShelvesByUserIDParams {
+		var getShelvesParams database.GetShelvesByUserIDParams
+
+		mockQueries := &MockQueries{
+			BaseMock: common.NewBaseMock(),
+			GetShelvesByUserIDFunc: func(ctx context.Context, arg database.GetShelvesByUserIDParams) ([]database.GetShelvesByUserIDRow, error) {
+				getShelvesParams = arg
+
+				return []database.GetShelvesByUserIDRow{{Shelf: newTestShelf(userId, true), BookCount: 3}}, nil
+			},
+		}
+
+		apiConfig := ShelfAPIConfig{APIConfig: common.APIConfig{DB: mockQueries}}
+		recorder := httptest.NewRecorder()
+
+		apiConfig.GetUserShelves(recorder, shelfRequest(http.MethodGet, "/api/v1/shelves/user/"+userId.String(), "", map[string]string{"userId": userId.String()}), viewerId)
+
+		var shelves []Shelf
+		json.Unmarshal(recorder.Body.Bytes(), &shelves)
+
+		if recorder.Code != http.StatusOK || len(shelves) != 1 || *shelves[0].BookCount != 3 {
+			t.Fatalf("Unexpected response %d: %s", recorder.Code, recorder.Body.String())
+		}
+
+		return getShelvesParams
+	}
+
+	// 1. Success: other members only get the public shelves.
+	tTesting.Run("OtherMember", func(t *testing.T) {
+		viewerId := uuid.New()
+
+		if getShelvesParams := getUserShelves(t, viewerId); getShelvesParams.UserID != userId || getShelvesParams.IncludePrivate {
+			t.Errorf("Expected public shelves only, got %+v", getShelvesParams)
+		} else if getShelvesParams.ViewerID != viewerId {
+			t.Errorf("Expected books counted for viewer %s, got %s", viewerId, getShelvesParams.ViewerID)
+		}
+	})
+
+	// 2. Success: owners get all of theirs.
+	tTesting.Run("Owner", func(t *testing.T) {
+		if getShelvesParams := getUserShelves(t, userId); !getShelvesParams.IncludePrivate {
+			t.Errorf("Expected private shelves too, got %+v", getShelvesParams)
+		}
+	})
+
+	// 3. Failure: invalid user id.
+	tTesting.Run("InvalidUserID", func(t *testing.T) {
+		apiConfig := ShelfAPIConfig{APIConfig: common.APIConfig{DB: &MockQueries{BaseMock: common.NewBaseMock()}}}
+		recorder := httptest.NewRecorder()
+
+		apiConfig.GetUserShelves(recorder, shelfRequest(http.MethodGet, "/api/v1/shelves/user/not-a-uuid", "", map[string]string{"userId": "not-a-uuid"}), userId)
+
+		if recorder.Code != http.StatusBadRequest {
+			t.Errorf("Expected status %d, got %d", http.StatusBadRequest, recorder.Code)
+		}
+	})
+}
+
+func TestGetShelf(tTesting *testing.T) {
+	userId := uuid.New()
+	publicShelf := newTestShelf(userId, true)
+	privateShelf := newTestShelf(userId, false)
+
+	getShelf := func(shelf database.Shelf, viewerId uuid.UUID) *httptest.ResponseRecorder {
+		mockQueries := &MockQueries{BaseMock: common.NewBaseMock(), GetShelfFunc: getShelfReturning(shelf, 2)}
+		apiConfig := ShelfAPIConfig{APIConfig: common.APIConfig{DB: mockQueries}}
+		recorder := httptest.NewRecorder()
+
+		apiConfig.GetShelf(recorder, shelfRequest(http.MethodGet, "/api/v1/shelves/"+shelf.ID.String(), "", map[string]string{"shelfId": shelf.ID.String()}), viewerId)
+
+		return recorder
+	}
+
+	// 1. Success: public shelves can be shared with anyone.
+	tTesting.Run("Public", func(t *testing.T) {
+		recorder := getShelf(publicShelf, uuid.New())
+
+		var shelf Shelf
+		json.Unmarshal(recorder.Body.Bytes(), &shelf)
+
+		if recorder.Code != http.StatusOK || shelf.ID != publicShelf.ID || *shelf.BookCount != 2 {
+			t.Errorf("Unexpected response %d: %s", recorder.Code, recorder.Body.String())
+		}
+	})
+
+	// 1a. Success: only the books the viewer can see are counted.
+	tTesting.Run("BookCountForViewer", func(t *testing.T) {
+		viewerId := uuid.New()
+		var getShelfParams database.GetShelfParams
+
+		mockQueries := &MockQueries{
+			BaseMock: common.NewBaseMock(),
+			GetShelfFunc: func(ctx context.Context, arg database.GetShelfParams) (database.GetShelfRow, error) {
+				getShelfParams = arg
+
+				return getShelfReturning(publicShelf, 1)(ctx, arg)
+			},
+		}
+
+		apiConfig := ShelfAPIConfig{APIConfig: common.APIConfig{DB: mockQueries}}
+		recorder := httptest.NewRecorder()
+
+		apiConfig.GetShelf(recorder, shelfRequest(http.MethodGet, "/api/v1/shelves/"+publicShelf.ID.String(), "", map[string]string{"shelfId": publicShelf.ID.String()}), viewerId)
+
+		if recorder.Code != http.StatusOK || getShelfParams.ID != publicShelf.ID || getShelfParams.ViewerID != viewerId {
+			t.Errorf("Expected shelf %s counted for viewer %s, got %+v (%d)", publicShelf.ID, viewerId, getShelfParams, recorder.Code)
+		}
+	})
+
+	// 2. Success: owners see their private shelves.
+	tTesting.Run("PrivateOwner", func(t *testing.T) {
+		if recorder := getShelf(privateShelf, userId); recorder.Code != http.StatusOK {
+			t.Errorf("Expected status %d, got %d", http.StatusOK, recorder.Code)
+		}
+	})
+
+	// 3. Failure: private shelves don't exist for anyone else.
+	tTesting.Run("PrivateOtherMember", func(t *testing.T) {
+		if recorder := getShelf(privateShelf, uuid.New()); recorder.Code != http.StatusNotFound {
+			t.Errorf("Expected status %d, got %d", http.StatusNotFound, recorder.Code)
+		}
+	})
+}
+
+func TestUpdateShelf(tTesting *testing.T) {
+	userId := uuid.New()
+	testShelf := newTestShelf(userId, false)
+	vars := map[string]string{"shelfId": testShelf.ID.String()}
+
+	// 1. Success: only the fields sent are changed.
+	tTesting.Run("Success", func(t *testing.T) {
+		var updateShelfParams database.UpdateShelfParams
+
+		mockQueries := &MockQueries{
+			BaseMock: common.NewBaseMock(),
+			GetShelfByNameFunc: func(ctx context.Context, arg database.GetShelfByNameParams) (database.Shelf, error) {
+				return testShelf, nil
+			},
+			UpdateShelfFunc: func(ctx context.Context, arg database.UpdateShelfParams) (database.Shelf, error) {
+				updateShelfParams = arg
+
+				return testShelf, nil
+			},
+		}
+
+		apiConfig := ShelfAPIConfig{APIConfig: common.APIConfig{DB: mockQueries}}
+		recorder := httptest.NewRecorder()
+
+		apiConfig.UpdateShelf(recorder, shelfRequest(http.MethodPatch, "/api/v1/shelves/"+testShelf.ID.String(), `{"name": "SCI-FI", "is_public": true}`, vars), userId)
+
+		if recorder.Code != http.StatusOK {
+			t.Fatalf("Expected status %d, got %d. Body: %s", http.StatusOK, recorder.Code, recorder.Body.String())
+		}
+
+		expectedParams := database.UpdateShelfParams{
+			Name:     sql.NullString{String: "SCI-FI", Valid: true},
+			IsPublic: sql.NullBool{Bool: true, Valid: true},
+			ID:       testShelf.ID,
+			UserID:   userId,
+		}
+
+		if updateShelfParams != expectedParams {
+			t.Errorf("Expected %+v, got %+v", expectedParams, updateShelfParams)
+		}
+	})
+
+	// 2. Failure: another of the member's shelves has the new name.
+	tTesting.Run("DuplicateName", func(t *testing.T) {
+		mockQueries := &MockQueries{
+			BaseMock: common.NewBaseMock(),
+			GetShelfByNameFunc: func(ctx context.Context, arg database.GetShelfByNameParams) (database.Shelf, error) {
+				return newTestShelf(userId, false), nil
+			},
+		}
+
+		apiConfig := ShelfAPIConfig{APIConfig: common.APIConfig{DB: mockQueries}}
+		recorder := httptest.NewRecorder()
+
+		apiConfig.UpdateShelf(recorder, shelfRequest(http.MethodPatch, "/api/v1/shelves/"+testShelf.ID.String(), `{"name": "Kids"}`, vars), userId)
+
+		if recorder.Code != http.StatusConflict {
+			t.Errorf("Expected status %d, got %d. Body: %s", http.StatusConflict, recorder.Code, recorder.Body.String())
+		}
+	})
+
+	// 3. Failure: not found or not the owner.
+	tTesting.Run("NotFoundOrUnauthorized", func(t *testing.T) {
+		mockQueries := &MockQueries{
+			BaseMock: common.NewBaseMock(),
+			UpdateShelfFunc: func(ctx context.Context, arg database.UpdateShelfParams) (database.Shelf, error) {
+				return database.Shelf{}, sql.ErrNoRows
+			},
+		}
+
+		apiConfig := ShelfAPIConfig{APIConfig: common.APIConfig{DB: mockQueries}}
+		recorder := httptest.NewRecorder()
+
+		apiConfig.UpdateShelf(recorder, shelfRequest(http.MethodPatch, "/api/v1/shelves/"+testShelf.ID.String(), `{"is_public": false}`, vars), uuid.New())
+
+		if recorder.Code != http.StatusNotFound {
+			t.Errorf("Expected status %d, got %d. Body: %s", http.StatusNotFound, recorder.Code, recorder.Body.String())
+		}
+	})
+}
+
+func TestDeleteShelf(tTesting *testing.T) {
+	userId := uuid.New()
+	shelfId := uuid.New()
+	vars := map[string]string{"shelfId": shelfId.String()}
+
+	// 1. Success test case
+	tTesting.Run("Success", func(t *testing.T) {
+		mockQueries := &MockQueries{
+			BaseMock: common.NewBaseMock(),
+			DeleteShelfFunc: func(ctx context.Context, arg database.DeleteShelfParams) (int64, error) {
+				if arg.ID != shelfId || arg.UserID != userId {
+					t.Errorf("Unexpected delete: %+v", arg)
+				}
+
+				return 1, nil
+			},
+		}
+
+		apiConfig := ShelfAPIConfig{APIConfig: common.APIConfig{DB: mockQueries}}
+		recorder := httptest.NewRecorder()
+
+		apiConfig.DeleteShelf(recorder, shelfRequest(http.MethodDelete, "/api/v1/shelves/"+shelfId.String(), "", vars), userId)
+
+		if recorder.Code != http.StatusOK {
+			t.Errorf("Expected status %d, got %d. Body: %s", http.StatusOK, recorder.Code, recorder.Body.String())
+		}
+	})
+
+	// 2. Failure: not found or not the owner.
+	tTesting.Run("NotFoundOrUnauthorized", func(t *testing.T) {
+		apiConfig := ShelfAPIConfig{APIConfig: common.APIConfig{DB: &MockQueries{BaseMock: common.NewBaseMock()}}}
+		recorder := httptest.NewRecorder()
+
+		apiConfig.DeleteShelf(recorder, shelfRequest(http.MethodDelete, "/api/v1/shelves/"+shelfId.String(), "", vars), userId)
+
+		if recorder.Code != http.StatusNotFound {
+			t.Errorf("Expected status %d, got %d", http.StatusNotFound, recorder.Code)
+		}
+	})
+}
+
+func TestAddShelfBook(tTesting *testing.T) {
+	userId := uuid.New()
+	testShelf := newTestShelf(userId, true)
+	bookId := uuid.New()
+	vars := map[string]string{"shelfId": testShelf.ID.String(), "bookId": bookId.String()}
+	target := fmt.Sprintf("/api/v1/shelves/%s/books/%s", testShelf.ID, bookId)
+
+	// 1. Success test case
+	tTesting.Run("Success", func(t *testing.T) {
+		mockQueries := &MockQueries{
+			BaseMock:     common.NewBaseMock(),
+			GetShelfFunc: getShelfReturning(testShelf, 1),
+			AddShelfBookFunc: func(ctx context.Context, arg database.AddShelfBookParams) (database.ShelfBook, error) {
+				if arg.ShelfID != testShelf.ID || arg.BookID != bookId || arg.UserID != userId {
+					t.Errorf("Unexpected add: %+v", arg)
+				}
+
+				return database.ShelfBook{ShelfID: arg.ShelfID, BookID: arg.BookID, Position: 2, AddedAt: time.Now().UTC()}, nil
+			},
+		}
+
+		apiConfig := ShelfAPIConfig{APIConfig: common.APIConfig{DB: mockQueries}}
+		recorder := httptest.NewRecorder()
+
+		apiConfig.AddShelfBook(recorder, shelfRequest(http.MethodPost, target, "", vars), userId)
+
+		var shelfBook ShelfBook
+		json.Unmarshal(recorder.Body.Bytes(), &shelfBook)
+
+		if recorder.Code != http.StatusCreated || shelfBook.Position != 2 {
+			t.Errorf("Unexpected response %d: %s", recorder.Code, recorder.Body.String())
+		}
+	})
+
+	// 2. Failure: someone else's shelf.
+	tTesting.Run("NotShelfOwner", func(t *testing.T) {
+		mockQueries := &MockQueries{BaseMock: common.NewBaseMock(), GetShelfFunc: getShelfReturning(testShelf, 1)}
+		apiConfig := ShelfAPIConfig{APIConfig: common.APIConfig{DB: mockQueries}}
+		recorder := httptest.NewRecorder()
+
+		apiConfig.AddShelfBook(recorder, shelfRequest(http.MethodPost, target, "", vars), uuid.New())
+
+		if recorder.Code != http.StatusNotFound {
+			t.Errorf("Expected status %d, got %d", http.StatusNotFound, recorder.Code)
+		}
+	})
+
+	// 3. Failure: the book is not in the member's library.
+	tTesting.Run("NotBookOwner", func(t *testing.T) {
+		mockQueries := &MockQueries{
+			BaseMock:     common.NewBaseMock(),
+			GetShelfFunc: getShelfReturning(testShelf, 1),
+			AddShelfBookFunc: func(ctx context.Context, arg database.AddShelfBookParams) (database.ShelfBook, error) {
+				return database.ShelfBook{}, sql.ErrNoRows
+			},
+		}
+
+		apiConfig := ShelfAPIConfig{APIConfig: common.APIConfig{DB: mockQueries}}
+		recorder := httptest.NewRecorder()
+
+		apiConfig.AddShelfBook(recorder, shelfRequest(http.MethodPost, target, "", vars), userId)
+
+		if recorder.Code != http.StatusNotFound {
+			t.Errorf("Expected status %d, got %d. Body: %s", http.StatusNotFound, recorder.Code, recorder.Body.String())
+		}
+	})
+}
+
+func TestRemoveShelfBook(tTesting *testing.T) {
+	userId := uuid.New()
+	shelfId := uuid.New()
+	bookId := uuid.New()
+	vars := map[string]string{"shelfId": shelfId.String(), "bookId": bookId.String()}
+	target := fmt.Sprintf("/api/v1/shelves/%s/books/%s", shelfId, bookId)
+
+	// 1. Success test case
+	tTesting.Run("Success", func(t *testing.T) {
+		mockQueries := &MockQueries{
+			BaseMock: common.NewBaseMock(),
+			RemoveShelfBookFunc: func(ctx context.Context, arg database.RemoveShelfBookParams) (int64, error) {
+				return 1, nil
+			},
+		}
+
+		apiConfig := ShelfAPIConfig{APIConfig: common.APIConfig{DB: mockQueries}}
+		recorder := httptest.NewRecorder()
+
+		apiConfig.RemoveShelfBook(recorder, shelfRequest(http.MethodDelete, target, "", vars), userId)
+
+		if recorder.Code != http.StatusOK {
+			t.Errorf("Expected status %d, got %d. Body: %s", http.StatusOK, recorder.Code, recorder.Body.String())
+		}
+	})
+
+	// 2. Failure: the book is not on the shelf, or it is not the member's shelf.
+	tTesting.Run("NotOnShelf", func(t *testing.T) {
+		apiConfig := ShelfAPIConfig{APIConfig: common.APIConfig{DB: &MockQueries{BaseMock: common.NewBaseMock()}}}
+		recorder := httptest.NewRecorder()
+
+		apiConfig.RemoveShelfBook(recorder, shelfRequest(http.MethodDelete, target, "", vars), userId)
+
+		if recorder.Code != http.StatusNotFound {
+			t.Errorf("Expected status %d, got %d", http.StatusNotFound, recorder.Code)
+		}
+	})
+}
+
+func TestGetShelfBooks(tTesting *testing.T) {
+	userId := uuid.New()
+	publicShelf := newTestShelf(userId, true)
+	shelfBooks := []database.GetShelfBooksRow{}
+
+	for position := int32(1); position <= 3; position++ {
+		book := database.Book{ID: uuid.New(), Title: fmt.Sprintf("Book %d", position), UserID: userId}
+		shelfBook := database.ShelfBook{ShelfID: publicShelf.ID, BookID: book.ID, Position: position}
+
+		shelfBooks = append(shelfBooks, database.GetShelfBooksRow{ShelfBook: shelfBook, Book: book, Tags: []string{"classic"}})
+	}
+
+	// 1. Success: books come in shelf order and the cursor picks up after the last position.
+	tTesting.Run("Paginates", func(t *testing.T) {
+		var getShelfBooksParams []database.GetShelfBooksParams
+
+		mockQueries := &MockQueries{
+			BaseMock:     common.NewBaseMock(),
+			GetShelfFunc: getShelfReturning(publicShelf, 3),
+			GetShelfBooksFunc: func(ctx context.Context, arg database.GetShelfBooksParams) ([]database.GetShelfBooksRow, error) {
+				getShelfBooksParams = append(getShelfBooksParams, arg)
+
+				if arg.CursorID.Valid {
+					return shelfBooks[2:], nil
+				}
+
+				return shelfBooks[:3], nil
+			},
+		}
+
+		apiConfig := ShelfAPIConfig{APIConfig: common.APIConfig{DB: mockQueries}}
+		vars := map[string]string{"shelfId": publicShelf.ID.String()}
+		target := fmt.Sprintf("/api/v1/shelves/%s/books?limit=2", publicShelf.ID)
+		recorder := httptest.NewRecorder()
+
+		apiConfig.GetShelfBooks(recorder, shelfRequest(http.MethodGet, target, "", vars), uuid.New())
+
+		var firstPage common.Page[ShelfBook]
+		json.Unmarshal(recorder.Body.Bytes(), &firstPage)
+
+		if recorder.Code != http.StatusOK || len(firstPage.Data) != 2 || firstPage.NextCursor == nil || firstPage.Data[0].Book == nil || firstPage.Data[0].Book.Tags[0] != "classic" {
+			t.Fatalf("Unexpected first page %d: %s", recorder.Code, recorder.Body.String())
+		}
+
+		recorder = httptest.NewRecorder()
+
+		apiConfig.GetShelfBooks(recorder, shelfRequest(http.MethodGet, target+"&cursor="+*firstPage.NextCursor, "", vars), uuid.New())
+
+		var secondPage common.Page[ShelfBook]
+		json.Unmarshal(recorder.Body.Bytes(), &secondPage)
+
+		if len(secondPage.Data) != 1 || secondPage.NextCursor != nil {
+			t.Fatalf("Unexpected second page: %s", recorder.Body.String())
+		}
+
+		if getShelfBooksParams[1].CursorPosition != 2 || getShelfBooksParams[1].CursorID.UUID != shelfBooks[1].Book.ID {
+			t.Errorf("Unexpected page queries: %+v", getShelfBooksParams)
+		}
+	})
+
+	// 2. Failure: private shelves are hidden from other members.
+	tTesting.Run("PrivateShelf", func(t *testing.T) {
+		privateShelf := newTestShelf(userId, false)
+		mockQueries := &MockQueries{BaseMock: common.NewBaseMock(), GetShelfFunc: getShelfReturning(privateShelf, 0)}
+		apiConfig := ShelfAPIConfig{APIConfig: common.APIConfig{DB: mockQueries}}
+		recorder := httptest.NewRecorder()
+
+		apiConfig.GetShelfBooks(recorder, shelfRequest(http.MethodGet, "/api/v1/shelves/"+privateShelf.ID.String()+"/books", "", map[string]string{"shelfId": privateShelf.ID.String()}), uuid.New())
+
+		if recorder.Code != http.StatusNotFound {
+			t.Errorf("Expected status %d, got %d", http.StatusNotFound, recorder.Code)
+		}
+	})
+}
+
+func TestReorderShelfBooks(tTesting *testing.T) {
+	userId := uuid.New()
+	testShelf := newTestShelf(userId, false)
+	shelfBookIds := []uuid.UUID{uuid.New(), uuid.New(), uuid.New()}
+	vars := map[string]string{"shelfId": testShelf.ID.String()}
+	target := fmt.Sprintf("/api/v1/shelves/%s/books", testShelf.ID)
+
+	reorderBody := func(bookIds ...uuid.UUID) string {
+		body, _ := json.Marshal(ReorderShelfBooksParameters{BookIDs: bookIds})
+
+		return string(body)
+	}
+
+	newMockQueries := func(reorderShelfBooksParams *database.ReorderShelfBooksParams) *MockQueries {
+		return &MockQueries{
+			BaseMock:     common.NewBaseMock(),
+			GetShelfFunc: getShelfReturning(testShelf, 3),
+			GetShelfBookIDsFunc: func(ctx context.Context, shelfID uuid.UUID) ([]uuid.UUID, error) {
+				return shelfBookIds, nil
+			},
+			ReorderShelfBooksFunc: func(ctx context.Context, arg database.ReorderShelfBooksParams) error {
+				*reorderShelfBooksParams = arg
+
+				return nil
+			},
+		}
+	}
+
+	// 1. Success: the books are written in the order given.
+	tTesting.Run("Success", func(t *testing.T) {
+		var reorderShelfBooksParams database.ReorderShelfBooksParams
+
+		apiConfig := ShelfAPIConfig{APIConfig: common.APIConfig{DB: newMockQueries(&reorderShelfBooksParams)}}
+		recorder := httptest.NewRecorder()
+
+		apiConfig.ReorderShelfBooks(recorder, shelfRequest(http.MethodPut, target, reorderBody(shelfBookIds[2], shelfBookIds[0], shelfBookIds[1]), vars), userId)
+
+		if recorder.Code != http.StatusOK {
+			t.Fatalf("Expected status %d, got %d. Body: %s", http.StatusOK, recorder.Code, recorder.Body.String())
+		}
+
+		if len(reorderShelfBooksParams.BookIds) != 3 || reorderShelfBooksParams.BookIds[0] != shelfBookIds[2] || reorderShelfBooksParams.ShelfID != testShelf.ID {
+			t.Errorf("Unexpected reorder: %+v", reorderShelfBooksParams)
+		}
+	})
+
+	// 2. Failure: missing, repeated or unknown books.
+	tTesting.Run("NotShelfOrder", func(t *testing.T) {
+		for _, body := range []string{
+			reorderBody(shelfBookIds[0], shelfBookIds[1]),
+			reorderBody(shelfBookIds[0], shelfBookIds[1], shelfBookIds[1]),
+			reorderBody(shelfBookIds[0], shelfBookIds[1], uuid.New()),
+		} {
+			var reorderShelfBooksParams database.ReorderShelfBooksParams
+
+			apiConfig := ShelfAPIConfig{APIConfig: common.APIConfig{DB: newMockQueries(&reorderShelfBooksParams)}}
+			recorder := httptest.NewRecorder()
+
+			apiConfig.ReorderShelfBooks(recorder, shelfRequest(http.MethodPut, target, body, vars), userId)
+
+			if recorder.Code != http.StatusBadRequest || reorderShelfBooksParams.BookIds != nil {
+				t.Errorf("%s: expected status %d without a reorder, got %d", body, http.StatusBadRequest, recorder.Code)
+			}
+		}
+	})
+
+	// 3. Failure: someone else's shelf.
+	tTesting.Run("NotShelfOwner", func(t *testing.T) {
+		var reorderShelfBooksParams database.ReorderShelfBooksParams
+
+		apiConfig := ShelfAPIConfig{APIConfig: common.APIConfig{DB: newMockQueries(&reorderShelfBooksParams)}}
+		recorder := httptest.NewRecorder()
+
+		apiConfig.ReorderShelfBooks(recorder, shelfRequest(http.MethodPut, target, reorderBody(shelfBookIds...), vars), uuid.New())
+
+		if recorder.Code != http.StatusNotFound {
+			t.Errorf("Expected status %d, got %d", http.StatusNotFound, recorder.Code)
+		}
+	})
+
+	// 4. Failure: database error.
+	tTesting.Run("InternalDBError", func(t *testing.T) {
+		mockQueries := &MockQueries{
+			BaseMock:     common.NewBaseMock(),
+			GetShelfFunc: getShelfReturning(testShelf, 3),
+			GetShelfBookIDsFunc: func(ctx context.Context, shelfID uuid.UUID) ([]uuid.UUID, error) {
+				return nil, errors.New("simulated DB connection failure")
+			},
+		}
+
+		apiConfig := ShelfAPIConfig{APIConfig: common.APIConfig{DB: mockQueries}}
+		recorder := httptest.NewRecorder()
+
+		apiConfig.ReorderShelfBooks(recorder, shelfRequest(http.MethodPut, target, reorderBody(shelfBookIds...), vars), userId)
+
+		if recorder.Code != http.StatusInternalServerError {
+			t.Errorf("Expected status %d, got %d", http.StatusInternalServerError, recorder.Code)
+		}
+	})
+}
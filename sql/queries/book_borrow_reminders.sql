@@ -0,0 +1,27 @@
+-- name: GetBookBorrowsDueForReminder :many
+SELECT sqlc.embed(bb), b.title AS book_title, b.user_id AS owner_id,
+    o.first_name AS owner_first_name, o.last_name AS owner_last_name, o.email AS owner_email, o.locale AS owner_locale,
+    u.first_name AS borrower_first_name, u.last_name AS borrower_last_name, u.email AS borrower_email, u.locale AS borrower_locale
+FROM book_borrows AS bb
+INNER JOIN books AS b ON b.id = bb.book_id
+INNER JOIN users AS o ON o.id = b.user_id
+INNER JOIN users AS u ON u.id = bb.borrower_id
+WHERE bb.return_confirmed_at IS NULL
+    AND (bb.returned_at IS NULL OR bb.return_status = 'disputed')
+    AND bb.lost_at IS NULL
+    AND bb.due_at <= sqlc.arg('due_before')::timestamp
+    AND (sqlc.narg('due_after')::timestamp IS NULL OR bb.due_at > sqlc.narg('due_after')::timestamp)
+    AND NOT EXISTS (
+        SELECT 1 FROM book_borrow_reminders AS bbr
+        WHERE bbr.book_borrow_id = bb.id AND bbr.threshold = sqlc.arg('threshold')::text AND bbr.due_at = bb.due_at
+    )
+ORDER BY b.user_id, bb.due_at;
+
+-- name: CreateBookBorrowReminder :one
+INSERT INTO book_borrow_reminders (id, threshold, sent_at, book_borrow_id, due_at)
+VALUES ($1, $2, NOW(), $3, $4)
+ON CONFLICT (book_borrow_id, threshold, due_at) DO NOTHING
+RETURNING *;
+
+-- name: DeleteBookBorrowReminder :exec
+DELETE FROM book_borrow_reminders WHERE id = $1;
@@ -0,0 +1,17 @@
+-- name: RenewBookBorrow :one
+WITH renewed_book_borrow AS (
+    UPDATE book_borrows
+    SET due_at = sqlc.arg('new_due_at')::timestamp, updated_at = NOW()
+    WHERE book_borrows.id = sqlc.arg('book_borrow_id') AND borrower_id = sqlc.arg('borrower_id') AND returned_at IS NULL AND lost_at IS NULL AND due_at = sqlc.arg('previous_due_at')::timestamp
+    RETURNING book_borrows.id, book_borrows.due_at
+)
+INSERT INTO book_borrow_renewals (id, previous_due_at, new_due_at, created_at, book_borrow_id)
+SELECT sqlc.arg('id'), sqlc.arg('previous_due_at')::timestamp, renewed_book_borrow.due_at, NOW(), renewed_book_borrow.id
+FROM renewed_book_borrow
+RETURNING id, previous_due_at, new_due_at, created_at, book_borrow_id;
+
+-- name: CountBookBorrowRenewals :one
+SELECT COUNT(*) FROM book_borrow_renewals WHERE book_borrow_id = $1;
+
+-- name: GetBookBorrowRenewals :many
+SELECT * FROM book_borrow_renewals WHERE book_borrow_id = $1 ORDER BY created_at;
\ No newline at end of file
@@ -0,0 +1,54 @@
+-- name: CreateBookBorrowRequest :one
+INSERT INTO book_borrow_requests (id, status, created_at, updated_at, book_id, requester_id)
+VALUES ($1, 'requested', NOW(), NOW(), $2, $3)
+RETURNING id, status, responded_at, created_at, updated_at, book_id, requester_id, book_borrow_id;
+
+-- name: GetOpenBookBorrowRequest :one
+SELECT * FROM book_borrow_requests WHERE book_id = $1 AND requester_id = $2 AND status IN ('requested', 'approved', 'issued');
+
+-- name: RespondToBookBorrowRequest :one
+UPDATE book_borrow_requests AS bbr
+SET status = $1, responded_at = NOW(), updated_at = NOW()
+FROM books AS b
+WHERE bbr.id = $2 AND bbr.book_id = b.id AND b.user_id = $3 AND bbr.status = 'requested'
+RETURNING bbr.id, bbr.status, bbr.responded_at, bbr.created_at, bbr.updated_at, bbr.book_id, bbr.requester_id, bbr.book_borrow_id;
+
+-- name: CancelBookBorrowRequest :one
+UPDATE book_borrow_requests
+SET status = 'cancelled', updated_at = NOW()
+WHERE id = $1 AND requester_id = $2 AND status IN ('requested', 'approved')
+RETURNING id, status, responded_at, created_at, updated_at, book_id, requester_id, book_borrow_id;
+
+-- An approval is used up by the loan it is issued with, no rows means it already was.
+-- name: MarkBookBorrowRequestIssued :execrows
+UPDATE book_borrow_requests
+SET status = 'issued', book_borrow_id = sqlc.arg('book_borrow_id')::uuid, updated_at = NOW()
+WHERE id = sqlc.arg('id') AND status = 'approved';
+
+-- name: MarkBookBorrowRequestReturned :exec
+UPDATE book_borrow_requests
+SET status = 'returned', updated_at = NOW()
+WHERE book_borrow_id = sqlc.arg('book_borrow_id')::uuid AND status = 'issued';
+
+-- name: GetIncomingBookBorrowRequests :many
+SELECT sqlc.embed(bbr), b.title AS book_title, u.first_name AS requester_first_name, u.last_name AS requester_last_name, sqlc.embed(brs)
+FROM book_borrow_requests AS bbr
+INNER JOIN books AS b ON b.id = bbr.book_id
+INNER JOIN users AS u ON u.id = bbr.requester_id
+INNER JOIN borrower_reputation_stats AS brs ON brs.borrower_id = bbr.requester_id
+WHERE b.user_id = sqlc.arg('user_id') AND (sqlc.narg('status')::text IS NULL OR bbr.status = sqlc.narg('status')::text)
+ORDER BY bbr.created_at DESC;
+
+-- name: GetOutgoingBookBorrowRequests :many
+SELECT sqlc.embed(bbr), b.title AS book_title, u.first_name AS owner_first_name, u.last_name AS owner_last_name
+FROM book_borrow_requests AS bbr
+INNER JOIN books AS b ON b.id = bbr.book_id
+INNER JOIN users AS u ON u.id = b.user_id
+WHERE bbr.requester_id = sqlc.arg('requester_id') AND (sqlc.narg('status')::text IS NULL OR bbr.status = sqlc.narg('status')::text)
+ORDER BY bbr.created_at DESC;
+
+-- Open requests for a book going to the trash are turned down.
+-- name: DeclineOpenBookBorrowRequests :exec
+UPDATE book_borrow_requests
+SET status = 'declined', responded_at = NOW(), updated_at = NOW()
+WHERE book_id = $1 AND status IN ('requested', 'approved');
\ No newline at end of file
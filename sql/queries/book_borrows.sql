@@ -1,13 +1,82 @@
--- name: IssueBook :one
-INSERT INTO book_borrows (id, issued_at, created_at, updated_at, book_id, borrower_id)
-VALUES ($1, NOW(), NOW(), NOW(), $2, $3)
-RETURNING id, issued_at, returned_at, created_at, updated_at, book_id, borrower_id;
+-- name: LockBookForIssue :exec
+-- Issues of the same book wait on each other, so the free copies counted for one can't
+-- be taken by another.
+SELECT id FROM books WHERE id = $1 FOR NO KEY UPDATE;
 
--- name: GetBookBorrow :one
-SELECT * FROM book_borrows WHERE book_id = $1 AND returned_at IS NULL;
+-- name: IssueBook :one
+-- Lends out the oldest copy that is not with another borrower or written off. Copies
+-- offered to someone else in the waitlist stay on the shelf for them. Returns no rows
+-- when every copy is out or held.
+INSERT INTO book_borrows (id, issued_at, created_at, updated_at, book_id, borrower_id, due_at, book_copy_id)
+SELECT sqlc.arg('id'), NOW(), NOW(), NOW(), bc.book_id, sqlc.arg('borrower_id'), sqlc.arg('due_at'), bc.id
+FROM book_copies AS bc
+WHERE bc.book_id = sqlc.arg('book_id') AND bc.condition <> 'lost' AND bc.retired_at IS NULL
+AND NOT EXISTS (SELECT 1 FROM book_borrows AS bb WHERE bb.book_copy_id = bc.id AND bb.return_confirmed_at IS NULL)
+AND (
+    SELECT COUNT(*) FROM book_copies AS free
+    WHERE free.book_id = sqlc.arg('book_id') AND free.condition <> 'lost' AND free.retired_at IS NULL
+    AND NOT EXISTS (SELECT 1 FROM book_borrows AS bb WHERE bb.book_copy_id = free.id AND bb.return_confirmed_at IS NULL)
+) > (
+    SELECT COUNT(*) FROM book_reservations AS br
+    WHERE br.book_id = sqlc.arg('book_id') AND br.status = 'offered' AND br.offer_expires_at > NOW()
+    AND br.user_id <> sqlc.arg('borrower_id')
+)
+ORDER BY bc.created_at, bc.id
+LIMIT 1
+FOR UPDATE SKIP LOCKED
+RETURNING id, issued_at, returned_at, created_at, updated_at, book_id, borrower_id, due_at, return_status, return_confirmed_at, return_disputed_at, book_copy_id, lost_at;
 
 -- name: ReturnBook :one
 UPDATE book_borrows 
-SET returned_at = NOW(), updated_at = NOW()
-WHERE id = $1 AND borrower_id = $2 AND returned_at IS NULL
-RETURNING id, issued_at, returned_at, created_at, updated_at, book_id, borrower_id;
\ No newline at end of file
+SET returned_at = NOW(), return_status = 'pending', updated_at = NOW()
+WHERE id = $1 AND borrower_id = $2 AND (returned_at IS NULL OR return_status = 'disputed')
+RETURNING id, issued_at, returned_at, created_at, updated_at, book_id, borrower_id, due_at, return_status, return_confirmed_at, return_disputed_at, book_copy_id, lost_at;
+
+-- name: GetBookBorrowByID :one
+SELECT * FROM book_borrows WHERE id = $1;
+
+-- name: GetBorrowedBooks :many
+SELECT sqlc.embed(bb), b.title AS book_title, u.first_name AS lender_first_name, u.last_name AS lender_last_name
+FROM book_borrows AS bb
+INNER JOIN books AS b ON b.id = bb.book_id
+INNER JOIN users AS u ON u.id = b.user_id
+WHERE bb.borrower_id = sqlc.arg('borrower_id') AND (
+	sqlc.narg('status')::text IS NULL
+	OR (sqlc.narg('status')::text = 'active' AND bb.return_confirmed_at IS NULL)
+	OR (sqlc.narg('status')::text = 'returned' AND bb.return_confirmed_at IS NOT NULL)
+	OR (sqlc.narg('status')::text = 'overdue' AND bb.return_confirmed_at IS NULL AND (bb.returned_at IS NULL OR bb.return_status = 'disputed') AND bb.due_at < NOW())
+	OR (sqlc.narg('status')::text IN ('pending', 'disputed') AND bb.return_status = sqlc.narg('status')::text)
+)
+AND (sqlc.narg('cursor_id')::uuid IS NULL OR (bb.issued_at, bb.id) < (sqlc.arg('cursor_issued_at')::timestamp, sqlc.narg('cursor_id')::uuid))
+ORDER BY bb.issued_at DESC, bb.id DESC
+LIMIT sqlc.arg('page_limit')::int;
+
+-- name: GetLentBooks :many
+SELECT sqlc.embed(bb), b.title AS book_title, u.first_name AS borrower_first_name, u.last_name AS borrower_last_name
+FROM book_borrows AS bb
+INNER JOIN books AS b ON b.id = bb.book_id
+INNER JOIN users AS u ON u.id = bb.borrower_id
+WHERE b.user_id = sqlc.arg('user_id') AND (
+	sqlc.narg('status')::text IS NULL
+	OR (sqlc.narg('status')::text = 'active' AND bb.return_confirmed_at IS NULL)
+	OR (sqlc.narg('status')::text = 'returned' AND bb.return_confirmed_at IS NOT NULL)
+	OR (sqlc.narg('status')::text = 'overdue' AND bb.return_confirmed_at IS NULL AND (bb.returned_at IS NULL OR bb.return_status = 'disputed') AND bb.due_at < NOW())
+	OR (sqlc.narg('status')::text IN ('pending', 'disputed') AND bb.return_status = sqlc.narg('status')::text)
+)
+AND (sqlc.narg('cursor_id')::uuid IS NULL OR (bb.issued_at, bb.id) < (sqlc.arg('cursor_issued_at')::timestamp, sqlc.narg('cursor_id')::uuid))
+ORDER BY bb.issued_at DESC, bb.id DESC
+LIMIT sqlc.arg('page_limit')::int;
+
+-- name: ConfirmBookReturn :one
+UPDATE book_borrows AS bb
+SET return_status = 'confirmed', return_confirmed_at = NOW(), updated_at = NOW()
+FROM books AS b
+WHERE bb.id = $1 AND b.id = bb.book_id AND b.user_id = $2 AND bb.return_status IN ('pending', 'disputed')
+RETURNING bb.id, bb.issued_at, bb.returned_at, bb.created_at, bb.updated_at, bb.book_id, bb.borrower_id, bb.due_at, bb.return_status, bb.return_confirmed_at, bb.return_disputed_at, bb.book_copy_id, bb.lost_at;
+
+-- name: DisputeBookReturn :one
+UPDATE book_borrows AS bb
+SET return_status = 'disputed', return_disputed_at = NOW(), updated_at = NOW()
+FROM books AS b
+WHERE bb.id = $1 AND b.id = bb.book_id AND b.user_id = $2 AND bb.return_status = 'pending'
+RETURNING bb.id, bb.issued_at, bb.returned_at, bb.created_at, bb.updated_at, bb.book_id, bb.borrower_id, bb.due_at, bb.return_status, bb.return_confirmed_at, bb.return_disputed_at, bb.book_copy_id, bb.lost_at;
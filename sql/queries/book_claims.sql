@@ -0,0 +1,80 @@
+-- A loan with the book owner, whoever is on either side can report on it.
+-- name: GetBookBorrowWithOwner :one
+SELECT sqlc.embed(bb), b.user_id AS owner_id, b.title AS book_title
+FROM book_borrows AS bb
+INNER JOIN books AS b ON b.id = bb.book_id
+WHERE bb.id = $1;
+
+-- name: CreateBookConditionReport :one
+INSERT INTO book_condition_reports (id, stage, condition, notes, photo_url, photo_key, created_at, book_borrow_id, reporter_id)
+VALUES ($1, $2, $3, $4, $5, $6, NOW(), $7, $8)
+ON CONFLICT (book_borrow_id, stage, reporter_id) DO NOTHING
+RETURNING *;
+
+-- name: GetBookConditionReports :many
+SELECT * FROM book_condition_reports WHERE book_borrow_id = $1 ORDER BY created_at, id;
+
+-- Only loans still out, or whose return the owner disputes, can go missing.
+-- name: ReportBookBorrowLost :one
+UPDATE book_borrows
+SET lost_at = NOW(), updated_at = NOW()
+WHERE id = $1 AND lost_at IS NULL AND return_confirmed_at IS NULL AND (returned_at IS NULL OR return_status = 'disputed')
+RETURNING id, issued_at, returned_at, created_at, updated_at, book_id, borrower_id, due_at, return_status, return_confirmed_at, return_disputed_at, book_copy_id, lost_at;
+
+-- The book turned up after all.
+-- name: ClearBookBorrowLost :exec
+UPDATE book_borrows SET lost_at = NULL, updated_at = NOW() WHERE id = $1;
+
+-- Closes a lost loan for good. The copy is written off separately.
+-- name: CloseLostBookBorrow :execrows
+UPDATE book_borrows
+SET return_status = 'lost', return_confirmed_at = NOW(), updated_at = NOW()
+WHERE id = $1 AND lost_at IS NOT NULL AND return_confirmed_at IS NULL;
+
+-- name: MarkBookCopyLost :exec
+UPDATE book_copies SET condition = 'lost', updated_at = NOW() WHERE id = $1;
+
+-- A loan has at most one open claim, a second one returns no rows.
+-- name: CreateBookClaim :one
+INSERT INTO book_claims (id, kind, status, description, created_at, updated_at, book_borrow_id, opened_by)
+VALUES ($1, $2, 'open', $3, NOW(), NOW(), $4, $5)
+ON CONFLICT (book_borrow_id) WHERE status = 'open' DO NOTHING
+RETURNING *;
+
+-- name: GetBookClaim :one
+SELECT sqlc.embed(bc), bb.borrower_id, bb.book_copy_id, b.user_id AS owner_id, b.title AS book_title
+FROM book_claims AS bc
+INNER JOIN book_borrows AS bb ON bb.id = bc.book_borrow_id
+INNER JOIN books AS b ON b.id = bb.book_id
+WHERE bc.id = $1;
+
+-- Claims on the member's loans, from either side.
+-- name: GetUserBookClaims :many
+SELECT sqlc.embed(bc), bb.borrower_id, b.user_id AS owner_id, b.title AS book_title
+FROM book_claims AS bc
+INNER JOIN book_borrows AS bb ON bb.id = bc.book_borrow_id
+INNER JOIN books AS b ON b.id = bb.book_id
+WHERE (bb.borrower_id = sqlc.arg('user_id') OR b.user_id = sqlc.arg('user_id'))
+AND (sqlc.narg('status')::text IS NULL OR bc.status = sqlc.narg('status')::text)
+ORDER BY bc.created_at DESC, bc.id;
+
+-- name: ResolveBookClaim :one
+UPDATE book_claims
+SET status = 'resolved', resolution = sqlc.arg('resolution'), resolution_note = sqlc.arg('resolution_note'), resolved_at = NOW(), updated_at = NOW()
+WHERE id = sqlc.arg('id') AND status = 'open'
+RETURNING *;
+
+-- Comments are only taken while the claim is open.
+-- name: CreateBookClaimComment :one
+INSERT INTO book_claim_comments (id, body, created_at, book_claim_id, author_id)
+SELECT sqlc.arg('id'), sqlc.arg('body'), NOW(), bc.id, sqlc.arg('author_id')
+FROM book_claims AS bc
+WHERE bc.id = sqlc.arg('book_claim_id') AND bc.status = 'open'
+RETURNING *;
+
+-- name: GetBookClaimComments :many
+SELECT sqlc.embed(bcc), u.first_name AS author_first_name, u.last_name AS author_last_name
+FROM book_claim_comments AS bcc
+INNER JOIN users AS u ON u.id = bcc.author_id
+WHERE bcc.book_claim_id = $1
+ORDER BY bcc.created_at, bcc.id;
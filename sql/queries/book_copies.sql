@@ -0,0 +1,40 @@
+-- name: CreateBookCopy :one
+INSERT INTO book_copies (id, condition, created_at, updated_at, book_id)
+VALUES ($1, $2, NOW(), NOW(), $3)
+RETURNING id, condition, created_at, updated_at, book_id, retired_at;
+
+-- name: GetBookCopies :many
+SELECT sqlc.embed(bc), bb.id AS book_borrow_id, bb.due_at
+FROM book_copies AS bc
+LEFT JOIN book_borrows AS bb ON bb.book_copy_id = bc.id AND bb.return_confirmed_at IS NULL
+WHERE bc.book_id = $1 AND bc.retired_at IS NULL
+ORDER BY bc.created_at, bc.id;
+
+-- name: UpdateBookCopy :one
+UPDATE book_copies AS bc
+SET condition = $1, updated_at = NOW()
+FROM books AS b
+WHERE bc.id = $2 AND b.id = bc.book_id AND b.user_id = $3 AND bc.retired_at IS NULL
+RETURNING bc.id, bc.condition, bc.created_at, bc.updated_at, bc.book_id, bc.retired_at;
+
+-- name: DeleteBookCopy :execrows
+-- Only copies that were never lent out can go for good, see RetireBookCopy for the rest.
+DELETE FROM book_copies AS bc
+USING books AS b
+WHERE bc.id = $1 AND b.id = bc.book_id AND b.user_id = $2
+AND NOT EXISTS (SELECT 1 FROM book_borrows AS bb WHERE bb.book_copy_id = bc.id)
+AND EXISTS (SELECT 1 FROM book_copies AS other WHERE other.book_id = bc.book_id AND other.id <> bc.id AND other.retired_at IS NULL);
+
+-- name: RetireBookCopy :execrows
+-- Takes a copy with loan history off the shelf and keeps the history.
+UPDATE book_copies AS bc
+SET retired_at = NOW(), updated_at = NOW()
+FROM books AS b
+WHERE bc.id = $1 AND b.id = bc.book_id AND b.user_id = $2 AND bc.retired_at IS NULL
+AND NOT EXISTS (SELECT 1 FROM book_borrows AS bb WHERE bb.book_copy_id = bc.id AND bb.return_confirmed_at IS NULL)
+AND EXISTS (SELECT 1 FROM book_copies AS other WHERE other.book_id = bc.book_id AND other.id <> bc.id AND other.retired_at IS NULL);
+
+-- name: CountAvailableBookCopies :one
+SELECT COUNT(*) FROM book_copies AS bc
+WHERE bc.book_id = $1 AND bc.condition <> 'lost' AND bc.retired_at IS NULL
+AND NOT EXISTS (SELECT 1 FROM book_borrows AS bb WHERE bb.book_copy_id = bc.id AND bb.return_confirmed_at IS NULL);
@@ -0,0 +1,25 @@
+-- name: GetMemberRelationship :one
+-- How a member stands with a book owner, for the visibility and lending rules.
+SELECT
+    EXISTS (SELECT 1 FROM user_subscribers WHERE user_subscribers.user_id = sqlc.arg('owner_id')::uuid AND user_subscribers.subscriber_id = sqlc.arg('member_id')::uuid)::boolean AS is_subscriber,
+    EXISTS (SELECT 1 FROM approved_borrowers WHERE approved_borrowers.user_id = sqlc.arg('owner_id')::uuid AND approved_borrowers.borrower_id = sqlc.arg('member_id')::uuid)::boolean AS is_approved_borrower;
+
+-- name: CreateApprovedBorrower :one
+INSERT INTO approved_borrowers (user_id, borrower_id, created_at)
+VALUES ($1, $2, NOW())
+RETURNING *;
+
+-- name: GetApprovedBorrower :one
+SELECT * FROM approved_borrowers WHERE user_id = $1 AND borrower_id = $2;
+
+-- name: GetApprovedBorrowers :many
+SELECT sqlc.embed(approved_borrowers), users.first_name AS borrower_first_name, users.last_name AS borrower_last_name
+FROM approved_borrowers
+INNER JOIN users ON users.id = approved_borrowers.borrower_id
+WHERE approved_borrowers.user_id = sqlc.arg('user_id')
+AND (sqlc.narg('cursor_id')::uuid IS NULL OR (approved_borrowers.created_at, approved_borrowers.borrower_id) < (sqlc.arg('cursor_created_at')::timestamp, sqlc.narg('cursor_id')::uuid))
+ORDER BY approved_borrowers.created_at DESC, approved_borrowers.borrower_id DESC
+LIMIT sqlc.arg('page_limit')::int;
+
+-- name: DeleteApprovedBorrower :execrows
+DELETE FROM approved_borrowers WHERE user_id = $1 AND borrower_id = $2;
@@ -0,0 +1,66 @@
+-- name: CreateBookReservation :one
+INSERT INTO book_reservations (id, status, created_at, updated_at, book_id, user_id)
+VALUES ($1, 'waiting', NOW(), NOW(), $2, $3)
+RETURNING id, status, offered_at, offer_expires_at, created_at, updated_at, book_id, user_id;
+
+-- name: GetActiveBookReservation :one
+SELECT * FROM book_reservations WHERE book_id = $1 AND user_id = $2 AND status IN ('waiting', 'offered');
+
+-- name: GetUserBookReservations :many
+SELECT sqlc.embed(br), b.title AS book_title,
+    (
+        SELECT COUNT(*) FROM book_reservations AS queue
+        WHERE queue.book_id = br.book_id AND queue.status = 'waiting' AND queue.created_at <= br.created_at
+    ) AS queue_position
+FROM book_reservations AS br
+INNER JOIN books AS b ON b.id = br.book_id
+WHERE br.user_id = $1 AND br.status IN ('waiting', 'offered')
+ORDER BY br.created_at;
+
+-- name: CountBookReservationsAhead :one
+SELECT COUNT(*) FROM book_reservations WHERE book_id = $1 AND status = 'waiting' AND created_at < $2;
+
+-- name: CancelBookReservation :one
+UPDATE book_reservations
+SET status = 'cancelled', updated_at = NOW()
+WHERE id = $1 AND user_id = $2 AND status IN ('waiting', 'offered')
+RETURNING id, status, offered_at, offer_expires_at, created_at, updated_at, book_id, user_id;
+
+-- name: ExpireBookReservationOffers :exec
+UPDATE book_reservations
+SET status = 'expired', updated_at = NOW()
+WHERE book_id = $1 AND status = 'offered' AND offer_expires_at <= NOW();
+
+-- name: GetBooksWithLapsedBookReservationOffers :many
+SELECT DISTINCT book_id FROM book_reservations
+WHERE status = 'offered' AND offer_expires_at <= sqlc.arg('expired_before')::timestamp;
+
+-- name: GetOfferedBookReservations :many
+SELECT * FROM book_reservations WHERE book_id = $1 AND status = 'offered' AND offer_expires_at > NOW()
+ORDER BY offered_at, id;
+
+-- name: OfferNextBookReservation :one
+UPDATE book_reservations
+SET status = 'offered', offered_at = NOW(), offer_expires_at = sqlc.arg('offer_expires_at')::timestamp, updated_at = NOW()
+WHERE id = (
+    SELECT queue.id FROM book_reservations AS queue
+    WHERE queue.book_id = sqlc.arg('book_id') AND queue.status = 'waiting'
+    ORDER BY queue.created_at, queue.id
+    LIMIT 1
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING id, status, offered_at, offer_expires_at, created_at, updated_at, book_id, user_id;
+
+-- name: FulfillBookReservation :exec
+UPDATE book_reservations
+SET status = 'fulfilled', updated_at = NOW()
+WHERE id = $1 AND status = 'offered';
+
+-- name: CountActiveBookReservations :one
+SELECT COUNT(*) FROM book_reservations WHERE book_id = $1 AND status IN ('waiting', 'offered') AND (offer_expires_at IS NULL OR offer_expires_at > NOW());
+
+-- Nobody waits for a book in the trash.
+-- name: CancelBookReservations :exec
+UPDATE book_reservations
+SET status = 'cancelled', updated_at = NOW()
+WHERE book_id = $1 AND status IN ('waiting', 'offered');
\ No newline at end of file
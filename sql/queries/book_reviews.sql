@@ -0,0 +1,54 @@
+-- Only members who borrowed the book and gave it back can review it. A return the owner
+-- disputes doesn't count.
+-- name: HasReturnedBookBorrow :one
+SELECT EXISTS (
+    SELECT 1 FROM book_borrows
+    WHERE book_id = sqlc.arg('book_id') AND borrower_id = sqlc.arg('borrower_id')
+    AND returned_at IS NOT NULL AND return_status IS DISTINCT FROM 'disputed'
+)::boolean AS has_returned;
+
+-- A member reviews a book once, a second review returns no rows.
+-- name: CreateBookReview :one
+INSERT INTO book_reviews (id, rating, body, created_at, updated_at, book_id, reviewer_id)
+VALUES ($1, $2, $3, NOW(), NOW(), $4, $5)
+ON CONFLICT (book_id, reviewer_id) DO NOTHING
+RETURNING *;
+
+-- Locks the review so the rating it takes off the book's totals is the one being replaced.
+-- name: GetBookReviewForUpdate :one
+SELECT * FROM book_reviews WHERE id = $1 FOR UPDATE;
+
+-- name: UpdateBookReview :one
+UPDATE book_reviews
+SET rating = $1, body = $2, updated_at = NOW()
+WHERE id = $3 AND reviewer_id = $4
+RETURNING *;
+
+-- name: DeleteBookReview :one
+DELETE FROM book_reviews WHERE id = $1 AND reviewer_id = $2
+RETURNING *;
+
+-- Only the book owner can reply, an empty reply takes it back.
+-- name: ReplyToBookReview :one
+UPDATE book_reviews
+SET reply = sqlc.arg('reply'), replied_at = CASE WHEN sqlc.arg('reply')::text = '' THEN NULL ELSE NOW() END
+FROM books
+WHERE book_reviews.id = sqlc.arg('id') AND books.id = book_reviews.book_id AND books.user_id = sqlc.arg('user_id')
+RETURNING book_reviews.*;
+
+-- Newest first.
+-- name: GetBookReviews :many
+SELECT sqlc.embed(book_reviews), users.first_name AS reviewer_first_name, users.last_name AS reviewer_last_name
+FROM book_reviews
+INNER JOIN users ON users.id = book_reviews.reviewer_id
+WHERE book_reviews.book_id = sqlc.arg('book_id')
+AND (sqlc.narg('cursor_id')::uuid IS NULL OR (book_reviews.created_at, book_reviews.id) < (sqlc.arg('cursor_created_at')::timestamp, sqlc.narg('cursor_id')::uuid))
+ORDER BY book_reviews.created_at DESC, book_reviews.id DESC
+LIMIT sqlc.arg('page_limit')::int;
+
+-- Moves the book's rating totals by the difference a review made. The row lock taken by
+-- the update keeps concurrent reviews from losing each other's changes.
+-- name: AdjustBookRating :exec
+UPDATE books
+SET rating_count = rating_count + sqlc.arg('count_delta')::int, rating_total = rating_total + sqlc.arg('total_delta')::int
+WHERE id = sqlc.arg('id');
@@ -0,0 +1,42 @@
+-- name: GetGenres :many
+-- Books only count for viewers who can see them, the same as in GetBooks.
+SELECT genres.slug, genres.name, COUNT(book_genres.book_id) AS book_count
+FROM genres
+LEFT JOIN book_genres ON book_genres.genre = genres.slug
+    AND EXISTS (
+        SELECT 1 FROM books WHERE books.id = book_genres.book_id AND books.deleted_at IS NULL
+        AND (books.visibility = 'public' OR books.user_id = sqlc.arg('viewer_id')::uuid OR (books.visibility = 'subscribers' AND EXISTS (SELECT 1 FROM user_subscribers WHERE user_subscribers.user_id = books.user_id AND user_subscribers.subscriber_id = sqlc.arg('viewer_id')::uuid)))
+    )
+GROUP BY genres.slug
+ORDER BY genres.name;
+
+-- name: GetPopularBookTags :many
+-- Books only count for viewers who can see them, the same as in GetBooks.
+SELECT tag, COUNT(*) AS book_count
+FROM book_tags
+INNER JOIN books ON books.id = book_tags.book_id
+WHERE books.deleted_at IS NULL
+AND (books.visibility = 'public' OR books.user_id = sqlc.arg('viewer_id')::uuid OR (books.visibility = 'subscribers' AND EXISTS (SELECT 1 FROM user_subscribers WHERE user_subscribers.user_id = books.user_id AND user_subscribers.subscriber_id = sqlc.arg('viewer_id')::uuid)))
+GROUP BY tag
+ORDER BY book_count DESC, tag
+LIMIT sqlc.arg('tag_limit')::int;
+
+-- name: GetBookGenresAndTags :one
+SELECT ARRAY(SELECT genre FROM book_genres WHERE book_genres.book_id = books.id ORDER BY genre)::text[] AS genres,
+    ARRAY(SELECT tag FROM book_tags WHERE book_tags.book_id = books.id ORDER BY tag)::text[] AS tags
+FROM books
+WHERE books.id = $1;
+
+-- name: DeleteBookGenres :exec
+DELETE FROM book_genres WHERE book_id = $1;
+
+-- name: CreateBookGenres :exec
+INSERT INTO book_genres (book_id, genre)
+SELECT sqlc.arg('book_id'), unnest(sqlc.arg('genres')::text[]);
+
+-- name: DeleteBookTags :exec
+DELETE FROM book_tags WHERE book_id = $1;
+
+-- name: CreateBookTags :exec
+INSERT INTO book_tags (book_id, tag)
+SELECT sqlc.arg('book_id'), unnest(sqlc.arg('tags')::text[]);
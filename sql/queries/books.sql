@@ -1,22 +1,128 @@
+-- A loan period of 0 gives the book its owner's default one.
 -- name: CreateBook :one
-INSERT INTO books (id, title, author, created_at, updated_at, user_id)
-VALUES ($1, $2, $3, $4, $5, $6)
-RETURNING id, title, author, created_at, updated_at, user_id;
+INSERT INTO books (id, title, author, created_at, updated_at, user_id, loan_period_days, isbn, publisher, published_year, page_count, cover_url, visibility, lendable, borrower_policy, min_borrower_reputation)
+VALUES (
+    sqlc.arg('id'), sqlc.arg('title'), sqlc.arg('author'), sqlc.arg('created_at'), sqlc.arg('updated_at'), sqlc.arg('user_id'),
+    COALESCE(NULLIF(sqlc.arg('loan_period_days')::integer, 0), (SELECT users.default_loan_period_days FROM users WHERE users.id = sqlc.arg('user_id'))),
+    sqlc.arg('isbn'), sqlc.arg('publisher'), sqlc.arg('published_year'), sqlc.arg('page_count'), sqlc.arg('cover_url'),
+    sqlc.arg('visibility'), sqlc.arg('lendable'), sqlc.arg('borrower_policy'), sqlc.arg('min_borrower_reputation')
+)
+RETURNING id, title, author, created_at, updated_at, user_id, loan_period_days, isbn, publisher, published_year, page_count, cover_url, visibility, lendable, borrower_policy, cover_thumbnail_url, cover_keys, deleted_at, rating_count, rating_total, rating_average, min_borrower_reputation;
 
 -- name: GetBooks :many
-SELECT * FROM books WHERE user_id = $1;
+SELECT sqlc.embed(books), sqlc.embed(book_availability),
+    ARRAY(SELECT genre FROM book_genres WHERE book_genres.book_id = books.id ORDER BY genre)::text[] AS genres,
+    ARRAY(SELECT tag FROM book_tags WHERE book_tags.book_id = books.id ORDER BY tag)::text[] AS tags
+FROM books
+INNER JOIN book_availability ON book_availability.book_id = books.id
+WHERE books.user_id = sqlc.arg('user_id') AND books.deleted_at IS NULL
+-- Private books are only listed for their owner, subscriber-only ones for the owner's subscribers too.
+AND (books.visibility = 'public' OR books.user_id = sqlc.arg('viewer_id')::uuid OR (books.visibility = 'subscribers' AND EXISTS (SELECT 1 FROM user_subscribers WHERE user_subscribers.user_id = books.user_id AND user_subscribers.subscriber_id = sqlc.arg('viewer_id')::uuid)))
+AND (sqlc.narg('shelf_id')::uuid IS NULL OR EXISTS (SELECT 1 FROM shelf_books WHERE shelf_books.shelf_id = sqlc.narg('shelf_id')::uuid AND shelf_books.book_id = books.id))
+AND (sqlc.narg('cursor_id')::uuid IS NULL OR (title, id) > (sqlc.arg('cursor_title')::text, sqlc.narg('cursor_id')::uuid))
+ORDER BY title, id
+LIMIT sqlc.arg('page_limit')::int;
 
 -- name: GetBook :one
-SELECT * FROM books WHERE id = $1;
+SELECT * FROM books WHERE id = $1 AND deleted_at IS NULL;
+
+-- name: GetBookWithAvailability :one
+SELECT sqlc.embed(books), sqlc.embed(book_availability),
+    ARRAY(SELECT genre FROM book_genres WHERE book_genres.book_id = books.id ORDER BY genre)::text[] AS genres,
+    ARRAY(SELECT tag FROM book_tags WHERE book_tags.book_id = books.id ORDER BY tag)::text[] AS tags
+FROM books
+INNER JOIN book_availability ON book_availability.book_id = books.id
+WHERE books.id = $1 AND books.deleted_at IS NULL;
 
 -- name: UpdateBook :one
 UPDATE books 
-SET title = $1, author = $2, updated_at = NOW() 
-WHERE id = $3 AND user_id = $4 
-RETURNING id, title, author, created_at, updated_at, user_id;
+SET title = sqlc.arg('title'), author = sqlc.arg('author'), loan_period_days = COALESCE(sqlc.narg('loan_period_days'), loan_period_days),
+    isbn = COALESCE(sqlc.narg('isbn'), isbn), publisher = COALESCE(sqlc.narg('publisher'), publisher), published_year = COALESCE(sqlc.narg('published_year'), published_year),
+    page_count = COALESCE(sqlc.narg('page_count'), page_count), cover_url = COALESCE(sqlc.narg('cover_url'), cover_url),
+    visibility = COALESCE(sqlc.narg('visibility'), visibility), lendable = COALESCE(sqlc.narg('lendable'), lendable), borrower_policy = COALESCE(sqlc.narg('borrower_policy'), borrower_policy),
+    min_borrower_reputation = COALESCE(sqlc.narg('min_borrower_reputation'), min_borrower_reputation), updated_at = NOW() 
+WHERE id = sqlc.arg('id') AND user_id = sqlc.arg('user_id') AND deleted_at IS NULL
+RETURNING id, title, author, created_at, updated_at, user_id, loan_period_days, isbn, publisher, published_year, page_count, cover_url, visibility, lendable, borrower_policy, cover_thumbnail_url, cover_keys, deleted_at, rating_count, rating_total, rating_average, min_borrower_reputation;
+
+-- name: SetBookCover :one
+UPDATE books
+SET cover_url = sqlc.arg('cover_url'), cover_thumbnail_url = sqlc.arg('cover_thumbnail_url'), cover_keys = sqlc.arg('cover_keys')::text[], updated_at = NOW()
+WHERE id = sqlc.arg('id') AND user_id = sqlc.arg('user_id') AND deleted_at IS NULL
+RETURNING id, title, author, created_at, updated_at, user_id, loan_period_days, isbn, publisher, published_year, page_count, cover_url, visibility, lendable, borrower_policy, cover_thumbnail_url, cover_keys, deleted_at, rating_count, rating_total, rating_average, min_borrower_reputation;
+
+-- What an import checks new books against to skip the ones the owner already has.
+-- name: GetBookIdentities :many
+SELECT isbn, title, author FROM books WHERE user_id = $1 AND deleted_at IS NULL;
 
+-- Moves the book to the trash, unless a copy is still out on loan. The loans,
+-- requests and reservations stay until the book is purged.
 -- name: DeleteBook :execrows
-DELETE FROM books WHERE id = $1 AND user_id = $2;
+UPDATE books SET deleted_at = NOW(), updated_at = NOW()
+WHERE books.id = $1 AND books.user_id = $2 AND books.deleted_at IS NULL
+AND NOT EXISTS (SELECT 1 FROM book_borrows WHERE book_borrows.book_id = books.id AND book_borrows.return_confirmed_at IS NULL);
+
+-- The owner's trash, most recently deleted first.
+-- name: GetDeletedBooks :many
+SELECT sqlc.embed(books),
+    ARRAY(SELECT genre FROM book_genres WHERE book_genres.book_id = books.id ORDER BY genre)::text[] AS genres,
+    ARRAY(SELECT tag FROM book_tags WHERE book_tags.book_id = books.id ORDER BY tag)::text[] AS tags
+FROM books
+WHERE books.user_id = sqlc.arg('user_id') AND books.deleted_at IS NOT NULL
+AND (sqlc.narg('cursor_id')::uuid IS NULL OR (books.deleted_at, books.id) < (sqlc.arg('cursor_deleted_at')::timestamp, sqlc.narg('cursor_id')::uuid))
+ORDER BY books.deleted_at DESC, books.id DESC
+LIMIT sqlc.arg('page_limit')::int;
+
+-- name: RestoreBook :one
+UPDATE books SET deleted_at = NULL, updated_at = NOW()
+WHERE id = $1 AND user_id = $2 AND deleted_at IS NOT NULL
+RETURNING id, title, author, created_at, updated_at, user_id, loan_period_days, isbn, publisher, published_year, page_count, cover_url, visibility, lendable, borrower_policy, cover_thumbnail_url, cover_keys, deleted_at, rating_count, rating_total, rating_average, min_borrower_reputation;
+
+-- Deletes a batch of books that have been in the trash since before the cutoff, along with
+-- everything that cascades from them. Returns the cover and condition photo blobs left to remove.
+-- name: PurgeDeletedBooks :many
+DELETE FROM books
+WHERE books.id IN (
+    SELECT trash.id FROM books AS trash
+    WHERE trash.deleted_at < sqlc.arg('deleted_before')::timestamp
+    AND NOT EXISTS (SELECT 1 FROM book_borrows WHERE book_borrows.book_id = trash.id AND book_borrows.return_confirmed_at IS NULL)
+    ORDER BY trash.deleted_at
+    LIMIT sqlc.arg('batch_size')::int
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING books.id, books.cover_keys,
+    ARRAY(
+        SELECT bcr.photo_key FROM book_condition_reports AS bcr
+        INNER JOIN book_borrows AS bb ON bb.id = bcr.book_borrow_id
+        WHERE bb.book_id = books.id AND bcr.photo_key <> ''
+    )::text[] AS condition_photo_keys;
 
 -- name: BrowseBooks :many
-SELECT * FROM books;
\ No newline at end of file
+SELECT sqlc.embed(books), sqlc.embed(book_availability),
+    ARRAY(SELECT genre FROM book_genres WHERE book_genres.book_id = books.id ORDER BY genre)::text[] AS genres,
+    ARRAY(SELECT tag FROM book_tags WHERE book_tags.book_id = books.id ORDER BY tag)::text[] AS tags,
+    COALESCE(ts_rank(to_tsvector('english', title || ' ' || author), websearch_to_tsquery('english', sqlc.narg('query')::text)), 0)::real AS rank
+FROM books
+INNER JOIN book_availability ON book_availability.book_id = books.id
+WHERE books.deleted_at IS NULL
+AND (sqlc.narg('query')::text IS NULL OR to_tsvector('english', title || ' ' || author) @@ websearch_to_tsquery('english', sqlc.narg('query')::text))
+AND (sqlc.narg('author')::text IS NULL OR author ILIKE '%' || sqlc.narg('author')::text || '%')
+AND (sqlc.narg('available')::boolean IS NULL OR sqlc.narg('available')::boolean = (book_availability.available_copies > 0))
+AND (sqlc.narg('genre')::text IS NULL OR EXISTS (SELECT 1 FROM book_genres WHERE book_genres.book_id = books.id AND book_genres.genre = sqlc.narg('genre')::text))
+AND (sqlc.narg('tag')::text IS NULL OR EXISTS (SELECT 1 FROM book_tags WHERE book_tags.book_id = books.id AND book_tags.tag = sqlc.narg('tag')::text))
+-- Private books are only listed for their owner, subscriber-only ones for the owner's subscribers too.
+AND (books.visibility = 'public' OR books.user_id = sqlc.arg('viewer_id')::uuid OR (books.visibility = 'subscribers' AND EXISTS (SELECT 1 FROM user_subscribers WHERE user_subscribers.user_id = books.user_id AND user_subscribers.subscriber_id = sqlc.arg('viewer_id')::uuid)))
+-- The cursor is the sort key and id of the last book on the previous page.
+AND (sqlc.narg('cursor_id')::uuid IS NULL OR CASE sqlc.arg('sort')::text
+    WHEN 'title' THEN (title, id) > (sqlc.arg('cursor_title')::text, sqlc.narg('cursor_id')::uuid)
+    WHEN 'created_at' THEN (created_at, id) < (sqlc.arg('cursor_created_at')::timestamp, sqlc.narg('cursor_id')::uuid)
+    WHEN 'rating' THEN (books.rating_average, id) < (sqlc.arg('cursor_rating')::float8, sqlc.narg('cursor_id')::uuid)
+    ELSE (ts_rank(to_tsvector('english', title || ' ' || author), websearch_to_tsquery('english', sqlc.narg('query')::text)), id) < (sqlc.arg('cursor_rank')::real, sqlc.narg('cursor_id')::uuid)
+END)
+ORDER BY
+    CASE WHEN sqlc.arg('sort')::text = 'relevance' THEN ts_rank(to_tsvector('english', title || ' ' || author), websearch_to_tsquery('english', sqlc.narg('query')::text)) END DESC,
+    CASE WHEN sqlc.arg('sort')::text = 'created_at' THEN created_at END DESC,
+    CASE WHEN sqlc.arg('sort')::text = 'rating' THEN books.rating_average END DESC,
+    CASE WHEN sqlc.arg('sort')::text = 'title' THEN title END,
+    CASE WHEN sqlc.arg('sort')::text = 'title' THEN id END,
+    id DESC
+LIMIT sqlc.arg('page_limit')::int;
\ No newline at end of file
@@ -0,0 +1,9 @@
+-- name: GetBorrowerReputationStats :one
+SELECT * FROM borrower_reputation_stats WHERE borrower_id = $1;
+
+-- The owner rates the borrower once per loan, a second rating returns no rows.
+-- name: CreateBorrowerRating :one
+INSERT INTO borrower_ratings (id, rating, comment, created_at, book_borrow_id, lender_id, borrower_id)
+VALUES ($1, $2, $3, NOW(), $4, $5, $6)
+ON CONFLICT (book_borrow_id) DO NOTHING
+RETURNING *;
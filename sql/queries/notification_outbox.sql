@@ -0,0 +1,41 @@
+-- name: CreateOutboxNotification :one
+INSERT INTO notification_outbox (id, sender, recipient, subject, body, html_body, next_attempt_at, created_at, updated_at)
+VALUES ($1, $2, $3, $4, $5, $6, NOW(), NOW(), NOW())
+RETURNING *;
+
+-- Leases due notifications to one worker by pushing next_attempt_at out, so another
+-- worker polling at the same time skips them.
+-- name: ClaimDueOutboxNotifications :many
+UPDATE notification_outbox
+SET next_attempt_at = sqlc.arg('lease_until')::timestamp, updated_at = NOW()
+WHERE id IN (
+    SELECT id FROM notification_outbox
+    WHERE status = 'pending' AND next_attempt_at <= NOW()
+    ORDER BY next_attempt_at
+    LIMIT sqlc.arg('batch_size')::int
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING *;
+
+-- name: MarkOutboxNotificationSent :exec
+UPDATE notification_outbox
+SET status = 'sent', attempts = attempts + 1, last_error = NULL, sent_at = NOW(), updated_at = NOW()
+WHERE id = $1;
+
+-- name: RecordOutboxNotificationFailure :one
+UPDATE notification_outbox
+SET status = sqlc.arg('status'), attempts = attempts + 1, last_error = sqlc.arg('last_error'), next_attempt_at = sqlc.arg('next_attempt_at'), updated_at = NOW()
+WHERE id = sqlc.arg('id')
+RETURNING *;
+
+-- name: GetOutboxNotifications :many
+SELECT * FROM notification_outbox
+WHERE sqlc.narg('status')::text IS NULL OR status = sqlc.narg('status')::text
+ORDER BY created_at DESC
+LIMIT 100;
+
+-- name: ReplayOutboxNotification :one
+UPDATE notification_outbox
+SET status = 'pending', attempts = 0, last_error = NULL, next_attempt_at = NOW(), updated_at = NOW()
+WHERE id = $1 AND status = 'dead'
+RETURNING *;
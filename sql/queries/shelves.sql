@@ -0,0 +1,74 @@
+-- name: CreateShelf :one
+INSERT INTO shelves (id, name, description, is_public, created_at, updated_at, user_id)
+VALUES ($1, $2, $3, $4, NOW(), NOW(), $5)
+RETURNING *;
+
+-- The book count only takes in the books the viewer can see, like GetShelfBooks.
+-- name: GetShelf :one
+SELECT sqlc.embed(shelves), (SELECT COUNT(*) FROM shelf_books INNER JOIN books ON books.id = shelf_books.book_id WHERE shelf_books.shelf_id = shelves.id AND books.deleted_at IS NULL
+    AND (books.visibility = 'public' OR books.user_id = sqlc.arg('viewer_id')::uuid OR (books.visibility = 'subscribers' AND EXISTS (SELECT 1 FROM user_subscribers WHERE user_subscribers.user_id = books.user_id AND user_subscribers.subscriber_id = sqlc.arg('viewer_id')::uuid)))) AS book_count
+FROM shelves
+WHERE shelves.id = sqlc.arg('id');
+
+-- name: GetShelfByName :one
+SELECT * FROM shelves WHERE user_id = $1 AND lower(name) = lower(sqlc.arg('name'));
+
+-- Counted for the viewer, like GetShelf.
+-- name: GetShelvesByUserID :many
+SELECT sqlc.embed(shelves), (SELECT COUNT(*) FROM shelf_books INNER JOIN books ON books.id = shelf_books.book_id WHERE shelf_books.shelf_id = shelves.id AND books.deleted_at IS NULL
+    AND (books.visibility = 'public' OR books.user_id = sqlc.arg('viewer_id')::uuid OR (books.visibility = 'subscribers' AND EXISTS (SELECT 1 FROM user_subscribers WHERE user_subscribers.user_id = books.user_id AND user_subscribers.subscriber_id = sqlc.arg('viewer_id')::uuid)))) AS book_count
+FROM shelves
+WHERE shelves.user_id = sqlc.arg('user_id') AND (sqlc.arg('include_private')::boolean OR shelves.is_public)
+ORDER BY lower(shelves.name), shelves.id;
+
+-- name: UpdateShelf :one
+UPDATE shelves
+SET name = COALESCE(sqlc.narg('name'), name), description = COALESCE(sqlc.narg('description'), description),
+    is_public = COALESCE(sqlc.narg('is_public'), is_public), updated_at = NOW()
+WHERE id = sqlc.arg('id') AND user_id = sqlc.arg('user_id')
+RETURNING *;
+
+-- name: DeleteShelf :execrows
+DELETE FROM shelves WHERE id = $1 AND user_id = $2;
+
+-- Only the shelf owner's own books go on it, new ones at the end. Adding a
+-- book that is already there leaves it where it is.
+-- name: AddShelfBook :one
+INSERT INTO shelf_books (shelf_id, book_id, position, added_at)
+SELECT shelves.id, books.id, COALESCE((SELECT MAX(position) FROM shelf_books WHERE shelf_books.shelf_id = shelves.id), 0) + 1, NOW()
+FROM shelves
+INNER JOIN books ON books.user_id = shelves.user_id
+WHERE shelves.id = sqlc.arg('shelf_id') AND books.id = sqlc.arg('book_id') AND shelves.user_id = sqlc.arg('user_id') AND books.deleted_at IS NULL
+ON CONFLICT (shelf_id, book_id) DO UPDATE SET position = shelf_books.position
+RETURNING *;
+
+-- name: RemoveShelfBook :execrows
+DELETE FROM shelf_books
+USING shelves
+WHERE shelf_books.shelf_id = shelves.id AND shelves.id = sqlc.arg('shelf_id') AND shelf_books.book_id = sqlc.arg('book_id') AND shelves.user_id = sqlc.arg('user_id');
+
+-- name: GetShelfBooks :many
+SELECT sqlc.embed(shelf_books), sqlc.embed(books),
+    ARRAY(SELECT genre FROM book_genres WHERE book_genres.book_id = books.id ORDER BY genre)::text[] AS genres,
+    ARRAY(SELECT tag FROM book_tags WHERE book_tags.book_id = books.id ORDER BY tag)::text[] AS tags
+FROM shelf_books
+INNER JOIN books ON books.id = shelf_books.book_id
+WHERE shelf_books.shelf_id = sqlc.arg('shelf_id') AND books.deleted_at IS NULL
+-- Private books are only listed for their owner, subscriber-only ones for the owner's subscribers too.
+AND (books.visibility = 'public' OR books.user_id = sqlc.arg('viewer_id')::uuid OR (books.visibility = 'subscribers' AND EXISTS (SELECT 1 FROM user_subscribers WHERE user_subscribers.user_id = books.user_id AND user_subscribers.subscriber_id = sqlc.arg('viewer_id')::uuid)))
+AND (sqlc.narg('cursor_id')::uuid IS NULL OR (shelf_books.position, books.id) > (sqlc.arg('cursor_position')::int, sqlc.narg('cursor_id')::uuid))
+ORDER BY shelf_books.position, books.id
+LIMIT sqlc.arg('page_limit')::int;
+
+-- Books in the trash keep their place but are left out of the order.
+-- name: GetShelfBookIDs :many
+SELECT shelf_books.book_id FROM shelf_books
+INNER JOIN books ON books.id = shelf_books.book_id
+WHERE shelf_books.shelf_id = $1 AND books.deleted_at IS NULL;
+
+-- Numbers the books 1, 2, 3... in the order given.
+-- name: ReorderShelfBooks :exec
+UPDATE shelf_books
+SET position = ordered_books.position
+FROM unnest(sqlc.arg('book_ids')::uuid[]) WITH ORDINALITY AS ordered_books(book_id, position)
+WHERE shelf_books.shelf_id = sqlc.arg('shelf_id') AND shelf_books.book_id = ordered_books.book_id;
@@ -10,7 +10,15 @@ SELECT * FROM user_subscribers WHERE subscriber_id = $1 AND user_id = $2;
 DELETE FROM user_subscribers WHERE subscriber_id = $1 AND user_id = $2;
 
 -- name: GetUserSubscribers :many
-SELECT * FROM user_subscribers WHERE user_id = $1;
+SELECT * FROM user_subscribers
+WHERE user_id = sqlc.arg('user_id')
+AND (sqlc.narg('cursor_id')::uuid IS NULL OR (created_at, id) < (sqlc.arg('cursor_created_at')::timestamp, sqlc.narg('cursor_id')::uuid))
+ORDER BY created_at DESC, id DESC
+LIMIT sqlc.arg('page_limit')::int;
 
 -- name: GetUserSubscriptions :many
-SELECT * FROM user_subscribers WHERE subscriber_id = $1;
\ No newline at end of file
+SELECT * FROM user_subscribers
+WHERE subscriber_id = sqlc.arg('subscriber_id')
+AND (sqlc.narg('cursor_id')::uuid IS NULL OR (created_at, id) < (sqlc.arg('cursor_created_at')::timestamp, sqlc.narg('cursor_id')::uuid))
+ORDER BY created_at DESC, id DESC
+LIMIT sqlc.arg('page_limit')::int;
\ No newline at end of file
@@ -1,7 +1,7 @@
 -- name: CreateUser :one
-INSERT INTO users (id, first_name, last_name, email, password, created_at, updated_at)
-VALUES ($1, $2, $3, $4, $5, $6, $7)
-RETURNING id, first_name, last_name, email, password, created_at, updated_at;
+INSERT INTO users (id, first_name, last_name, email, password, created_at, updated_at, locale)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+RETURNING id, first_name, last_name, email, password, created_at, updated_at, locale, default_loan_period_days;
 
 -- name: GetUserByEmail :one
 SELECT * FROM users WHERE email = $1;
@@ -14,4 +14,20 @@ ON us.subscriber_id = u.ID
 WHERE us.user_id = $1;
 
 -- name: GetUserByID :one
-SELECT * FROM users WHERE id = $1;
\ No newline at end of file
+SELECT * FROM users WHERE id = $1;
+
+-- name: UpdateUserLocale :one
+UPDATE users SET locale = $1, updated_at = NOW()
+WHERE id = $2
+RETURNING id, first_name, last_name, email, password, created_at, updated_at, locale, default_loan_period_days;
+
+-- name: UpdateUserDefaultLoanPeriod :one
+UPDATE users SET default_loan_period_days = $1, updated_at = NOW()
+WHERE id = $2
+RETURNING id, first_name, last_name, email, password, created_at, updated_at, locale, default_loan_period_days;
+
+-- name: GetUserProfile :one
+SELECT sqlc.embed(users), sqlc.embed(borrower_reputation_stats)
+FROM users
+INNER JOIN borrower_reputation_stats ON borrower_reputation_stats.borrower_id = users.id
+WHERE users.id = $1;
@@ -0,0 +1,15 @@
+-- +goose Up
+
+ALTER TABLE books ADD COLUMN loan_period_days INTEGER NOT NULL DEFAULT 14;
+
+ALTER TABLE book_borrows ADD COLUMN due_at TIMESTAMP NULL;
+
+UPDATE book_borrows SET due_at = issued_at + (INTERVAL '1 day' * 14);
+
+ALTER TABLE book_borrows ALTER COLUMN due_at SET NOT NULL;
+
+-- +goose Down
+
+ALTER TABLE book_borrows DROP COLUMN due_at;
+
+ALTER TABLE books DROP COLUMN loan_period_days;
@@ -0,0 +1,19 @@
+-- +goose Up
+
+CREATE TABLE book_borrow_requests (
+    id UUID PRIMARY KEY,
+    status TEXT NOT NULL DEFAULT 'requested' CHECK (status IN ('requested', 'approved', 'declined', 'issued', 'returned', 'cancelled')),
+    responded_at TIMESTAMP NULL,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    book_id UUID NOT NULL REFERENCES books(id) ON DELETE CASCADE,
+    requester_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    book_borrow_id UUID NULL REFERENCES book_borrows(id) ON DELETE SET NULL
+);
+
+-- A borrower can only have one open request per book.
+CREATE UNIQUE INDEX book_borrow_requests_open_idx ON book_borrow_requests (book_id, requester_id) WHERE status IN ('requested', 'approved', 'issued');
+
+-- +goose Down
+
+DROP TABLE book_borrow_requests;
@@ -0,0 +1,21 @@
+-- +goose Up
+
+CREATE TABLE book_reservations (
+    id UUID PRIMARY KEY,
+    status TEXT NOT NULL DEFAULT 'waiting' CHECK (status IN ('waiting', 'offered', 'fulfilled', 'expired', 'cancelled')),
+    offered_at TIMESTAMP NULL,
+    offer_expires_at TIMESTAMP NULL,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    book_id UUID NOT NULL REFERENCES books(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE
+);
+
+-- A user can only hold one place in the waitlist of a book.
+CREATE UNIQUE INDEX book_reservations_active_idx ON book_reservations (book_id, user_id) WHERE status IN ('waiting', 'offered');
+
+CREATE INDEX book_reservations_queue_idx ON book_reservations (book_id, created_at) WHERE status = 'waiting';
+
+-- +goose Down
+
+DROP TABLE book_reservations;
@@ -0,0 +1,13 @@
+-- +goose Up
+
+CREATE TABLE book_borrow_renewals (
+    id UUID PRIMARY KEY,
+    previous_due_at TIMESTAMP NOT NULL,
+    new_due_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    book_borrow_id UUID NOT NULL REFERENCES book_borrows(id) ON DELETE CASCADE
+);
+
+-- +goose Down
+
+DROP TABLE book_borrow_renewals;
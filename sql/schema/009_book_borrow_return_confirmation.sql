@@ -0,0 +1,14 @@
+-- +goose Up
+
+ALTER TABLE book_borrows ADD COLUMN return_status TEXT NULL CHECK (return_status IN ('pending', 'confirmed', 'disputed'));
+ALTER TABLE book_borrows ADD COLUMN return_confirmed_at TIMESTAMP NULL;
+ALTER TABLE book_borrows ADD COLUMN return_disputed_at TIMESTAMP NULL;
+
+-- Returns made before owners had to confirm them are treated as confirmed.
+UPDATE book_borrows SET return_status = 'confirmed', return_confirmed_at = returned_at WHERE returned_at IS NOT NULL;
+
+-- +goose Down
+
+ALTER TABLE book_borrows DROP COLUMN return_disputed_at;
+ALTER TABLE book_borrows DROP COLUMN return_confirmed_at;
+ALTER TABLE book_borrows DROP COLUMN return_status;
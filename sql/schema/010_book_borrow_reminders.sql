@@ -0,0 +1,15 @@
+-- +goose Up
+
+CREATE TABLE book_borrow_reminders (
+    id UUID PRIMARY KEY,
+    threshold TEXT NOT NULL,
+    sent_at TIMESTAMP NOT NULL,
+    book_borrow_id UUID NOT NULL REFERENCES book_borrows(id) ON DELETE CASCADE
+);
+
+-- A reminder is only ever sent once per borrow and threshold.
+CREATE UNIQUE INDEX book_borrow_reminders_threshold_idx ON book_borrow_reminders (book_borrow_id, threshold);
+
+-- +goose Down
+
+DROP TABLE book_borrow_reminders;
@@ -0,0 +1,11 @@
+-- +goose Up
+
+ALTER TABLE users ADD COLUMN locale TEXT NOT NULL DEFAULT 'en';
+
+ALTER TABLE notification_outbox ADD COLUMN html_body TEXT NOT NULL DEFAULT '';
+
+-- +goose Down
+
+ALTER TABLE notification_outbox DROP COLUMN html_body;
+
+ALTER TABLE users DROP COLUMN locale;
@@ -0,0 +1,8 @@
+-- +goose Up
+
+-- BrowseBooks must use this exact expression for the index to be picked up.
+CREATE INDEX books_search_idx ON books USING GIN (to_tsvector('english', title || ' ' || author));
+
+-- +goose Down
+
+DROP INDEX books_search_idx;
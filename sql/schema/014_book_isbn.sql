@@ -0,0 +1,21 @@
+-- +goose Up
+
+-- ISBNs are stored normalized to ISBN-13, the other columns come from the catalog
+-- provider or the owner and are empty/zero when unknown.
+ALTER TABLE books ADD COLUMN isbn TEXT NOT NULL DEFAULT '';
+ALTER TABLE books ADD COLUMN publisher TEXT NOT NULL DEFAULT '';
+ALTER TABLE books ADD COLUMN published_year INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE books ADD COLUMN page_count INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE books ADD COLUMN cover_url TEXT NOT NULL DEFAULT '';
+
+CREATE INDEX books_isbn_idx ON books (isbn) WHERE isbn <> '';
+
+-- +goose Down
+
+DROP INDEX books_isbn_idx;
+
+ALTER TABLE books DROP COLUMN cover_url;
+ALTER TABLE books DROP COLUMN page_count;
+ALTER TABLE books DROP COLUMN published_year;
+ALTER TABLE books DROP COLUMN publisher;
+ALTER TABLE books DROP COLUMN isbn;
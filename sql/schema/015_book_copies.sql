@@ -0,0 +1,33 @@
+-- +goose Up
+
+-- A book is an edition, each copy of it is lent out on its own.
+CREATE TABLE book_copies (
+    id UUID PRIMARY KEY,
+    condition TEXT NOT NULL DEFAULT 'good' CHECK (condition IN ('new', 'good', 'fair', 'poor')),
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    book_id UUID NOT NULL REFERENCES books(id) ON DELETE CASCADE
+);
+
+CREATE INDEX book_copies_book_idx ON book_copies (book_id);
+
+-- Every book so far was a single copy.
+INSERT INTO book_copies (id, condition, created_at, updated_at, book_id)
+SELECT gen_random_uuid(), 'good', created_at, created_at, id FROM books;
+
+ALTER TABLE book_borrows ADD COLUMN book_copy_id UUID NULL REFERENCES book_copies(id) ON DELETE CASCADE;
+
+UPDATE book_borrows SET book_copy_id = book_copies.id FROM book_copies WHERE book_copies.book_id = book_borrows.book_id;
+
+ALTER TABLE book_borrows ALTER COLUMN book_copy_id SET NOT NULL;
+
+-- A copy can only be out with one borrower at a time.
+CREATE UNIQUE INDEX book_borrows_active_copy_idx ON book_borrows (book_copy_id) WHERE return_confirmed_at IS NULL;
+
+-- +goose Down
+
+DROP INDEX book_borrows_active_copy_idx;
+
+ALTER TABLE book_borrows DROP COLUMN book_copy_id;
+
+DROP TABLE book_copies;
@@ -0,0 +1,41 @@
+-- +goose Up
+
+-- Lending state of every book, joined into the book queries so listing books does not
+-- need a lookup per book. Copies held for someone in the waitlist are not available to
+-- anyone else, and the current borrower is the one whose copy is due back first.
+CREATE VIEW book_availability AS
+SELECT
+    books.id AS book_id,
+    copies.total_copies::bigint AS total_copies,
+    GREATEST(copies.free_copies - offers.offered_copies, 0)::bigint AS available_copies,
+    queue.queue_length::bigint AS queue_length,
+    next_return.borrower_id AS current_borrower_id,
+    current_borrower.first_name AS current_borrower_first_name,
+    current_borrower.last_name AS current_borrower_last_name,
+    next_return.due_at AS due_at
+FROM books
+CROSS JOIN LATERAL (
+    SELECT COUNT(*) AS total_copies, COUNT(*) FILTER (
+        WHERE NOT EXISTS (SELECT 1 FROM book_borrows WHERE book_borrows.book_copy_id = book_copies.id AND book_borrows.return_confirmed_at IS NULL)
+    ) AS free_copies
+    FROM book_copies WHERE book_copies.book_id = books.id
+) AS copies
+CROSS JOIN LATERAL (
+    SELECT COUNT(*) AS offered_copies FROM book_reservations
+    WHERE book_reservations.book_id = books.id AND book_reservations.status = 'offered' AND book_reservations.offer_expires_at > NOW()
+) AS offers
+CROSS JOIN LATERAL (
+    SELECT COUNT(*) AS queue_length FROM book_reservations
+    WHERE book_reservations.book_id = books.id AND book_reservations.status = 'waiting'
+) AS queue
+LEFT JOIN book_borrows AS next_return ON next_return.id = (
+    SELECT book_borrows.id FROM book_borrows
+    WHERE book_borrows.book_id = books.id AND book_borrows.return_confirmed_at IS NULL
+    ORDER BY book_borrows.due_at, book_borrows.id
+    LIMIT 1
+)
+LEFT JOIN users AS current_borrower ON current_borrower.id = next_return.borrower_id;
+
+-- +goose Down
+
+DROP VIEW book_availability;
@@ -0,0 +1,54 @@
+-- +goose Up
+
+-- The curated genres owners pick from, tags are free-form.
+CREATE TABLE genres (
+    slug TEXT PRIMARY KEY,
+    name TEXT NOT NULL
+);
+
+INSERT INTO genres (slug, name) VALUES
+    ('biography', 'Biography & Memoir'),
+    ('business', 'Business & Economics'),
+    ('childrens', 'Children''s'),
+    ('classics', 'Classics'),
+    ('comics', 'Comics & Graphic Novels'),
+    ('cooking', 'Cooking'),
+    ('fantasy', 'Fantasy'),
+    ('historical-fiction', 'Historical Fiction'),
+    ('history', 'History'),
+    ('horror', 'Horror'),
+    ('literary-fiction', 'Literary Fiction'),
+    ('mystery', 'Mystery & Crime'),
+    ('philosophy', 'Philosophy'),
+    ('poetry', 'Poetry'),
+    ('romance', 'Romance'),
+    ('science', 'Science & Nature'),
+    ('science-fiction', 'Science Fiction'),
+    ('self-help', 'Self-Help'),
+    ('thriller', 'Thriller'),
+    ('travel', 'Travel'),
+    ('young-adult', 'Young Adult');
+
+CREATE TABLE book_genres (
+    book_id UUID NOT NULL REFERENCES books(id) ON DELETE CASCADE,
+    genre TEXT NOT NULL REFERENCES genres(slug) ON UPDATE CASCADE,
+    PRIMARY KEY (book_id, genre)
+);
+
+CREATE INDEX book_genres_genre_idx ON book_genres (genre);
+
+CREATE TABLE book_tags (
+    book_id UUID NOT NULL REFERENCES books(id) ON DELETE CASCADE,
+    tag TEXT NOT NULL,
+    PRIMARY KEY (book_id, tag)
+);
+
+CREATE INDEX book_tags_tag_idx ON book_tags (tag);
+
+-- +goose Down
+
+DROP TABLE book_tags;
+
+DROP TABLE book_genres;
+
+DROP TABLE genres;
@@ -0,0 +1,30 @@
+-- +goose Up
+
+-- Named collections a member sorts their own books into.
+CREATE TABLE shelves (
+    id UUID PRIMARY KEY,
+    name TEXT NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    is_public BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE UNIQUE INDEX shelves_user_name_idx ON shelves (user_id, lower(name));
+
+CREATE TABLE shelf_books (
+    shelf_id UUID NOT NULL REFERENCES shelves(id) ON DELETE CASCADE,
+    book_id UUID NOT NULL REFERENCES books(id) ON DELETE CASCADE,
+    position INTEGER NOT NULL,
+    added_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (shelf_id, book_id)
+);
+
+CREATE INDEX shelf_books_book_idx ON shelf_books (book_id);
+
+-- +goose Down
+
+DROP TABLE shelf_books;
+
+DROP TABLE shelves;
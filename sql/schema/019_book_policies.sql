@@ -0,0 +1,22 @@
+-- +goose Up
+
+-- Who can see a book and who can borrow it, chosen by its owner.
+ALTER TABLE books ADD COLUMN visibility TEXT NOT NULL DEFAULT 'public' CHECK (visibility IN ('public', 'subscribers', 'private'));
+ALTER TABLE books ADD COLUMN lendable BOOLEAN NOT NULL DEFAULT TRUE;
+ALTER TABLE books ADD COLUMN borrower_policy TEXT NOT NULL DEFAULT 'anyone' CHECK (borrower_policy IN ('anyone', 'subscribers', 'approved'));
+
+-- Members an owner trusts with the books that only lend to approved borrowers.
+CREATE TABLE approved_borrowers (
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    borrower_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    created_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (user_id, borrower_id)
+);
+
+-- +goose Down
+
+DROP TABLE approved_borrowers;
+
+ALTER TABLE books DROP COLUMN borrower_policy;
+ALTER TABLE books DROP COLUMN lendable;
+ALTER TABLE books DROP COLUMN visibility;
@@ -0,0 +1,11 @@
+-- +goose Up
+
+-- Uploaded covers are kept in the blob store, cover_keys lists the stored files so
+-- they can be removed when the cover is replaced. Covers from the catalog have none.
+ALTER TABLE books ADD COLUMN cover_thumbnail_url TEXT NOT NULL DEFAULT '';
+ALTER TABLE books ADD COLUMN cover_keys TEXT[] NOT NULL DEFAULT '{}';
+
+-- +goose Down
+
+ALTER TABLE books DROP COLUMN cover_keys;
+ALTER TABLE books DROP COLUMN cover_thumbnail_url;
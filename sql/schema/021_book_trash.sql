@@ -0,0 +1,13 @@
+-- +goose Up
+
+-- Deleted books go to their owner's trash, keeping their loan history, until they are
+-- restored or purged once the retention window has passed.
+ALTER TABLE books ADD COLUMN deleted_at TIMESTAMP NULL;
+
+CREATE INDEX books_deleted_at_idx ON books (deleted_at) WHERE deleted_at IS NOT NULL;
+
+-- +goose Down
+
+DROP INDEX books_deleted_at_idx;
+
+ALTER TABLE books DROP COLUMN deleted_at;
@@ -0,0 +1,137 @@
+-- +goose Up
+
+-- A copy lost on loan and written off is kept with its loan history, but never lent again.
+ALTER TABLE book_copies DROP CONSTRAINT book_copies_condition_check;
+ALTER TABLE book_copies ADD CONSTRAINT book_copies_condition_check CHECK (condition IN ('new', 'good', 'fair', 'poor', 'lost'));
+
+-- A loan reported lost stays open until the claim about it is settled, and is then
+-- closed with a lost return status unless the book turned up.
+ALTER TABLE book_borrows ADD COLUMN lost_at TIMESTAMP NULL;
+ALTER TABLE book_borrows DROP CONSTRAINT book_borrows_return_status_check;
+ALTER TABLE book_borrows ADD CONSTRAINT book_borrows_return_status_check CHECK (return_status IN ('pending', 'confirmed', 'disputed', 'lost'));
+
+-- What a copy looked like when it changed hands, as seen by each side of the loan.
+CREATE TABLE book_condition_reports (
+    id UUID PRIMARY KEY,
+    stage TEXT NOT NULL CHECK (stage IN ('issue', 'return')),
+    condition TEXT NOT NULL CHECK (condition IN ('new', 'good', 'fair', 'poor', 'damaged')),
+    notes TEXT NOT NULL DEFAULT '',
+    photo_url TEXT NOT NULL DEFAULT '',
+    photo_key TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL,
+    book_borrow_id UUID NOT NULL REFERENCES book_borrows(id) ON DELETE CASCADE,
+    reporter_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE UNIQUE INDEX book_condition_reports_stage_idx ON book_condition_reports (book_borrow_id, stage, reporter_id);
+
+-- Damage and loss claims on a loan, open until the book owner settles them.
+CREATE TABLE book_claims (
+    id UUID PRIMARY KEY,
+    kind TEXT NOT NULL CHECK (kind IN ('damage', 'loss')),
+    status TEXT NOT NULL DEFAULT 'open' CHECK (status IN ('open', 'resolved')),
+    description TEXT NOT NULL DEFAULT '',
+    resolution TEXT NULL CHECK (resolution IN ('compensated', 'waived', 'found')),
+    resolution_note TEXT NOT NULL DEFAULT '',
+    resolved_at TIMESTAMP NULL,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    book_borrow_id UUID NOT NULL REFERENCES book_borrows(id) ON DELETE CASCADE,
+    opened_by UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE UNIQUE INDEX book_claims_open_idx ON book_claims (book_borrow_id) WHERE status = 'open';
+
+CREATE TABLE book_claim_comments (
+    id UUID PRIMARY KEY,
+    body TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    book_claim_id UUID NOT NULL REFERENCES book_claims(id) ON DELETE CASCADE,
+    author_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX book_claim_comments_claim_idx ON book_claim_comments (book_claim_id, created_at);
+
+-- Same as before, except written off copies no longer count.
+CREATE OR REPLACE VIEW book_availability AS
+SELECT
+    books.id AS book_id,
+    copies.total_copies::bigint AS total_copies,
+    GREATEST(copies.free_copies - offers.offered_copies, 0)::bigint AS available_copies,
+    queue.queue_length::bigint AS queue_length,
+    next_return.borrower_id AS current_borrower_id,
+    current_borrower.first_name AS current_borrower_first_name,
+    current_borrower.last_name AS current_borrower_last_name,
+    next_return.due_at AS due_at
+FROM books
+CROSS JOIN LATERAL (
+    SELECT COUNT(*) AS total_copies, COUNT(*) FILTER (
+        WHERE NOT EXISTS (SELECT 1 FROM book_borrows WHERE book_borrows.book_copy_id = book_copies.id AND book_borrows.return_confirmed_at IS NULL)
+    ) AS free_copies
+    FROM book_copies WHERE book_copies.book_id = books.id AND book_copies.condition <> 'lost'
+) AS copies
+CROSS JOIN LATERAL (
+    SELECT COUNT(*) AS offered_copies FROM book_reservations
+    WHERE book_reservations.book_id = books.id AND book_reservations.status = 'offered' AND book_reservations.offer_expires_at > NOW()
+) AS offers
+CROSS JOIN LATERAL (
+    SELECT COUNT(*) AS queue_length FROM book_reservations
+    WHERE book_reservations.book_id = books.id AND book_reservations.status = 'waiting'
+) AS queue
+LEFT JOIN book_borrows AS next_return ON next_return.id = (
+    SELECT book_borrows.id FROM book_borrows
+    WHERE book_borrows.book_id = books.id AND book_borrows.return_confirmed_at IS NULL
+    ORDER BY book_borrows.due_at, book_borrows.id
+    LIMIT 1
+)
+LEFT JOIN users AS current_borrower ON current_borrower.id = next_return.borrower_id;
+
+-- +goose Down
+
+CREATE OR REPLACE VIEW book_availability AS
+SELECT
+    books.id AS book_id,
+    copies.total_copies::bigint AS total_copies,
+    GREATEST(copies.free_copies - offers.offered_copies, 0)::bigint AS available_copies,
+    queue.queue_length::bigint AS queue_length,
+    next_return.borrower_id AS current_borrower_id,
+    current_borrower.first_name AS current_borrower_first_name,
+    current_borrower.last_name AS current_borrower_last_name,
+    next_return.due_at AS due_at
+FROM books
+CROSS JOIN LATERAL (
+    SELECT COUNT(*) AS total_copies, COUNT(*) FILTER (
+        WHERE NOT EXISTS (SELECT 1 FROM book_borrows WHERE book_borrows.book_copy_id = book_copies.id AND book_borrows.return_confirmed_at IS NULL)
+    ) AS free_copies
+    FROM book_copies WHERE book_copies.book_id = books.id
+) AS copies
+CROSS JOIN LATERAL (
+    SELECT COUNT(*) AS offered_copies FROM book_reservations
+    WHERE book_reservations.book_id = books.id AND book_reservations.status = 'offered' AND book_reservations.offer_expires_at > NOW()
+) AS offers
+CROSS JOIN LATERAL (
+    SELECT COUNT(*) AS queue_length FROM book_reservations
+    WHERE book_reservations.book_id = books.id AND book_reservations.status = 'waiting'
+) AS queue
+LEFT JOIN book_borrows AS next_return ON next_return.id = (
+    SELECT book_borrows.id FROM book_borrows
+    WHERE book_borrows.book_id = books.id AND book_borrows.return_confirmed_at IS NULL
+    ORDER BY book_borrows.due_at, book_borrows.id
+    LIMIT 1
+)
+LEFT JOIN users AS current_borrower ON current_borrower.id = next_return.borrower_id;
+
+DROP TABLE book_claim_comments;
+
+DROP TABLE book_claims;
+
+DROP TABLE book_condition_reports;
+
+UPDATE book_borrows SET return_status = 'confirmed' WHERE return_status = 'lost';
+ALTER TABLE book_borrows DROP CONSTRAINT book_borrows_return_status_check;
+ALTER TABLE book_borrows ADD CONSTRAINT book_borrows_return_status_check CHECK (return_status IN ('pending', 'confirmed', 'disputed'));
+ALTER TABLE book_borrows DROP COLUMN lost_at;
+
+UPDATE book_copies SET condition = 'poor' WHERE condition = 'lost';
+ALTER TABLE book_copies DROP CONSTRAINT book_copies_condition_check;
+ALTER TABLE book_copies ADD CONSTRAINT book_copies_condition_check CHECK (condition IN ('new', 'good', 'fair', 'poor'));
@@ -0,0 +1,35 @@
+-- +goose Up
+
+-- A member's star rating and review of a book they borrowed, with the owner's reply.
+CREATE TABLE book_reviews (
+    id UUID PRIMARY KEY,
+    rating INTEGER NOT NULL CHECK (rating BETWEEN 1 AND 5),
+    body TEXT NOT NULL DEFAULT '',
+    reply TEXT NOT NULL DEFAULT '',
+    replied_at TIMESTAMP NULL,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    book_id UUID NOT NULL REFERENCES books(id) ON DELETE CASCADE,
+    reviewer_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE UNIQUE INDEX book_reviews_reviewer_idx ON book_reviews (book_id, reviewer_id);
+CREATE INDEX book_reviews_book_created_at_idx ON book_reviews (book_id, created_at DESC, id DESC);
+
+-- Running totals kept up to date with the reviews, so books can be listed and sorted by
+-- rating without adding them up every time.
+ALTER TABLE books ADD COLUMN rating_count INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE books ADD COLUMN rating_total INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE books ADD COLUMN rating_average DOUBLE PRECISION NOT NULL GENERATED ALWAYS AS (CASE WHEN rating_count = 0 THEN 0 ELSE rating_total::double precision / rating_count END) STORED;
+
+CREATE INDEX books_rating_average_idx ON books (rating_average DESC, id DESC) WHERE deleted_at IS NULL;
+
+-- +goose Down
+
+DROP INDEX books_rating_average_idx;
+
+ALTER TABLE books DROP COLUMN rating_average;
+ALTER TABLE books DROP COLUMN rating_total;
+ALTER TABLE books DROP COLUMN rating_count;
+
+DROP TABLE book_reviews;
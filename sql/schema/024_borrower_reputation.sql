@@ -0,0 +1,72 @@
+-- +goose Up
+
+-- The book owner's rating of how a borrower handled a loan, left once it is over.
+CREATE TABLE borrower_ratings (
+    id UUID PRIMARY KEY,
+    rating INTEGER NOT NULL CHECK (rating BETWEEN 1 AND 5),
+    comment TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL,
+    book_borrow_id UUID NOT NULL UNIQUE REFERENCES book_borrows(id) ON DELETE CASCADE,
+    lender_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    borrower_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX borrower_ratings_borrower_idx ON borrower_ratings (borrower_id);
+
+-- Owners can keep a book from borrowers whose reputation is below this, 0 lends to everyone.
+ALTER TABLE books ADD COLUMN min_borrower_reputation INTEGER NOT NULL DEFAULT 0 CHECK (min_borrower_reputation BETWEEN 0 AND 100);
+
+-- What every member's borrow history adds up to. Loans only count once they are over or
+-- overdue, a loan still inside its period says nothing yet. A disputed return is overdue
+-- until the owner confirms it, the same as in the borrow lists, and lost books are counted
+-- as lost rather than as days overdue.
+CREATE VIEW borrower_reputation_stats AS
+SELECT
+    users.id AS borrower_id,
+    loans.scored_loans::integer AS scored_loans,
+    loans.on_time_returns::integer AS on_time_returns,
+    loans.late_loans::integer AS late_loans,
+    loans.overdue_days::integer AS overdue_days,
+    loans.capped_overdue_days::integer AS capped_overdue_days,
+    loans.disputed_returns::integer AS disputed_returns,
+    loans.lost_books::integer AS lost_books,
+    ratings.rating_count::integer AS rating_count,
+    ratings.rating_average::double precision AS rating_average
+FROM users
+CROSS JOIN LATERAL (
+    SELECT
+        COUNT(*) AS scored_loans,
+        COUNT(*) FILTER (WHERE ended_loans.lost_at IS NULL AND ended_loans.overdue_days = 0) AS on_time_returns,
+        COUNT(*) FILTER (WHERE ended_loans.overdue_days > 0) AS late_loans,
+        COALESCE(SUM(ended_loans.overdue_days), 0) AS overdue_days,
+        -- One very late book shouldn't outweigh everything else the borrower did.
+        COALESCE(SUM(LEAST(ended_loans.overdue_days, 20)), 0) AS capped_overdue_days,
+        COUNT(*) FILTER (WHERE ended_loans.return_disputed_at IS NOT NULL) AS disputed_returns,
+        COUNT(*) FILTER (WHERE ended_loans.lost_at IS NOT NULL) AS lost_books
+    FROM (
+        SELECT book_borrows.lost_at, book_borrows.return_disputed_at,
+            CASE WHEN book_borrows.lost_at IS NOT NULL THEN 0
+            ELSE GREATEST(CEIL(EXTRACT(EPOCH FROM (
+                CASE
+                    WHEN book_borrows.returned_at IS NOT NULL AND book_borrows.return_status IS DISTINCT FROM 'disputed' THEN book_borrows.returned_at
+                    WHEN book_borrows.return_confirmed_at IS NOT NULL THEN book_borrows.return_confirmed_at
+                    ELSE NOW()::timestamp
+                END - book_borrows.due_at)) / 86400), 0)
+            END AS overdue_days
+        FROM book_borrows
+        WHERE book_borrows.borrower_id = users.id
+        AND (book_borrows.returned_at IS NOT NULL OR book_borrows.lost_at IS NOT NULL OR book_borrows.due_at < NOW())
+    ) AS ended_loans
+) AS loans
+CROSS JOIN LATERAL (
+    SELECT COUNT(*) AS rating_count, COALESCE(AVG(borrower_ratings.rating), 0) AS rating_average
+    FROM borrower_ratings WHERE borrower_ratings.borrower_id = users.id
+) AS ratings;
+
+-- +goose Down
+
+DROP VIEW borrower_reputation_stats;
+
+ALTER TABLE books DROP COLUMN min_borrower_reputation;
+
+DROP TABLE borrower_ratings;
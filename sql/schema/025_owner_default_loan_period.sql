@@ -0,0 +1,8 @@
+-- +goose Up
+
+-- The loan period new books get when the owner doesn't set one on the book itself.
+ALTER TABLE users ADD COLUMN default_loan_period_days INTEGER NOT NULL DEFAULT 14 CHECK (default_loan_period_days BETWEEN 1 AND 365);
+
+-- +goose Down
+
+ALTER TABLE users DROP COLUMN default_loan_period_days;
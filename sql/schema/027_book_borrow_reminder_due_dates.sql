@@ -0,0 +1,25 @@
+-- +goose Up
+
+-- Reminders are for a due date, so a loan renewed to a new one is reminded again.
+ALTER TABLE book_borrow_reminders ADD COLUMN due_at TIMESTAMP NULL;
+
+UPDATE book_borrow_reminders SET due_at = book_borrows.due_at FROM book_borrows WHERE book_borrows.id = book_borrow_reminders.book_borrow_id;
+
+ALTER TABLE book_borrow_reminders ALTER COLUMN due_at SET NOT NULL;
+
+DROP INDEX book_borrow_reminders_threshold_idx;
+
+-- A reminder is only ever sent once per borrow, threshold and due date.
+CREATE UNIQUE INDEX book_borrow_reminders_threshold_idx ON book_borrow_reminders (book_borrow_id, threshold, due_at);
+
+-- +goose Down
+
+DROP INDEX book_borrow_reminders_threshold_idx;
+
+-- Only the reminders for each borrow's latest due date are kept.
+DELETE FROM book_borrow_reminders USING book_borrows
+WHERE book_borrows.id = book_borrow_reminders.book_borrow_id AND book_borrow_reminders.due_at <> book_borrows.due_at;
+
+CREATE UNIQUE INDEX book_borrow_reminders_threshold_idx ON book_borrow_reminders (book_borrow_id, threshold);
+
+ALTER TABLE book_borrow_reminders DROP COLUMN due_at;
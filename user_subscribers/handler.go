@@ -1,6 +1,12 @@
 package user_subscribers
 
-import "github.com/elorenzorodz/co-library/internal/database"
+import (
+	"errors"
+	"time"
+
+	"github.com/elorenzorodz/co-library/common"
+	"github.com/elorenzorodz/co-library/internal/database"
+)
 
 func DatabaseUserSubscriberToUserSubscriberJSON(databaseUserSubscriber database.UserSubscriber) UserSubscriber {
 	return UserSubscriber{
@@ -20,4 +26,24 @@ func DatabaseUserSubscribersToUserSubscribersJSON(databaseUserSubscribers []data
 	}
 
 	return userSubscribers
-}
\ No newline at end of file
+}
+
+// Subscriptions are paged newest first.
+func UserSubscriberCursor(databaseUserSubscriber database.UserSubscriber) common.Cursor {
+	return common.Cursor{Value: databaseUserSubscriber.CreatedAt.Format(time.RFC3339Nano), ID: databaseUserSubscriber.ID}
+}
+
+// The created_at of the last subscription on the previous page, zero on the first page.
+func ParseUserSubscriberCursor(pageParameters common.PageParameters) (time.Time, error) {
+	if pageParameters.Cursor == nil {
+		return time.Time{}, nil
+	}
+
+	cursorCreatedAt, parseError := time.Parse(time.RFC3339Nano, pageParameters.Cursor.Value)
+
+	if parseError != nil {
+		return time.Time{}, errors.New("invalid cursor")
+	}
+
+	return cursorCreatedAt, nil
+}
@@ -108,7 +108,30 @@ func (userSubscriberAPIConfig *UserSubscriberAPIConfig) DeleteUserSubscriber(wri
 }
 
 func (userSubscriberAPIConfig *UserSubscriberAPIConfig) GetUserSubscribers(writer http.ResponseWriter, request *http.Request, userId uuid.UUID) {
-	userSubscribers, getUserSubscribersError := userSubscriberAPIConfig.DB.GetUserSubscribers(request.Context(), userId)
+	pageParameters, parsePageError := common.ParsePageParameters(request.URL.Query())
+
+	if parsePageError != nil {
+		common.ErrorResponse(writer, http.StatusBadRequest, parsePageError.Error())
+
+		return
+	}
+
+	cursorCreatedAt, parseCursorError := ParseUserSubscriberCursor(pageParameters)
+
+	if parseCursorError != nil {
+		common.ErrorResponse(writer, http.StatusBadRequest, parseCursorError.Error())
+
+		return
+	}
+
+	getUserSubscribersParams := database.GetUserSubscribersParams{
+		UserID:          userId,
+		CursorID:        pageParameters.CursorID(),
+		CursorCreatedAt: cursorCreatedAt,
+		PageLimit:       pageParameters.QueryLimit(),
+	}
+
+	userSubscribers, getUserSubscribersError := userSubscriberAPIConfig.DB.GetUserSubscribers(request.Context(), getUserSubscribersParams)
 
 	if getUserSubscribersError != nil {
 		if getUserSubscribersError == sql.ErrNoRows {
@@ -120,11 +143,36 @@ func (userSubscriberAPIConfig *UserSubscriberAPIConfig) GetUserSubscribers(write
 		return
 	}
 
-	common.JSONResponse(writer, http.StatusOK, DatabaseUserSubscribersToUserSubscribersJSON(userSubscribers))
+	userSubscribers, nextCursor := common.SplitPage(userSubscribers, pageParameters, UserSubscriberCursor)
+
+	common.JSONResponse(writer, http.StatusOK, common.Page[UserSubscriber]{Data: DatabaseUserSubscribersToUserSubscribersJSON(userSubscribers), NextCursor: nextCursor})
 }
 
 func (userSubscriberAPIConfig *UserSubscriberAPIConfig) GetUserSubscriptions(writer http.ResponseWriter, request *http.Request, subscriberId uuid.UUID) {
-	userSubscriptions, getUserSubscriptionsError := userSubscriberAPIConfig.DB.GetUserSubscriptions(request.Context(), subscriberId)
+	pageParameters, parsePageError := common.ParsePageParameters(request.URL.Query())
+
+	if parsePageError != nil {
+		common.ErrorResponse(writer, http.StatusBadRequest, parsePageError.Error())
+
+		return
+	}
+
+	cursorCreatedAt, parseCursorError := ParseUserSubscriberCursor(pageParameters)
+
+	if parseCursorError != nil {
+		common.ErrorResponse(writer, http.StatusBadRequest, parseCursorError.Error())
+
+		return
+	}
+
+	getUserSubscriptionsParams := database.GetUserSubscriptionsParams{
+		SubscriberID:    subscriberId,
+		CursorID:        pageParameters.CursorID(),
+		CursorCreatedAt: cursorCreatedAt,
+		PageLimit:       pageParameters.QueryLimit(),
+	}
+
+	userSubscriptions, getUserSubscriptionsError := userSubscriberAPIConfig.DB.GetUserSubscriptions(request.Context(), getUserSubscriptionsParams)
 
 	if getUserSubscriptionsError != nil {
 		if getUserSubscriptionsError == sql.ErrNoRows {
@@ -136,5 +184,7 @@ func (userSubscriberAPIConfig *UserSubscriberAPIConfig) GetUserSubscriptions(wri
 		return
 	}
 
-	common.JSONResponse(writer, http.StatusOK, DatabaseUserSubscribersToUserSubscribersJSON(userSubscriptions))
+	userSubscriptions, nextCursor := common.SplitPage(userSubscriptions, pageParameters, UserSubscriberCursor)
+
+	common.JSONResponse(writer, http.StatusOK, common.Page[UserSubscriber]{Data: DatabaseUserSubscribersToUserSubscribersJSON(userSubscriptions), NextCursor: nextCursor})
 }
\ No newline at end of file
@@ -3,6 +3,7 @@ package user_subscribers
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"net/http"
@@ -24,8 +25,8 @@ type MockUserSubscribersDB struct {
 	GetUserSubscriberFunc    func(ctx context.Context, arg database.GetUserSubscriberParams) (database.UserSubscriber, error)
 	CreateUserSubscriberFunc func(ctx context.Context, arg database.CreateUserSubscriberParams) (database.UserSubscriber, error)
 	DeleteUserSubscriberFunc func(ctx context.Context, arg database.DeleteUserSubscriberParams) (int64, error)
-	GetUserSubscribersFunc   func(ctx context.Context, userID uuid.UUID) ([]database.UserSubscriber, error)
-	GetUserSubscriptionsFunc func(ctx context.Context, subscriberID uuid.UUID) ([]database.UserSubscriber, error)
+	GetUserSubscribersFunc   func(ctx context.Context, arg database.GetUserSubscribersParams) ([]database.UserSubscriber, error)
+	GetUserSubscriptionsFunc func(ctx context.Context, arg database.GetUserSubscriptionsParams) ([]database.UserSubscriber, error)
 }
 
 func (mockQueries *MockUserSubscribersDB) GetUserByID(ctx context.Context, id uuid.UUID) (database.User, error) {
@@ -56,18 +57,18 @@ func (mockQueries *MockUserSubscribersDB) DeleteUserSubscriber(ctx context.Conte
 	return mockQueries.BaseMock.DeleteUserSubscriber(ctx, arg) 
 }
 
-func (mockQueries *MockUserSubscribersDB) GetUserSubscribers(ctx context.Context, userID uuid.UUID) ([]database.UserSubscriber, error) {
+func (mockQueries *MockUserSubscribersDB) GetUserSubscribers(ctx context.Context, arg database.GetUserSubscribersParams) ([]database.UserSubscriber, error) {
 	if mockQueries.GetUserSubscribersFunc != nil {
-		return mockQueries.GetUserSubscribersFunc(ctx, userID)
+		return mockQueries.GetUserSubscribersFunc(ctx, arg)
 	}
-	return mockQueries.BaseMock.GetUserSubscribers(ctx, userID)
+	return mockQueries.BaseMock.GetUserSubscribers(ctx, arg)
 }
 
-func (mockQueries *MockUserSubscribersDB) GetUserSubscriptions(ctx context.Context, subscriberID uuid.UUID) ([]database.UserSubscriber, error) {
+func (mockQueries *MockUserSubscribersDB) GetUserSubscriptions(ctx context.Context, arg database.GetUserSubscriptionsParams) ([]database.UserSubscriber, error) {
 	if mockQueries.GetUserSubscriptionsFunc != nil {
-		return mockQueries.GetUserSubscriptionsFunc(ctx, subscriberID)
+		return mockQueries.GetUserSubscriptionsFunc(ctx, arg)
 	}
-	return mockQueries.BaseMock.GetUserSubscriptions(ctx, subscriberID)
+	return mockQueries.BaseMock.GetUserSubscriptions(ctx, arg)
 }
 
 func newTestUserID() uuid.UUID {
@@ -343,7 +344,7 @@ func TestGetUserSubscribers(tTesting *testing.T) {
 	tTesting.Run("SuccessNonEmptyList", func(t *testing.T) {
 		mockDB := &MockUserSubscribersDB{
 			BaseMock: common.NewBaseMock(),
-			GetUserSubscribersFunc: func(ctx context.Context, id uuid.UUID) ([]database.UserSubscriber, error) {
+			GetUserSubscribersFunc: func(ctx context.Context, arg database.GetUserSubscribersParams) ([]database.UserSubscriber, error) {
 				return subscribers, nil
 			},
 		}
@@ -363,7 +364,7 @@ func TestGetUserSubscribers(tTesting *testing.T) {
 	tTesting.Run("SuccessEmptyList", func(t *testing.T) {
 		mockDB := &MockUserSubscribersDB{
 			BaseMock: common.NewBaseMock(),
-			GetUserSubscribersFunc: func(ctx context.Context, id uuid.UUID) ([]database.UserSubscriber, error) {
+			GetUserSubscribersFunc: func(ctx context.Context, arg database.GetUserSubscribersParams) ([]database.UserSubscriber, error) {
 				return nil, sql.ErrNoRows
 			},
 		}
@@ -383,7 +384,7 @@ func TestGetUserSubscribers(tTesting *testing.T) {
 	tTesting.Run("DBError", func(t *testing.T) {
 		mockDB := &MockUserSubscribersDB{
 			BaseMock: common.NewBaseMock(),
-			GetUserSubscribersFunc: func(ctx context.Context, id uuid.UUID) ([]database.UserSubscriber, error) {
+			GetUserSubscribersFunc: func(ctx context.Context, arg database.GetUserSubscribersParams) ([]database.UserSubscriber, error) {
 				return nil, errors.New("simulated DB error on get subscribers")
 			},
 		}
@@ -398,6 +399,41 @@ func TestGetUserSubscribers(tTesting *testing.T) {
 			t.Errorf("Expected status %d, got %d. Body: %s", http.StatusInternalServerError, recorder.Code, recorder.Body.String())
 		}
 	})
+	// 4. Success: the next page starts after the created_at and id of the last subscriber.
+	tTesting.Run("Paginates", func(t *testing.T) {
+		var getUserSubscribersParams database.GetUserSubscribersParams
+
+		mockDB := &MockUserSubscribersDB{
+			BaseMock: common.NewBaseMock(),
+			GetUserSubscribersFunc: func(ctx context.Context, arg database.GetUserSubscribersParams) ([]database.UserSubscriber, error) {
+				getUserSubscribersParams = arg
+
+				return subscribers, nil
+			},
+		}
+
+		apiConfig := UserSubscriberAPIConfig{APIConfig: common.APIConfig{DB: mockDB}}
+		request := httptest.NewRequest(http.MethodGet, "/api/v1/subscribers/followers?limit=1", nil)
+		recorder := httptest.NewRecorder()
+
+		apiConfig.GetUserSubscribers(recorder, request, userID)
+
+		var response common.Page[UserSubscriber]
+		json.Unmarshal(recorder.Body.Bytes(), &response)
+
+		if len(response.Data) != 1 || response.NextCursor == nil {
+			t.Fatalf("Expected one subscriber and a next cursor, got %s", recorder.Body.String())
+		}
+
+		request = httptest.NewRequest(http.MethodGet, "/api/v1/subscribers/followers?limit=1&cursor="+*response.NextCursor, nil)
+		recorder = httptest.NewRecorder()
+
+		apiConfig.GetUserSubscribers(recorder, request, userID)
+
+		if !getUserSubscribersParams.CursorCreatedAt.Equal(subscribers[0].CreatedAt) || getUserSubscribersParams.CursorID.UUID != subscribers[0].ID || getUserSubscribersParams.PageLimit != 2 {
+			t.Errorf("Unexpected page query: %+v", getUserSubscribersParams)
+		}
+	})
 }
 
 func TestGetUserSubscriptions(tTesting *testing.T) {
@@ -411,7 +447,7 @@ func TestGetUserSubscriptions(tTesting *testing.T) {
 	tTesting.Run("SuccessNonEmptyList", func(t *testing.T) {
 		mockDB := &MockUserSubscribersDB{
 			BaseMock: common.NewBaseMock(),
-			GetUserSubscriptionsFunc: func(ctx context.Context, id uuid.UUID) ([]database.UserSubscriber, error) {
+			GetUserSubscriptionsFunc: func(ctx context.Context, arg database.GetUserSubscriptionsParams) ([]database.UserSubscriber, error) {
 				return subscriptions, nil
 			},
 		}
@@ -431,7 +467,7 @@ func TestGetUserSubscriptions(tTesting *testing.T) {
 	tTesting.Run("SuccessEmptyList", func(t *testing.T) {
 		mockDB := &MockUserSubscribersDB{
 			BaseMock: common.NewBaseMock(),
-			GetUserSubscriptionsFunc: func(ctx context.Context, id uuid.UUID) ([]database.UserSubscriber, error) {
+			GetUserSubscriptionsFunc: func(ctx context.Context, arg database.GetUserSubscriptionsParams) ([]database.UserSubscriber, error) {
 				return nil, sql.ErrNoRows
 			},
 		}
@@ -451,7 +487,7 @@ func TestGetUserSubscriptions(tTesting *testing.T) {
 	tTesting.Run("DBError", func(t *testing.T) {
 		mockDB := &MockUserSubscribersDB{
 			BaseMock: common.NewBaseMock(),
-			GetUserSubscriptionsFunc: func(ctx context.Context, id uuid.UUID) ([]database.UserSubscriber, error) {
+			GetUserSubscriptionsFunc: func(ctx context.Context, arg database.GetUserSubscriptionsParams) ([]database.UserSubscriber, error) {
 				return nil, errors.New("simulated DB error on get subscriptions")
 			},
 		}
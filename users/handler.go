@@ -4,11 +4,12 @@ import (
 	"context"
 	"fmt"
 	"log"
-	"sync"
 	"time"
 
+	"github.com/elorenzorodz/co-library/borrower_reputation"
+	"github.com/elorenzorodz/co-library/common"
 	"github.com/elorenzorodz/co-library/internal/database"
-	"github.com/mailgun/mailgun-go/v4"
+	"github.com/elorenzorodz/co-library/notification_templates"
 	"golang.org/x/crypto/bcrypt"
 )
 
@@ -20,6 +21,18 @@ func DatabaseUserToUserJSON(databaseUser database.User) User {
 		Email: databaseUser.Email,
 		CreatedAt: databaseUser.CreatedAt,
 		UpdatedAt: databaseUser.UpdatedAt,
+		Locale: databaseUser.Locale,
+		DefaultLoanPeriodDays: databaseUser.DefaultLoanPeriodDays,
+	}
+}
+
+func DatabaseUserProfileRowToUserProfileJSON(databaseRow database.GetUserProfileRow) UserProfile {
+	return UserProfile{
+		ID:                 databaseRow.User.ID,
+		FirstName:          databaseRow.User.FirstName,
+		LastName:           databaseRow.User.LastName,
+		CreatedAt:          databaseRow.User.CreatedAt,
+		BorrowerReputation: borrower_reputation.DatabaseBorrowerReputationStatToBorrowerReputationJSON(databaseRow.BorrowerReputationStat),
 	}
 }
 
@@ -39,44 +52,75 @@ func VerifyPassword(password, hash string) error {
     return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
 }
 
-func DispatchNewBookAlertsSync(bookTitle string, subscribers []database.User, sender database.User, mailGunAPIKey string, mailGunSendingDomain string) {
-	waitGroup := &sync.WaitGroup{}
+func UserName(databaseUser database.User) string {
+	return fmt.Sprintf("%s %s", databaseUser.FirstName, databaseUser.LastName)
+}
+
+func UserNameAndEmail(databaseUser database.User) string {
+	return fmt.Sprintf("%s <%s>", UserName(databaseUser), databaseUser.Email)
+}
+
+// Rendered in the subscriber's locale.
+func NewBookAlertNotification(templates common.TemplateRenderer, sender database.User, subscriber database.User, bookTitle string) (common.Notification, error) {
+	newBookAlertData := notification_templates.NewBookAlertData{
+		SenderName:     UserName(sender),
+		SubscriberName: UserName(subscriber),
+		BookTitle:      bookTitle,
+	}
+
+	renderedTemplate, renderError := templates.Render(notification_templates.NewBookAlertTemplate, subscriber.Locale, newBookAlertData)
 
-	for _, subscriber := range subscribers {
-		waitGroup.Add(1)
+	if renderError != nil {
+		return common.Notification{}, renderError
+	}
 
-		senderName := fmt.Sprintf("%s %s", sender.FirstName, sender.LastName)
-		subscriberName := fmt.Sprintf("%s %s", subscriber.FirstName, subscriber.LastName)
+	return renderedTemplate.Notification(UserNameAndEmail(sender), UserNameAndEmail(subscriber)), nil
+}
 
-		go SendNewBookAlert(mailGunSendingDomain, mailGunAPIKey, senderName, sender.Email, subscriberName, subscriber.Email, bookTitle, waitGroup)
+// Rendered in the subscriber's locale, listing at most maxTitles of the titles.
+func NewBooksAlertNotification(templates common.TemplateRenderer, sender database.User, subscriber database.User, bookTitles []string, maxTitles int) (common.Notification, error) {
+	newBooksAlertData := notification_templates.NewBooksAlertData{
+		SenderName:     UserName(sender),
+		SubscriberName: UserName(subscriber),
+		BookCount:      len(bookTitles),
+		BookTitles:     bookTitles[:min(len(bookTitles), maxTitles)],
+		MoreBooks:      max(0, len(bookTitles)-maxTitles),
 	}
 
-	waitGroup.Wait()
+	renderedTemplate, renderError := templates.Render(notification_templates.NewBooksAlertTemplate, subscriber.Locale, newBooksAlertData)
 
-	log.Printf("New book alert sent to %v subscribers", len(subscribers))
+	if renderError != nil {
+		return common.Notification{}, renderError
+	}
+
+	return renderedTemplate.Notification(UserNameAndEmail(sender), UserNameAndEmail(subscriber)), nil
 }
 
-func SendNewBookAlert(mailGunSendingDomain, mailGunAPIKey, senderName, senderEmail, subscriberName, subscriberEmail, bookTitle string, waitGroup *sync.WaitGroup) {
-	defer waitGroup.Done()
+// Rendered in the borrower's locale.
+func BookReservationOfferAlertNotification(templates common.TemplateRenderer, owner database.User, borrower database.User, bookTitle string, offerExpiresAt time.Time) (common.Notification, error) {
+	bookReservationOfferData := notification_templates.BookReservationOfferData{
+		OwnerName:      UserName(owner),
+		BorrowerName:   UserName(borrower),
+		BookTitle:      bookTitle,
+		OfferExpiresAt: offerExpiresAt,
+	}
 
-	fromNameAndEmail := fmt.Sprintf("%s <%s>", senderName, senderEmail)
-	toNameAndEmail := fmt.Sprintf("%s <%s>", subscriberName, subscriberEmail)
+	renderedTemplate, renderError := templates.Render(notification_templates.BookReservationOfferTemplate, borrower.Locale, bookReservationOfferData)
 
-	mg := mailgun.NewMailgun(mailGunSendingDomain, mailGunAPIKey)
+	if renderError != nil {
+		return common.Notification{}, renderError
+	}
 
-	mailgunMessage := mailgun.NewMessage(
-		fromNameAndEmail,
-		"My Library Just Got Updated",
-		fmt.Sprintf("Hi %s, \n\nI've added a new book in my library: %s \n\nCheck it out! Thank you.", subscriberName, bookTitle),
-		toNameAndEmail,
-	)
+	return renderedTemplate.Notification(UserNameAndEmail(owner), UserNameAndEmail(borrower)), nil
+}
 
-	ctx, cancel := context.WithTimeout(context.Background(), time.Second*30)
-	defer cancel()
+// For alerts sent in the background, after the request that triggered them is done.
+func SendNotification(notifier common.Notifier, notification common.Notification) error {
+	sendError := notifier.Send(context.Background(), notification)
 
-	sendMessage, id, sendError := mg.Send(ctx, mailgunMessage)
-	
 	if sendError != nil {
-		log.Printf("Mailgun send error | ID: %s | Message: %s | Error: %s", id, sendMessage, sendError)
+		log.Printf("failed to send %q to %s: %s", notification.Subject, notification.To, sendError)
 	}
-}
\ No newline at end of file
+
+	return sendError
+}
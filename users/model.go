@@ -3,6 +3,7 @@ package users
 import (
 	"time"
 
+	"github.com/elorenzorodz/co-library/borrower_reputation"
 	"github.com/elorenzorodz/co-library/common"
 	"github.com/google/uuid"
 )
@@ -19,6 +20,18 @@ type User struct {
 	Password  string    `json:"password"`
 	CreatedAt time.Time `json:"createdAt"`
 	UpdatedAt time.Time `json:"updatedAt"`
+	Locale    string    `json:"locale"`
+	// Given to new books that don't set their own loan period.
+	DefaultLoanPeriodDays int32 `json:"default_loan_period_days"`
+}
+
+// What other members see of a user, without the email and settings.
+type UserProfile struct {
+	ID                 uuid.UUID                              `json:"id"`
+	FirstName          string                                 `json:"first_name"`
+	LastName           string                                 `json:"last_name"`
+	CreatedAt          time.Time                              `json:"createdAt"`
+	BorrowerReputation borrower_reputation.BorrowerReputation `json:"borrower_reputation"`
 }
 
 type CreateUserParameters struct {
@@ -26,6 +39,15 @@ type CreateUserParameters struct {
 	LastName  string `json:"last_name"`
 	Email     string `json:"email"`
 	Password  string `json:"password"`
+	Locale    string `json:"locale"`
+}
+
+type UserLocaleParameters struct {
+	Locale string `json:"locale"`
+}
+
+type UserDefaultLoanPeriodParameters struct {
+	DefaultLoanPeriodDays int32 `json:"default_loan_period_days"`
 }
 
 type UserLoginParameters struct {
@@ -11,8 +11,10 @@ import (
 
 	"github.com/elorenzorodz/co-library/common"
 	"github.com/elorenzorodz/co-library/internal/database"
+	"github.com/elorenzorodz/co-library/notification_templates"
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
+	"github.com/gorilla/mux"
 )
 
 func (userAPIConfig *UserAPIConfig) CreateUser(writer http.ResponseWriter, request *http.Request) {
@@ -67,6 +69,17 @@ func (userAPIConfig *UserAPIConfig) CreateUser(writer http.ResponseWriter, reque
 		return
 	}
 
+	// Notifications are sent in the default locale unless the user picks another.
+	if createUserParameters.Locale == "" {
+		createUserParameters.Locale = notification_templates.DefaultLocale
+	}
+
+	if !notification_templates.IsLocaleSupported(createUserParameters.Locale) {
+		common.ErrorResponse(writer, http.StatusBadRequest, fmt.Sprintf("error creating user: unsupported locale %s", createUserParameters.Locale))
+
+		return
+	}
+
 	hashedPassword, hashPasswordError := HashPassword(createUserParameters.Password)
 
 	if hashPasswordError != nil {
@@ -83,6 +96,7 @@ func (userAPIConfig *UserAPIConfig) CreateUser(writer http.ResponseWriter, reque
 		Password: hashedPassword,
 		CreatedAt: time.Now().UTC(),
 		UpdatedAt: time.Now().UTC(),
+		Locale: createUserParameters.Locale,
 	}
 
 	newUser, createUserError := userAPIConfig.DB.CreateUser(request.Context(), createUserParams)
@@ -158,4 +172,97 @@ func (userAPIConfig *UserAPIConfig) Login(writer http.ResponseWriter, request *h
 	userAuthorized.Token = signedToken
 
 	common.JSONResponse(writer, http.StatusOK, userAuthorized)
-}
\ No newline at end of file
+}
+
+func (userAPIConfig *UserAPIConfig) UpdateUserLocale(writer http.ResponseWriter, request *http.Request, userId uuid.UUID) {
+	userLocaleParameters := UserLocaleParameters{}
+
+	decoder := json.NewDecoder(request.Body)
+	decoderError := decoder.Decode(&userLocaleParameters)
+
+	if decoderError != nil {
+		common.ErrorResponse(writer, http.StatusBadRequest, fmt.Sprintf("error parsing JSON: %s", decoderError))
+
+		return
+	}
+
+	if !notification_templates.IsLocaleSupported(userLocaleParameters.Locale) {
+		common.ErrorResponse(writer, http.StatusBadRequest, fmt.Sprintf("unsupported locale: %s, supported locales are %s", userLocaleParameters.Locale, strings.Join(notification_templates.SupportedLocales, ", ")))
+
+		return
+	}
+
+	updateUserLocaleParams := database.UpdateUserLocaleParams{
+		Locale: userLocaleParameters.Locale,
+		ID:     userId,
+	}
+
+	updatedUser, updateUserLocaleError := userAPIConfig.DB.UpdateUserLocale(request.Context(), updateUserLocaleParams)
+
+	if updateUserLocaleError != nil {
+		common.ErrorResponse(writer, http.StatusInternalServerError, fmt.Sprintf("error updating locale: %s", updateUserLocaleError))
+
+		return
+	}
+
+	common.JSONResponse(writer, http.StatusOK, DatabaseUserToUserJSON(updatedUser))
+}
+
+func (userAPIConfig *UserAPIConfig) UpdateUserDefaultLoanPeriod(writer http.ResponseWriter, request *http.Request, userId uuid.UUID) {
+	userDefaultLoanPeriodParameters := UserDefaultLoanPeriodParameters{}
+
+	decoder := json.NewDecoder(request.Body)
+	decoderError := decoder.Decode(&userDefaultLoanPeriodParameters)
+
+	if decoderError != nil {
+		common.ErrorResponse(writer, http.StatusBadRequest, fmt.Sprintf("error parsing JSON: %s", decoderError))
+
+		return
+	}
+
+	if !common.IsLoanPeriodValid(userDefaultLoanPeriodParameters.DefaultLoanPeriodDays) {
+		common.ErrorResponse(writer, http.StatusBadRequest, fmt.Sprintf("default_loan_period_days must be between 1 and %d", common.MaxLoanPeriodDays))
+
+		return
+	}
+
+	updateUserDefaultLoanPeriodParams := database.UpdateUserDefaultLoanPeriodParams{
+		DefaultLoanPeriodDays: userDefaultLoanPeriodParameters.DefaultLoanPeriodDays,
+		ID:                    userId,
+	}
+
+	updatedUser, updateUserDefaultLoanPeriodError := userAPIConfig.DB.UpdateUserDefaultLoanPeriod(request.Context(), updateUserDefaultLoanPeriodParams)
+
+	if updateUserDefaultLoanPeriodError != nil {
+		common.ErrorResponse(writer, http.StatusInternalServerError, fmt.Sprintf("error updating default loan period: %s", updateUserDefaultLoanPeriodError))
+
+		return
+	}
+
+	common.JSONResponse(writer, http.StatusOK, DatabaseUserToUserJSON(updatedUser))
+}
+
+func (userAPIConfig *UserAPIConfig) GetUserProfile(writer http.ResponseWriter, request *http.Request, userId uuid.UUID) {
+	vars := mux.Vars(request)
+	profileUserId, parseUserIdError := uuid.Parse(vars["userId"])
+
+	if parseUserIdError != nil {
+		common.ErrorResponse(writer, http.StatusBadRequest, "invalid user id")
+
+		return
+	}
+
+	getUserProfile, getUserProfileError := userAPIConfig.DB.GetUserProfile(request.Context(), profileUserId)
+
+	if getUserProfileError != nil {
+		if getUserProfileError == sql.ErrNoRows {
+			common.ErrorResponse(writer, http.StatusNotFound, "user not found")
+		} else {
+			common.ErrorResponse(writer, http.StatusInternalServerError, "failed to get user profile, please try again in a few minutes")
+		}
+
+		return
+	}
+
+	common.JSONResponse(writer, http.StatusOK, DatabaseUserProfileRowToUserProfileJSON(getUserProfile))
+}
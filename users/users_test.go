@@ -14,6 +14,7 @@ import (
 	"github.com/elorenzorodz/co-library/internal/database"
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
+	"github.com/gorilla/mux"
 	"golang.org/x/crypto/bcrypt"
 )
 
@@ -23,6 +24,9 @@ type MockQueries struct {
     CreateUserFunc     func(ctx context.Context, arg database.CreateUserParams) (database.User, error)
     GetUserByEmailFunc func(ctx context.Context, email string) (database.User, error)
     GetUserByIDFunc    func(ctx context.Context, id uuid.UUID) (database.User, error)
+    UpdateUserLocaleFunc func(ctx context.Context, arg database.UpdateUserLocaleParams) (database.User, error)
+    GetUserProfileFunc   func(ctx context.Context, id uuid.UUID) (database.GetUserProfileRow, error)
+    UpdateUserDefaultLoanPeriodFunc func(ctx context.Context, arg database.UpdateUserDefaultLoanPeriodParams) (database.User, error)
 }
 
 func (mockQueries *MockQueries) CreateUser(ctx context.Context, arg database.CreateUserParams) (database.User, error) {
@@ -48,6 +52,30 @@ func (mockQueries *MockQueries) GetUserByID(ctx context.Context, id uuid.UUID) (
 	return mockQueries.BaseMock.GetUserByID(ctx, id)
 }
 
+func (mockQueries *MockQueries) UpdateUserLocale(ctx context.Context, arg database.UpdateUserLocaleParams) (database.User, error) {
+	if mockQueries.UpdateUserLocaleFunc != nil {
+		return mockQueries.UpdateUserLocaleFunc(ctx, arg)
+	}
+
+	return mockQueries.BaseMock.UpdateUserLocale(ctx, arg)
+}
+
+func (mockQueries *MockQueries) UpdateUserDefaultLoanPeriod(ctx context.Context, arg database.UpdateUserDefaultLoanPeriodParams) (database.User, error) {
+	if mockQueries.UpdateUserDefaultLoanPeriodFunc != nil {
+		return mockQueries.UpdateUserDefaultLoanPeriodFunc(ctx, arg)
+	}
+
+	return mockQueries.BaseMock.UpdateUserDefaultLoanPeriod(ctx, arg)
+}
+
+func (mockQueries *MockQueries) GetUserProfile(ctx context.Context, id uuid.UUID) (database.GetUserProfileRow, error) {
+	if mockQueries.GetUserProfileFunc != nil {
+		return mockQueries.GetUserProfileFunc(ctx, id)
+	}
+
+	return mockQueries.BaseMock.GetUserProfile(ctx, id)
+}
+
 func newTestUser() database.User {
 	hashedPassword, _ := bcrypt.GenerateFromPassword([]byte("!Password123"), bcrypt.DefaultCost)
 	return database.User{
@@ -58,6 +86,7 @@ func newTestUser() database.User {
 		UpdatedAt: time.Now(),
 		Email:     "test@email.com",
 		Password:  string(hashedPassword),
+		Locale:    "en",
 	}
 }
 
@@ -69,6 +98,10 @@ func TestCreateUser(tTesting *testing.T) {
 		mockQueries := &MockQueries{
 			BaseMock: common.NewBaseMock(),
 			CreateUserFunc: func(ctx context.Context, arg database.CreateUserParams) (database.User, error) {
+				if arg.Locale != "en" {
+					t.Errorf("Expected the default locale, got %q", arg.Locale)
+				}
+
 				return testUser, nil
 			},
 			GetUserByEmailFunc: func(ctx context.Context, email string) (database.User, error) {
@@ -148,6 +181,35 @@ func TestCreateUser(tTesting *testing.T) {
 			t.Errorf("Expected status %d (Conflict), got %d. Body: %s", http.StatusConflict, recorder.Code, recorder.Body.String())
 		}
 	})
+
+	// 4. Unsupported locale test case
+	tTesting.Run("UnsupportedLocale", func(t *testing.T) {
+		mockQueries := &MockQueries{
+			BaseMock: common.NewBaseMock(),
+			GetUserByEmailFunc: func(ctx context.Context, email string) (database.User, error) {
+				return database.User{}, sql.ErrNoRows
+			},
+		}
+
+		userAPIConfig := UserAPIConfig{APIConfig: common.APIConfig{DB: mockQueries}}
+
+		requestBody, _ := json.Marshal(struct {
+			FirstName string `json:"first_name"`
+			LastName  string `json:"last_name"`
+			Email     string `json:"email"`
+			Password  string `json:"password"`
+			Locale    string `json:"locale"`
+		}{FirstName: testUser.FirstName, LastName: testUser.LastName, Email: testUser.Email, Password: "!Password123", Locale: "xx"})
+
+		request := httptest.NewRequest(http.MethodPost, "/api/v1/user/register", bytes.NewBuffer(requestBody))
+		recorder := httptest.NewRecorder()
+
+		userAPIConfig.CreateUser(recorder, request)
+
+		if recorder.Code != http.StatusBadRequest {
+			t.Errorf("Expected status %d, got %d. Body: %s", http.StatusBadRequest, recorder.Code, recorder.Body.String())
+		}
+	})
 }
 
 func TestLogin(tTesting *testing.T) {
@@ -261,3 +323,176 @@ GEPIHTMy6cJHSf+xLGKcIp40vHg1A9Rg8GeWhax4bIghE3cuKj5RNyQc
 		}
 	})
 }
+
+func TestUpdateUserLocale(tTesting *testing.T) {
+	testUser := newTestUser()
+
+	// 1. Success: the locale is saved and returned.
+	tTesting.Run("Success", func(t *testing.T) {
+		mockQueries := &MockQueries{
+			BaseMock: common.NewBaseMock(),
+			UpdateUserLocaleFunc: func(ctx context.Context, arg database.UpdateUserLocaleParams) (database.User, error) {
+				if arg.ID != testUser.ID {
+					t.Errorf("Expected user %s, got %s", testUser.ID, arg.ID)
+				}
+
+				updatedUser := testUser
+				updatedUser.Locale = arg.Locale
+
+				return updatedUser, nil
+			},
+		}
+
+		userAPIConfig := UserAPIConfig{APIConfig: common.APIConfig{DB: mockQueries}}
+
+		requestBody, _ := json.Marshal(UserLocaleParameters{Locale: "es"})
+
+		request := httptest.NewRequest(http.MethodPatch, "/api/v1/user/locale", bytes.NewBuffer(requestBody))
+		recorder := httptest.NewRecorder()
+
+		userAPIConfig.UpdateUserLocale(recorder, request, testUser.ID)
+
+		if recorder.Code != http.StatusOK {
+			t.Fatalf("Expected status %d, got %d. Body: %s", http.StatusOK, recorder.Code, recorder.Body.String())
+		}
+
+		var user User
+		json.Unmarshal(recorder.Body.Bytes(), &user)
+
+		if user.Locale != "es" {
+			t.Errorf("Expected locale es, got %q", user.Locale)
+		}
+	})
+
+	// 2. Failure: unsupported locales are rejected.
+	tTesting.Run("UnsupportedLocale", func(t *testing.T) {
+		mockQueries := &MockQueries{BaseMock: common.NewBaseMock()}
+		userAPIConfig := UserAPIConfig{APIConfig: common.APIConfig{DB: mockQueries}}
+
+		requestBody, _ := json.Marshal(UserLocaleParameters{Locale: "xx"})
+
+		request := httptest.NewRequest(http.MethodPatch, "/api/v1/user/locale", bytes.NewBuffer(requestBody))
+		recorder := httptest.NewRecorder()
+
+		userAPIConfig.UpdateUserLocale(recorder, request, testUser.ID)
+
+		if recorder.Code != http.StatusBadRequest {
+			t.Errorf("Expected status %d, got %d. Body: %s", http.StatusBadRequest, recorder.Code, recorder.Body.String())
+		}
+	})
+}
+
+func TestUpdateUserDefaultLoanPeriod(tTesting *testing.T) {
+	testUser := newTestUser()
+
+	updateUserDefaultLoanPeriod := func(mockQueries *MockQueries, body string) *httptest.ResponseRecorder {
+		userAPIConfig := UserAPIConfig{APIConfig: common.APIConfig{DB: mockQueries}}
+
+		request := httptest.NewRequest(http.MethodPatch, "/api/v1/user/loan-period", bytes.NewBufferString(body))
+		recorder := httptest.NewRecorder()
+
+		userAPIConfig.UpdateUserDefaultLoanPeriod(recorder, request, testUser.ID)
+
+		return recorder
+	}
+
+	// 1. Success: the default is saved and returned.
+	tTesting.Run("Success", func(t *testing.T) {
+		mockQueries := &MockQueries{
+			BaseMock: common.NewBaseMock(),
+			UpdateUserDefaultLoanPeriodFunc: func(ctx context.Context, arg database.UpdateUserDefaultLoanPeriodParams) (database.User, error) {
+				if arg.ID != testUser.ID {
+					t.Errorf("Expected user %s, got %s", testUser.ID, arg.ID)
+				}
+
+				updatedUser := testUser
+				updatedUser.DefaultLoanPeriodDays = arg.DefaultLoanPeriodDays
+
+				return updatedUser, nil
+			},
+		}
+
+		recorder := updateUserDefaultLoanPeriod(mockQueries, `{"default_loan_period_days": 21}`)
+
+		if recorder.Code != http.StatusOK {
+			t.Fatalf("Expected status %d, got %d. Body: %s", http.StatusOK, recorder.Code, recorder.Body.String())
+		}
+
+		var user User
+		json.Unmarshal(recorder.Body.Bytes(), &user)
+
+		if user.DefaultLoanPeriodDays != 21 {
+			t.Errorf("Expected a default loan period of 21, got %d", user.DefaultLoanPeriodDays)
+		}
+	})
+
+	// 2. Failure: the default has to be a valid loan period.
+	tTesting.Run("InvalidLoanPeriod", func(t *testing.T) {
+		for _, body := range []string{`{"default_loan_period_days": 0}`, `{"default_loan_period_days": 366}`} {
+			if recorder := updateUserDefaultLoanPeriod(&MockQueries{BaseMock: common.NewBaseMock()}, body); recorder.Code != http.StatusBadRequest {
+				t.Errorf("%s: expected status %d, got %d", body, http.StatusBadRequest, recorder.Code)
+			}
+		}
+	})
+}
+
+func TestGetUserProfile(tTesting *testing.T) {
+	testUser := newTestUser()
+	viewerId := uuid.New()
+
+	getUserProfile := func(mockQueries *MockQueries, profileUserId string) *httptest.ResponseRecorder {
+		userAPIConfig := UserAPIConfig{APIConfig: common.APIConfig{DB: mockQueries}}
+
+		request := httptest.NewRequest(http.MethodGet, "/api/v1/users/profile/"+profileUserId, nil)
+		request = mux.SetURLVars(request, map[string]string{"userId": profileUserId})
+		recorder := httptest.NewRecorder()
+
+		userAPIConfig.GetUserProfile(recorder, request, viewerId)
+
+		return recorder
+	}
+
+	// 1. Success: the profile shows the borrower reputation, not the email.
+	tTesting.Run("Success", func(t *testing.T) {
+		mockQueries := &MockQueries{
+			BaseMock: common.NewBaseMock(),
+			GetUserProfileFunc: func(ctx context.Context, id uuid.UUID) (database.GetUserProfileRow, error) {
+				stat := database.BorrowerReputationStat{BorrowerID: id, ScoredLoans: 2, OnTimeReturns: 1, LateLoans: 1, OverdueDays: 3, CappedOverdueDays: 3, RatingCount: 1, RatingAverage: 4}
+
+				return database.GetUserProfileRow{User: testUser, BorrowerReputationStat: stat}, nil
+			},
+		}
+
+		recorder := getUserProfile(mockQueries, testUser.ID.String())
+
+		if recorder.Code != http.StatusOK {
+			t.Fatalf("Expected status %d, got %d. Body: %s", http.StatusOK, recorder.Code, recorder.Body.String())
+		}
+
+		var userProfile UserProfile
+		json.Unmarshal(recorder.Body.Bytes(), &userProfile)
+
+		if userProfile.ID != testUser.ID || userProfile.BorrowerReputation.Loans != 2 || userProfile.BorrowerReputation.LateReturns != 1 ||
+			userProfile.BorrowerReputation.Score != 72 || userProfile.BorrowerReputation.New {
+			t.Errorf("Unexpected profile: %+v", userProfile)
+		}
+
+		if bytes.Contains(recorder.Body.Bytes(), []byte(testUser.Email)) {
+			t.Error("Expected the email to stay private")
+		}
+	})
+
+	// 2. Failure: unknown user.
+	tTesting.Run("NotFound", func(t *testing.T) {
+		if recorder := getUserProfile(&MockQueries{BaseMock: common.NewBaseMock()}, uuid.New().String()); recorder.Code != http.StatusNotFound {
+			t.Errorf("Expected status %d, got %d", http.StatusNotFound, recorder.Code)
+		}
+	})
+
+	// 3. Failure: invalid user id.
+	tTesting.Run("InvalidID", func(t *testing.T) {
+		if recorder := getUserProfile(&MockQueries{BaseMock: common.NewBaseMock()}, "not-a-uuid"); recorder.Code != http.StatusBadRequest {
+			t.Errorf("Expected status %d, got %d", http.StatusBadRequest, recorder.Code)
+		}
+	})
+}